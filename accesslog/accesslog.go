@@ -0,0 +1,167 @@
+// package accesslog parses and tails access logs for real client
+// traffic to a node (e.g. from nginx or envoy proxying the node's
+// api) so error rates and latencies observed by clients can be
+// measured alongside the doctor's own synthetic checks
+package accesslog
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// nginx combined log format, optionally with
+	// $request_time (in seconds) appended to each line
+	NginxFormat = "nginx"
+	// envoy default access log format
+	EnvoyFormat = "envoy"
+	// json objects with a `status` code and `request_time` in seconds
+	JSONFormat = "json"
+)
+
+var (
+	ValidFormats = []string{NginxFormat, EnvoyFormat, JSONFormat}
+
+	// $remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent "$http_referer" "$http_user_agent" [$request_time]
+	nginxLinePattern = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]+\] "[^"]*" (\d{3}) \S+(?: "[^"]*" "[^"]*")?(?: (\d+(?:\.\d+)?))?`)
+	// [%START_TIME%] "%REQ(:METHOD)% %REQ(X-ENVOY-ORIGINAL-PATH?:PATH)% %PROTOCOL%" %RESPONSE_CODE% %RESPONSE_FLAGS% %BYTES_RECEIVED% %BYTES_SENT% %DURATION% ...
+	envoyLinePattern = regexp.MustCompile(`^\[[^\]]+\] "[^"]*" (\d{3}) \S+ \d+ \d+ (\d+)`)
+)
+
+// Entry is a single request parsed from an access log
+type Entry struct {
+	Status int
+	// how long the request took to serve
+	// only valid if HasLatency is true
+	Latency    time.Duration
+	HasLatency bool
+}
+
+// ServerError returns whether the request failed due to the server
+func (e Entry) ServerError() bool {
+	return e.Status >= 500
+}
+
+// ClientError returns whether the request failed due to the client
+func (e Entry) ClientError() bool {
+	return e.Status >= 400 && e.Status < 500
+}
+
+// Parser parses a single line of an access log
+// returning the entry and error (if any)
+type Parser func(line string) (Entry, error)
+
+// NewParser returns the parser for the specified
+// log format, or error if the format is not supported
+func NewParser(format string) (Parser, error) {
+	switch format {
+	case NginxFormat:
+		return parseNginxLine, nil
+	case EnvoyFormat:
+		return parseEnvoyLine, nil
+	case JSONFormat:
+		return parseJSONLine, nil
+	}
+
+	return nil, fmt.Errorf("invalid access log format %s, valid formats are %v", format, ValidFormats)
+}
+
+func parseNginxLine(line string) (Entry, error) {
+	matches := nginxLinePattern.FindStringSubmatch(line)
+
+	if matches == nil {
+		return Entry{}, fmt.Errorf("line %q is not in nginx combined log format", line)
+	}
+
+	status, err := strconv.Atoi(matches[1])
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	entry := Entry{
+		Status: status,
+	}
+
+	if matches[2] != "" {
+		requestTimeSeconds, err := strconv.ParseFloat(matches[2], 64)
+
+		if err != nil {
+			return Entry{}, err
+		}
+
+		entry.Latency = time.Duration(requestTimeSeconds * float64(time.Second))
+		entry.HasLatency = true
+	}
+
+	return entry, nil
+}
+
+func parseEnvoyLine(line string) (Entry, error) {
+	matches := envoyLinePattern.FindStringSubmatch(line)
+
+	if matches == nil {
+		return Entry{}, fmt.Errorf("line %q is not in envoy default log format", line)
+	}
+
+	status, err := strconv.Atoi(matches[1])
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	durationMilliseconds, err := strconv.ParseInt(matches[2], 10, 64)
+
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return Entry{
+		Status:     status,
+		Latency:    time.Duration(durationMilliseconds) * time.Millisecond,
+		HasLatency: true,
+	}, nil
+}
+
+// jsonLine is the subset of fields read from json access logs
+// status and request_time are often logged as strings by nginx
+type jsonLine struct {
+	Status      json.Number `json:"status"`
+	RequestTime json.Number `json:"request_time"`
+}
+
+func parseJSONLine(line string) (Entry, error) {
+	var fields jsonLine
+
+	err := json.Unmarshal([]byte(line), &fields)
+
+	if err != nil {
+		return Entry{}, fmt.Errorf("%w: line %q is not a json object", err, line)
+	}
+
+	status, err := strconv.Atoi(fields.Status.String())
+
+	if err != nil {
+		return Entry{}, fmt.Errorf("line %q has no valid status", line)
+	}
+
+	entry := Entry{
+		Status: status,
+	}
+
+	if fields.RequestTime != "" {
+		requestTimeSeconds, err := fields.RequestTime.Float64()
+
+		if err != nil {
+			return Entry{}, fmt.Errorf("%w: line %q has invalid request_time", err, line)
+		}
+
+		entry.Latency = time.Duration(requestTimeSeconds * float64(time.Second))
+		entry.HasLatency = true
+	}
+
+	return entry, nil
+}
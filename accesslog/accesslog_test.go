@@ -0,0 +1,105 @@
+package accesslog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLines(t *testing.T) {
+	testCases := []struct {
+		format string
+		line   string
+		entry  Entry
+	}{
+		{
+			format: NginxFormat,
+			line:   `10.0.0.1 - - [16/Oct/2026:10:00:00 +0000] "POST / HTTP/1.1" 502 157 "-" "curl/7.68.0" 0.250`,
+			entry:  Entry{Status: 502, Latency: 250 * time.Millisecond, HasLatency: true},
+		},
+		{
+			format: NginxFormat,
+			line:   `10.0.0.1 - - [16/Oct/2026:10:00:00 +0000] "GET /status HTTP/1.1" 200 1024 "-" "curl/7.68.0"`,
+			entry:  Entry{Status: 200},
+		},
+		{
+			format: EnvoyFormat,
+			line:   `[2026-10-16T10:00:00.000Z] "GET /status HTTP/1.1" 429 - 0 58 12 11 "10.0.0.1" "curl/7.68.0" "id" "rpc" "127.0.0.1:26657"`,
+			entry:  Entry{Status: 429, Latency: 12 * time.Millisecond, HasLatency: true},
+		},
+		{
+			format: JSONFormat,
+			line:   `{"status":"500","request_time":"1.5"}`,
+			entry:  Entry{Status: 500, Latency: 1500 * time.Millisecond, HasLatency: true},
+		},
+	}
+
+	for _, testCase := range testCases {
+		parse, err := NewParser(testCase.format)
+		assert.Nil(t, err)
+
+		entry, err := parse(testCase.line)
+
+		assert.Nil(t, err, testCase.line)
+		assert.Equal(t, testCase.entry, entry, testCase.line)
+	}
+
+	_, err := NewParser("apache")
+
+	assert.Error(t, err)
+}
+
+func TestTailerFollowsTruncationAndRotation(t *testing.T) {
+	logFilepath := filepath.Join(t.TempDir(), "access.log")
+
+	assert.Nil(t, os.WriteFile(logFilepath, []byte("history\n"), 0644))
+
+	tailer := NewTailer(logFilepath)
+	defer tailer.Close()
+
+	// existing lines are skipped
+	lines, err := tailer.ReadLines()
+	assert.Nil(t, err)
+	assert.Empty(t, lines)
+
+	appendToFile(t, logFilepath, "one\ntw")
+
+	lines, err = tailer.ReadLines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"one"}, lines)
+
+	appendToFile(t, logFilepath, "o\n")
+
+	lines, err = tailer.ReadLines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"two"}, lines)
+
+	// truncate in place
+	assert.Nil(t, os.WriteFile(logFilepath, []byte("three\n"), 0644))
+
+	lines, err = tailer.ReadLines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"three"}, lines)
+
+	// rotate
+	appendToFile(t, logFilepath, "four\n")
+	assert.Nil(t, os.Rename(logFilepath, logFilepath+".1"))
+	assert.Nil(t, os.WriteFile(logFilepath, []byte("five\n"), 0644))
+
+	lines, err = tailer.ReadLines()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"four", "five"}, lines)
+}
+
+func appendToFile(t *testing.T, filepath string, data string) {
+	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+
+	defer file.Close()
+
+	_, err = file.WriteString(data)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,146 @@
+package accesslog
+
+import (
+	"io"
+	"os"
+	"strings"
+)
+
+// Tailer reads lines appended to a log file, following the
+// file across truncation and rotation (e.g. by logrotate)
+type Tailer struct {
+	filepath string
+	file     *os.File
+	offset   int64
+	// trailing line not yet terminated by a newline
+	partialLine string
+}
+
+// NewTailer returns a new tailer for the log file at filepath,
+// only lines written after the first read are returned
+func NewTailer(filepath string) *Tailer {
+	return &Tailer{
+		filepath: filepath,
+	}
+}
+
+// ReadLines returns all complete lines written to the log
+// since the previous read, returning error (if any)
+func (t *Tailer) ReadLines() ([]string, error) {
+	// only tail new traffic instead of the log's entire history
+	if t.file == nil {
+		err := t.open(io.SeekEnd)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fileInfo, err := os.Stat(t.filepath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	openFileInfo, err := t.file.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+
+	switch {
+	case !os.SameFile(fileInfo, openFileInfo):
+		// rotated, finish reading the old file before
+		// reading the new file from the beginning
+		data, err = t.readToEnd()
+
+		if err != nil {
+			return nil, err
+		}
+
+		t.file.Close()
+
+		err = t.open(io.SeekStart)
+
+		if err != nil {
+			return nil, err
+		}
+	case fileInfo.Size() < t.offset:
+		// truncated in place, read from the beginning
+		t.offset, err = t.file.Seek(0, io.SeekStart)
+
+		if err != nil {
+			return nil, err
+		}
+
+		t.partialLine = ""
+	}
+
+	newData, err := t.readToEnd()
+
+	if err != nil {
+		return nil, err
+	}
+
+	data = append(data, newData...)
+
+	lines := strings.Split(t.partialLine+string(data), "\n")
+
+	// the last element is either empty or a line still being written
+	t.partialLine = lines[len(lines)-1]
+
+	var completeLines []string
+
+	for _, line := range lines[:len(lines)-1] {
+		line = strings.TrimSuffix(line, "\r")
+
+		if line != "" {
+			completeLines = append(completeLines, line)
+		}
+	}
+
+	return completeLines, nil
+}
+
+// Close closes the log file being tailed (if open)
+func (t *Tailer) Close() error {
+	if t.file == nil {
+		return nil
+	}
+
+	return t.file.Close()
+}
+
+// open opens the log file, positioned at whence
+func (t *Tailer) open(whence int) error {
+	file, err := os.Open(t.filepath)
+
+	if err != nil {
+		return err
+	}
+
+	offset, err := file.Seek(0, whence)
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	t.file = file
+	t.offset = offset
+
+	return nil
+}
+
+// readToEnd reads from the current offset to
+// the end of the open file, returning error (if any)
+func (t *Tailer) readToEnd() ([]byte, error) {
+	data, err := io.ReadAll(t.file)
+
+	t.offset += int64(len(data))
+
+	return data, err
+}
@@ -0,0 +1,65 @@
+// package annotation provides types and functions for recording
+// annotations (e.g. a deploy of a new node version) to a file
+// that running doctors watch so they can be shown alongside the
+// health events of a node when reconstructing an incident
+package annotation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Annotation is a note about a change made to a node
+// (or all nodes if NodeId is empty) at a point in time
+type Annotation struct {
+	NodeId     string    `json:"node_id,omitempty"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Append appends the annotation as a single line of json
+// to the file at filepath, creating the file if needed
+// and returning error (if any)
+func Append(filepath string, annotation Annotation) error {
+	encodedAnnotation, err := json.Marshal(annotation)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = file.Write(append(encodedAnnotation, '\n'))
+
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+// Parse parses an annotation from a line
+// of an annotation file, returning error (if any)
+func Parse(line string) (Annotation, error) {
+	var annotation Annotation
+
+	err := json.Unmarshal([]byte(line), &annotation)
+
+	if err != nil {
+		return Annotation{}, fmt.Errorf("invalid annotation %s: %w", line, err)
+	}
+
+	if annotation.Message == "" || annotation.OccurredAt.IsZero() {
+		return Annotation{}, fmt.Errorf("invalid annotation %s: message and occurred_at are required", line)
+	}
+
+	return annotation, nil
+}
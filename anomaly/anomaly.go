@@ -0,0 +1,210 @@
+// package anomaly detects samples of a node's signals (e.g. status
+// check latency) that deviate strongly from the node's own recent
+// baseline, so that nodes with very different normal behavior (e.g.
+// archival and pruned nodes, or mainnet and testnet nodes) can be
+// alerted on without tuning a static threshold for each of them
+package anomaly
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// signals observed for each node
+	// milliseconds taken for the node to respond to a status check
+	StatusLatencySignal = "status_latency"
+	// blocks per minute produced by the chain as seen by the node
+	BlockRateSignal = "block_rate"
+
+	// scales the median absolute deviation to be comparable to a
+	// standard deviation for normally distributed samples
+	madScale = 0.6745
+	// scales the mean absolute deviation to be comparable to a
+	// standard deviation, used when over half the baseline samples
+	// are identical (i.e. the median absolute deviation is 0)
+	meanADScale = 1.253314
+
+	DefaultZScoreThreshold = 5
+	DefaultBaselineSamples = 120
+	// samples required in a baseline before any sample is scored
+	// against it, as the spread of fewer samples is unreliable
+	MinBaselineSamples = 30
+)
+
+// Observation is the result of scoring a sample against
+// the baseline of the node's previous samples of the signal
+type Observation struct {
+	// modified z-score of the sample, how many (robust) standard
+	// deviations it is from the median of the baseline, 0 if the
+	// baseline doesn't have enough samples to score against
+	ZScore float64
+	// median of the baseline the sample was scored against
+	Median float64
+	// whether the absolute z-score exceeds the threshold
+	Anomalous bool
+	// whether the previous sample of the signal wasn't anomalous,
+	// i.e. an anomaly was detected rather than is ongoing
+	Started bool
+	// whether the previous sample of the signal was anomalous
+	// and this sample isn't, i.e. the anomaly has ended
+	Ended bool
+}
+
+// baselineKey identifies the baseline of a signal for a node
+type baselineKey struct {
+	nodeId string
+	signal string
+}
+
+// baseline is the most recent samples of a
+// signal for a node, oldest sample first
+type baseline struct {
+	samples   []float64
+	anomalous bool
+}
+
+// Detector scores samples of the signals of each node using the
+// median and median absolute deviation of a rolling window of their
+// previous samples, which unlike a mean and standard deviation are
+// barely moved by the outliers being detected
+// Samples are added to the baseline whether or not they're anomalous,
+// so a lasting change in a node's behavior becomes its new baseline
+// Detector is not safe for concurrent use
+type Detector struct {
+	zScoreThreshold float64
+	baselineSamples int
+	baselines       map[baselineKey]*baseline
+}
+
+// NewDetector returns a new detector that considers samples with an
+// absolute modified z-score above zScoreThreshold against the previous
+// baselineSamples samples (or defaults if not positive) anomalous
+func NewDetector(zScoreThreshold float64, baselineSamples int) *Detector {
+	if zScoreThreshold <= 0 {
+		zScoreThreshold = DefaultZScoreThreshold
+	}
+
+	if baselineSamples <= 0 {
+		baselineSamples = DefaultBaselineSamples
+	}
+
+	return &Detector{
+		zScoreThreshold: zScoreThreshold,
+		baselineSamples: baselineSamples,
+		baselines:       make(map[baselineKey]*baseline),
+	}
+}
+
+// Observe scores the value of a sample of the signal for the node
+// against the baseline of its previous samples, then adds it to the
+// baseline, returning the resulting observation
+func (d *Detector) Observe(nodeId string, signal string, value float64) Observation {
+	key := baselineKey{nodeId: nodeId, signal: signal}
+	history, exists := d.baselines[key]
+
+	if !exists {
+		history = &baseline{}
+		d.baselines[key] = history
+	}
+
+	var observation Observation
+
+	if len(history.samples) >= MinBaselineSamples || len(history.samples) >= d.baselineSamples {
+		observation.ZScore, observation.Median = zScore(history.samples, value)
+		observation.Anomalous = math.Abs(observation.ZScore) > d.zScoreThreshold
+	}
+
+	observation.Started = observation.Anomalous && !history.anomalous
+	observation.Ended = !observation.Anomalous && history.anomalous
+	history.anomalous = observation.Anomalous
+
+	history.samples = append(history.samples, value)
+
+	if len(history.samples) > d.baselineSamples {
+		history.samples = history.samples[len(history.samples)-d.baselineSamples:]
+	}
+
+	return observation
+}
+
+// Baselines returns a copy of the samples of the baseline of
+// each signal for each node, keyed by node id and signal
+func (d *Detector) Baselines() map[string]map[string][]float64 {
+	baselines := make(map[string]map[string][]float64)
+
+	for key, history := range d.baselines {
+		if baselines[key.nodeId] == nil {
+			baselines[key.nodeId] = make(map[string][]float64)
+		}
+
+		samples := make([]float64, len(history.samples))
+		copy(samples, history.samples)
+
+		baselines[key.nodeId][key.signal] = samples
+	}
+
+	return baselines
+}
+
+// Restore replaces the baselines of the signals of each node with the
+// provided samples (e.g. baselines saved before the doctor restarted),
+// keeping the most recent samples if there are more than the detector
+// retains, so samples are scored without waiting for a new baseline
+func (d *Detector) Restore(baselines map[string]map[string][]float64) {
+	for nodeId, signals := range baselines {
+		for signal, samples := range signals {
+			if len(samples) > d.baselineSamples {
+				samples = samples[len(samples)-d.baselineSamples:]
+			}
+
+			restored := make([]float64, len(samples))
+			copy(restored, samples)
+
+			d.baselines[baselineKey{nodeId: nodeId, signal: signal}] = &baseline{samples: restored}
+		}
+	}
+}
+
+// zScore returns the modified z-score of value against
+// samples along with the median of samples
+func zScore(samples []float64, value float64) (float64, float64) {
+	median := medianOf(samples)
+
+	deviations := make([]float64, len(samples))
+	var deviationSum float64
+
+	for i, sample := range samples {
+		deviations[i] = math.Abs(sample - median)
+		deviationSum += deviations[i]
+	}
+
+	if mad := medianOf(deviations); mad > 0 {
+		return madScale * (value - median) / mad, median
+	}
+
+	if meanAD := deviationSum / float64(len(deviations)); meanAD > 0 {
+		return (value - median) / (meanAD * meanADScale), median
+	}
+
+	// every sample is identical, leaving no spread to
+	// judge how unusual a different value is
+	return 0, median
+}
+
+// medianOf returns the median of the values, without
+// changing the order of the values
+func medianOf(values []float64) float64 {
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+
+	sort.Float64s(sorted)
+
+	middle := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+
+	return sorted[middle]
+}
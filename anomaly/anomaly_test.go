@@ -0,0 +1,100 @@
+package anomaly
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	TestNodeId = "06ff9460163caac703c44da1b2e3108e1ba087cd"
+)
+
+// observeBaseline observes samples alternating around
+// the value so the baseline has some spread
+func observeBaseline(detector *Detector, nodeId string, signal string, value float64, samples int) {
+	for i := 0; i < samples; i++ {
+		offset := float64(i%5) - 2
+
+		detector.Observe(nodeId, signal, value+offset)
+	}
+}
+
+func TestDetectorDetectsDeviationFromBaseline(t *testing.T) {
+	detector := NewDetector(5, 60)
+
+	observeBaseline(detector, TestNodeId, StatusLatencySignal, 100, 60)
+
+	observation := detector.Observe(TestNodeId, StatusLatencySignal, 101)
+
+	assert.False(t, observation.Anomalous, "sample within the spread of the baseline")
+	assert.Equal(t, float64(100), observation.Median)
+
+	observation = detector.Observe(TestNodeId, StatusLatencySignal, 400)
+
+	assert.True(t, observation.Anomalous)
+	assert.True(t, observation.Started)
+	assert.Greater(t, observation.ZScore, float64(5))
+
+	observation = detector.Observe(TestNodeId, StatusLatencySignal, 420)
+
+	assert.True(t, observation.Anomalous)
+	assert.False(t, observation.Started, "anomaly is already ongoing")
+
+	observation = detector.Observe(TestNodeId, StatusLatencySignal, 99)
+
+	assert.False(t, observation.Anomalous)
+	assert.True(t, observation.Ended)
+
+	observation = detector.Observe(TestNodeId, StatusLatencySignal, -300)
+
+	assert.True(t, observation.Started, "deviations below the baseline are anomalous too")
+	assert.Less(t, observation.ZScore, float64(-5))
+}
+
+func TestDetectorBaselinesAreRelativeToEachNode(t *testing.T) {
+	detector := NewDetector(5, 60)
+
+	// an archival node responds far slower than a pruned one
+	observeBaseline(detector, "archival", StatusLatencySignal, 900, 60)
+	observeBaseline(detector, "pruned", StatusLatencySignal, 50, 60)
+
+	assert.False(t, detector.Observe("archival", StatusLatencySignal, 905).Anomalous)
+	assert.True(t, detector.Observe("pruned", StatusLatencySignal, 905).Anomalous)
+	assert.False(t, detector.Observe("pruned", BlockRateSignal, 905).Anomalous, "signals have separate baselines")
+}
+
+func TestDetectorWaitsForBaselineAndAdaptsToLastingChange(t *testing.T) {
+	detector := NewDetector(5, 40)
+
+	observeBaseline(detector, TestNodeId, BlockRateSignal, 10, MinBaselineSamples-1)
+
+	observation := detector.Observe(TestNodeId, BlockRateSignal, 1000)
+
+	assert.False(t, observation.Anomalous, "too few samples to score against")
+	assert.Equal(t, float64(0), observation.ZScore)
+
+	// the chain's block time halves for good
+	observeBaseline(detector, TestNodeId, BlockRateSignal, 20, 40)
+
+	assert.False(t, detector.Observe(TestNodeId, BlockRateSignal, 20).Anomalous)
+	assert.True(t, detector.Observe(TestNodeId, BlockRateSignal, 10).Anomalous)
+}
+
+func TestDetectorIgnoresBaselineWithoutSpread(t *testing.T) {
+	detector := NewDetector(5, 40)
+
+	for i := 0; i < 40; i++ {
+		detector.Observe(TestNodeId, BlockRateSignal, 10)
+	}
+
+	assert.False(t, detector.Observe(TestNodeId, BlockRateSignal, 11).Anomalous)
+
+	// a few outliers give the baseline a spread
+	// even though the median deviation is 0
+	detector.Observe(TestNodeId, BlockRateSignal, 12)
+
+	observation := detector.Observe(TestNodeId, BlockRateSignal, 30)
+
+	assert.True(t, observation.Anomalous)
+}
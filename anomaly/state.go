@@ -0,0 +1,85 @@
+package anomaly
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// how often baselines are saved while the doctor is running,
+	// bounding the samples lost if the doctor crashes
+	StateSaveInterval = 1 * time.Minute
+)
+
+// State is the baselines of a detector saved to disk so
+// they survive restarts of the doctor
+type State struct {
+	SavedAt time.Time `json:"saved_at"`
+	// samples of the baseline of each signal, keyed by node id
+	// and signal, ordered from oldest to newest
+	Baselines map[string]map[string][]float64 `json:"baselines"`
+}
+
+// LoadState returns the state saved to the file at path, an
+// empty state if no state has been saved yet, and error (if any)
+func LoadState(path string) (State, error) {
+	contents, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, nil
+	}
+
+	if err != nil {
+		return State{}, err
+	}
+
+	var state State
+
+	if err := json.Unmarshal(contents, &state); err != nil {
+		return State{}, fmt.Errorf("error %s parsing anomaly baselines file %s", err, path)
+	}
+
+	return state, nil
+}
+
+// SaveState saves the state to the file at path, replacing any
+// previously saved state only once the new state is fully written
+// so a crash while saving never leaves a truncated file, returning
+// error (if any)
+func SaveState(path string, state State) error {
+	contents, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(contents); err != nil {
+		tempFile.Close()
+
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
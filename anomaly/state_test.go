@@ -0,0 +1,55 @@
+package anomaly
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedBaselinesAreRestoredWithoutWarmUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doctor", "anomaly-baselines.json")
+
+	state, err := LoadState(path)
+
+	assert.Nil(t, err, "no baselines saved yet")
+	assert.Empty(t, state.Baselines)
+
+	detector := NewDetector(5, 60)
+
+	observeBaseline(detector, TestNodeId, StatusLatencySignal, 100, 60)
+
+	savedAt := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, SaveState(path, State{SavedAt: savedAt, Baselines: detector.Baselines()}))
+
+	state, err = LoadState(path)
+
+	assert.Nil(t, err)
+	assert.Equal(t, savedAt, state.SavedAt)
+	assert.Len(t, state.Baselines[TestNodeId][StatusLatencySignal], 60)
+
+	// a restarted doctor retaining fewer samples keeps the most recent
+	restarted := NewDetector(5, 40)
+	restarted.Restore(state.Baselines)
+
+	assert.Equal(t, state.Baselines[TestNodeId][StatusLatencySignal][20:], restarted.Baselines()[TestNodeId][StatusLatencySignal])
+	assert.True(t, restarted.Observe(TestNodeId, StatusLatencySignal, 400).Anomalous)
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1, "temporary files are cleaned up")
+}
+
+func TestLoadStateRejectsCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "anomaly-baselines.json")
+
+	assert.Nil(t, os.WriteFile(path, []byte(`{"baselines":`), 0600))
+
+	_, err := LoadState(path)
+
+	assert.NotNil(t, err)
+}
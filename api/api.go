@@ -0,0 +1,299 @@
+// package api provides an http server exposing the doctor's computed
+// view of the health of each node (e.g. its latest samples, hash rate,
+// uptime and latency percentiles) so other tooling can query it
+// instead of recomputing it from the node, optionally served over tls
+// and requiring a bearer token
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	NodesPath = "/api/v1/nodes"
+	// suffix of the path of the metrics of a node
+	// e.g. /api/v1/nodes/<url escaped node id>/metrics
+	NodeMetricsPathSuffix = "/metrics"
+	// optional query parameter for including the
+	// samples taken in the last number of seconds
+	WindowSecondsParam = "window_seconds"
+	// how long to wait for in progress requests
+	// to finish when shutting down the server
+	ShutdownTimeout = 5 * time.Second
+)
+
+// ServerConfig wraps values for configuring an api server
+type ServerConfig struct {
+	// address to listen for requests on e.g. localhost:8080
+	Address string
+	// optional pem encoded certificate and key to serve
+	// the api over tls with, served over plain http if empty
+	TLSCertFilepath string
+	TLSKeyFilepath  string
+	// optional token clients must send as a
+	// bearer token in the Authorization header
+	AuthToken string
+	// samples and synthetic metrics to serve
+	Endpoint *endpoint.Endpoint
+}
+
+// Server serves the samples and synthetic metrics
+// for each node that samples have been taken for
+type Server struct {
+	address string
+	// nil if the api is served over plain http
+	tlsConfig *tls.Config
+	authToken string
+	endpoint  *endpoint.Endpoint
+}
+
+// PercentileValues wraps the values below which
+// 50, 95 and 99 percent of values fall
+type PercentileValues struct {
+	P50 float64 `json:"p50"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+}
+
+// NodeMetricsResponse wraps the samples and synthetic
+// metrics for a node, with synthetic metrics omitted
+// when there aren't enough samples to calculate them
+type NodeMetricsResponse struct {
+	NodeId                         string                 `json:"node_id"`
+	Latest                         endpoint.NodeMetrics   `json:"latest"`
+	HashRatePerSecond              *float32               `json:"hash_rate_per_second,omitempty"`
+	UptimePercent                  *float32               `json:"uptime_percent,omitempty"`
+	StatusCheckLatencyMilliseconds *PercentileValues      `json:"status_check_latency_milliseconds,omitempty"`
+	SecondsBehindLive              *PercentileValues      `json:"seconds_behind_live,omitempty"`
+	Samples                        []endpoint.NodeMetrics `json:"samples,omitempty"`
+}
+
+// NodesResponse wraps the ids of the nodes
+// (or endpoint urls) that samples are retained for
+type NodesResponse struct {
+	NodeIds []string `json:"node_ids"`
+}
+
+// ErrorResponse wraps the reason a request failed
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer returns a new api server using the provided config,
+// returning error (if any) if the certificate to serve the api
+// with is configured but can't be loaded
+func NewServer(config ServerConfig) (*Server, error) {
+	var tlsConfig *tls.Config
+
+	if config.TLSCertFilepath != "" || config.TLSKeyFilepath != "" {
+		certificate, err := tls.LoadX509KeyPair(config.TLSCertFilepath, config.TLSKeyFilepath)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s loading status api certificate %s", err, config.TLSCertFilepath)
+		}
+
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	return &Server{
+		address:   config.Address,
+		tlsConfig: tlsConfig,
+		authToken: config.AuthToken,
+		endpoint:  config.Endpoint,
+	}, nil
+}
+
+// Serve serves requests (until the context is cancelled) returning
+// error (if any) listening for requests, once the context is cancelled
+// in progress requests are given a chance to finish before returning
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.address)
+
+	if err != nil {
+		return err
+	}
+
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	server := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// ServeHTTP routes the request to the handler for its path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "missing or invalid bearer token"})
+
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "only GET requests are supported"})
+
+		return
+	}
+
+	// node ids may be endpoint urls, which are url
+	// escaped to fit in a single segment of the path
+	path := r.URL.EscapedPath()
+
+	if path == NodesPath || path == NodesPath+"/" {
+		writeJSON(w, http.StatusOK, NodesResponse{NodeIds: s.endpoint.NodeIds()})
+
+		return
+	}
+
+	escapedNodeId := strings.TrimPrefix(path, NodesPath+"/")
+
+	if escapedNodeId == path || !strings.HasSuffix(escapedNodeId, NodeMetricsPathSuffix) {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "not found, expected " + NodesPath + "/{id}" + NodeMetricsPathSuffix})
+
+		return
+	}
+
+	escapedNodeId = strings.TrimSuffix(escapedNodeId, NodeMetricsPathSuffix)
+
+	nodeId, err := url.PathUnescape(escapedNodeId)
+
+	if err != nil || nodeId == "" || strings.Contains(escapedNodeId, "/") {
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid node id " + escapedNodeId + ", node ids containing a / must be url escaped"})
+
+		return
+	}
+
+	s.serveNodeMetrics(w, r, nodeId)
+}
+
+// authorized returns whether the request has the
+// expected bearer token, or true if none is configured
+func (s *Server) authorized(r *http.Request) bool {
+	if s.authToken == "" {
+		return true
+	}
+
+	expected := "Bearer " + s.authToken
+
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
+
+// serveNodeMetrics responds with the samples
+// and synthetic metrics for the node
+func (s *Server) serveNodeMetrics(w http.ResponseWriter, r *http.Request, nodeId string) {
+	latest, err := s.endpoint.GetLatest(nodeId)
+
+	if errors.Is(err, endpoint.ErrNodeMetricsNotFound) {
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "no metrics found for node " + nodeId})
+
+		return
+	}
+
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, ErrorResponse{Error: fmt.Sprintf("%s getting metrics for node %s", err, nodeId)})
+
+		return
+	}
+
+	response := NodeMetricsResponse{
+		NodeId: nodeId,
+		Latest: latest,
+	}
+
+	if hashRate, err := s.endpoint.CalculateHashRate(nodeId); err == nil {
+		response.HashRatePerSecond = &hashRate
+	}
+
+	if uptime, err := s.endpoint.CalculateUptime(nodeId); err == nil {
+		uptimePercent := uptime * 100
+		response.UptimePercent = &uptimePercent
+	}
+
+	response.StatusCheckLatencyMilliseconds = s.percentiles(nodeId, func(sample *metric.SyncStatusMetrics) float64 {
+		return float64(sample.SampleLatencyMilliseconds)
+	})
+
+	response.SecondsBehindLive = s.percentiles(nodeId, func(sample *metric.SyncStatusMetrics) float64 {
+		return float64(sample.SecondsBehindLive)
+	})
+
+	if rawWindowSeconds := r.URL.Query().Get(WindowSecondsParam); rawWindowSeconds != "" {
+		windowSeconds, err := strconv.Atoi(rawWindowSeconds)
+
+		if err != nil || windowSeconds <= 0 {
+			writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: "invalid " + WindowSecondsParam + " " + rawWindowSeconds + ", must be a positive number of seconds"})
+
+			return
+		}
+
+		response.Samples, _ = s.endpoint.GetWindow(nodeId, time.Now().Add(-time.Duration(windowSeconds)*time.Second))
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// percentiles returns the percentiles of the values selected
+// by the provided function for the node's sync status samples,
+// or nil if there aren't enough samples to calculate them
+func (s *Server) percentiles(nodeId string, value func(*metric.SyncStatusMetrics) float64) *PercentileValues {
+	var values PercentileValues
+
+	for percentile, calculated := range map[float64]*float64{
+		50: &values.P50,
+		95: &values.P95,
+		99: &values.P99,
+	} {
+		var err error
+
+		*calculated, err = s.endpoint.CalculatePercentile(nodeId, percentile, value)
+
+		if err != nil {
+			return nil
+		}
+	}
+
+	return &values
+}
+
+// writeJSON writes the json encoded body with the status code
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(body)
+}
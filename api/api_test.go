@@ -0,0 +1,200 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	TestNodeId      = "06ff9460163caac703c44da1b2e3108e1ba087cd"
+	TestEndpointURL = "https://rpc.data.kava.io"
+)
+
+func TestServeHTTPListsNodeIds(t *testing.T) {
+	server := createServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, NodesPath, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response NodesResponse
+
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	assert.Equal(t, []string{TestNodeId, TestEndpointURL}, response.NodeIds)
+}
+
+func TestServeHTTPServesNodeMetrics(t *testing.T) {
+	server := createServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, NodesPath+"/"+TestNodeId+NodeMetricsPathSuffix, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response NodeMetricsResponse
+
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	assert.Equal(t, TestNodeId, response.NodeId)
+
+	assert.NotNil(t, response.Latest.SyncStatusMetrics)
+	assert.Equal(t, int64(102), response.Latest.SyncStatusMetrics.SyncStatus.LatestBlockHeight)
+
+	assert.NotNil(t, response.HashRatePerSecond)
+	// one block per minute
+	assert.InDelta(t, 1.0/60, *response.HashRatePerSecond, 0.0001)
+
+	assert.NotNil(t, response.StatusCheckLatencyMilliseconds)
+	assert.Equal(t, float64(20), response.StatusCheckLatencyMilliseconds.P50)
+
+	assert.Nil(t, response.UptimePercent, "node has no uptime samples")
+	assert.Nil(t, response.Samples, "samples are only included when a window is requested")
+}
+
+func TestServeHTTPServesNodeMetricsForEscapedEndpointURL(t *testing.T) {
+	server := createServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, NodesPath+"/"+url.PathEscape(TestEndpointURL)+NodeMetricsPathSuffix, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response NodeMetricsResponse
+
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	assert.Equal(t, TestEndpointURL, response.NodeId)
+
+	assert.NotNil(t, response.UptimePercent)
+	assert.Equal(t, float32(50), *response.UptimePercent)
+}
+
+func TestServeHTTPIncludesSamplesInWindow(t *testing.T) {
+	server := createServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, NodesPath+"/"+TestNodeId+NodeMetricsPathSuffix+"?"+WindowSecondsParam+"=90", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var response NodeMetricsResponse
+
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	assert.Equal(t, 2, len(response.Samples), "only samples from the last 90 seconds should be included")
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, NodesPath+"/"+TestNodeId+NodeMetricsPathSuffix+"?"+WindowSecondsParam+"=soon", nil))
+
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestServeHTTPReturnsNotFoundForUnknownNode(t *testing.T) {
+	server := createServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, NodesPath+"/unknown"+NodeMetricsPathSuffix, nil))
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	var response ErrorResponse
+
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+
+	assert.Contains(t, response.Error, "unknown")
+}
+
+func TestServeHTTPRejectsNonGetRequests(t *testing.T) {
+	server := createServer(t)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, NodesPath, nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestServeHTTPRequiresAuthToken(t *testing.T) {
+	server := createServer(t)
+	server.authToken = "secret"
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, NodesPath, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	request := httptest.NewRequest(http.MethodGet, NodesPath, nil)
+	request.Header.Set("Authorization", "Bearer secret")
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestNewServerReturnsErrorForMissingCertificate(t *testing.T) {
+	_, err := NewServer(ServerConfig{
+		TLSCertFilepath: filepath.Join(t.TempDir(), "cert.pem"),
+		TLSKeyFilepath:  filepath.Join(t.TempDir(), "key.pem"),
+	})
+
+	assert.NotNil(t, err)
+}
+
+// createServer returns a server for an endpoint with three sync status
+// samples a minute apart for TestNodeId and two uptime samples for
+// TestEndpointURL
+func createServer(t *testing.T) *Server {
+	t.Helper()
+
+	kavaEndpoint := endpoint.New(endpoint.EndpointConfig{URL: TestEndpointURL})
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		sampledAt := now.Add(time.Duration(i-2) * time.Minute)
+
+		err := kavaEndpoint.AddSample(TestNodeId, endpoint.NodeMetrics{
+			SyncStatusMetrics: &metric.SyncStatusMetrics{
+				NodeId:    TestNodeId,
+				SampledAt: sampledAt,
+				SyncStatus: kava.SyncInfo{
+					LatestBlockHeight: int64(100 + i),
+					LatestBlockTime:   sampledAt,
+				},
+				SampleLatencyMilliseconds: int64(10 * (i + 1)),
+			},
+		})
+
+		assert.Nil(t, err)
+	}
+
+	for _, up := range []bool{true, false} {
+		err := kavaEndpoint.AddSample(TestEndpointURL, endpoint.NodeMetrics{
+			UptimeMetric: &metric.UptimeMetric{
+				EndpointURL: TestEndpointURL,
+				SampledAt:   now,
+				Up:          up,
+			},
+		})
+
+		assert.Nil(t, err)
+	}
+
+	server, err := NewServer(ServerConfig{Endpoint: kavaEndpoint})
+
+	assert.Nil(t, err)
+
+	return server
+}
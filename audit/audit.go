@@ -0,0 +1,309 @@
+// package audit provides types and functions for signing and verifying
+// collected metrics so that operators can prove records of a node's
+// availability (e.g. for SLA disputes) haven't been altered after collection
+package audit
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	HMACSHA256Algorithm = "hmac-sha256"
+	Ed25519Algorithm    = "ed25519"
+)
+
+var (
+	ValidSigningAlgorithms = []string{HMACSHA256Algorithm, Ed25519Algorithm}
+	ErrInvalidSignature    = errors.New("invalid signature")
+)
+
+// Signer signs and verifies arbitrary data
+type Signer interface {
+	// Algorithm returns the name of the signing algorithm
+	Algorithm() string
+	// Sign returns the signature for data and error (if any)
+	Sign(data []byte) ([]byte, error)
+	// Verify returns ErrInvalidSignature if signature
+	// isn't a valid signature of data
+	Verify(data []byte, signature []byte) error
+}
+
+// HMACSigner implements the Signer interface using
+// HMAC-SHA256 with a shared secret key
+type HMACSigner struct {
+	key []byte
+}
+
+// NewHMACSigner returns a new HMACSigner using the provided secret key
+func NewHMACSigner(key []byte) *HMACSigner {
+	return &HMACSigner{key: key}
+}
+
+// Algorithm returns the name of the signing algorithm
+func (s *HMACSigner) Algorithm() string {
+	return HMACSHA256Algorithm
+}
+
+// Sign returns the HMAC-SHA256 of data
+func (s *HMACSigner) Sign(data []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+
+	return mac.Sum(nil), nil
+}
+
+// Verify returns ErrInvalidSignature if signature
+// isn't the HMAC-SHA256 of data
+func (s *HMACSigner) Verify(data []byte, signature []byte) error {
+	expected, _ := s.Sign(data)
+
+	if !hmac.Equal(expected, signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Ed25519Signer implements the Signer interface using an
+// ed25519 key pair, allowing third parties to verify signatures
+// using only the public key
+type Ed25519Signer struct {
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// NewEd25519Signer returns a new Ed25519Signer using the provided private key
+func NewEd25519Signer(privateKey ed25519.PrivateKey) *Ed25519Signer {
+	return &Ed25519Signer{
+		privateKey: privateKey,
+		publicKey:  privateKey.Public().(ed25519.PublicKey),
+	}
+}
+
+// NewEd25519Verifier returns a new Ed25519Signer that is
+// only able to verify signatures using the provided public key
+func NewEd25519Verifier(publicKey ed25519.PublicKey) *Ed25519Signer {
+	return &Ed25519Signer{
+		publicKey: publicKey,
+	}
+}
+
+// Algorithm returns the name of the signing algorithm
+func (s *Ed25519Signer) Algorithm() string {
+	return Ed25519Algorithm
+}
+
+// Sign returns the ed25519 signature of data and error (if any)
+func (s *Ed25519Signer) Sign(data []byte) ([]byte, error) {
+	if s.privateKey == nil {
+		return nil, fmt.Errorf("signer was created for verification only")
+	}
+
+	return ed25519.Sign(s.privateKey, data), nil
+}
+
+// Verify returns ErrInvalidSignature if signature
+// isn't a valid ed25519 signature of data
+func (s *Ed25519Signer) Verify(data []byte, signature []byte) error {
+	if !ed25519.Verify(s.publicKey, data, signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// NewSignerFromKeyFile returns a signer for the specified algorithm
+// using the key stored at keyFilepath, where HMAC keys are the raw contents
+// of the file and ed25519 keys are PEM encoded PKCS #8 private keys
+// (e.g. as generated by `openssl genpkey -algorithm ed25519`)
+func NewSignerFromKeyFile(algorithm string, keyFilepath string) (Signer, error) {
+	key, err := os.ReadFile(keyFilepath)
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s reading signing key file %s", err, keyFilepath)
+	}
+
+	switch algorithm {
+	case HMACSHA256Algorithm:
+		key = bytes.TrimSpace(key)
+
+		if len(key) == 0 {
+			return nil, fmt.Errorf("signing key file %s is empty", keyFilepath)
+		}
+
+		return NewHMACSigner(key), nil
+	case Ed25519Algorithm:
+		parsedKey, err := parsePEMKey(key, x509.ParsePKCS8PrivateKey)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s parsing signing key file %s", err, keyFilepath)
+		}
+
+		privateKey, ok := parsedKey.(ed25519.PrivateKey)
+
+		if !ok {
+			return nil, fmt.Errorf("signing key file %s does not contain an ed25519 private key", keyFilepath)
+		}
+
+		return NewEd25519Signer(privateKey), nil
+	}
+
+	return nil, fmt.Errorf("invalid signing algorithm %s, valid algorithms are %v", algorithm, ValidSigningAlgorithms)
+}
+
+// NewVerifierFromKeyFile returns a signer capable of verifying signatures
+// for the specified algorithm using the key stored at keyFilepath, where
+// HMAC keys are the raw contents of the file and ed25519 keys are PEM encoded
+// PKIX public keys (e.g. as generated by `openssl pkey -pubout`)
+func NewVerifierFromKeyFile(algorithm string, keyFilepath string) (Signer, error) {
+	if algorithm != Ed25519Algorithm {
+		return NewSignerFromKeyFile(algorithm, keyFilepath)
+	}
+
+	key, err := os.ReadFile(keyFilepath)
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s reading verification key file %s", err, keyFilepath)
+	}
+
+	parsedKey, err := parsePEMKey(key, x509.ParsePKIXPublicKey)
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s parsing verification key file %s", err, keyFilepath)
+	}
+
+	publicKey, ok := parsedKey.(ed25519.PublicKey)
+
+	if !ok {
+		return nil, fmt.Errorf("verification key file %s does not contain an ed25519 public key", keyFilepath)
+	}
+
+	return NewEd25519Verifier(publicKey), nil
+}
+
+func parsePEMKey(encoded []byte, parse func([]byte) (interface{}, error)) (interface{}, error) {
+	block, _ := pem.Decode(encoded)
+
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	return parse(block.Bytes)
+}
+
+// SignedRecord wraps an arbitrary json encoded record
+// with a signature over the record and the signature
+// of the previous record, chaining records together so
+// that any modification, removal or reordering of
+// records can be detected
+type SignedRecord struct {
+	Sequence          uint64          `json:"sequence"`
+	Record            json.RawMessage `json:"record"`
+	Algorithm         string          `json:"algorithm"`
+	PreviousSignature string          `json:"previous_signature"`
+	Signature         string          `json:"signature"`
+}
+
+// signedContent returns the bytes covered by the signature of a record
+func signedContent(sequence uint64, record []byte, previousSignature string) []byte {
+	return []byte(fmt.Sprintf("%d\n%s\n%s", sequence, previousSignature, record))
+}
+
+// Chain signs a sequence of records, chaining
+// each record's signature to the previous record's
+// Chain is not safe for concurrent use
+type Chain struct {
+	signer            Signer
+	sequence          uint64
+	previousSignature string
+}
+
+// NewChain returns a new chain of records signed by signer
+func NewChain(signer Signer) *Chain {
+	return &Chain{signer: signer}
+}
+
+// Sign returns the signed record for the
+// next record in the chain and error (if any)
+func (c *Chain) Sign(record []byte) (SignedRecord, error) {
+	signature, err := c.signer.Sign(signedContent(c.sequence, record, c.previousSignature))
+
+	if err != nil {
+		return SignedRecord{}, err
+	}
+
+	signedRecord := SignedRecord{
+		Sequence:          c.sequence,
+		Record:            record,
+		Algorithm:         c.signer.Algorithm(),
+		PreviousSignature: c.previousSignature,
+		Signature:         hex.EncodeToString(signature),
+	}
+
+	c.sequence++
+	c.previousSignature = signedRecord.Signature
+
+	return signedRecord, nil
+}
+
+// VerifyChain reads a stream of json encoded signed records
+// verifying each record's signature and that the records form
+// an unbroken chain, returning the number of verified records
+// and error (if any) describing the first invalid record found
+func VerifyChain(stream io.Reader, verifier Signer) (int, error) {
+	decoder := json.NewDecoder(stream)
+
+	var verified int
+	var expectedSequence uint64
+	var previousSignature string
+
+	for {
+		var signedRecord SignedRecord
+
+		err := decoder.Decode(&signedRecord)
+
+		if err == io.EOF {
+			return verified, nil
+		}
+
+		if err != nil {
+			return verified, fmt.Errorf("error %s decoding record %d", err, verified)
+		}
+
+		if !strings.EqualFold(signedRecord.Algorithm, verifier.Algorithm()) {
+			return verified, fmt.Errorf("record %d was signed using %s, expected %s", signedRecord.Sequence, signedRecord.Algorithm, verifier.Algorithm())
+		}
+
+		if signedRecord.Sequence != expectedSequence || signedRecord.PreviousSignature != previousSignature {
+			return verified, fmt.Errorf("chain broken at record %d, expected sequence %d, records have been removed or reordered", signedRecord.Sequence, expectedSequence)
+		}
+
+		signature, err := hex.DecodeString(signedRecord.Signature)
+
+		if err != nil {
+			return verified, fmt.Errorf("error %s decoding signature for record %d", err, signedRecord.Sequence)
+		}
+
+		err = verifier.Verify(signedContent(signedRecord.Sequence, signedRecord.Record, signedRecord.PreviousSignature), signature)
+
+		if err != nil {
+			return verified, fmt.Errorf("%w for record %d, record has been altered", err, signedRecord.Sequence)
+		}
+
+		verified++
+		expectedSequence++
+		previousSignature = signedRecord.Signature
+	}
+}
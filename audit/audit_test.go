@@ -0,0 +1,81 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signRecords(t *testing.T, signer Signer, records ...string) []SignedRecord {
+	chain := NewChain(signer)
+
+	var signedRecords []SignedRecord
+
+	for _, record := range records {
+		signedRecord, err := chain.Sign([]byte(record))
+
+		assert.Nil(t, err)
+
+		signedRecords = append(signedRecords, signedRecord)
+	}
+
+	return signedRecords
+}
+
+func encodeRecords(t *testing.T, signedRecords []SignedRecord) *bytes.Buffer {
+	var stream bytes.Buffer
+
+	for _, signedRecord := range signedRecords {
+		encoded, err := json.Marshal(signedRecord)
+
+		assert.Nil(t, err)
+
+		stream.Write(encoded)
+	}
+
+	return &stream
+}
+
+func TestVerifyChainAcceptsUnalteredRecords(t *testing.T) {
+	signer := NewHMACSigner([]byte("secret"))
+
+	signedRecords := signRecords(t, signer, `{"up":true}`, `{"up":false}`, `{"up":true}`)
+
+	verified, err := VerifyChain(encodeRecords(t, signedRecords), signer)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, verified)
+}
+
+func TestVerifyChainDetectsAlteredRecord(t *testing.T) {
+	signer := NewHMACSigner([]byte("secret"))
+
+	signedRecords := signRecords(t, signer, `{"up":true}`, `{"up":false}`)
+	signedRecords[1].Record = []byte(`{"up":true}`)
+
+	verified, err := VerifyChain(encodeRecords(t, signedRecords), signer)
+
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+	assert.Equal(t, 1, verified)
+}
+
+func TestVerifyChainDetectsRemovedRecord(t *testing.T) {
+	signer := NewHMACSigner([]byte("secret"))
+
+	signedRecords := signRecords(t, signer, `{"up":true}`, `{"up":false}`, `{"up":true}`)
+	signedRecords = append(signedRecords[:1], signedRecords[2:]...)
+
+	_, err := VerifyChain(encodeRecords(t, signedRecords), signer)
+
+	assert.NotNil(t, err)
+}
+
+func TestVerifyChainRejectsWrongKey(t *testing.T) {
+	signedRecords := signRecords(t, NewHMACSigner([]byte("secret")), `{"up":true}`)
+
+	_, err := VerifyChain(encodeRecords(t, signedRecords), NewHMACSigner([]byte("guess")))
+
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
@@ -0,0 +1,89 @@
+// package backpressure counts the messages dropped instead of sent on
+// the doctor's internal channels (e.g. metrics, log messages and events)
+// when their readers fall behind, so that a stalled display never blocks
+// the routines watching and autohealing the node
+package backpressure
+
+import (
+	"sort"
+	"sync"
+)
+
+// Counter counts the messages dropped per channel, either counted by
+// the sender as each message is dropped or by a component (e.g. the
+// logger) that counts the messages it drops itself
+// Counter is safe to use across go-routines
+type Counter struct {
+	countsLock *sync.Mutex
+	counts     map[string]uint64
+	// functions returning the number of messages dropped
+	// by components tracking their own drops, per channel
+	tracked map[string]func() uint64
+}
+
+// NewCounter returns a new counter with no dropped messages
+func NewCounter() *Counter {
+	return &Counter{
+		countsLock: &sync.Mutex{},
+		counts:     make(map[string]uint64),
+		tracked:    make(map[string]func() uint64),
+	}
+}
+
+// Drop counts a message dropped on the specified channel,
+// doing nothing if the counter is nil so senders don't
+// have to check whether drops are being counted
+func (c *Counter) Drop(channel string) {
+	if c == nil {
+		return
+	}
+
+	c.countsLock.Lock()
+	defer c.countsLock.Unlock()
+
+	c.counts[channel]++
+}
+
+// Track includes the messages dropped on the specified channel as
+// returned by dropped (e.g. a logger's Dropped method) in the counts
+func (c *Counter) Track(channel string, dropped func() uint64) {
+	c.countsLock.Lock()
+	defer c.countsLock.Unlock()
+
+	c.tracked[channel] = dropped
+}
+
+// Counts returns the total number of messages
+// dropped on each channel any messages were dropped on
+func (c *Counter) Counts() map[string]uint64 {
+	c.countsLock.Lock()
+	defer c.countsLock.Unlock()
+
+	counts := make(map[string]uint64, len(c.counts)+len(c.tracked))
+
+	for channel, count := range c.counts {
+		counts[channel] = count
+	}
+
+	for channel, dropped := range c.tracked {
+		if count := dropped(); count > 0 {
+			counts[channel] += count
+		}
+	}
+
+	return counts
+}
+
+// Channels returns the sorted names of the
+// channels in the (e.g. Counts returned) counts
+func Channels(counts map[string]uint64) []string {
+	channels := make([]string, 0, len(counts))
+
+	for channel := range counts {
+		channels = append(channels, channel)
+	}
+
+	sort.Strings(channels)
+
+	return channels
+}
@@ -0,0 +1,46 @@
+package backpressure
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterCountsDroppedAndTrackedMessages(t *testing.T) {
+	counter := NewCounter()
+
+	assert.Empty(t, counter.Counts(), "no messages dropped")
+
+	counter.Drop("sync_status_metrics")
+	counter.Drop("sync_status_metrics")
+	counter.Drop("uptime_metrics")
+
+	var loggerDropped uint64
+
+	counter.Track("log_messages", func() uint64 { return loggerDropped })
+
+	assert.Equal(t, map[string]uint64{
+		"sync_status_metrics": 2,
+		"uptime_metrics":      1,
+	}, counter.Counts(), "tracked channels are only included once they drop messages")
+
+	loggerDropped = 5
+
+	counts := counter.Counts()
+
+	assert.Equal(t, map[string]uint64{
+		"log_messages":        5,
+		"sync_status_metrics": 2,
+		"uptime_metrics":      1,
+	}, counts)
+
+	assert.Equal(t, []string{"log_messages", "sync_status_metrics", "uptime_metrics"}, Channels(counts))
+}
+
+func TestNilCounterIgnoresDrops(t *testing.T) {
+	var counter *Counter
+
+	assert.NotPanics(t, func() {
+		counter.Drop("sync_status_metrics")
+	})
+}
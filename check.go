@@ -0,0 +1,169 @@
+// check.go contains types and functions for performing a single
+// health check of a node (as opposed to continuously watching it)
+// for use by cron jobs, ci smoke tests and nagios style checks
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	dconfig "github.com/kava-labs/doctor/config"
+)
+
+const (
+	CheckStatusHealthy     = "healthy"
+	CheckStatusBehind      = "behind"
+	CheckStatusUnreachable = "unreachable"
+)
+
+// checkExitCodes maps the status of a check
+// to the exit code of the doctor program
+var checkExitCodes = map[string]int{
+	CheckStatusHealthy:     0,
+	CheckStatusBehind:      1,
+	CheckStatusUnreachable: 2,
+}
+
+// CheckResult wraps the outcome of a single health check of a node
+type CheckResult struct {
+	Status      string    `json:"status"`
+	EndpointURL string    `json:"endpoint_url"`
+	CheckedAt   time.Time `json:"checked_at"`
+	// whether the node responded to the status request
+	Up                             bool   `json:"up"`
+	StatusCheckLatencyMilliseconds int64  `json:"status_check_latency_milliseconds"`
+	NodeId                         string `json:"node_id,omitempty"`
+	Moniker                        string `json:"moniker,omitempty"`
+	LatestBlockHeight              int64  `json:"latest_block_height,omitempty"`
+	CatchingUp                     bool   `json:"catching_up"`
+	SecondsBehindLive              int64  `json:"seconds_behind_live"`
+	// how many blocks the node is behind the reference
+	// endpoint's chain head, nil if no reference is configured
+	// or the reference couldn't be queried
+	BlocksBehindReference *int64 `json:"blocks_behind_reference,omitempty"`
+	// why the node isn't healthy, if it isn't
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExitCode returns the exit code of the doctor program for the result
+func (r CheckResult) ExitCode() int {
+	return checkExitCodes[r.Status]
+}
+
+// evaluateCheck determines the status of the node from its
+// response (or error) to a status request, treating the node as
+// behind if it's catching up, more than syncLatencyToleranceSeconds
+// behind live or (if set) more than blocksBehindReferenceTolerance
+// blocks behind the reference endpoint
+func evaluateCheck(result CheckResult, nodeState kava.NodeState, err error, syncLatencyToleranceSeconds int, blocksBehindReferenceTolerance int) CheckResult {
+	if err != nil {
+		result.Status = CheckStatusUnreachable
+		result.Reason = fmt.Sprintf("error %s getting node status", err)
+
+		return result
+	}
+
+	result.Up = true
+	result.NodeId = nodeState.NodeInfo.Id
+	result.Moniker = nodeState.NodeInfo.Moniker
+	result.LatestBlockHeight = nodeState.SyncInfo.LatestBlockHeight
+	result.CatchingUp = nodeState.SyncInfo.CatchingUp
+	result.SecondsBehindLive = int64(result.CheckedAt.Sub(nodeState.SyncInfo.LatestBlockTime).Seconds())
+	result.Status = CheckStatusHealthy
+
+	switch {
+	case result.CatchingUp:
+		result.Status = CheckStatusBehind
+		result.Reason = "node is catching up"
+	case result.SecondsBehindLive > int64(syncLatencyToleranceSeconds):
+		result.Status = CheckStatusBehind
+		result.Reason = fmt.Sprintf("node is %d seconds behind live, more than the tolerance of %d seconds", result.SecondsBehindLive, syncLatencyToleranceSeconds)
+	case result.BlocksBehindReference != nil && blocksBehindReferenceTolerance > 0 && *result.BlocksBehindReference > int64(blocksBehindReferenceTolerance):
+		result.Status = CheckStatusBehind
+		result.Reason = fmt.Sprintf("node is %d blocks behind the reference endpoint, more than the tolerance of %d blocks", *result.BlocksBehindReference, blocksBehindReferenceTolerance)
+	}
+
+	return result
+}
+
+// runCheck performs a single health check of the node, printing
+// a json summary of the result to stdout and returning 0 if the
+// node is healthy, 1 if it's behind and 2 if it's unreachable
+func runCheck(config *dconfig.DoctorConfig) int {
+	kavaClient, err := kava.New(kava.ClientConfig{
+		JSONRPCURL:             config.KavaNodeRPCURL,
+		HTTPReadTimeoutSeconds: config.HealthChecksTimeoutSeconds,
+		Auth:                   config.KavaAPIAuth,
+		Transport:              config.Transport,
+		Status:                 config.Status,
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: could not initialize kava client\n", err)
+
+		return checkExitCodes[CheckStatusUnreachable]
+	}
+
+	result := CheckResult{
+		EndpointURL: config.KavaNodeRPCURL,
+		CheckedAt:   config.TimeFormat.In(time.Now()),
+	}
+
+	// poll the reference endpoint (if any) in parallel with the
+	// node so both block heights are sampled at the same time
+	referenceHeights := make(chan int64, 1)
+
+	if config.ReferenceAPIAddress != "" {
+		go func() {
+			defer close(referenceHeights)
+
+			referenceClient, err := kava.New(kava.ClientConfig{
+				JSONRPCURL:             config.ReferenceAPIAddress,
+				HTTPReadTimeoutSeconds: config.HealthChecksTimeoutSeconds,
+				Auth:                   config.ReferenceAPIAuth,
+				Transport:              config.Transport,
+				Status:                 config.Status,
+			})
+
+			if err != nil {
+				return
+			}
+
+			referenceState, err := referenceClient.GetNodeState()
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error %s getting reference endpoint %s status\n", err, config.ReferenceAPIAddress)
+
+				return
+			}
+
+			referenceHeights <- referenceState.SyncInfo.LatestBlockHeight
+		}()
+	} else {
+		close(referenceHeights)
+	}
+
+	nodeState, err := kavaClient.GetNodeState()
+	result.StatusCheckLatencyMilliseconds = time.Since(result.CheckedAt).Milliseconds()
+
+	if referenceHeight, polled := <-referenceHeights; polled && err == nil {
+		blocksBehindReference := referenceHeight - nodeState.SyncInfo.LatestBlockHeight
+		result.BlocksBehindReference = &blocksBehindReference
+	}
+
+	result = evaluateCheck(result, nodeState, err, config.AutohealSyncLatencyToleranceSeconds, config.AutohealBlocksBehindReferenceTolerance)
+
+	summary, err := json.Marshal(result)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: could not encode check result %+v\n", err, result)
+	} else {
+		fmt.Println(string(summary))
+	}
+
+	return result.ExitCode()
+}
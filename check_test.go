@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateCheckStatusAndExitCode(t *testing.T) {
+	checkedAt := time.Now()
+
+	nodeState := func(secondsBehindLive int, catchingUp bool) kava.NodeState {
+		return kava.NodeState{
+			NodeInfo: kava.NodeInfo{Id: "node"},
+			SyncInfo: kava.SyncInfo{
+				LatestBlockHeight: 100,
+				LatestBlockTime:   checkedAt.Add(-time.Duration(secondsBehindLive) * time.Second),
+				CatchingUp:        catchingUp,
+			},
+		}
+	}
+
+	blocksBehindReference := int64(50)
+
+	testCases := []struct {
+		name                  string
+		nodeState             kava.NodeState
+		err                   error
+		blocksBehindReference *int64
+		expectedStatus        string
+		expectedExitCode      int
+	}{
+		{
+			name:             "synched node is healthy",
+			nodeState:        nodeState(5, false),
+			expectedStatus:   CheckStatusHealthy,
+			expectedExitCode: 0,
+		},
+		{
+			name:             "node behind live is behind",
+			nodeState:        nodeState(300, false),
+			expectedStatus:   CheckStatusBehind,
+			expectedExitCode: 1,
+		},
+		{
+			name:             "catching up node is behind",
+			nodeState:        nodeState(5, true),
+			expectedStatus:   CheckStatusBehind,
+			expectedExitCode: 1,
+		},
+		{
+			name:                  "node behind reference is behind",
+			nodeState:             nodeState(5, false),
+			blocksBehindReference: &blocksBehindReference,
+			expectedStatus:        CheckStatusBehind,
+			expectedExitCode:      1,
+		},
+		{
+			name:             "node that errors is unreachable",
+			err:              errors.New("connection refused"),
+			expectedStatus:   CheckStatusUnreachable,
+			expectedExitCode: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := evaluateCheck(CheckResult{CheckedAt: checkedAt, BlocksBehindReference: tc.blocksBehindReference}, tc.nodeState, tc.err, 120, 10)
+
+			assert.Equal(t, tc.expectedStatus, result.Status)
+			assert.Equal(t, tc.expectedExitCode, result.ExitCode())
+			assert.Equal(t, tc.err == nil, result.Up)
+		})
+	}
+}
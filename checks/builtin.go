@@ -0,0 +1,602 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	// built in health checks
+	SyncStatusCheckName  = "sync-status"
+	UptimeProbeCheckName = "uptime-probe"
+	PeersCheckName       = "peers"
+	DiskCheckName        = "disk"
+	EVMCheckName         = "evm"
+	QueryCheckName       = "query"
+	SyntheticTxCheckName = "synthetic-tx"
+
+	// tendermint endpoint that responds
+	// successfully if the node is running
+	HealthEndpointPath = "/health"
+
+	// how often to check if a broadcast
+	// transaction has been included in a block
+	TxInclusionPollInterval = 500 * time.Millisecond
+)
+
+// syncStatusCheck implements the HealthCheck interface, checking the
+// node isn't catching up or further than the tolerance behind live
+type syncStatusCheck struct {
+	config Config
+}
+
+func newSyncStatusCheck(config Config) (HealthCheck, error) {
+	if config.KavaClient == nil {
+		return nil, fmt.Errorf("a kava client is required for the %s health check", SyncStatusCheckName)
+	}
+
+	return &syncStatusCheck{config: config}, nil
+}
+
+func (sc *syncStatusCheck) Name() string {
+	return SyncStatusCheckName
+}
+
+func (sc *syncStatusCheck) Interval() time.Duration {
+	return sc.config.Interval
+}
+
+func (sc *syncStatusCheck) Policy() Policy {
+	return sc.config.Policy
+}
+
+func (sc *syncStatusCheck) Run(ctx context.Context) CheckResult {
+	var nodeState kava.NodeState
+
+	err := callWithContext(ctx, func() (err error) {
+		nodeState, err = sc.config.KavaClient.GetNodeState()
+
+		return err
+	})
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s getting node status", err)}
+	}
+
+	secondsBehindLive := int64(time.Since(nodeState.SyncInfo.LatestBlockTime).Seconds())
+
+	result := CheckResult{
+		Healthy: true,
+		Message: fmt.Sprintf("node is synched up to block %d, %d seconds behind live", nodeState.SyncInfo.LatestBlockHeight, secondsBehindLive),
+		Value:   float64(secondsBehindLive),
+		Unit:    metric.UnitSeconds,
+	}
+
+	switch {
+	case nodeState.SyncInfo.CatchingUp:
+		result.Healthy = false
+		result.Message = fmt.Sprintf("node is catching up, %d seconds behind live", secondsBehindLive)
+	case secondsBehindLive > int64(sc.config.SyncLatencyToleranceSeconds):
+		result.Healthy = false
+		result.Message = fmt.Sprintf("node is %d seconds behind live, more than the tolerance of %d seconds", secondsBehindLive, sc.config.SyncLatencyToleranceSeconds)
+	}
+
+	return result
+}
+
+// uptimeProbeCheck implements the HealthCheck interface,
+// checking the node's health endpoint responds successfully
+type uptimeProbeCheck struct {
+	config Config
+	url    string
+	client *http.Client
+}
+
+func newUptimeProbeCheck(config Config) (HealthCheck, error) {
+	if config.RPCURL == "" {
+		return nil, fmt.Errorf("an rpc url is required for the %s health check", UptimeProbeCheckName)
+	}
+
+	rpcURL, socketPath, err := kava.ParseRPCAddress(config.RPCURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid rpc url for the %s health check", err, UptimeProbeCheckName)
+	}
+
+	transportConfig := config.Transport
+	transportConfig.SocketPath = socketPath
+
+	transport, err := kava.NewTransport(transportConfig)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not configure transport for the %s health check", err, UptimeProbeCheckName)
+	}
+
+	return &uptimeProbeCheck{
+		config: config,
+		url:    rpcURL + HealthEndpointPath,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (uc *uptimeProbeCheck) Name() string {
+	return UptimeProbeCheckName
+}
+
+func (uc *uptimeProbeCheck) Interval() time.Duration {
+	return uc.config.Interval
+}
+
+func (uc *uptimeProbeCheck) Policy() Policy {
+	return uc.config.Policy
+}
+
+func (uc *uptimeProbeCheck) Run(ctx context.Context) CheckResult {
+	startedAt := time.Now()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, uc.url, nil)
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s creating request to %s", err, uc.url)}
+	}
+
+	response, err := uc.client.Do(request)
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s probing %s", err, uc.url)}
+	}
+
+	response.Body.Close()
+
+	latency := time.Since(startedAt)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return CheckResult{
+			Message: fmt.Sprintf("%s responded with status %d", uc.url, response.StatusCode),
+			Value:   float64(latency.Milliseconds()),
+			Unit:    metric.UnitMilliseconds,
+		}
+	}
+
+	return CheckResult{
+		Healthy: true,
+		Message: fmt.Sprintf("%s responded in %d milliseconds", uc.url, latency.Milliseconds()),
+		Value:   float64(latency.Milliseconds()),
+		Unit:    metric.UnitMilliseconds,
+	}
+}
+
+// peersCheck implements the HealthCheck interface, checking
+// the node is connected to at least the minimum number of peers
+type peersCheck struct {
+	config Config
+}
+
+func newPeersCheck(config Config) (HealthCheck, error) {
+	if config.KavaClient == nil {
+		return nil, fmt.Errorf("a kava client is required for the %s health check", PeersCheckName)
+	}
+
+	return &peersCheck{config: config}, nil
+}
+
+func (pc *peersCheck) Name() string {
+	return PeersCheckName
+}
+
+func (pc *peersCheck) Interval() time.Duration {
+	return pc.config.Interval
+}
+
+func (pc *peersCheck) Policy() Policy {
+	return pc.config.Policy
+}
+
+func (pc *peersCheck) Run(ctx context.Context) CheckResult {
+	var netInfo kava.NetInfo
+
+	err := callWithContext(ctx, func() (err error) {
+		netInfo, err = pc.config.KavaClient.GetNetInfo()
+
+		return err
+	})
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s getting node net info", err)}
+	}
+
+	result := CheckResult{
+		Healthy: netInfo.NumberOfPeers >= int64(pc.config.MinPeers),
+		Message: fmt.Sprintf("node is connected to %d peers", netInfo.NumberOfPeers),
+		Value:   float64(netInfo.NumberOfPeers),
+		Unit:    metric.UnitCount,
+	}
+
+	if !result.Healthy {
+		result.Message = fmt.Sprintf("node is connected to %d peers, fewer than the minimum of %d", netInfo.NumberOfPeers, pc.config.MinPeers)
+	}
+
+	return result
+}
+
+// diskCheck implements the HealthCheck interface, checking the
+// filesystem of the node's data has at least the minimum free space
+type diskCheck struct {
+	config Config
+}
+
+func newDiskCheck(config Config) (HealthCheck, error) {
+	if config.DiskPath == "" {
+		return nil, fmt.Errorf("a disk path is required for the %s health check", DiskCheckName)
+	}
+
+	return &diskCheck{config: config}, nil
+}
+
+func (dc *diskCheck) Name() string {
+	return DiskCheckName
+}
+
+func (dc *diskCheck) Interval() time.Duration {
+	return dc.config.Interval
+}
+
+func (dc *diskCheck) Policy() Policy {
+	return dc.config.Policy
+}
+
+func (dc *diskCheck) Run(ctx context.Context) CheckResult {
+	var stats syscall.Statfs_t
+
+	// statfs can hang on unresponsive network filesystems
+	err := callWithContext(ctx, func() error {
+		return syscall.Statfs(dc.config.DiskPath, &stats)
+	})
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s getting filesystem stats for %s", err, dc.config.DiskPath)}
+	}
+
+	freePercent := freeDiskPercent(stats.Bavail, stats.Blocks)
+
+	result := CheckResult{
+		Healthy: freePercent >= dc.config.DiskMinFreePercent,
+		Message: fmt.Sprintf("%.1f%% of the filesystem of %s is free", freePercent, dc.config.DiskPath),
+		Value:   freePercent,
+		Unit:    metric.UnitPercent,
+	}
+
+	if !result.Healthy {
+		result.Message = fmt.Sprintf("%.1f%% of the filesystem of %s is free, less than the minimum of %.1f%%", freePercent, dc.config.DiskPath, dc.config.DiskMinFreePercent)
+	}
+
+	return result
+}
+
+// freeDiskPercent returns the percent of blocks of a
+// filesystem that are available to unprivileged users
+func freeDiskPercent(availableBlocks uint64, totalBlocks uint64) float64 {
+	if totalBlocks == 0 {
+		return 0
+	}
+
+	return float64(availableBlocks) / float64(totalBlocks) * 100
+}
+
+// evmCheck implements the HealthCheck interface, checking
+// the node's evm json rpc api returns the latest block number
+type evmCheck struct {
+	config Config
+	client *http.Client
+}
+
+func newEVMCheck(config Config) (HealthCheck, error) {
+	if config.EVMRPCURL == "" {
+		return nil, fmt.Errorf("an evm rpc url is required for the %s health check", EVMCheckName)
+	}
+
+	transport, err := kava.NewTransport(config.Transport)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not configure transport for the %s health check", err, EVMCheckName)
+	}
+
+	return &evmCheck{
+		config: config,
+		client: &http.Client{Transport: transport},
+	}, nil
+}
+
+func (ec *evmCheck) Name() string {
+	return EVMCheckName
+}
+
+func (ec *evmCheck) Interval() time.Duration {
+	return ec.config.Interval
+}
+
+func (ec *evmCheck) Policy() Policy {
+	return ec.config.Policy
+}
+
+// evmBlockNumberResponse wraps the response
+// to an eth_blockNumber json rpc request
+type evmBlockNumberResponse struct {
+	Result string `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (ec *evmCheck) Run(ctx context.Context) CheckResult {
+	body := []byte(`{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, ec.config.EVMRPCURL, bytes.NewReader(body))
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s creating request to %s", err, ec.config.EVMRPCURL)}
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := ec.client.Do(request)
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s getting evm block number from %s", err, ec.config.EVMRPCURL)}
+	}
+
+	defer response.Body.Close()
+
+	var blockNumber evmBlockNumberResponse
+
+	err = json.NewDecoder(response.Body).Decode(&blockNumber)
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s decoding evm block number response with status %d", err, response.StatusCode)}
+	}
+
+	if blockNumber.Error != nil {
+		return CheckResult{Message: fmt.Sprintf("evm rpc api responded with error %d %s", blockNumber.Error.Code, blockNumber.Error.Message)}
+	}
+
+	height, err := strconv.ParseUint(strings.TrimPrefix(blockNumber.Result, "0x"), 16, 64)
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s parsing evm block number %s", err, blockNumber.Result)}
+	}
+
+	return CheckResult{
+		Healthy: true,
+		Message: fmt.Sprintf("evm rpc api is at block %d", height),
+		Value:   float64(height),
+		Unit:    metric.UnitNone,
+	}
+}
+
+// queryCheck implements the HealthCheck interface, checking the
+// node serves queries (a recent block and optionally an account)
+// as nodes can serve their status while their query layer is broken
+type queryCheck struct {
+	config Config
+}
+
+func newQueryCheck(config Config) (HealthCheck, error) {
+	if config.KavaClient == nil {
+		return nil, fmt.Errorf("a kava client is required for the %s health check", QueryCheckName)
+	}
+
+	if config.QueryAccountAddress != "" && config.RESTClient == nil {
+		return nil, fmt.Errorf("a rest api url is required to query account %s in the %s health check", config.QueryAccountAddress, QueryCheckName)
+	}
+
+	return &queryCheck{config: config}, nil
+}
+
+func (qc *queryCheck) Name() string {
+	return QueryCheckName
+}
+
+func (qc *queryCheck) Interval() time.Duration {
+	return qc.config.Interval
+}
+
+func (qc *queryCheck) Policy() Policy {
+	return qc.config.Policy
+}
+
+func (qc *queryCheck) Run(ctx context.Context) CheckResult {
+	var nodeState kava.NodeState
+
+	err := callWithContext(ctx, func() (err error) {
+		nodeState, err = qc.config.KavaClient.GetNodeState()
+
+		return err
+	})
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s getting node status", err)}
+	}
+
+	// query the block before the latest as the
+	// latest block may not be fully committed yet
+	height := nodeState.SyncInfo.LatestBlockHeight - 1
+
+	if height < 1 {
+		height = 1
+	}
+
+	startedAt := time.Now()
+
+	var block kava.BlockHashes
+
+	err = callWithContext(ctx, func() (err error) {
+		block, err = qc.config.KavaClient.GetBlockHashes(height)
+
+		return err
+	})
+
+	if err == nil && (block.Height != height || block.BlockHash == "") {
+		err = fmt.Errorf("response was missing block %d", height)
+	}
+
+	if err != nil {
+		return CheckResult{
+			Message: fmt.Sprintf("node status succeeded but querying block %d failed with error %s", height, err),
+			Value:   float64(time.Since(startedAt).Milliseconds()),
+			Unit:    metric.UnitMilliseconds,
+		}
+	}
+
+	queried := fmt.Sprintf("block %d", height)
+
+	if qc.config.QueryAccountAddress != "" {
+		err = callWithContext(ctx, func() error {
+			_, err := qc.config.RESTClient.GetAccount(qc.config.QueryAccountAddress)
+
+			return err
+		})
+
+		if err != nil {
+			return CheckResult{
+				Message: fmt.Sprintf("node status succeeded but querying account %s failed with error %s", qc.config.QueryAccountAddress, err),
+				Value:   float64(time.Since(startedAt).Milliseconds()),
+				Unit:    metric.UnitMilliseconds,
+			}
+		}
+
+		queried = fmt.Sprintf("%s and account %s", queried, qc.config.QueryAccountAddress)
+	}
+
+	latency := time.Since(startedAt)
+
+	return CheckResult{
+		Healthy: true,
+		Message: fmt.Sprintf("queried %s in %d milliseconds", queried, latency.Milliseconds()),
+		Value:   float64(latency.Milliseconds()),
+		Unit:    metric.UnitMilliseconds,
+	}
+}
+
+// syntheticTxCheck implements the HealthCheck interface, checking the
+// node accepts and includes a transaction (e.g. a self transfer from a
+// funded key) in a block as nodes can serve queries while refusing
+// to accept or gossip transactions
+type syntheticTxCheck struct {
+	config Config
+}
+
+func newSyntheticTxCheck(config Config) (HealthCheck, error) {
+	if config.KavaClient == nil {
+		return nil, fmt.Errorf("a kava client is required for the %s health check", SyntheticTxCheckName)
+	}
+
+	if len(config.SyntheticTxCommand) == 0 {
+		return nil, fmt.Errorf("a command to sign the transaction is required for the %s health check", SyntheticTxCheckName)
+	}
+
+	return &syntheticTxCheck{config: config}, nil
+}
+
+func (sc *syntheticTxCheck) Name() string {
+	return SyntheticTxCheckName
+}
+
+func (sc *syntheticTxCheck) Interval() time.Duration {
+	return sc.config.Interval
+}
+
+func (sc *syntheticTxCheck) Policy() Policy {
+	return sc.config.Policy
+}
+
+func (sc *syntheticTxCheck) Run(ctx context.Context) CheckResult {
+	command := sc.config.SyntheticTxCommand
+
+	// the command is responsible for the sequence
+	// and fees of the transaction as well as signing it
+	output, err := exec.CommandContext(ctx, command[0], command[1:]...).Output()
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s signing transaction running %s", err, strings.Join(command, " "))}
+	}
+
+	tx, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(output)))
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s decoding signed transaction printed by %s, expected base64", err, strings.Join(command, " "))}
+	}
+
+	broadcastAt := time.Now()
+
+	var broadcast kava.BroadcastTxResult
+
+	err = callWithContext(ctx, func() (err error) {
+		broadcast, err = sc.config.KavaClient.BroadcastTxSync(tx)
+
+		return err
+	})
+
+	if err != nil {
+		return CheckResult{Message: fmt.Sprintf("error %s broadcasting transaction", err)}
+	}
+
+	ticker := time.NewTicker(TxInclusionPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return CheckResult{
+				Message: fmt.Sprintf("transaction %s wasn't included in a block within %.1f seconds of broadcast", broadcast.Hash, time.Since(broadcastAt).Seconds()),
+				Value:   time.Since(broadcastAt).Seconds(),
+				Unit:    metric.UnitSeconds,
+			}
+		case <-ticker.C:
+			var height int64
+
+			err = callWithContext(ctx, func() (err error) {
+				height, err = sc.config.KavaClient.GetTxHeight(broadcast.Hash)
+
+				return err
+			})
+
+			if errors.Is(err, kava.ErrTxNotFound) {
+				continue
+			}
+
+			// the context being done is reported
+			// the next time around the loop
+			if err != nil && ctx.Err() != nil {
+				continue
+			}
+
+			if err != nil {
+				return CheckResult{Message: fmt.Sprintf("error %s getting broadcast transaction %s", err, broadcast.Hash)}
+			}
+
+			inclusionLatency := time.Since(broadcastAt)
+
+			return CheckResult{
+				Healthy:    true,
+				Message:    fmt.Sprintf("transaction %s was included in block %d %.1f seconds after broadcast", broadcast.Hash, height, inclusionLatency.Seconds()),
+				Value:      inclusionLatency.Seconds(),
+				Unit:       metric.UnitSeconds,
+				MetricName: "TxInclusionLatencySeconds",
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// package checks provides health checks of a node (e.g. its sync
+// status, peers or free disk space) and a scheduler for running
+// each check at its own interval
+
+package checks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/metric"
+)
+
+// CheckResult is the outcome of a single run of a health check
+type CheckResult struct {
+	// name of the check that was run
+	Check   string
+	Healthy bool
+	// why the node is (or isn't) healthy
+	Message string
+	// optional value measured by the check
+	// (e.g. percent of free disk space) and its unit
+	Value float64
+	Unit  metric.Unit
+	// optional name of a metric to also collect the value
+	// as (e.g. TxInclusionLatencySeconds) when healthy
+	MetricName string
+	StartedAt  time.Time
+	// how long all attempts of the check took
+	Duration time.Duration
+	// how many times the check was attempted,
+	// more than 1 if failed attempts were retried
+	Attempts int
+}
+
+// HealthCheck is a single check of the health
+// of a node that is run at a regular interval
+type HealthCheck interface {
+	// Name returns the name the check is registered under
+	Name() string
+	// Interval returns how often to run the check
+	Interval() time.Duration
+	// Policy returns how long to wait for each attempt
+	// of the check and how to retry failed attempts
+	Policy() Policy
+	// Run runs the check, returning the result
+	Run(ctx context.Context) CheckResult
+}
+
+// Policy wraps how long to wait for an attempt of a
+// health check and how to retry attempts that fail
+type Policy struct {
+	// how long to wait for a single attempt of the check,
+	// 0 waits as long as the context of the run allows
+	Timeout time.Duration
+	// how many times to retry a failed attempt
+	// before reporting the check as failed
+	Retries int
+	// how long to wait before the first retry,
+	// doubling for each retry after that
+	RetryBackoff time.Duration
+}
+
+// Config wraps values used for creating a health check
+type Config struct {
+	// how often to run the check
+	Interval time.Duration
+	// url of and client for the rpc api of the node
+	RPCURL     string
+	KavaClient *kava.Client
+	// how far behind live the node can be and be healthy
+	SyncLatencyToleranceSeconds int
+	// minimum number of peers for the node to be healthy
+	MinPeers int
+	// path of the filesystem to check the free space of
+	DiskPath string
+	// minimum percent of free space for the node to be healthy
+	DiskMinFreePercent float64
+	// url of the node's evm json rpc api
+	EVMRPCURL string
+	// optional client for the cosmos rest api of the node
+	RESTClient *kava.Client
+	// optional address of an account to query
+	QueryAccountAddress string
+	// binary and arguments of a command that prints a signed
+	// (base64 encoded) transaction to broadcast to stdout
+	SyntheticTxCommand []string
+	// proxy and tls settings for the http requests of
+	// checks that don't use the kava or rest clients
+	Transport kava.TransportConfig
+	// timeout and retries for each run of the check
+	Policy Policy
+}
+
+// Factory creates a health check using the
+// provided config, returning error (if any)
+type Factory func(config Config) (HealthCheck, error)
+
+var (
+	registryLock = &sync.RWMutex{}
+	registry     = map[string]Factory{
+		SyncStatusCheckName:  newSyncStatusCheck,
+		UptimeProbeCheckName: newUptimeProbeCheck,
+		PeersCheckName:       newPeersCheck,
+		DiskCheckName:        newDiskCheck,
+		EVMCheckName:         newEVMCheck,
+		QueryCheckName:       newQueryCheck,
+		SyntheticTxCheckName: newSyntheticTxCheck,
+	}
+)
+
+// Register registers factory for creating the health check with
+// the specified name, replacing any check already registered
+// with that name
+func Register(name string, factory Factory) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	registry[name] = factory
+}
+
+// ValidChecks returns the names of all registered health checks
+func ValidChecks() []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var names []string
+
+	for name := range registry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// New creates the health check registered with the
+// specified name using the provided config, returning
+// error (if any)
+func New(name string, config Config) (HealthCheck, error) {
+	registryLock.RLock()
+	factory, exists := registry[name]
+	registryLock.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("invalid health check %s, valid checks are %v", name, ValidChecks())
+	}
+
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("invalid interval %v for health check %s, must be positive", config.Interval, name)
+	}
+
+	if config.Policy.Timeout < 0 || config.Policy.Retries < 0 || config.Policy.RetryBackoff < 0 {
+		return nil, fmt.Errorf("invalid policy %+v for health check %s, timeout, retries and retry backoff can't be negative", config.Policy, name)
+	}
+
+	return factory(config)
+}
+
+// callWithContext calls call in the background, returning the
+// context's error if it's done before call returns (e.g. the
+// attempt timed out), otherwise the error returned by call
+// Used for wrapping calls that don't accept a context
+func callWithContext(ctx context.Context, call func() error) error {
+	done := make(chan error, 1)
+
+	go func() {
+		done <- call()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
@@ -0,0 +1,155 @@
+package checks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/backpressure"
+	"github.com/kava-labs/doctor/event"
+)
+
+const (
+	// name of the channel results are sent on
+	// when counting dropped results
+	ResultsChannel = "health_check_results"
+)
+
+// Scheduler runs health checks, each at its own interval
+type Scheduler struct {
+	checks []HealthCheck
+	// optional bus to publish checks starting
+	// to fail and recovering to
+	Events *event.Bus
+	// optional counter of results dropped because
+	// they weren't read as fast as checks were run
+	Dropped *backpressure.Counter
+}
+
+// NewScheduler returns a new scheduler for running the provided checks
+func NewScheduler(checks ...HealthCheck) *Scheduler {
+	return &Scheduler{
+		checks: checks,
+	}
+}
+
+// Run runs each check immediately and then every interval
+// of the check, sending the result of each run to results
+// (without blocking, dropping results if results is full)
+// until the context is cancelled
+// A check that takes longer than its interval to run delays
+// its next run rather than being run concurrently with itself
+func (s *Scheduler) Run(ctx context.Context, results chan<- CheckResult) {
+	var checkRoutines sync.WaitGroup
+
+	for _, check := range s.checks {
+		checkRoutines.Add(1)
+
+		go func(check HealthCheck) {
+			defer checkRoutines.Done()
+
+			s.runEvery(ctx, check, results)
+		}(check)
+	}
+
+	checkRoutines.Wait()
+}
+
+// runEvery runs the check every interval, sending the result
+// of each run to results until the context is cancelled
+func (s *Scheduler) runEvery(ctx context.Context, check HealthCheck, results chan<- CheckResult) {
+	ticker := time.NewTicker(check.Interval())
+	defer ticker.Stop()
+
+	// checks are assumed to pass until they first run
+	healthy := true
+
+	for {
+		result := run(ctx, check)
+
+		if result.Healthy != healthy {
+			s.publishTransition(result)
+
+			healthy = result.Healthy
+		}
+
+		select {
+		case results <- result:
+		default:
+			s.Dropped.Drop(ResultsChannel)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishTransition publishes the check starting to fail
+// or recovering as of the result to the event bus (if any)
+func (s *Scheduler) publishTransition(result CheckResult) {
+	eventType := event.HealthCheckFailedType
+
+	if result.Healthy {
+		eventType = event.NodeRecoveredType
+	}
+
+	s.Events.Publish(event.Event{
+		Type:       eventType,
+		Source:     result.Check,
+		Message:    result.Message,
+		OccurredAt: result.StartedAt,
+	})
+}
+
+// run runs the check, retrying failed attempts as per the policy
+// of the check, returning the result of the last attempt
+func run(ctx context.Context, check HealthCheck) CheckResult {
+	policy := check.Policy()
+	backoff := policy.RetryBackoff
+	startedAt := time.Now()
+
+	var result CheckResult
+
+	for attempt := 1; ; attempt++ {
+		result = runAttempt(ctx, check, policy.Timeout)
+		result.Attempts = attempt
+
+		if result.Healthy || attempt > policy.Retries {
+			break
+		}
+
+		// wait before retrying unless the run is cancelled
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+
+		if ctx.Err() != nil {
+			break
+		}
+
+		backoff *= 2
+	}
+
+	result.Check = check.Name()
+	result.StartedAt = startedAt
+	result.Duration = time.Since(startedAt)
+
+	return result
+}
+
+// runAttempt runs a single attempt of the check,
+// cancelling the attempt after timeout (if non zero)
+func runAttempt(ctx context.Context, check HealthCheck, timeout time.Duration) CheckResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return check.Run(ctx)
+}
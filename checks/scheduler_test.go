@@ -0,0 +1,310 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/backpressure"
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/event"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCheck struct {
+	name     string
+	interval time.Duration
+	policy   Policy
+	// number of attempts that fail before the check passes
+	failures int
+	attempts int
+}
+
+func (fc *fakeCheck) Name() string {
+	return fc.name
+}
+
+func (fc *fakeCheck) Interval() time.Duration {
+	return fc.interval
+}
+
+func (fc *fakeCheck) Policy() Policy {
+	return fc.policy
+}
+
+func (fc *fakeCheck) Run(ctx context.Context) CheckResult {
+	fc.attempts++
+
+	if fc.attempts <= fc.failures {
+		return CheckResult{Message: "failed"}
+	}
+
+	return CheckResult{Healthy: true}
+}
+
+func TestSchedulerRunsChecksAtTheirOwnIntervals(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+
+	scheduler := NewScheduler(
+		&fakeCheck{name: "fast", interval: 20 * time.Millisecond},
+		&fakeCheck{name: "slow", interval: time.Hour},
+	)
+
+	results := make(chan CheckResult, 10)
+	runs := make(map[string]int)
+	counted := make(chan struct{})
+
+	go func() {
+		defer close(counted)
+
+		for result := range results {
+			assert.True(t, result.Healthy)
+			assert.False(t, result.StartedAt.IsZero())
+
+			runs[result.Check]++
+		}
+	}()
+
+	scheduler.Run(ctx, results)
+	close(results)
+	<-counted
+
+	// both checks run immediately, only the fast check runs again
+	assert.Greater(t, runs["fast"], 5)
+	assert.Equal(t, 1, runs["slow"])
+}
+
+func TestSchedulerPublishesChecksFailingAndRecovering(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scheduler := NewScheduler(&fakeCheck{name: "flaky", interval: time.Millisecond, failures: 2})
+	scheduler.Events = event.NewBus()
+
+	subscription := scheduler.Events.Subscribe("test", 10)
+	results := make(chan CheckResult, 10)
+
+	go scheduler.Run(ctx, results)
+
+	// two failed runs then passing runs, only
+	// the changes in health are published
+	for i := 0; i < 4; i++ {
+		<-results
+	}
+
+	failed := <-subscription.Events()
+
+	assert.Equal(t, event.HealthCheckFailedType, failed.Type)
+	assert.Equal(t, "flaky", failed.Source)
+	assert.Equal(t, "failed", failed.Message)
+
+	recovered := <-subscription.Events()
+
+	assert.Equal(t, event.NodeRecoveredType, recovered.Type)
+	assert.Len(t, subscription.Events(), 0)
+}
+
+func TestSchedulerDropsResultsInsteadOfBlocking(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	scheduler := NewScheduler(&fakeCheck{name: "fast", interval: time.Millisecond})
+	scheduler.Dropped = backpressure.NewCounter()
+
+	// no one reads the results
+	results := make(chan CheckResult, 1)
+
+	scheduler.Run(ctx, results)
+
+	assert.Len(t, results, 1)
+	assert.Greater(t, scheduler.Dropped.Counts()[ResultsChannel], uint64(0))
+}
+
+func TestRunRetriesFailedAttemptsWithBackoff(t *testing.T) {
+	check := &fakeCheck{
+		name:     "flaky",
+		policy:   Policy{Retries: 2, RetryBackoff: 10 * time.Millisecond},
+		failures: 2,
+	}
+
+	result := run(context.Background(), check)
+
+	assert.True(t, result.Healthy)
+	assert.Equal(t, 3, result.Attempts)
+	// waits 10 then 20 milliseconds before retrying
+	assert.GreaterOrEqual(t, result.Duration, 30*time.Millisecond)
+
+	check = &fakeCheck{
+		name:     "broken",
+		policy:   Policy{Retries: 1},
+		failures: 5,
+	}
+
+	result = run(context.Background(), check)
+
+	assert.False(t, result.Healthy)
+	assert.Equal(t, 2, result.Attempts)
+}
+
+func TestEVMCheckTimesOutAsPerPolicy(t *testing.T) {
+	unblock := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	check, err := New(EVMCheckName, Config{
+		Interval:  time.Minute,
+		EVMRPCURL: server.URL,
+		Policy:    Policy{Timeout: 20 * time.Millisecond},
+	})
+	assert.Nil(t, err)
+
+	result := run(context.Background(), check)
+
+	assert.False(t, result.Healthy)
+	assert.Less(t, result.Duration, time.Second)
+}
+
+func TestEVMCheckReportsBlockNumber(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":"0x1b4"}`)
+	}))
+	defer server.Close()
+
+	check, err := New(EVMCheckName, Config{
+		Interval:  time.Minute,
+		EVMRPCURL: server.URL,
+		Policy:    Policy{Timeout: time.Second},
+	})
+	assert.Nil(t, err)
+
+	result := check.Run(context.Background())
+
+	assert.True(t, result.Healthy)
+	assert.Equal(t, float64(436), result.Value)
+}
+
+func TestQueryCheckFailsWhenQueriesFailWhileStatusSucceeds(t *testing.T) {
+	servingBlocks := true
+
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case kava.StatusEndpointPath:
+			fmt.Fprint(w, `{"result":{"sync_info":{"latest_block_height":"101","latest_block_time":"2022-07-29T15:52:29Z","catching_up":false}}}`)
+		case kava.BlockEndpointPath:
+			if !servingBlocks {
+				w.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			assert.Equal(t, "100", r.URL.Query().Get("height"))
+
+			fmt.Fprint(w, `{"result":{"block_id":{"hash":"ABC"},"block":{"header":{"height":"100","app_hash":"DEF"}}}}`)
+		}
+	}))
+	defer rpcServer.Close()
+
+	restServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount"}}`)
+	}))
+	defer restServer.Close()
+
+	kavaClient, err := kava.New(kava.ClientConfig{JSONRPCURL: rpcServer.URL, HTTPReadTimeoutSeconds: 1})
+	assert.Nil(t, err)
+
+	restClient, err := kava.New(kava.ClientConfig{JSONRPCURL: restServer.URL, HTTPReadTimeoutSeconds: 1})
+	assert.Nil(t, err)
+
+	check, err := New(QueryCheckName, Config{
+		Interval:            time.Minute,
+		KavaClient:          kavaClient,
+		RESTClient:          restClient,
+		QueryAccountAddress: "kava1qy0xn7za2gd3t3kd3tclp6wjcamfp3ygj3g2sd",
+	})
+	assert.Nil(t, err)
+
+	result := check.Run(context.Background())
+
+	assert.True(t, result.Healthy, result.Message)
+	assert.Contains(t, result.Message, "account kava1qy0xn7za2gd3t3kd3tclp6wjcamfp3ygj3g2sd")
+
+	servingBlocks = false
+
+	result = check.Run(context.Background())
+
+	assert.False(t, result.Healthy)
+	assert.Contains(t, result.Message, "node status succeeded but querying block 100 failed")
+}
+
+func TestQueryCheckRequiresRESTClientToQueryAccounts(t *testing.T) {
+	kavaClient, err := kava.New(kava.ClientConfig{JSONRPCURL: "http://localhost:26657", HTTPReadTimeoutSeconds: 1})
+	assert.Nil(t, err)
+
+	_, err = New(QueryCheckName, Config{
+		Interval:            time.Minute,
+		KavaClient:          kavaClient,
+		QueryAccountAddress: "kava1qy0xn7za2gd3t3kd3tclp6wjcamfp3ygj3g2sd",
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestSyntheticTxCheckMeasuresInclusionLatency(t *testing.T) {
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case kava.BroadcastTxSyncEndpointPath:
+			assert.Equal(t, "0x0a0b", r.URL.Query().Get("tx"))
+
+			fmt.Fprint(w, `{"result":{"code":0,"log":"[]","hash":"ABCD"}}`)
+		case kava.TxEndpointPath:
+			fmt.Fprint(w, `{"result":{"hash":"ABCD","height":"1234"}}`)
+		}
+	}))
+	defer rpcServer.Close()
+
+	kavaClient, err := kava.New(kava.ClientConfig{JSONRPCURL: rpcServer.URL, HTTPReadTimeoutSeconds: 1})
+	assert.Nil(t, err)
+
+	check, err := New(SyntheticTxCheckName, Config{
+		Interval:           time.Minute,
+		KavaClient:         kavaClient,
+		SyntheticTxCommand: []string{"echo", "Cgs="},
+	})
+	assert.Nil(t, err)
+
+	result := check.Run(context.Background())
+
+	assert.True(t, result.Healthy, result.Message)
+	assert.Contains(t, result.Message, "included in block 1234")
+	assert.Equal(t, "TxInclusionLatencySeconds", result.MetricName)
+}
+
+func TestSyntheticTxCheckFailsWhenBroadcastRejected(t *testing.T) {
+	rpcServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"code":32,"codespace":"sdk","log":"account sequence mismatch","hash":"ABCD"}}`)
+	}))
+	defer rpcServer.Close()
+
+	kavaClient, err := kava.New(kava.ClientConfig{JSONRPCURL: rpcServer.URL, HTTPReadTimeoutSeconds: 1})
+	assert.Nil(t, err)
+
+	check, err := New(SyntheticTxCheckName, Config{
+		Interval:           time.Minute,
+		KavaClient:         kavaClient,
+		SyntheticTxCommand: []string{"echo", "Cgs="},
+	})
+	assert.Nil(t, err)
+
+	result := check.Run(context.Background())
+
+	assert.False(t, result.Healthy)
+	assert.Contains(t, result.Message, "account sequence mismatch")
+}
@@ -6,11 +6,22 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/kava-labs/doctor/backpressure"
 	"github.com/kava-labs/doctor/collect"
 	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/event"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
 	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/slo"
+	"github.com/kava-labs/doctor/store"
+	"github.com/kava-labs/doctor/syncphase"
 )
 
 // CLIConfig wraps values
@@ -20,10 +31,39 @@ type CLIConfig struct {
 	KavaURL                                    string
 	MaxMetricSamplesToRetainPerNode            int
 	MetricSamplesForSyntheticMetricCalculation int
-	MetricCollectors                           []string
-	AWSRegion                                  string
-	MetricNamespace                            string
-	Logger                                     *log.Logger
+	MetricCollectorsConfig
+	SampleStore store.Store
+	Logger      *logging.Logger
+	LogOutput   io.Writer
+	LogFormat   string
+	// time zone and layout to display times in
+	TimeFormat     logging.TimeFormat
+	ProgressOutput *os.File
+	// peer churn per minute above which to alert, 0 disables alerting
+	PeerChurnAlertThresholdPerMinute float64
+	// 95th percentile block interval above which to alert, 0 disables alerting
+	BlockIntervalP95AlertThresholdSeconds float64
+	// whether to discard samples from stale (e.g. cached) status responses
+	DiscardStaleSamples bool
+	// config values that silently disable protection, reported
+	// (and collected as metrics) once the display starts
+	ConfigWarnings []dconfig.ConfigWarning
+	// named groups of nodes (or endpoints) to roll up metrics for
+	NodeGroups []dconfig.NodeGroup
+	// seconds behind live beyond which a group member is counted as unhealthy
+	NodeGroupSyncLatencyToleranceSeconds int
+	// windows to calculate the uptime of the endpoint over
+	UptimeWindows []time.Duration
+	// half life of the moving average uptime, 0 disables the average
+	UptimeEWMAHalfLife time.Duration
+	// service level objectives to track the error budgets of
+	SLOs []slo.Objective
+	// settings reloaded from config (e.g. on SIGHUP in daemon mode)
+	Reloads <-chan DisplayReload
+	// counter of messages dropped on the doctor's channels
+	DroppedMessages *backpressure.Counter
+	// optional manager to publish alerts through
+	Alerts *incident.AlertManager
 }
 
 // CLI controls the display
@@ -31,46 +71,136 @@ type CLIConfig struct {
 // using either stdout or file based
 // output devices
 type CLI struct {
-	kavaEndpoint *Endpoint
-	*log.Logger
+	kavaEndpoint *endpoint.Endpoint
+	*logging.Logger
+	logOutput        io.Writer
+	logFormat        string
+	timeFormat       logging.TimeFormat
 	metricCollectors []collect.Collector
+	progressOutput   io.Writer
+	// whether the progress output device is a terminal
+	// that supports redrawing the current line in place
+	progressInPlace                       bool
+	showingProgress                       bool
+	peerChurnAlertThresholdPerMinute      float64
+	blockIntervalP95AlertThresholdSeconds float64
+	discardStaleSamples                   bool
+	configWarnings                        []dconfig.ConfigWarning
+	nodeGroups                            []dconfig.NodeGroup
+	nodeGroupSyncLatencyToleranceSeconds  int
+	uptimeWindows                         []time.Duration
+	uptimeEWMAHalfLife                    time.Duration
+	sloTracker                            *slo.Tracker
+	reloads                               <-chan DisplayReload
+	// number of dropped log messages already collected as metrics
+	reportedDroppedLogMessages uint64
+	droppedMessages            *backpressure.Counter
+	// number of messages dropped per channel
+	// already collected as metrics
+	reportedDroppedMessages map[string]uint64
+	// publishes alerts once as they fire, escalate and resolve
+	alerts *incident.AlertManager
 }
 
-// Watch watches for new measurements and log messages for the kava node with the
-// specified rpc api url, outputting them to the cli device in the desired format
-func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <-chan string, kavaNodeRPCURL string) error {
+// Watch watches (until the context is cancelled) for new measurements and log
+// messages for the kava node with the specified rpc api url, outputting them
+// to the cli device in the desired format
+func (c *CLI) Watch(ctx context.Context, metricReadOnlyChannels MetricReadOnlyChannels, logMessages <-chan logging.Entry, kavaNodeRPCURL string) error {
 	// handle logging in separate go-routines to avoid
 	// congestion with metric event emission
 	go func() {
 		for logMessage := range logMessages {
-			c.Println(logMessage)
+			fmt.Fprintln(c.logOutput, logMessage.Format(c.logFormat, c.timeFormat))
 		}
 	}()
 
+	// flag config that silently disables protection
+	for _, warning := range c.configWarnings {
+		c.Warnf("config warning: %s", warning.Message)
+	}
+
+	collectMetrics(c.metricCollectors, configWarningMetrics(c.configWarnings, time.Now()), c.Logger)
+
 	// event handlers for non-interactive mode
 	// loop over events
 	for {
 		select {
+		case <-ctx.Done():
+			if c.showingProgress {
+				fmt.Fprintln(c.progressOutput)
+			}
+
+			return nil
+		case reload := <-c.reloads:
+			c.applyReload(reload)
 		case syncStatusMetrics := <-metricReadOnlyChannels.SyncStatusMetrics:
 			// record sample in-memory for use in synthetic metric calculation
-			c.kavaEndpoint.AddSample(syncStatusMetrics.NodeId, NodeMetrics{
+			err := c.kavaEndpoint.AddSample(syncStatusMetrics.NodeId, endpoint.NodeMetrics{
 				SyncStatusMetrics: &syncStatusMetrics,
 			})
 
-			// calculate hash rate for this node
+			if err != nil {
+				c.Errorf("error %s persisting sample for %s", err, syncStatusMetrics.NodeId)
+			}
+
 			nodeId := syncStatusMetrics.NodeId
 
-			hashRatePerSecond, err := c.kavaEndpoint.CalculateNodeHashRatePerSecond(nodeId)
+			// stale responses aren't representative of the node's current
+			// sync status so only count them when discarding stale samples
+			if syncStatusMetrics.Stale && c.discardStaleSamples {
+				// log to stdout
+				fmt.Printf("%s node %s returned a stale status response for block %d, discarding sample\n", kavaNodeRPCURL, nodeId, syncStatusMetrics.SyncStatus.LatestBlockHeight)
+
+				collectMetrics(c.metricCollectors, append(staleResponseMetrics(syncStatusMetrics), metric.Metric{
+					Name: "SyncStatus",
+					Dimensions: map[string]string{
+						"node_id": nodeId,
+					},
+					Data:      syncStatusMetrics,
+					Timestamp: syncStatusMetrics.SampledAt,
+				}), c.Logger)
+
+				continue
+			}
+
+			// calculate hash rate for this node
+
+			hashRatePerSecond, err := c.kavaEndpoint.CalculateHashRate(nodeId)
 			if err != nil {
-				c.Printf("error %s calculating hash rate for node %s\n", err, nodeId)
+				c.Debugf("error %s calculating hash rate for node %s", err, nodeId)
 			}
 
 			latestBlockHeight := syncStatusMetrics.SyncStatus.LatestBlockHeight
 			secondsBehindLive := syncStatusMetrics.SecondsBehindLive
 			syncStatusLatencyMilliseconds := syncStatusMetrics.SampleLatencyMilliseconds
 
-			// log to stdout
-			fmt.Printf("%s node %s is synched up to block %d, %d seconds behind live, hashing %f blocks per second, status check took %d milliseconds\n", kavaNodeRPCURL, nodeId, latestBlockHeight, secondsBehindLive, hashRatePerSecond, syncStatusLatencyMilliseconds)
+			var progressMetrics []metric.Metric
+
+			// while the node is catching up show a progress indicator
+			// instead of repeating the full sync status line
+			if syncStatusMetrics.SyncStatus.CatchingUp {
+				var progress metric.SyncProgressMetric
+
+				// a node restoring a snapshot has no blocks to estimate from
+				if syncStatusMetrics.SyncPhase != syncphase.StateSync {
+					progressMetrics, progress, err = syncProgressMetrics(c.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+					if err != nil {
+						c.Debugf("error %s estimating sync progress for node %s", err, nodeId)
+					}
+				}
+
+				c.renderSyncProgress(kavaNodeRPCURL, nodeId, syncStatusMetrics.SyncPhase, progress, err)
+			} else {
+				if c.showingProgress {
+					// move off the line the progress indicator was drawn on
+					fmt.Fprintln(c.progressOutput)
+					c.showingProgress = false
+				}
+
+				// log to stdout
+				fmt.Printf("%s node %s is synched up to block %d, %d seconds behind live, hashing %f blocks per second, status check took %d milliseconds\n", kavaNodeRPCURL, nodeId, latestBlockHeight, secondsBehindLive, hashRatePerSecond, syncStatusLatencyMilliseconds)
+			}
 
 			// collect metrics to external storage backends
 			var metrics []metric.Metric
@@ -86,7 +216,6 @@ func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(hashRatePerSecond),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       true,
 				CollectToCloudwatch: true,
 			}
 
@@ -99,7 +228,6 @@ func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Data:                syncStatusMetrics,
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       true,
 				CollectToCloudwatch: false,
 			}
 
@@ -112,7 +240,6 @@ func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(latestBlockHeight),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       false,
 				CollectToCloudwatch: true,
 			}
 
@@ -125,7 +252,7 @@ func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(secondsBehindLive),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       false,
+				Unit:                metric.UnitSeconds,
 				CollectToCloudwatch: true,
 			}
 
@@ -138,18 +265,75 @@ func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(syncStatusLatencyMilliseconds),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       false,
+				Unit:                metric.UnitMilliseconds,
 				CollectToCloudwatch: true,
 			}
 
 			metrics = append(metrics, statusCheckMillisecondLatencyMetric)
 
+			latencyMetrics, err := statusCheckLatencyMetrics(c.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+			if err != nil {
+				c.Debugf("error %s calculating status check latency percentiles for node %s", err, nodeId)
+			}
+
+			metrics = append(metrics, latencyMetrics...)
+
+			c.sloTracker.RecordStatusLatency(time.Duration(syncStatusMetrics.SampleLatencyMilliseconds)*time.Millisecond, syncStatusMetrics.SampledAt)
+
+			metrics = append(metrics, c.sloMetrics(slo.LatencyObjectiveKind, syncStatusMetrics.SampledAt)...)
+
+			intervalMetrics, blockInterval, err := blockIntervalMetrics(c.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+			if err != nil {
+				c.Debugf("error %s calculating block interval for node %s", err, nodeId)
+			} else {
+				// slow block production indicates consensus rounds
+				// are failing or validators are missing blocks
+				slowBlocks := c.blockIntervalP95AlertThresholdSeconds > 0 && blockInterval.P95Seconds > c.blockIntervalP95AlertThresholdSeconds
+
+				if slowBlocks {
+					c.Warnf("node %s p95 block interval of %f seconds exceeds alert threshold of %f seconds, consensus may be slow", nodeId, blockInterval.P95Seconds, c.blockIntervalP95AlertThresholdSeconds)
+				}
+
+				c.alerts.Set(nodeId, "BlockIntervalP95", slowBlocks, fmt.Sprintf("p95 block interval of %.1f seconds", blockInterval.P95Seconds), syncStatusMetrics.SampledAt)
+			}
+
+			metrics = append(metrics, intervalMetrics...)
+
+			metrics = append(metrics, progressMetrics...)
+
+			metrics = append(metrics, staleResponseMetrics(syncStatusMetrics)...)
+
+			metrics = append(metrics, blocksBehindReferenceMetrics(syncStatusMetrics)...)
+
+			c.alerts.Set(nodeId, "ChainIdMismatch", syncStatusMetrics.ChainIdMismatch, fmt.Sprintf("node is on chain %s", syncStatusMetrics.ChainId), syncStatusMetrics.SampledAt)
+
+			metrics = append(metrics, chainIdMismatchMetrics(syncStatusMetrics)...)
+
+			metrics = append(metrics, syncPhaseMetrics(syncStatusMetrics)...)
+
+			if syncStatusMetrics.AutohealCircuitBreakerTripped != nil {
+				c.alerts.Set(nodeId, "AutohealCircuitBreaker", *syncStatusMetrics.AutohealCircuitBreakerTripped, fmt.Sprintf("autoheal restart cap reached, autohealing stopped until reset using the %s command", ResetAutohealCommand), syncStatusMetrics.SampledAt)
+			}
+
+			metrics = append(metrics, autohealCircuitBreakerMetrics(syncStatusMetrics)...)
+
+			groupMetrics, rollups := nodeGroupRollupMetrics(c.kavaEndpoint, c.nodeGroups, nodeId, int64(c.nodeGroupSyncLatencyToleranceSeconds), syncStatusMetrics.SampledAt)
+
+			for _, rollup := range rollups {
+				// log to stdout
+				fmt.Printf("%s %s\n", kavaNodeRPCURL, formatNodeGroupRollup(rollup))
+			}
+
+			metrics = append(metrics, groupMetrics...)
+
 			for _, collector := range c.metricCollectors {
 				for _, metric := range metrics {
 					err := collector.Collect(metric)
 
 					if err != nil {
-						c.Printf("error %s collecting metric %+v\n", err, metric)
+						c.Errorf("error %s collecting metric %+v", err, metric)
 					}
 				}
 
@@ -157,15 +341,19 @@ func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 		case uptimeMetric := <-metricReadOnlyChannels.UptimeMetrics:
 			endpointURL := uptimeMetric.EndpointURL
 			// record sample in-memory for use in synthetic metric calculation
-			c.kavaEndpoint.AddSample(endpointURL, NodeMetrics{
+			err := c.kavaEndpoint.AddSample(endpointURL, endpoint.NodeMetrics{
 				UptimeMetric: &uptimeMetric,
 			})
 
+			if err != nil {
+				c.Errorf("error %s persisting sample for %s", err, endpointURL)
+			}
+
 			// calculate uptime
 			uptime, err := c.kavaEndpoint.CalculateUptime(endpointURL)
 
 			if err != nil {
-				c.Printf(fmt.Sprintf("error %s calculating uptime for %s\n", err, endpointURL))
+				c.Errorf("error %s calculating uptime for %s", err, endpointURL)
 				continue
 			}
 
@@ -184,66 +372,406 @@ func (c *CLI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				Data:                uptimeMetric,
 				Value:               float64(uptimeMetric.RollingAveragePercentAvailable),
 				Timestamp:           uptimeMetric.SampledAt,
-				CollectToFile:       true,
+				Unit:                metric.UnitPercent,
 				CollectToCloudwatch: true,
 			}
 
 			metrics = append(metrics, uptimeMetricForCollection)
+			metrics = append(metrics, statusCheckAttemptsMetrics(uptimeMetric)...)
+			metrics = append(metrics, uptimeProbeMetrics(uptimeMetric)...)
+
+			windowMetrics, uptimeWindows := uptimeWindowMetrics(c.kavaEndpoint, endpointURL, c.uptimeWindows, c.uptimeEWMAHalfLife, uptimeMetric.SampledAt)
+
+			if len(windowMetrics) > 0 {
+				// log to stdout
+				fmt.Printf("%s uptime %s\n", endpointURL, formatUptimeWindows(uptimeWindows, c.uptimeWindows))
+			}
+
+			metrics = append(metrics, windowMetrics...)
+
+			c.sloTracker.RecordUptime(uptimeMetric.Up, uptimeMetric.SampledAt)
+
+			metrics = append(metrics, c.sloMetrics(slo.UptimeObjectiveKind, uptimeMetric.SampledAt)...)
+
+			groupMetrics, rollups := nodeGroupRollupMetrics(c.kavaEndpoint, c.nodeGroups, endpointURL, int64(c.nodeGroupSyncLatencyToleranceSeconds), uptimeMetric.SampledAt)
+
+			for _, rollup := range rollups {
+				// log to stdout
+				fmt.Printf("%s %s\n", endpointURL, formatNodeGroupRollup(rollup))
+			}
+
+			metrics = append(metrics, groupMetrics...)
+
+			// report log messages dropped since last reported
+			droppedLogMessages := c.Dropped()
+			metrics = append(metrics, droppedLogMessagesMetrics(droppedLogMessages-c.reportedDroppedLogMessages, uptimeMetric.SampledAt)...)
+			c.reportedDroppedLogMessages = droppedLogMessages
+
+			// report messages dropped on any channel since last reported
+			if c.droppedMessages != nil {
+				metrics = append(metrics, droppedMessagesMetrics(c.droppedMessages.Counts(), c.reportedDroppedMessages, uptimeMetric.SampledAt)...)
+			}
 
 			for _, collector := range c.metricCollectors {
 				for _, metric := range metrics {
 					err := collector.Collect(metric)
 
 					if err != nil {
-						c.Printf("error %s collecting metric %+v\n", err, metric)
+						c.Errorf("error %s collecting metric %+v", err, metric)
 					}
 				}
 
 			}
+		case peerConnectionMetrics := <-metricReadOnlyChannels.PeerConnectionMetrics:
+			nodeId := peerConnectionMetrics.NodeId
+			// record sample in-memory for use in synthetic metric calculation
+			err := c.kavaEndpoint.AddSample(nodeId, endpoint.NodeMetrics{
+				PeerConnectionMetrics: &peerConnectionMetrics,
+			})
+
+			if err != nil {
+				c.Errorf("error %s persisting sample for %s", err, nodeId)
+			}
+
+			// frozen nodes with poor peering have their peers refreshed
+			c.alerts.Set(nodeId, "PoorPeering", peerConnectionMetrics.PoorPeering, fmt.Sprintf("%d of %d peers low quality", len(peerConnectionMetrics.LowQualityPeerIds), len(peerConnectionMetrics.PeerIds)), peerConnectionMetrics.SampledAt)
+
+			// calculate peer churn for this node
+			metrics, peerChurnPerMinute, err := peerChurnMetrics(c.kavaEndpoint, peerConnectionMetrics)
+
+			if err != nil {
+				c.Debugf("error %s calculating peer churn for node %s", err, nodeId)
+				continue
+			}
+
+			// log to stdout
+			fmt.Printf("%s node %s connected to %d peers, churning %f peers per minute\n", kavaNodeRPCURL, nodeId, len(peerConnectionMetrics.PeerIds), peerChurnPerMinute)
+
+			// abnormal churn typically precedes sync instability
+			highChurn := c.peerChurnAlertThresholdPerMinute > 0 && peerChurnPerMinute > c.peerChurnAlertThresholdPerMinute
+
+			if highChurn {
+				c.Warnf("node %s peer churn of %f peers per minute exceeds alert threshold of %f, node sync may become unstable", nodeId, peerChurnPerMinute, c.peerChurnAlertThresholdPerMinute)
+			}
+
+			c.alerts.Set(nodeId, "PeerChurn", highChurn, fmt.Sprintf("peer churn of %.1f peers per minute", peerChurnPerMinute), peerConnectionMetrics.SampledAt)
+
+			// collect metrics to external storage backends
+			for _, collector := range c.metricCollectors {
+				for _, metric := range metrics {
+					err := collector.Collect(metric)
+
+					if err != nil {
+						c.Errorf("error %s collecting metric %+v", err, metric)
+					}
+				}
+			}
+		case serviceHealth := <-metricReadOnlyChannels.ServiceHealthMetrics:
+			if serviceHealth.RestartLoop {
+				c.Warnf("ServiceRestartLoop: systemd is repeatedly restarting %s service (%d restarts, state %s/%s), autoheal restarts are suspended", serviceHealth.ServiceName, serviceHealth.Restarts, serviceHealth.ActiveState, serviceHealth.SubState)
+			}
+
+			c.alerts.Set("", "ServiceRestartLoop", serviceHealth.RestartLoop, fmt.Sprintf("systemd restarting %s service", serviceHealth.ServiceName), serviceHealth.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, serviceHealthMetrics(serviceHealth), c.Logger)
+		case consensusState := <-metricReadOnlyChannels.ConsensusStateMetrics:
+			if consensusState.Frozen {
+				c.Warnf("node %s consensus frozen at height %d round %d step %s for %.0f seconds", consensusState.NodeId, consensusState.Height, consensusState.Round, consensusState.StepName, consensusState.SecondsInStep)
+			}
+
+			c.alerts.Set(consensusState.NodeId, "ConsensusFrozen", consensusState.Frozen, fmt.Sprintf("consensus frozen at height %d round %d step %s", consensusState.Height, consensusState.Round, consensusState.StepName), consensusState.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, consensusStateMetrics(consensusState), c.Logger)
+		case mempool := <-metricReadOnlyChannels.MempoolMetrics:
+			if mempool.Backlogged {
+				c.Warnf("node %s mempool size of %d transactions (%d bytes) has stayed above alert threshold, node or network may be unable to keep up", mempool.NodeId, mempool.Size, mempool.Bytes)
+			}
+
+			c.alerts.Set(mempool.NodeId, "MempoolBacklog", mempool.Backlogged, fmt.Sprintf("mempool backlog of %d transactions", mempool.Size), mempool.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, mempoolMetrics(mempool), c.Logger)
+		case host := <-metricReadOnlyChannels.HostMetrics:
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, hostMetrics(host), c.Logger)
+		case rpcMethods := <-metricReadOnlyChannels.RPCMethodsMetrics:
+			var unavailableMethods []string
+
+			for _, method := range rpcMethods.Methods {
+				if !method.Available {
+					c.Warnf("node %s rpc method %s unavailable: %s", rpcMethods.NodeId, method.Method, method.Error)
+
+					unavailableMethods = append(unavailableMethods, method.Method)
+				}
+			}
+
+			c.alerts.Set(rpcMethods.NodeId, "RPCMethodsUnavailable", len(unavailableMethods) > 0, fmt.Sprintf("rpc methods %s unavailable", strings.Join(unavailableMethods, ", ")), rpcMethods.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, rpcMethodsMetrics(rpcMethods), c.Logger)
+		case websocket := <-metricReadOnlyChannels.WebsocketMetrics:
+			if websocket.Dropped {
+				c.Warnf("node %s websocket subscription to new block events dropped", websocket.NodeId)
+			} else if !websocket.Connected {
+				c.Warnf("node %s websocket subscription to new block events failed", websocket.NodeId)
+			}
+
+			c.alerts.Set(websocket.NodeId, "WebsocketDisconnected", !websocket.Connected, "websocket subscription to new block events disconnected", websocket.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, websocketMetrics(websocket), c.Logger)
+		case regionLatency := <-metricReadOnlyChannels.RegionLatencyMetrics:
+			var unavailableRegions []string
+
+			for _, region := range regionLatency.Regions {
+				if !region.Up {
+					c.Warnf("endpoint %s unavailable from region %s: %s", regionLatency.EndpointURL, region.Region, region.Error)
+
+					unavailableRegions = append(unavailableRegions, region.Region)
+				}
+			}
+
+			c.alerts.Set(regionLatency.EndpointURL, "RegionsUnavailable", len(unavailableRegions) > 0, fmt.Sprintf("endpoint unavailable from regions %s", strings.Join(unavailableRegions, ", ")), regionLatency.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, regionLatencyMetrics(regionLatency), c.Logger)
+		case consistency := <-metricReadOnlyChannels.ChainConsistencyMetrics:
+			if consistency.Diverged {
+				c.Warnf("node %s block %d hash %s app hash %s differs from reference block hash %s app hash %s", consistency.NodeId, consistency.Height, consistency.BlockHash, consistency.AppHash, consistency.ReferenceBlockHash, consistency.ReferenceAppHash)
+			}
+
+			c.alerts.Set(consistency.NodeId, "ChainDiverged", consistency.Diverged, fmt.Sprintf("block %d hashes differ from reference", consistency.Height), consistency.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, chainConsistencyMetrics(consistency), c.Logger)
+		case votingPower := <-metricReadOnlyChannels.VotingPowerMetrics:
+			if votingPower.Degraded {
+				c.Warnf("%.2f%% of voting power of chain %s signed block %d, chain is halted or at risk of halting", votingPower.OnlinePercent, votingPower.ChainId, votingPower.Height)
+			}
+
+			c.alerts.Set(votingPower.ChainId, "ChainDegraded", votingPower.Degraded, fmt.Sprintf("%.2f%% of voting power online at block %d", votingPower.OnlinePercent, votingPower.Height), votingPower.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, votingPowerMetrics(votingPower), c.Logger)
+		case addressMetrics := <-metricReadOnlyChannels.EndpointAddressMetrics:
+			// record sample in-memory keyed by the address
+			// for calculating the uptime of the address
+			err := c.kavaEndpoint.AddSample(addressMetrics.Address, endpoint.NodeMetrics{
+				UptimeMetric: &metric.UptimeMetric{
+					EndpointURL: addressMetrics.Address,
+					Up:          addressMetrics.Up,
+					SampledAt:   addressMetrics.SampledAt,
+				},
+			})
+
+			if err != nil {
+				c.Errorf("error %s persisting sample for %s", err, addressMetrics.Address)
+			}
+
+			uptime, err := c.kavaEndpoint.CalculateUptime(addressMetrics.Address)
+
+			if err != nil {
+				c.Errorf("error %s calculating uptime for %s", err, addressMetrics.Address)
+				continue
+			}
+
+			addressMetrics.RollingAveragePercentAvailable = uptime * 100
+
+			// log to stdout
+			fmt.Printf("%s address %s served by node %s uptime %f%%\n", kavaNodeRPCURL, addressMetrics.Address, addressMetrics.NodeId, addressMetrics.RollingAveragePercentAvailable)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, endpointAddressMetrics(addressMetrics), c.Logger)
+		case healthCheck := <-metricReadOnlyChannels.HealthCheckResults:
+			status := "passed"
+
+			if !healthCheck.Healthy {
+				status = "failed"
+			}
+
+			// log to stdout
+			fmt.Printf("%s %s health check %s: %s\n", kavaNodeRPCURL, healthCheck.Check, status, healthCheck.Message)
+
+			// health checks are of the configured node
+			c.alerts.Set("", "HealthCheck "+healthCheck.Check, !healthCheck.Healthy, healthCheck.Message, healthCheck.StartedAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, healthCheckMetrics(healthCheck), c.Logger)
+		case accessLog := <-metricReadOnlyChannels.AccessLogMetrics:
+			// log to stdout
+			fmt.Printf("%s observed %d requests, %.2f%% server errors, %.2f%% client errors, p95 latency %.0f milliseconds\n", kavaNodeRPCURL, accessLog.Requests, accessLog.ServerErrorRate*100, accessLog.ClientErrorRate*100, accessLog.LatencyP95Milliseconds)
+
+			if accessLog.UnparsedLines > 0 {
+				c.Debugf("skipped %d unparseable lines in access log %s", accessLog.UnparsedLines, accessLog.LogFilepath)
+			}
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, accessLogMetrics(accessLog), c.Logger)
+		case incidentEvent := <-metricReadOnlyChannels.IncidentEvents:
+			// already logged by the routine publishing the event,
+			// only the gui correlates events on a timeline
+			c.Debugf("%s event for node %s: %s", incidentEvent.Type, incidentEvent.NodeId, incidentEvent.Message)
+		case newAnnotation := <-metricReadOnlyChannels.Annotations:
+			c.Infof("annotation: %s", newAnnotation.Message)
+		case busEvent := <-metricReadOnlyChannels.Events:
+			if busEvent.Type == event.HealthCheckFailedType {
+				c.Warnf("%s", formatEvent(busEvent))
+			} else {
+				c.Infof("%s", formatEvent(busEvent))
+			}
+
+			// collect metrics to external storage backends
+			collectMetrics(c.metricCollectors, eventMetrics(busEvent), c.Logger)
+		}
+	}
+}
+
+// applyReload replaces the collectors and thresholds
+// of the cli with those reloaded from config, flushing
+// and closing the collectors that were replaced
+func (c *CLI) applyReload(reload DisplayReload) {
+	err := collect.CloseAll(c.metricCollectors)
+
+	if err != nil {
+		c.Errorf("error %s closing replaced metric collectors", err)
+	}
+
+	c.metricCollectors = reload.MetricCollectors
+	c.peerChurnAlertThresholdPerMinute = reload.PeerChurnAlertThresholdPerMinute
+	c.blockIntervalP95AlertThresholdSeconds = reload.BlockIntervalP95AlertThresholdSeconds
+	c.nodeGroups = reload.NodeGroups
+	c.nodeGroupSyncLatencyToleranceSeconds = reload.NodeGroupSyncLatencyToleranceSeconds
+}
+
+// Close flushes and closes all metric collectors
+// used by the cli, returning error (if any)
+func (c *CLI) Close() error {
+	return collect.CloseAll(c.metricCollectors)
+}
+
+// Endpoint returns the samples and synthetic
+// metrics collected for each node
+func (c *CLI) Endpoint() *endpoint.Endpoint {
+	return c.kavaEndpoint
+}
+
+// sloMetrics returns metrics for the error budgets of the objectives
+// of the kind, alerting on any error budgets burning too fast
+func (c *CLI) sloMetrics(kind string, evaluatedAt time.Time) []metric.Metric {
+	statuses := c.sloTracker.Statuses(kind, evaluatedAt)
+
+	for _, status := range statuses {
+		if status.Alert != "" {
+			c.Warnf("%s", formatSLOAlert(status))
 		}
+
+		c.alerts.Set("", "SLO "+status.Name, status.Alert != "", formatSLOAlert(status), evaluatedAt)
 	}
+
+	return sloMetrics(statuses)
+}
+
+// renderSyncProgress outputs the sync progress for a catching up node
+// in the specified sync phase, redrawing the progress in place when
+// output is to a terminal, err is the error (if any) estimating the
+// progress
+func (c *CLI) renderSyncProgress(kavaNodeRPCURL string, nodeId string, syncPhase string, progress metric.SyncProgressMetric, err error) {
+	var line string
+
+	switch {
+	case syncPhase == syncphase.StateSync:
+		line = fmt.Sprintf("%s node %s is restoring a state sync snapshot...", kavaNodeRPCURL, nodeId)
+	case err != nil:
+		line = fmt.Sprintf("%s node %s is catching up, estimating sync progress...", kavaNodeRPCURL, nodeId)
+	default:
+		line = fmt.Sprintf("%s node %s catching up %s %.2f%% block %d of ~%d, %d blocks remaining, %.2f blocks per second, eta %s", kavaNodeRPCURL, nodeId, progressBar(progress.PercentSynced, 20), progress.PercentSynced, progress.LatestBlockHeight, progress.EstimatedHeadHeight, progress.BlocksRemaining, progress.BlocksPerSecond, formatEstimatedTimeToCatchUp(progress))
+	}
+
+	if !c.progressInPlace {
+		fmt.Fprintln(c.progressOutput, line)
+
+		return
+	}
+
+	// return to the start of the line and clear it before redrawing
+	fmt.Fprintf(c.progressOutput, "\r\033[K%s", line)
+
+	c.showingProgress = true
+}
+
+// progressBar renders percent as a fixed width text progress bar
+func progressBar(percent float64, width int) string {
+	filled := int(percent / 100 * float64(width))
+
+	if filled < 0 {
+		filled = 0
+	}
+
+	if filled > width {
+		filled = width
+	}
+
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
 }
 
 // NewCLI creates and returns a new cli
 // using the provided configuration and error (if any)
 func NewCLI(config CLIConfig) (*CLI, error) {
-	endpoint := NewEndpoint(EndpointConfig{URL: config.KavaURL,
+	kavaEndpoint := endpoint.New(endpoint.EndpointConfig{URL: config.KavaURL,
 		MetricSamplesToKeepPerNode:                 config.MaxMetricSamplesToRetainPerNode,
 		MetricSamplesForSyntheticMetricCalculation: config.MetricSamplesForSyntheticMetricCalculation,
+		SampleStore:         config.SampleStore,
+		DiscardStaleSamples: config.DiscardStaleSamples,
 	})
 
-	collectors := []collect.Collector{}
+	// restore samples from previous runs (if any)
+	err := kavaEndpoint.LoadSamples()
 
-	for _, collector := range config.MetricCollectors {
-		switch collector {
-		case dconfig.FileMetricCollector:
-			fileCollector, err := collect.NewFileCollector(collect.FileCollectorConfig{})
+	if err != nil {
+		return nil, err
+	}
 
-			if err != nil {
-				return nil, err
-			}
+	collectors, err := NewMetricCollectors(config.MetricCollectorsConfig, config.Logger)
 
-			collectors = append(collectors, fileCollector)
-		case dconfig.CloudwatchMetricCollector:
-			cloudwatchConfig := collect.CloudWatchCollectorConfig{
-				Ctx:             context.Background(),
-				AWSRegion:       config.AWSRegion,
-				MetricNamespace: config.MetricNamespace,
-			}
+	if err != nil {
+		return nil, err
+	}
 
-			cloudwatchCollector, err := collect.NewCloudWatchCollector(cloudwatchConfig)
+	progressOutput := config.ProgressOutput
 
-			if err != nil {
-				return nil, err
-			}
+	if progressOutput == nil {
+		progressOutput = os.Stdout
+	}
 
-			collectors = append(collectors, cloudwatchCollector)
-		}
+	var progressInPlace bool
+
+	if fileInfo, err := progressOutput.Stat(); err == nil {
+		progressInPlace = fileInfo.Mode()&os.ModeCharDevice != 0
 	}
 
 	return &CLI{
-		kavaEndpoint:     endpoint,
-		Logger:           config.Logger,
-		metricCollectors: collectors,
+		progressOutput:                        progressOutput,
+		progressInPlace:                       progressInPlace,
+		kavaEndpoint:                          kavaEndpoint,
+		Logger:                                config.Logger,
+		logOutput:                             config.LogOutput,
+		logFormat:                             config.LogFormat,
+		timeFormat:                            config.TimeFormat,
+		metricCollectors:                      collectors,
+		peerChurnAlertThresholdPerMinute:      config.PeerChurnAlertThresholdPerMinute,
+		blockIntervalP95AlertThresholdSeconds: config.BlockIntervalP95AlertThresholdSeconds,
+		discardStaleSamples:                   config.DiscardStaleSamples,
+		configWarnings:                        config.ConfigWarnings,
+		nodeGroups:                            config.NodeGroups,
+		nodeGroupSyncLatencyToleranceSeconds:  config.NodeGroupSyncLatencyToleranceSeconds,
+		uptimeWindows:                         config.UptimeWindows,
+		uptimeEWMAHalfLife:                    config.UptimeEWMAHalfLife,
+		sloTracker:                            slo.NewTracker(config.SLOs),
+		reloads:                               config.Reloads,
+		droppedMessages:                       config.DroppedMessages,
+		alerts:                                config.Alerts,
+		reportedDroppedMessages:               make(map[string]uint64),
 	}, nil
 }
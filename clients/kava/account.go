@@ -0,0 +1,51 @@
+package kava
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	AccountsEndpointPath = "/cosmos/auth/v1beta1/accounts/"
+)
+
+// Account wraps values for an account
+// queried from the cosmos rest api
+type Account struct {
+	// type of the account
+	// e.g. /cosmos.auth.v1beta1.BaseAccount
+	Type string `json:"@type"`
+}
+
+// accountResponse wraps the cosmos rest api
+// response, with a nil account if it isn't found
+type accountResponse struct {
+	Account *Account `json:"account"`
+}
+
+// GetAccount gets the account with the specified address
+// from the cosmos rest api the client is configured with,
+// returning the account and error (if any)
+func (c *Client) GetAccount(address string) (Account, error) {
+	var account accountResponse
+
+	path := c.config.JSONRPCURL + AccountsEndpointPath + url.PathEscape(address)
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return Account{}, err
+	}
+
+	_, err = MakeJSONRequest(c.Client, request, &account)
+
+	if err != nil {
+		return Account{}, err
+	}
+
+	if account.Account == nil {
+		return Account{}, fmt.Errorf("no account in response for address %s", address)
+	}
+
+	return *account.Account, nil
+}
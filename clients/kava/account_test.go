@@ -0,0 +1,39 @@
+package kava
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAccount(t *testing.T) {
+	address := "kava1qy0xn7za2gd3t3kd3tclp6wjcamfp3ygj3g2sd"
+	response := `{"account":{"@type":"/cosmos.auth.v1beta1.BaseAccount","address":"kava1qy0xn7za2gd3t3kd3tclp6wjcamfp3ygj3g2sd","account_number":"1"}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, AccountsEndpointPath+address, r.URL.Path)
+
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	account, err := client.GetAccount(address)
+
+	assert.Nil(t, err)
+	assert.Equal(t, Account{Type: "/cosmos.auth.v1beta1.BaseAccount"}, account)
+
+	// response without an account
+	response = `{}`
+
+	_, err = client.GetAccount(address)
+
+	assert.NotNil(t, err)
+}
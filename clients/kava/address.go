@@ -0,0 +1,75 @@
+package kava
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+const (
+	// host of the urls requests are made to for a node listening
+	// on a unix domain socket, only used for the host header as
+	// connections are made to the socket
+	UnixSocketHost = "unix"
+)
+
+// ParseRPCAddress normalizes an address for the rpc api of a node,
+// either a url (e.g. https://rpc.data.kava.io) or a tendermint style
+// listen address (e.g. tcp://0.0.0.0:26657, 127.0.0.1:26657 or
+// unix:///var/run/kava/rpc.sock), returning the url to make requests
+// to, the path of the unix domain socket to connect to (if any), and
+// error (if any)
+func ParseRPCAddress(address string) (string, string, error) {
+	if address == "" {
+		return "", "", nil
+	}
+
+	if strings.HasPrefix(address, "unix://") {
+		socketPath := strings.TrimPrefix(address, "unix://")
+
+		if socketPath == "" {
+			return "", "", fmt.Errorf("invalid unix socket address %s, expected the form unix://<path>", address)
+		}
+
+		return "http://" + UnixSocketHost, socketPath, nil
+	}
+
+	// tendermint defaults to tcp for addresses without a scheme
+	if !strings.Contains(address, "://") {
+		address = "tcp://" + address
+	}
+
+	rpcURL, err := url.Parse(address)
+
+	if err != nil {
+		return "", "", fmt.Errorf("invalid rpc address %s: %w", address, err)
+	}
+
+	switch rpcURL.Scheme {
+	case "http", "https":
+	case "tcp":
+		if rpcURL.Port() == "" {
+			return "", "", fmt.Errorf("invalid rpc address %s, expected the form tcp://<host>:<port>", address)
+		}
+
+		// a node listening on all interfaces
+		// is reachable over the loopback address
+		host := rpcURL.Hostname()
+
+		if host == "" || net.ParseIP(host).IsUnspecified() {
+			host = "127.0.0.1"
+		}
+
+		rpcURL.Scheme = "http"
+		rpcURL.Host = net.JoinHostPort(host, rpcURL.Port())
+	default:
+		return "", "", fmt.Errorf("unsupported scheme %s for rpc address %s, expected http, https, tcp or unix", rpcURL.Scheme, address)
+	}
+
+	if rpcURL.Host == "" {
+		return "", "", fmt.Errorf("invalid rpc address %s, missing host", address)
+	}
+
+	return rpcURL.String(), "", nil
+}
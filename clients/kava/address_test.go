@@ -0,0 +1,74 @@
+package kava
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRPCAddress(t *testing.T) {
+	testCases := []struct {
+		address            string
+		expectedURL        string
+		expectedSocketPath string
+	}{
+		{"https://rpc.data.kava.io", "https://rpc.data.kava.io", ""},
+		{"http://localhost:26657/", "http://localhost:26657/", ""},
+		{"tcp://127.0.0.1:26657", "http://127.0.0.1:26657", ""},
+		{"tcp://0.0.0.0:26657", "http://127.0.0.1:26657", ""},
+		{"localhost:26657", "http://localhost:26657", ""},
+		{"unix:///var/run/kava/rpc.sock", "http://unix", "/var/run/kava/rpc.sock"},
+		{"", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.address, func(t *testing.T) {
+			rpcURL, socketPath, err := ParseRPCAddress(tc.address)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expectedURL, rpcURL)
+			assert.Equal(t, tc.expectedSocketPath, socketPath)
+		})
+	}
+}
+
+func TestParseRPCAddressRejectsInvalidAddresses(t *testing.T) {
+	for _, address := range []string{"unix://", "tcp://127.0.0.1", "grpc://localhost:9090", "http://"} {
+		t.Run(address, func(t *testing.T) {
+			_, _, err := ParseRPCAddress(address)
+
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestClientRequestsOverUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "rpc.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+	assert.Nil(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, TestNodeStateResponse)
+	}))
+	server.Listener = listener
+	server.Start()
+	defer server.Close()
+
+	// requests to a socket never go through a proxy
+	client, err := New(ClientConfig{
+		JSONRPCURL: "unix://" + socketPath,
+		Transport:  TransportConfig{ProxyURL: "http://proxy.invalid:3128"},
+	})
+	assert.Nil(t, err)
+
+	nodeState, err := client.GetNodeState()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "node-1", nodeState.NodeInfo.Id)
+}
@@ -0,0 +1,157 @@
+package kava
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	// kinds of credentials that can be sent to an endpoint
+	BasicAuthKind  = "basic"
+	BearerAuthKind = "bearer"
+	HeaderAuthKind = "header"
+)
+
+var (
+	ValidAuthKinds = []string{BasicAuthKind, BearerAuthKind, HeaderAuthKind}
+)
+
+// Auth wraps the credentials sent with every request (and websocket
+// handshake) to an endpoint e.g. one behind an authenticating proxy
+type Auth struct {
+	Username string
+	Password string
+	// sent as an Authorization: Bearer header,
+	// instead of basic auth if both are set
+	BearerToken string
+	// additional headers e.g. X-API-Key
+	Headers map[string]string
+}
+
+// ParseAuth parses credentials in the form basic:<username>:<password>,
+// bearer:<token> or header:<name>:<value>, multiple credentials (e.g.
+// several headers) can be separated by semicolons, returning the
+// credentials and error (if any)
+// Errors never include the credentials
+func ParseAuth(rawAuth string) (Auth, error) {
+	var auth Auth
+
+	for _, rawCredential := range strings.Split(rawAuth, ";") {
+		rawCredential = strings.TrimSpace(rawCredential)
+
+		if rawCredential == "" {
+			continue
+		}
+
+		kind, value, _ := strings.Cut(rawCredential, ":")
+
+		switch kind {
+		case BasicAuthKind:
+			username, password, found := strings.Cut(value, ":")
+
+			if !found || username == "" {
+				return Auth{}, fmt.Errorf("invalid basic auth, expected basic:<username>:<password>")
+			}
+
+			auth.Username, auth.Password = username, password
+		case BearerAuthKind:
+			if value == "" {
+				return Auth{}, fmt.Errorf("invalid bearer auth, expected bearer:<token>")
+			}
+
+			auth.BearerToken = value
+		case HeaderAuthKind:
+			name, headerValue, found := strings.Cut(value, ":")
+			name = strings.TrimSpace(name)
+
+			if !found || name == "" {
+				return Auth{}, fmt.Errorf("invalid header auth, expected header:<name>:<value>")
+			}
+
+			if auth.Headers == nil {
+				auth.Headers = make(map[string]string)
+			}
+
+			auth.Headers[name] = headerValue
+		default:
+			return Auth{}, fmt.Errorf("unsupported auth kind %s, supported kinds are %v", kind, ValidAuthKinds)
+		}
+	}
+
+	return auth, nil
+}
+
+// Header returns the headers to send the
+// credentials with, nil if there are none
+func (a Auth) Header() http.Header {
+	if a.Username == "" && a.BearerToken == "" && len(a.Headers) == 0 {
+		return nil
+	}
+
+	header := make(http.Header)
+
+	for name, value := range a.Headers {
+		header.Set(name, value)
+	}
+
+	switch {
+	case a.BearerToken != "":
+		header.Set("Authorization", "Bearer "+a.BearerToken)
+	case a.Username != "":
+		request := &http.Request{Header: header}
+		request.SetBasicAuth(a.Username, a.Password)
+	}
+
+	return header
+}
+
+// String describes which credentials are set without
+// including their values, so configs holding credentials
+// can be logged
+func (a Auth) String() string {
+	var credentials []string
+
+	if a.Username != "" {
+		credentials = append(credentials, fmt.Sprintf("%s(%s)", BasicAuthKind, a.Username))
+	}
+
+	if a.BearerToken != "" {
+		credentials = append(credentials, BearerAuthKind)
+	}
+
+	var names []string
+
+	for name := range a.Headers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		credentials = append(credentials, fmt.Sprintf("%s(%s)", HeaderAuthKind, name))
+	}
+
+	return "[" + strings.Join(credentials, " ") + "]"
+}
+
+// authTransport implements the http.RoundTripper interface,
+// adding the headers for credentials to every request
+type authTransport struct {
+	transport http.RoundTripper
+	header    http.Header
+}
+
+// RoundTrip makes the request with the credentials headers added,
+// without modifying the request, returning the response and error
+// (if any)
+func (at *authTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	authenticated := request.Clone(request.Context())
+
+	for name, values := range at.header {
+		authenticated.Header[name] = values
+	}
+
+	return at.transport.RoundTrip(authenticated)
+}
@@ -0,0 +1,110 @@
+package kava
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAuth(t *testing.T) {
+	auth, err := ParseAuth("basic:doctor:pa:ss; header:X-API-Key:abc=; header: X-Tenant :kava")
+
+	assert.Nil(t, err)
+	assert.Equal(t, Auth{
+		Username: "doctor",
+		Password: "pa:ss",
+		Headers: map[string]string{
+			"X-API-Key": "abc=",
+			"X-Tenant":  "kava",
+		},
+	}, auth)
+
+	auth, err = ParseAuth("bearer:token")
+
+	assert.Nil(t, err)
+	assert.Equal(t, Auth{BearerToken: "token"}, auth)
+
+	auth, err = ParseAuth("")
+
+	assert.Nil(t, err)
+	assert.Nil(t, auth.Header(), "no credentials")
+}
+
+func TestParseAuthRejectsInvalidCredentials(t *testing.T) {
+	testCases := []struct {
+		rawAuth       string
+		expectedError string
+	}{
+		{"basic:secret", "invalid basic auth, expected basic:<username>:<password>"},
+		{"bearer:", "invalid bearer auth, expected bearer:<token>"},
+		{"header:secret", "invalid header auth, expected header:<name>:<value>"},
+		{"secret", "unsupported auth kind secret, supported kinds are [basic bearer header]"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rawAuth, func(t *testing.T) {
+			_, err := ParseAuth(tc.rawAuth)
+
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestAuthStringOmitsSecrets(t *testing.T) {
+	auth := Auth{
+		Username:    "doctor",
+		Password:    "password",
+		BearerToken: "token",
+		Headers:     map[string]string{"X-API-Key": "key"},
+	}
+
+	assert.Equal(t, "[basic(doctor) bearer header(X-API-Key)]", auth.String())
+	assert.NotContains(t, fmt.Sprintf("%+v", ClientConfig{Auth: auth}), "password")
+}
+
+func TestClientSendsCredentials(t *testing.T) {
+	var requests []*http.Request
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+
+		fmt.Fprint(w, `{"result":{"node_info":{"id":"node-1"},"sync_info":{"latest_block_height":"10","latest_block_time":"2022-01-01T00:00:00Z"}}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL: server.URL,
+		Auth: Auth{
+			Username: "doctor",
+			Password: "password",
+			Headers:  map[string]string{"X-API-Key": "key"},
+		},
+	})
+	assert.Nil(t, err)
+
+	_, err = client.GetNodeState()
+	assert.Nil(t, err)
+
+	client, err = New(ClientConfig{
+		JSONRPCURL: server.URL,
+		Auth:       Auth{BearerToken: "token"},
+	})
+	assert.Nil(t, err)
+
+	_, err = client.GetNodeState()
+	assert.Nil(t, err)
+
+	assert.Len(t, requests, 2)
+
+	username, password, ok := requests[0].BasicAuth()
+
+	assert.True(t, ok)
+	assert.Equal(t, "doctor", username)
+	assert.Equal(t, "password", password)
+	assert.Equal(t, "key", requests[0].Header.Get("X-API-Key"))
+
+	assert.Equal(t, "Bearer token", requests[1].Header.Get("Authorization"))
+}
@@ -0,0 +1,62 @@
+package kava
+
+import "strconv"
+
+const (
+	BlockEndpointPath = "/block"
+)
+
+// BlockHashes wraps the hash of a block and
+// the app hash (of the state after the previous
+// block) committed to in the block's header
+type BlockHashes struct {
+	Height    int64
+	BlockHash string
+	AppHash   string
+}
+
+// JSON-RPC generic response wrapper
+type blockResponse struct {
+	Result struct {
+		BlockId struct {
+			Hash string `json:"hash"`
+		} `json:"block_id"`
+		Block struct {
+			Header struct {
+				Height  int64  `json:"height,string"`
+				AppHash string `json:"app_hash"`
+			} `json:"header"`
+		} `json:"block"`
+	} `json:"result"`
+}
+
+// GetBlockHashes gets the block hash and app hash of the
+// block at the specified height from the kava node,
+// returning the hashes and error (if any)
+func (c *Client) GetBlockHashes(height int64) (BlockHashes, error) {
+	var block blockResponse
+
+	path := c.config.JSONRPCURL + BlockEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return BlockHashes{}, err
+	}
+
+	query := request.URL.Query()
+	query.Set("height", strconv.FormatInt(height, 10))
+	request.URL.RawQuery = query.Encode()
+
+	_, err = MakeJSONRequest(c.Client, request, &block)
+
+	if err != nil {
+		return BlockHashes{}, err
+	}
+
+	return BlockHashes{
+		Height:    block.Result.Block.Header.Height,
+		BlockHash: block.Result.BlockId.Hash,
+		AppHash:   block.Result.Block.Header.AppHash,
+	}, nil
+}
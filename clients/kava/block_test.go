@@ -0,0 +1,31 @@
+package kava
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBlockHashes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, BlockEndpointPath, r.URL.Path)
+		assert.Equal(t, "1234", r.URL.Query().Get("height"))
+
+		fmt.Fprint(w, `{"result":{"block_id":{"hash":"B10C"},"block":{"header":{"height":"1234","app_hash":"A995"}}}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	hashes, err := client.GetBlockHashes(1234)
+
+	assert.Nil(t, err)
+	assert.Equal(t, BlockHashes{Height: 1234, BlockHash: "B10C", AppHash: "A995"}, hashes)
+}
@@ -0,0 +1,216 @@
+package kava
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// chains the status of a node can be parsed for, all
+	// cometbft (tendermint) chains share the status format
+	// of kava unless served through a gateway that changes it
+	KavaChainType   = "kava"
+	CosmosChainType = "cosmos"
+	// names of the values of a node's status
+	// that can be mapped to a status field
+	NodeIdStatusValue            = "node_id"
+	MonikerStatusValue           = "moniker"
+	NetworkStatusValue           = "network"
+	LatestBlockHeightStatusValue = "latest_block_height"
+	LatestBlockTimeStatusValue   = "latest_block_time"
+	CatchingUpStatusValue        = "catching_up"
+)
+
+var (
+	ValidChainTypes = []string{KavaChainType, CosmosChainType}
+	// fields of the tendermint status response
+	DefaultStatusFields = StatusFields{
+		NodeId:            "result.node_info.id",
+		Moniker:           "result.node_info.moniker",
+		Network:           "result.node_info.network",
+		LatestBlockHeight: "result.sync_info.latest_block_height",
+		LatestBlockTime:   "result.sync_info.latest_block_time",
+		CatchingUp:        "result.sync_info.catching_up",
+	}
+)
+
+// StatusFields maps each value of a node's status to the dot
+// separated path of the field holding it in the status response
+type StatusFields struct {
+	NodeId            string
+	Moniker           string
+	Network           string
+	LatestBlockHeight string
+	LatestBlockTime   string
+	CatchingUp        string
+}
+
+// StatusConfig wraps the endpoint to request a node's status
+// from and the fields to parse the status from, for chains (or
+// gateways) whose status differs from the tendermint status
+type StatusConfig struct {
+	// path of the status endpoint, defaults to StatusEndpointPath
+	EndpointPath string
+	// fields of the status response, defaults to DefaultStatusFields
+	Fields StatusFields
+}
+
+// withDefaults returns the status config with
+// any settings that are unset set to their defaults
+func (sc StatusConfig) withDefaults() StatusConfig {
+	if sc.EndpointPath == "" {
+		sc.EndpointPath = StatusEndpointPath
+	}
+
+	if sc.Fields == (StatusFields{}) {
+		sc.Fields = DefaultStatusFields
+	}
+
+	return sc
+}
+
+// ParseStatusFields parses a comma separated list of mappings of
+// status values to fields of the status response in the form
+// <value>=<dot separated path>, e.g. latest_block_height=sync_info.height,
+// values that are omitted use the field of DefaultStatusFields,
+// returning the fields and error (if any)
+func ParseStatusFields(rawFields string) (StatusFields, error) {
+	fields := DefaultStatusFields
+
+	paths := map[string]*string{
+		NodeIdStatusValue:            &fields.NodeId,
+		MonikerStatusValue:           &fields.Moniker,
+		NetworkStatusValue:           &fields.Network,
+		LatestBlockHeightStatusValue: &fields.LatestBlockHeight,
+		LatestBlockTimeStatusValue:   &fields.LatestBlockTime,
+		CatchingUpStatusValue:        &fields.CatchingUp,
+	}
+
+	for _, rawField := range strings.Split(rawFields, ",") {
+		rawField = strings.TrimSpace(rawField)
+
+		if rawField == "" {
+			continue
+		}
+
+		value, path, found := strings.Cut(rawField, "=")
+		value, path = strings.TrimSpace(value), strings.TrimSpace(path)
+
+		if !found || path == "" {
+			return StatusFields{}, fmt.Errorf("invalid status field %s, expected <value>=<path>", rawField)
+		}
+
+		field, valid := paths[value]
+
+		if !valid {
+			var values []string
+
+			for value := range paths {
+				values = append(values, value)
+			}
+
+			sort.Strings(values)
+
+			return StatusFields{}, fmt.Errorf("unsupported status value %s, supported values are %v", value, values)
+		}
+
+		*field = path
+	}
+
+	return fields, nil
+}
+
+// parseNodeState parses the node state from the decoded json of a
+// status response using the provided fields, leaving values whose
+// field is missing from the response unset, returning the state and
+// error (if any)
+func parseNodeState(status interface{}, fields StatusFields) (NodeState, error) {
+	var nodeState NodeState
+	var rawHeight, rawTime, rawCatchingUp string
+
+	for _, field := range []struct {
+		path  string
+		value *string
+	}{
+		{fields.NodeId, &nodeState.NodeInfo.Id},
+		{fields.Moniker, &nodeState.NodeInfo.Moniker},
+		{fields.Network, &nodeState.NodeInfo.Network},
+		{fields.LatestBlockHeight, &rawHeight},
+		{fields.LatestBlockTime, &rawTime},
+		{fields.CatchingUp, &rawCatchingUp},
+	} {
+		rawValue, err := statusString(status, field.path)
+
+		if err != nil {
+			return NodeState{}, err
+		}
+
+		*field.value = rawValue
+	}
+
+	if rawHeight != "" {
+		height, err := strconv.ParseInt(rawHeight, 10, 64)
+
+		if err != nil {
+			return NodeState{}, fmt.Errorf("invalid latest block height %s at status field %s: %w", rawHeight, fields.LatestBlockHeight, err)
+		}
+
+		nodeState.SyncInfo.LatestBlockHeight = height
+	}
+
+	if rawTime != "" {
+		blockTime, err := time.Parse(time.RFC3339Nano, rawTime)
+
+		if err != nil {
+			return NodeState{}, fmt.Errorf("invalid latest block time %s at status field %s: %w", rawTime, fields.LatestBlockTime, err)
+		}
+
+		nodeState.SyncInfo.LatestBlockTime = blockTime
+	}
+
+	if rawCatchingUp != "" {
+		catchingUp, err := strconv.ParseBool(rawCatchingUp)
+
+		if err != nil {
+			return NodeState{}, fmt.Errorf("invalid catching up %s at status field %s: %w", rawCatchingUp, fields.CatchingUp, err)
+		}
+
+		nodeState.SyncInfo.CatchingUp = catchingUp
+	}
+
+	return nodeState, nil
+}
+
+// statusString returns the value of the field at the dot separated
+// path of the decoded json status as a string, whether it's a json
+// string, number or boolean, an empty string if the field is missing
+// (or null), and error (if any)
+func statusString(status interface{}, path string) (string, error) {
+	value := status
+
+	for _, key := range strings.Split(path, ".") {
+		object, isObject := value.(map[string]interface{})
+
+		if !isObject {
+			return "", nil
+		}
+
+		value = object[key]
+	}
+
+	switch typedValue := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return typedValue, nil
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(typedValue), nil
+	default:
+		return "", fmt.Errorf("status field %s is not a string, number or boolean", path)
+	}
+}
@@ -0,0 +1,101 @@
+package kava
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStatusFields(t *testing.T) {
+	fields, err := ParseStatusFields("latest_block_height = sync_info.height, node_id=node.id")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "sync_info.height", fields.LatestBlockHeight)
+	assert.Equal(t, "node.id", fields.NodeId)
+	assert.Equal(t, DefaultStatusFields.LatestBlockTime, fields.LatestBlockTime, "omitted values use the default field")
+
+	fields, err = ParseStatusFields("")
+
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultStatusFields, fields)
+
+	_, err = ParseStatusFields("latest_block_height")
+
+	assert.EqualError(t, err, "invalid status field latest_block_height, expected <value>=<path>")
+
+	_, err = ParseStatusFields("app_hash=result.app_hash")
+
+	assert.EqualError(t, err, "unsupported status value app_hash, supported values are [catching_up latest_block_height latest_block_time moniker network node_id]")
+}
+
+func TestGetNodeStateParsesTendermintStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, StatusEndpointPath, r.URL.Path)
+
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":-1,"result":{"node_info":{"id":"node-1","moniker":"osmosis-1-a","network":"osmosis-1"},"sync_info":{"latest_block_height":"11000000","latest_block_time":"2023-08-01T12:00:00.123456789Z","catching_up":true}}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{JSONRPCURL: server.URL})
+	assert.Nil(t, err)
+
+	nodeState, err := client.GetNodeState()
+
+	assert.Nil(t, err)
+	assert.Equal(t, NodeState{
+		NodeInfo: NodeInfo{Id: "node-1", Moniker: "osmosis-1-a", Network: "osmosis-1"},
+		SyncInfo: SyncInfo{
+			LatestBlockHeight: 11000000,
+			LatestBlockTime:   time.Date(2023, 8, 1, 12, 0, 0, 123456789, time.UTC),
+			CatchingUp:        true,
+		},
+	}, nodeState)
+}
+
+func TestGetNodeStateUsesConfiguredStatusFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/cosmos/status", r.URL.Path)
+
+		// a gateway that unwraps the result and reports numeric heights
+		fmt.Fprint(w, `{"node":{"id":"node-1"},"sync":{"height":11000000,"time":"2023-08-01T12:00:00Z","syncing":false}}`)
+	}))
+	defer server.Close()
+
+	fields, err := ParseStatusFields("node_id=node.id,latest_block_height=sync.height,latest_block_time=sync.time,catching_up=sync.syncing")
+	assert.Nil(t, err)
+
+	client, err := New(ClientConfig{
+		JSONRPCURL: server.URL,
+		Status: StatusConfig{
+			EndpointPath: "/cosmos/status",
+			Fields:       fields,
+		},
+	})
+	assert.Nil(t, err)
+
+	nodeState, err := client.GetNodeState()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "node-1", nodeState.NodeInfo.Id)
+	assert.Equal(t, "", nodeState.NodeInfo.Network, "fields missing from the response are left unset")
+	assert.Equal(t, int64(11000000), nodeState.SyncInfo.LatestBlockHeight)
+	assert.Equal(t, time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC), nodeState.SyncInfo.LatestBlockTime)
+}
+
+func TestGetNodeStateRejectsMalformedStatusValues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":{"node_info":{"id":"node-1"},"sync_info":{"latest_block_height":"ten","latest_block_time":"2023-08-01T12:00:00Z"}}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{JSONRPCURL: server.URL})
+	assert.Nil(t, err)
+
+	_, err = client.GetNodeState()
+
+	assert.ErrorContains(t, err, "invalid latest block height ten at status field result.sync_info.latest_block_height")
+}
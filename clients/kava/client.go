@@ -1,9 +1,12 @@
 // package kava provides definitions and implementations
 // for making requests to the JSON RPC API for a kava node
+// (or a node of any other cometbft chain)
 package kava
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
 	"time"
 )
@@ -11,14 +14,40 @@ import (
 // ClientConfig wraps parameters
 // for configuring a kava node client
 type ClientConfig struct {
+	// url or tendermint style address (e.g. tcp://127.0.0.1:26657
+	// or unix:///var/run/kava/rpc.sock) of the api
 	JSONRPCURL             string
 	HTTPReadTimeoutSeconds int
+	// optional ip address to connect to instead of the address
+	// the host of the url resolves to, for making requests to a
+	// single node behind a load balanced endpoint
+	DialAddress string
+	// max number of times to retry a request that failed with
+	// a transient error (e.g. a connection reset) or gateway
+	// error, each attempt timing out after the read timeout,
+	// 0 disables retries
+	MaxRetries int
+	// how long to wait before the first retry, doubled
+	// (with jitter) for each further retry
+	RetryBackoff time.Duration
+	// optional credentials to send with every request
+	// e.g. for endpoints behind an authenticating proxy
+	Auth Auth
+	// optional proxy and tls settings e.g. for endpoints
+	// only reachable through an egress proxy
+	Transport TransportConfig
+	// optional status endpoint and fields for
+	// chains whose status differs from kava's
+	Status StatusConfig
 }
 
 // Client is used for communicating with
 // the api for a kava node
 type Client struct {
 	config ClientConfig
+	// transport requests are made over
+	// (between retries) and websockets dialed with
+	transport *http.Transport
 	*http.Client
 	*log.Logger
 }
@@ -26,10 +55,62 @@ type Client struct {
 // New returns a new client configured with
 // the provided config, and error (if any)
 func New(config ClientConfig) (*Client, error) {
-	return &Client{
-		Client: &http.Client{
-			Timeout: time.Duration(time.Duration(config.HTTPReadTimeoutSeconds) * time.Second),
+	jsonRPCURL, socketPath, err := ParseRPCAddress(config.JSONRPCURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// requests are made to the normalized url
+	config.JSONRPCURL = jsonRPCURL
+	config.Transport.SocketPath = socketPath
+
+	transport, err := NewTransport(config.Transport)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if config.DialAddress != "" && socketPath == "" {
+		// requests keep the host of the url for the host
+		// header and tls server name, only the connection
+		// is made to the dial address
+		dialer := &net.Dialer{}
+
+		transport.DialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+			_, port, err := net.SplitHostPort(address)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return dialer.DialContext(ctx, network, net.JoinHostPort(config.DialAddress, port))
+		}
+	}
+
+	var authenticatedTransport http.RoundTripper = transport
+
+	if header := config.Auth.Header(); header != nil {
+		authenticatedTransport = &authTransport{
+			transport: transport,
+			header:    header,
+		}
+	}
+
+	// the read timeout applies to each attempt
+	// of a request instead of all attempts
+	httpClient := &http.Client{
+		Transport: &retryTransport{
+			transport:      authenticatedTransport,
+			maxRetries:     config.MaxRetries,
+			backoff:        config.RetryBackoff,
+			attemptTimeout: time.Duration(config.HTTPReadTimeoutSeconds) * time.Second,
 		},
-		config: config,
+	}
+
+	return &Client{
+		Client:    httpClient,
+		config:    config,
+		transport: transport,
 	}, nil
 }
@@ -0,0 +1,40 @@
+package kava
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConnectsToDialAddress(t *testing.T) {
+	var requestedHost string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedHost = r.Host
+
+		fmt.Fprint(w, `{"result":{"node_info":{"id":"node-1"},"sync_info":{"latest_block_height":"10","latest_block_time":"2022-01-01T00:00:00Z"}}}`)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	assert.Nil(t, err)
+
+	// the host doesn't resolve, only the dial address is reachable
+	client, err := New(ClientConfig{
+		JSONRPCURL:             "http://rpc.doctor.invalid:" + serverURL.Port(),
+		HTTPReadTimeoutSeconds: 1,
+		DialAddress:            serverURL.Hostname(),
+	})
+	assert.Nil(t, err)
+
+	nodeState, err := client.GetNodeState()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "node-1", nodeState.NodeInfo.Id)
+	assert.Equal(t, net.JoinHostPort("rpc.doctor.invalid", serverURL.Port()), requestedHost)
+}
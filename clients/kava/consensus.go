@@ -0,0 +1,118 @@
+package kava
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ConsensusStateEndpointPath = "/consensus_state"
+)
+
+// names of the tendermint round steps indexed by step number
+var roundStepNames = map[int]string{
+	1: "NewHeight",
+	2: "NewRound",
+	3: "Propose",
+	4: "Prevote",
+	5: "PrevoteWait",
+	6: "Precommit",
+	7: "PrecommitWait",
+	8: "Commit",
+}
+
+// ConsensusState wraps values for the progress
+// of a kava node's current round of consensus
+type ConsensusState struct {
+	Height int64
+	Round  int
+	// numeric tendermint round step
+	// e.g. 3 for propose, 4 for prevote
+	Step int
+	// when the node started the current height
+	StartTime time.Time
+}
+
+// StepName returns the name of the current round step
+func (cs ConsensusState) StepName() string {
+	if name, ok := roundStepNames[cs.Step]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Unknown(%d)", cs.Step)
+}
+
+// roundState wraps the raw round state returned by the
+// node where height, round and step are combined in a
+// single `height/round/step` formatted string
+type roundState struct {
+	HeightRoundStep string    `json:"height/round/step"`
+	StartTime       time.Time `json:"start_time"`
+}
+
+// JSON-RPC generic response wrapper
+type consensusStateResponse struct {
+	Result struct {
+		RoundState roundState `json:"round_state"`
+	} `json:"result"`
+}
+
+// GetConsensusState gets the current height, round and
+// step of consensus the kava node is participating in,
+// returning the consensus state and error (if any)
+func (c *Client) GetConsensusState() (ConsensusState, error) {
+	var consensusState consensusStateResponse
+
+	path := c.config.JSONRPCURL + ConsensusStateEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return ConsensusState{}, err
+	}
+
+	_, err = MakeJSONRequest(c.Client, request, &consensusState)
+
+	if err != nil {
+		return ConsensusState{}, err
+	}
+
+	return parseRoundState(consensusState.Result.RoundState)
+}
+
+// parseRoundState parses the raw round state returned
+// by the node, returning the consensus state and error (if any)
+func parseRoundState(state roundState) (ConsensusState, error) {
+	parts := strings.Split(state.HeightRoundStep, "/")
+
+	if len(parts) != 3 {
+		return ConsensusState{}, fmt.Errorf("invalid height/round/step %q", state.HeightRoundStep)
+	}
+
+	height, err := strconv.ParseInt(parts[0], 10, 64)
+
+	if err != nil {
+		return ConsensusState{}, fmt.Errorf("%w: invalid height in height/round/step %q", err, state.HeightRoundStep)
+	}
+
+	round, err := strconv.Atoi(parts[1])
+
+	if err != nil {
+		return ConsensusState{}, fmt.Errorf("%w: invalid round in height/round/step %q", err, state.HeightRoundStep)
+	}
+
+	step, err := strconv.Atoi(parts[2])
+
+	if err != nil {
+		return ConsensusState{}, fmt.Errorf("%w: invalid step in height/round/step %q", err, state.HeightRoundStep)
+	}
+
+	return ConsensusState{
+		Height:    height,
+		Round:     round,
+		Step:      step,
+		StartTime: state.StartTime,
+	}, nil
+}
@@ -0,0 +1,24 @@
+package kava
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoundState(t *testing.T) {
+	consensusState, err := parseRoundState(roundState{
+		HeightRoundStep: "1234/5/4",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1234), consensusState.Height)
+	assert.Equal(t, 5, consensusState.Round)
+	assert.Equal(t, "Prevote", consensusState.StepName())
+
+	_, err = parseRoundState(roundState{
+		HeightRoundStep: "1234/5",
+	})
+
+	assert.Error(t, err)
+}
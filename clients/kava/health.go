@@ -0,0 +1,95 @@
+package kava
+
+import (
+	"fmt"
+)
+
+const (
+	HealthEndpointPath   = "/health"
+	ABCIInfoEndpointPath = "/abci_info"
+)
+
+// ABCIInfo wraps values for the state of the application
+// (e.g. kava) a kava node is running consensus for
+type ABCIInfo struct {
+	// name of the application
+	Data    string `json:"data"`
+	Version string `json:"version"`
+	// latest block committed by the application
+	LastBlockHeight int64 `json:"last_block_height,string"`
+}
+
+// JSON-RPC error wrapper for responses
+// that only report whether the request succeeded
+type healthResponse struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// JSON-RPC generic response wrapper
+type abciInfoResponse struct {
+	Result struct {
+		Response ABCIInfo `json:"response"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// CheckHealth checks that the kava node is running,
+// returning error (if any) if the node isn't healthy
+func (c *Client) CheckHealth() error {
+	var health healthResponse
+
+	path := c.config.JSONRPCURL + HealthEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = MakeJSONRequest(c.Client, request, &health)
+
+	if err != nil {
+		return err
+	}
+
+	if health.Error != nil {
+		return fmt.Errorf("health check responded with error %d %s %s", health.Error.Code, health.Error.Message, health.Error.Data)
+	}
+
+	return nil
+}
+
+// GetABCIInfo gets the state of the application the kava node
+// is running consensus for, returning the state and error (if any)
+// e.g. if the node can't reach the application because it crashed
+func (c *Client) GetABCIInfo() (ABCIInfo, error) {
+	var abciInfo abciInfoResponse
+
+	path := c.config.JSONRPCURL + ABCIInfoEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return ABCIInfo{}, err
+	}
+
+	_, err = MakeJSONRequest(c.Client, request, &abciInfo)
+
+	if err != nil {
+		return ABCIInfo{}, err
+	}
+
+	if abciInfo.Error != nil {
+		return ABCIInfo{}, fmt.Errorf("abci info responded with error %d %s %s", abciInfo.Error.Code, abciInfo.Error.Message, abciInfo.Error.Data)
+	}
+
+	return abciInfo.Result.Response, nil
+}
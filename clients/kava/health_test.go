@@ -0,0 +1,63 @@
+package kava
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, HealthEndpointPath, r.URL.Path)
+
+		w.Write([]byte(`{"jsonrpc":"2.0","id":-1,"result":{}}`))
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, client.CheckHealth())
+}
+
+func TestGetABCIInfo(t *testing.T) {
+	var applicationDown bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, ABCIInfoEndpointPath, r.URL.Path)
+
+		if applicationDown {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":-1,"error":{"code":-32603,"message":"Internal error","data":"connection refused"}}`))
+
+			return
+		}
+
+		w.Write([]byte(`{"jsonrpc":"2.0","id":-1,"result":{"response":{"data":"kava","version":"0.26.0","last_block_height":"8123456","last_block_app_hash":"3q2+7w=="}}}`))
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	abciInfo, err := client.GetABCIInfo()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "kava", abciInfo.Data)
+	assert.Equal(t, int64(8123456), abciInfo.LastBlockHeight)
+
+	// the node answers while the application it runs is dead
+	applicationDown = true
+
+	_, err = client.GetABCIInfo()
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,42 @@
+package kava
+
+const (
+	NumUnconfirmedTxsEndpointPath = "/num_unconfirmed_txs"
+)
+
+// MempoolState wraps values for the
+// unconfirmed transactions in a kava node's mempool
+type MempoolState struct {
+	// number of transactions in the mempool
+	NumberOfTxs int64 `json:"total,string"`
+	// total size in bytes of all transactions in the mempool
+	TotalBytes int64 `json:"total_bytes,string"`
+}
+
+// JSON-RPC generic response wrapper
+type mempoolStateResponse struct {
+	Result MempoolState `json:"result"`
+}
+
+// GetMempoolState gets the number and size of
+// unconfirmed transactions in the kava node's mempool,
+// returning the mempool state and error (if any)
+func (c *Client) GetMempoolState() (MempoolState, error) {
+	var mempoolState mempoolStateResponse
+
+	path := c.config.JSONRPCURL + NumUnconfirmedTxsEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return MempoolState{}, err
+	}
+
+	_, err = MakeJSONRequest(c.Client, request, &mempoolState)
+
+	if err != nil {
+		return MempoolState{}, err
+	}
+
+	return mempoolState.Result, nil
+}
@@ -0,0 +1,102 @@
+package kava
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	NetInfoEndpointPath = "/net_info"
+	DialPeersMethod     = "dial_peers"
+)
+
+// NetInfo wraps values for the
+// peers a kava node is connected to
+type NetInfo struct {
+	Listening     bool   `json:"listening"`
+	NumberOfPeers int64  `json:"n_peers,string"`
+	Peers         []Peer `json:"peers"`
+}
+
+// Peer wraps values for a single
+// peer connected to a kava node
+type Peer struct {
+	NodeInfo         NodeInfo         `json:"node_info"`
+	IsOutbound       bool             `json:"is_outbound"`
+	ConnectionStatus ConnectionStatus `json:"connection_status"`
+	RemoteIP         string           `json:"remote_ip"`
+}
+
+// ConnectionStatus wraps values for how long a kava node has
+// been connected to a peer and the data exchanged with it
+type ConnectionStatus struct {
+	// nanoseconds the peer has been connected for
+	Duration    int64      `json:"Duration,string"`
+	SendMonitor FlowStatus `json:"SendMonitor"`
+	RecvMonitor FlowStatus `json:"RecvMonitor"`
+}
+
+// FlowStatus wraps values for the data
+// sent to or received from a peer
+type FlowStatus struct {
+	// total bytes transferred
+	Bytes int64 `json:"Bytes,string"`
+	// bytes per second transferred recently
+	// and since the peer connected
+	CurRate int64 `json:"CurRate,string"`
+	AvgRate int64 `json:"AvgRate,string"`
+}
+
+// ConnectedFor returns how long the
+// node has been connected to the peer
+func (p Peer) ConnectedFor() time.Duration {
+	return time.Duration(p.ConnectionStatus.Duration)
+}
+
+// JSON-RPC generic response wrapper
+type netInfoResponse struct {
+	Result NetInfo `json:"result"`
+}
+
+// GetNetInfo gets the current peer connections
+// of the kava node, returning the net info
+// and error (if any)
+func (c *Client) GetNetInfo() (NetInfo, error) {
+	var netInfo netInfoResponse
+
+	path := c.config.JSONRPCURL + NetInfoEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return NetInfo{}, err
+	}
+
+	_, err = MakeJSONRequest(c.Client, request, &netInfo)
+
+	if err != nil {
+		return NetInfo{}, err
+	}
+
+	return netInfo.Result, nil
+}
+
+// DialPeers asks the kava node to dial the peers (each in the form
+// <node id>@<host>:<port>), keeping them as persistent peers if
+// persistent, returning error (if any) e.g. if the node's unsafe
+// rpc methods aren't enabled
+func (c *Client) DialPeers(peers []string, persistent bool) error {
+	params, err := json.Marshal(struct {
+		Peers      []string `json:"peers"`
+		Persistent bool     `json:"persistent"`
+	}{
+		Peers:      peers,
+		Persistent: persistent,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return c.CallMethod(DialPeersMethod, params)
+}
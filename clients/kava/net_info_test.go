@@ -0,0 +1,56 @@
+package kava
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetNetInfoParsesConnectionStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, NetInfoEndpointPath, r.URL.Path)
+
+		w.Write([]byte(`{"jsonrpc":"2.0","id":-1,"result":{"listening":true,"n_peers":"1","peers":[{"node_info":{"id":"5b0a8ab2e6bd5d1fe5b4fdba0dcc7ac9d7d3a6d1"},"is_outbound":true,"connection_status":{"Duration":"90000000000","SendMonitor":{"Bytes":"2048","CurRate":"12","AvgRate":"22"},"RecvMonitor":{"Bytes":"512000","CurRate":"4096","AvgRate":"5688"}},"remote_ip":"10.0.0.1"}]}}`))
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	netInfo, err := client.GetNetInfo()
+
+	assert.Nil(t, err)
+	assert.Len(t, netInfo.Peers, 1)
+	assert.Equal(t, 90*time.Second, netInfo.Peers[0].ConnectedFor())
+	assert.Equal(t, int64(22), netInfo.Peers[0].ConnectionStatus.SendMonitor.AvgRate)
+	assert.Equal(t, int64(5688), netInfo.Peers[0].ConnectionStatus.RecvMonitor.AvgRate)
+}
+
+func TestDialPeers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request rpcRequest
+
+		err := json.NewDecoder(r.Body).Decode(&request)
+		assert.Nil(t, err)
+		assert.Equal(t, DialPeersMethod, request.Method)
+		assert.JSONEq(t, `{"peers":["5b0a8ab2e6bd5d1fe5b4fdba0dcc7ac9d7d3a6d1@10.0.0.1:26656"],"persistent":true}`, string(request.Params))
+
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"log":"Dialing peers in progress. See /net_info for details"}}`))
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, client.DialPeers([]string{"5b0a8ab2e6bd5d1fe5b4fdba0dcc7ac9d7d3a6d1@10.0.0.1:26656"}, true))
+}
@@ -0,0 +1,162 @@
+package kava
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+const (
+	// longest to wait between attempts of a request
+	// however many times it has been retried
+	MaxRetryBackoff = 5 * time.Second
+)
+
+// attemptsKey is the context key for the number of
+// attempts made to complete a request
+type attemptsKey struct{}
+
+// withAttemptCounter returns a copy of the request that records the
+// number of attempts made to complete it in the returned counter
+func withAttemptCounter(request *http.Request) (*http.Request, *int) {
+	attempts := new(int)
+
+	return request.WithContext(context.WithValue(request.Context(), attemptsKey{}, attempts)), attempts
+}
+
+// retryTransport implements the http.RoundTripper interface,
+// retrying requests that fail with a transient error (e.g. a
+// connection reset) or a gateway error with jittered exponential
+// backoff, timing out each attempt independently
+type retryTransport struct {
+	transport      http.RoundTripper
+	maxRetries     int
+	backoff        time.Duration
+	attemptTimeout time.Duration
+}
+
+// RoundTrip makes the request, retrying it up to max retries times
+// if it fails with a transient error or a gateway error response,
+// returning the response of the last attempt and error (if any)
+func (rt *retryTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	attempts, _ := request.Context().Value(attemptsKey{}).(*int)
+
+	for attempt := 0; ; attempt++ {
+		if attempts != nil {
+			*attempts = attempt + 1
+		}
+
+		response, err := rt.roundTripOnce(request)
+
+		if attempt >= rt.maxRetries || !retryable(request, response, err) {
+			return response, err
+		}
+
+		// the body of a response being retried is never read
+		if response != nil {
+			response.Body.Close()
+		}
+
+		select {
+		case <-time.After(jitteredBackoff(rt.backoff, attempt)):
+		case <-request.Context().Done():
+			return nil, request.Context().Err()
+		}
+
+		if request.GetBody != nil {
+			request.Body, err = request.GetBody()
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// roundTripOnce makes a single attempt of the request, timing
+// out the attempt (including reading the response body) after
+// the attempt timeout if set
+func (rt *retryTransport) roundTripOnce(request *http.Request) (*http.Response, error) {
+	if rt.attemptTimeout <= 0 {
+		return rt.transport.RoundTrip(request)
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), rt.attemptTimeout)
+
+	response, err := rt.transport.RoundTrip(request.WithContext(ctx))
+
+	if err != nil {
+		cancel()
+
+		return nil, err
+	}
+
+	// only stop timing the attempt once the body is read
+	response.Body = &cancelOnClose{ReadCloser: response.Body, cancel: cancel}
+
+	return response, nil
+}
+
+// retryable returns whether the request may be retried
+// after failing with the response or error, which is only
+// when the request body can be sent again, the request
+// wasn't cancelled by its caller and the failure is likely
+// transient e.g. a connection reset or a gateway timeout
+func retryable(request *http.Request, response *http.Response, err error) bool {
+	if request.Body != nil && request.Body != http.NoBody && request.GetBody == nil {
+		return false
+	}
+
+	if request.Context().Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	switch response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// jitteredBackoff returns how long to wait before retrying after
+// the specified (zero based) attempt, doubling the backoff for each
+// attempt up to MaxRetryBackoff and picking a random duration between
+// half and all of it so retries from many doctors don't align
+func jitteredBackoff(backoff time.Duration, attempt int) time.Duration {
+	for i := 0; i < attempt && backoff < MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > MaxRetryBackoff {
+		backoff = MaxRetryBackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+}
+
+// cancelOnClose cancels the context of a
+// request once its response body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the context
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+
+	c.cancel()
+
+	return err
+}
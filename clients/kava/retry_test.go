@@ -0,0 +1,126 @@
+package kava
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	TestNodeStateResponse = `{"result":{"node_info":{"id":"node-1"},"sync_info":{"latest_block_height":"10","latest_block_time":"2022-01-01T00:00:00Z"}}}`
+)
+
+func TestGetNodeStateRetriesConnectionResets(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// reset the connection of the first request
+		if atomic.AddInt32(&requests, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.Nil(t, err)
+
+			conn.Close()
+
+			return
+		}
+
+		fmt.Fprint(w, TestNodeStateResponse)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+		MaxRetries:             2,
+		RetryBackoff:           time.Millisecond,
+	})
+	assert.Nil(t, err)
+
+	nodeState, attempts, err := client.GetNodeStateAttempts()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "node-1", nodeState.NodeInfo.Id)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestGetNodeStateOnlyRetriesGatewayErrors(t *testing.T) {
+	testCases := []struct {
+		status           int
+		expectedAttempts int
+	}{
+		{http.StatusServiceUnavailable, 3},
+		{http.StatusGatewayTimeout, 3},
+		{http.StatusInternalServerError, 1},
+		{http.StatusNotFound, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(http.StatusText(tc.status), func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client, err := New(ClientConfig{
+				JSONRPCURL:   server.URL,
+				MaxRetries:   2,
+				RetryBackoff: time.Millisecond,
+			})
+			assert.Nil(t, err)
+
+			_, attempts, err := client.GetNodeStateAttempts()
+
+			assert.NotNil(t, err)
+			assert.Equal(t, tc.expectedAttempts, attempts)
+		})
+	}
+}
+
+func TestRetryTransportTimesOutEachAttempt(t *testing.T) {
+	var requests int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the first attempt takes longer than the attempt timeout
+		if atomic.AddInt32(&requests, 1) == 1 {
+			time.Sleep(200 * time.Millisecond)
+		}
+
+		fmt.Fprint(w, TestNodeStateResponse)
+	}))
+	defer server.Close()
+
+	client := &Client{
+		config: ClientConfig{JSONRPCURL: server.URL},
+		Client: &http.Client{
+			Transport: &retryTransport{
+				transport:      http.DefaultTransport,
+				maxRetries:     1,
+				backoff:        time.Millisecond,
+				attemptTimeout: 50 * time.Millisecond,
+			},
+		},
+	}
+
+	nodeState, attempts, err := client.GetNodeStateAttempts()
+
+	assert.Nil(t, err)
+	assert.Equal(t, "node-1", nodeState.NodeInfo.Id)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestJitteredBackoffDoublesUpToMax(t *testing.T) {
+	for attempt, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		backoff := jitteredBackoff(100*time.Millisecond, attempt)
+
+		assert.GreaterOrEqual(t, backoff, expected/2)
+		assert.LessOrEqual(t, backoff, expected)
+	}
+
+	assert.LessOrEqual(t, jitteredBackoff(time.Second, 20), MaxRetryBackoff)
+	assert.Equal(t, time.Duration(0), jitteredBackoff(0, 3))
+}
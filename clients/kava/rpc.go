@@ -0,0 +1,75 @@
+package kava
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// rpcRequest wraps a JSON-RPC 2.0 request
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Id      int             `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// rpcResponse wraps the error (if any)
+// of a JSON-RPC 2.0 response
+type rpcResponse struct {
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// CallMethod calls the json rpc method of the kava node with the
+// params (an empty object if nil), discarding the result and
+// returning error (if any) making the call or returned by the method
+// e.g. if tx indexing is disabled when calling tx_search
+func (c *Client) CallMethod(method string, params json.RawMessage) error {
+	if params == nil {
+		params = json.RawMessage(`{}`)
+	}
+
+	request, err := PrepareJSONRequest("POST", c.config.JSONRPCURL, rpcRequest{
+		JSONRPC: "2.0",
+		Id:      1,
+		Method:  method,
+		Params:  params,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.Client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	// tendermint responds to failed calls with a server error
+	// status, decode the body first for the reason it failed
+	var result rpcResponse
+
+	decodeErr := json.NewDecoder(response.Body).Decode(&result)
+
+	if decodeErr == nil && result.Error != nil {
+		return fmt.Errorf("%s responded with error %d %s %s", method, result.Error.Code, result.Error.Message, result.Error.Data)
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s responded with status %d", method, response.StatusCode)
+	}
+
+	if decodeErr != nil {
+		return fmt.Errorf("error %s decoding %s response", decodeErr, method)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package kava
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallMethod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request rpcRequest
+
+		err := json.NewDecoder(r.Body).Decode(&request)
+		assert.Nil(t, err)
+
+		switch request.Method {
+		case "abci_info":
+			assert.JSONEq(t, `{}`, string(request.Params))
+
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"response":{"data":"kava"}}}`))
+		case "tx_search":
+			assert.JSONEq(t, `{"query":"tx.height>0"}`, string(request.Params))
+
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"Internal error","data":"transaction indexing is disabled"}}`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	err = client.CallMethod("abci_info", nil)
+
+	assert.Nil(t, err)
+
+	err = client.CallMethod("tx_search", json.RawMessage(`{"query":"tx.height>0"}`))
+
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "transaction indexing is disabled")
+	}
+}
@@ -1,6 +1,10 @@
 package kava
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 const (
 	StatusEndpointPath = "/status"
@@ -18,6 +22,21 @@ type NodeState struct {
 type NodeInfo struct {
 	Id      string `json:"id"`
 	Moniker string `json:"moniker"`
+	// chain id of the network the node is on
+	Network string `json:"network"`
+}
+
+var ErrChainIdMismatch = errors.New("node is on a chain other than the expected chain")
+
+// ValidateChainId returns `ErrChainIdMismatch` if the node is
+// on a chain other than the expected chain, returning nil if
+// no chain is expected
+func (ni NodeInfo) ValidateChainId(expectedChainId string) error {
+	if expectedChainId == "" || ni.Network == expectedChainId {
+		return nil
+	}
+
+	return fmt.Errorf("%w, node %s is on chain %s instead of %s", ErrChainIdMismatch, ni.Id, ni.Network, expectedChainId)
 }
 
 // SyncInfo wraps values for a kava node's
@@ -29,30 +48,41 @@ type SyncInfo struct {
 	CatchingUp        bool      `json:"catching_up"`
 }
 
-// JSON-RPC generic response wrapper
-type nodeStateResponse struct {
-	Result NodeState `json:"result"`
-}
-
 // GetNodeState gets the current status
 // of the kava node, returning the state
 // and error (if any)
 func (c *Client) GetNodeState() (NodeState, error) {
-	var nodeState nodeStateResponse
+	nodeState, _, err := c.GetNodeStateAttempts()
 
-	path := c.config.JSONRPCURL + StatusEndpointPath
+	return nodeState, err
+}
+
+// GetNodeStateAttempts gets the current status of the
+// kava node, returning the state, the number of attempts
+// made to get it (more than one if the request was retried)
+// and error (if any)
+func (c *Client) GetNodeStateAttempts() (NodeState, int, error) {
+	var status interface{}
+
+	statusConfig := c.config.Status.withDefaults()
+
+	path := c.config.JSONRPCURL + statusConfig.EndpointPath
 
 	request, err := PrepareJSONRequest("GET", path, nil)
 
 	if err != nil {
-		return NodeState{}, err
+		return NodeState{}, 0, err
 	}
 
-	_, err = MakeJSONRequest(c.Client, request, &nodeState)
+	request, attempts := withAttemptCounter(request)
+
+	_, err = MakeJSONRequest(c.Client, request, &status)
 
 	if err != nil {
-		return NodeState{}, err
+		return NodeState{}, *attempts, err
 	}
 
-	return nodeState.Result, nil
+	nodeState, err := parseNodeState(status, statusConfig.Fields)
+
+	return nodeState, *attempts, err
 }
@@ -0,0 +1,20 @@
+package kava
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateChainId(t *testing.T) {
+	nodeInfo := NodeInfo{Id: "node", Network: "kava_2221-16000"}
+
+	assert.Nil(t, nodeInfo.ValidateChainId(""))
+	assert.Nil(t, nodeInfo.ValidateChainId("kava_2221-16000"))
+
+	err := nodeInfo.ValidateChainId("kava_2222-10")
+
+	assert.True(t, errors.Is(err, ErrChainIdMismatch))
+	assert.Contains(t, err.Error(), "kava_2221-16000")
+}
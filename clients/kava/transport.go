@@ -0,0 +1,140 @@
+package kava
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TransportConfig wraps the proxy, tls and socket settings
+// used for connecting to an endpoint e.g. one only
+// reachable through an egress proxy or served with a
+// certificate signed by a private ca
+type TransportConfig struct {
+	// optional url of the http(s) proxy to make requests through,
+	// defaults to the proxy set by the HTTP_PROXY, HTTPS_PROXY and
+	// NO_PROXY environment variables
+	ProxyURL string
+	// optional path of a pem encoded bundle of certificates to
+	// trust in addition to the system's certificate authorities
+	CAFilepath string
+	// optional paths of a pem encoded certificate and key to
+	// present to endpoints that require client certificates
+	ClientCertFilepath string
+	ClientKeyFilepath  string
+	// whether to skip verifying the certificate of the endpoint,
+	// only intended for testing
+	InsecureSkipVerify bool
+	// optional path of the unix domain socket to connect
+	// to instead of the host of the url of a request
+	SocketPath string
+}
+
+// String describes the transport settings,
+// omitting any credentials in the proxy url
+// so configs holding them can be logged
+func (tc TransportConfig) String() string {
+	proxyURL := tc.ProxyURL
+
+	if parsedURL, err := url.Parse(tc.ProxyURL); err == nil {
+		proxyURL = parsedURL.Redacted()
+	}
+
+	return fmt.Sprintf("{ProxyURL:%s CAFilepath:%s ClientCertFilepath:%s ClientKeyFilepath:%s InsecureSkipVerify:%t SocketPath:%s}",
+		proxyURL, tc.CAFilepath, tc.ClientCertFilepath, tc.ClientKeyFilepath, tc.InsecureSkipVerify, tc.SocketPath)
+}
+
+// NewTransport returns a copy of the default http transport
+// using the proxy and tls settings of the provided config,
+// and error (if any) e.g. the ca bundle couldn't be read
+func NewTransport(config TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+
+		if proxyURL.Scheme == "" || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url %s, expected <scheme>://<host>[:<port>]", proxyURL.Redacted())
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	if config.SocketPath != "" {
+		// requests to a local socket never go through a proxy
+		dialer := &net.Dialer{}
+
+		transport.Proxy = nil
+		transport.DialContext = func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", config.SocketPath)
+		}
+	}
+
+	return transport, nil
+}
+
+// newTLSConfig returns the tls config for the certificate
+// authorities and client certificate of the provided config,
+// nil if the defaults should be used, and error (if any)
+func newTLSConfig(config TransportConfig) (*tls.Config, error) {
+	if config.CAFilepath == "" && config.ClientCertFilepath == "" && config.ClientKeyFilepath == "" && !config.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+
+	if config.CAFilepath != "" {
+		caBundle, err := os.ReadFile(config.CAFilepath)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s reading ca bundle", err)
+		}
+
+		rootCAs, err := x509.SystemCertPool()
+
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			return nil, fmt.Errorf("no pem encoded certificates found in ca bundle %s", config.CAFilepath)
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if (config.ClientCertFilepath == "") != (config.ClientKeyFilepath == "") {
+		return nil, fmt.Errorf("both a client certificate and key are required to use a client certificate")
+	}
+
+	if config.ClientCertFilepath != "" {
+		certificate, err := tls.LoadX509KeyPair(config.ClientCertFilepath, config.ClientKeyFilepath)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s loading client certificate", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
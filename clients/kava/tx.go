@@ -0,0 +1,150 @@
+package kava
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	BroadcastTxSyncEndpointPath = "/broadcast_tx_sync"
+	TxEndpointPath              = "/tx"
+)
+
+var (
+	ErrTxNotFound = errors.New("tx not found")
+)
+
+// BroadcastTxResult wraps the result of checking
+// a transaction broadcast to the kava node
+type BroadcastTxResult struct {
+	// hex encoded hash of the transaction
+	Hash      string
+	Code      uint32
+	Codespace string
+	Log       string
+}
+
+// JSON-RPC generic response wrapper
+type broadcastTxResponse struct {
+	Result struct {
+		Code      uint32 `json:"code"`
+		Codespace string `json:"codespace"`
+		Log       string `json:"log"`
+		Hash      string `json:"hash"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// JSON-RPC generic response wrapper
+type txResponse struct {
+	Result struct {
+		Height int64 `json:"height,string"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// BroadcastTxSync broadcasts the signed transaction to the kava
+// node, returning once the node has checked the transaction with
+// the result and error (if any) broadcasting the transaction or
+// if the node rejected it (e.g. for an invalid sequence)
+func (c *Client) BroadcastTxSync(tx []byte) (BroadcastTxResult, error) {
+	var broadcast broadcastTxResponse
+
+	path := c.config.JSONRPCURL + BroadcastTxSyncEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return BroadcastTxResult{}, err
+	}
+
+	query := request.URL.Query()
+	query.Set("tx", "0x"+hex.EncodeToString(tx))
+	request.URL.RawQuery = query.Encode()
+
+	response, err := c.Client.Do(request)
+
+	if err != nil {
+		return BroadcastTxResult{}, err
+	}
+
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(&broadcast)
+
+	if err != nil {
+		return BroadcastTxResult{}, fmt.Errorf("error %s decoding broadcast response with status %d", err, response.StatusCode)
+	}
+
+	if broadcast.Error != nil {
+		return BroadcastTxResult{}, fmt.Errorf("broadcast failed with error %d %s %s", broadcast.Error.Code, broadcast.Error.Message, broadcast.Error.Data)
+	}
+
+	result := BroadcastTxResult{
+		Hash:      broadcast.Result.Hash,
+		Code:      broadcast.Result.Code,
+		Codespace: broadcast.Result.Codespace,
+		Log:       broadcast.Result.Log,
+	}
+
+	if result.Code != 0 {
+		return result, fmt.Errorf("node rejected tx %s with code %d %s %s", result.Hash, result.Code, result.Codespace, result.Log)
+	}
+
+	return result, nil
+}
+
+// GetTxHeight gets the height of the block the transaction with
+// the hex encoded hash was included in from the kava node, returning
+// the height and `ErrTxNotFound` if the transaction hasn't been
+// included (yet) or error (if any) getting the transaction
+func (c *Client) GetTxHeight(hash string) (int64, error) {
+	var tx txResponse
+
+	path := c.config.JSONRPCURL + TxEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	query := request.URL.Query()
+	query.Set("hash", "0x"+hash)
+	request.URL.RawQuery = query.Encode()
+
+	response, err := c.Client.Do(request)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	err = json.NewDecoder(response.Body).Decode(&tx)
+
+	if err != nil {
+		return 0, fmt.Errorf("error %s decoding tx response with status %d", err, response.StatusCode)
+	}
+
+	if tx.Error != nil {
+		if strings.Contains(tx.Error.Data, "not found") {
+			return 0, ErrTxNotFound
+		}
+
+		return 0, fmt.Errorf("getting tx %s failed with error %d %s %s", hash, tx.Error.Code, tx.Error.Message, tx.Error.Data)
+	}
+
+	return tx.Result.Height, nil
+}
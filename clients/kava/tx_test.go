@@ -0,0 +1,82 @@
+package kava
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBroadcastTxSync(t *testing.T) {
+	rejected := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, BroadcastTxSyncEndpointPath, r.URL.Path)
+		assert.Equal(t, "0x0a0b", r.URL.Query().Get("tx"))
+
+		if rejected {
+			fmt.Fprint(w, `{"result":{"code":32,"codespace":"sdk","log":"account sequence mismatch","hash":"ABCD"}}`)
+
+			return
+		}
+
+		fmt.Fprint(w, `{"result":{"code":0,"codespace":"","log":"[]","hash":"ABCD"}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	result, err := client.BroadcastTxSync([]byte{0x0a, 0x0b})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ABCD", result.Hash)
+
+	rejected = true
+
+	_, err = client.BroadcastTxSync([]byte{0x0a, 0x0b})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "account sequence mismatch")
+}
+
+func TestGetTxHeight(t *testing.T) {
+	included := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, TxEndpointPath, r.URL.Path)
+		assert.Equal(t, "0xABCD", r.URL.Query().Get("hash"))
+
+		if !included {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"error":{"code":-32603,"message":"Internal error","data":"tx (ABCD) not found"}}`)
+
+			return
+		}
+
+		fmt.Fprint(w, `{"result":{"hash":"ABCD","height":"1234"}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	_, err = client.GetTxHeight("ABCD")
+
+	assert.ErrorIs(t, err, ErrTxNotFound)
+
+	included = true
+
+	height, err := client.GetTxHeight("ABCD")
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1234), height)
+}
@@ -0,0 +1,43 @@
+package kava
+
+const (
+	CurrentUpgradePlanEndpointPath = "/cosmos/upgrade/v1beta1/current_plan"
+)
+
+// UpgradePlan wraps values for a chain upgrade
+// scheduled using the upgrade module
+type UpgradePlan struct {
+	Name string `json:"name"`
+	// height the chain halts at to be upgraded
+	Height int64 `json:"height,string"`
+}
+
+// upgradePlanResponse wraps the cosmos rest api
+// response, with a nil plan if no upgrade is scheduled
+type upgradePlanResponse struct {
+	Plan *UpgradePlan `json:"plan"`
+}
+
+// GetCurrentUpgradePlan gets the currently scheduled upgrade
+// (if any) from the cosmos rest api the client is configured
+// with, returning the plan (nil if no upgrade is scheduled)
+// and error (if any)
+func (c *Client) GetCurrentUpgradePlan() (*UpgradePlan, error) {
+	var upgradePlan upgradePlanResponse
+
+	path := c.config.JSONRPCURL + CurrentUpgradePlanEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = MakeJSONRequest(c.Client, request, &upgradePlan)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return upgradePlan.Plan, nil
+}
@@ -0,0 +1,39 @@
+package kava
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCurrentUpgradePlan(t *testing.T) {
+	response := `{"plan":{"name":"v0.24","height":"5000000"}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, CurrentUpgradePlanEndpointPath, r.URL.Path)
+
+		w.Write([]byte(response))
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	plan, err := client.GetCurrentUpgradePlan()
+
+	assert.Nil(t, err)
+	assert.Equal(t, &UpgradePlan{Name: "v0.24", Height: 5000000}, plan)
+
+	// no upgrade scheduled
+	response = `{"plan":null}`
+
+	plan, err = client.GetCurrentUpgradePlan()
+
+	assert.Nil(t, err)
+	assert.Nil(t, plan)
+}
@@ -0,0 +1,152 @@
+package kava
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	ValidatorsEndpointPath = "/validators"
+	CommitEndpointPath     = "/commit"
+	// max number of validators tendermint
+	// returns per page of the validator set
+	ValidatorsPerPage = 100
+	// block id flag of a signature for the committed
+	// block, as opposed to an absent or nil vote
+	BlockIdFlagCommit = 2
+)
+
+// Validator wraps values for a validator
+// in the validator set at a given height
+type Validator struct {
+	// hex encoded address of the validator's consensus key
+	Address     string `json:"address"`
+	VotingPower int64  `json:"voting_power,string"`
+}
+
+// CommitSignature wraps values for the vote
+// of a validator in the commit for a block
+type CommitSignature struct {
+	BlockIdFlag      int    `json:"block_id_flag"`
+	ValidatorAddress string `json:"validator_address"`
+}
+
+// Commit wraps the votes of the
+// validators that committed a block
+type Commit struct {
+	Height     int64             `json:"height,string"`
+	Round      int               `json:"round"`
+	Signatures []CommitSignature `json:"signatures"`
+}
+
+// JSON-RPC generic response wrapper
+type validatorsResponse struct {
+	Result struct {
+		Validators []Validator `json:"validators"`
+		Total      int         `json:"total,string"`
+	} `json:"result"`
+}
+
+// JSON-RPC generic response wrapper
+type commitResponse struct {
+	Result struct {
+		SignedHeader struct {
+			Commit Commit `json:"commit"`
+		} `json:"signed_header"`
+	} `json:"result"`
+}
+
+// GetValidators gets every validator in the validator set at
+// the specified height from the kava node, requesting each page
+// of the set, returning the validators and error (if any)
+func (c *Client) GetValidators(height int64) ([]Validator, error) {
+	var validators []Validator
+
+	for page := 1; ; page++ {
+		var response validatorsResponse
+
+		path := c.config.JSONRPCURL + ValidatorsEndpointPath
+
+		request, err := PrepareJSONRequest("GET", path, nil)
+
+		if err != nil {
+			return nil, err
+		}
+
+		query := request.URL.Query()
+		query.Set("height", strconv.FormatInt(height, 10))
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(ValidatorsPerPage))
+		request.URL.RawQuery = query.Encode()
+
+		_, err = MakeJSONRequest(c.Client, request, &response)
+
+		if err != nil {
+			return nil, err
+		}
+
+		validators = append(validators, response.Result.Validators...)
+
+		if len(response.Result.Validators) == 0 || len(validators) >= response.Result.Total {
+			break
+		}
+	}
+
+	if len(validators) == 0 {
+		return nil, fmt.Errorf("no validators in validator set at height %d", height)
+	}
+
+	return validators, nil
+}
+
+// GetCommit gets the commit for the block at the specified height
+// from the kava node, returning the commit and error (if any)
+func (c *Client) GetCommit(height int64) (Commit, error) {
+	var commit commitResponse
+
+	path := c.config.JSONRPCURL + CommitEndpointPath
+
+	request, err := PrepareJSONRequest("GET", path, nil)
+
+	if err != nil {
+		return Commit{}, err
+	}
+
+	query := request.URL.Query()
+	query.Set("height", strconv.FormatInt(height, 10))
+	request.URL.RawQuery = query.Encode()
+
+	_, err = MakeJSONRequest(c.Client, request, &commit)
+
+	if err != nil {
+		return Commit{}, err
+	}
+
+	return commit.Result.SignedHeader.Commit, nil
+}
+
+// VotingPowerOnline returns the voting power of the validators
+// that signed the commit and the total voting power of the
+// validator set the commit was made by
+func VotingPowerOnline(validators []Validator, commit Commit) (int64, int64) {
+	signed := make(map[string]bool)
+
+	for _, signature := range commit.Signatures {
+		if signature.BlockIdFlag == BlockIdFlagCommit {
+			signed[strings.ToUpper(signature.ValidatorAddress)] = true
+		}
+	}
+
+	var online, total int64
+
+	for _, validator := range validators {
+		total += validator.VotingPower
+
+		if signed[strings.ToUpper(validator.Address)] {
+			online += validator.VotingPower
+		}
+	}
+
+	return online, total
+}
@@ -0,0 +1,71 @@
+package kava
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetValidatorsRequestsEveryPage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, ValidatorsEndpointPath, r.URL.Path)
+		assert.Equal(t, "1234", r.URL.Query().Get("height"))
+
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"result":{"block_height":"1234","validators":[{"address":"AA01","voting_power":"60"},{"address":"AA02","voting_power":"30"}],"count":"2","total":"3"}}`)
+		case "2":
+			fmt.Fprint(w, `{"result":{"block_height":"1234","validators":[{"address":"AA03","voting_power":"10"}],"count":"1","total":"3"}}`)
+		default:
+			t.Errorf("unexpected page %s requested", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	validators, err := client.GetValidators(1234)
+
+	assert.Nil(t, err)
+	assert.Len(t, validators, 3)
+	assert.Equal(t, Validator{Address: "AA03", VotingPower: 10}, validators[2])
+}
+
+func TestVotingPowerOnline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, CommitEndpointPath, r.URL.Path)
+		assert.Equal(t, "1234", r.URL.Query().Get("height"))
+
+		fmt.Fprint(w, `{"result":{"signed_header":{"header":{"height":"1234"},"commit":{"height":"1234","round":1,"signatures":[{"block_id_flag":2,"validator_address":"aa01"},{"block_id_flag":1,"validator_address":""},{"block_id_flag":3,"validator_address":"AA03"}]}},"canonical":true}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	commit, err := client.GetCommit(1234)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1234), commit.Height)
+	assert.Equal(t, 1, commit.Round)
+
+	// absent and nil votes aren't counted as online
+	online, total := VotingPowerOnline([]Validator{
+		{Address: "AA01", VotingPower: 60},
+		{Address: "AA02", VotingPower: 30},
+		{Address: "AA03", VotingPower: 10},
+	}, commit)
+
+	assert.Equal(t, int64(60), online)
+	assert.Equal(t, int64(100), total)
+}
@@ -0,0 +1,240 @@
+package kava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	WebsocketEndpointPath = "/websocket"
+	// tendermint query for new block events
+	NewBlockEventQuery = "tm.event='NewBlock'"
+	// how long to wait for any message (including the pings
+	// tendermint sends every ~27 seconds) before considering
+	// the subscription dropped
+	WebsocketReadTimeout = 60 * time.Second
+)
+
+var (
+	ErrSubscriptionClosed = errors.New("websocket subscription closed")
+)
+
+// NewBlockEvent wraps the header of a new block
+// received over a websocket subscription
+type NewBlockEvent struct {
+	Height    int64
+	BlockTime time.Time
+	// when the event was received
+	ReceivedAt time.Time
+}
+
+// websocketMessage wraps a json rpc response or
+// event received over a websocket subscription
+type websocketMessage struct {
+	Result struct {
+		Data struct {
+			Value struct {
+				Block struct {
+					Header struct {
+						Height int64     `json:"height,string"`
+						Time   time.Time `json:"time"`
+					} `json:"header"`
+				} `json:"block"`
+			} `json:"value"`
+		} `json:"data"`
+	} `json:"result"`
+	Error *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Data    string `json:"data"`
+	} `json:"error"`
+}
+
+// NewBlockSubscription is a subscription to the new block
+// events of a kava node over its websocket interface
+type NewBlockSubscription struct {
+	conn      *websocket.Conn
+	closeOnce *sync.Once
+	closed    chan struct{}
+}
+
+// WebsocketURL returns the url of the websocket interface
+// of the node with the json rpc api at jsonRPCURL
+func WebsocketURL(jsonRPCURL string) (string, error) {
+	websocketURL, err := url.Parse(jsonRPCURL)
+
+	if err != nil {
+		return "", err
+	}
+
+	switch websocketURL.Scheme {
+	case "http":
+		websocketURL.Scheme = "ws"
+	case "https":
+		websocketURL.Scheme = "wss"
+	default:
+		return "", fmt.Errorf("unsupported scheme %s for websocket url of %s", websocketURL.Scheme, jsonRPCURL)
+	}
+
+	websocketURL.Path = strings.TrimSuffix(websocketURL.Path, "/") + WebsocketEndpointPath
+
+	return websocketURL.String(), nil
+}
+
+// SubscribeNewBlocks connects to the websocket interface of the
+// kava node and subscribes to new block events, returning the
+// subscription once the node has acknowledged it and error (if any)
+// The subscription is closed once the context is cancelled
+func (c *Client) SubscribeNewBlocks(ctx context.Context) (*NewBlockSubscription, error) {
+	websocketURL, err := WebsocketURL(c.config.JSONRPCURL)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: time.Duration(c.config.HTTPReadTimeoutSeconds) * time.Second,
+	}
+
+	// connect to the same address (e.g. a single node
+	// behind a load balancer) through the same proxy and
+	// with the same tls settings as the client's requests
+	if c.transport != nil {
+		dialer.NetDialContext = c.transport.DialContext
+		dialer.Proxy = c.transport.Proxy
+		dialer.TLSClientConfig = c.transport.TLSClientConfig
+	}
+
+	conn, _, err := dialer.DialContext(ctx, websocketURL, c.config.Auth.Header())
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s connecting to %s", err, websocketURL)
+	}
+
+	subscription := &NewBlockSubscription{
+		conn:      conn,
+		closeOnce: &sync.Once{},
+		closed:    make(chan struct{}),
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			subscription.Close()
+		case <-subscription.closed:
+		}
+	}()
+
+	// pings are sent by the node to check the connection
+	// is alive, so also signal the subscription is alive
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(WebsocketReadTimeout))
+
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	err = conn.WriteJSON(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "subscribe",
+		"params": map[string]string{
+			"query": NewBlockEventQuery,
+		},
+	})
+
+	if err != nil {
+		subscription.Close()
+
+		return nil, fmt.Errorf("error %s subscribing to new block events", err)
+	}
+
+	// wait for the node to acknowledge the subscription
+	acknowledgement, err := subscription.read()
+
+	if err != nil {
+		subscription.Close()
+
+		return nil, fmt.Errorf("error %s subscribing to new block events", err)
+	}
+
+	if acknowledgement.Error != nil {
+		subscription.Close()
+
+		return nil, fmt.Errorf("node rejected subscription to new block events with error %d %s %s", acknowledgement.Error.Code, acknowledgement.Error.Message, acknowledgement.Error.Data)
+	}
+
+	return subscription, nil
+}
+
+// read reads the next message of the subscription, returning
+// error (if any) if the subscription dropped or was closed
+func (s *NewBlockSubscription) read() (websocketMessage, error) {
+	var message websocketMessage
+
+	s.conn.SetReadDeadline(time.Now().Add(WebsocketReadTimeout))
+
+	err := s.conn.ReadJSON(&message)
+
+	if err != nil {
+		select {
+		case <-s.closed:
+			return message, ErrSubscriptionClosed
+		default:
+			return message, err
+		}
+	}
+
+	return message, nil
+}
+
+// Next waits for the next new block event, returning the
+// event and error (if any) if the subscription dropped or
+// returns `ErrSubscriptionClosed` if it was closed
+func (s *NewBlockSubscription) Next() (NewBlockEvent, error) {
+	for {
+		message, err := s.read()
+
+		if err != nil {
+			return NewBlockEvent{}, err
+		}
+
+		if message.Error != nil {
+			return NewBlockEvent{}, fmt.Errorf("subscription failed with error %d %s %s", message.Error.Code, message.Error.Message, message.Error.Data)
+		}
+
+		header := message.Result.Data.Value.Block.Header
+
+		// skip messages that aren't new block events
+		if header.Height == 0 {
+			continue
+		}
+
+		return NewBlockEvent{
+			Height:     header.Height,
+			BlockTime:  header.Time,
+			ReceivedAt: time.Now(),
+		}, nil
+	}
+}
+
+// Close closes the subscription, causing any
+// waiting call of Next to return `ErrSubscriptionClosed`
+// Close is safe to call multiple times and across go-routines
+func (s *NewBlockSubscription) Close() error {
+	var err error
+
+	s.closeOnce.Do(func() {
+		close(s.closed)
+
+		err = s.conn.Close()
+	})
+
+	return err
+}
@@ -0,0 +1,111 @@
+package kava
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebsocketURL(t *testing.T) {
+	websocketURL, err := WebsocketURL("https://rpc.data.kava.io")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "wss://rpc.data.kava.io/websocket", websocketURL)
+
+	websocketURL, err = WebsocketURL("http://localhost:26657/")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "ws://localhost:26657/websocket", websocketURL)
+
+	_, err = WebsocketURL("tcp://localhost:26657")
+
+	assert.NotNil(t, err)
+}
+
+func TestSubscribeNewBlocks(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, WebsocketEndpointPath, r.URL.Path)
+
+		conn, err := upgrader.Upgrade(w, r, nil)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		var subscribe struct {
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+
+		err = conn.ReadJSON(&subscribe)
+		assert.Nil(t, err)
+		assert.Equal(t, "subscribe", subscribe.Method)
+		assert.Equal(t, NewBlockEventQuery, subscribe.Params["query"])
+
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"result":{"query":"tm.event='NewBlock'","data":{"type":"tendermint/event/NewBlock","value":{"block":{"header":{"height":"42","time":"2022-07-29T15:52:29Z"}}}}}}`))
+
+		// drop the subscription
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	subscription, err := client.SubscribeNewBlocks(context.Background())
+	assert.Nil(t, err)
+	defer subscription.Close()
+
+	event, err := subscription.Next()
+
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), event.Height)
+	assert.Equal(t, time.Date(2022, 7, 29, 15, 52, 29, 0, time.UTC), event.BlockTime.UTC())
+
+	_, err = subscription.Next()
+
+	assert.NotNil(t, err)
+	assert.NotErrorIs(t, err, ErrSubscriptionClosed)
+}
+
+func TestNewBlockSubscriptionClosesWhenContextCancelled(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		assert.Nil(t, err)
+		defer conn.Close()
+
+		conn.ReadMessage()
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":1,"result":{}}`))
+
+		// wait for the client to close the connection
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	client, err := New(ClientConfig{
+		JSONRPCURL:             server.URL,
+		HTTPReadTimeoutSeconds: 1,
+	})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	subscription, err := client.SubscribeNewBlocks(ctx)
+	assert.Nil(t, err)
+
+	cancel()
+
+	_, err = subscription.Next()
+
+	assert.ErrorIs(t, err, ErrSubscriptionClosed)
+}
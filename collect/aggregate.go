@@ -0,0 +1,217 @@
+package collect
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/metric"
+)
+
+// AggregatingCollectorConfig wraps values
+// for configuring an AggregatingCollector
+type AggregatingCollectorConfig struct {
+	// collector to collect the aggregated metrics to
+	Collector Collector
+	// length of the periods samples are aggregated over
+	Period time.Duration
+	// optional logger for reporting errors collecting
+	// aggregates in the background
+	Logger *logging.Logger
+}
+
+// aggregate wraps the statistics of the samples
+// of a series collected during a single period
+type aggregate struct {
+	metric       metric.Metric
+	periodStart  time.Time
+	statisticSet metric.StatisticSet
+}
+
+// AggregatingCollector implements the Collector interface, aggregating
+// the samples of each series (metric name and dimensions) collected to
+// CloudWatch during each period into a single metric with the statistics
+// (count, sum, minimum and maximum) of the samples, bounding the number
+// of metrics sent (and the cost) regardless of how often samples are
+// taken. Metrics not collected to CloudWatch are passed through as is.
+// Aggregates are collected once their period has ended, either when a
+// later sample of the series is collected or by a background go-routine.
+// AggregatingCollector is safe to use across go-routines
+type AggregatingCollector struct {
+	collector      Collector
+	period         time.Duration
+	logger         *logging.Logger
+	aggregates     map[string]*aggregate
+	aggregatesLock *sync.Mutex
+	// closed to stop the background go-routine
+	done chan struct{}
+	// closed once the background go-routine has stopped
+	stopped chan struct{}
+}
+
+// NewAggregatingCollector creates a new AggregatingCollector using the
+// specified config, starting the background go-routine that collects
+// aggregates for series that stopped being sampled
+func NewAggregatingCollector(config AggregatingCollectorConfig) *AggregatingCollector {
+	ac := &AggregatingCollector{
+		collector:      config.Collector,
+		period:         config.Period,
+		logger:         config.Logger,
+		aggregates:     make(map[string]*aggregate),
+		aggregatesLock: &sync.Mutex{},
+		done:           make(chan struct{}),
+		stopped:        make(chan struct{}),
+	}
+
+	go ac.flushEndedPeriods()
+
+	return ac
+}
+
+// Collect adds the metric to the aggregate for its series and period,
+// collecting the series' aggregate for any earlier period to the
+// underlying collector and returning error (if any) from it
+func (ac *AggregatingCollector) Collect(sample metric.Metric) error {
+	if !sample.CollectToCloudwatch {
+		return ac.collector.Collect(sample)
+	}
+
+	periodStart := sample.Timestamp.Truncate(ac.period)
+	key := seriesKey(sample)
+
+	ac.aggregatesLock.Lock()
+
+	var ended *aggregate
+
+	current, exists := ac.aggregates[key]
+
+	if exists && !current.periodStart.Equal(periodStart) {
+		ended = current
+		exists = false
+	}
+
+	if !exists {
+		current = &aggregate{
+			metric:      sample,
+			periodStart: periodStart,
+			statisticSet: metric.StatisticSet{
+				Minimum: math.Inf(1),
+				Maximum: math.Inf(-1),
+			},
+		}
+
+		ac.aggregates[key] = current
+	}
+
+	current.add(sample)
+
+	ac.aggregatesLock.Unlock()
+
+	if ended == nil {
+		return nil
+	}
+
+	return ac.collector.Collect(ended.aggregated())
+}
+
+// add adds the sample (or pre-aggregated statistics) to the aggregate
+func (a *aggregate) add(sample metric.Metric) {
+	statistics := metric.StatisticSet{
+		SampleCount: 1,
+		Sum:         sample.Value,
+		Minimum:     sample.Value,
+		Maximum:     sample.Value,
+	}
+
+	if sample.StatisticValues != nil {
+		statistics = *sample.StatisticValues
+	}
+
+	a.statisticSet.SampleCount += statistics.SampleCount
+	a.statisticSet.Sum += statistics.Sum
+	a.statisticSet.Minimum = math.Min(a.statisticSet.Minimum, statistics.Minimum)
+	a.statisticSet.Maximum = math.Max(a.statisticSet.Maximum, statistics.Maximum)
+}
+
+// aggregated returns the metric to collect for the aggregate,
+// timestamped with the start of the period it covers
+func (a *aggregate) aggregated() metric.Metric {
+	aggregated := a.metric
+	statisticSet := a.statisticSet
+
+	aggregated.Value = 0
+	aggregated.StatisticValues = &statisticSet
+	aggregated.Timestamp = a.periodStart
+
+	return aggregated
+}
+
+// flushEndedPeriods collects the aggregates whose period has
+// ended every period until the collector is closed
+func (ac *AggregatingCollector) flushEndedPeriods() {
+	defer close(ac.stopped)
+
+	ticker := time.NewTicker(ac.period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ac.done:
+			return
+		case now := <-ticker.C:
+			err := ac.flush(now)
+
+			if err != nil && ac.logger != nil {
+				ac.logger.Errorf("error %s collecting aggregated metrics", err)
+			}
+		}
+	}
+}
+
+// flush collects the aggregates whose period ended before now
+// (or every aggregate if now is zero) to the underlying collector,
+// returning the first error (if any) collecting an aggregate
+func (ac *AggregatingCollector) flush(now time.Time) error {
+	var ended []*aggregate
+
+	ac.aggregatesLock.Lock()
+
+	for key, aggregate := range ac.aggregates {
+		if !now.IsZero() && now.Before(aggregate.periodStart.Add(ac.period)) {
+			continue
+		}
+
+		ended = append(ended, aggregate)
+
+		delete(ac.aggregates, key)
+	}
+
+	ac.aggregatesLock.Unlock()
+
+	var firstErr error
+
+	for _, aggregate := range ended {
+		if err := ac.collector.Collect(aggregate.aggregated()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// Close collects the aggregates of the current period
+// (even though it hasn't ended) and closes the underlying
+// collector, returning the first error (if any)
+func (ac *AggregatingCollector) Close() error {
+	close(ac.done)
+	<-ac.stopped
+
+	err := ac.flush(time.Time{})
+
+	if closeErr := ac.collector.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,100 @@
+package collect
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAggregatingCollectorAggregatesEachSeriesPerPeriod(t *testing.T) {
+	recorder := &recordingCollector{}
+
+	aggregatingCollector := &AggregatingCollector{
+		collector:      recorder,
+		period:         time.Minute,
+		aggregates:     make(map[string]*aggregate),
+		aggregatesLock: &sync.Mutex{},
+	}
+
+	periodStart := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, value := range []float64{3, 1, 5} {
+		for _, nodeId := range []string{"node-1", "node-2"} {
+			assert.Nil(t, aggregatingCollector.Collect(metric.Metric{
+				Name:                "SecondsBehindLive",
+				Dimensions:          map[string]string{"node_id": nodeId},
+				Value:               value,
+				Timestamp:           periodStart.Add(time.Duration(i*5) * time.Second),
+				CollectToCloudwatch: true,
+			}))
+		}
+	}
+
+	// metrics not sent to cloudwatch aren't aggregated
+	assert.Nil(t, aggregatingCollector.Collect(metric.Metric{
+		Name:      "SyncStatus",
+		Timestamp: periodStart,
+	}))
+
+	assert.Equal(t, 1, len(recorder.metrics))
+
+	// a sample in the next period ends the series' current period
+	assert.Nil(t, aggregatingCollector.Collect(metric.Metric{
+		Name:                "SecondsBehindLive",
+		Dimensions:          map[string]string{"node_id": "node-1"},
+		Value:               2,
+		Timestamp:           periodStart.Add(time.Minute),
+		CollectToCloudwatch: true,
+	}))
+
+	assert.Equal(t, 2, len(recorder.metrics))
+
+	aggregated := recorder.metrics[1]
+
+	assert.Equal(t, "SecondsBehindLive", aggregated.Name)
+	assert.Equal(t, "node-1", aggregated.Dimensions["node_id"])
+	assert.Equal(t, periodStart, aggregated.Timestamp)
+	assert.Equal(t, &metric.StatisticSet{SampleCount: 3, Sum: 9, Minimum: 1, Maximum: 5}, aggregated.StatisticValues)
+
+	// the other series' period ended without a later sample
+	assert.Nil(t, aggregatingCollector.flush(periodStart.Add(time.Minute+time.Second)))
+
+	assert.Equal(t, 3, len(recorder.metrics))
+	assert.Equal(t, "node-2", recorder.metrics[2].Dimensions["node_id"])
+	assert.Equal(t, &metric.StatisticSet{SampleCount: 3, Sum: 9, Minimum: 1, Maximum: 5}, recorder.metrics[2].StatisticValues)
+}
+
+func TestAggregatingCollectorMergesStatisticSetsAndFlushesOnClose(t *testing.T) {
+	recorder := &recordingCollector{}
+
+	aggregatingCollector := NewAggregatingCollector(AggregatingCollectorConfig{
+		Collector: recorder,
+		Period:    time.Hour,
+	})
+
+	now := time.Now()
+
+	assert.Nil(t, aggregatingCollector.Collect(metric.Metric{
+		Name:                "StatusCheckLatencyMillisecondsDistribution",
+		StatisticValues:     &metric.StatisticSet{SampleCount: 10, Sum: 100, Minimum: 2, Maximum: 30},
+		Timestamp:           now,
+		CollectToCloudwatch: true,
+	}))
+
+	assert.Nil(t, aggregatingCollector.Collect(metric.Metric{
+		Name:                "StatusCheckLatencyMillisecondsDistribution",
+		StatisticValues:     &metric.StatisticSet{SampleCount: 5, Sum: 20, Minimum: 1, Maximum: 8},
+		Timestamp:           now,
+		CollectToCloudwatch: true,
+	}))
+
+	assert.Equal(t, 0, len(recorder.metrics))
+
+	assert.Nil(t, aggregatingCollector.Close())
+
+	assert.Equal(t, 1, len(recorder.metrics))
+	assert.Equal(t, &metric.StatisticSet{SampleCount: 15, Sum: 120, Minimum: 1, Maximum: 30}, recorder.metrics[0].StatisticValues)
+}
@@ -2,7 +2,11 @@ package collect
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"time"
 
+	"github.com/kava-labs/doctor/logging"
 	"github.com/kava-labs/doctor/metric"
 
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
@@ -11,23 +15,78 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/ec2metadata"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/smithy-go"
+)
+
+const (
+	// maximum number of metric datums CloudWatch
+	// accepts in a single PutMetricData request
+	MaxCloudWatchDatumsPerRequest     = 1000
+	DefaultCloudWatchFlushInterval    = 30 * time.Second
+	DefaultCloudWatchMaxQueuedMetrics = 10000
+	DefaultCloudWatchMaxFlushRetries  = 5
+	DefaultCloudWatchRetryBackoff     = 1 * time.Second
+	// how long closing the collector waits for
+	// the queued metrics to be sent to CloudWatch
+	DefaultCloudWatchCloseTimeout = 10 * time.Second
+)
+
+var (
+	ErrCloudWatchQueueFull = errors.New("cloudwatch metric queue is full, dropping metric")
+	ErrCollectorClosed     = errors.New("collector is closed")
 )
 
 // CloudWatchCollectorConfig wraps values
 // for configuring a CloudWatch
 type CloudWatchCollectorConfig struct {
+	// context requests to CloudWatch are made with, metrics still
+	// queued when it's cancelled are sent when the collector is closed
 	Ctx             context.Context
 	AWSRegion       string
 	MetricNamespace string
+	// how often queued metrics are sent to CloudWatch
+	// (metrics are also sent as soon as a full batch is queued)
+	FlushInterval time.Duration
+	// maximum number of metrics to queue before
+	// dropping newly collected metrics
+	MaxQueuedMetrics int
+	// maximum time Close waits for the queued metrics to be sent
+	// (e.g. while CloudWatch is unreachable or throttling requests)
+	// before dropping them, defaults to DefaultCloudWatchCloseTimeout
+	CloseTimeout time.Duration
+	// optional logger for reporting errors
+	// sending metrics in the background
+	Logger *logging.Logger
+}
+
+// putMetricDataAPI is the subset of the CloudWatch
+// client used by the CloudWatchCollector
+type putMetricDataAPI interface {
+	PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error)
 }
 
 // CloudWatchCollector implements the Collector interface,
-// collecting metrics to a file
+// collecting metrics to CloudWatch by queueing them and
+// sending them in batches from a background go-routine
 type CloudWatchCollector struct {
-	cloudwatchClient *cloudwatch.Client
+	cloudwatchClient putMetricDataAPI
 	ctx              context.Context
 	metricNamespace  string
 	awsInstanceId    string
+	flushInterval    time.Duration
+	closeTimeout     time.Duration
+	maxFlushRetries  int
+	retryBackoff     time.Duration
+	logger           *logging.Logger
+	queue            chan awsTypes.MetricDatum
+	// closed once all queued metrics have been flushed
+	// after the collector is closed
+	flushed   chan struct{}
+	closeLock *sync.RWMutex
+	closed    bool
+	// context the queued metrics are sent with once the
+	// collector is closed, done after the close timeout
+	closeCtx context.Context
 }
 
 // NewCloudWatchCollector attempts to create a new CloudWatchCollector
@@ -67,17 +126,55 @@ func NewCloudWatchCollector(config CloudWatchCollectorConfig) (*CloudWatchCollec
 		awsInstanceId = nodeEC2IdentityDocument.InstanceID
 	}
 
-	return &CloudWatchCollector{
+	return newCloudWatchCollector(config, cloudwatchClient, awsInstanceId), nil
+}
+
+// newCloudWatchCollector creates a new CloudWatchCollector that sends
+// metrics using the provided client, starting the background go-routine
+// that flushes queued metrics
+func newCloudWatchCollector(config CloudWatchCollectorConfig, cloudwatchClient putMetricDataAPI, awsInstanceId string) *CloudWatchCollector {
+	flushInterval := DefaultCloudWatchFlushInterval
+
+	if config.FlushInterval > 0 {
+		flushInterval = config.FlushInterval
+	}
+
+	maxQueuedMetrics := DefaultCloudWatchMaxQueuedMetrics
+
+	if config.MaxQueuedMetrics > 0 {
+		maxQueuedMetrics = config.MaxQueuedMetrics
+	}
+
+	closeTimeout := DefaultCloudWatchCloseTimeout
+
+	if config.CloseTimeout > 0 {
+		closeTimeout = config.CloseTimeout
+	}
+
+	cwc := &CloudWatchCollector{
 		ctx:              config.Ctx,
 		cloudwatchClient: cloudwatchClient,
 		metricNamespace:  config.MetricNamespace,
 		awsInstanceId:    awsInstanceId,
-	}, nil
+		flushInterval:    flushInterval,
+		closeTimeout:     closeTimeout,
+		maxFlushRetries:  DefaultCloudWatchMaxFlushRetries,
+		retryBackoff:     DefaultCloudWatchRetryBackoff,
+		logger:           config.Logger,
+		queue:            make(chan awsTypes.MetricDatum, maxQueuedMetrics),
+		flushed:          make(chan struct{}),
+		closeLock:        &sync.RWMutex{},
+	}
+
+	go cwc.flushQueuedMetrics()
+
+	return cwc
 }
 
-// Collect collects metric to CloudWatch returning error (if any)
-// (rotation is only triggered when a metric is collection)
-// Collect is safe to call across go-routines
+// Collect queues metric to be sent to CloudWatch by the next
+// flush, returning `ErrCloudWatchQueueFull` if the queue is full
+// Collect never blocks on requests to CloudWatch and
+// is safe to call across go-routines
 func (cwc *CloudWatchCollector) Collect(metric metric.Metric) error {
 	if !metric.CollectToCloudwatch {
 		// no-op
@@ -88,8 +185,8 @@ func (cwc *CloudWatchCollector) Collect(metric metric.Metric) error {
 	awsDimensions := []awsTypes.Dimension{}
 	for key, value := range metric.Dimensions {
 		awsDimensions = append(awsDimensions, awsTypes.Dimension{
-			Name:  &key,
-			Value: &value,
+			Name:  aws.String(key),
+			Value: aws.String(value),
 		})
 	}
 
@@ -100,22 +197,192 @@ func (cwc *CloudWatchCollector) Collect(metric metric.Metric) error {
 		})
 	}
 
-	_, err := cwc.cloudwatchClient.PutMetricData(cwc.ctx, &cloudwatch.PutMetricDataInput{
-		Namespace: aws.String(cwc.metricNamespace),
-		MetricData: []awsTypes.MetricDatum{
-			{
-				MetricName: &metric.Name,
-				Dimensions: awsDimensions,
-				Timestamp:  &metric.Timestamp,
-				Value:      &metric.Value,
-				Unit:       awsTypes.StandardUnitNone,
-			},
-		},
-	})
+	datum := awsTypes.MetricDatum{
+		MetricName: aws.String(metric.Name),
+		Dimensions: awsDimensions,
+		Timestamp:  aws.Time(metric.Timestamp),
+		Unit:       cloudWatchUnit(metric.Unit),
+	}
 
-	if err != nil {
-		return err
+	// send pre-aggregated statistics (e.g. for a window of
+	// latency samples) as a statistic set so CloudWatch can
+	// compute averages, minimums and maximums across them
+	if metric.StatisticValues != nil {
+		datum.StatisticValues = &awsTypes.StatisticSet{
+			SampleCount: aws.Float64(metric.StatisticValues.SampleCount),
+			Sum:         aws.Float64(metric.StatisticValues.Sum),
+			Minimum:     aws.Float64(metric.StatisticValues.Minimum),
+			Maximum:     aws.Float64(metric.StatisticValues.Maximum),
+		}
+	} else {
+		datum.Value = aws.Float64(metric.Value)
+	}
+
+	cwc.closeLock.RLock()
+	defer cwc.closeLock.RUnlock()
+
+	if cwc.closed {
+		return ErrCollectorClosed
+	}
+
+	select {
+	case cwc.queue <- datum:
+		return nil
+	default:
+		return ErrCloudWatchQueueFull
+	}
+}
+
+// cloudWatchUnit returns the CloudWatch unit for the metric unit
+// so values can be graphed and alarmed on without unit conversions
+func cloudWatchUnit(unit metric.Unit) awsTypes.StandardUnit {
+	switch unit {
+	case metric.UnitSeconds:
+		return awsTypes.StandardUnitSeconds
+	case metric.UnitMilliseconds:
+		return awsTypes.StandardUnitMilliseconds
+	case metric.UnitCount:
+		return awsTypes.StandardUnitCount
+	case metric.UnitPercent:
+		return awsTypes.StandardUnitPercent
+	case metric.UnitBytes:
+		return awsTypes.StandardUnitBytes
+	case metric.UnitBytesPerSecond:
+		return awsTypes.StandardUnitBytesSecond
+	case metric.UnitCountPerSecond:
+		return awsTypes.StandardUnitCountSecond
+	}
+
+	return awsTypes.StandardUnitNone
+}
+
+// flushQueuedMetrics sends queued metrics to CloudWatch in
+// batches of up to MaxCloudWatchDatumsPerRequest, whenever a
+// full batch is queued or every flush interval, until the queue
+// is closed at which point any remaining metrics are flushed, giving
+// up on them after the close timeout so shutdown can't hang
+func (cwc *CloudWatchCollector) flushQueuedMetrics() {
+	defer close(cwc.flushed)
+
+	ticker := time.NewTicker(cwc.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]awsTypes.MetricDatum, 0, MaxCloudWatchDatumsPerRequest)
+
+	flush := func(ctx context.Context) {
+		if len(batch) == 0 {
+			return
+		}
+
+		err := cwc.putMetricData(ctx, batch)
+
+		if err != nil && cwc.logger != nil {
+			cwc.logger.Errorf("error %s sending %d metrics to cloudwatch, metrics dropped", err, len(batch))
+		}
+
+		batch = make([]awsTypes.MetricDatum, 0, MaxCloudWatchDatumsPerRequest)
+	}
+
+	for {
+		select {
+		case datum, ok := <-cwc.queue:
+			if !ok {
+				flush(cwc.requestContext())
+
+				return
+			}
+
+			batch = append(batch, datum)
+
+			if len(batch) == MaxCloudWatchDatumsPerRequest {
+				flush(cwc.requestContext())
+			}
+		case <-ticker.C:
+			flush(cwc.requestContext())
+		}
+	}
+}
+
+// requestContext returns the context to send metrics with, the
+// close context once the collector is closed as the doctor's
+// context is usually cancelled by the time it's closed on shutdown
+func (cwc *CloudWatchCollector) requestContext() context.Context {
+	cwc.closeLock.RLock()
+	defer cwc.closeLock.RUnlock()
+
+	if cwc.closed {
+		return cwc.closeCtx
+	}
+
+	return cwc.ctx
+}
+
+// putMetricData sends a batch of metrics to CloudWatch,
+// retrying with exponential backoff if the request
+// is throttled until the context is done, returning error (if any)
+func (cwc *CloudWatchCollector) putMetricData(ctx context.Context, batch []awsTypes.MetricDatum) error {
+	backoff := cwc.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		_, err := cwc.cloudwatchClient.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(cwc.metricNamespace),
+			MetricData: batch,
+		})
+
+		if err == nil || !isThrottlingError(err) || attempt == cwc.maxFlushRetries {
+			return err
+		}
+
+		if cwc.logger != nil {
+			cwc.logger.Debugf("cloudwatch request throttled, retrying in %v", backoff)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+// isThrottlingError returns whether err indicates
+// the request was rejected due to rate limiting
+func isThrottlingError(err error) bool {
+	var apiErr smithy.APIError
+
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	switch apiErr.ErrorCode() {
+	case "Throttling", "ThrottlingException", "TooManyRequestsException", "RequestLimitExceeded":
+		return true
 	}
 
+	return false
+}
+
+// Close stops accepting new metrics and blocks until all queued
+// metrics have been sent to CloudWatch or the close timeout passes
+// Close is safe to call across go-routines
+func (cwc *CloudWatchCollector) Close() error {
+	cwc.closeLock.Lock()
+
+	if !cwc.closed {
+		var cancel context.CancelFunc
+
+		cwc.closeCtx, cancel = context.WithTimeout(context.Background(), cwc.closeTimeout)
+		defer cancel()
+
+		cwc.closed = true
+		close(cwc.queue)
+	}
+
+	cwc.closeLock.Unlock()
+
+	<-cwc.flushed
+
 	return nil
 }
@@ -0,0 +1,155 @@
+package collect
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	awsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/smithy-go"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockCloudWatchClient struct {
+	lock           sync.Mutex
+	batchSizes     []int
+	units          []awsTypes.StandardUnit
+	throttledCalls int
+	// number of requests to throttle before succeeding
+	throttleFirstN int
+}
+
+func (m *mockCloudWatchClient) PutMetricData(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if m.throttledCalls < m.throttleFirstN {
+		m.throttledCalls++
+
+		return nil, &smithy.GenericAPIError{Code: "Throttling", Message: "Rate exceeded"}
+	}
+
+	m.batchSizes = append(m.batchSizes, len(params.MetricData))
+
+	for _, datum := range params.MetricData {
+		m.units = append(m.units, datum.Unit)
+	}
+
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
+
+func testMetric() metric.Metric {
+	return metric.Metric{
+		Name:                "LatestBlockHeight",
+		Dimensions:          map[string]string{"node_id": "test"},
+		Value:               1,
+		Timestamp:           time.Now(),
+		CollectToCloudwatch: true,
+	}
+}
+
+func TestCloudWatchCollectorSendsMetricsInBatches(t *testing.T) {
+	client := &mockCloudWatchClient{}
+
+	collector := newCloudWatchCollector(CloudWatchCollectorConfig{
+		Ctx:              context.Background(),
+		FlushInterval:    time.Hour,
+		MaxQueuedMetrics: 5000,
+	}, client, "")
+
+	for i := 0; i < 2500; i++ {
+		assert.Nil(t, collector.Collect(testMetric()))
+	}
+
+	assert.Nil(t, collector.Close())
+
+	assert.Equal(t, []int{1000, 1000, 500}, client.batchSizes)
+	assert.Equal(t, ErrCollectorClosed, collector.Collect(testMetric()))
+}
+
+func TestCloudWatchCollectorRetriesThrottledRequests(t *testing.T) {
+	client := &mockCloudWatchClient{throttleFirstN: 2}
+
+	collector := newCloudWatchCollector(CloudWatchCollectorConfig{
+		Ctx: context.Background(),
+	}, client, "")
+
+	collector.retryBackoff = time.Millisecond
+
+	assert.Nil(t, collector.Collect(testMetric()))
+	assert.Nil(t, collector.Close())
+
+	assert.Equal(t, 2, client.throttledCalls)
+	assert.Equal(t, []int{1}, client.batchSizes)
+}
+
+func TestCloudWatchCollectorSendsQueuedMetricsAfterContextCancelled(t *testing.T) {
+	client := &mockCloudWatchClient{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	collector := newCloudWatchCollector(CloudWatchCollectorConfig{
+		Ctx:           ctx,
+		FlushInterval: time.Hour,
+	}, client, "")
+
+	assert.Nil(t, collector.Collect(testMetric()))
+
+	cancel()
+
+	assert.Nil(t, collector.Close())
+
+	assert.Equal(t, []int{1}, client.batchSizes)
+}
+
+func TestCloudWatchCollectorCloseGivesUpAfterCloseTimeout(t *testing.T) {
+	// throttle every request so sending never succeeds
+	client := &mockCloudWatchClient{throttleFirstN: 1000}
+
+	collector := newCloudWatchCollector(CloudWatchCollectorConfig{
+		Ctx:           context.Background(),
+		FlushInterval: time.Hour,
+		CloseTimeout:  50 * time.Millisecond,
+	}, client, "")
+
+	collector.retryBackoff = time.Second
+
+	assert.Nil(t, collector.Collect(testMetric()))
+
+	start := time.Now()
+
+	assert.Nil(t, collector.Close())
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Empty(t, client.batchSizes)
+}
+
+func TestCloudWatchCollectorSendsMetricUnits(t *testing.T) {
+	client := &mockCloudWatchClient{}
+
+	collector := newCloudWatchCollector(CloudWatchCollectorConfig{
+		Ctx: context.Background(),
+	}, client, "")
+
+	for _, unit := range []metric.Unit{metric.UnitNone, metric.UnitMilliseconds, metric.UnitPercent} {
+		latencyMetric := testMetric()
+		latencyMetric.Unit = unit
+
+		assert.Nil(t, collector.Collect(latencyMetric))
+	}
+
+	assert.Nil(t, collector.Close())
+
+	assert.Equal(t, []awsTypes.StandardUnit{
+		awsTypes.StandardUnitNone,
+		awsTypes.StandardUnitMilliseconds,
+		awsTypes.StandardUnitPercent,
+	}, client.units)
+}
@@ -9,4 +9,23 @@ import (
 // for historical and real time monitoring purposes
 type Collector interface {
 	Collect(metric metric.Metric) error
+	// Close flushes any pending metrics and releases
+	// resources held by the collector, after which
+	// the collector should no longer be used
+	Close() error
+}
+
+// CloseAll closes all the provided collectors, returning
+// the first error encountered (if any) after attempting
+// to close every collector
+func CloseAll(collectors []Collector) error {
+	var firstErr error
+
+	for _, collector := range collectors {
+		if err := collector.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
 }
@@ -0,0 +1,507 @@
+package collect
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	DefaultCSVMetricFileNameSuffix = "doctor-metrics.csv"
+)
+
+var (
+	// columns of every metric without structured data
+	valueMetricColumns = []string{"timestamp", "dimensions", "unit", "value", "sample_count", "sum", "minimum", "maximum"}
+	timeType           = reflect.TypeOf(time.Time{})
+)
+
+// CSVCollectorConfig wraps values
+// for configuring a CSVCollector
+type CSVCollectorConfig struct {
+	MetricFileNameSuffix string
+	FileRotationInterval *time.Duration
+	// directory to create metric files in,
+	// defaults to the current working directory
+	OutputDirectory string
+	// whether to gzip metric files once they are rotated
+	CompressRotatedFiles bool
+	// if set, rotated metric files last modified longer
+	// than RetentionPeriod ago are deleted
+	RetentionPeriod time.Duration
+}
+
+// csvFile wraps the file metrics with
+// a single name are collected to
+type csvFile struct {
+	file   *os.File
+	path   string
+	writer *csv.Writer
+	// type of the structured data of the metrics
+	// in the file, nil for metrics without data
+	dataType reflect.Type
+}
+
+// CSVCollector implements the Collector interface, collecting
+// metrics to csv files, one file per metric name so each file
+// has a single header with columns that are stable across files
+// - metrics without structured data have the columns of
+// `valueMetricColumns`
+// - metrics with structured data have a timestamp and dimensions
+// column followed by a column for each (nested) field of the data
+type CSVCollector struct {
+	// files for the current rotation by metric name
+	currentFiles         map[string]*csvFile
+	currentFilesOpenedAt time.Time
+	fileRotationInterval time.Duration
+	fileLock             *sync.Mutex
+	metricFileNameSuffix string
+	outputDirectory      string
+	compressRotatedFiles bool
+	retentionPeriod      time.Duration
+}
+
+// NewCSVCollector attempts to create a new CSVCollector
+// using the specified config (or default values where appropriate)
+// returning the CSVCollector and error (if any)
+func NewCSVCollector(config CSVCollectorConfig) (*CSVCollector, error) {
+	metricFileNameSuffix := DefaultCSVMetricFileNameSuffix
+
+	if config.MetricFileNameSuffix != "" {
+		metricFileNameSuffix = config.MetricFileNameSuffix
+	}
+
+	fileRotationInterval := DefaultFileRotationInterval
+
+	if config.FileRotationInterval != nil {
+		fileRotationInterval = *config.FileRotationInterval
+	}
+
+	if config.OutputDirectory != "" {
+		err := os.MkdirAll(config.OutputDirectory, 0755)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+
+	csvCollector := &CSVCollector{
+		currentFiles:         make(map[string]*csvFile),
+		currentFilesOpenedAt: now,
+		fileRotationInterval: fileRotationInterval,
+		fileLock:             &sync.Mutex{},
+		metricFileNameSuffix: metricFileNameSuffix,
+		outputDirectory:      config.OutputDirectory,
+		compressRotatedFiles: config.CompressRotatedFiles,
+		retentionPeriod:      config.RetentionPeriod,
+	}
+
+	// clean up files left by previous runs of the doctor
+	err := removeExpiredMetricFiles(csvCollector.outputDirectory, metricFileNameSuffix, csvCollector.retentionPeriod, now, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return csvCollector, nil
+}
+
+// Collect collects metric as a row of the csv file for its
+// name, creating the file with a header row for the first
+// metric with the name since the files were last rotated,
+// returning error (if any)
+// Collect is safe to call across go-routines
+func (cc *CSVCollector) Collect(metric metric.Metric) error {
+	cc.fileLock.Lock()
+	defer cc.fileLock.Unlock()
+
+	// any error rotating is returned after the metric has
+	// been collected so the metric isn't dropped
+	var rotationErr error
+
+	if time.Since(cc.currentFilesOpenedAt) >= cc.fileRotationInterval {
+		rotationErr = cc.rotateFiles()
+	}
+
+	var dataType reflect.Type
+
+	if metric.Data != nil {
+		dataType = reflect.TypeOf(metric.Data)
+	}
+
+	file, exists := cc.currentFiles[metric.Name]
+
+	if !exists {
+		var err error
+
+		file, err = cc.openFile(metric.Name, dataType)
+
+		if err != nil {
+			return err
+		}
+
+		cc.currentFiles[metric.Name] = file
+	}
+
+	if file.dataType != dataType {
+		return fmt.Errorf("data of metric %s changed from %v to %v, not collecting it to %s", metric.Name, file.dataType, dataType, file.path)
+	}
+
+	err := file.writer.Write(csvRow(metric, time.Now()))
+
+	if err == nil {
+		file.writer.Flush()
+
+		err = file.writer.Error()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return rotationErr
+}
+
+// openFile creates the csv file for metrics with the
+// name for the current rotation, writing the header row
+// for the data type, returning the file and error (if any)
+func (cc *CSVCollector) openFile(metricName string, dataType reflect.Type) (*csvFile, error) {
+	path := filepath.Join(cc.outputDirectory, fmt.Sprintf("%d-%s-%s", cc.currentFilesOpenedAt.Unix(), metricName, cc.metricFileNameSuffix))
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	metricFile := &csvFile{
+		file:     file,
+		path:     path,
+		writer:   csv.NewWriter(file),
+		dataType: dataType,
+	}
+
+	columns := valueMetricColumns
+
+	if dataType != nil {
+		columns = append([]string{"timestamp", "dimensions"}, dataColumns(dataType)...)
+	}
+
+	err = metricFile.writer.Write(columns)
+
+	if err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	return metricFile, nil
+}
+
+// Close flushes any metrics written to the current
+// files to disk and closes them, returning the first
+// error (if any)
+// Close is safe to call across go-routines and will block
+// until any in progress collection completes
+func (cc *CSVCollector) Close() error {
+	cc.fileLock.Lock()
+	defer cc.fileLock.Unlock()
+
+	_, err := cc.closeFiles()
+
+	return err
+}
+
+// closeFiles syncs and closes the current files, returning the
+// paths of the closed files and the first error (if any)
+func (cc *CSVCollector) closeFiles() ([]string, error) {
+	var closedFilepaths []string
+	var firstErr error
+
+	for metricName, file := range cc.currentFiles {
+		err := file.file.Sync()
+
+		if closeErr := file.file.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+
+		closedFilepaths = append(closedFilepaths, file.path)
+
+		delete(cc.currentFiles, metricName)
+	}
+
+	sort.Strings(closedFilepaths)
+
+	return closedFilepaths, firstErr
+}
+
+// rotateFiles closes the files of the current rotation so
+// metrics are collected to new files, compressing the rotated
+// files and deleting expired files if configured to, returning
+// error (if any)
+func (cc *CSVCollector) rotateFiles() error {
+	now := time.Now()
+
+	// file names only have second precision,
+	// keep using the current files
+	if now.Unix() == cc.currentFilesOpenedAt.Unix() {
+		return nil
+	}
+
+	rotatedFilepaths, err := cc.closeFiles()
+
+	cc.currentFilesOpenedAt = now
+
+	if err != nil {
+		return err
+	}
+
+	if cc.compressRotatedFiles {
+		for _, rotatedFilepath := range rotatedFilepaths {
+			err = compressFile(rotatedFilepath)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return removeExpiredMetricFiles(cc.outputDirectory, cc.metricFileNameSuffix, cc.retentionPeriod, now, nil)
+}
+
+// csvRow returns the values of the columns of the csv row
+// for metric, using the time it was collected at as the
+// timestamp of metrics without one
+func csvRow(metric metric.Metric, collectedAt time.Time) []string {
+	timestamp := metric.Timestamp
+
+	if timestamp.IsZero() {
+		timestamp = collectedAt
+	}
+
+	row := []string{timestamp.UTC().Format(time.RFC3339Nano), formatDimensions(metric.Dimensions)}
+
+	if metric.Data != nil {
+		return append(row, dataValues(reflect.ValueOf(metric.Data))...)
+	}
+
+	row = append(row, string(metric.Unit))
+
+	if metric.StatisticValues == nil {
+		return append(row, formatFloat(metric.Value), "", "", "", "")
+	}
+
+	return append(row,
+		"",
+		formatFloat(metric.StatisticValues.SampleCount),
+		formatFloat(metric.StatisticValues.Sum),
+		formatFloat(metric.StatisticValues.Minimum),
+		formatFloat(metric.StatisticValues.Maximum),
+	)
+}
+
+// formatDimensions returns the dimensions as a single
+// column of key=value pairs sorted by key and separated
+// by semicolons, as metrics with the same name may have
+// different dimensions
+func formatDimensions(dimensions metric.MetricDimensions) string {
+	var pairs []string
+
+	for key, value := range dimensions {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ";")
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// csvField returns the name of the column (or prefix
+// of the nested columns) of the struct field and
+// whether the field is encoded, following its json tag
+func csvField(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+
+	return name, true
+}
+
+// flattened returns whether the fields of values of
+// the type are flattened into their own columns
+func flattened(dataType reflect.Type) bool {
+	for dataType.Kind() == reflect.Pointer {
+		dataType = dataType.Elem()
+	}
+
+	return dataType.Kind() == reflect.Struct && dataType != timeType
+}
+
+// dataColumns returns the columns for structured data of
+// the type, one per field with nested structs flattened
+// into dot separated columns, or a single data column
+// if the data isn't a struct
+func dataColumns(dataType reflect.Type) []string {
+	if !flattened(dataType) {
+		return []string{"data"}
+	}
+
+	for dataType.Kind() == reflect.Pointer {
+		dataType = dataType.Elem()
+	}
+
+	var columns []string
+
+	for i := 0; i < dataType.NumField(); i++ {
+		field := dataType.Field(i)
+		name, encoded := csvField(field)
+
+		if !encoded {
+			continue
+		}
+
+		if !flattened(field.Type) {
+			columns = append(columns, name)
+
+			continue
+		}
+
+		for _, nestedColumn := range dataColumns(field.Type) {
+			// embedded structs are flattened without a prefix like json
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				columns = append(columns, nestedColumn)
+			} else {
+				columns = append(columns, name+"."+nestedColumn)
+			}
+		}
+	}
+
+	return columns
+}
+
+// dataValues returns the values of the columns returned by
+// dataColumns for the value, leaving the columns of nil
+// pointers empty
+func dataValues(value reflect.Value) []string {
+	if !flattened(value.Type()) {
+		return []string{formatValue(value)}
+	}
+
+	dataType := value.Type()
+
+	for dataType.Kind() == reflect.Pointer {
+		dataType = dataType.Elem()
+
+		if value.IsValid() && !value.IsNil() {
+			value = value.Elem()
+		} else {
+			value = reflect.Value{}
+		}
+	}
+
+	var values []string
+
+	for i := 0; i < dataType.NumField(); i++ {
+		field := dataType.Field(i)
+
+		if _, encoded := csvField(field); !encoded {
+			continue
+		}
+
+		fieldValue := reflect.Zero(field.Type)
+
+		if value.IsValid() {
+			fieldValue = value.Field(i)
+		}
+
+		if !flattened(field.Type) {
+			values = append(values, formatValue(fieldValue))
+
+			continue
+		}
+
+		// nil pointers to structs leave all nested columns empty
+		if fieldValue.Kind() == reflect.Pointer && fieldValue.IsNil() {
+			values = append(values, make([]string, len(dataColumns(field.Type)))...)
+
+			continue
+		}
+
+		values = append(values, dataValues(fieldValue)...)
+	}
+
+	return values
+}
+
+// formatValue returns the value of a column, formatting
+// scalars as plain text and anything else (e.g. slices
+// or maps) as json
+func formatValue(value reflect.Value) string {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return ""
+		}
+
+		value = value.Elem()
+	}
+
+	if value.Type() == timeType {
+		timestamp := value.Interface().(time.Time)
+
+		if timestamp.IsZero() {
+			return ""
+		}
+
+		return timestamp.UTC().Format(time.RFC3339Nano)
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return strconv.FormatBool(value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return formatFloat(value.Float())
+	}
+
+	encodedValue, err := json.Marshal(value.Interface())
+
+	if err != nil {
+		return ""
+	}
+
+	return string(encodedValue)
+}
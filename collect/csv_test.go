@@ -0,0 +1,113 @@
+package collect
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+type testCSVData struct {
+	Height    int64     `json:"height"`
+	SampledAt time.Time `json:"sampled_at"`
+	Peers     []string  `json:"peers"`
+	Latency   struct {
+		P50 float64 `json:"p50"`
+		P99 float64 `json:"p99"`
+	} `json:"latency"`
+	Error    *string `json:"error,omitempty"`
+	internal bool
+	Ignored  bool `json:"-"`
+}
+
+func readCSVFile(t *testing.T, pattern string) [][]string {
+	metricFiles, err := filepath.Glob(pattern)
+	assert.Nil(t, err)
+	assert.Len(t, metricFiles, 1)
+
+	file, err := os.Open(metricFiles[0])
+	assert.Nil(t, err)
+	defer file.Close()
+
+	rows, err := csv.NewReader(file).ReadAll()
+	assert.Nil(t, err)
+
+	return rows
+}
+
+func TestCSVCollectorWritesAFilePerMetricName(t *testing.T) {
+	outputDirectory := t.TempDir()
+	timestamp := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	csvCollector, err := NewCSVCollector(CSVCollectorConfig{OutputDirectory: outputDirectory})
+	assert.Nil(t, err)
+
+	assert.Nil(t, csvCollector.Collect(metric.Metric{
+		Name:       "LatestBlockHeight",
+		Dimensions: metric.MetricDimensions{"node_id": "node-1", "endpoint_url": "http://localhost:26657"},
+		Value:      42,
+		Timestamp:  timestamp,
+	}))
+	assert.Nil(t, csvCollector.Collect(metric.Metric{
+		Name:            "LatestBlockHeight",
+		Value:           43,
+		StatisticValues: &metric.StatisticSet{SampleCount: 2, Sum: 85, Minimum: 42, Maximum: 43},
+		Unit:            metric.UnitCount,
+		Timestamp:       timestamp,
+	}))
+
+	data := testCSVData{
+		Height:    42,
+		SampledAt: timestamp,
+		Peers:     []string{"a", "b"},
+	}
+	data.Latency.P50 = 0.5
+
+	assert.Nil(t, csvCollector.Collect(metric.Metric{Name: "SyncStatus", Data: data, Timestamp: timestamp}))
+	assert.NotNil(t, csvCollector.Collect(metric.Metric{Name: "SyncStatus", Data: map[string]int{"height": 42}}), "data of a different type doesn't match the columns of the file")
+	assert.Nil(t, csvCollector.Close())
+
+	assert.Equal(t, [][]string{
+		{"timestamp", "dimensions", "unit", "value", "sample_count", "sum", "minimum", "maximum"},
+		{"2023-08-01T12:00:00Z", "endpoint_url=http://localhost:26657;node_id=node-1", "", "42", "", "", "", ""},
+		{"2023-08-01T12:00:00Z", "", "Count", "", "2", "85", "42", "43"},
+	}, readCSVFile(t, filepath.Join(outputDirectory, "*-LatestBlockHeight-doctor-metrics.csv")))
+
+	assert.Equal(t, [][]string{
+		{"timestamp", "dimensions", "height", "sampled_at", "peers", "latency.p50", "latency.p99", "error"},
+		{"2023-08-01T12:00:00Z", "", "42", "2023-08-01T12:00:00Z", `["a","b"]`, "0.5", "0", ""},
+	}, readCSVFile(t, filepath.Join(outputDirectory, "*-SyncStatus-doctor-metrics.csv")))
+}
+
+func TestCSVCollectorRotatesAndCompressesFiles(t *testing.T) {
+	outputDirectory := t.TempDir()
+	rotationInterval := time.Duration(0)
+
+	csvCollector, err := NewCSVCollector(CSVCollectorConfig{
+		OutputDirectory:      outputDirectory,
+		FileRotationInterval: &rotationInterval,
+		CompressRotatedFiles: true,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, csvCollector.Collect(metric.Metric{Name: "LatestBlockHeight", Value: 42}))
+
+	// file names have second precision
+	time.Sleep(time.Second)
+
+	assert.Nil(t, csvCollector.Collect(metric.Metric{Name: "LatestBlockHeight", Value: 43}))
+	assert.Nil(t, csvCollector.Close())
+
+	compressedFiles, err := filepath.Glob(filepath.Join(outputDirectory, "*-LatestBlockHeight-doctor-metrics.csv.gz"))
+	assert.Nil(t, err)
+	assert.Len(t, compressedFiles, 1)
+
+	rows := readCSVFile(t, filepath.Join(outputDirectory, "*-LatestBlockHeight-doctor-metrics.csv"))
+
+	assert.Len(t, rows, 2, "each file has its own header")
+	assert.Equal(t, "43", rows[1][3])
+}
@@ -0,0 +1,66 @@
+package collect
+
+import (
+	"github.com/kava-labs/doctor/metric"
+)
+
+// DimensionCollectorConfig wraps values
+// for configuring a DimensionCollector
+type DimensionCollectorConfig struct {
+	// collector to collect the metrics to
+	Collector Collector
+	// returns the dimensions to add to each metric at
+	// the time it's collected, nil adds no dimensions
+	Dimensions func() metric.MetricDimensions
+}
+
+// DimensionCollector implements the Collector interface, adding
+// dimensions (e.g. whether the doctor is in maintenance mode) that
+// may change over time to the metrics collected to another collector
+// DimensionCollector is safe to use across go-routines if
+// the underlying collector and dimensions function are
+type DimensionCollector struct {
+	collector  Collector
+	dimensions func() metric.MetricDimensions
+}
+
+// NewDimensionCollector creates a new DimensionCollector
+// using the specified config
+func NewDimensionCollector(config DimensionCollectorConfig) *DimensionCollector {
+	return &DimensionCollector{
+		collector:  config.Collector,
+		dimensions: config.Dimensions,
+	}
+}
+
+// Collect adds the current dimensions to the metric and collects
+// it to the underlying collector, returning error (if any)
+func (dc *DimensionCollector) Collect(metric metric.Metric) error {
+	dimensions := dc.dimensions()
+
+	if len(dimensions) == 0 {
+		return dc.collector.Collect(metric)
+	}
+
+	// dimensions are often shared across metrics
+	// so copy them rather than modifying them
+	merged := make(map[string]string, len(metric.Dimensions)+len(dimensions))
+
+	for key, value := range metric.Dimensions {
+		merged[key] = value
+	}
+
+	for key, value := range dimensions {
+		merged[key] = value
+	}
+
+	metric.Dimensions = merged
+
+	return dc.collector.Collect(metric)
+}
+
+// Close closes the underlying collector
+// returning error (if any)
+func (dc *DimensionCollector) Close() error {
+	return dc.collector.Close()
+}
@@ -0,0 +1,33 @@
+package collect
+
+import (
+	"testing"
+
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDimensionCollectorAddsCurrentDimensions(t *testing.T) {
+	recorder := &recordingCollector{}
+
+	var dimensions metric.MetricDimensions
+
+	dimensionCollector := NewDimensionCollector(DimensionCollectorConfig{
+		Collector: recorder,
+		Dimensions: func() metric.MetricDimensions {
+			return dimensions
+		},
+	})
+
+	shared := map[string]string{"node_id": "node-1"}
+
+	assert.Nil(t, dimensionCollector.Collect(metric.Metric{Name: "Uptime", Dimensions: shared}))
+
+	dimensions = metric.MetricDimensions{"maintenance": "true"}
+
+	assert.Nil(t, dimensionCollector.Collect(metric.Metric{Name: "Uptime", Dimensions: shared}))
+
+	assert.Equal(t, map[string]string{"node_id": "node-1"}, recorder.metrics[0].Dimensions)
+	assert.Equal(t, map[string]string{"node_id": "node-1", "maintenance": "true"}, recorder.metrics[1].Dimensions)
+	assert.Equal(t, map[string]string{"node_id": "node-1"}, shared, "shared dimensions should not be modified")
+}
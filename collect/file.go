@@ -1,18 +1,26 @@
 package collect
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
+	"github.com/kava-labs/doctor/audit"
 	"github.com/kava-labs/doctor/metric"
 )
 
 const (
 	DefaultMetricFileNameSuffix = "doctor-metrics.json"
 	DefaultFileRotationInterval = 1 * time.Hour
+	// extension added to rotated metric files when compressed
+	CompressedMetricFileExtension = ".gz"
+	// metric name matching every metric
+	AllMetrics = "*"
 )
 
 // FileCollectorConfig wraps values
@@ -20,16 +28,55 @@ const (
 type FileCollectorConfig struct {
 	MetricFileNameSuffix string
 	FileRotationInterval *time.Duration
+	// if set, metrics are written as a chain of signed records
+	// that can be verified to detect tampering
+	Signer audit.Signer
+	// names of the metrics to collect to the file, or `AllMetrics`
+	// to collect every metric, if empty only metrics with structured
+	// data (e.g. SyncStatus) are collected
+	MetricNames []string
+	// directory to create metric files in,
+	// defaults to the current working directory
+	OutputDirectory string
+	// whether to gzip metric files once they are rotated
+	CompressRotatedFiles bool
+	// if set, rotated metric files last modified longer
+	// than RetentionPeriod ago are deleted
+	RetentionPeriod time.Duration
+}
+
+// fileMetric is the json encoding of a metric
+// collected to a file, only including the value and
+// timestamp for metrics without structured data
+type fileMetric struct {
+	Name            string                  `json:"name"`
+	Dimensions      metric.MetricDimensions `json:"dimensions"`
+	Data            interface{}             `json:"data,omitempty"`
+	Value           *float64                `json:"value,omitempty"`
+	StatisticValues *metric.StatisticSet    `json:"statistic_values,omitempty"`
+	Timestamp       *time.Time              `json:"timestamp,omitempty"`
 }
 
 // FileCollector implements the Collector interface,
 // collecting metrics to a file
 type FileCollector struct {
 	currentFile          *os.File
+	currentFilepath      string
 	currentFileOpenedAt  time.Time
 	fileRotationInterval time.Duration
 	fileLock             *sync.Mutex
 	metricFileNameSuffix string
+	outputDirectory      string
+	compressRotatedFiles bool
+	retentionPeriod      time.Duration
+	signer               audit.Signer
+	// chain of signed records for the current file
+	// nil if signing is not enabled
+	signedRecords *audit.Chain
+	// names of metrics to collect, nil if only
+	// metrics with structured data are collected
+	metricNames map[string]bool
+	allMetrics  bool
 }
 
 // NewFileCollector attempts to create a new FileCollector
@@ -48,9 +95,17 @@ func NewFileCollector(config FileCollectorConfig) (*FileCollector, error) {
 		fileRotationInterval = *config.FileRotationInterval
 	}
 
+	if config.OutputDirectory != "" {
+		err := os.MkdirAll(config.OutputDirectory, 0755)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	now := time.Now()
 
-	fileName := fmt.Sprintf("%d-%s", now.Unix(), metricFileNameSuffix)
+	fileName := filepath.Join(config.OutputDirectory, fmt.Sprintf("%d-%s", now.Unix(), metricFileNameSuffix))
 
 	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 
@@ -58,24 +113,67 @@ func NewFileCollector(config FileCollectorConfig) (*FileCollector, error) {
 		return nil, err
 	}
 
-	return &FileCollector{
+	var signedRecords *audit.Chain
+
+	if config.Signer != nil {
+		signedRecords = audit.NewChain(config.Signer)
+	}
+
+	var metricNames map[string]bool
+	var allMetrics bool
+
+	for _, metricName := range config.MetricNames {
+		if metricName == AllMetrics {
+			allMetrics = true
+
+			continue
+		}
+
+		if metricNames == nil {
+			metricNames = make(map[string]bool)
+		}
+
+		metricNames[metricName] = true
+	}
+
+	fileCollector := &FileCollector{
+		metricNames:          metricNames,
+		allMetrics:           allMetrics,
+		signer:               config.Signer,
+		signedRecords:        signedRecords,
 		metricFileNameSuffix: metricFileNameSuffix,
+		outputDirectory:      config.OutputDirectory,
+		compressRotatedFiles: config.CompressRotatedFiles,
+		retentionPeriod:      config.RetentionPeriod,
 		currentFile:          file,
+		currentFilepath:      fileName,
 		currentFileOpenedAt:  now,
 		fileRotationInterval: fileRotationInterval,
 		fileLock:             &sync.Mutex{},
-	}, nil
+	}
+
+	// clean up files left by previous runs of the doctor
+	err = fileCollector.removeExpiredFiles(now)
+
+	if err != nil {
+		file.Close()
+
+		return nil, err
+	}
+
+	return fileCollector, nil
 }
 
 // Collect collects metric to a file, returning error (if any)
 // Collect will ensure that the file is rotated at most
 // `fileRotationInterval`
 // (rotation is only triggered when a metric is collection)
+// metrics are written as newline delimited json
 // Collect is safe to call across go-routines, and will block
 // to ensure an in progress collection completes cleanly before
 // a new metric is collected
 func (fc *FileCollector) Collect(metric metric.Metric) error {
-	if !metric.CollectToFile {
+	if !fc.collects(metric) {
 		// no-op
 		return nil
 	}
@@ -86,35 +184,108 @@ func (fc *FileCollector) Collect(metric metric.Metric) error {
 	// ensure lock is released
 	defer fc.fileLock.Unlock()
 
-	// check if we need to rotate the current file
+	// check if we need to rotate the current file, any error
+	// rotating is returned after the metric has been collected
+	// to the current file so the metric isn't dropped
+	var rotationErr error
+
 	if time.Since(fc.currentFileOpenedAt) >= fc.fileRotationInterval {
-		fc.rotateFile()
+		rotationErr = fc.rotateFile()
 	}
 
 	// encode metric to json
-	marshalledMetric, err := json.Marshal(metric)
+	encodedMetric := fileMetric{
+		Name:       metric.Name,
+		Dimensions: metric.Dimensions,
+		Data:       metric.Data,
+	}
+
+	if metric.Data == nil {
+		encodedMetric.Timestamp = &metric.Timestamp
+
+		if metric.StatisticValues != nil {
+			encodedMetric.StatisticValues = metric.StatisticValues
+		} else {
+			encodedMetric.Value = &metric.Value
+		}
+	}
+
+	marshalledMetric, err := json.Marshal(encodedMetric)
 
 	if err != nil {
 		return err
 	}
 
-	// collect the metric
-	_, err = fc.currentFile.Write(marshalledMetric)
+	// sign the metric, chaining it to the
+	// previous metric written to the file
+	if fc.signedRecords != nil {
+		signedRecord, err := fc.signedRecords.Sign(marshalledMetric)
+
+		if err != nil {
+			return err
+		}
+
+		marshalledMetric, err = json.Marshal(signedRecord)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// collect the metric, one per line
+	_, err = fc.currentFile.Write(append(marshalledMetric, '\n'))
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return rotationErr
+}
+
+// collects returns whether metric is routed to this collector
+func (fc *FileCollector) collects(metric metric.Metric) bool {
+	if fc.allMetrics {
+		return true
+	}
+
+	if fc.metricNames == nil {
+		return metric.Data != nil
+	}
+
+	return fc.metricNames[metric.Name]
+}
+
+// Close flushes any metrics written to the current
+// file to disk and closes it, returning error (if any)
+// Close is safe to call across go-routines and will block
+// until any in progress collection completes
+func (fc *FileCollector) Close() error {
+	fc.fileLock.Lock()
+	defer fc.fileLock.Unlock()
+
+	err := fc.currentFile.Sync()
+
+	if err != nil {
+		return err
+	}
+
+	return fc.currentFile.Close()
 }
 
 // rotateFile attempts to close the current
 // collection file and open a new one for use,
-// returning error (if any)
+// compressing the rotated file and deleting expired
+// files if configured to, returning error (if any)
 func (fc *FileCollector) rotateFile() error {
 	now := time.Now()
 
-	fileName := fmt.Sprintf("%d-%s", now.Unix(), fc.metricFileNameSuffix)
+	fileName := filepath.Join(fc.outputDirectory, fmt.Sprintf("%d-%s", now.Unix(), fc.metricFileNameSuffix))
+
+	// file names only have second precision, keep
+	// using the current file instead of compressing it
+	if fileName == fc.currentFilepath {
+		return nil
+	}
 
 	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 
@@ -122,8 +293,129 @@ func (fc *FileCollector) rotateFile() error {
 		return err
 	}
 
+	// release the file handle for the rotated file
+	err = fc.currentFile.Close()
+
+	if err != nil {
+		return err
+	}
+
+	rotatedFilepath := fc.currentFilepath
+
 	fc.currentFile = file
+	fc.currentFilepath = fileName
 	fc.currentFileOpenedAt = now
 
+	// start a new chain of signed records for the new file
+	if fc.signer != nil {
+		fc.signedRecords = audit.NewChain(fc.signer)
+	}
+
+	if fc.compressRotatedFiles {
+		err = compressFile(rotatedFilepath)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return fc.removeExpiredFiles(now)
+}
+
+// removeExpiredFiles deletes metric files (other than the current
+// file) that were last modified longer than the retention period
+// before now, returning error (if any)
+func (fc *FileCollector) removeExpiredFiles(now time.Time) error {
+	return removeExpiredMetricFiles(fc.outputDirectory, fc.metricFileNameSuffix, fc.retentionPeriod, now, map[string]bool{fc.currentFilepath: true})
+}
+
+// removeExpiredMetricFiles deletes the metric files with the suffix
+// in the output directory (other than the current files) that were
+// last modified longer than the retention period before now, returning
+// error (if any)
+func removeExpiredMetricFiles(outputDirectory string, metricFileNameSuffix string, retentionPeriod time.Duration, now time.Time, currentFilepaths map[string]bool) error {
+	if retentionPeriod <= 0 {
+		return nil
+	}
+
+	var metricFilepaths []string
+
+	for _, pattern := range []string{"*-" + metricFileNameSuffix, "*-" + metricFileNameSuffix + CompressedMetricFileExtension} {
+		matches, err := filepath.Glob(filepath.Join(outputDirectory, pattern))
+
+		if err != nil {
+			return err
+		}
+
+		metricFilepaths = append(metricFilepaths, matches...)
+	}
+
+	for _, metricFilepath := range metricFilepaths {
+		if currentFilepaths[metricFilepath] {
+			continue
+		}
+
+		fileInfo, err := os.Stat(metricFilepath)
+
+		if err != nil {
+			return err
+		}
+
+		if now.Sub(fileInfo.ModTime()) < retentionPeriod {
+			continue
+		}
+
+		err = os.Remove(metricFilepath)
+
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// compressFile replaces the file at path with a gzip
+// compressed copy, returning error (if any)
+func compressFile(path string) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	compressedFile, err := os.OpenFile(path+CompressedMetricFileExtension, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(compressedFile)
+
+	_, err = io.Copy(writer, file)
+
+	if err == nil {
+		err = writer.Close()
+	}
+
+	if err == nil {
+		err = compressedFile.Sync()
+	}
+
+	closeErr := compressedFile.Close()
+
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		// don't leave a partially compressed copy
+		os.Remove(path + CompressedMetricFileExtension)
+
+		return err
+	}
+
+	return os.Remove(path)
+}
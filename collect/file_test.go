@@ -0,0 +1,105 @@
+package collect
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileCollectorOnlyCollectsRoutedMetrics(t *testing.T) {
+	workingDirectory, err := os.Getwd()
+	assert.Nil(t, err)
+
+	// metric files are created relative to the working directory
+	assert.Nil(t, os.Chdir(t.TempDir()))
+	defer os.Chdir(workingDirectory)
+
+	fileCollector, err := NewFileCollector(FileCollectorConfig{
+		MetricFileNameSuffix: "samples.json",
+		MetricNames:          []string{"LatestBlockHeight"},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, fileCollector.Collect(metric.Metric{Name: "LatestBlockHeight", Value: 42}))
+	assert.Nil(t, fileCollector.Collect(metric.Metric{Name: "SyncStatus", Data: map[string]int{"height": 42}}))
+	assert.Nil(t, fileCollector.Close())
+
+	metricFiles, err := filepath.Glob("*-samples.json")
+	assert.Nil(t, err)
+	assert.Len(t, metricFiles, 1)
+
+	contents, err := os.ReadFile(metricFiles[0])
+	assert.Nil(t, err)
+	assert.Contains(t, string(contents), `"name":"LatestBlockHeight"`)
+	assert.Contains(t, string(contents), `"value":42`)
+	assert.NotContains(t, string(contents), "SyncStatus")
+}
+
+func TestFileCollectorWritesNewlineDelimitedMetricsAndRemovesExpiredFiles(t *testing.T) {
+	outputDirectory := filepath.Join(t.TempDir(), "metrics")
+
+	assert.Nil(t, os.MkdirAll(outputDirectory, 0755))
+
+	expiredFilepath := filepath.Join(outputDirectory, "1-samples.json.gz")
+	retainedFilepath := filepath.Join(outputDirectory, "2-samples.json")
+
+	for _, path := range []string{expiredFilepath, retainedFilepath} {
+		assert.Nil(t, os.WriteFile(path, []byte("{}\n"), 0644))
+	}
+
+	expiredAt := time.Now().Add(-48 * time.Hour)
+	assert.Nil(t, os.Chtimes(expiredFilepath, expiredAt, expiredAt))
+
+	fileCollector, err := NewFileCollector(FileCollectorConfig{
+		MetricFileNameSuffix: "samples.json",
+		MetricNames:          []string{AllMetrics},
+		OutputDirectory:      outputDirectory,
+		CompressRotatedFiles: true,
+		RetentionPeriod:      24 * time.Hour,
+	})
+	assert.Nil(t, err)
+
+	assert.NoFileExists(t, expiredFilepath)
+	assert.FileExists(t, retainedFilepath)
+
+	assert.Nil(t, fileCollector.Collect(metric.Metric{Name: "LatestBlockHeight", Value: 42}))
+	assert.Nil(t, fileCollector.Collect(metric.Metric{Name: "LatestBlockHeight", Value: 43}))
+	assert.Nil(t, fileCollector.Close())
+
+	currentFilepath := fileCollector.currentFilepath
+
+	contents, err := os.ReadFile(currentFilepath)
+	assert.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+	assert.Len(t, lines, 2)
+
+	for _, line := range lines {
+		var decoded fileMetric
+		assert.Nil(t, json.Unmarshal([]byte(line), &decoded))
+	}
+
+	// rotated files are replaced with a compressed copy
+	assert.Nil(t, compressFile(currentFilepath))
+	assert.NoFileExists(t, currentFilepath)
+
+	compressedFile, err := os.Open(currentFilepath + CompressedMetricFileExtension)
+	assert.Nil(t, err)
+
+	defer compressedFile.Close()
+
+	gzipReader, err := gzip.NewReader(compressedFile)
+	assert.Nil(t, err)
+
+	decompressed, err := io.ReadAll(gzipReader)
+	assert.Nil(t, err)
+	assert.Equal(t, contents, decompressed)
+}
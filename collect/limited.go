@@ -0,0 +1,157 @@
+package collect
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	// window over which the rate of emitted metrics is limited
+	EmissionRateLimitWindow = 1 * time.Minute
+)
+
+// EmissionLimit wraps limits on how often
+// metrics with a given name are emitted
+type EmissionLimit struct {
+	// name of the metric to limit, or `AllMetrics`
+	// for all metrics without a limit of their own
+	MetricName string
+	// if greater than 1, only 1 of every SampleEvery
+	// metrics are emitted, 0 emits every metric
+	SampleEvery int
+	// if greater than 0, max number of metrics emitted
+	// per minute, metrics over the limit are dropped
+	MaxPerMinute int
+}
+
+// LimitedCollectorConfig wraps values
+// for configuring a LimitedCollector
+type LimitedCollectorConfig struct {
+	// collector to emit metrics within the limits to
+	Collector Collector
+	Limits    []EmissionLimit
+}
+
+// emissionState tracks the metrics seen and emitted
+// for a single series (metric name and dimensions)
+type emissionState struct {
+	seen          int
+	windowStart   time.Time
+	windowEmitted int
+}
+
+// LimitedCollector implements the Collector interface, sampling
+// and rate limiting the metrics collected to another collector
+// to control the write volume (and cost) of a metric backend
+// limits are applied separately to each series of metrics
+// (e.g. the same metric for different nodes)
+// LimitedCollector is safe to use across go-routines
+type LimitedCollector struct {
+	collector  Collector
+	limits     map[string]EmissionLimit
+	states     map[string]*emissionState
+	statesLock *sync.Mutex
+}
+
+// NewLimitedCollector creates a new LimitedCollector
+// using the specified config
+func NewLimitedCollector(config LimitedCollectorConfig) *LimitedCollector {
+	limits := make(map[string]EmissionLimit)
+
+	for _, limit := range config.Limits {
+		limits[limit.MetricName] = limit
+	}
+
+	return &LimitedCollector{
+		collector:  config.Collector,
+		limits:     limits,
+		states:     make(map[string]*emissionState),
+		statesLock: &sync.Mutex{},
+	}
+}
+
+// Collect collects the metric to the underlying collector
+// if it's within the limits for the metric, returning error
+// (if any) from the underlying collector, metrics dropped
+// due to the limits are not treated as an error
+func (lc *LimitedCollector) Collect(metric metric.Metric) error {
+	if !lc.allow(metric, time.Now()) {
+		return nil
+	}
+
+	return lc.collector.Collect(metric)
+}
+
+// allow returns whether the metric collected
+// at now is within the limits for the metric
+func (lc *LimitedCollector) allow(metric metric.Metric, now time.Time) bool {
+	limit, exists := lc.limits[metric.Name]
+
+	if !exists {
+		limit, exists = lc.limits[AllMetrics]
+	}
+
+	if !exists {
+		return true
+	}
+
+	lc.statesLock.Lock()
+	defer lc.statesLock.Unlock()
+
+	key := seriesKey(metric)
+
+	state, exists := lc.states[key]
+
+	if !exists {
+		state = &emissionState{}
+		lc.states[key] = state
+	}
+
+	seen := state.seen
+	state.seen++
+
+	if limit.SampleEvery > 1 && seen%limit.SampleEvery != 0 {
+		return false
+	}
+
+	if limit.MaxPerMinute <= 0 {
+		return true
+	}
+
+	if now.Sub(state.windowStart) >= EmissionRateLimitWindow {
+		state.windowStart = now
+		state.windowEmitted = 0
+	}
+
+	if state.windowEmitted >= limit.MaxPerMinute {
+		return false
+	}
+
+	state.windowEmitted++
+
+	return true
+}
+
+// Close closes the underlying collector
+// returning error (if any)
+func (lc *LimitedCollector) Close() error {
+	return lc.collector.Close()
+}
+
+// seriesKey returns a key identifying the series of the
+// metric by its name and (sorted) dimensions
+func seriesKey(metric metric.Metric) string {
+	dimensions := make([]string, 0, len(metric.Dimensions))
+
+	for key, value := range metric.Dimensions {
+		dimensions = append(dimensions, key+"="+value)
+	}
+
+	sort.Strings(dimensions)
+
+	return metric.Name + "/" + strings.Join(dimensions, ",")
+}
@@ -0,0 +1,66 @@
+package collect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingCollector records the metrics it collects
+type recordingCollector struct {
+	metrics []metric.Metric
+}
+
+func (rc *recordingCollector) Collect(metric metric.Metric) error {
+	rc.metrics = append(rc.metrics, metric)
+
+	return nil
+}
+
+func (rc *recordingCollector) Close() error {
+	return nil
+}
+
+func TestLimitedCollectorSamplesAndRateLimitsEachSeries(t *testing.T) {
+	recorder := &recordingCollector{}
+
+	limitedCollector := NewLimitedCollector(LimitedCollectorConfig{
+		Collector: recorder,
+		Limits: []EmissionLimit{
+			{MetricName: "StatusCheckLatencyMilliseconds", SampleEvery: 3},
+			{MetricName: AllMetrics, MaxPerMinute: 2},
+		},
+	})
+
+	for i := 0; i < 6; i++ {
+		for _, nodeId := range []string{"node-1", "node-2"} {
+			assert.Nil(t, limitedCollector.Collect(metric.Metric{
+				Name:       "StatusCheckLatencyMilliseconds",
+				Dimensions: map[string]string{"node_id": nodeId},
+				Value:      float64(i),
+			}))
+		}
+
+		assert.Nil(t, limitedCollector.Collect(metric.Metric{Name: "LatestBlockHeight", Value: float64(i)}))
+	}
+
+	var latencies, heights []float64
+
+	for _, collected := range recorder.metrics {
+		switch collected.Name {
+		case "StatusCheckLatencyMilliseconds":
+			latencies = append(latencies, collected.Value)
+		case "LatestBlockHeight":
+			heights = append(heights, collected.Value)
+		}
+	}
+
+	// 1 of every 3 for each node, not limited by the default rate
+	assert.Equal(t, []float64{0, 0, 3, 3}, latencies)
+	assert.Equal(t, []float64{0, 1}, heights)
+
+	// rate limit resets once the window has passed
+	assert.True(t, limitedCollector.allow(metric.Metric{Name: "LatestBlockHeight"}, time.Now().Add(EmissionRateLimitWindow)))
+}
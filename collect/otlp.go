@@ -0,0 +1,618 @@
+package collect
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/checks"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	// paths of the otlp/http endpoints of an
+	// opentelemetry collector for each signal
+	OTLPMetricsPath             = "/v1/metrics"
+	OTLPTracesPath              = "/v1/traces"
+	OTLPScopeName               = "github.com/kava-labs/doctor"
+	OTLPServiceNameAttribute    = "service.name"
+	DefaultOTLPServiceName      = "doctor"
+	DefaultOTLPFlushInterval    = 10 * time.Second
+	DefaultOTLPMaxQueuedMetrics = 10000
+	DefaultOTLPMaxFlushRetries  = 3
+	DefaultOTLPRetryBackoff     = 1 * time.Second
+	DefaultOTLPHTTPTimeout      = 10 * time.Second
+	// maximum number of metrics (and spans)
+	// sent in a single export request
+	MaxOTLPMetricsPerRequest = 1000
+	// values of the otlp span status code
+	otlpStatusCodeOk    = 1
+	otlpStatusCodeError = 2
+	// value of the otlp internal span kind
+	otlpSpanKindInternal = 1
+)
+
+var (
+	ErrOTLPQueueFull = errors.New("otlp metric queue is full, dropping metric")
+)
+
+// OTLPCollectorConfig wraps values
+// for configuring an OTLPCollector
+type OTLPCollectorConfig struct {
+	// base url of the otlp/http receiver of an opentelemetry
+	// collector, e.g. http://otel-collector:4318
+	Endpoint string
+	// headers sent with every export request, e.g. for
+	// authenticating with a hosted collector
+	Headers map[string]string
+	// attributes of the resource (the doctor) every metric
+	// and span is exported for, service.name defaults to doctor
+	ResourceAttributes map[string]string
+	// whether to export a span for each run of a health check
+	Traces bool
+	// how often queued metrics are exported
+	// (metrics are also exported as soon as a full batch is queued)
+	FlushInterval time.Duration
+	// maximum number of metrics to queue before
+	// dropping newly collected metrics
+	MaxQueuedMetrics int
+	// optional logger for reporting errors
+	// exporting metrics in the background
+	Logger *logging.Logger
+}
+
+// OTLPCollector implements the Collector interface, exporting
+// metrics (and optionally spans for health checks) to an
+// opentelemetry collector over otlp/http using the json
+// encoding, queueing them and exporting them in batches from
+// a background go-routine
+// Only metrics collected to CloudWatch are exported, as
+// only they have a value that can be aggregated
+type OTLPCollector struct {
+	endpoint        string
+	headers         map[string]string
+	resource        otlpResource
+	traces          bool
+	flushInterval   time.Duration
+	maxFlushRetries int
+	retryBackoff    time.Duration
+	httpClient      *http.Client
+	logger          *logging.Logger
+	queue           chan metric.Metric
+	// closed once all queued metrics have been exported
+	// after the collector is closed
+	flushed   chan struct{}
+	closeLock *sync.RWMutex
+	closed    bool
+}
+
+// NewOTLPCollector creates a new OTLPCollector using the
+// specified config (or default values where appropriate),
+// starting the background go-routine that exports queued
+// metrics, returning the collector and error (if any)
+func NewOTLPCollector(config OTLPCollectorConfig) (*OTLPCollector, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("an endpoint is required for the otlp collector")
+	}
+
+	flushInterval := DefaultOTLPFlushInterval
+
+	if config.FlushInterval > 0 {
+		flushInterval = config.FlushInterval
+	}
+
+	maxQueuedMetrics := DefaultOTLPMaxQueuedMetrics
+
+	if config.MaxQueuedMetrics > 0 {
+		maxQueuedMetrics = config.MaxQueuedMetrics
+	}
+
+	resourceAttributes := map[string]string{
+		OTLPServiceNameAttribute: DefaultOTLPServiceName,
+	}
+
+	for key, value := range config.ResourceAttributes {
+		resourceAttributes[key] = value
+	}
+
+	oc := &OTLPCollector{
+		endpoint:        strings.TrimSuffix(config.Endpoint, "/"),
+		headers:         config.Headers,
+		resource:        otlpResource{Attributes: otlpAttributes(resourceAttributes)},
+		traces:          config.Traces,
+		flushInterval:   flushInterval,
+		maxFlushRetries: DefaultOTLPMaxFlushRetries,
+		retryBackoff:    DefaultOTLPRetryBackoff,
+		httpClient:      &http.Client{Timeout: DefaultOTLPHTTPTimeout},
+		logger:          config.Logger,
+		queue:           make(chan metric.Metric, maxQueuedMetrics),
+		flushed:         make(chan struct{}),
+		closeLock:       &sync.RWMutex{},
+	}
+
+	go oc.flushQueuedMetrics()
+
+	return oc, nil
+}
+
+// otlp json encoding of the payloads of export requests, see
+// https://github.com/open-telemetry/opentelemetry-proto
+// 64 bit integers are encoded as strings and trace and span
+// ids as hex encoded strings
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+}
+
+type otlpQuantileValue struct {
+	Quantile float64 `json:"quantile"`
+	Value    float64 `json:"value"`
+}
+
+type otlpSummaryDataPoint struct {
+	Attributes     []otlpKeyValue      `json:"attributes,omitempty"`
+	TimeUnixNano   string              `json:"timeUnixNano"`
+	Count          string              `json:"count"`
+	Sum            float64             `json:"sum"`
+	QuantileValues []otlpQuantileValue `json:"quantileValues"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpSummary struct {
+	DataPoints []otlpSummaryDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name    string       `json:"name"`
+	Unit    string       `json:"unit,omitempty"`
+	Gauge   *otlpGauge   `json:"gauge,omitempty"`
+	Summary *otlpSummary `json:"summary,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetricsRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// Collect queues metric to be exported by the next flush,
+// returning `ErrOTLPQueueFull` if the queue is full
+// Collect never blocks on export requests and
+// is safe to call across go-routines
+func (oc *OTLPCollector) Collect(metric metric.Metric) error {
+	_, isCheckResult := metric.Data.(checks.CheckResult)
+
+	if !metric.CollectToCloudwatch && !(oc.traces && isCheckResult) {
+		// no-op
+		return nil
+	}
+
+	oc.closeLock.RLock()
+	defer oc.closeLock.RUnlock()
+
+	if oc.closed {
+		return ErrCollectorClosed
+	}
+
+	select {
+	case oc.queue <- metric:
+		return nil
+	default:
+		return ErrOTLPQueueFull
+	}
+}
+
+// flushQueuedMetrics exports queued metrics in batches of up
+// to MaxOTLPMetricsPerRequest, whenever a full batch is queued
+// or every flush interval, until the queue is closed at which
+// point any remaining metrics are exported
+func (oc *OTLPCollector) flushQueuedMetrics() {
+	defer close(oc.flushed)
+
+	ticker := time.NewTicker(oc.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]metric.Metric, 0, MaxOTLPMetricsPerRequest)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		err := oc.export(batch)
+
+		if err != nil && oc.logger != nil {
+			oc.logger.Errorf("error %s exporting %d metrics to %s, metrics dropped", err, len(batch), oc.endpoint)
+		}
+
+		batch = make([]metric.Metric, 0, MaxOTLPMetricsPerRequest)
+	}
+
+	for {
+		select {
+		case queuedMetric, ok := <-oc.queue:
+			if !ok {
+				flush()
+
+				return
+			}
+
+			batch = append(batch, queuedMetric)
+
+			if len(batch) == MaxOTLPMetricsPerRequest {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// export sends the metrics in the batch (and spans for
+// the health checks in the batch if enabled) to the
+// collector, returning the first error (if any)
+func (oc *OTLPCollector) export(batch []metric.Metric) error {
+	var metricsErr, tracesErr error
+
+	if metrics := otlpMetrics(batch); len(metrics) > 0 {
+		metricsErr = oc.post(OTLPMetricsPath, otlpMetricsRequest{
+			ResourceMetrics: []otlpResourceMetrics{
+				{
+					Resource: oc.resource,
+					ScopeMetrics: []otlpScopeMetrics{
+						{Scope: otlpScope{Name: OTLPScopeName}, Metrics: metrics},
+					},
+				},
+			},
+		})
+	}
+
+	if !oc.traces {
+		return metricsErr
+	}
+
+	if spans := otlpSpans(batch); len(spans) > 0 {
+		tracesErr = oc.post(OTLPTracesPath, otlpTracesRequest{
+			ResourceSpans: []otlpResourceSpans{
+				{
+					Resource: oc.resource,
+					ScopeSpans: []otlpScopeSpans{
+						{Scope: otlpScope{Name: OTLPScopeName}, Spans: spans},
+					},
+				},
+			},
+		})
+	}
+
+	if metricsErr != nil {
+		return metricsErr
+	}
+
+	return tracesErr
+}
+
+// post sends the payload to the otlp endpoint at path, retrying
+// with exponential backoff if the collector is unavailable or
+// throttles the request, returning error (if any)
+func (oc *OTLPCollector) post(path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	backoff := oc.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		retryable, err := oc.postOnce(oc.endpoint+path, body)
+
+		if err == nil || !retryable || attempt == oc.maxFlushRetries {
+			return err
+		}
+
+		if oc.logger != nil {
+			oc.logger.Debugf("error %s exporting to %s, retrying in %v", err, oc.endpoint+path, backoff)
+		}
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+	}
+}
+
+// postOnce makes a single export request, returning
+// whether it should be retried and error (if any)
+func (oc *OTLPCollector) postOnce(url string, body []byte) (bool, error) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+
+	if err != nil {
+		return false, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	for name, value := range oc.headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := oc.httpClient.Do(request)
+
+	if err != nil {
+		return true, err
+	}
+
+	defer response.Body.Close()
+
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, response.Body)
+
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode <= 299:
+		return false, nil
+	case response.StatusCode == http.StatusTooManyRequests,
+		response.StatusCode == http.StatusBadGateway,
+		response.StatusCode == http.StatusServiceUnavailable,
+		response.StatusCode == http.StatusGatewayTimeout:
+		return true, fmt.Errorf("otlp endpoint responded with status %d", response.StatusCode)
+	}
+
+	return false, fmt.Errorf("otlp endpoint responded with status %d", response.StatusCode)
+}
+
+// Close stops accepting new metrics and blocks until
+// all queued metrics have been exported
+// Close is safe to call across go-routines
+func (oc *OTLPCollector) Close() error {
+	oc.closeLock.Lock()
+
+	if !oc.closed {
+		oc.closed = true
+		close(oc.queue)
+	}
+
+	oc.closeLock.Unlock()
+
+	<-oc.flushed
+
+	return nil
+}
+
+// otlpMetrics returns the otlp metrics for the metrics in the
+// batch that are collected to CloudWatch, one metric per name
+// and unit with a data point for each sample, pre-aggregated
+// statistics are exported as a summary with the minimum and
+// maximum as the 0 and 1 quantiles
+func otlpMetrics(batch []metric.Metric) []otlpMetric {
+	var metrics []otlpMetric
+
+	indexes := make(map[string]int)
+
+	for _, sample := range batch {
+		if !sample.CollectToCloudwatch {
+			continue
+		}
+
+		unit := otlpUnit(sample.Unit)
+		key := sample.Name + "\x00" + unit
+		attributes := otlpAttributes(sample.Dimensions)
+		timestamp := strconv.FormatInt(sample.Timestamp.UnixNano(), 10)
+
+		index, exists := indexes[key]
+
+		if !exists {
+			index = len(metrics)
+			indexes[key] = index
+
+			metrics = append(metrics, otlpMetric{Name: sample.Name, Unit: unit})
+		}
+
+		if sample.StatisticValues != nil {
+			if metrics[index].Summary == nil {
+				metrics[index].Summary = &otlpSummary{}
+			}
+
+			metrics[index].Summary.DataPoints = append(metrics[index].Summary.DataPoints, otlpSummaryDataPoint{
+				Attributes:   attributes,
+				TimeUnixNano: timestamp,
+				Count:        strconv.FormatInt(int64(sample.StatisticValues.SampleCount), 10),
+				Sum:          sample.StatisticValues.Sum,
+				QuantileValues: []otlpQuantileValue{
+					{Quantile: 0, Value: sample.StatisticValues.Minimum},
+					{Quantile: 1, Value: sample.StatisticValues.Maximum},
+				},
+			})
+
+			continue
+		}
+
+		if metrics[index].Gauge == nil {
+			metrics[index].Gauge = &otlpGauge{}
+		}
+
+		metrics[index].Gauge.DataPoints = append(metrics[index].Gauge.DataPoints, otlpNumberDataPoint{
+			Attributes:   attributes,
+			TimeUnixNano: timestamp,
+			AsDouble:     sample.Value,
+		})
+	}
+
+	// a metric can't hold both a gauge and a summary, split
+	// metrics sampled both with and without statistics
+	for i := range metrics {
+		if metrics[i].Gauge != nil && metrics[i].Summary != nil {
+			metrics = append(metrics, otlpMetric{
+				Name:    metrics[i].Name,
+				Unit:    metrics[i].Unit,
+				Summary: metrics[i].Summary,
+			})
+
+			metrics[i].Summary = nil
+		}
+	}
+
+	return metrics
+}
+
+// otlpSpans returns a span (in its own trace) for
+// each run of a health check in the batch
+func otlpSpans(batch []metric.Metric) []otlpSpan {
+	var spans []otlpSpan
+
+	for _, sample := range batch {
+		result, isCheckResult := sample.Data.(checks.CheckResult)
+
+		if !isCheckResult {
+			continue
+		}
+
+		status := otlpStatus{Code: otlpStatusCodeOk}
+
+		if !result.Healthy {
+			status = otlpStatus{Code: otlpStatusCodeError, Message: result.Message}
+		}
+
+		spans = append(spans, otlpSpan{
+			TraceId:           randomHexId(16),
+			SpanId:            randomHexId(8),
+			Name:              "health_check " + result.Check,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(result.StartedAt.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(result.StartedAt.Add(result.Duration).UnixNano(), 10),
+			Attributes: otlpAttributes(map[string]string{
+				"check":    result.Check,
+				"healthy":  strconv.FormatBool(result.Healthy),
+				"attempts": strconv.Itoa(result.Attempts),
+				"message":  result.Message,
+			}),
+			Status: status,
+		})
+	}
+
+	return spans
+}
+
+// otlpAttributes returns the attributes
+// for the key value pairs sorted by key
+func otlpAttributes(pairs map[string]string) []otlpKeyValue {
+	var keys []string
+
+	for key := range pairs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var attributes []otlpKeyValue
+
+	for _, key := range keys {
+		attributes = append(attributes, otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: pairs[key]}})
+	}
+
+	return attributes
+}
+
+// otlpUnit returns the ucum unit opentelemetry uses
+// for the metric unit, empty for unitless metrics
+func otlpUnit(unit metric.Unit) string {
+	switch unit {
+	case metric.UnitSeconds:
+		return "s"
+	case metric.UnitMilliseconds:
+		return "ms"
+	case metric.UnitCount:
+		return "{count}"
+	case metric.UnitPercent:
+		return "%"
+	case metric.UnitBytes:
+		return "By"
+	case metric.UnitBytesPerSecond:
+		return "By/s"
+	case metric.UnitCountPerSecond:
+		return "{count}/s"
+	}
+
+	return ""
+}
+
+// randomHexId returns a random hex encoded id of
+// the specified number of bytes for a trace or span
+func randomHexId(size int) string {
+	id := make([]byte, size)
+
+	// ids only need to be unique, not secret
+	rand.Read(id)
+
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,170 @@
+package collect
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/checks"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+// testOTLPReceiver records the payloads exported to each otlp path
+type testOTLPReceiver struct {
+	sync.Mutex
+	payloads map[string][]map[string]interface{}
+	headers  http.Header
+}
+
+func newTestOTLPReceiver(t *testing.T) (*testOTLPReceiver, *httptest.Server) {
+	receiver := &testOTLPReceiver{payloads: make(map[string][]map[string]interface{})}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		receiver.Lock()
+		defer receiver.Unlock()
+
+		receiver.payloads[r.URL.Path] = append(receiver.payloads[r.URL.Path], payload)
+		receiver.headers = r.Header
+	}))
+
+	return receiver, server
+}
+
+func TestOTLPCollectorExportsCloudWatchMetrics(t *testing.T) {
+	receiver, server := newTestOTLPReceiver(t)
+	defer server.Close()
+
+	otlpCollector, err := NewOTLPCollector(OTLPCollectorConfig{
+		Endpoint:           server.URL + "/",
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		ResourceAttributes: map[string]string{"deployment.environment": "mainnet"},
+	})
+	assert.Nil(t, err)
+
+	timestamp := time.Unix(1690891200, 0)
+
+	assert.Nil(t, otlpCollector.Collect(metric.Metric{
+		Name:                "LatestBlockHeight",
+		Dimensions:          metric.MetricDimensions{"node_id": "node-1"},
+		Value:               42,
+		Unit:                metric.UnitCount,
+		Timestamp:           timestamp,
+		CollectToCloudwatch: true,
+	}))
+	assert.Nil(t, otlpCollector.Collect(metric.Metric{
+		Name:                "SecondsBehindLive",
+		StatisticValues:     &metric.StatisticSet{SampleCount: 2, Sum: 3, Minimum: 1, Maximum: 2},
+		Unit:                metric.UnitSeconds,
+		Timestamp:           timestamp,
+		CollectToCloudwatch: true,
+	}))
+	assert.Nil(t, otlpCollector.Collect(metric.Metric{Name: "SyncStatus", Data: map[string]int{"height": 42}}), "metrics not collected to CloudWatch are ignored")
+	assert.Nil(t, otlpCollector.Close())
+	assert.Equal(t, ErrCollectorClosed, otlpCollector.Collect(metric.Metric{Name: "LatestBlockHeight", CollectToCloudwatch: true}))
+
+	receiver.Lock()
+	defer receiver.Unlock()
+
+	assert.Len(t, receiver.payloads[OTLPMetricsPath], 1, "queued metrics are exported in one batch")
+	assert.Len(t, receiver.payloads[OTLPTracesPath], 0, "spans are only exported when traces are enabled")
+	assert.Equal(t, "Bearer token", receiver.headers.Get("Authorization"))
+
+	exported, err := json.Marshal(receiver.payloads[OTLPMetricsPath][0])
+	assert.Nil(t, err)
+
+	assert.JSONEq(t, `{"resourceMetrics":[{
+		"resource":{"attributes":[
+			{"key":"deployment.environment","value":{"stringValue":"mainnet"}},
+			{"key":"service.name","value":{"stringValue":"doctor"}}
+		]},
+		"scopeMetrics":[{
+			"scope":{"name":"github.com/kava-labs/doctor"},
+			"metrics":[
+				{"name":"LatestBlockHeight","unit":"{count}","gauge":{"dataPoints":[
+					{"attributes":[{"key":"node_id","value":{"stringValue":"node-1"}}],"timeUnixNano":"1690891200000000000","asDouble":42}
+				]}},
+				{"name":"SecondsBehindLive","unit":"s","summary":{"dataPoints":[
+					{"timeUnixNano":"1690891200000000000","count":"2","sum":3,"quantileValues":[{"quantile":0,"value":1},{"quantile":1,"value":2}]}
+				]}}
+			]
+		}]
+	}]}`, string(exported))
+}
+
+func TestOTLPCollectorExportsHealthCheckSpans(t *testing.T) {
+	receiver, server := newTestOTLPReceiver(t)
+	defer server.Close()
+
+	otlpCollector, err := NewOTLPCollector(OTLPCollectorConfig{Endpoint: server.URL, Traces: true})
+	assert.Nil(t, err)
+
+	startedAt := time.Unix(1690891200, 0)
+
+	assert.Nil(t, otlpCollector.Collect(metric.Metric{
+		Name: "HealthCheckHealthy",
+		Data: checks.CheckResult{
+			Check:     "peers",
+			Healthy:   false,
+			Message:   "node has 0 peers",
+			StartedAt: startedAt,
+			Duration:  250 * time.Millisecond,
+			Attempts:  2,
+		},
+		Value:     0,
+		Timestamp: startedAt,
+	}))
+	assert.Nil(t, otlpCollector.Close())
+
+	receiver.Lock()
+	defer receiver.Unlock()
+
+	assert.Len(t, receiver.payloads[OTLPMetricsPath], 0, "health check results not collected to CloudWatch aren't exported as metrics")
+	assert.Len(t, receiver.payloads[OTLPTracesPath], 1)
+
+	span := receiver.payloads[OTLPTracesPath][0]["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})[0].(map[string]interface{})
+
+	assert.Equal(t, "health_check peers", span["name"])
+	assert.Len(t, span["traceId"], 32)
+	assert.Len(t, span["spanId"], 16)
+	assert.Equal(t, "1690891200000000000", span["startTimeUnixNano"])
+	assert.Equal(t, "1690891200250000000", span["endTimeUnixNano"])
+	assert.Equal(t, map[string]interface{}{"code": float64(2), "message": "node has 0 peers"}, span["status"])
+}
+
+func TestOTLPCollectorRetriesUnavailableEndpoint(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	otlpCollector, err := NewOTLPCollector(OTLPCollectorConfig{Endpoint: server.URL})
+	assert.Nil(t, err)
+
+	otlpCollector.retryBackoff = time.Millisecond
+
+	assert.Nil(t, otlpCollector.Collect(metric.Metric{Name: "LatestBlockHeight", Value: 42, CollectToCloudwatch: true}))
+	assert.Nil(t, otlpCollector.Close())
+
+	assert.Equal(t, 2, requests)
+}
+
+func TestNewOTLPCollectorRequiresEndpoint(t *testing.T) {
+	_, err := NewOTLPCollector(OTLPCollectorConfig{})
+
+	assert.NotNil(t, err)
+}
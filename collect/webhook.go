@@ -0,0 +1,149 @@
+package collect
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/webhook"
+)
+
+const (
+	DefaultWebhookMaxQueuedMetrics = 1000
+)
+
+var (
+	ErrWebhookQueueFull = errors.New("webhook metric queue is full, dropping metric")
+)
+
+// WebhookCollectorConfig wraps values
+// for configuring a WebhookCollector
+type WebhookCollectorConfig struct {
+	Client *webhook.Client
+	// maximum number of metrics to queue before
+	// dropping newly collected metrics
+	MaxQueuedMetrics int
+	// optional logger for reporting errors
+	// sending metrics in the background
+	Logger *logging.Logger
+}
+
+// webhookMetric is the json encoding of a
+// metric sent to a webhook, including the values
+// otherwise only collected to CloudWatch
+type webhookMetric struct {
+	Name            string                  `json:"name"`
+	Dimensions      metric.MetricDimensions `json:"dimensions,omitempty"`
+	Data            interface{}             `json:"data,omitempty"`
+	Value           *float64                `json:"value,omitempty"`
+	StatisticValues *metric.StatisticSet    `json:"statistic_values,omitempty"`
+	Timestamp       time.Time               `json:"timestamp"`
+}
+
+// WebhookCollector implements the Collector interface,
+// collecting metrics by posting them to a webhook
+// from a background go-routine
+type WebhookCollector struct {
+	client *webhook.Client
+	logger *logging.Logger
+	queue  chan webhookMetric
+	// closed once all queued metrics have been sent
+	// after the collector is closed
+	sent      chan struct{}
+	closeLock *sync.RWMutex
+	closed    bool
+}
+
+// NewWebhookCollector creates a new WebhookCollector using
+// the specified config, starting the background go-routine
+// that sends queued metrics to the webhook
+func NewWebhookCollector(config WebhookCollectorConfig) *WebhookCollector {
+	maxQueuedMetrics := DefaultWebhookMaxQueuedMetrics
+
+	if config.MaxQueuedMetrics > 0 {
+		maxQueuedMetrics = config.MaxQueuedMetrics
+	}
+
+	wc := &WebhookCollector{
+		client:    config.Client,
+		logger:    config.Logger,
+		queue:     make(chan webhookMetric, maxQueuedMetrics),
+		sent:      make(chan struct{}),
+		closeLock: &sync.RWMutex{},
+	}
+
+	go wc.sendQueuedMetrics()
+
+	return wc
+}
+
+// Collect queues metric to be posted to the webhook,
+// returning `ErrWebhookQueueFull` if the queue is full
+// Collect never blocks on requests to the webhook and
+// is safe to call across go-routines
+func (wc *WebhookCollector) Collect(metric metric.Metric) error {
+	encodedMetric := webhookMetric{
+		Name:            metric.Name,
+		Dimensions:      metric.Dimensions,
+		Data:            metric.Data,
+		StatisticValues: metric.StatisticValues,
+		Timestamp:       metric.Timestamp,
+	}
+
+	if metric.Data == nil && metric.StatisticValues == nil {
+		value := metric.Value
+		encodedMetric.Value = &value
+	}
+
+	if encodedMetric.Timestamp.IsZero() {
+		encodedMetric.Timestamp = time.Now()
+	}
+
+	wc.closeLock.RLock()
+	defer wc.closeLock.RUnlock()
+
+	if wc.closed {
+		return ErrCollectorClosed
+	}
+
+	select {
+	case wc.queue <- encodedMetric:
+		return nil
+	default:
+		return ErrWebhookQueueFull
+	}
+}
+
+// sendQueuedMetrics posts each queued metric to the
+// webhook until the queue is closed and drained
+func (wc *WebhookCollector) sendQueuedMetrics() {
+	defer close(wc.sent)
+
+	for encodedMetric := range wc.queue {
+		err := wc.client.Post(webhook.MetricPayloadType, encodedMetric)
+
+		if err != nil && wc.logger != nil {
+			wc.logger.Errorf("error %s sending metric %s to webhook, metric dropped", err, encodedMetric.Name)
+		}
+	}
+}
+
+// Close stops accepting new metrics and blocks
+// until all queued metrics have been sent to the webhook
+// Close is safe to call across go-routines
+func (wc *WebhookCollector) Close() error {
+	wc.closeLock.Lock()
+
+	if !wc.closed {
+		wc.closed = true
+		close(wc.queue)
+	}
+
+	wc.closeLock.Unlock()
+
+	<-wc.sent
+
+	return nil
+}
@@ -0,0 +1,263 @@
+// collectors.go contains types and functions for creating
+// the metric collectors used by the gui and cli display modes
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/kava-labs/doctor/audit"
+	"github.com/kava-labs/doctor/collect"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/control"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/webhook"
+)
+
+// MetricCollectorsConfig wraps values used
+// to configure the metric collectors used
+// by a display mode of the doctor program
+type MetricCollectorsConfig struct {
+	MetricCollectors []string
+	AWSRegion        string
+	MetricNamespace  string
+	MetricSigner     audit.Signer
+	// files to collect metrics to when using the file
+	// collector, if empty a single default file is used
+	FileMetricRoutes []dconfig.FileMetricRoute
+	// directory to create metric files in when using the file
+	// collector, defaults to the current working directory
+	MetricFileDirectory string
+	// whether to gzip metric files once they are rotated
+	CompressRotatedMetricFiles bool
+	// if set, how long to keep rotated metric files for
+	MetricFileRetentionPeriod time.Duration
+	// how often metrics queued for CloudWatch are sent
+	CloudWatchFlushInterval    time.Duration
+	CloudWatchMaxQueuedMetrics int
+	// if set, the period samples are aggregated over
+	// before they are sent to CloudWatch
+	CloudWatchAggregation time.Duration
+	// client for posting metrics to a webhook,
+	// required if the webhook collector is used
+	WebhookClient *webhook.Client
+	// opentelemetry collector to export metrics
+	// to when the otlp collector is used
+	OTLPEndpoint           string
+	OTLPHeaders            map[string]string
+	OTLPResourceAttributes map[string]string
+	OTLPTraces             bool
+	// limits on how often each collector emits
+	// metrics, metrics over the limits are dropped
+	MetricEmissionLimits []dconfig.MetricEmissionLimit
+	// optional maintenance mode switch, metrics collected
+	// while in maintenance mode are tagged as such
+	Maintenance *Maintenance
+	// optional stream of metrics to control api clients,
+	// collected to in addition to the requested collectors
+	MetricStream *control.MetricStream
+	// context metrics are sent to remote collectors with,
+	// cancelled when the doctor shuts down
+	Ctx context.Context
+}
+
+// NewMetricCollectorsConfig returns the config for the metric
+// collectors requested in the doctor config, signing metrics collected
+// to files with metricSigner (if any) and posting metrics to webhooks
+// using webhookClient (if any)
+func NewMetricCollectorsConfig(config *dconfig.DoctorConfig, metricSigner audit.Signer, webhookClient *webhook.Client) MetricCollectorsConfig {
+	return MetricCollectorsConfig{
+		MetricCollectors:           config.MetricCollectors,
+		MetricNamespace:            config.MetricNamespace,
+		AWSRegion:                  config.AWSRegion,
+		MetricSigner:               metricSigner,
+		FileMetricRoutes:           config.FileMetricRoutes,
+		MetricFileDirectory:        config.MetricFileDirectory,
+		CompressRotatedMetricFiles: config.CompressRotatedMetricFiles,
+		MetricFileRetentionPeriod:  time.Duration(config.MetricFileRetentionDays) * 24 * time.Hour,
+		CloudWatchFlushInterval:    time.Duration(config.CloudWatchFlushIntervalSeconds) * time.Second,
+		CloudWatchMaxQueuedMetrics: config.CloudWatchMaxQueuedMetrics,
+		CloudWatchAggregation:      time.Duration(config.CloudWatchAggregationPeriodSeconds) * time.Second,
+		WebhookClient:              webhookClient,
+		OTLPEndpoint:               config.OTLPEndpoint,
+		OTLPHeaders:                config.OTLPHeaders,
+		OTLPResourceAttributes:     config.OTLPResourceAttributes,
+		OTLPTraces:                 config.OTLPTraces,
+		MetricEmissionLimits:       config.MetricEmissionLimits,
+	}
+}
+
+// NewMetricCollectors creates and returns the collectors
+// specified in the provided configuration and error (if any)
+// using logger to report errors collecting metrics in the background
+func NewMetricCollectors(config MetricCollectorsConfig, logger *logging.Logger) ([]collect.Collector, error) {
+	collectors := []collect.Collector{}
+
+	for _, collector := range config.MetricCollectors {
+		// index of the first collector created for this kind
+		// of collector (e.g. multiple file collectors)
+		firstCollector := len(collectors)
+
+		switch collector {
+		case dconfig.FileMetricCollector:
+			fileCollectorConfigs := []collect.FileCollectorConfig{
+				{},
+			}
+
+			if len(config.FileMetricRoutes) > 0 {
+				fileCollectorConfigs = nil
+
+				for _, route := range config.FileMetricRoutes {
+					fileCollectorConfigs = append(fileCollectorConfigs, collect.FileCollectorConfig{
+						MetricFileNameSuffix: route.FileNameSuffix,
+						MetricNames:          route.MetricNames,
+					})
+				}
+			}
+
+			for _, fileCollectorConfig := range fileCollectorConfigs {
+				fileCollectorConfig.Signer = config.MetricSigner
+				fileCollectorConfig.OutputDirectory = config.MetricFileDirectory
+				fileCollectorConfig.CompressRotatedFiles = config.CompressRotatedMetricFiles
+				fileCollectorConfig.RetentionPeriod = config.MetricFileRetentionPeriod
+
+				fileCollector, err := collect.NewFileCollector(fileCollectorConfig)
+
+				if err != nil {
+					return nil, err
+				}
+
+				collectors = append(collectors, fileCollector)
+			}
+		case dconfig.CSVMetricCollector:
+			csvCollector, err := collect.NewCSVCollector(collect.CSVCollectorConfig{
+				OutputDirectory:      config.MetricFileDirectory,
+				CompressRotatedFiles: config.CompressRotatedMetricFiles,
+				RetentionPeriod:      config.MetricFileRetentionPeriod,
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			collectors = append(collectors, csvCollector)
+		case dconfig.CloudwatchMetricCollector:
+			ctx := config.Ctx
+
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			cloudwatchConfig := collect.CloudWatchCollectorConfig{
+				Ctx:              ctx,
+				AWSRegion:        config.AWSRegion,
+				MetricNamespace:  config.MetricNamespace,
+				FlushInterval:    config.CloudWatchFlushInterval,
+				MaxQueuedMetrics: config.CloudWatchMaxQueuedMetrics,
+				Logger:           logger,
+			}
+
+			cloudwatchCollector, err := collect.NewCloudWatchCollector(cloudwatchConfig)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if config.CloudWatchAggregation <= 0 {
+				collectors = append(collectors, cloudwatchCollector)
+
+				continue
+			}
+
+			// aggregate samples so the number of metrics sent doesn't
+			// grow with how often samples are taken
+			collectors = append(collectors, collect.NewAggregatingCollector(collect.AggregatingCollectorConfig{
+				Collector: cloudwatchCollector,
+				Period:    config.CloudWatchAggregation,
+				Logger:    logger,
+			}))
+		case dconfig.WebhookMetricCollector:
+			if config.WebhookClient == nil {
+				return nil, fmt.Errorf("a webhook client is required for the %s metric collector", dconfig.WebhookMetricCollector)
+			}
+
+			webhookCollector := collect.NewWebhookCollector(collect.WebhookCollectorConfig{
+				Client: config.WebhookClient,
+				Logger: logger,
+			})
+
+			collectors = append(collectors, webhookCollector)
+		case dconfig.OTLPMetricCollector:
+			otlpCollector, err := collect.NewOTLPCollector(collect.OTLPCollectorConfig{
+				Endpoint:           config.OTLPEndpoint,
+				Headers:            config.OTLPHeaders,
+				ResourceAttributes: config.OTLPResourceAttributes,
+				Traces:             config.OTLPTraces,
+				Logger:             logger,
+			})
+
+			if err != nil {
+				return nil, err
+			}
+
+			collectors = append(collectors, otlpCollector)
+		}
+
+		var emissionLimits []collect.EmissionLimit
+
+		for _, limit := range config.MetricEmissionLimits {
+			if limit.Collector != collector {
+				continue
+			}
+
+			emissionLimits = append(emissionLimits, collect.EmissionLimit{
+				MetricName:   limit.MetricName,
+				SampleEvery:  limit.SampleEvery,
+				MaxPerMinute: limit.MaxPerMinute,
+			})
+		}
+
+		if len(emissionLimits) == 0 {
+			continue
+		}
+
+		for i := firstCollector; i < len(collectors); i++ {
+			collectors[i] = collect.NewLimitedCollector(collect.LimitedCollectorConfig{
+				Collector: collectors[i],
+				Limits:    emissionLimits,
+			})
+		}
+	}
+
+	if config.MetricStream != nil {
+		collectors = append(collectors, config.MetricStream)
+	}
+
+	if config.Maintenance != nil {
+		for i, collector := range collectors {
+			collectors[i] = collect.NewDimensionCollector(collect.DimensionCollectorConfig{
+				Collector:  collector,
+				Dimensions: config.Maintenance.Dimensions,
+			})
+		}
+	}
+
+	return collectors, nil
+}
+
+// collectMetrics collects each metric to every collector,
+// logging any errors encountered collecting a metric
+func collectMetrics(collectors []collect.Collector, metrics []metric.Metric, logger *logging.Logger) {
+	for _, collector := range collectors {
+		for _, metric := range metrics {
+			err := collector.Collect(metric)
+
+			if err != nil {
+				logger.Errorf("error %s collecting metric %+v", err, metric)
+			}
+		}
+	}
+}
@@ -0,0 +1,606 @@
+// commands.go contains functions for running the command
+// specified as the first positional argument to the doctor
+// program, either watching a node or a one-off command
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kava-labs/doctor/annotation"
+	"github.com/kava-labs/doctor/audit"
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/collect"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/heal"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/preflight"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// watch commands are handled by watching
+	// the node instead of by runCommand
+	WatchCommand = "watch"
+	// watches a fake node instead of a real node
+	DemoCommand = "demo"
+
+	CheckCommand         = "check"
+	HealCommand          = "heal"
+	VerifyMetricsCommand = "verify-metrics"
+	PreflightNodeCommand = "preflight-node"
+	AnnotateCommand      = "annotate"
+	ResetAutohealCommand = "reset-autoheal"
+	ServiceHelperCommand = "service-helper"
+	FleetCommand         = "fleet"
+	VersionCommand       = "version"
+
+	// shorthand for the restart-service heal strategy
+	RestartHealAction = "restart"
+)
+
+// Version of the doctor program, set when building
+// release binaries using
+// -ldflags "-X main.Version=<version>"
+var Version = "dev"
+
+// commandDescriptions describes each command for the usage message
+var commandDescriptions = []struct {
+	Name        string
+	Description string
+}{
+	{WatchCommand, "watch the node, displaying and collecting metrics about its health (the default command)"},
+	{DemoCommand, "watch a fake node that cycles through scripted failures"},
+	{CheckCommand, "check the node once, printing a json summary and exiting with 0 if it's healthy, 1 if it's behind and 2 if it's unreachable"},
+	{HealCommand + " " + strings.Join(healActions(), "|"), "heal the node using the specified strategy, as autohealing would"},
+	{VerifyMetricsCommand + " METRIC_FILE...", "verify the signatures of the metrics collected to each file"},
+	{PreflightNodeCommand, "check a freshly provisioned node is set up to sync"},
+	{AnnotateCommand + " MESSAGE...", "record an annotation (e.g. a deploy) for running doctors to show"},
+	{ResetAutohealCommand, "resume autohealing of the doctor running in daemon mode after it hit its restart cap"},
+	{ServiceHelperCommand, "manage the blockchain service on behalf of doctors asking over the service helper socket, run with the privileges to do so"},
+	{FleetCommand, "receive reports pushed by other doctors and show a fleet-wide summary of their endpoints and nodes"},
+	{VersionCommand, "print the version of the doctor"},
+}
+
+// usage prints the commands and flags supported by the doctor program
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: doctor [flags] [command]\n\nCommands:\n")
+
+	for _, command := range commandDescriptions {
+		fmt.Fprintf(os.Stderr, "  %-52s %s\n", command.Name, command.Description)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nFlags:\n%s", pflag.CommandLine.FlagUsages())
+}
+
+// isWatchCommand returns whether the specified
+// positional arguments are for watching a node
+func isWatchCommand(args []string) bool {
+	return len(args) == 0 || args[0] == WatchCommand || args[0] == DemoCommand
+}
+
+// runCommand runs the one-off command specified by the first
+// positional argument, returning the exit code for the doctor program
+func runCommand(config *dconfig.DoctorConfig) int {
+	command, args := config.Args[0], config.Args[1:]
+
+	switch command {
+	case CheckCommand:
+		return runCheck(config)
+	case HealCommand:
+		return healNode(config, args)
+	case VerifyMetricsCommand:
+		return verifyMetrics(config, args)
+	case PreflightNodeCommand:
+		return preflightNode(config)
+	case AnnotateCommand:
+		return annotate(config, args)
+	case ResetAutohealCommand:
+		return resetAutoheal(config)
+	case ServiceHelperCommand:
+		return serveServiceHelper(config)
+	case FleetCommand:
+		return runFleet(config)
+	case VersionCommand:
+		fmt.Println(Version)
+
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %s\n\n", command)
+	usage()
+
+	return 2
+}
+
+// healActions returns the heal actions supported by the heal command
+func healActions() []string {
+	return append([]string{RestartHealAction}, heal.ValidStrategies()...)
+}
+
+// controlHealStrategies returns the heal strategies control api
+// clients can heal the node with, including restart as shorthand
+// for restart-service as with the heal command
+func controlHealStrategies(config *dconfig.DoctorConfig) []string {
+	for _, strategyName := range config.ControlHealStrategies {
+		if strategyName == heal.RestartServiceStrategyName {
+			return append([]string{RestartHealAction}, config.ControlHealStrategies...)
+		}
+	}
+
+	return config.ControlHealStrategies
+}
+
+// describeHeal returns a description of the
+// action the named heal strategy takes
+func describeHeal(strategyName string, config *dconfig.DoctorConfig) string {
+	switch strategyName {
+	case heal.StandbyStrategyName:
+		return fmt.Sprintf("place this host on standby with its autoscaling group until the node at %s catches up", config.KavaNodeRPCURL)
+	case heal.RestartServiceStrategyName:
+		return fmt.Sprintf("restart the %s %s service", config.AutohealBlockchainServiceName, config.AutohealServiceManager)
+	case heal.RebootInstanceStrategyName:
+		return "reboot this ec2 instance"
+	case heal.DeregisterTargetStrategyName:
+		return fmt.Sprintf("deregister this ec2 instance from target groups %s until the node at %s catches up", strings.Join(config.AutohealTargetGroupARNs, ","), config.KavaNodeRPCURL)
+	case heal.DrainDNSRecordStrategyName:
+		return fmt.Sprintf("set the weight of record %s (%s) to 0 until the node at %s catches up", config.AutohealRoute53RecordName, config.AutohealRoute53SetIdentifier, config.KavaNodeRPCURL)
+	case heal.ExecHookStrategyName:
+		return fmt.Sprintf("run %s", strings.Join(config.AutohealExecHookCommand, " "))
+	case heal.RefreshPeersStrategyName:
+		return fmt.Sprintf("dial peers %s or replace the seeds of the node at %s with %s, clear its address book and restart the %s %s service", strings.Join(config.AutohealRefreshPeers, ","), config.KavaNodeRPCURL, strings.Join(config.AutohealRefreshSeeds, ","), config.AutohealBlockchainServiceName, config.AutohealServiceManager)
+	case heal.ReplaceInstanceStrategyName:
+		return fmt.Sprintf("have this ec2 instance's autoscaling group replace it using %s", config.AutohealReplaceMethod)
+	case heal.RestoreStateStrategyName:
+		source := "state sync"
+
+		if config.AutohealSnapshotURL != "" {
+			source = config.AutohealSnapshotURL
+		}
+
+		return fmt.Sprintf("restore state from %s next to %s, stop the %s %s service and swap the restored state in for its data", source, config.NodeHome, config.AutohealBlockchainServiceName, config.AutohealServiceManager)
+	}
+
+	return fmt.Sprintf("run the %s heal strategy", strategyName)
+}
+
+// confirm prints the prompt to output and returns whether
+// the answer read from input is yes, treating no answer
+// (e.g. input isn't a terminal) as no
+func confirm(prompt string, input io.Reader, output io.Writer) bool {
+	fmt.Fprintf(output, "%s [y/N] ", prompt)
+
+	answer, err := bufio.NewReader(input).ReadString('\n')
+
+	if err != nil && answer == "" {
+		fmt.Fprintln(output)
+
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+
+	return false
+}
+
+// newHealStrategy returns the named heal strategy configured
+// as requested in the doctor config, checking blocked (if not nil)
+// before each step and counting restarts against restartBreaker
+// (if not nil), returning error (if any) if the strategy or the
+// service manager it uses couldn't be created (e.g. the strategy's
+// prerequisites aren't met)
+func newHealStrategy(strategyName string, config *dconfig.DoctorConfig, blocked heal.BlockedFunc, restartBreaker *heal.RestartBreaker) (heal.Strategy, error) {
+	serviceManager, err := heal.NewServiceManager(heal.ServiceManagerConfig{
+		Manager:            config.AutohealServiceManager,
+		ServiceName:        config.AutohealBlockchainServiceName,
+		RestartCommand:     config.AutohealRestartCommand,
+		Sudo:               config.AutohealServiceSudo,
+		HelperSocket:       config.AutohealServiceHelperSocket,
+		PreRestartCommands: config.AutohealPreRestartCommands,
+		PreRestartTimeout:  time.Duration(config.ExecHookTimeoutSeconds) * time.Second,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not create service manager", err)
+	}
+
+	strategy, err := heal.NewStrategy(strategyName, heal.StrategyConfig{
+		BlockchainServiceName:      config.AutohealBlockchainServiceName,
+		Blocked:                    blocked,
+		ServiceManager:             serviceManager,
+		RestartBreaker:             restartBreaker,
+		RestartVerificationTimeout: time.Duration(config.AutohealRestartVerificationSeconds) * time.Second,
+		NodeHome:                   config.NodeHome,
+		SnapshotURL:                config.AutohealSnapshotURL,
+		ReplaceMethod:              config.AutohealReplaceMethod,
+		ReplaceMinHealthyInstances: config.AutohealReplaceMinHealthyInstances,
+		ReplaceLifecycleHookName:   config.AutohealReplaceLifecycleHookName,
+		TargetGroupARNs:            config.AutohealTargetGroupARNs,
+		Route53HostedZoneId:        config.AutohealRoute53HostedZoneId,
+		Route53RecordName:          config.AutohealRoute53RecordName,
+		Route53RecordType:          config.AutohealRoute53RecordType,
+		Route53SetIdentifier:       config.AutohealRoute53SetIdentifier,
+		ExecHookCommand:            config.AutohealExecHookCommand,
+		ExecHookTimeout:            time.Duration(config.ExecHookTimeoutSeconds) * time.Second,
+		RefreshPeers:               config.AutohealRefreshPeers,
+		RefreshSeeds:               config.AutohealRefreshSeeds,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not create %s heal strategy", err, strategyName)
+	}
+
+	return strategy, nil
+}
+
+// healNode heals the node using the heal strategy specified by
+// the first argument (e.g. placing the host on standby until the
+// node catches up), exercising the same code paths as autohealing
+// does, returning 0 if the strategy succeeded (or would be run
+// in a dry run), 1 if it failed and 2 if the strategy couldn't
+// be created or the heal wasn't confirmed
+func healNode(config *dconfig.DoctorConfig, args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: doctor %s %s\n", HealCommand, strings.Join(healActions(), "|"))
+
+		return 2
+	}
+
+	strategyName := args[0]
+
+	if strategyName == RestartHealAction {
+		strategyName = heal.RestartServiceStrategyName
+	}
+
+	strategy, err := newHealStrategy(strategyName, config, nil, nil)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 2
+	}
+
+	description := describeHeal(strategyName, config)
+
+	// the strategy is created in a dry run as creating
+	// it checks the heal's prerequisites (e.g. that the
+	// doctor is running on an ec2 instance)
+	if config.DryRun {
+		fmt.Printf("dry run, would %s\n", description)
+
+		return 0
+	}
+
+	if !config.Yes && !confirm(fmt.Sprintf("%s?", strings.ToUpper(description[:1])+description[1:]), os.Stdin, os.Stderr) {
+		fmt.Fprintf(os.Stderr, "%s heal not confirmed, pass --%s to heal without confirmation\n", strategy.Name(), dconfig.YesFlagName)
+
+		return 2
+	}
+
+	kavaClient, err := kava.New(kava.ClientConfig{
+		JSONRPCURL:             config.KavaNodeRPCURL,
+		HTTPReadTimeoutSeconds: config.HealthChecksTimeoutSeconds,
+		Auth:                   config.KavaAPIAuth,
+		Transport:              config.Transport,
+		Status:                 config.Status,
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: could not initialize kava client\n", err)
+
+		return 2
+	}
+
+	webhookClient, err := newWebhookClient(config)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 2
+	}
+
+	// publish heal events so manual heals are
+	// recorded alongside automatic heals
+	incidentPublisher, err := incident.New(incident.PublisherConfig{
+		Publishers:             config.IncidentPublishers,
+		AWSRegion:              config.AWSRegion,
+		LogGroupName:           config.IncidentLogGroupName,
+		EventBusName:           config.IncidentEventBusName,
+		WebhookClient:          webhookClient,
+		Email:                  config.Email,
+		TelegramBotToken:       config.TelegramBotToken,
+		TelegramChatId:         config.TelegramChatId,
+		DiscordWebhookURL:      config.DiscordWebhookURL,
+		ExecHooks:              config.ExecHooks,
+		ExecHookTimeout:        time.Duration(config.ExecHookTimeoutSeconds) * time.Second,
+		ExecHookMaxConcurrency: config.ExecHookMaxConcurrency,
+		Schedules:              config.IncidentPublisherSchedules,
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: could not initialize incident publishers\n", err)
+
+		return 2
+	}
+
+	// print log messages from the strategy as it heals the node
+	logMessages := make(chan logging.Entry, config.LogChannelBufferSize)
+	logger := logging.New(logMessages, config.LogLevel)
+	printed := make(chan struct{})
+
+	go func() {
+		defer close(printed)
+
+		for entry := range logMessages {
+			fmt.Fprintln(os.Stderr, entry.Format(config.LogFormat, config.TimeFormat))
+		}
+	}()
+
+	// strategies that wait for the node to catch
+	// up are aborted on interrupt or terminate
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	err = strategy.Heal(ctx, logger, kavaClient, heal.HealerConfig{
+		AutohealSyncToLiveToleranceSeconds: config.AutohealSyncToLiveToleranceSeconds,
+		EndpointURL:                        config.KavaNodeRPCURL,
+		MinHealthyInServiceInstances:       config.AutohealStandbyMinHealthyInstances,
+		IncidentPublisher:                  incidentPublisher,
+	})
+
+	close(logMessages)
+	<-printed
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s heal failed\n", err, strategy.Name())
+
+		return 1
+	}
+
+	fmt.Printf("%s heal succeeded\n", strategy.Name())
+
+	return 0
+}
+
+// verifyMetrics verifies the signatures of the metrics collected
+// to each of the specified files, returning 0 if all files are
+// unaltered and 1 otherwise
+func verifyMetrics(config *dconfig.DoctorConfig, metricFilepaths []string) int {
+	if config.MetricSigningAlgorithm == "" || config.MetricVerificationKeyFilepath == "" {
+		fmt.Fprintf(os.Stderr, "%s and %s (or %s) must be set to verify metrics\n", dconfig.MetricSigningAlgorithmFlagName, dconfig.MetricVerificationKeyFilepathFlagName, dconfig.MetricSigningKeyFilepathFlagName)
+
+		return 2
+	}
+
+	if len(metricFilepaths) == 0 {
+		fmt.Fprintf(os.Stderr, "usage: doctor %s METRIC_FILE...\n", VerifyMetricsCommand)
+
+		return 2
+	}
+
+	verifier, err := audit.NewVerifierFromKeyFile(config.MetricSigningAlgorithm, config.MetricVerificationKeyFilepath)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 2
+	}
+
+	exitCode := 0
+
+	for _, metricFilepath := range metricFilepaths {
+		metricFile, err := os.Open(metricFilepath)
+
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", metricFilepath, err)
+			exitCode = 1
+
+			continue
+		}
+
+		var records io.Reader = metricFile
+
+		// rotated metric files may have been compressed
+		if strings.HasSuffix(metricFilepath, collect.CompressedMetricFileExtension) {
+			gzipReader, err := gzip.NewReader(metricFile)
+
+			if err != nil {
+				metricFile.Close()
+
+				fmt.Printf("FAIL %s: %s\n", metricFilepath, err)
+				exitCode = 1
+
+				continue
+			}
+
+			records = gzipReader
+		}
+
+		verified, err := audit.VerifyChain(records, verifier)
+
+		metricFile.Close()
+
+		if err != nil {
+			fmt.Printf("FAIL %s: %s (%d records verified)\n", metricFilepath, err, verified)
+			exitCode = 1
+
+			continue
+		}
+
+		fmt.Printf("OK %s: %d records verified\n", metricFilepath, verified)
+	}
+
+	return exitCode
+}
+
+// preflightNode checks a freshly provisioned node is set up to
+// sync, printing a checklist of the result of each check and
+// returning 0 if all checks passed and 1 otherwise
+func preflightNode(config *dconfig.DoctorConfig) int {
+	results := preflight.Run(preflight.Config{
+		NodeHome:              config.NodeHome,
+		ExpectedGenesisSHA256: config.ExpectedGenesisSHA256,
+		DialTimeout:           time.Duration(config.HealthChecksTimeoutSeconds) * time.Second,
+	})
+
+	for _, result := range results {
+		status := "PASS"
+
+		if !result.Passed {
+			status = "FAIL"
+		}
+
+		fmt.Printf("%s %s: %s\n", status, result.Name, result.Detail)
+	}
+
+	if !preflight.Passed(results) {
+		return 1
+	}
+
+	return 0
+}
+
+// annotate records an annotation (e.g. a deploy) with the provided
+// message to the annotations file for running doctors to show on their
+// timeline, returning 0 if the annotation was recorded and 2 otherwise
+func annotate(config *dconfig.DoctorConfig, args []string) int {
+	if config.AnnotationsFilepath == "" {
+		fmt.Fprintf(os.Stderr, "%s must be set to record annotations\n", dconfig.AnnotationsFilepathFlagName)
+
+		return 2
+	}
+
+	message := strings.TrimSpace(strings.Join(args, " "))
+
+	if message == "" {
+		fmt.Fprintf(os.Stderr, "usage: doctor %s MESSAGE...\n", AnnotateCommand)
+
+		return 2
+	}
+
+	err := annotation.Append(config.AnnotationsFilepath, annotation.Annotation{
+		Message:    message,
+		OccurredAt: time.Now(),
+	})
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 2
+	}
+
+	return 0
+}
+
+// resetAutoheal signals the doctor running in daemon mode to reset
+// its autoheal restart breaker, returning 0 if the doctor was
+// signalled and 2 if it couldn't be
+func resetAutoheal(config *dconfig.DoctorConfig) int {
+	err := signalRunningDoctor(config.PIDFilepath, syscall.SIGUSR2)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		return 2
+	}
+
+	fmt.Println("reset autohealing")
+
+	return 0
+}
+
+// listenServiceHelperSocket listens on a unix socket at socketPath
+// that only the helper's user and group (e.g. the doctor's group) may
+// connect to, creating it under a restrictive umask so there is no
+// window in which other users could connect before it's locked down
+func listenServiceHelperSocket(socketPath string) (net.Listener, error) {
+	previousUmask := syscall.Umask(0117)
+	defer syscall.Umask(previousUmask)
+
+	return net.Listen("unix", socketPath)
+}
+
+// serveServiceHelper listens on the service helper socket, restarting,
+// stopping and starting the blockchain service when asked to by a doctor
+// until interrupted, so the doctor itself needs no privileges to manage
+// the service, returning 0 if the helper was stopped and 2 if it failed
+func serveServiceHelper(config *dconfig.DoctorConfig) int {
+	socketPath := config.AutohealServiceHelperSocket
+
+	if socketPath == "" {
+		fmt.Fprintf(os.Stderr, "%s must be set to run the %s command\n", dconfig.AutohealServiceHelperSocketFlagName, ServiceHelperCommand)
+
+		return 2
+	}
+
+	// the helper manages the service directly
+	serviceManager, err := heal.NewServiceManager(heal.ServiceManagerConfig{
+		Manager:        config.AutohealServiceManager,
+		ServiceName:    config.AutohealBlockchainServiceName,
+		RestartCommand: config.AutohealRestartCommand,
+		Sudo:           config.AutohealServiceSudo,
+	})
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: could not create service manager\n", err)
+
+		return 2
+	}
+
+	// remove the socket left behind by a previous helper (if any)
+	if info, err := os.Lstat(socketPath); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(socketPath)
+	}
+
+	listener, err := listenServiceHelperSocket(socketPath)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: could not listen on %s\n", err, socketPath)
+
+		return 2
+	}
+
+	logMessages := make(chan logging.Entry, config.LogChannelBufferSize)
+	logger := logging.New(logMessages, config.LogLevel).With(logging.Fields{"service": config.AutohealBlockchainServiceName})
+	printed := make(chan struct{})
+
+	go func() {
+		defer close(printed)
+
+		for entry := range logMessages {
+			fmt.Fprintln(os.Stderr, entry.Format(config.LogFormat, config.TimeFormat))
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	logger.Infof("service helper listening on %s", socketPath)
+
+	err = heal.ServeServiceHelper(ctx, listener, serviceManager, logger)
+
+	close(logMessages)
+	<-printed
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: service helper failed\n", err)
+
+		return 2
+	}
+
+	return 0
+}
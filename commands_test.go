@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/heal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirmOnlyAcceptsYes(t *testing.T) {
+	testCases := []struct {
+		input     string
+		confirmed bool
+	}{
+		{input: "y\n", confirmed: true},
+		{input: "YES\n", confirmed: true},
+		{input: "yes", confirmed: true},
+		{input: "n\n", confirmed: false},
+		{input: "\n", confirmed: false},
+		// e.g. stdin isn't a terminal
+		{input: "", confirmed: false},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.confirmed, confirm("Heal?", strings.NewReader(tc.input), io.Discard), "input %q", tc.input)
+	}
+}
+
+func TestControlHealStrategiesIncludesRestartShorthand(t *testing.T) {
+	config := dconfig.DoctorConfig{
+		ControlHealStrategies: []string{heal.RestartServiceStrategyName, heal.StandbyStrategyName},
+	}
+
+	assert.Equal(t, []string{RestartHealAction, heal.RestartServiceStrategyName, heal.StandbyStrategyName}, controlHealStrategies(&config))
+
+	config.ControlHealStrategies = []string{heal.StandbyStrategyName}
+
+	assert.Equal(t, []string{heal.StandbyStrategyName}, controlHealStrategies(&config))
+}
+
+func TestListenServiceHelperSocketOnlyAllowsUserAndGroup(t *testing.T) {
+	// a permissive umask would otherwise leave the socket
+	// connectable by other users until its mode is changed
+	previousUmask := syscall.Umask(0)
+	defer syscall.Umask(previousUmask)
+
+	socketPath := filepath.Join(t.TempDir(), "service-helper.sock")
+
+	listener, err := listenServiceHelperSocket(socketPath)
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer listener.Close()
+
+	info, err := os.Stat(socketPath)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.NotZero(t, info.Mode()&os.ModeSocket)
+	assert.Equal(t, os.FileMode(0660), info.Mode().Perm())
+}
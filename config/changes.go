@@ -0,0 +1,86 @@
+// changes.go contains functions for finding the settings
+// that changed between two loads of the doctor's config
+
+package config
+
+import (
+	"flag"
+	"reflect"
+	"strings"
+
+	"github.com/kava-labs/doctor/logging"
+)
+
+var (
+	// flags set fields whose names don't match the flag's name
+	fieldFlagNames = map[string]string{
+		"KavaNodeRPCURL":  KavaAPIAddressFlagName,
+		"InteractiveMode": "interactive",
+		"MetricSamplesForSyntheticMetricCalculation": MetricSamplesForSyntheticMetricCalculationFlagName,
+		"AutohealInitialAllowedDelaySeconds":         AutohealInitialDelaySecondsFlagName,
+		"HealthChecksTimeoutSeconds":                 HealthChecksTimeoutSecondsFlagName,
+	}
+	// fields set by the doctor each time the config is loaded,
+	// rather than from a setting
+	runtimeFields = []string{"Logger", "Args"}
+)
+
+// ChangedSettings returns the names of the settings whose values
+// differ between the initial and reloaded configs, except those set by
+// the fields named in ignoredFields (e.g. as they're reloaded), named
+// by the flag that sets them where there is one
+func ChangedSettings(initial *DoctorConfig, reloaded *DoctorConfig, ignoredFields ...string) []string {
+	ignored := make(map[string]bool)
+
+	for _, field := range append(ignoredFields, runtimeFields...) {
+		ignored[field] = true
+	}
+
+	initialValue := reflect.ValueOf(*initial)
+	reloadedValue := reflect.ValueOf(*reloaded)
+
+	var changed []string
+
+	for i := 0; i < initialValue.NumField(); i++ {
+		field := initialValue.Type().Field(i).Name
+
+		if ignored[field] || settingEqual(initialValue.Field(i).Interface(), reloadedValue.Field(i).Interface()) {
+			continue
+		}
+
+		changed = append(changed, settingName(field))
+	}
+
+	return changed
+}
+
+// settingEqual returns whether the values of a setting are equal,
+// comparing time zones by name as loading the same time zone twice
+// doesn't return equal locations
+func settingEqual(a interface{}, b interface{}) bool {
+	if aTimeFormat, ok := a.(logging.TimeFormat); ok {
+		bTimeFormat := b.(logging.TimeFormat)
+
+		return aTimeFormat.Layout == bTimeFormat.Layout && aTimeFormat.Location.String() == bTimeFormat.Location.String()
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// settingName returns the name of the flag that sets the
+// field, or the name of the field if there isn't one
+func settingName(field string) string {
+	if flagName, ok := fieldFlagNames[field]; ok {
+		return flagName
+	}
+
+	name := field
+
+	flag.CommandLine.VisitAll(func(f *flag.Flag) {
+		if strings.EqualFold(strings.ReplaceAll(f.Name, "_", ""), field) {
+			name = f.Name
+		}
+	})
+
+	return name
+}
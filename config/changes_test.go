@@ -0,0 +1,52 @@
+package config
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/heal"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedSettingsNamesChangedSettingsByFlag(t *testing.T) {
+	initial := newValidConfig()
+	initial.KavaNodeRPCURL = "http://localhost:26657"
+
+	reloaded := newValidConfig()
+	reloaded.KavaNodeRPCURL = "http://kava:26657"
+	reloaded.AutohealStrategies = []AutohealStrategy{{Name: heal.RestartServiceStrategyName, MaxAttempts: 3}}
+	reloaded.FleetServerAddress = "0.0.0.0:8090"
+	reloaded.SampleStoreRetentionHours = 48
+	// ignored as reloaded
+	reloaded.MetricCollectors = []string{FileMetricCollector}
+	// set each time the config is loaded
+	reloaded.Logger = log.New(os.Stderr, "", 0)
+
+	assert.Equal(t, []string{KavaAPIAddressFlagName, SampleStoreRetentionHoursFlagName, AutohealStrategiesFlagName, FleetServerAddressFlagName}, ChangedSettings(&initial, &reloaded, "MetricCollectors"))
+}
+
+func TestChangedSettingsComparesTimeZonesByName(t *testing.T) {
+	initialLocation, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	// look up a time in the zone so the locations' caches differ
+	time.Now().In(initialLocation).Zone()
+
+	reloadedLocation, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	initial := newValidConfig()
+	initial.TimeFormat = logging.TimeFormat{Location: initialLocation, Layout: time.RFC3339}
+
+	reloaded := newValidConfig()
+	reloaded.TimeFormat = logging.TimeFormat{Location: reloadedLocation, Layout: time.RFC3339}
+
+	assert.Empty(t, ChangedSettings(&initial, &reloaded))
+
+	reloaded.TimeFormat.Location = time.UTC
+
+	assert.Equal(t, 1, len(ChangedSettings(&initial, &reloaded)))
+}
@@ -4,13 +4,29 @@
 package config
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/kava-labs/doctor/accesslog"
+	"github.com/kava-labs/doctor/anomaly"
+	"github.com/kava-labs/doctor/audit"
+	"github.com/kava-labs/doctor/checks"
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/heal"
+	"github.com/kava-labs/doctor/host"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/secrets"
+	"github.com/kava-labs/doctor/slo"
+	"github.com/kava-labs/doctor/store"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -26,10 +42,17 @@ const (
 	KavaAPIAddressFlagName                             = "kava_api_address"
 	MaxMetricSamplesToRetainPerNodeFlagName            = "max_metric_samples_to_retain_per_node"
 	MetricSamplesForSyntheticMetricCalculationFlagName = "metric_samples_to_use_for_synthetic_metrics"
+	UptimeShortWindowSecondsFlagName                   = "uptime_short_window_seconds"
+	UptimeMediumWindowSecondsFlagName                  = "uptime_medium_window_seconds"
+	UptimeLongWindowSecondsFlagName                    = "uptime_long_window_seconds"
+	UptimeEWMAHalfLifeSecondsFlagName                  = "uptime_ewma_half_life_seconds"
 	MetricCollectorsFlagName                           = "metric_collectors"
 	DefaultMetricCollector                             = "file"
 	FileMetricCollector                                = "file"
 	CloudwatchMetricCollector                          = "cloudwatch"
+	WebhookMetricCollector                             = "webhook"
+	CSVMetricCollector                                 = "csv"
+	OTLPMetricCollector                                = "otlp"
 	AWSRegionFlagName                                  = "aws_region"
 	MetricNamespaceFlagName                            = "metric_namespace"
 	AutohealFlagName                                   = "autoheal"
@@ -48,20 +71,308 @@ const (
 	DefaultAutohealChecksStartupDelaySecondsFlag = 2700
 	HealthChecksTimeoutSecondsFlagName           = "health_check_timeout_seconds"
 	DefaultHealthChecksTimeoutSecondsFlagName    = 10
+	RPCMaxRetriesFlagName                        = "rpc_max_retries"
+	DefaultRPCMaxRetries                         = 2
+	RPCRetryBackoffMillisecondsFlagName          = "rpc_retry_backoff_milliseconds"
+	DefaultRPCRetryBackoffMilliseconds           = 250
 	AutohealRestartDelaySecondsFlagName          = "autoheal_restart_delay_seconds"
 	// 45 minutes
 	DefaultAutohealRestartDelaySeconds = 2700
+	LogFormatFlagName                  = "log_format"
+	DefaultLogFormat                   = logging.TextFormat
+	LogLevelFlagName                   = "log_level"
+	DefaultLogLevel                    = "info"
+	MaxChartPointsPerSeriesFlagName    = "max_chart_points_per_series"
+	DefaultMaxChartPointsPerSeries     = 500
+	MaxMessagesToRetainFlagName        = "max_messages_to_retain"
+	DefaultMaxMessagesToRetain         = 1000
+	MetricSigningAlgorithmFlagName     = "metric_signing_algorithm"
+	MetricSigningKeyFilepathFlagName   = "metric_signing_key_filepath"
+	// ed25519 signed metrics are verified using
+	// the public key rather than the signing key
+	MetricVerificationKeyFilepathFlagName = "metric_verification_key_filepath"
+	SampleStoreBackendFlagName            = "sample_store_backend"
+	SampleStoreFilepathFlagName           = "sample_store_filepath"
+	DefaultSampleStoreFilepath            = "~/.kava/doctor/samples.db"
+	SampleStoreRetentionHoursFlagName     = "sample_store_retention_hours"
+	// 1 week
+	DefaultSampleStoreRetentionHours         = 168
+	PeerChurnAlertThresholdPerMinuteFlagName = "peer_churn_alert_threshold_per_minute"
+	DefaultPeerChurnAlertThresholdPerMinute  = 10
+	PeerMinRecvBytesPerSecondFlagName        = "peer_min_recv_bytes_per_second"
+	DefaultPeerMinRecvBytesPerSecond         = 512
+	CloudWatchFlushIntervalSecondsFlagName   = "cloudwatch_flush_interval_seconds"
+	DefaultCloudWatchFlushIntervalSeconds    = 30
+	CloudWatchMaxQueuedMetricsFlagName       = "cloudwatch_max_queued_metrics"
+	DefaultCloudWatchMaxQueuedMetrics        = 10000
+	CloudWatchAggregationPeriodFlagName      = "cloudwatch_aggregation_period_seconds"
+	DefaultCloudWatchAggregationSeconds      = 60
+	MetricChannelBufferSizeFlagName          = "metric_channel_buffer_size"
+	DefaultMetricChannelBufferSize           = 100
+	LogChannelBufferSizeFlagName             = "log_channel_buffer_size"
+	DefaultLogChannelBufferSize              = 1000
+	EventChannelBufferSizeFlagName           = "event_channel_buffer_size"
+	DefaultEventChannelBufferSize            = 100
+	StaleResponseBehaviorFlagName            = "stale_response_behavior"
+	// status responses with a block time older than a previous
+	// response (e.g. served from a caching proxy) are excluded
+	// from synthetic metrics and autohealing decisions
+	StaleResponseBehaviorDiscard = "discard"
+	// stale status responses are counted but otherwise
+	// treated the same as any other response
+	StaleResponseBehaviorAccept                    = "accept"
+	DefaultStaleResponseBehavior                   = StaleResponseBehaviorDiscard
+	IncidentPublishersFlagName                     = "incident_publishers"
+	IncidentLogGroupNameFlagName                   = "incident_log_group_name"
+	IncidentEventBusNameFlagName                   = "incident_event_bus_name"
+	EmailSMTPAddressFlagName                       = "email_smtp_address"
+	EmailSMTPSecurityFlagName                      = "email_smtp_security"
+	EmailSMTPUsernameFlagName                      = "email_smtp_username"
+	EmailSMTPPasswordFlagName                      = "email_smtp_password"
+	EmailFromFlagName                              = "email_from"
+	EmailRecipientsFlagName                        = "email_recipients"
+	EmailSubjectTemplateFlagName                   = "email_subject_template"
+	EmailBodyTemplateFilepathFlagName              = "email_body_template_filepath"
+	TelegramBotTokenFlagName                       = "telegram_bot_token"
+	TelegramChatIdFlagName                         = "telegram_chat_id"
+	DiscordWebhookURLFlagName                      = "discord_webhook_url"
+	IncidentPublisherSchedulesFlagName             = "incident_publisher_schedules"
+	ExecHooksFlagName                              = "exec_hooks"
+	ExecHookTimeoutSecondsFlagName                 = "exec_hook_timeout_seconds"
+	DefaultExecHookTimeoutSeconds                  = 60
+	ExecHookMaxConcurrencyFlagName                 = "exec_hook_max_concurrency"
+	DefaultExecHookMaxConcurrency                  = 4
+	PublishAlertsFlagName                          = "publish_alerts"
+	AlertCooldownSecondsFlagName                   = "alert_cooldown_seconds"
+	DefaultAlertCooldownSeconds                    = 300
+	AlertEscalationSecondsFlagName                 = "alert_escalation_seconds"
+	DefaultAlertEscalationSeconds                  = 900
+	WebhookURLFlagName                             = "webhook_url"
+	WebhookSigningKeyFilepathFlagName              = "webhook_signing_key_filepath"
+	WebhookMaxRetriesFlagName                      = "webhook_max_retries"
+	DefaultWebhookMaxRetries                       = 3
+	BlockIntervalP95AlertThresholdSecondsFlagName  = "block_interval_p95_alert_threshold_seconds"
+	DefaultBlockIntervalP95AlertThresholdSeconds   = 15
+	FileMetricRoutesFlagName                       = "file_metric_routes"
+	DiagnosticsAgentFlagName                       = "diagnostics_agent"
+	DiagnosticsAgentAddressFlagName                = "diagnostics_agent_address"
+	DefaultDiagnosticsAgentAddress                 = "127.0.0.1:0"
+	AutohealStrategiesFlagName                     = "autoheal_strategies"
+	DefaultAutohealStrategies                      = "standby"
+	AutohealEscalationDelaySecondsFlagName         = "autoheal_escalation_delay_seconds"
+	DefaultAutohealEscalationDelaySeconds          = 600
+	ReferenceAPIAddressFlagName                    = "reference_api_address"
+	AutohealBlocksBehindReferenceToleranceFlagName = "autoheal_blocks_behind_reference_tolerance"
+	DefaultAutohealBlocksBehindReferenceTolerance  = 20
+	AccessLogFilepathFlagName                      = "access_log_filepath"
+	AccessLogFormatFlagName                        = "access_log_format"
+	DefaultAccessLogFormat                         = accesslog.NginxFormat
+	MempoolSizeAlertThresholdFlagName              = "mempool_size_alert_threshold"
+	DefaultMempoolSizeAlertThreshold               = 0
+	MempoolSizeAlertDurationSecondsFlagName        = "mempool_size_alert_duration_seconds"
+	DefaultMempoolSizeAlertDurationSeconds         = 300
+	AnomalyZScoreThresholdFlagName                 = "anomaly_z_score_threshold"
+	AnomalyBaselineSamplesFlagName                 = "anomaly_baseline_samples"
+	AnomalyBaselinesFilepathFlagName               = "anomaly_baselines_filepath"
+	ConsensusFrozenThresholdSecondsFlagName        = "consensus_frozen_threshold_seconds"
+	DefaultConsensusFrozenThresholdSeconds         = 300
+	AutohealFrozenConsensusFlagName                = "autoheal_frozen_consensus"
+	AutohealStandbyMinHealthyInstancesFlagName     = "autoheal_standby_min_healthy_instances"
+	DefaultAutohealStandbyMinHealthyInstances      = 1
+	AutohealMaxRestartsPerHourFlagName             = "autoheal_max_restarts_per_hour"
+	AutohealMaxRestartsPerDayFlagName              = "autoheal_max_restarts_per_day"
+	AutohealSnapshotURLFlagName                    = "autoheal_snapshot_url"
+	AutohealReplaceMethodFlagName                  = "autoheal_replace_method"
+	AutohealReplaceMinHealthyInstancesFlagName     = "autoheal_replace_min_healthy_instances"
+	DefaultAutohealReplaceMinHealthyInstances      = 1
+	AutohealReplaceLifecycleHookNameFlagName       = "autoheal_replace_lifecycle_hook_name"
+	AutohealTargetGroupARNsFlagName                = "autoheal_target_group_arns"
+	AutohealRoute53HostedZoneIdFlagName            = "autoheal_route53_hosted_zone_id"
+	AutohealRoute53RecordNameFlagName              = "autoheal_route53_record_name"
+	AutohealRoute53RecordTypeFlagName              = "autoheal_route53_record_type"
+	AutohealRoute53SetIdentifierFlagName           = "autoheal_route53_set_identifier"
+	AutohealExecHookCommandFlagName                = "autoheal_exec_hook_command"
+	AutohealRefreshPeersFlagName                   = "autoheal_refresh_peers"
+	AutohealRefreshSeedsFlagName                   = "autoheal_refresh_seeds"
+	AutohealRestoreStateThresholdSecondsFlagName   = "autoheal_restore_state_threshold_seconds"
+	AutohealStateSyncThresholdSecondsFlagName      = "autoheal_state_sync_threshold_seconds"
+	DefaultAutohealStateSyncThresholdSeconds       = 3600
+	AutohealServiceManagerFlagName                 = "autoheal_service_manager"
+	AutohealRestartCommandFlagName                 = "autoheal_restart_command"
+	AutohealPreRestartCommandsFlagName             = "autoheal_pre_restart_commands"
+	AutohealRestartVerificationSecondsFlagName     = "autoheal_restart_verification_seconds"
+	AutohealServiceSudoFlagName                    = "autoheal_service_sudo"
+	AutohealServiceHelperSocketFlagName            = "autoheal_service_helper_socket"
+	MetricFileDirectoryFlagName                    = "metric_file_directory"
+	CompressRotatedMetricFilesFlagName             = "compress_rotated_metric_files"
+	MetricFileRetentionDaysFlagName                = "metric_file_retention_days"
+	NodeHomeFlagName                               = "node_home"
+	DefaultNodeHome                                = "~/.kava"
+	ExpectedGenesisSHA256FlagName                  = "expected_genesis_sha256"
+	AnnotationsFilepathFlagName                    = "annotations_filepath"
+	HostMetricsFlagName                            = "host_metrics"
+	HostProcPathFlagName                           = "host_proc_path"
+	MetricEmissionLimitsFlagName                   = "metric_emission_limits"
+	ExpectedNodeIdFlagName                         = "expected_node_id"
+	ExpectedNodeMonikerFlagName                    = "expected_node_moniker"
+	ChainIdFlagName                                = "chain_id"
+	ChainConsistencyCheckIntervalSecondsFlagName   = "chain_consistency_check_interval_seconds"
+	DefaultChainConsistencyCheckIntervalSeconds    = 60
+	VotingPowerCheckIntervalSecondsFlagName        = "voting_power_check_interval_seconds"
+	DefaultVotingPowerCheckIntervalSeconds         = 60
+	ChainDegradedVotingPowerPercentFlagName        = "chain_degraded_voting_power_percent"
+	DefaultChainDegradedVotingPowerPercent         = 70
+	UpgradeAPIAddressFlagName                      = "upgrade_api_address"
+	UpgradeHeightFlagName                          = "upgrade_height"
+	UpgradeWindowSecondsFlagName                   = "upgrade_window_seconds"
+	DefaultUpgradeWindowSeconds                    = 3600
+	NodeGroupsFlagName                             = "node_groups"
+	SLOsFlagName                                   = "slos"
+	RPCProbeMethodsFlagName                        = "rpc_probe_methods"
+	WebsocketMonitoringFlagName                    = "websocket_monitoring"
+	PushNewBlocksFlagName                          = "push_new_blocks"
+	VantageEndpointsFlagName                       = "vantage_endpoints"
+	RegionFlagName                                 = "region"
+	DefaultRegion                                  = "local"
+	StatusServerAddressFlagName                    = "status_server_address"
+	StatusServerAuthTokenFlagName                  = "status_server_auth_token"
+	StatusServerTLSCertFilepathFlagName            = "status_server_tls_cert_filepath"
+	StatusServerTLSKeyFilepathFlagName             = "status_server_tls_key_filepath"
+	FleetAggregatorURLFlagName                     = "fleet_aggregator_url"
+	FleetAgentIdFlagName                           = "fleet_agent_id"
+	FleetPushIntervalSecondsFlagName               = "fleet_push_interval_seconds"
+	DefaultFleetPushIntervalSeconds                = 30
+	FleetAuthTokenFlagName                         = "fleet_auth_token"
+	FleetServerAddressFlagName                     = "fleet_server_address"
+	DefaultFleetServerAddress                      = "127.0.0.1:8090"
+	FleetServerTLSCertFilepathFlagName             = "fleet_server_tls_cert_filepath"
+	FleetServerTLSKeyFilepathFlagName              = "fleet_server_tls_key_filepath"
+	FleetStaleSecondsFlagName                      = "fleet_stale_seconds"
+	DefaultFleetStaleSeconds                       = 120
+	ControlServerAddressFlagName                   = "control_server_address"
+	ControlTLSCertFilepathFlagName                 = "control_tls_cert_filepath"
+	ControlTLSKeyFilepathFlagName                  = "control_tls_key_filepath"
+	ControlTLSClientCAFilepathFlagName             = "control_tls_client_ca_filepath"
+	ControlHealStrategiesFlagName                  = "control_heal_strategies"
+	DefaultControlHealStrategies                   = "restart-service,standby,deregister-target"
+	CheckFlagName                                  = "check"
+	DaemonFlagName                                 = "daemon"
+	PIDFilepathFlagName                            = "pid_filepath"
+	DefaultPIDFilepath                             = "~/.kava/doctor/doctor.pid"
+	WatchConfigFileFlagName                        = "watch_config_file"
+	MaintenanceFilepathFlagName                    = "maintenance_filepath"
+	DefaultMaintenanceFilepath                     = "~/.kava/doctor/maintenance"
+	HealthChecksFlagName                           = "health_checks"
+	HealthCheckMinPeersFlagName                    = "health_check_min_peers"
+	DefaultHealthCheckMinPeers                     = 1
+	HealthCheckDiskMinFreePercentFlagName          = "health_check_disk_min_free_percent"
+	DefaultHealthCheckDiskMinFreePercent           = 10
+	EVMRPCAddressFlagName                          = "evm_rpc_address"
+	RESTAPIAddressFlagName                         = "rest_api_address"
+	KavaAPIAuthFlagName                            = "kava_api_auth"
+	ReferenceAPIAuthFlagName                       = "reference_api_auth"
+	RESTAPIAuthFlagName                            = "rest_api_auth"
+	UpgradeAPIAuthFlagName                         = "upgrade_api_auth"
+	HTTPProxyURLFlagName                           = "http_proxy_url"
+	TLSCAFilepathFlagName                          = "tls_ca_filepath"
+	TLSClientCertFilepathFlagName                  = "tls_client_cert_filepath"
+	TLSClientKeyFilepathFlagName                   = "tls_client_key_filepath"
+	TLSInsecureSkipVerifyFlagName                  = "tls_insecure_skip_verify"
+	ChainTypeFlagName                              = "chain_type"
+	DefaultChainType                               = kava.KavaChainType
+	StatusEndpointPathFlagName                     = "status_endpoint_path"
+	DefaultStatusEndpointPath                      = kava.StatusEndpointPath
+	StatusFieldsFlagName                           = "status_fields"
+	UptimeProbesFlagName                           = "uptime_probes"
+	OTLPEndpointFlagName                           = "otlp_endpoint"
+	OTLPHeadersFlagName                            = "otlp_headers"
+	OTLPResourceAttributesFlagName                 = "otlp_resource_attributes"
+	OTLPTracesFlagName                             = "otlp_traces"
+	HealthCheckQueryAccountFlagName                = "health_check_query_account"
+	SyntheticTxCommandFlagName                     = "synthetic_tx_command"
+	HealthCheckTimeoutsFlagName                    = "health_check_timeouts"
+	HealthCheckRetriesFlagName                     = "health_check_retries"
+	HealthCheckRetryBackoffsFlagName               = "health_check_retry_backoffs"
+	DefaultHealthCheckRetryBackoffSeconds          = 1
+	YesFlagName                                    = "yes"
+	DryRunFlagName                                 = "dry_run"
+	ProbeEndpointAddressesFlagName                 = "probe_endpoint_addresses"
+	DisplayTimeZoneFlagName                        = "display_time_zone"
+	DefaultDisplayTimeZone                         = "UTC"
+	TimestampFormatFlagName                        = "timestamp_format"
+	DefaultTimestampFormat                         = "rfc3339"
 )
 
 const (
 	DefaultMetricSamplesToKeepPerNode                 = 10000
 	DefaultMetricSamplesForSyntheticMetricCalculation = 60
+	DefaultUptimeShortWindowSeconds                   = 5 * 60
+	DefaultUptimeMediumWindowSeconds                  = 60 * 60
+	DefaultUptimeLongWindowSeconds                    = 24 * 60 * 60
+	DefaultUptimeEWMAHalfLifeSeconds                  = 5 * 60
+)
+
+const (
+	// the status of the node is always probed as the
+	// sync status of the node is sampled from it
+	UptimeProbeStatus = "status"
+	// checks the node is running
+	UptimeProbeHealth = "health"
+	// checks the node can reach the application (e.g. kava) it
+	// runs consensus for, which can die while the node keeps
+	// answering status requests
+	UptimeProbeABCIInfo = "abci_info"
+	DefaultUptimeProbes = UptimeProbeStatus
 )
 
 var (
 	ValidMetricCollectors = []string{
 		FileMetricCollector,
 		CloudwatchMetricCollector,
+		WebhookMetricCollector,
+		CSVMetricCollector,
+		OTLPMetricCollector,
+	}
+	ValidUptimeProbes = []string{
+		UptimeProbeStatus,
+		UptimeProbeHealth,
+		UptimeProbeABCIInfo,
+	}
+	ValidStaleResponseBehaviors = []string{
+		StaleResponseBehaviorDiscard,
+		StaleResponseBehaviorAccept,
+	}
+	// settings that may hold credentials (e.g. tokens embedded
+	// in urls) and so can reference secrets instead of holding
+	// them directly, see secrets.Resolver
+	SecretSettings = []string{
+		WebhookURLFlagName,
+		KavaAPIAddressFlagName,
+		ReferenceAPIAddressFlagName,
+		EVMRPCAddressFlagName,
+		RESTAPIAddressFlagName,
+		UpgradeAPIAddressFlagName,
+		AutohealSnapshotURLFlagName,
+		KavaAPIAuthFlagName,
+		ReferenceAPIAuthFlagName,
+		RESTAPIAuthFlagName,
+		UpgradeAPIAuthFlagName,
+		HTTPProxyURLFlagName,
+		OTLPEndpointFlagName,
+		OTLPHeadersFlagName,
+		EmailSMTPPasswordFlagName,
+		TelegramBotTokenFlagName,
+		DiscordWebhookURLFlagName,
+		FleetAggregatorURLFlagName,
+		FleetAuthTokenFlagName,
+		StatusServerAuthTokenFlagName,
+	}
+	// named layouts that can be used as the timestamp format
+	TimestampFormatLayouts = map[string]string{
+		"rfc3339":     time.RFC3339,
+		"rfc3339nano": time.RFC3339Nano,
+		"datetime":    "2006-01-02 15:04:05",
+		"kitchen":     time.Kitchen,
 	}
 	// cli flags
 	// while the majority of time configuration values will be
@@ -69,26 +380,258 @@ var (
 	// specifying these allows setting default values and
 	// auto populates help text in the output of --help
 	configFilepathFlag                             = flag.String(ConfigFilepathFlagName, "~/.kava/doctor/config.json", "filepath to json config file to use")
-	kavaAPIAddressFlag                             = flag.String(KavaAPIAddressFlagName, "https://rpc.data.kava.io", "URL of the endpoint that doctor should monitor")
+	kavaAPIAddressFlag                             = flag.String(KavaAPIAddressFlagName, "https://rpc.data.kava.io", "URL of the endpoint that doctor should monitor, or the rpc listen address of a local node (e.g. tcp://127.0.0.1:26657 or unix:///var/run/kava/rpc.sock)")
 	debugModeFlag                                  = flag.Bool("debug", false, "controls whether debug logging is enabled")
 	interactiveModeFlag                            = flag.Bool("interactive", false, "controls whether an interactive terminal UI is displayed")
 	defaultMonitoringIntervalSecondsFlag           = flag.Int(DefaultMonitoringIntervalSecondsFlagName, 5, "default interval doctor will use for the various monitoring routines")
 	maxMetricSamplesToRetainPerNodeFlag            = flag.Int(MaxMetricSamplesToRetainPerNodeFlagName, DefaultMetricSamplesToKeepPerNode, "maximum number of metric samples that will be kept in memory per node")
 	metricSamplesForSyntheticMetricCalculationFlag = flag.Int(MetricSamplesForSyntheticMetricCalculationFlagName, DefaultMetricSamplesForSyntheticMetricCalculation, "number of metric samples to use when calculating synthetic metrics such as the node hash rate")
+	uptimeShortWindowSecondsFlag                   = flag.Int(UptimeShortWindowSecondsFlagName, DefaultUptimeShortWindowSeconds, "number of seconds of uptime samples to calculate the shortest windowed uptime of kava_api_address over, 0 disables the window")
+	uptimeMediumWindowSecondsFlag                  = flag.Int(UptimeMediumWindowSecondsFlagName, DefaultUptimeMediumWindowSeconds, "number of seconds of uptime samples to calculate the medium windowed uptime of kava_api_address over, 0 disables the window")
+	uptimeLongWindowSecondsFlag                    = flag.Int(UptimeLongWindowSecondsFlagName, DefaultUptimeLongWindowSeconds, "number of seconds of uptime samples to calculate the longest windowed uptime of kava_api_address over, limited to the samples retained by max_metric_samples_to_retain_per_node, 0 disables the window")
+	uptimeEWMAHalfLifeSecondsFlag                  = flag.Int(UptimeEWMAHalfLifeSecondsFlagName, DefaultUptimeEWMAHalfLifeSeconds, "number of seconds after which an uptime sample counts for half as much in the exponentially weighted moving average uptime of kava_api_address, 0 disables the average")
 	metricCollectorsFlag                           = flag.String(MetricCollectorsFlagName, DefaultMetricCollector, fmt.Sprintf("where to send collected metrics to, multiple collectors can be specified as a comma separated list, supported collectors are %v", ValidMetricCollectors))
 	awsRegionFlag                                  = flag.String(AWSRegionFlagName, "us-east-1", "aws region to use for sending metrics to CloudWatch")
 	metricNamespaceFlag                            = flag.String(MetricNamespaceFlagName, "kava", "top level namespace to use for grouping all metrics sent to cloudwatch")
 	autohealFlag                                   = flag.Bool(AutohealFlagName, false, "whether doctor should take active measures to attempt to heal the kava process (e.g. place on standby if it falls significantly behind live)")
-	autohealBlockchainServiceNameFlag              = flag.String(AutohealBlockchainServiceNameFlagName, "kava", "the name of the systemd service running the blockchain. this is the service that gets restarted in the autoheal process")
+	autohealBlockchainServiceNameFlag              = flag.String(AutohealBlockchainServiceNameFlagName, "kava", "the name of the service running the blockchain (e.g. systemd unit, OpenRC service, launchd label or Windows service). this is the service that gets restarted in the autoheal process")
 	autohealSyncLatencyToleranceSecondsFlag        = flag.Int(AutohealSyncLatencyToleranceSecondsFlagName, 120, "how far behind live the node is allowed to fall before autohealing actions are attempted")
 	autohealSyncToLiveToleranceSecondsFlag         = flag.Int(AutohealSyncToLiveToleranceSecondsFlagName, 12, "how close to the current time the node must resync to before being considered in sync again")
 	autohealInitialDelaySecondsFlag                = flag.Int(AutohealInitialDelaySecondsFlagName, 0, "initial delay before autoheal attempts a restart. useful for allowing longer startup time for the chain, like during statesync initialization")
 	downtimeRestartThresholdSecondsFlag            = flag.Int(DowntimeRestartThresholdSecondsFlagName, DefaultDowntimeRestartThresholdSeconds, "how many continuous seconds the endpoint being monitored has to be offline or unresponsive before autohealing will be attempted")
 	noNewBlocksRestartThresholdSecondsFlag         = flag.Int(NoNewBlocksRestartThresholdSecondsFlagName, DefaultNoNewBlocksRestartThresholdSeconds, "how many continuous seconds the endpoint being monitored has not produce a new bloc before autohealing will be attempted")
 	healthChecksTimeoutSecondsFlag                 = flag.Int(HealthChecksTimeoutSecondsFlagName, DefaultHealthChecksTimeoutSecondsFlagName, "max number of seconds doctor will wait for a health check response from the endpoint")
+	rpcMaxRetriesFlag                              = flag.Int(RPCMaxRetriesFlagName, DefaultRPCMaxRetries, fmt.Sprintf("max number of times to retry a request to the endpoint that failed with a transient error (e.g. a connection reset) or gateway error before counting it as failed, each attempt times out after %s, 0 disables retries", HealthChecksTimeoutSecondsFlagName))
+	rpcRetryBackoffMillisecondsFlag                = flag.Int(RPCRetryBackoffMillisecondsFlagName, DefaultRPCRetryBackoffMilliseconds, "number of milliseconds to wait before retrying a failed request to the endpoint, doubled with jitter for each further retry")
 	autohealRestartDelaySecondsFlag                = flag.Int(AutohealRestartDelaySecondsFlagName, DefaultAutohealRestartDelaySeconds, fmt.Sprintf("number of seconds autohealing routines will wait to restart the endpoint, effective from the last time it was restarted and over riding the values %s %s", DowntimeRestartThresholdSecondsFlagName, NoNewBlocksRestartThresholdSecondsFlagName))
+	logFormatFlag                                  = flag.String(LogFormatFlagName, DefaultLogFormat, fmt.Sprintf("format to output log messages in, supported formats are %v", logging.ValidFormats))
+	maxChartPointsPerSeriesFlag                    = flag.Int(MaxChartPointsPerSeriesFlagName, DefaultMaxChartPointsPerSeries, "maximum number of points to render per chart series in interactive mode, retained samples beyond this are downsampled preserving the min and max values")
+	maxMessagesToRetainFlag                        = flag.Int(MaxMessagesToRetainFlagName, DefaultMaxMessagesToRetain, "maximum number of log messages retained for scrolling back through in interactive mode")
+	metricSigningAlgorithmFlag                     = flag.String(MetricSigningAlgorithmFlagName, "", fmt.Sprintf("if set, algorithm to use for signing metrics collected to files so they can be verified as unaltered using the verify-metrics command, supported algorithms are %v", audit.ValidSigningAlgorithms))
+	metricSigningKeyFilepathFlag                   = flag.String(MetricSigningKeyFilepathFlagName, "", "filepath to the key to sign collected metrics with, the raw secret for hmac-sha256 or a PEM encoded PKCS #8 private key for ed25519")
+	metricVerificationKeyFilepathFlag              = flag.String(MetricVerificationKeyFilepathFlagName, "", "filepath to the key to verify signed metrics with, the raw secret for hmac-sha256 or a PEM encoded public key for ed25519, defaults to the signing key")
+	sampleStoreBackendFlag                         = flag.String(SampleStoreBackendFlagName, "", fmt.Sprintf("if set, backend to persist metric samples to so that synthetic metrics can be calculated using samples from previous runs, supported backends are %v", store.ValidBackends))
+	sampleStoreFilepathFlag                        = flag.String(SampleStoreFilepathFlagName, DefaultSampleStoreFilepath, "filepath of the database to persist metric samples to")
+	sampleStoreRetentionHoursFlag                  = flag.Int(SampleStoreRetentionHoursFlagName, DefaultSampleStoreRetentionHours, "how many hours persisted metric samples are retained for before being deleted, 0 retains samples forever")
+	peerChurnAlertThresholdPerMinuteFlag           = flag.Float64(PeerChurnAlertThresholdPerMinuteFlagName, DefaultPeerChurnAlertThresholdPerMinute, "number of peers connected to or disconnected from per minute above which a node's peer churn is considered abnormal and alerted on, 0 disables alerting")
+	peerMinRecvBytesPerSecondFlag                  = flag.Float64(PeerMinRecvBytesPerSecondFlagName, DefaultPeerMinRecvBytesPerSecond, "average bytes per second a peer connected for at least a minute must send the node to not be considered low quality, a node with no peers or mostly low quality peers is alerted on and has its peers refreshed when frozen, 0 disables judging peers")
+	cloudWatchFlushIntervalSecondsFlag             = flag.Int(CloudWatchFlushIntervalSecondsFlagName, DefaultCloudWatchFlushIntervalSeconds, "how often metrics queued for cloudwatch are sent in batches, metrics are also sent as soon as a full batch of 1000 metrics is queued")
+	cloudWatchMaxQueuedMetricsFlag                 = flag.Int(CloudWatchMaxQueuedMetricsFlagName, DefaultCloudWatchMaxQueuedMetrics, "maximum number of metrics to queue for sending to cloudwatch, metrics collected while the queue is full are dropped")
+	cloudWatchAggregationPeriodFlag                = flag.Int(CloudWatchAggregationPeriodFlagName, DefaultCloudWatchAggregationSeconds, "how many seconds of samples of each metric to aggregate into a single metric (with the count, sum, minimum and maximum of the samples) before sending it to cloudwatch, 0 sends every sample")
+	metricChannelBufferSizeFlag                    = flag.Int(MetricChannelBufferSizeFlagName, DefaultMetricChannelBufferSize, "maximum number of each kind of metric waiting to be displayed and collected, metrics measured while the display is that far behind are dropped instead of blocking the routines watching the node")
+	logChannelBufferSizeFlag                       = flag.Int(LogChannelBufferSizeFlagName, DefaultLogChannelBufferSize, "maximum number of log messages waiting to be displayed, beyond which the oldest messages are dropped")
+	eventChannelBufferSizeFlag                     = flag.Int(EventChannelBufferSizeFlagName, DefaultEventChannelBufferSize, "maximum number of incident and lifecycle events waiting to be handled by the display and each alerter, beyond which events are dropped")
+	staleResponseBehaviorFlag                      = flag.String(StaleResponseBehaviorFlagName, DefaultStaleResponseBehavior, fmt.Sprintf("how to handle status responses with a block time older than a previously seen response (e.g. when served stale by a caching proxy), supported behaviors are %v", ValidStaleResponseBehaviors))
+	incidentPublishersFlag                         = flag.String(IncidentPublishersFlagName, "", fmt.Sprintf("where to publish incident (e.g. node offline) and heal action events to, multiple publishers can be specified as a comma separated list, supported publishers are %v", incident.ValidPublishers))
+	incidentLogGroupNameFlag                       = flag.String(IncidentLogGroupNameFlagName, incident.DefaultLogGroupName, "name of the cloudwatch logs log group to publish incident events to")
+	incidentEventBusNameFlag                       = flag.String(IncidentEventBusNameFlagName, incident.DefaultEventBusName, "name of the eventbridge event bus to publish incident events to")
+	emailSMTPAddressFlag                           = flag.String(EmailSMTPAddressFlagName, "", fmt.Sprintf("host:port of the smtp server (e.g. smtp.example.com:587) to email incident events through when the %s incident publisher is used", incident.EmailPublisherName))
+	emailSMTPSecurityFlag                          = flag.String(EmailSMTPSecurityFlagName, incident.DefaultEmailSecurity, fmt.Sprintf("how to secure the connection to the smtp server, starttls upgrades a plaintext connection (e.g. port 587), tls connects over tls (e.g. port 465) and none sends email in plaintext, supported values are %v", incident.ValidEmailSecurities))
+	emailSMTPUsernameFlag                          = flag.String(EmailSMTPUsernameFlagName, "", "optional username to authenticate with the smtp server")
+	emailSMTPPasswordFlag                          = flag.String(EmailSMTPPasswordFlagName, "", fmt.Sprintf("optional password of %s", EmailSMTPUsernameFlagName))
+	emailFromFlag                                  = flag.String(EmailFromFlagName, "", "address to email incident events from, e.g. Doctor <doctor@example.com>")
+	emailRecipientsFlag                            = flag.String(EmailRecipientsFlagName, "", fmt.Sprintf("semicolon separated list of who to email incident events of each severity to in the form <severity>=<comma separated addresses>, e.g. critical=oncall@example.com,ops@example.com;info=ops@example.com, events of severities without recipients aren't emailed, supported severities are %v", incident.ValidSeverities))
+	emailSubjectTemplateFlag                       = flag.String(EmailSubjectTemplateFlagName, incident.DefaultEmailSubjectTemplate, "go template for the subject of emailed incident events")
+	emailBodyTemplateFilepathFlag                  = flag.String(EmailBodyTemplateFilepathFlagName, "", "optional filepath to a go template for the body of emailed incident events, defaults to a summary of the event")
+	telegramBotTokenFlag                           = flag.String(TelegramBotTokenFlagName, "", fmt.Sprintf("token of the telegram bot to send incident events from when the %s incident publisher is used, the bot must be a member of telegram_chat_id", incident.TelegramPublisherName))
+	telegramChatIdFlag                             = flag.String(TelegramChatIdFlagName, "", "id of the telegram chat (e.g. -1001234567890) or @username of the channel to send incident events to")
+	discordWebhookURLFlag                          = flag.String(DiscordWebhookURLFlagName, "", fmt.Sprintf("url of the discord webhook to post incident events to when the %s incident publisher is used", incident.DiscordPublisherName))
+	incidentPublisherSchedulesFlag                 = flag.String(IncidentPublisherSchedulesFlagName, "", fmt.Sprintf("optional semicolon separated list of when incident events of a severity are published to an incident publisher, each in the form <publisher>:<severity>=<comma separated HH:MM-HH:MM windows in UTC>, e.g. email:warning=09:00-21:00, events outside the windows are discarded and events of severities without a schedule are always published, supported severities are %v", incident.ValidSeverities))
+	execHooksFlag                                  = flag.String(ExecHooksFlagName, "", fmt.Sprintf("semicolon separated list of commands the %s incident publisher runs for incident events, each in the form <event type>[:<kind or heal action>]=<binary and arguments> (or * for every event), e.g. incident_opened:node_frozen=/usr/local/bin/rotate-peers, the event is passed as json on stdin, supported event types are %v", incident.ExecPublisherName, incident.ValidEventTypes))
+	execHookTimeoutSecondsFlag                     = flag.Int(ExecHookTimeoutSecondsFlagName, DefaultExecHookTimeoutSeconds, "how many seconds an exec hook, the exec-hook autoheal strategy's command or an autoheal pre restart command may run for before it is killed")
+	execHookMaxConcurrencyFlag                     = flag.Int(ExecHookMaxConcurrencyFlagName, DefaultExecHookMaxConcurrency, "maximum number of exec hook commands to run at once, further commands wait for a running command to exit")
+	publishAlertsFlag                              = flag.Bool(PublishAlertsFlagName, false, "whether alerts (e.g. high peer churn or a failing health check) are published to the incident publishers as they start firing, are escalated and resolve, instead of only being displayed")
+	alertCooldownSecondsFlag                       = flag.Int(AlertCooldownSecondsFlagName, DefaultAlertCooldownSeconds, "minimum number of seconds between publishing that the same alert started firing, so an alert flapping around its threshold is only published once")
+	alertEscalationSecondsFlag                     = flag.Int(AlertEscalationSecondsFlagName, DefaultAlertEscalationSeconds, "number of seconds an alert has to keep firing before it's published again as critical, 0 disables escalation")
+	webhookURLFlag                                 = flag.String(WebhookURLFlagName, "", fmt.Sprintf("URL to post metrics and/or incident events to when the %s metric collector or incident publisher is used", WebhookMetricCollector))
+	webhookSigningKeyFilepathFlag                  = flag.String(WebhookSigningKeyFilepathFlagName, "", "if set, filepath to a shared secret used to sign payloads posted to the webhook with hmac-sha256, sent as a hex encoded signature in the X-Doctor-Signature header")
+	webhookMaxRetriesFlag                          = flag.Int(WebhookMaxRetriesFlagName, DefaultWebhookMaxRetries, "maximum number of times to retry posting to the webhook if it's unavailable or responds with a server error")
+	blockIntervalP95AlertThresholdSecondsFlag      = flag.Float64(BlockIntervalP95AlertThresholdSecondsFlagName, DefaultBlockIntervalP95AlertThresholdSeconds, "95th percentile of seconds between blocks above which to alert that consensus is slow, 0 disables alerting")
+	fileMetricRoutesFlag                           = flag.String(FileMetricRoutesFlagName, "", "semicolon separated list of files to collect metrics to when using the file collector, each in the form <file name suffix>=<comma separated metric names or * for all metrics>, e.g. sync-status.json=SyncStatus;samples.json=LatestBlockHeight,SecondsBehindLive, defaults to a single file containing metrics with structured data")
+	diagnosticsAgentFlag                           = flag.Bool(DiagnosticsAgentFlagName, false, "controls whether a gops diagnostics agent is started so the goroutines, memory and gc stats of the running doctor can be inspected using the gops cli")
+	diagnosticsAgentAddressFlag                    = flag.String(DiagnosticsAgentAddressFlagName, DefaultDiagnosticsAgentAddress, "loopback address for the diagnostics agent to listen on, by default a random local port discoverable by running gops without arguments, addresses reachable from other hosts are refused as the agent doesn't support tls or authentication")
+	autohealStrategiesFlag                         = flag.String(AutohealStrategiesFlagName, DefaultAutohealStrategies, fmt.Sprintf("comma separated list of strategies to escalate through when autohealing a node that has fallen behind live, each in the form <strategy>[:<max attempts per incident>] (unlimited attempts if omitted), e.g. standby:1,restart-service:3,reboot-instance:1, supported strategies are %v", heal.ValidStrategies()))
+	autohealEscalationDelaySecondsFlag             = flag.Int(AutohealEscalationDelaySecondsFlagName, DefaultAutohealEscalationDelaySeconds, "how long to wait for a node to catch up after attempting a heal strategy before the next (possibly escalated) strategy is attempted")
+	referenceAPIAddressFlag                        = flag.String(ReferenceAPIAddressFlagName, "", "optional url of an rpc endpoint for the same chain (e.g. https://rpc.kava.io) to compare the node's block height against")
+	autohealBlocksBehindReferenceToleranceFlag     = flag.Int(AutohealBlocksBehindReferenceToleranceFlagName, DefaultAutohealBlocksBehindReferenceTolerance, "how many blocks behind the reference endpoint the node can be before it is considered out of sync, used instead of autoheal_sync_latency_tolerance_seconds when reference_api_address is set")
+	accessLogFilepathFlag                          = flag.String(AccessLogFilepathFlagName, "", "optional path of an access log of real client requests to the node (e.g. from nginx or envoy) to observe error rates and latencies from")
+	accessLogFormatFlag                            = flag.String(AccessLogFormatFlagName, DefaultAccessLogFormat, fmt.Sprintf("format of the access log, one of %v", accesslog.ValidFormats))
+	mempoolSizeAlertThresholdFlag                  = flag.Int(MempoolSizeAlertThresholdFlagName, DefaultMempoolSizeAlertThreshold, "number of unconfirmed transactions above which a node's mempool is considered backed up and alerted on once it stays above the threshold for mempool_size_alert_duration_seconds, 0 disables alerting")
+	mempoolSizeAlertDurationSecondsFlag            = flag.Int(MempoolSizeAlertDurationSecondsFlagName, DefaultMempoolSizeAlertDurationSeconds, "how long a node's mempool size must stay above mempool_size_alert_threshold before it is alerted on")
+	anomalyZScoreThresholdFlag                     = flag.Float64(AnomalyZScoreThresholdFlagName, anomaly.DefaultZScoreThreshold, "modified z-score (robust standard deviations from the median) of a node's status check latency or block production rate against its own baseline above which an anomaly is detected and published as an event, 0 disables detection")
+	anomalyBaselineSamplesFlag                     = flag.Int(AnomalyBaselineSamplesFlagName, anomaly.DefaultBaselineSamples, fmt.Sprintf("number of a node's most recent samples of each signal to use as its baseline for anomaly detection, samples are only scored once the baseline has %d samples or is full", anomaly.MinBaselineSamples))
+	anomalyBaselinesFilepathFlag                   = flag.String(AnomalyBaselinesFilepathFlagName, "", "optional filepath (e.g. ~/.kava/doctor/anomaly-baselines.json) to save the baselines of each node to every minute and on shutdown, loaded on startup so anomaly detection resumes without collecting new baselines")
+	consensusFrozenThresholdSecondsFlag            = flag.Int(ConsensusFrozenThresholdSecondsFlagName, DefaultConsensusFrozenThresholdSeconds, "how many seconds a node's consensus can stay in the same height, round and step before it is considered frozen, 0 disables detection")
+	autohealFrozenConsensusFlag                    = flag.Bool(AutohealFrozenConsensusFlagName, false, "whether autohealing should restart the blockchain service when consensus is frozen, independently of no_new_blocks_restart_threshold_seconds")
+	autohealStandbyMinHealthyInstancesFlag         = flag.Int(AutohealStandbyMinHealthyInstancesFlagName, DefaultAutohealStandbyMinHealthyInstances, "minimum number of other healthy in service instances that must remain in the autoscaling group (or healthy targets in each target group) for autohealing to place a node on standby (or deregister it from its target groups), 0 allows taking the last healthy instance out of service")
+	autohealMaxRestartsPerHourFlag                 = flag.Int(AutohealMaxRestartsPerHourFlagName, 0, "max number of times autohealing restarts the blockchain service in any rolling hour, once hit autohealing stops until reset with the reset-autoheal command or SIGUSR2, 0 allows unlimited restarts")
+	autohealReplaceMethodFlag                      = flag.String(AutohealReplaceMethodFlagName, heal.DefaultReplaceMethod, fmt.Sprintf("how the replace-instance autoheal strategy has the autoscaling group replace the instance, supported methods are %v", heal.ValidReplaceMethods))
+	autohealReplaceMinHealthyInstancesFlag         = flag.Int(AutohealReplaceMinHealthyInstancesFlagName, DefaultAutohealReplaceMinHealthyInstances, "minimum number of other healthy in service instances that must remain in the autoscaling group for the replace-instance autoheal strategy to replace the instance, 0 allows replacing the last healthy instance")
+	autohealReplaceLifecycleHookNameFlag           = flag.String(AutohealReplaceLifecycleHookNameFlagName, "", "optional name of the autoscaling group's termination lifecycle hook, once the replace-instance autoheal strategy has the instance replaced it waits for the hook, stops the blockchain service and completes the lifecycle action so termination continues")
+	autohealTargetGroupARNsFlag                    = flag.String(AutohealTargetGroupARNsFlagName, "", "comma separated list of arns of the load balancer target groups the deregister-target autoheal strategy deregisters the instance from until it catches up")
+	autohealRoute53HostedZoneIdFlag                = flag.String(AutohealRoute53HostedZoneIdFlagName, "", "id of the Route53 hosted zone with the node's weighted record for the drain-dns-record autoheal strategy to set to weight 0 until the node catches up")
+	autohealRoute53RecordNameFlag                  = flag.String(AutohealRoute53RecordNameFlagName, "", "name of the node's weighted record (e.g. rpc.example.com) for the drain-dns-record autoheal strategy")
+	autohealRoute53RecordTypeFlag                  = flag.String(AutohealRoute53RecordTypeFlagName, heal.DefaultRoute53RecordType, "type of the node's weighted record for the drain-dns-record autoheal strategy")
+	autohealRoute53SetIdentifierFlag               = flag.String(AutohealRoute53SetIdentifierFlagName, "", "set identifier of the node's weighted record for the drain-dns-record autoheal strategy")
+	autohealExecHookCommandFlag                    = flag.String(AutohealExecHookCommandFlagName, "", "binary and arguments (e.g. \"/usr/local/bin/rotate-peers --clear-addrbook\") run as the doctor's user by the exec-hook autoheal strategy, the node being healed is passed as json on stdin and a non zero exit status fails the strategy")
+	autohealRefreshPeersFlag                       = flag.String(AutohealRefreshPeersFlagName, "", "optional comma separated list of known good peers (<node id>@<host>:<port>) for the refresh-peers autoheal strategy (and restarts of frozen nodes with mostly low quality peers) to have the node dial as persistent peers, falling back to setting them as the node's persistent peers in config.toml and restarting the blockchain service")
+	autohealRefreshSeedsFlag                       = flag.String(AutohealRefreshSeedsFlagName, "", "optional comma separated list of known good seeds (<node id>@<host>:<port>) for the refresh-peers autoheal strategy (and restarts of frozen nodes with mostly low quality peers) to set as the node's seeds in config.toml before clearing its address book and restarting the blockchain service")
+	autohealSnapshotURLFlag                        = flag.String(AutohealSnapshotURLFlagName, "", "optional url of a tar (or gzipped tar) snapshot of the node's home directory for the restore-state autoheal strategy to restore the node's data from, if not set the strategy enables state sync using the statesync.rpc_servers in the node's config.toml instead")
+	autohealRestoreStateThresholdSecondsFlag       = flag.Int64(AutohealRestoreStateThresholdSecondsFlagName, heal.DefaultRestoreStateThresholdSeconds, "how many seconds behind live the node must be for the restore-state autoheal strategy to replace its data (except the priv validator state) with state restored from a snapshot or state sync, skipped when escalating if the node is less far behind")
+	autohealStateSyncThresholdSecondsFlag          = flag.Int(AutohealStateSyncThresholdSecondsFlagName, DefaultAutohealStateSyncThresholdSeconds, "how many continuous seconds a node restoring a state sync snapshot may go without synching a new block before autohealing will restart it, used instead of no_new_blocks_restart_threshold_seconds while the node is restoring the snapshot, 0 never restarts a node restoring a snapshot")
+	autohealServiceManagerFlag                     = flag.String(AutohealServiceManagerFlagName, heal.DefaultServiceManager, fmt.Sprintf("service manager used to restart, stop and start the blockchain service, supported service managers are %v", heal.ValidServiceManagers))
+	autohealRestartCommandFlag                     = flag.String(AutohealRestartCommandFlagName, "", "binary and arguments (e.g. \"docker restart kava\") run as the doctor's user to restart the blockchain service when autoheal_service_manager is exec, required for that service manager, which can only restart the service so can't be used with the restore-state autoheal strategy")
+	autohealPreRestartCommandsFlag                 = flag.String(AutohealPreRestartCommandsFlagName, "", "optional semicolon separated list of commands (binary and arguments) run in order as the doctor's user before each restart of the blockchain service, e.g. to snapshot the node's database or drain it from a load balancer, the service isn't restarted if any command fails or runs past exec_hook_timeout_seconds")
+	autohealRestartVerificationSecondsFlag         = flag.Int(AutohealRestartVerificationSecondsFlagName, 0, "how many seconds to wait after restarting the blockchain service for the node to respond to status checks and sync a new block before the restart is considered failed (escalating to the next autoheal strategy), 0 considers the restart done once the service manager returns")
+	autohealServiceSudoFlag                        = flag.Bool(AutohealServiceSudoFlagName, true, "whether to run the service manager's commands with non-interactive sudo, set to false to run them as the doctor's user (e.g. when allowed by polkit), ignored for the windows and exec service managers")
+	autohealServiceHelperSocketFlag                = flag.String(AutohealServiceHelperSocketFlagName, "", "optional path of the unix socket of a privileged service helper (started with the service-helper command) to ask to restart, stop and start the blockchain service instead of managing it directly")
+	autohealMaxRestartsPerDayFlag                  = flag.Int(AutohealMaxRestartsPerDayFlagName, 0, "max number of times autohealing restarts the blockchain service in any rolling day, once hit autohealing stops until reset with the reset-autoheal command or SIGUSR2, 0 allows unlimited restarts")
+	metricFileDirectoryFlag                        = flag.String(MetricFileDirectoryFlagName, "", "directory to create metric files in when using the file or csv collector, defaults to the current working directory")
+	compressRotatedMetricFilesFlag                 = flag.Bool(CompressRotatedMetricFilesFlagName, false, "whether to gzip metric files once they are rotated when using the file or csv collector")
+	metricFileRetentionDaysFlag                    = flag.Int(MetricFileRetentionDaysFlagName, 0, "if greater than 0, rotated metric files older than this many days are deleted when using the file or csv collector")
+	nodeHomeFlag                                   = flag.String(NodeHomeFlagName, DefaultNodeHome, "home directory of the node to run preflight checks against using the preflight-node command, to check the free space of using the disk health check, and to restore the data of using the restore-state autoheal strategy")
+	expectedGenesisSHA256Flag                      = flag.String(ExpectedGenesisSHA256FlagName, "", "if set, hex encoded sha256 hash the node's genesis file is expected to have when running the preflight-node command")
+	expectedNodeIdFlag                             = flag.String(ExpectedNodeIdFlagName, "", "if set, id of the node the endpoint is expected to resolve to, autohealing is refused (and an incident opened) while the endpoint resolves to a different node")
+	expectedNodeMonikerFlag                        = flag.String(ExpectedNodeMonikerFlagName, "", "if set, moniker of the node the endpoint is expected to resolve to, autohealing is refused (and an incident opened) while the endpoint resolves to a node with a different moniker")
+	chainConsistencyCheckIntervalSecondsFlag       = flag.Int(ChainConsistencyCheckIntervalSecondsFlagName, DefaultChainConsistencyCheckIntervalSeconds, "how often (in seconds) to compare the block and app hashes of a recent block on the node against reference_api_address (if set) to detect the node being on a fork or having corrupt state, 0 disables the comparison")
+	votingPowerCheckIntervalSecondsFlag            = flag.Int(VotingPowerCheckIntervalSecondsFlagName, DefaultVotingPowerCheckIntervalSeconds, "how often (in seconds) to compare the voting power of the validators that signed the latest commit against the validator set (queried from reference_api_address if set, otherwise the node) to detect the chain itself halting or being at risk of halting, 0 disables the check")
+	chainDegradedVotingPowerPercentFlag            = flag.Float64(ChainDegradedVotingPowerPercentFlagName, DefaultChainDegradedVotingPowerPercent, "percent of voting power online below which the chain is considered degraded, autoheal restarts are suppressed while the chain is degraded as the chain can't produce blocks with less than two thirds of voting power online however many times the node is restarted")
+	upgradeAPIAddressFlag                          = flag.String(UpgradeAPIAddressFlagName, "", "optional url of the node's cosmos rest api (e.g. http://localhost:1317) to query the upgrade module for scheduled upgrades, autohealing is suppressed while the node is halted for an upgrade")
+	upgradeHeightFlag                              = flag.Int64(UpgradeHeightFlagName, 0, "optional height of a scheduled upgrade, autohealing is suppressed while the node is halted for the upgrade")
+	upgradeWindowSecondsFlag                       = flag.Int(UpgradeWindowSecondsFlagName, DefaultUpgradeWindowSeconds, "max number of seconds autohealing is suppressed for after the node halts for an upgrade, if it doesn't produce blocks past the upgrade height before then")
+	chainIdFlag                                    = flag.String(ChainIdFlagName, "", "if set, chain id (e.g. kava_2222-10) the node is expected to be on, autohealing is refused (and an incident opened) while the node is on a different chain")
+	metricEmissionLimitsFlag                       = flag.String(MetricEmissionLimitsFlagName, "", "semicolon separated list of limits on how often a metric collector emits metrics (for controlling backend costs), each in the form <collector>.<metric name or * for metrics without their own limit>=<limit>[,<limit>] where a limit is sample:<emit 1 of every N metrics> or rate:<max metrics per minute>, applied to each series (e.g. node) of a metric separately, e.g. cloudwatch.StatusCheckLatencyMilliseconds=sample:10;cloudwatch.*=rate:60")
+	daemonFlag                                     = flag.Bool(DaemonFlagName, false, "if set, runs non-interactively as a long lived agent, writing its process id to pid_filepath and reloading the config file on SIGHUP without dropping in-memory samples")
+	watchConfigFileFlag                            = flag.Bool(WatchConfigFileFlagName, false, "if set, reloads the config file whenever it changes (in addition to on SIGHUP in daemon mode) without dropping in-memory samples, only supported in non-interactive mode")
+	pidFilepathFlag                                = flag.String(PIDFilepathFlagName, DefaultPIDFilepath, "filepath to write the process id of the doctor to when running in daemon mode")
+	maintenanceFilepathFlag                        = flag.String(MaintenanceFilepathFlagName, DefaultMaintenanceFilepath, "filepath that puts the doctor in maintenance mode (autohealing paused and metrics tagged with maintenance=true) while it exists, maintenance mode can also be toggled by sending the doctor SIGUSR1")
+	checkFlag                                      = flag.Bool(CheckFlagName, false, "if set, performs a single health check of the node instead of watching it, printing a json summary and exiting with 0 if the node is healthy, 1 if it's behind live and 2 if it's unreachable")
+	rpcProbeMethodsFlag                            = flag.String(RPCProbeMethodsFlagName, "", "semicolon separated list of json rpc methods of the node to call every default_monitoring_interval_seconds, emitting whether each method succeeded and its latency, each in the form <method>[=<json params>], e.g. abci_info;block_results;tx_search={\"query\":\"tx.height>0\",\"per_page\":\"1\"}")
+	websocketMonitoringFlag                        = flag.Bool(WebsocketMonitoringFlagName, false, "whether to subscribe to new block events over the node's websocket interface, collecting whether the subscription is connected and how long after each block its event is delivered")
+	pushNewBlocksFlag                              = flag.Bool(PushNewBlocksFlagName, false, "if set, the sync status of the node is checked whenever a new block event is received over the node's websocket interface instead of every default_monitoring_interval_seconds, falling back to polling while the subscription is down or no new blocks are received")
+	vantageEndpointsFlag                           = flag.String(VantageEndpointsFlagName, "", "semicolon separated list of urls that reach the same logical endpoint as kava_api_address from other regions (e.g. regional proxies), each in the form <region>=<url>, whose status latencies are compared to the latency from region every default_monitoring_interval_seconds, e.g. eu-west-1=https://eu.rpc.data.kava.io;ap-southeast-1=https://ap.rpc.data.kava.io")
+	statusServerAddressFlag                        = flag.String(StatusServerAddressFlagName, "", fmt.Sprintf("optional address (e.g. localhost:8080) to serve the samples and computed metrics (e.g. hash rate, uptime and latency percentiles) of each node on as json at /api/v1/nodes/{id}/metrics, the api is unauthenticated plain http unless %s and %s are set so should only listen on a loopback address otherwise, disabled if empty", StatusServerAuthTokenFlagName, StatusServerTLSCertFilepathFlagName))
+	statusServerAuthTokenFlag                      = flag.String(StatusServerAuthTokenFlagName, "", "optional token the status api requires requests to send as a bearer token")
+	statusServerTLSCertFilepathFlag                = flag.String(StatusServerTLSCertFilepathFlagName, "", "optional path of the pem encoded certificate the status api is served over https with, served over plain http if empty")
+	statusServerTLSKeyFilepathFlag                 = flag.String(StatusServerTLSKeyFilepathFlagName, "", fmt.Sprintf("path of the pem encoded key of %s", StatusServerTLSCertFilepathFlagName))
+	fleetAggregatorURLFlag                         = flag.String(FleetAggregatorURLFlagName, "", "optional url of a doctor running the fleet command (e.g. https://fleet.example.com:8090) to push a report of the latest samples of the endpoint and its nodes to every fleet_push_interval_seconds, disabled if empty")
+	fleetAgentIdFlag                               = flag.String(FleetAgentIdFlagName, "", "id this doctor reports to the fleet aggregator as, defaults to the hostname")
+	fleetPushIntervalSecondsFlag                   = flag.Int(FleetPushIntervalSecondsFlagName, DefaultFleetPushIntervalSeconds, "how often to push a report to fleet_aggregator_url")
+	fleetAuthTokenFlag                             = flag.String(FleetAuthTokenFlagName, "", "optional token sent as a bearer token with reports pushed to fleet_aggregator_url, and required by the fleet command of reports and requests for the fleet summary")
+	fleetServerAddressFlag                         = flag.String(FleetServerAddressFlagName, DefaultFleetServerAddress, fmt.Sprintf("address the fleet command listens for reports from other doctors on, serving the fleet-wide summary as json at /api/v1/fleet, the fleet command refuses to listen on an address reachable from other hosts unless %s, %s and %s are set", FleetAuthTokenFlagName, FleetServerTLSCertFilepathFlagName, FleetServerTLSKeyFilepathFlagName))
+	fleetServerTLSCertFilepathFlag                 = flag.String(FleetServerTLSCertFilepathFlagName, "", "optional path of the pem encoded certificate the fleet command serves reports and the fleet summary over https with, served over plain http if empty")
+	fleetServerTLSKeyFilepathFlag                  = flag.String(FleetServerTLSKeyFilepathFlagName, "", fmt.Sprintf("path of the pem encoded key of %s", FleetServerTLSCertFilepathFlagName))
+	fleetStaleSecondsFlag                          = flag.Int(FleetStaleSecondsFlagName, DefaultFleetStaleSeconds, "how many seconds after its last report the fleet command considers a doctor stale, excluding its report from the summary of its endpoint")
+	controlServerAddressFlag                       = flag.String(ControlServerAddressFlagName, "", fmt.Sprintf("optional address (e.g. 0.0.0.0:9090) to serve the grpc control api on, for querying the node's health, streaming metrics, toggling maintenance mode and healing the node, served over mutual tls so requires %s, %s and %s, disabled if empty", ControlTLSCertFilepathFlagName, ControlTLSKeyFilepathFlagName, ControlTLSClientCAFilepathFlagName))
+	controlTLSCertFilepathFlag                     = flag.String(ControlTLSCertFilepathFlagName, "", "path of the pem encoded certificate the control api is served with")
+	controlTLSKeyFilepathFlag                      = flag.String(ControlTLSKeyFilepathFlagName, "", fmt.Sprintf("path of the pem encoded key of %s", ControlTLSCertFilepathFlagName))
+	controlTLSClientCAFilepathFlag                 = flag.String(ControlTLSClientCAFilepathFlagName, "", "path of a pem encoded bundle of the certificate authorities that sign the client certificates control api clients must present")
+	controlHealStrategiesFlag                      = flag.String(ControlHealStrategiesFlagName, DefaultControlHealStrategies, fmt.Sprintf("comma separated list of the autoheal strategies control api clients can heal the node with (restart-service can also be requested as restart), destructive strategies (e.g. restore-state, replace-instance and reboot-instance) must be added explicitly, supported strategies are %v", heal.ValidStrategies()))
+	regionFlag                                     = flag.String(RegionFlagName, DefaultRegion, "name of the region the doctor is running in, used as the region dimension of the latency to kava_api_address when comparing it to the latency from vantage_endpoints")
+	slosFlag                                       = flag.String(SLOsFlagName, "", "semicolon separated list of service level objectives to track the error budget of, emitting the remaining error budget and warning when it burns too fast, each in the form <name>=uptime:<target percent>:<window days> or <name>=latency:<target percent of status checks faster than threshold>:<threshold milliseconds>:<window days>, e.g. availability=uptime:99.9:30;status-latency=latency:99:500:30")
+	nodeGroupsFlag                                 = flag.String(NodeGroupsFlagName, "", "semicolon separated list of named groups of nodes to emit and display rollup metrics (worst seconds behind live, mean uptime and unhealthy count) for, each in the form <group>=<node id or endpoint url>[,<node id or endpoint url>], e.g. public-api=https://rpc.data.kava.io;validators=06ff9460163caac703c44da1b2e3108e1ba087cd")
+	annotationsFilepathFlag                        = flag.String(AnnotationsFilepathFlagName, "", "optional path of a file to record annotations (e.g. deploys) to using the annotate command, watched for annotations to show on the gui timeline")
+	hostMetricsFlag                                = flag.Bool(HostMetricsFlagName, false, "whether to collect metrics for the load average, memory usage and network and disk io (including latency) of the host running the node, sampled from the proc filesystem every default_monitoring_interval_seconds")
+	hostProcPathFlag                               = flag.String(HostProcPathFlagName, host.DefaultProcPath, "path the proc filesystem of the host running the node is mounted at (e.g. when running the doctor in a container with the host's /proc mounted)")
+	logLevelFlag                                   = flag.String(LogLevelFlagName, DefaultLogLevel, fmt.Sprintf("minimum severity of log messages to output, supported levels are %v, overridden to debug when debug is enabled", logging.ValidLevels))
+	healthChecksFlag                               = flag.String(HealthChecksFlagName, "", fmt.Sprintf("comma separated list of health checks to run, each at its own interval, in the form <check>[:<interval seconds>] (default_monitoring_interval_seconds if omitted), e.g. peers:30,disk:300, supported checks are %v", checks.ValidChecks()))
+	healthCheckMinPeersFlag                        = flag.Int(HealthCheckMinPeersFlagName, DefaultHealthCheckMinPeers, "minimum number of peers the node must be connected to for the peers health check to pass")
+	healthCheckDiskMinFreePercentFlag              = flag.Float64(HealthCheckDiskMinFreePercentFlagName, DefaultHealthCheckDiskMinFreePercent, "minimum percent of free space the filesystem of node_home must have for the disk health check to pass")
+	evmRPCAddressFlag                              = flag.String(EVMRPCAddressFlagName, "", "url of the node's evm json rpc api (e.g. http://localhost:8545) to check using the evm health check")
+	restAPIAddressFlag                             = flag.String(RESTAPIAddressFlagName, "", "url of the node's cosmos rest api (e.g. http://localhost:1317) to query health_check_query_account from using the query health check")
+	kavaAPIAuthFlag                                = flag.String(KavaAPIAuthFlagName, "", fmt.Sprintf("optional credentials to send with every request to %s (e.g. when it's behind an authenticating proxy) in the form basic:<username>:<password>, bearer:<token> or header:<name>:<value>, multiple credentials (e.g. several headers) can be separated by semicolons", KavaAPIAddressFlagName))
+	referenceAPIAuthFlag                           = flag.String(ReferenceAPIAuthFlagName, "", fmt.Sprintf("optional credentials to send with every request to %s (e.g. when it's behind an authenticating proxy) in the form basic:<username>:<password>, bearer:<token> or header:<name>:<value>, multiple credentials (e.g. several headers) can be separated by semicolons", ReferenceAPIAddressFlagName))
+	restAPIAuthFlag                                = flag.String(RESTAPIAuthFlagName, "", fmt.Sprintf("optional credentials to send with every request to %s (e.g. when it's behind an authenticating proxy) in the form basic:<username>:<password>, bearer:<token> or header:<name>:<value>, multiple credentials (e.g. several headers) can be separated by semicolons", RESTAPIAddressFlagName))
+	upgradeAPIAuthFlag                             = flag.String(UpgradeAPIAuthFlagName, "", fmt.Sprintf("optional credentials to send with every request to %s (e.g. when it's behind an authenticating proxy) in the form basic:<username>:<password>, bearer:<token> or header:<name>:<value>, multiple credentials (e.g. several headers) can be separated by semicolons", UpgradeAPIAddressFlagName))
+	httpProxyURLFlag                               = flag.String(HTTPProxyURLFlagName, "", "optional url of the http(s) proxy (e.g. http://proxy.internal:3128) to make requests to the kava, reference, rest, evm and upgrade apis and the uptime probe through, defaults to the proxy set by the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables")
+	tlsCAFilepathFlag                              = flag.String(TLSCAFilepathFlagName, "", "optional path of a pem encoded bundle of certificate authorities (e.g. a private ca internal endpoints are signed with) to trust in addition to the system's when connecting to the kava, reference, rest, evm and upgrade apis and the uptime probe")
+	tlsClientCertFilepathFlag                      = flag.String(TLSClientCertFilepathFlagName, "", fmt.Sprintf("optional path of a pem encoded client certificate to present to endpoints that require one (mutual tls), requires %s", TLSClientKeyFilepathFlagName))
+	tlsClientKeyFilepathFlag                       = flag.String(TLSClientKeyFilepathFlagName, "", fmt.Sprintf("optional path of the pem encoded key of %s", TLSClientCertFilepathFlagName))
+	tlsInsecureSkipVerifyFlag                      = flag.Bool(TLSInsecureSkipVerifyFlagName, false, "whether to skip verifying the certificates of endpoints, only intended for testing as it allows requests to be intercepted")
+	chainTypeFlag                                  = flag.String(ChainTypeFlagName, DefaultChainType, fmt.Sprintf("type of chain the monitored node is on, supported types are %v, cosmos monitors any other cometbft (tendermint) chain (e.g. osmosis or the cosmos hub) and requires %s to be set", kava.ValidChainTypes, KavaAPIAddressFlagName))
+	statusEndpointPathFlag                         = flag.String(StatusEndpointPathFlagName, DefaultStatusEndpointPath, "path of the endpoint to request the status of the node from, for nodes served through a gateway that changes it")
+	statusFieldsFlag                               = flag.String(StatusFieldsFlagName, "", "comma separated list of the fields of the status response to parse the node's status from in the form <value>=<dot separated path>, e.g. latest_block_height=sync_info.height, values that are omitted use the field of the tendermint status response")
+	uptimeProbesFlag                               = flag.String(UptimeProbesFlagName, DefaultUptimeProbes, fmt.Sprintf("comma separated list of the endpoints to probe when sampling the uptime of the node, the node is only up if every probe succeeds, supported probes are %v, the status is always probed", ValidUptimeProbes))
+	otlpEndpointFlag                               = flag.String(OTLPEndpointFlagName, "", fmt.Sprintf("base url of the otlp/http receiver of an opentelemetry collector (e.g. http://otel-collector:4318) to export metrics to when the %s metric collector is used", OTLPMetricCollector))
+	otlpHeadersFlag                                = flag.String(OTLPHeadersFlagName, "", "optional comma separated list of headers to send with every request to otlp_endpoint in the form <name>=<value>, e.g. authorization=Bearer <token>")
+	otlpResourceAttributesFlag                     = flag.String(OTLPResourceAttributesFlagName, "", "optional comma separated list of resource attributes to export metrics and spans with in the form <key>=<value>, e.g. service.name=doctor,deployment.environment=mainnet, service.name defaults to doctor")
+	otlpTracesFlag                                 = flag.Bool(OTLPTracesFlagName, false, "whether the otlp collector also exports a span for each run of a health check")
+	healthCheckQueryAccountFlag                    = flag.String(HealthCheckQueryAccountFlagName, "", "optional address of an account the query health check queries from rest_api_address (in addition to a recent block) to verify the node serves queries")
+	syntheticTxCommandFlag                         = flag.String(SyntheticTxCommandFlagName, "", "binary and arguments of a command that signs a minimal transaction (e.g. a self transfer from a funded key) and prints it base64 encoded, broadcast by the synthetic-tx health check to measure how long the node takes to include it in a block")
+	healthCheckTimeoutsFlag                        = flag.String(HealthCheckTimeoutsFlagName, "", "comma separated list of per check timeouts in the form <check>:<seconds>, e.g. sync-status:30,uptime-probe:2, checks that are omitted use health_check_timeout_seconds")
+	healthCheckRetriesFlag                         = flag.String(HealthCheckRetriesFlagName, "", "comma separated list of how many times to retry a failed attempt of a check before reporting it as failed in the form <check>:<retries>, e.g. peers:2, checks that are omitted aren't retried")
+	healthCheckRetryBackoffsFlag                   = flag.String(HealthCheckRetryBackoffsFlagName, "", fmt.Sprintf("comma separated list of how long to wait before the first retry of a check (doubling for each retry after that) in the form <check>:<seconds>, e.g. peers:5, checks that are omitted wait %d second", DefaultHealthCheckRetryBackoffSeconds))
+	yesFlag                                        = flag.Bool(YesFlagName, false, "if set, the heal command heals the node without asking for confirmation")
+	dryRunFlag                                     = flag.Bool(DryRunFlagName, false, "if set, the heal command prints the heal action it would take (after checking its prerequisites) instead of taking it")
+	probeEndpointAddressesFlag                     = flag.Bool(ProbeEndpointAddressesFlagName, false, "if set, doctor resolves the host of kava_api_address every interval and probes each ip address it resolves to individually, for observing every node behind a load balanced endpoint")
+	displayTimeZoneFlag                            = flag.String(DisplayTimeZoneFlagName, DefaultDisplayTimeZone, "time zone to display times in across log messages, the cli, the gui and check results, either Local for the time zone of the host or a name from the IANA time zone database, e.g. America/Los_Angeles")
+	timestampFormatFlag                            = flag.String(TimestampFormatFlagName, DefaultTimestampFormat, "format to display times in across log messages, the cli and the gui, either one of [rfc3339 rfc3339nano datetime kitchen] or a go time layout, e.g. 2006-01-02 15:04:05 MST")
 )
 
+// AutohealStrategy wraps the name of a heal strategy and
+// how many times it can be attempted per incident before escalating
+type AutohealStrategy struct {
+	Name string
+	// 0 allows unlimited attempts
+	MaxAttempts int
+}
+
+// HealthCheckSchedule wraps the name of a health check,
+// how often it's run and how failed attempts are retried
+type HealthCheckSchedule struct {
+	Name string
+	// 0 runs the check every default monitoring interval
+	IntervalSeconds int
+	// 0 uses the health check timeout
+	TimeoutSeconds      int
+	Retries             int
+	RetryBackoffSeconds int
+}
+
+// MetricEmissionLimit wraps limits on how often
+// a metric collector emits metrics with a given name
+type MetricEmissionLimit struct {
+	Collector string
+	// name of the metric, or * for all
+	// metrics without a limit of their own
+	MetricName string
+	// 0 emits every metric
+	SampleEvery int
+	// 0 doesn't limit the rate
+	MaxPerMinute int
+}
+
+// NodeGroup wraps the name of a group of monitored nodes
+// (or endpoints) that metrics are rolled up for
+type NodeGroup struct {
+	Name string
+	// node ids or endpoint urls
+	Members []string
+}
+
+// RPCProbeMethod wraps the name of a json rpc method of the
+// node to probe and the params (if any) to call it with
+type RPCProbeMethod struct {
+	Method string
+	// json encoded params, empty to call
+	// the method without params
+	Params string
+}
+
+// VantageEndpoint wraps the url of an endpoint that reaches
+// the same logical endpoint as the node from another region
+type VantageEndpoint struct {
+	Region string
+	URL    string
+}
+
+// FileMetricRoute wraps the names of
+// metrics to collect to a metric file
+type FileMetricRoute struct {
+	FileNameSuffix string
+	MetricNames    []string
+}
+
 // DoctorConfig wraps values used to configure
 // the execution of the doctor program
 type DoctorConfig struct {
@@ -98,6 +641,10 @@ type DoctorConfig struct {
 	DefaultMonitoringIntervalSeconds           int
 	MaxMetricSamplesToRetainPerNode            int
 	MetricSamplesForSyntheticMetricCalculation int
+	UptimeShortWindowSeconds                   int
+	UptimeMediumWindowSeconds                  int
+	UptimeLongWindowSeconds                    int
+	UptimeEWMAHalfLifeSeconds                  int
 	MetricCollectors                           []string
 	AWSRegion                                  string
 	MetricNamespace                            string
@@ -109,28 +656,211 @@ type DoctorConfig struct {
 	AutohealRestartDelaySeconds                int
 	AutohealInitialAllowedDelaySeconds         int
 	HealthChecksTimeoutSeconds                 int
+	RPCMaxRetries                              int
+	RPCRetryBackoffMilliseconds                int
 	NoNewBlocksRestartThresholdSeconds         int
 	DowntimeRestartThresholdSeconds            int
+	LogFormat                                  string
+	LogLevel                                   logging.Level
+	MaxChartPointsPerSeries                    int
+	MaxMessagesToRetain                        int
+	MetricSigningAlgorithm                     string
+	MetricSigningKeyFilepath                   string
+	MetricVerificationKeyFilepath              string
+	SampleStoreBackend                         string
+	SampleStoreFilepath                        string
+	SampleStoreRetentionHours                  int
+	PeerChurnAlertThresholdPerMinute           float64
+	PeerMinRecvBytesPerSecond                  float64
+	CloudWatchFlushIntervalSeconds             int
+	CloudWatchMaxQueuedMetrics                 int
+	CloudWatchAggregationPeriodSeconds         int
+	MetricChannelBufferSize                    int
+	LogChannelBufferSize                       int
+	EventChannelBufferSize                     int
+	StaleResponseBehavior                      string
+	IncidentPublishers                         []string
+	IncidentLogGroupName                       string
+	IncidentEventBusName                       string
+	WebhookURL                                 string
+	WebhookSigningKeyFilepath                  string
+	WebhookMaxRetries                          int
+	BlockIntervalP95AlertThresholdSeconds      float64
+	FileMetricRoutes                           []FileMetricRoute
+	DiagnosticsAgent                           bool
+	DiagnosticsAgentAddress                    string
+	AutohealStrategies                         []AutohealStrategy
+	HealthChecks                               []HealthCheckSchedule
+	HealthCheckMinPeers                        int
+	HealthCheckDiskMinFreePercent              float64
+	EVMRPCAddress                              string
+	RESTAPIAddress                             string
+	HealthCheckQueryAccount                    string
+	SyntheticTxCommand                         []string
+	AutohealEscalationDelaySeconds             int
+	ReferenceAPIAddress                        string
+	AutohealBlocksBehindReferenceTolerance     int
+	AccessLogFilepath                          string
+	AccessLogFormat                            string
+	MempoolSizeAlertThreshold                  int
+	MempoolSizeAlertDurationSeconds            int
+	ConsensusFrozenThresholdSeconds            int
+	AutohealFrozenConsensus                    bool
+	AutohealStandbyMinHealthyInstances         int
+	AutohealMaxRestartsPerHour                 int
+	AutohealMaxRestartsPerDay                  int
+	AutohealSnapshotURL                        string
+	AutohealReplaceMethod                      string
+	AutohealReplaceMinHealthyInstances         int
+	AutohealReplaceLifecycleHookName           string
+	AutohealTargetGroupARNs                    []string
+	AutohealRoute53HostedZoneId                string
+	AutohealRoute53RecordName                  string
+	AutohealRoute53RecordType                  string
+	AutohealRoute53SetIdentifier               string
+	AutohealRestoreStateThresholdSeconds       int64
+	AutohealStateSyncThresholdSeconds          int
+	AutohealServiceManager                     string
+	AutohealRestartCommand                     []string
+	AutohealPreRestartCommands                 [][]string
+	AutohealRestartVerificationSeconds         int
+	AutohealServiceSudo                        bool
+	AutohealServiceHelperSocket                string
+	MetricFileDirectory                        string
+	CompressRotatedMetricFiles                 bool
+	MetricFileRetentionDays                    int
+	NodeHome                                   string
+	ExpectedGenesisSHA256                      string
+	AnnotationsFilepath                        string
+	HostMetrics                                bool
+	HostProcPath                               string
+	MetricEmissionLimits                       []MetricEmissionLimit
+	ExpectedNodeId                             string
+	ExpectedNodeMoniker                        string
+	ChainId                                    string
+	ChainConsistencyCheckIntervalSeconds       int
+	VotingPowerCheckIntervalSeconds            int
+	ChainDegradedVotingPowerPercent            float64
+	UpgradeAPIAddress                          string
+	UpgradeHeight                              int64
+	UpgradeWindowSeconds                       int
+	NodeGroups                                 []NodeGroup
+	SLOs                                       []slo.Objective
+	RPCProbeMethods                            []RPCProbeMethod
+	WebsocketMonitoring                        bool
+	PushNewBlocks                              bool
+	VantageEndpoints                           []VantageEndpoint
+	Region                                     string
+	StatusServerAddress                        string
+	StatusServerAuthToken                      string
+	StatusServerTLSCertFilepath                string
+	StatusServerTLSKeyFilepath                 string
+	FleetAggregatorURL                         string
+	FleetAgentId                               string
+	FleetPushIntervalSeconds                   int
+	FleetAuthToken                             string
+	FleetServerAddress                         string
+	FleetServerTLSCertFilepath                 string
+	FleetServerTLSKeyFilepath                  string
+	FleetStaleSeconds                          int
+	ControlServerAddress                       string
+	ControlTLSCertFilepath                     string
+	ControlTLSKeyFilepath                      string
+	ControlTLSClientCAFilepath                 string
+	ControlHealStrategies                      []string
+	Check                                      bool
+	Daemon                                     bool
+	WatchConfigFile                            bool
+	PIDFilepath                                string
+	MaintenanceFilepath                        string
+	Yes                                        bool
+	DryRun                                     bool
+	ProbeEndpointAddresses                     bool
+	// credentials to send with requests to each endpoint
+	KavaAPIAuth      kava.Auth
+	ReferenceAPIAuth kava.Auth
+	RESTAPIAuth      kava.Auth
+	UpgradeAPIAuth   kava.Auth
+	// proxy and tls settings for requests to endpoints
+	Transport kava.TransportConfig
+	// chain the node is on and how to parse its status
+	ChainType string
+	Status    kava.StatusConfig
+	// endpoints that must all respond for the
+	// node to be up, starting with the status
+	UptimeProbes []string
+	// smtp server and recipients to email incident events to
+	Email incident.EmailConfig
+	// chats to send incident events to
+	TelegramBotToken  string
+	TelegramChatId    string
+	DiscordWebhookURL string
+	// when events of each severity are published to each publisher
+	IncidentPublisherSchedules map[string]incident.Schedule
+	// commands run for incident events and how
+	// long and how many of them can run
+	ExecHooks              []incident.ExecHook
+	ExecHookTimeoutSeconds int
+	ExecHookMaxConcurrency int
+	// command run by the exec-hook autoheal strategy
+	AutohealExecHookCommand []string
+	// known good peers and seeds the refresh-peers
+	// autoheal strategy reconnects the node to
+	AutohealRefreshPeers []string
+	AutohealRefreshSeeds []string
+	// sensitivity and window of the baselines
+	// anomalies are detected against
+	AnomalyZScoreThreshold float64
+	AnomalyBaselineSamples int
+	// optional file to persist baselines to across restarts
+	AnomalyBaselinesFilepath string
+	// whether and how often alerts are published
+	PublishAlerts          bool
+	AlertCooldownSeconds   int
+	AlertEscalationSeconds int
+	// opentelemetry collector to export metrics to
+	OTLPEndpoint           string
+	OTLPHeaders            map[string]string
+	OTLPResourceAttributes map[string]string
+	OTLPTraces             bool
+	// path of the config file values were read from
+	ConfigFilepath string
+	// time zone and layout to display times in
+	TimeFormat logging.TimeFormat
+	// positional (non flag) command line arguments
+	// e.g. the name of a command to run
+	Args []string
 }
 
+// whether command line flags have been parsed, as
+// they can only be parsed once per invocation
+var flagsParsed bool
+
 // GetDoctorConfig gets an instance of DoctorConfig
 // populated with values provided via the command line
 // environment, and or config files
+// Calling GetDoctorConfig again (e.g. to reload config on SIGHUP)
+// re-reads the config file, replacing any values previously read from it
 func GetDoctorConfig() (*DoctorConfig, error) {
 	config := &DoctorConfig{}
 
-	// set default configuration settings
-	viper.SetEnvPrefix(DoctorConfigEnvironmentVariablePrefix)
-	viper.SetConfigType("json")
+	if !flagsParsed {
+		// set default configuration settings
+		viper.SetEnvPrefix(DoctorConfigEnvironmentVariablePrefix)
+		viper.SetConfigType("json")
 
-	// allow viper to merge in config provided via command-line flags
-	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
-	pflag.Parse()
-	viper.BindPFlags(pflag.CommandLine)
+		// allow viper to merge in config provided via command-line flags
+		pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+		pflag.CommandLine.MarkDeprecated(CheckFlagName, "use the check command instead")
+		pflag.Parse()
+		viper.BindPFlags(pflag.CommandLine)
 
-	// allow for overriding any configuration using environment variables
-	// prefixed with `DoctorConfigEnvironmentVariablePrefix`
-	viper.AutomaticEnv()
+		// allow for overriding any configuration using environment variables
+		// prefixed with `DoctorConfigEnvironmentVariablePrefix`
+		viper.AutomaticEnv()
+
+		flagsParsed = true
+	}
 
 	// get the absolute path to the configuration file
 	configFilepath, err := homedir.Expand(viper.GetString(ConfigFilepathFlagName))
@@ -192,25 +922,1322 @@ func GetDoctorConfig() (*DoctorConfig, error) {
 		validCollectors = append(validCollectors, DefaultMetricCollector)
 	}
 
-	return &DoctorConfig{
-		InteractiveMode:                  viper.GetBool("interactive"),
-		KavaNodeRPCURL:                   viper.GetString(KavaAPIAddressFlagName),
-		DefaultMonitoringIntervalSeconds: viper.GetInt(DefaultMonitoringIntervalSecondsFlagName),
-		DebugMode:                        debugMode,
-		Logger:                           logger,
-		MetricCollectors:                 validCollectors,
-		MaxMetricSamplesToRetainPerNode:  viper.GetInt(MaxMetricSamplesToRetainPerNodeFlagName),
-		MetricSamplesForSyntheticMetricCalculation: viper.GetInt(MetricSamplesForSyntheticMetricCalculationFlagName),
-		AWSRegion:                           viper.GetString(AWSRegionFlagName),
-		MetricNamespace:                     viper.GetString(MetricNamespaceFlagName),
-		Autoheal:                            viper.GetBool(AutohealFlagName),
-		AutohealBlockchainServiceName:       viper.GetString(AutohealBlockchainServiceNameFlagName),
-		AutohealSyncLatencyToleranceSeconds: viper.GetInt(AutohealSyncLatencyToleranceSecondsFlagName),
-		AutohealSyncToLiveToleranceSeconds:  viper.GetInt(AutohealSyncToLiveToleranceSecondsFlagName),
-		AutohealRestartDelaySeconds:         viper.GetInt(AutohealRestartDelaySecondsFlagName),
-		AutohealInitialAllowedDelaySeconds:  viper.GetInt(AutohealInitialDelaySecondsFlagName),
-		HealthChecksTimeoutSeconds:          viper.GetInt(HealthChecksTimeoutSecondsFlagName),
-		NoNewBlocksRestartThresholdSeconds:  viper.GetInt(NoNewBlocksRestartThresholdSecondsFlagName),
-		DowntimeRestartThresholdSeconds:     viper.GetInt(DowntimeRestartThresholdSecondsFlagName),
-	}, nil
+	// validate requested incident publishers
+	var incidentPublishers []string
+
+	for _, requestedPublisher := range strings.Split(viper.GetString(IncidentPublishersFlagName), ",") {
+		requestedPublisher = strings.TrimSpace(requestedPublisher)
+
+		if requestedPublisher == "" {
+			continue
+		}
+
+		var valid bool
+
+		for _, validPublisher := range incident.ValidPublishers {
+			if requestedPublisher == validPublisher {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return config, fmt.Errorf("invalid incident publisher %s, valid publishers are %v", requestedPublisher, incident.ValidPublishers)
+		}
+
+		incidentPublishers = append(incidentPublishers, requestedPublisher)
+	}
+
+	// validate requested stale response behavior
+	staleResponseBehavior := viper.GetString(StaleResponseBehaviorFlagName)
+
+	switch staleResponseBehavior {
+	case StaleResponseBehaviorDiscard, StaleResponseBehaviorAccept:
+	default:
+		return config, fmt.Errorf("invalid stale response behavior %s, valid behaviors are %v", staleResponseBehavior, ValidStaleResponseBehaviors)
+	}
+
+	// validate requested chain and status parsing
+	chainType := viper.GetString(ChainTypeFlagName)
+
+	switch chainType {
+	case kava.KavaChainType:
+	case kava.CosmosChainType:
+		// the default endpoint is the kava mainnet
+		if !viper.IsSet(KavaAPIAddressFlagName) {
+			return config, fmt.Errorf("%s is %s but %s isn't set, set it to the endpoint of the node to monitor", ChainTypeFlagName, chainType, KavaAPIAddressFlagName)
+		}
+	default:
+		return config, fmt.Errorf("invalid chain type %s, valid types are %v", chainType, kava.ValidChainTypes)
+	}
+
+	statusEndpointPath := viper.GetString(StatusEndpointPathFlagName)
+
+	if !strings.HasPrefix(statusEndpointPath, "/") {
+		return config, fmt.Errorf("invalid %s %s, must start with /", StatusEndpointPathFlagName, statusEndpointPath)
+	}
+
+	statusFields, err := kava.ParseStatusFields(viper.GetString(StatusFieldsFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", StatusFieldsFlagName, err)
+	}
+
+	statusConfig := kava.StatusConfig{
+		EndpointPath: statusEndpointPath,
+		Fields:       statusFields,
+	}
+
+	// validate requested uptime probes
+	uptimeProbes, err := parseUptimeProbes(viper.GetString(UptimeProbesFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", UptimeProbesFlagName, err)
+	}
+
+	var autohealTargetGroupARNs []string
+
+	for _, targetGroupARN := range strings.Split(viper.GetString(AutohealTargetGroupARNsFlagName), ",") {
+		if targetGroupARN = strings.TrimSpace(targetGroupARN); targetGroupARN != "" {
+			autohealTargetGroupARNs = append(autohealTargetGroupARNs, targetGroupARN)
+		}
+	}
+
+	// validate requested instance replacement method
+	autohealReplaceMethod := viper.GetString(AutohealReplaceMethodFlagName)
+
+	switch autohealReplaceMethod {
+	case heal.SetUnhealthyReplaceMethod, heal.TerminateReplaceMethod:
+	default:
+		return config, fmt.Errorf("invalid autoheal replace method %s, valid methods are %v", autohealReplaceMethod, heal.ValidReplaceMethods)
+	}
+
+	// validate requested service manager
+	autohealServiceManager := viper.GetString(AutohealServiceManagerFlagName)
+	autohealRestartCommand, err := heal.ParseCommand(viper.GetString(AutohealRestartCommandFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid autoheal restart command: %w", err)
+	}
+
+	switch autohealServiceManager {
+	case heal.SystemdServiceManager, heal.OpenRCServiceManager, heal.LaunchdServiceManager, heal.WindowsServiceManager:
+	case heal.ExecServiceManager:
+		if len(autohealRestartCommand) == 0 {
+			return config, fmt.Errorf("invalid autoheal restart command, a command is required for the %s service manager", heal.ExecServiceManager)
+		}
+	default:
+		return config, fmt.Errorf("invalid autoheal service manager %s, valid service managers are %v", autohealServiceManager, heal.ValidServiceManagers)
+	}
+
+	var autohealPreRestartCommands [][]string
+
+	for _, rawCommand := range strings.Split(viper.GetString(AutohealPreRestartCommandsFlagName), ";") {
+		command, err := heal.ParseCommand(rawCommand)
+
+		if err != nil {
+			return config, fmt.Errorf("invalid autoheal pre restart command: %w", err)
+		}
+
+		if len(command) > 0 {
+			autohealPreRestartCommands = append(autohealPreRestartCommands, command)
+		}
+	}
+
+	syntheticTxCommand, err := heal.ParseCommand(viper.GetString(SyntheticTxCommandFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid synthetic tx command: %w", err)
+	}
+
+	logLevel, err := logging.ParseLevel(viper.GetString(LogLevelFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	if debugMode {
+		logLevel = logging.DebugLevel
+	}
+
+	timeFormat, err := parseTimeFormat(viper.GetString(DisplayTimeZoneFlagName), viper.GetString(TimestampFormatFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested metric signing configuration
+	metricSigningAlgorithm := viper.GetString(MetricSigningAlgorithmFlagName)
+	metricSigningKeyFilepath, err := homedir.Expand(viper.GetString(MetricSigningKeyFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(MetricSigningKeyFilepathFlagName))
+	}
+
+	metricVerificationKeyFilepath, err := homedir.Expand(viper.GetString(MetricVerificationKeyFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(MetricVerificationKeyFilepathFlagName))
+	}
+
+	if metricVerificationKeyFilepath == "" {
+		metricVerificationKeyFilepath = metricSigningKeyFilepath
+	}
+
+	if metricSigningAlgorithm != "" && metricVerificationKeyFilepath == "" {
+		return config, fmt.Errorf("%s (or %s when verifying metrics) must be specified when %s is set", MetricSigningKeyFilepathFlagName, MetricVerificationKeyFilepathFlagName, MetricSigningAlgorithmFlagName)
+	}
+
+	sampleStoreFilepath, err := homedir.Expand(viper.GetString(SampleStoreFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(SampleStoreFilepathFlagName))
+	}
+
+	anomalyBaselinesFilepath, err := homedir.Expand(viper.GetString(AnomalyBaselinesFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(AnomalyBaselinesFilepathFlagName))
+	}
+
+	accessLogFilepath, err := homedir.Expand(viper.GetString(AccessLogFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(AccessLogFilepathFlagName))
+	}
+
+	annotationsFilepath, err := homedir.Expand(viper.GetString(AnnotationsFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(AnnotationsFilepathFlagName))
+	}
+
+	pidFilepath, err := homedir.Expand(viper.GetString(PIDFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(PIDFilepathFlagName))
+	}
+
+	maintenanceFilepath, err := homedir.Expand(viper.GetString(MaintenanceFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(MaintenanceFilepathFlagName))
+	}
+
+	nodeHome, err := homedir.Expand(viper.GetString(NodeHomeFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(NodeHomeFlagName))
+	}
+
+	metricFileDirectory, err := homedir.Expand(viper.GetString(MetricFileDirectoryFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(MetricFileDirectoryFlagName))
+	}
+
+	// validate requested access log format
+	accessLogFormat := viper.GetString(AccessLogFormatFlagName)
+
+	_, err = accesslog.NewParser(accessLogFormat)
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested autoheal strategies
+	autohealStrategies, err := parseAutohealStrategies(viper.GetString(AutohealStrategiesFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested control api heal strategies
+	controlHealStrategies, err := parseControlHealStrategies(viper.GetString(ControlHealStrategiesFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested health checks
+	healthChecks, err := parseHealthChecks(viper.GetString(HealthChecksFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested health check timeouts and retries
+	err = parseHealthCheckPolicies(healthChecks, viper.GetString(HealthCheckTimeoutsFlagName), viper.GetString(HealthCheckRetriesFlagName), viper.GetString(HealthCheckRetryBackoffsFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested file metric routes
+	fileMetricRoutes, err := parseFileMetricRoutes(viper.GetString(FileMetricRoutesFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested metric emission limits
+	metricEmissionLimits, err := parseMetricEmissionLimits(viper.GetString(MetricEmissionLimitsFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested node groups
+	nodeGroups, err := parseNodeGroups(viper.GetString(NodeGroupsFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested service level objectives
+	slos, err := slo.ParseObjectives(viper.GetString(SLOsFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested rpc probe methods
+	rpcProbeMethods, err := parseRPCProbeMethods(viper.GetString(RPCProbeMethodsFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// validate requested vantage endpoints
+	vantageEndpoints, err := parseVantageEndpoints(viper.GetString(VantageEndpointsFlagName))
+
+	if err != nil {
+		return config, err
+	}
+
+	// resolve references to secrets so they don't
+	// have to be stored in the config file
+	secretResolver := secrets.NewResolver(secrets.ResolverConfig{
+		AWSRegion: viper.GetString(AWSRegionFlagName),
+	})
+
+	resolvedSecrets := make(map[string]string)
+
+	for _, setting := range SecretSettings {
+		resolvedSecrets[setting], err = secretResolver.Resolve(viper.GetString(setting))
+
+		if err != nil {
+			return config, fmt.Errorf("error %s resolving secret for %s", err, setting)
+		}
+	}
+
+	// validate requested endpoint credentials
+	endpointAuths := make(map[string]kava.Auth)
+
+	for _, setting := range []string{KavaAPIAuthFlagName, ReferenceAPIAuthFlagName, RESTAPIAuthFlagName, UpgradeAPIAuthFlagName} {
+		endpointAuths[setting], err = kava.ParseAuth(resolvedSecrets[setting])
+
+		if err != nil {
+			return config, fmt.Errorf("invalid %s: %w", setting, err)
+		}
+	}
+
+	// validate requested endpoint addresses
+	for _, setting := range []string{KavaAPIAddressFlagName, ReferenceAPIAddressFlagName, RESTAPIAddressFlagName, UpgradeAPIAddressFlagName} {
+		if _, _, err := kava.ParseRPCAddress(resolvedSecrets[setting]); err != nil {
+			return config, fmt.Errorf("invalid %s: %w", setting, err)
+		}
+	}
+
+	// validate requested proxy and tls settings
+	transportFilepaths := make(map[string]string)
+
+	for _, setting := range []string{TLSCAFilepathFlagName, TLSClientCertFilepathFlagName, TLSClientKeyFilepathFlagName} {
+		transportFilepaths[setting], err = homedir.Expand(viper.GetString(setting))
+
+		if err != nil {
+			return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(setting))
+		}
+	}
+
+	// validate requested control, status api and fleet server tls settings
+	controlFilepaths := make(map[string]string)
+
+	for _, setting := range []string{ControlTLSCertFilepathFlagName, ControlTLSKeyFilepathFlagName, ControlTLSClientCAFilepathFlagName, StatusServerTLSCertFilepathFlagName, StatusServerTLSKeyFilepathFlagName, FleetServerTLSCertFilepathFlagName, FleetServerTLSKeyFilepathFlagName} {
+		controlFilepaths[setting], err = homedir.Expand(viper.GetString(setting))
+
+		if err != nil {
+			return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(setting))
+		}
+	}
+
+	transportConfig := kava.TransportConfig{
+		ProxyURL:           resolvedSecrets[HTTPProxyURLFlagName],
+		CAFilepath:         transportFilepaths[TLSCAFilepathFlagName],
+		ClientCertFilepath: transportFilepaths[TLSClientCertFilepathFlagName],
+		ClientKeyFilepath:  transportFilepaths[TLSClientKeyFilepathFlagName],
+		InsecureSkipVerify: viper.GetBool(TLSInsecureSkipVerifyFlagName),
+	}
+
+	if _, err := kava.NewTransport(transportConfig); err != nil {
+		return config, fmt.Errorf("invalid proxy or tls settings: %w", err)
+	}
+
+	// validate requested webhook configuration
+	webhookURL := resolvedSecrets[WebhookURLFlagName]
+
+	usesWebhook := false
+
+	for _, collector := range validCollectors {
+		if collector == WebhookMetricCollector {
+			usesWebhook = true
+		}
+	}
+
+	for _, publisher := range incidentPublishers {
+		if publisher == incident.WebhookPublisherName {
+			usesWebhook = true
+		}
+	}
+
+	if usesWebhook && webhookURL == "" {
+		return config, fmt.Errorf("%s must be specified when using the %s metric collector or incident publisher", WebhookURLFlagName, WebhookMetricCollector)
+	}
+
+	// validate requested otlp configuration
+	otlpEndpoint := resolvedSecrets[OTLPEndpointFlagName]
+
+	for _, collector := range validCollectors {
+		if collector == OTLPMetricCollector && otlpEndpoint == "" {
+			return config, fmt.Errorf("%s must be specified when using the %s metric collector", OTLPEndpointFlagName, OTLPMetricCollector)
+		}
+	}
+
+	otlpHeaders, err := parseKeyValuePairs(resolvedSecrets[OTLPHeadersFlagName])
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", OTLPHeadersFlagName, err)
+	}
+
+	otlpResourceAttributes, err := parseKeyValuePairs(viper.GetString(OTLPResourceAttributesFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", OTLPResourceAttributesFlagName, err)
+	}
+
+	// validate requested email configuration
+	emailRecipients, err := incident.ParseEmailRecipients(viper.GetString(EmailRecipientsFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", EmailRecipientsFlagName, err)
+	}
+
+	emailBodyTemplateFilepath, err := homedir.Expand(viper.GetString(EmailBodyTemplateFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(EmailBodyTemplateFilepathFlagName))
+	}
+
+	emailConfig := incident.EmailConfig{
+		SMTPAddress:          viper.GetString(EmailSMTPAddressFlagName),
+		Security:             viper.GetString(EmailSMTPSecurityFlagName),
+		Username:             viper.GetString(EmailSMTPUsernameFlagName),
+		Password:             resolvedSecrets[EmailSMTPPasswordFlagName],
+		From:                 viper.GetString(EmailFromFlagName),
+		Recipients:           emailRecipients,
+		SubjectTemplate:      viper.GetString(EmailSubjectTemplateFlagName),
+		BodyTemplateFilepath: emailBodyTemplateFilepath,
+	}
+
+	for _, publisher := range incidentPublishers {
+		if publisher != incident.EmailPublisherName {
+			continue
+		}
+
+		if _, err := incident.NewEmailPublisher(emailConfig); err != nil {
+			return config, fmt.Errorf("invalid email settings for the %s incident publisher: %w", incident.EmailPublisherName, err)
+		}
+	}
+
+	// validate requested chat configuration
+	telegramBotToken := resolvedSecrets[TelegramBotTokenFlagName]
+	telegramChatId := viper.GetString(TelegramChatIdFlagName)
+	discordWebhookURL := resolvedSecrets[DiscordWebhookURLFlagName]
+
+	for _, publisher := range incidentPublishers {
+		switch publisher {
+		case incident.TelegramPublisherName:
+			if _, err := incident.NewTelegramPublisher(telegramBotToken, telegramChatId); err != nil {
+				return config, fmt.Errorf("%s and %s must be specified when using the %s incident publisher", TelegramBotTokenFlagName, TelegramChatIdFlagName, incident.TelegramPublisherName)
+			}
+		case incident.DiscordPublisherName:
+			if _, err := incident.NewDiscordPublisher(discordWebhookURL); err != nil {
+				return config, fmt.Errorf("a valid %s must be specified when using the %s incident publisher", DiscordWebhookURLFlagName, incident.DiscordPublisherName)
+			}
+		}
+	}
+
+	incidentPublisherSchedules, err := incident.ParseSchedules(viper.GetString(IncidentPublisherSchedulesFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", IncidentPublisherSchedulesFlagName, err)
+	}
+
+	execHooks, err := parseExecHooks(viper.GetString(ExecHooksFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", ExecHooksFlagName, err)
+	}
+
+	for _, publisher := range incidentPublishers {
+		if publisher != incident.ExecPublisherName {
+			continue
+		}
+
+		if _, err := incident.NewExecPublisher(execHooks, time.Duration(viper.GetInt(ExecHookTimeoutSecondsFlagName))*time.Second, viper.GetInt(ExecHookMaxConcurrencyFlagName)); err != nil {
+			return config, fmt.Errorf("invalid %s for the %s incident publisher: %w", ExecHooksFlagName, incident.ExecPublisherName, err)
+		}
+	}
+
+	autohealExecHookCommand, err := heal.ParseCommand(viper.GetString(AutohealExecHookCommandFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid autoheal exec hook command: %w", err)
+	}
+
+	autohealRefreshPeers, err := heal.ParsePeerAddresses(viper.GetString(AutohealRefreshPeersFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", AutohealRefreshPeersFlagName, err)
+	}
+
+	autohealRefreshSeeds, err := heal.ParsePeerAddresses(viper.GetString(AutohealRefreshSeedsFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("invalid %s: %w", AutohealRefreshSeedsFlagName, err)
+	}
+
+	webhookSigningKeyFilepath, err := homedir.Expand(viper.GetString(WebhookSigningKeyFilepathFlagName))
+
+	if err != nil {
+		return config, fmt.Errorf("error %s trying to expand home directory for path %s", err, viper.GetString(WebhookSigningKeyFilepathFlagName))
+	}
+
+	config = &DoctorConfig{
+		InteractiveMode:                  viper.GetBool("interactive"),
+		KavaNodeRPCURL:                   resolvedSecrets[KavaAPIAddressFlagName],
+		DefaultMonitoringIntervalSeconds: viper.GetInt(DefaultMonitoringIntervalSecondsFlagName),
+		DebugMode:                        debugMode,
+		Logger:                           logger,
+		MetricCollectors:                 validCollectors,
+		MaxMetricSamplesToRetainPerNode:  viper.GetInt(MaxMetricSamplesToRetainPerNodeFlagName),
+		MetricSamplesForSyntheticMetricCalculation: viper.GetInt(MetricSamplesForSyntheticMetricCalculationFlagName),
+		AWSRegion:                              viper.GetString(AWSRegionFlagName),
+		MetricNamespace:                        viper.GetString(MetricNamespaceFlagName),
+		Autoheal:                               viper.GetBool(AutohealFlagName),
+		AutohealBlockchainServiceName:          viper.GetString(AutohealBlockchainServiceNameFlagName),
+		AutohealSyncLatencyToleranceSeconds:    viper.GetInt(AutohealSyncLatencyToleranceSecondsFlagName),
+		AutohealSyncToLiveToleranceSeconds:     viper.GetInt(AutohealSyncToLiveToleranceSecondsFlagName),
+		AutohealRestartDelaySeconds:            viper.GetInt(AutohealRestartDelaySecondsFlagName),
+		AutohealInitialAllowedDelaySeconds:     viper.GetInt(AutohealInitialDelaySecondsFlagName),
+		HealthChecksTimeoutSeconds:             viper.GetInt(HealthChecksTimeoutSecondsFlagName),
+		RPCMaxRetries:                          viper.GetInt(RPCMaxRetriesFlagName),
+		RPCRetryBackoffMilliseconds:            viper.GetInt(RPCRetryBackoffMillisecondsFlagName),
+		NoNewBlocksRestartThresholdSeconds:     viper.GetInt(NoNewBlocksRestartThresholdSecondsFlagName),
+		DowntimeRestartThresholdSeconds:        viper.GetInt(DowntimeRestartThresholdSecondsFlagName),
+		LogFormat:                              viper.GetString(LogFormatFlagName),
+		LogLevel:                               logLevel,
+		MaxChartPointsPerSeries:                viper.GetInt(MaxChartPointsPerSeriesFlagName),
+		MaxMessagesToRetain:                    viper.GetInt(MaxMessagesToRetainFlagName),
+		MetricSigningAlgorithm:                 metricSigningAlgorithm,
+		MetricSigningKeyFilepath:               metricSigningKeyFilepath,
+		MetricVerificationKeyFilepath:          metricVerificationKeyFilepath,
+		SampleStoreBackend:                     viper.GetString(SampleStoreBackendFlagName),
+		SampleStoreFilepath:                    sampleStoreFilepath,
+		SampleStoreRetentionHours:              viper.GetInt(SampleStoreRetentionHoursFlagName),
+		PeerChurnAlertThresholdPerMinute:       viper.GetFloat64(PeerChurnAlertThresholdPerMinuteFlagName),
+		PeerMinRecvBytesPerSecond:              viper.GetFloat64(PeerMinRecvBytesPerSecondFlagName),
+		UptimeShortWindowSeconds:               viper.GetInt(UptimeShortWindowSecondsFlagName),
+		UptimeMediumWindowSeconds:              viper.GetInt(UptimeMediumWindowSecondsFlagName),
+		UptimeLongWindowSeconds:                viper.GetInt(UptimeLongWindowSecondsFlagName),
+		UptimeEWMAHalfLifeSeconds:              viper.GetInt(UptimeEWMAHalfLifeSecondsFlagName),
+		CloudWatchFlushIntervalSeconds:         viper.GetInt(CloudWatchFlushIntervalSecondsFlagName),
+		CloudWatchMaxQueuedMetrics:             viper.GetInt(CloudWatchMaxQueuedMetricsFlagName),
+		CloudWatchAggregationPeriodSeconds:     viper.GetInt(CloudWatchAggregationPeriodFlagName),
+		MetricChannelBufferSize:                viper.GetInt(MetricChannelBufferSizeFlagName),
+		LogChannelBufferSize:                   viper.GetInt(LogChannelBufferSizeFlagName),
+		EventChannelBufferSize:                 viper.GetInt(EventChannelBufferSizeFlagName),
+		StaleResponseBehavior:                  staleResponseBehavior,
+		IncidentPublishers:                     incidentPublishers,
+		IncidentLogGroupName:                   viper.GetString(IncidentLogGroupNameFlagName),
+		IncidentEventBusName:                   viper.GetString(IncidentEventBusNameFlagName),
+		WebhookURL:                             webhookURL,
+		WebhookSigningKeyFilepath:              webhookSigningKeyFilepath,
+		WebhookMaxRetries:                      viper.GetInt(WebhookMaxRetriesFlagName),
+		BlockIntervalP95AlertThresholdSeconds:  viper.GetFloat64(BlockIntervalP95AlertThresholdSecondsFlagName),
+		FileMetricRoutes:                       fileMetricRoutes,
+		DiagnosticsAgent:                       viper.GetBool(DiagnosticsAgentFlagName),
+		DiagnosticsAgentAddress:                viper.GetString(DiagnosticsAgentAddressFlagName),
+		AutohealStrategies:                     autohealStrategies,
+		HealthChecks:                           healthChecks,
+		HealthCheckMinPeers:                    viper.GetInt(HealthCheckMinPeersFlagName),
+		HealthCheckDiskMinFreePercent:          viper.GetFloat64(HealthCheckDiskMinFreePercentFlagName),
+		EVMRPCAddress:                          resolvedSecrets[EVMRPCAddressFlagName],
+		RESTAPIAddress:                         resolvedSecrets[RESTAPIAddressFlagName],
+		HealthCheckQueryAccount:                viper.GetString(HealthCheckQueryAccountFlagName),
+		SyntheticTxCommand:                     syntheticTxCommand,
+		AutohealEscalationDelaySeconds:         viper.GetInt(AutohealEscalationDelaySecondsFlagName),
+		ReferenceAPIAddress:                    resolvedSecrets[ReferenceAPIAddressFlagName],
+		AutohealBlocksBehindReferenceTolerance: viper.GetInt(AutohealBlocksBehindReferenceToleranceFlagName),
+		AccessLogFilepath:                      accessLogFilepath,
+		AccessLogFormat:                        accessLogFormat,
+		MempoolSizeAlertThreshold:              viper.GetInt(MempoolSizeAlertThresholdFlagName),
+		MempoolSizeAlertDurationSeconds:        viper.GetInt(MempoolSizeAlertDurationSecondsFlagName),
+		ConsensusFrozenThresholdSeconds:        viper.GetInt(ConsensusFrozenThresholdSecondsFlagName),
+		AutohealFrozenConsensus:                viper.GetBool(AutohealFrozenConsensusFlagName),
+		AutohealStandbyMinHealthyInstances:     viper.GetInt(AutohealStandbyMinHealthyInstancesFlagName),
+		AutohealMaxRestartsPerHour:             viper.GetInt(AutohealMaxRestartsPerHourFlagName),
+		AutohealMaxRestartsPerDay:              viper.GetInt(AutohealMaxRestartsPerDayFlagName),
+		AutohealSnapshotURL:                    resolvedSecrets[AutohealSnapshotURLFlagName],
+		AutohealReplaceMethod:                  autohealReplaceMethod,
+		AutohealReplaceMinHealthyInstances:     viper.GetInt(AutohealReplaceMinHealthyInstancesFlagName),
+		AutohealReplaceLifecycleHookName:       viper.GetString(AutohealReplaceLifecycleHookNameFlagName),
+		AutohealTargetGroupARNs:                autohealTargetGroupARNs,
+		AutohealRoute53HostedZoneId:            viper.GetString(AutohealRoute53HostedZoneIdFlagName),
+		AutohealRoute53RecordName:              viper.GetString(AutohealRoute53RecordNameFlagName),
+		AutohealRoute53RecordType:              viper.GetString(AutohealRoute53RecordTypeFlagName),
+		AutohealRoute53SetIdentifier:           viper.GetString(AutohealRoute53SetIdentifierFlagName),
+		AutohealRestoreStateThresholdSeconds:   viper.GetInt64(AutohealRestoreStateThresholdSecondsFlagName),
+		AutohealStateSyncThresholdSeconds:      viper.GetInt(AutohealStateSyncThresholdSecondsFlagName),
+		AutohealServiceManager:                 autohealServiceManager,
+		AutohealRestartCommand:                 autohealRestartCommand,
+		AutohealPreRestartCommands:             autohealPreRestartCommands,
+		AutohealRestartVerificationSeconds:     viper.GetInt(AutohealRestartVerificationSecondsFlagName),
+		AutohealServiceSudo:                    viper.GetBool(AutohealServiceSudoFlagName),
+		AutohealServiceHelperSocket:            viper.GetString(AutohealServiceHelperSocketFlagName),
+		MetricFileDirectory:                    metricFileDirectory,
+		CompressRotatedMetricFiles:             viper.GetBool(CompressRotatedMetricFilesFlagName),
+		MetricFileRetentionDays:                viper.GetInt(MetricFileRetentionDaysFlagName),
+		NodeHome:                               nodeHome,
+		ExpectedGenesisSHA256:                  viper.GetString(ExpectedGenesisSHA256FlagName),
+		AnnotationsFilepath:                    annotationsFilepath,
+		HostMetrics:                            viper.GetBool(HostMetricsFlagName),
+		HostProcPath:                           viper.GetString(HostProcPathFlagName),
+		MetricEmissionLimits:                   metricEmissionLimits,
+		ExpectedNodeId:                         viper.GetString(ExpectedNodeIdFlagName),
+		ExpectedNodeMoniker:                    viper.GetString(ExpectedNodeMonikerFlagName),
+		ChainId:                                viper.GetString(ChainIdFlagName),
+		ChainConsistencyCheckIntervalSeconds:   viper.GetInt(ChainConsistencyCheckIntervalSecondsFlagName),
+		VotingPowerCheckIntervalSeconds:        viper.GetInt(VotingPowerCheckIntervalSecondsFlagName),
+		ChainDegradedVotingPowerPercent:        viper.GetFloat64(ChainDegradedVotingPowerPercentFlagName),
+		UpgradeAPIAddress:                      resolvedSecrets[UpgradeAPIAddressFlagName],
+		UpgradeHeight:                          viper.GetInt64(UpgradeHeightFlagName),
+		UpgradeWindowSeconds:                   viper.GetInt(UpgradeWindowSecondsFlagName),
+		NodeGroups:                             nodeGroups,
+		SLOs:                                   slos,
+		RPCProbeMethods:                        rpcProbeMethods,
+		WebsocketMonitoring:                    viper.GetBool(WebsocketMonitoringFlagName),
+		PushNewBlocks:                          viper.GetBool(PushNewBlocksFlagName),
+		VantageEndpoints:                       vantageEndpoints,
+		Region:                                 viper.GetString(RegionFlagName),
+		StatusServerAddress:                    viper.GetString(StatusServerAddressFlagName),
+		StatusServerAuthToken:                  resolvedSecrets[StatusServerAuthTokenFlagName],
+		StatusServerTLSCertFilepath:            controlFilepaths[StatusServerTLSCertFilepathFlagName],
+		StatusServerTLSKeyFilepath:             controlFilepaths[StatusServerTLSKeyFilepathFlagName],
+		FleetAggregatorURL:                     resolvedSecrets[FleetAggregatorURLFlagName],
+		FleetAgentId:                           viper.GetString(FleetAgentIdFlagName),
+		FleetPushIntervalSeconds:               viper.GetInt(FleetPushIntervalSecondsFlagName),
+		FleetAuthToken:                         resolvedSecrets[FleetAuthTokenFlagName],
+		FleetServerAddress:                     viper.GetString(FleetServerAddressFlagName),
+		FleetServerTLSCertFilepath:             controlFilepaths[FleetServerTLSCertFilepathFlagName],
+		FleetServerTLSKeyFilepath:              controlFilepaths[FleetServerTLSKeyFilepathFlagName],
+		FleetStaleSeconds:                      viper.GetInt(FleetStaleSecondsFlagName),
+		ControlServerAddress:                   viper.GetString(ControlServerAddressFlagName),
+		ControlTLSCertFilepath:                 controlFilepaths[ControlTLSCertFilepathFlagName],
+		ControlTLSKeyFilepath:                  controlFilepaths[ControlTLSKeyFilepathFlagName],
+		ControlTLSClientCAFilepath:             controlFilepaths[ControlTLSClientCAFilepathFlagName],
+		ControlHealStrategies:                  controlHealStrategies,
+		Check:                                  viper.GetBool(CheckFlagName),
+		Daemon:                                 viper.GetBool(DaemonFlagName),
+		WatchConfigFile:                        viper.GetBool(WatchConfigFileFlagName),
+		ConfigFilepath:                         configFilepath,
+		PIDFilepath:                            pidFilepath,
+		MaintenanceFilepath:                    maintenanceFilepath,
+		Yes:                                    viper.GetBool(YesFlagName),
+		DryRun:                                 viper.GetBool(DryRunFlagName),
+		ProbeEndpointAddresses:                 viper.GetBool(ProbeEndpointAddressesFlagName),
+		KavaAPIAuth:                            endpointAuths[KavaAPIAuthFlagName],
+		ReferenceAPIAuth:                       endpointAuths[ReferenceAPIAuthFlagName],
+		RESTAPIAuth:                            endpointAuths[RESTAPIAuthFlagName],
+		UpgradeAPIAuth:                         endpointAuths[UpgradeAPIAuthFlagName],
+		Transport:                              transportConfig,
+		ChainType:                              chainType,
+		Status:                                 statusConfig,
+		UptimeProbes:                           uptimeProbes,
+		Email:                                  emailConfig,
+		TelegramBotToken:                       telegramBotToken,
+		TelegramChatId:                         telegramChatId,
+		DiscordWebhookURL:                      discordWebhookURL,
+		IncidentPublisherSchedules:             incidentPublisherSchedules,
+		ExecHooks:                              execHooks,
+		ExecHookTimeoutSeconds:                 viper.GetInt(ExecHookTimeoutSecondsFlagName),
+		ExecHookMaxConcurrency:                 viper.GetInt(ExecHookMaxConcurrencyFlagName),
+		AutohealExecHookCommand:                autohealExecHookCommand,
+		AutohealRefreshPeers:                   autohealRefreshPeers,
+		AutohealRefreshSeeds:                   autohealRefreshSeeds,
+		AnomalyZScoreThreshold:                 viper.GetFloat64(AnomalyZScoreThresholdFlagName),
+		AnomalyBaselineSamples:                 viper.GetInt(AnomalyBaselineSamplesFlagName),
+		AnomalyBaselinesFilepath:               anomalyBaselinesFilepath,
+		PublishAlerts:                          viper.GetBool(PublishAlertsFlagName),
+		AlertCooldownSeconds:                   viper.GetInt(AlertCooldownSecondsFlagName),
+		AlertEscalationSeconds:                 viper.GetInt(AlertEscalationSecondsFlagName),
+		OTLPEndpoint:                           otlpEndpoint,
+		OTLPHeaders:                            otlpHeaders,
+		OTLPResourceAttributes:                 otlpResourceAttributes,
+		OTLPTraces:                             viper.GetBool(OTLPTracesFlagName),
+		TimeFormat:                             timeFormat,
+		Args:                                   pflag.Args(),
+	}
+
+	// fail fast on nonsensical combinations of values
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return config, nil
+}
+
+// parseTimeFormat parses the time zone (a name from the IANA
+// time zone database or Local) and timestamp format (a named
+// layout or a go time layout) to display times in, returning
+// the time format and error (if any)
+func parseTimeFormat(timeZone string, timestampFormat string) (logging.TimeFormat, error) {
+	location, err := time.LoadLocation(timeZone)
+
+	if err != nil {
+		return logging.TimeFormat{}, fmt.Errorf("invalid display time zone %s: %s", timeZone, err)
+	}
+
+	layout, named := TimestampFormatLayouts[strings.ToLower(timestampFormat)]
+
+	if !named {
+		layout = timestampFormat
+
+		// a layout without any elements formats every time the same
+		if strings.TrimSpace(layout) == "" || time.Unix(0, 0).UTC().Format(layout) == layout {
+			return logging.TimeFormat{}, fmt.Errorf("invalid timestamp format %q, must be one of [rfc3339 rfc3339nano datetime kitchen] or a go time layout", timestampFormat)
+		}
+	}
+
+	return logging.TimeFormat{
+		Location: location,
+		Layout:   layout,
+	}, nil
+}
+
+// parseFileMetricRoutes parses file metric routes in the form
+// <file name suffix>=<comma separated metric names>[;...]
+// returning the routes and error (if any)
+func parseFileMetricRoutes(rawRoutes string) ([]FileMetricRoute, error) {
+	var routes []FileMetricRoute
+
+	fileNameSuffixes := make(map[string]bool)
+
+	for _, rawRoute := range strings.Split(rawRoutes, ";") {
+		rawRoute = strings.TrimSpace(rawRoute)
+
+		if rawRoute == "" {
+			continue
+		}
+
+		fileNameSuffix, rawMetricNames, found := strings.Cut(rawRoute, "=")
+		fileNameSuffix = strings.TrimSpace(fileNameSuffix)
+
+		if !found || fileNameSuffix == "" {
+			return nil, fmt.Errorf("invalid file metric route %s, expected <file name suffix>=<metric names>", rawRoute)
+		}
+
+		if fileNameSuffixes[fileNameSuffix] {
+			return nil, fmt.Errorf("file name suffix %s used by multiple file metric routes", fileNameSuffix)
+		}
+
+		fileNameSuffixes[fileNameSuffix] = true
+
+		var metricNames []string
+
+		for _, metricName := range strings.Split(rawMetricNames, ",") {
+			metricName = strings.TrimSpace(metricName)
+
+			if metricName != "" {
+				metricNames = append(metricNames, metricName)
+			}
+		}
+
+		if len(metricNames) == 0 {
+			return nil, fmt.Errorf("file metric route %s must include at least one metric name", rawRoute)
+		}
+
+		routes = append(routes, FileMetricRoute{
+			FileNameSuffix: fileNameSuffix,
+			MetricNames:    metricNames,
+		})
+	}
+
+	return routes, nil
+}
+
+// parseKeyValuePairs parses a comma separated list of key value
+// pairs in the form <key>=<value>, e.g. the headers or resource
+// attributes of the otlp collector, returning the pairs and error (if any)
+func parseKeyValuePairs(rawPairs string) (map[string]string, error) {
+	pairs := make(map[string]string)
+
+	for _, rawPair := range strings.Split(rawPairs, ",") {
+		rawPair = strings.TrimSpace(rawPair)
+
+		if rawPair == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(rawPair, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid pair %s, expected <key>=<value>", rawPair)
+		}
+
+		if _, duplicate := pairs[key]; duplicate {
+			return nil, fmt.Errorf("key %s specified multiple times", key)
+		}
+
+		pairs[key] = value
+	}
+
+	return pairs, nil
+}
+
+// parseMetricEmissionLimits parses metric emission limits in the form
+// <collector>.<metric name>=<limit>[,<limit>][;...] where each limit is
+// sample:<N> or rate:<max per minute>, returning the limits and error (if any)
+func parseMetricEmissionLimits(rawLimits string) ([]MetricEmissionLimit, error) {
+	var limits []MetricEmissionLimit
+
+	limitedMetrics := make(map[string]bool)
+
+	for _, rawLimit := range strings.Split(rawLimits, ";") {
+		rawLimit = strings.TrimSpace(rawLimit)
+
+		if rawLimit == "" {
+			continue
+		}
+
+		limitedMetric, rawValues, found := strings.Cut(rawLimit, "=")
+		limitedMetric = strings.TrimSpace(limitedMetric)
+
+		collector, metricName, hasMetricName := strings.Cut(limitedMetric, ".")
+
+		if !found || !hasMetricName || metricName == "" {
+			return nil, fmt.Errorf("invalid metric emission limit %s, expected <collector>.<metric name>=<limit>", rawLimit)
+		}
+
+		var valid bool
+
+		for _, validCollector := range ValidMetricCollectors {
+			if collector == validCollector {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("invalid collector %s for metric emission limit %s, valid collectors are %v", collector, rawLimit, ValidMetricCollectors)
+		}
+
+		if limitedMetrics[limitedMetric] {
+			return nil, fmt.Errorf("metric %s limited by multiple metric emission limits", limitedMetric)
+		}
+
+		limitedMetrics[limitedMetric] = true
+
+		limit := MetricEmissionLimit{
+			Collector:  collector,
+			MetricName: metricName,
+		}
+
+		for _, rawValue := range strings.Split(rawValues, ",") {
+			kind, rawAmount, _ := strings.Cut(strings.TrimSpace(rawValue), ":")
+
+			amount, err := strconv.Atoi(rawAmount)
+
+			if err != nil || amount <= 0 {
+				return nil, fmt.Errorf("invalid limit %s for metric emission limit %s, must be sample:<N> or rate:<max per minute> with a positive integer", rawValue, rawLimit)
+			}
+
+			switch kind {
+			case "sample":
+				limit.SampleEvery = amount
+			case "rate":
+				limit.MaxPerMinute = amount
+			default:
+				return nil, fmt.Errorf("invalid limit %s for metric emission limit %s, must be sample:<N> or rate:<max per minute> with a positive integer", rawValue, rawLimit)
+			}
+		}
+
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// parseUptimeProbes parses a comma separated list of uptime
+// probes, returning the probes starting with the status probe
+// (which is always probed) and error (if any) if any probe isn't
+// supported
+func parseUptimeProbes(rawProbes string) ([]string, error) {
+	probes := []string{UptimeProbeStatus}
+
+	for _, probe := range strings.Split(rawProbes, ",") {
+		probe = strings.TrimSpace(probe)
+
+		if probe == "" {
+			continue
+		}
+
+		var valid bool
+
+		for _, validProbe := range ValidUptimeProbes {
+			if probe == validProbe {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("invalid probe %s, valid probes are %v", probe, ValidUptimeProbes)
+		}
+
+		var duplicate bool
+
+		for _, existing := range probes {
+			if probe == existing {
+				duplicate = true
+
+				break
+			}
+		}
+
+		if !duplicate {
+			probes = append(probes, probe)
+		}
+	}
+
+	return probes, nil
+}
+
+// parseRPCProbeMethods parses rpc methods to probe in the form
+// <method>[=<json params>][;...] returning the methods and error (if any)
+func parseRPCProbeMethods(rawMethods string) ([]RPCProbeMethod, error) {
+	var methods []RPCProbeMethod
+
+	methodNames := make(map[string]bool)
+
+	for _, rawMethod := range strings.Split(rawMethods, ";") {
+		rawMethod = strings.TrimSpace(rawMethod)
+
+		if rawMethod == "" {
+			continue
+		}
+
+		method, params, _ := strings.Cut(rawMethod, "=")
+		method = strings.TrimSpace(method)
+		params = strings.TrimSpace(params)
+
+		if method == "" {
+			return nil, fmt.Errorf("invalid rpc probe method %s, expected <method>[=<json params>]", rawMethod)
+		}
+
+		if methodNames[method] {
+			return nil, fmt.Errorf("rpc probe method %s specified multiple times", method)
+		}
+
+		methodNames[method] = true
+
+		if params != "" && !json.Valid([]byte(params)) {
+			return nil, fmt.Errorf("invalid params %s for rpc probe method %s, must be json e.g. {\"query\":\"tx.height>0\"}", params, method)
+		}
+
+		methods = append(methods, RPCProbeMethod{
+			Method: method,
+			Params: params,
+		})
+	}
+
+	return methods, nil
+}
+
+// parseExecHooks parses hooks in the form
+// <selector>=<binary and arguments>[;...]
+// returning the hooks and error (if any)
+func parseExecHooks(rawHooks string) ([]incident.ExecHook, error) {
+	var hooks []incident.ExecHook
+
+	for _, rawHook := range strings.Split(rawHooks, ";") {
+		rawHook = strings.TrimSpace(rawHook)
+
+		if rawHook == "" {
+			continue
+		}
+
+		selector, rawCommand, found := strings.Cut(rawHook, "=")
+		selector = strings.TrimSpace(selector)
+
+		if !found || selector == "" {
+			return nil, fmt.Errorf("invalid exec hook %s, expected <event type>[:<kind>]=<command>", rawHook)
+		}
+
+		if err := incident.ValidateExecHookSelector(selector); err != nil {
+			return nil, err
+		}
+
+		command, err := heal.ParseCommand(rawCommand)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid command for exec hook %s: %w", selector, err)
+		}
+
+		if len(command) == 0 {
+			return nil, fmt.Errorf("invalid exec hook %s, a command is required", rawHook)
+		}
+
+		hooks = append(hooks, incident.ExecHook{
+			Selector: selector,
+			Command:  command,
+		})
+	}
+
+	return hooks, nil
+}
+
+// parseVantageEndpoints parses vantage endpoints in the form
+// <region>=<url>[;...] returning the endpoints and error (if any)
+func parseVantageEndpoints(rawEndpoints string) ([]VantageEndpoint, error) {
+	var endpoints []VantageEndpoint
+
+	regions := make(map[string]bool)
+
+	for _, rawEndpoint := range strings.Split(rawEndpoints, ";") {
+		rawEndpoint = strings.TrimSpace(rawEndpoint)
+
+		if rawEndpoint == "" {
+			continue
+		}
+
+		region, rawURL, found := strings.Cut(rawEndpoint, "=")
+		region = strings.TrimSpace(region)
+		rawURL = strings.TrimSpace(rawURL)
+
+		if !found || region == "" || rawURL == "" {
+			return nil, fmt.Errorf("invalid vantage endpoint %s, expected <region>=<url>", rawEndpoint)
+		}
+
+		if regions[region] {
+			return nil, fmt.Errorf("vantage endpoint for region %s specified multiple times", region)
+		}
+
+		regions[region] = true
+
+		endpointURL, err := url.Parse(rawURL)
+
+		if err != nil || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") || endpointURL.Host == "" {
+			return nil, fmt.Errorf("invalid url %s for vantage endpoint %s, must be an http or https url e.g. https://eu.rpc.data.kava.io", rawURL, region)
+		}
+
+		endpoints = append(endpoints, VantageEndpoint{
+			Region: region,
+			URL:    rawURL,
+		})
+	}
+
+	return endpoints, nil
+}
+
+// parseNodeGroups parses node groups in the form
+// <group>=<comma separated node ids or endpoint urls>[;...]
+// returning the groups and error (if any)
+func parseNodeGroups(rawGroups string) ([]NodeGroup, error) {
+	var groups []NodeGroup
+
+	groupNames := make(map[string]bool)
+
+	for _, rawGroup := range strings.Split(rawGroups, ";") {
+		rawGroup = strings.TrimSpace(rawGroup)
+
+		if rawGroup == "" {
+			continue
+		}
+
+		name, rawMembers, found := strings.Cut(rawGroup, "=")
+		name = strings.TrimSpace(name)
+
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid node group %s, expected <group>=<node ids or endpoint urls>", rawGroup)
+		}
+
+		if groupNames[name] {
+			return nil, fmt.Errorf("node group %s defined multiple times", name)
+		}
+
+		groupNames[name] = true
+
+		var members []string
+
+		for _, member := range strings.Split(rawMembers, ",") {
+			member = strings.TrimSpace(member)
+
+			if member != "" {
+				members = append(members, member)
+			}
+		}
+
+		if len(members) == 0 {
+			return nil, fmt.Errorf("node group %s must include at least one node id or endpoint url", name)
+		}
+
+		groups = append(groups, NodeGroup{
+			Name:    name,
+			Members: members,
+		})
+	}
+
+	return groups, nil
+}
+
+// parseHealthChecks parses health checks in the form
+// <check>[:<interval seconds>][,...] returning the
+// check schedules and error (if any)
+func parseHealthChecks(rawChecks string) ([]HealthCheckSchedule, error) {
+	var schedules []HealthCheckSchedule
+
+	names := make(map[string]bool)
+
+	for _, rawCheck := range strings.Split(rawChecks, ",") {
+		rawCheck = strings.TrimSpace(rawCheck)
+
+		if rawCheck == "" {
+			continue
+		}
+
+		name, rawInterval, hasInterval := strings.Cut(rawCheck, ":")
+
+		var valid bool
+
+		for _, validCheck := range checks.ValidChecks() {
+			if name == validCheck {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("invalid health check %s, valid checks are %v", name, checks.ValidChecks())
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("invalid health checks %s, check %s is specified more than once", rawChecks, name)
+		}
+
+		names[name] = true
+
+		var intervalSeconds int
+
+		if hasInterval {
+			var err error
+
+			intervalSeconds, err = strconv.Atoi(rawInterval)
+
+			if err != nil || intervalSeconds <= 0 {
+				return nil, fmt.Errorf("invalid interval %s for health check %s, must be a positive integer", rawInterval, name)
+			}
+		}
+
+		schedules = append(schedules, HealthCheckSchedule{
+			Name:                name,
+			IntervalSeconds:     intervalSeconds,
+			RetryBackoffSeconds: DefaultHealthCheckRetryBackoffSeconds,
+		})
+	}
+
+	return schedules, nil
+}
+
+// parseHealthCheckPolicies parses per check timeouts, retries and
+// retry backoffs, each in the form <check>:<value>[,...], setting
+// them on the schedules of the checks and returning error (if any)
+func parseHealthCheckPolicies(schedules []HealthCheckSchedule, rawTimeouts string, rawRetries string, rawRetryBackoffs string) error {
+	timeouts, err := parseHealthCheckValues(HealthCheckTimeoutsFlagName, rawTimeouts, schedules, 1)
+
+	if err != nil {
+		return err
+	}
+
+	retries, err := parseHealthCheckValues(HealthCheckRetriesFlagName, rawRetries, schedules, 0)
+
+	if err != nil {
+		return err
+	}
+
+	retryBackoffs, err := parseHealthCheckValues(HealthCheckRetryBackoffsFlagName, rawRetryBackoffs, schedules, 0)
+
+	if err != nil {
+		return err
+	}
+
+	for index := range schedules {
+		name := schedules[index].Name
+
+		if timeout, exists := timeouts[name]; exists {
+			schedules[index].TimeoutSeconds = timeout
+		}
+
+		if retryCount, exists := retries[name]; exists {
+			schedules[index].Retries = retryCount
+		}
+
+		if retryBackoff, exists := retryBackoffs[name]; exists {
+			schedules[index].RetryBackoffSeconds = retryBackoff
+		}
+	}
+
+	return nil
+}
+
+// parseHealthCheckValues parses values for health checks in the form
+// <check>:<value>[,...], returning the value for each check and error
+// (if any) if a check isn't scheduled or its value is less than min
+func parseHealthCheckValues(flagName string, rawValues string, schedules []HealthCheckSchedule, min int) (map[string]int, error) {
+	values := make(map[string]int)
+
+	for _, rawValue := range strings.Split(rawValues, ",") {
+		rawValue = strings.TrimSpace(rawValue)
+
+		if rawValue == "" {
+			continue
+		}
+
+		name, rawNumber, found := strings.Cut(rawValue, ":")
+
+		if !found {
+			return nil, fmt.Errorf("invalid %s %s, must be in the form <check>:<value>", flagName, rawValue)
+		}
+
+		var scheduled bool
+
+		for _, schedule := range schedules {
+			if schedule.Name == name {
+				scheduled = true
+
+				break
+			}
+		}
+
+		if !scheduled {
+			return nil, fmt.Errorf("invalid %s %s, check %s isn't one of the configured %s", flagName, rawValue, name, HealthChecksFlagName)
+		}
+
+		value, err := strconv.Atoi(rawNumber)
+
+		if err != nil || value < min {
+			return nil, fmt.Errorf("invalid %s %s, value for check %s must be an integer of at least %d", flagName, rawValue, name, min)
+		}
+
+		values[name] = value
+	}
+
+	return values, nil
+}
+
+// parseControlHealStrategies parses a comma separated list of the
+// autoheal strategies control api clients can heal the node with,
+// returning the strategies and error (if any)
+func parseControlHealStrategies(rawStrategies string) ([]string, error) {
+	var strategies []string
+
+	for _, name := range strings.Split(rawStrategies, ",") {
+		name = strings.TrimSpace(name)
+
+		if name == "" {
+			continue
+		}
+
+		var valid bool
+
+		for _, validStrategy := range heal.ValidStrategies() {
+			if name == validStrategy {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("invalid %s strategy %s, valid strategies are %v", ControlHealStrategiesFlagName, name, heal.ValidStrategies())
+		}
+
+		strategies = append(strategies, name)
+	}
+
+	return strategies, nil
+}
+
+// parseAutohealStrategies parses autoheal strategies in the form
+// <strategy>[:<max attempts>][,...] returning the strategies and error (if any)
+func parseAutohealStrategies(rawStrategies string) ([]AutohealStrategy, error) {
+	var strategies []AutohealStrategy
+
+	for _, rawStrategy := range strings.Split(rawStrategies, ",") {
+		rawStrategy = strings.TrimSpace(rawStrategy)
+
+		if rawStrategy == "" {
+			continue
+		}
+
+		name, rawMaxAttempts, hasMaxAttempts := strings.Cut(rawStrategy, ":")
+
+		var valid bool
+
+		for _, validStrategy := range heal.ValidStrategies() {
+			if name == validStrategy {
+				valid = true
+
+				break
+			}
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("invalid autoheal strategy %s, valid strategies are %v", name, heal.ValidStrategies())
+		}
+
+		var maxAttempts int
+
+		if hasMaxAttempts {
+			var err error
+
+			maxAttempts, err = strconv.Atoi(rawMaxAttempts)
+
+			if err != nil || maxAttempts < 0 {
+				return nil, fmt.Errorf("invalid max attempts %s for autoheal strategy %s, must be a non-negative integer", rawMaxAttempts, name)
+			}
+		}
+
+		strategies = append(strategies, AutohealStrategy{
+			Name:        name,
+			MaxAttempts: maxAttempts,
+		})
+	}
+
+	return strategies, nil
 }
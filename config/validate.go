@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/kava-labs/doctor/heal"
+	"github.com/kava-labs/doctor/logging"
+)
+
+// Validate returns an error describing the first nonsensical
+// combination of config values (if any) e.g. negative intervals
+// or autohealing without a service to heal, which would otherwise
+// silently produce zero durations or bizarre autoheal behavior
+func (c *DoctorConfig) Validate() error {
+	if c.DefaultMonitoringIntervalSeconds <= 0 {
+		return fmt.Errorf("%s must be a positive number of seconds, got %d", DefaultMonitoringIntervalSecondsFlagName, c.DefaultMonitoringIntervalSeconds)
+	}
+
+	switch c.LogFormat {
+	case logging.TextFormat, logging.JSONFormat:
+	default:
+		return fmt.Errorf("invalid %s %s, valid formats are %v", LogFormatFlagName, c.LogFormat, logging.ValidFormats)
+	}
+
+	nonNegativeSettings := []struct {
+		name  string
+		value int64
+	}{
+		{AutohealSyncLatencyToleranceSecondsFlagName, int64(c.AutohealSyncLatencyToleranceSeconds)},
+		{AutohealSyncToLiveToleranceSecondsFlagName, int64(c.AutohealSyncToLiveToleranceSeconds)},
+		{AutohealRestartDelaySecondsFlagName, int64(c.AutohealRestartDelaySeconds)},
+		{AutohealInitialDelaySecondsFlagName, int64(c.AutohealInitialAllowedDelaySeconds)},
+		{AutohealEscalationDelaySecondsFlagName, int64(c.AutohealEscalationDelaySeconds)},
+		{AutohealRestoreStateThresholdSecondsFlagName, c.AutohealRestoreStateThresholdSeconds},
+		{AutohealStateSyncThresholdSecondsFlagName, int64(c.AutohealStateSyncThresholdSeconds)},
+		{AutohealRestartVerificationSecondsFlagName, int64(c.AutohealRestartVerificationSeconds)},
+		{HealthChecksTimeoutSecondsFlagName, int64(c.HealthChecksTimeoutSeconds)},
+		{RPCMaxRetriesFlagName, int64(c.RPCMaxRetries)},
+		{RPCRetryBackoffMillisecondsFlagName, int64(c.RPCRetryBackoffMilliseconds)},
+		{NoNewBlocksRestartThresholdSecondsFlagName, int64(c.NoNewBlocksRestartThresholdSeconds)},
+		{DowntimeRestartThresholdSecondsFlagName, int64(c.DowntimeRestartThresholdSeconds)},
+		{ConsensusFrozenThresholdSecondsFlagName, int64(c.ConsensusFrozenThresholdSeconds)},
+		{MempoolSizeAlertDurationSecondsFlagName, int64(c.MempoolSizeAlertDurationSeconds)},
+		{ChainConsistencyCheckIntervalSecondsFlagName, int64(c.ChainConsistencyCheckIntervalSeconds)},
+		{VotingPowerCheckIntervalSecondsFlagName, int64(c.VotingPowerCheckIntervalSeconds)},
+		{UpgradeWindowSecondsFlagName, int64(c.UpgradeWindowSeconds)},
+		{SampleStoreRetentionHoursFlagName, int64(c.SampleStoreRetentionHours)},
+		{CloudWatchFlushIntervalSecondsFlagName, int64(c.CloudWatchFlushIntervalSeconds)},
+		{CloudWatchAggregationPeriodFlagName, int64(c.CloudWatchAggregationPeriodSeconds)},
+		{MetricChannelBufferSizeFlagName, int64(c.MetricChannelBufferSize)},
+		{LogChannelBufferSizeFlagName, int64(c.LogChannelBufferSize)},
+		{EventChannelBufferSizeFlagName, int64(c.EventChannelBufferSize)},
+		{MetricFileRetentionDaysFlagName, int64(c.MetricFileRetentionDays)},
+		{UptimeShortWindowSecondsFlagName, int64(c.UptimeShortWindowSeconds)},
+		{UptimeMediumWindowSecondsFlagName, int64(c.UptimeMediumWindowSeconds)},
+		{UptimeLongWindowSecondsFlagName, int64(c.UptimeLongWindowSeconds)},
+		{UptimeEWMAHalfLifeSecondsFlagName, int64(c.UptimeEWMAHalfLifeSeconds)},
+		{AlertCooldownSecondsFlagName, int64(c.AlertCooldownSeconds)},
+		{AlertEscalationSecondsFlagName, int64(c.AlertEscalationSeconds)},
+		{ExecHookTimeoutSecondsFlagName, int64(c.ExecHookTimeoutSeconds)},
+		{ExecHookMaxConcurrencyFlagName, int64(c.ExecHookMaxConcurrency)},
+		{FleetPushIntervalSecondsFlagName, int64(c.FleetPushIntervalSeconds)},
+		{FleetStaleSecondsFlagName, int64(c.FleetStaleSeconds)},
+	}
+
+	for _, setting := range nonNegativeSettings {
+		if setting.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", setting.name, setting.value)
+		}
+	}
+
+	if c.AnomalyZScoreThreshold < 0 {
+		return fmt.Errorf("%s must not be negative, got %f", AnomalyZScoreThresholdFlagName, c.AnomalyZScoreThreshold)
+	}
+
+	if c.PeerMinRecvBytesPerSecond < 0 {
+		return fmt.Errorf("%s must not be negative, got %f", PeerMinRecvBytesPerSecondFlagName, c.PeerMinRecvBytesPerSecond)
+	}
+
+	if c.ChainDegradedVotingPowerPercent < 0 || c.ChainDegradedVotingPowerPercent > 100 {
+		return fmt.Errorf("%s must be between 0 and 100, got %f", ChainDegradedVotingPowerPercentFlagName, c.ChainDegradedVotingPowerPercent)
+	}
+
+	if c.AnomalyZScoreThreshold > 0 && c.AnomalyBaselineSamples <= 0 {
+		return fmt.Errorf("%s must be a positive number of samples when anomaly detection is enabled, got %d", AnomalyBaselineSamplesFlagName, c.AnomalyBaselineSamples)
+	}
+
+	if c.Autoheal {
+		if c.AutohealServiceManager == "" {
+			return fmt.Errorf("%s is enabled but %s is empty, set it to the service manager running the node (one of %v) or disable %s", AutohealFlagName, AutohealServiceManagerFlagName, heal.ValidServiceManagers, AutohealFlagName)
+		}
+
+		if c.AutohealBlockchainServiceName == "" {
+			return fmt.Errorf("%s is enabled but %s is empty, set it to the name of the service running the node (e.g. kava) or disable %s", AutohealFlagName, AutohealBlockchainServiceNameFlagName, AutohealFlagName)
+		}
+
+		if c.AutohealSyncLatencyToleranceSeconds < c.DefaultMonitoringIntervalSeconds {
+			return fmt.Errorf("%s of %d is less than %s of %d, the node would be considered out of sync between every check, increase %s to at least %d", AutohealSyncLatencyToleranceSecondsFlagName, c.AutohealSyncLatencyToleranceSeconds, DefaultMonitoringIntervalSecondsFlagName, c.DefaultMonitoringIntervalSeconds, AutohealSyncLatencyToleranceSecondsFlagName, c.DefaultMonitoringIntervalSeconds)
+		}
+	}
+
+	for _, strategy := range c.AutohealStrategies {
+		if strategy.Name == heal.ExecHookStrategyName && len(c.AutohealExecHookCommand) == 0 {
+			return fmt.Errorf("the %s autoheal strategy is enabled but %s is empty, set it to the command to run to heal the node", heal.ExecHookStrategyName, AutohealExecHookCommandFlagName)
+		}
+
+		if strategy.Name == heal.RefreshPeersStrategyName && len(c.AutohealRefreshPeers) == 0 && len(c.AutohealRefreshSeeds) == 0 {
+			return fmt.Errorf("the %s autoheal strategy is enabled but %s and %s are empty, set either to known good peers to reconnect the node to", heal.RefreshPeersStrategyName, AutohealRefreshPeersFlagName, AutohealRefreshSeedsFlagName)
+		}
+
+		// the exec service manager only has a command to restart the
+		// service, so stopping it would fail at heal time instead
+		if strategy.Name == heal.RestoreStateStrategyName && c.AutohealServiceManager == heal.ExecServiceManager && c.AutohealServiceHelperSocket == "" {
+			return fmt.Errorf("the %s autoheal strategy stops and starts the blockchain service but the %s %s can only restart it, use a different %s or remove the strategy", heal.RestoreStateStrategyName, heal.ExecServiceManager, AutohealServiceManagerFlagName, AutohealServiceManagerFlagName)
+		}
+	}
+
+	for _, collector := range c.MetricCollectors {
+		if collector == CloudwatchMetricCollector && c.AWSRegion == "" {
+			return fmt.Errorf("%s is empty but the %s metric collector is enabled, set it to the aws region to send metrics to (e.g. us-east-1)", AWSRegionFlagName, CloudwatchMetricCollector)
+		}
+	}
+
+	if c.ControlServerAddress != "" {
+		for _, setting := range []struct {
+			name  string
+			value string
+		}{
+			{ControlTLSCertFilepathFlagName, c.ControlTLSCertFilepath},
+			{ControlTLSKeyFilepathFlagName, c.ControlTLSKeyFilepath},
+			{ControlTLSClientCAFilepathFlagName, c.ControlTLSClientCAFilepath},
+		} {
+			if setting.value == "" {
+				return fmt.Errorf("%s is set but %s is empty, the control api is only served over mutual tls", ControlServerAddressFlagName, setting.name)
+			}
+		}
+	}
+
+	// gops agents don't support tls or authentication and allow
+	// dumping stacks and heap profiles and forcing garbage collection
+	if c.DiagnosticsAgent && !isLoopbackAddress(c.DiagnosticsAgentAddress) {
+		return fmt.Errorf("%s of %s is reachable from other hosts, the diagnostics agent doesn't support tls or authentication so can only listen on a loopback address", DiagnosticsAgentAddressFlagName, c.DiagnosticsAgentAddress)
+	}
+
+	if (c.StatusServerTLSCertFilepath == "") != (c.StatusServerTLSKeyFilepath == "") {
+		return fmt.Errorf("only one of %s and %s is set, set both to serve the status api over https or neither to serve it over plain http", StatusServerTLSCertFilepathFlagName, StatusServerTLSKeyFilepathFlagName)
+	}
+
+	if (c.FleetServerTLSCertFilepath == "") != (c.FleetServerTLSKeyFilepath == "") {
+		return fmt.Errorf("only one of %s and %s is set, set both to serve the fleet server over https or neither to serve it over plain http", FleetServerTLSCertFilepathFlagName, FleetServerTLSKeyFilepathFlagName)
+	}
+
+	for _, vantage := range c.VantageEndpoints {
+		if vantage.Region == c.Region {
+			return fmt.Errorf("vantage endpoint %s has the same region %s as %s, latencies from the two couldn't be told apart, set %s to the region the doctor is running in", vantage.URL, vantage.Region, RegionFlagName, RegionFlagName)
+		}
+	}
+
+	return nil
+}
+
+// ValidateFleetServer returns an error if the fleet server would
+// listen on an address reachable from other hosts without requiring
+// a token over tls, as anyone that could reach it could push false
+// reports and read the health of every node in the fleet
+func (c *DoctorConfig) ValidateFleetServer() error {
+	if isLoopbackAddress(c.FleetServerAddress) || (c.FleetAuthToken != "" && c.FleetServerTLSCertFilepath != "") {
+		return nil
+	}
+
+	return fmt.Errorf("%s of %s is reachable from other hosts, set %s, %s and %s so the fleet server requires a token over https or only listen on a loopback address", FleetServerAddressFlagName, c.FleetServerAddress, FleetAuthTokenFlagName, FleetServerTLSCertFilepathFlagName, FleetServerTLSKeyFilepathFlagName)
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/kava-labs/doctor/heal"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func newValidConfig() DoctorConfig {
+	return DoctorConfig{
+		DefaultMonitoringIntervalSeconds:    5,
+		LogFormat:                           logging.TextFormat,
+		MetricCollectors:                    []string{CloudwatchMetricCollector},
+		AWSRegion:                           "us-east-1",
+		Autoheal:                            true,
+		AutohealServiceManager:              heal.DefaultServiceManager,
+		AutohealBlockchainServiceName:       "kava",
+		AutohealSyncLatencyToleranceSeconds: 120,
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	config := newValidConfig()
+
+	assert.Nil(t, config.Validate())
+}
+
+func TestValidateRejectsNonsensicalConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		modify        func(config *DoctorConfig)
+		expectedError string
+	}{
+		{
+			name:          "zero monitoring interval",
+			modify:        func(config *DoctorConfig) { config.DefaultMonitoringIntervalSeconds = 0 },
+			expectedError: DefaultMonitoringIntervalSecondsFlagName + " must be a positive number of seconds, got 0",
+		},
+		{
+			name:          "invalid log format",
+			modify:        func(config *DoctorConfig) { config.LogFormat = "xml" },
+			expectedError: "invalid " + LogFormatFlagName + " xml",
+		},
+		{
+			name:          "negative restart delay",
+			modify:        func(config *DoctorConfig) { config.AutohealRestartDelaySeconds = -1 },
+			expectedError: AutohealRestartDelaySecondsFlagName + " must not be negative, got -1",
+		},
+		{
+			name:          "negative uptime window",
+			modify:        func(config *DoctorConfig) { config.UptimeLongWindowSeconds = -1 },
+			expectedError: UptimeLongWindowSecondsFlagName + " must not be negative, got -1",
+		},
+		{
+			name: "anomaly detection without a baseline",
+			modify: func(config *DoctorConfig) {
+				config.AnomalyZScoreThreshold = 5
+				config.AnomalyBaselineSamples = 0
+			},
+			expectedError: AnomalyBaselineSamplesFlagName + " must be a positive number of samples",
+		},
+		{
+			name:          "autoheal without service manager",
+			modify:        func(config *DoctorConfig) { config.AutohealServiceManager = "" },
+			expectedError: AutohealServiceManagerFlagName + " is empty",
+		},
+		{
+			name:          "autoheal without service name",
+			modify:        func(config *DoctorConfig) { config.AutohealBlockchainServiceName = "" },
+			expectedError: AutohealBlockchainServiceNameFlagName + " is empty",
+		},
+		{
+			name:          "sync tolerance shorter than monitoring interval",
+			modify:        func(config *DoctorConfig) { config.AutohealSyncLatencyToleranceSeconds = 3 },
+			expectedError: "increase " + AutohealSyncLatencyToleranceSecondsFlagName + " to at least 5",
+		},
+		{
+			name: "exec-hook strategy without a command",
+			modify: func(config *DoctorConfig) {
+				config.AutohealStrategies = []AutohealStrategy{{Name: heal.ExecHookStrategyName}}
+			},
+			expectedError: AutohealExecHookCommandFlagName + " is empty",
+		},
+		{
+			name: "refresh-peers strategy without peers or seeds",
+			modify: func(config *DoctorConfig) {
+				config.AutohealStrategies = []AutohealStrategy{{Name: heal.RefreshPeersStrategyName}}
+			},
+			expectedError: AutohealRefreshPeersFlagName + " and " + AutohealRefreshSeedsFlagName + " are empty",
+		},
+		{
+			name: "restore-state strategy with the exec service manager",
+			modify: func(config *DoctorConfig) {
+				config.AutohealServiceManager = heal.ExecServiceManager
+				config.AutohealRestartCommand = []string{"docker", "restart", "kava"}
+				config.AutohealStrategies = []AutohealStrategy{{Name: heal.RestartServiceStrategyName}, {Name: heal.RestoreStateStrategyName}}
+			},
+			expectedError: "the " + heal.RestoreStateStrategyName + " autoheal strategy stops and starts the blockchain service but the " + heal.ExecServiceManager + " " + AutohealServiceManagerFlagName + " can only restart it",
+		},
+		{
+			name:          "negative peer min recv rate",
+			modify:        func(config *DoctorConfig) { config.PeerMinRecvBytesPerSecond = -1 },
+			expectedError: PeerMinRecvBytesPerSecondFlagName + " must not be negative",
+		},
+		{
+			name:          "chain degraded voting power above 100 percent",
+			modify:        func(config *DoctorConfig) { config.ChainDegradedVotingPowerPercent = 101 },
+			expectedError: ChainDegradedVotingPowerPercentFlagName + " must be between 0 and 100",
+		},
+		{
+			name: "control api without client ca",
+			modify: func(config *DoctorConfig) {
+				config.ControlServerAddress = "0.0.0.0:9090"
+				config.ControlTLSCertFilepath = "server.pem"
+				config.ControlTLSKeyFilepath = "server-key.pem"
+			},
+			expectedError: ControlTLSClientCAFilepathFlagName + " is empty",
+		},
+		{
+			name: "status api certificate without key",
+			modify: func(config *DoctorConfig) {
+				config.StatusServerAddress = "0.0.0.0:8080"
+				config.StatusServerTLSCertFilepath = "server.pem"
+			},
+			expectedError: "only one of " + StatusServerTLSCertFilepathFlagName + " and " + StatusServerTLSKeyFilepathFlagName + " is set",
+		},
+		{
+			name:          "fleet server key without certificate",
+			modify:        func(config *DoctorConfig) { config.FleetServerTLSKeyFilepath = "server-key.pem" },
+			expectedError: "only one of " + FleetServerTLSCertFilepathFlagName + " and " + FleetServerTLSKeyFilepathFlagName + " is set",
+		},
+		{
+			name:          "cloudwatch without region",
+			modify:        func(config *DoctorConfig) { config.AWSRegion = "" },
+			expectedError: AWSRegionFlagName + " is empty",
+		},
+		{
+			name: "vantage endpoint in the doctor's region",
+			modify: func(config *DoctorConfig) {
+				config.VantageEndpoints = []VantageEndpoint{{Region: DefaultRegion, URL: "https://eu.rpc.data.kava.io"}}
+				config.Region = DefaultRegion
+			},
+			expectedError: "has the same region " + DefaultRegion,
+		},
+	}
+
+	for _, testCase := range testCases {
+		config := newValidConfig()
+
+		testCase.modify(&config)
+
+		err := config.Validate()
+
+		if assert.NotNil(t, err, testCase.name) {
+			assert.Contains(t, err.Error(), testCase.expectedError, testCase.name)
+		}
+	}
+}
+
+func TestValidateOnlyChecksAutohealSettingsWhenAutohealing(t *testing.T) {
+	config := newValidConfig()
+
+	config.Autoheal = false
+	config.AutohealBlockchainServiceName = ""
+	config.AutohealSyncLatencyToleranceSeconds = 0
+
+	assert.Nil(t, config.Validate())
+}
+
+func TestValidateFleetServerRequiresTokenAndTLSOffLoopback(t *testing.T) {
+	config := newValidConfig()
+	config.FleetServerAddress = DefaultFleetServerAddress
+
+	assert.Nil(t, config.ValidateFleetServer())
+
+	config.FleetServerAddress = "0.0.0.0:8090"
+	config.FleetAuthToken = "secret"
+
+	err := config.ValidateFleetServer()
+
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), FleetServerAddressFlagName+" of 0.0.0.0:8090 is reachable from other hosts")
+	}
+
+	config.FleetServerTLSCertFilepath = "server.pem"
+	config.FleetServerTLSKeyFilepath = "server-key.pem"
+
+	assert.Nil(t, config.ValidateFleetServer())
+}
+
+func TestValidateRefusesDiagnosticsAgentReachableFromOtherHosts(t *testing.T) {
+	config := newValidConfig()
+	config.DiagnosticsAgent = true
+
+	for _, address := range []string{DefaultDiagnosticsAgentAddress, "localhost:9000", "[::1]:9000"} {
+		config.DiagnosticsAgentAddress = address
+
+		assert.Nil(t, config.Validate(), address)
+	}
+
+	for _, address := range []string{"0.0.0.0:9000", ":9000", "10.0.0.5:9000"} {
+		config.DiagnosticsAgentAddress = address
+
+		err := config.Validate()
+
+		if assert.NotNil(t, err, address) {
+			assert.Contains(t, err.Error(), DiagnosticsAgentAddressFlagName+" of "+address+" is reachable from other hosts")
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"time"
+)
+
+const (
+	// autoheal restart delays longer than this effectively
+	// disable restarting the node as a heal action
+	MaxEffectiveAutohealRestartDelay = 24 * time.Hour
+)
+
+// ConfigWarning describes a (valid) combination of config
+// values that effectively disables a feature of the doctor
+// or exposes one of its listeners without the operator
+// necessarily realizing
+type ConfigWarning struct {
+	// name of the setting that should be changed
+	Setting string `json:"setting"`
+	Message string `json:"message"`
+}
+
+// Warnings returns a warning for each combination of
+// config values that silently disables protection
+// e.g. autohealing or freeze detection, or leaves
+// one of the doctor's listeners open to other hosts
+func (c *DoctorConfig) Warnings() []ConfigWarning {
+	var warnings []ConfigWarning
+
+	warn := func(setting string, format string, args ...interface{}) {
+		warnings = append(warnings, ConfigWarning{
+			Setting: setting,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	interval := c.DefaultMonitoringIntervalSeconds
+
+	if c.Autoheal {
+		restartDelay := time.Duration(c.AutohealRestartDelaySeconds) * time.Second
+
+		if restartDelay > MaxEffectiveAutohealRestartDelay {
+			warn(AutohealRestartDelaySecondsFlagName, "%s of %v is longer than %v, the node will be restarted at most once every %v", AutohealRestartDelaySecondsFlagName, restartDelay, MaxEffectiveAutohealRestartDelay, restartDelay)
+		}
+
+		initialDelay := time.Duration(c.AutohealInitialAllowedDelaySeconds) * time.Second
+
+		if initialDelay > MaxEffectiveAutohealRestartDelay {
+			warn(AutohealInitialDelaySecondsFlagName, "%s of %v is longer than %v, the node won't be autohealed until %v after the doctor starts", AutohealInitialDelaySecondsFlagName, initialDelay, MaxEffectiveAutohealRestartDelay, initialDelay)
+		}
+
+		if interval >= c.DowntimeRestartThresholdSeconds {
+			warn(DefaultMonitoringIntervalSecondsFlagName, "%s of %d is not shorter than %s of %d, a single failed check will be treated as sustained downtime", DefaultMonitoringIntervalSecondsFlagName, interval, DowntimeRestartThresholdSecondsFlagName, c.DowntimeRestartThresholdSeconds)
+		}
+
+		if interval >= c.NoNewBlocksRestartThresholdSeconds {
+			warn(DefaultMonitoringIntervalSecondsFlagName, "%s of %d is not shorter than %s of %d, a single check without a new block will be treated as the node being stuck", DefaultMonitoringIntervalSecondsFlagName, interval, NoNewBlocksRestartThresholdSecondsFlagName, c.NoNewBlocksRestartThresholdSeconds)
+		}
+
+		if c.AutohealFrozenConsensus && c.ConsensusFrozenThresholdSeconds == 0 {
+			warn(ConsensusFrozenThresholdSecondsFlagName, "%s is set but %s is 0, frozen consensus will never be detected or healed", AutohealFrozenConsensusFlagName, ConsensusFrozenThresholdSecondsFlagName)
+		}
+	}
+
+	if c.ConsensusFrozenThresholdSeconds > 0 && interval >= c.ConsensusFrozenThresholdSeconds {
+		warn(ConsensusFrozenThresholdSecondsFlagName, "%s of %d is not shorter than %s of %d, consensus will be considered frozen whenever it doesn't advance between two checks", DefaultMonitoringIntervalSecondsFlagName, interval, ConsensusFrozenThresholdSecondsFlagName, c.ConsensusFrozenThresholdSeconds)
+	}
+
+	if c.MempoolSizeAlertThreshold > 0 && interval > c.MempoolSizeAlertDurationSeconds {
+		warn(MempoolSizeAlertDurationSecondsFlagName, "%s of %d is longer than %s of %d, a single sample above the threshold will be alerted on", DefaultMonitoringIntervalSecondsFlagName, interval, MempoolSizeAlertDurationSecondsFlagName, c.MempoolSizeAlertDurationSeconds)
+	}
+
+	if c.MetricSamplesForSyntheticMetricCalculation > c.MaxMetricSamplesToRetainPerNode {
+		warn(MetricSamplesForSyntheticMetricCalculationFlagName, "%s of %d is more than %s of %d, only %d samples will be used for synthetic metrics", MetricSamplesForSyntheticMetricCalculationFlagName, c.MetricSamplesForSyntheticMetricCalculation, MaxMetricSamplesToRetainPerNodeFlagName, c.MaxMetricSamplesToRetainPerNode, c.MaxMetricSamplesToRetainPerNode)
+	}
+
+	if c.PublishAlerts && len(c.IncidentPublishers) == 0 {
+		warn(IncidentPublishersFlagName, "%s is set but %s is empty, alerts will only be displayed", PublishAlertsFlagName, IncidentPublishersFlagName)
+	}
+
+	var scheduledPublishers []string
+
+	for publisher := range c.IncidentPublisherSchedules {
+		scheduledPublishers = append(scheduledPublishers, publisher)
+	}
+
+	sort.Strings(scheduledPublishers)
+
+	for _, publisher := range scheduledPublishers {
+		var used bool
+
+		for _, incidentPublisher := range c.IncidentPublishers {
+			if publisher == incidentPublisher {
+				used = true
+			}
+		}
+
+		if !used {
+			warn(IncidentPublisherSchedulesFlagName, "%s schedules events for the %s incident publisher but it's not in %s, the schedule will never be used", IncidentPublisherSchedulesFlagName, publisher, IncidentPublishersFlagName)
+		}
+	}
+
+	// the control api is always served over mutual tls and the
+	// diagnostics agent only on a loopback address (see Validate),
+	// and the fleet command refuses to listen on an address reachable
+	// from other hosts unprotected (see ValidateFleetServer), leaving
+	// the listeners that can be served unprotected to warn about
+
+	// without both a token and tls anyone that can reach
+	// the address can read (or sniff) the node's metrics
+	if c.StatusServerAddress != "" && !isLoopbackAddress(c.StatusServerAddress) && (c.StatusServerAuthToken == "" || c.StatusServerTLSCertFilepath == "") {
+		warn(StatusServerAddressFlagName, "%s of %s is reachable from other hosts, set %s and %s so the status api requires a token over https or only listen on a loopback address", StatusServerAddressFlagName, c.StatusServerAddress, StatusServerAuthTokenFlagName, StatusServerTLSCertFilepathFlagName)
+	}
+
+	// the fleet server only accepts a token over https from other
+	// hosts, so pushing to it over plain http either fails or goes
+	// to a fleet server that can't be serving other hosts safely
+	if c.FleetAggregatorURL != "" && isPlaintextRemoteURL(c.FleetAggregatorURL) {
+		warn(FleetAggregatorURLFlagName, "%s is a plain http url of another host, reports and %s are sent unencrypted, use https", FleetAggregatorURLFlagName, FleetAuthTokenFlagName)
+	}
+
+	return warnings
+}
+
+// isPlaintextRemoteURL returns whether the url is
+// plain http to a host other than the local host
+func isPlaintextRemoteURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+
+	if err != nil || parsed.Scheme != "http" {
+		return false
+	}
+
+	port := parsed.Port()
+
+	if port == "" {
+		port = "80"
+	}
+
+	return !isLoopbackAddress(net.JoinHostPort(parsed.Hostname(), port))
+}
+
+// isLoopbackAddress returns whether the host of
+// the listen address only accepts local connections
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
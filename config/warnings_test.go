@@ -0,0 +1,96 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/incident"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarningsFlagConfigThatDisablesProtection(t *testing.T) {
+	config := DoctorConfig{
+		Autoheal:                           true,
+		DefaultMonitoringIntervalSeconds:   600,
+		AutohealRestartDelaySeconds:        2 * 24 * 60 * 60,
+		DowntimeRestartThresholdSeconds:    DefaultDowntimeRestartThresholdSeconds,
+		NoNewBlocksRestartThresholdSeconds: DefaultNoNewBlocksRestartThresholdSeconds,
+		ConsensusFrozenThresholdSeconds:    DefaultConsensusFrozenThresholdSeconds,
+		MaxMetricSamplesToRetainPerNode:    DefaultMetricSamplesToKeepPerNode,
+	}
+
+	var settings []string
+
+	for _, warning := range config.Warnings() {
+		settings = append(settings, warning.Setting)
+	}
+
+	assert.Equal(t, []string{
+		AutohealRestartDelaySecondsFlagName,
+		DefaultMonitoringIntervalSecondsFlagName,
+		DefaultMonitoringIntervalSecondsFlagName,
+		ConsensusFrozenThresholdSecondsFlagName,
+	}, settings)
+
+	config.DefaultMonitoringIntervalSeconds = 5
+	config.AutohealRestartDelaySeconds = DefaultAutohealRestartDelaySeconds
+
+	assert.Empty(t, config.Warnings())
+}
+
+func TestWarningsFlagUnprotectedStatusServerReachableFromOtherHosts(t *testing.T) {
+	config := DoctorConfig{
+		StatusServerAddress: "localhost:8080",
+	}
+
+	assert.Empty(t, config.Warnings())
+
+	config.StatusServerAddress = "0.0.0.0:8080"
+	config.StatusServerAuthToken = "secret"
+
+	warnings := config.Warnings()
+
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, StatusServerAddressFlagName, warnings[0].Setting)
+
+	config.StatusServerTLSCertFilepath = "server.pem"
+	config.StatusServerTLSKeyFilepath = "server-key.pem"
+
+	assert.Empty(t, config.Warnings())
+}
+
+func TestWarningsFlagFleetReportsPushedUnencrypted(t *testing.T) {
+	config := DoctorConfig{
+		FleetAggregatorURL: "https://fleet.example.com:8090",
+	}
+
+	assert.Empty(t, config.Warnings())
+
+	config.FleetAggregatorURL = "http://localhost:8090"
+
+	assert.Empty(t, config.Warnings())
+
+	config.FleetAggregatorURL = "http://fleet.example.com"
+
+	warnings := config.Warnings()
+
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, FleetAggregatorURLFlagName, warnings[0].Setting)
+	assert.NotContains(t, warnings[0].Message, "fleet.example.com")
+}
+
+func TestWarningsFlagScheduleOfUnusedIncidentPublisher(t *testing.T) {
+	config := DoctorConfig{
+		IncidentPublishers: []string{incident.EmailPublisherName},
+		IncidentPublisherSchedules: map[string]incident.Schedule{
+			incident.EmailPublisherName:    {incident.WarningSeverity: {{Start: 9 * time.Hour, End: 21 * time.Hour}}},
+			incident.TelegramPublisherName: {incident.WarningSeverity: {{Start: 9 * time.Hour, End: 21 * time.Hour}}},
+		},
+	}
+
+	warnings := config.Warnings()
+
+	assert.Len(t, warnings, 1)
+	assert.Equal(t, IncidentPublisherSchedulesFlagName, warnings[0].Setting)
+	assert.Contains(t, warnings[0].Message, incident.TelegramPublisherName)
+}
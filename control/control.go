@@ -0,0 +1,365 @@
+// package control provides a grpc api (defined in controlpb/control.proto)
+// for deployment tooling to query the health of the node the doctor
+// watches, stream the metrics it collects, toggle maintenance mode
+// (e.g. pausing autohealing before a deploy) and heal the node,
+// served over mutual tls so only clients with a certificate signed
+// by a trusted certificate authority can control the doctor
+package control
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/kava-labs/doctor/control/controlpb"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	// how long to wait for in progress requests to finish when
+	// shutting down the server before closing their connections
+	ShutdownTimeout = 5 * time.Second
+	// number of metrics buffered for each client streaming
+	// metrics if not otherwise configured
+	DefaultStreamBufferSize = 100
+)
+
+var (
+	ErrHealInProgress = errors.New("heal already in progress")
+)
+
+// Maintenance is the doctor's maintenance mode switch
+type Maintenance interface {
+	Enabled() bool
+	// Set turns maintenance mode on or off, returning
+	// whether maintenance mode is enabled
+	Set(enabled bool) bool
+}
+
+// HealFunc heals the node using the named strategy,
+// returning error (if any) if the strategy failed
+type HealFunc func(ctx context.Context, strategyName string) error
+
+// ServerConfig wraps values for configuring a control api server
+type ServerConfig struct {
+	// address to listen for requests on e.g. 0.0.0.0:9090
+	Address string
+	// pem encoded certificate and key to serve the api with
+	TLSCertFilepath string
+	TLSKeyFilepath  string
+	// pem encoded bundle of the certificate authorities
+	// that sign the certificates clients must present
+	TLSClientCAFilepath string
+	// samples to report the health of the node from
+	Endpoint *endpoint.Endpoint
+	// metrics to stream to clients
+	MetricStream *MetricStream
+	// number of metrics buffered for each client streaming metrics
+	StreamBufferSize int
+	Maintenance      Maintenance
+	// names of the heal strategies clients can heal the node
+	// with and the function to heal the node using them
+	HealStrategies []string
+	Heal           HealFunc
+	// optional function returning the reason (if any) the node
+	// shouldn't be healed e.g. the doctor is in maintenance mode
+	Blocked func() error
+	// optional lock held while healing, shared with autohealing so
+	// requested heals don't race autoheal actions, which hold it for
+	// reading as they already coordinate with each other
+	HealLock *sync.RWMutex
+	// optional logger for recording requests that change
+	// the doctor or the node and the client that made them
+	Logger *logging.Logger
+}
+
+// Server serves the control api
+type Server struct {
+	controlpb.UnimplementedControlServer
+
+	address          string
+	tlsConfig        *tls.Config
+	endpoint         *endpoint.Endpoint
+	metricStream     *MetricStream
+	streamBufferSize int
+	maintenance      Maintenance
+	healStrategies   []string
+	heal             HealFunc
+	blocked          func() error
+	// held while healing so heals requested at the
+	// same time (or autoheals) don't race each other
+	healLock *sync.RWMutex
+	logger   *logging.Logger
+}
+
+// NewServer returns a new control api server using the provided
+// config, returning error (if any) if the certificates to serve
+// the api with and verify clients against can't be loaded
+func NewServer(config ServerConfig) (*Server, error) {
+	certificate, err := tls.LoadX509KeyPair(config.TLSCertFilepath, config.TLSKeyFilepath)
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s loading control api certificate %s", err, config.TLSCertFilepath)
+	}
+
+	clientCAs, err := os.ReadFile(config.TLSClientCAFilepath)
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s reading control api client ca bundle %s", err, config.TLSClientCAFilepath)
+	}
+
+	clientCAPool := x509.NewCertPool()
+
+	if !clientCAPool.AppendCertsFromPEM(clientCAs) {
+		return nil, fmt.Errorf("no pem encoded certificates found in control api client ca bundle %s", config.TLSClientCAFilepath)
+	}
+
+	streamBufferSize := config.StreamBufferSize
+
+	if streamBufferSize <= 0 {
+		streamBufferSize = DefaultStreamBufferSize
+	}
+
+	healLock := config.HealLock
+
+	if healLock == nil {
+		healLock = &sync.RWMutex{}
+	}
+
+	return &Server{
+		address: config.Address,
+		tlsConfig: &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			ClientCAs:    clientCAPool,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		},
+		endpoint:         config.Endpoint,
+		metricStream:     config.MetricStream,
+		streamBufferSize: streamBufferSize,
+		maintenance:      config.Maintenance,
+		healStrategies:   config.HealStrategies,
+		heal:             config.Heal,
+		blocked:          config.Blocked,
+		healLock:         healLock,
+		logger:           config.Logger,
+	}, nil
+}
+
+// Serve listens for and serves requests until the context is
+// cancelled, returning error (if any) if the server failed
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.address)
+
+	if err != nil {
+		return err
+	}
+
+	return s.serve(ctx, listener)
+}
+
+// serve serves requests received by the listener
+// until the context is cancelled, waiting (up to
+// ShutdownTimeout) for in progress requests to finish
+func (s *Server) serve(ctx context.Context, listener net.Listener) error {
+	grpcServer := grpc.NewServer(grpc.Creds(credentials.NewTLS(s.tlsConfig)))
+	controlpb.RegisterControlServer(grpcServer, s)
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- grpcServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		grpcServer.GracefulStop()
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(ShutdownTimeout):
+		// metric streams last until clients cancel them
+		grpcServer.Stop()
+	}
+
+	return nil
+}
+
+// GetHealth returns the latest samples of the
+// endpoint and each node that serves it
+func (s *Server) GetHealth(ctx context.Context, request *controlpb.GetHealthRequest) (*controlpb.GetHealthResponse, error) {
+	health := s.endpoint.Health()
+
+	response := &controlpb.GetHealthResponse{
+		EndpointUrl:   s.endpoint.URL,
+		Up:            health.Up,
+		UptimePercent: health.UptimePercent,
+		Nodes:         []*controlpb.NodeHealth{},
+		Maintenance:   s.maintenance.Enabled(),
+	}
+
+	for _, nodeHealth := range health.Nodes {
+		response.Nodes = append(response.Nodes, &controlpb.NodeHealth{
+			NodeId:                         nodeHealth.NodeId,
+			LatestBlockHeight:              nodeHealth.LatestBlockHeight,
+			SecondsBehindLive:              nodeHealth.SecondsBehindLive,
+			CatchingUp:                     nodeHealth.CatchingUp,
+			StatusCheckLatencyMilliseconds: nodeHealth.StatusCheckLatencyMilliseconds,
+			PeerCount:                      int32(nodeHealth.PeerCount),
+			SampledAt:                      timestamppb.New(nodeHealth.SampledAt),
+		})
+	}
+
+	return response, nil
+}
+
+// StreamMetrics sends each metric (with one of the requested
+// names if any) as it's collected until the client cancels the
+// stream, dropping the oldest metrics if the client falls behind
+func (s *Server) StreamMetrics(request *controlpb.StreamMetricsRequest, stream controlpb.Control_StreamMetricsServer) error {
+	metricNames := make(map[string]bool)
+
+	for _, metricName := range request.MetricNames {
+		metricNames[metricName] = true
+	}
+
+	subscription := s.metricStream.Subscribe(s.streamBufferSize)
+	defer s.metricStream.Unsubscribe(subscription)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case collected := <-subscription.Metrics():
+			if len(metricNames) > 0 && !metricNames[collected.Name] {
+				continue
+			}
+
+			data, err := json.Marshal(collected.Data)
+
+			if err != nil {
+				return status.Errorf(codes.Internal, "error %s encoding %s metric", err, collected.Name)
+			}
+
+			err = stream.Send(&controlpb.Metric{
+				Name:        collected.Name,
+				Dimensions:  collected.Dimensions,
+				DataJson:    data,
+				CollectedAt: timestamppb.New(collected.CollectedAt),
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// SetMaintenance turns maintenance mode on or off
+func (s *Server) SetMaintenance(ctx context.Context, request *controlpb.SetMaintenanceRequest) (*controlpb.SetMaintenanceResponse, error) {
+	enabled := s.maintenance.Set(request.Enabled)
+
+	s.logf(ctx, "set maintenance mode to %t, maintenance mode is %t", request.Enabled, enabled)
+
+	return &controlpb.SetMaintenanceResponse{
+		Enabled: enabled,
+	}, nil
+}
+
+// Heal heals the node using the requested strategy, returning
+// once it's finished, one heal is run at a time and heals are
+// aborted if the client cancels the request
+func (s *Server) Heal(ctx context.Context, request *controlpb.HealRequest) (*controlpb.HealResponse, error) {
+	if !s.validStrategy(request.Strategy) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid heal strategy %s, valid strategies are %v", request.Strategy, s.healStrategies)
+	}
+
+	if !s.healLock.TryLock() {
+		return nil, status.Error(codes.FailedPrecondition, ErrHealInProgress.Error())
+	}
+
+	defer s.healLock.Unlock()
+
+	if s.blocked != nil {
+		if err := s.blocked(); err != nil {
+			s.logf(ctx, "requested %s heal, refused as %s", request.Strategy, err)
+
+			return nil, status.Errorf(codes.FailedPrecondition, "%s: not healing the node", err)
+		}
+	}
+
+	s.logf(ctx, "requested %s heal", request.Strategy)
+
+	if err := s.heal(ctx, request.Strategy); err != nil {
+		return nil, status.Errorf(codes.Aborted, "%s: %s heal failed", err, request.Strategy)
+	}
+
+	return &controlpb.HealResponse{
+		Strategy: request.Strategy,
+	}, nil
+}
+
+// validStrategy returns whether clients can heal
+// the node using the named heal strategy
+func (s *Server) validStrategy(strategyName string) bool {
+	for _, valid := range s.healStrategies {
+		if strategyName == valid {
+			return true
+		}
+	}
+
+	return false
+}
+
+// logf logs a request that changes the doctor or
+// the node, along with the client that made it
+func (s *Server) logf(ctx context.Context, format string, args ...interface{}) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.Infof("control api client %s %s", clientName(ctx), fmt.Sprintf(format, args...))
+}
+
+// clientName returns the common name of the certificate
+// presented by the client making the request
+func clientName(ctx context.Context) string {
+	client, ok := peer.FromContext(ctx)
+
+	if !ok {
+		return "unknown"
+	}
+
+	tlsInfo, ok := client.AuthInfo.(credentials.TLSInfo)
+
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return client.Addr.String()
+	}
+
+	return tlsInfo.State.PeerCertificates[0].Subject.CommonName
+}
@@ -0,0 +1,415 @@
+package control
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/control/controlpb"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	TestEndpointURL = "https://rpc.data.kava.io"
+	TestNodeId      = "06ff9460163caac703c44da1b2e3108e1ba087cd"
+)
+
+// testCertificateAuthority signs the server and client certificates used in tests
+type testCertificateAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+func newTestCertificateAuthority(t *testing.T, commonName string) testCertificateAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Nil(t, err)
+
+	certificate, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+
+	return testCertificateAuthority{
+		certificate: certificate,
+		key:         key,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	}
+}
+
+// issue returns a pem encoded certificate and key signed by the ca
+func (ca testCertificateAuthority) issue(t *testing.T, commonName string, extKeyUsage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{extKeyUsage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	assert.Nil(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+type testMaintenance struct {
+	enabled bool
+}
+
+func (m *testMaintenance) Enabled() bool {
+	return m.enabled
+}
+
+func (m *testMaintenance) Set(enabled bool) bool {
+	m.enabled = enabled
+
+	return m.enabled
+}
+
+// testControlAPI serves the control api on a random local port
+// until the test finishes, returning the server's ca (which
+// also signs trusted client certificates) and address
+func testControlAPI(t *testing.T, config ServerConfig) (testCertificateAuthority, string) {
+	ca := newTestCertificateAuthority(t, "doctor test ca")
+	serverCert, serverKey := ca.issue(t, "doctor", x509.ExtKeyUsageServerAuth)
+
+	directory := t.TempDir()
+
+	config.TLSCertFilepath = filepath.Join(directory, "server.pem")
+	config.TLSKeyFilepath = filepath.Join(directory, "server-key.pem")
+	config.TLSClientCAFilepath = filepath.Join(directory, "ca.pem")
+
+	assert.Nil(t, os.WriteFile(config.TLSCertFilepath, serverCert, 0600))
+	assert.Nil(t, os.WriteFile(config.TLSKeyFilepath, serverKey, 0600))
+	assert.Nil(t, os.WriteFile(config.TLSClientCAFilepath, ca.pem, 0600))
+
+	if config.Endpoint == nil {
+		config.Endpoint = endpoint.New(endpoint.EndpointConfig{URL: TestEndpointURL})
+	}
+
+	if config.MetricStream == nil {
+		config.MetricStream = NewMetricStream()
+	}
+
+	if config.Maintenance == nil {
+		config.Maintenance = &testMaintenance{}
+	}
+
+	server, err := NewServer(config)
+	assert.Nil(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+
+	go func() {
+		served <- server.serve(ctx, listener)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.Nil(t, <-served)
+	})
+
+	return ca, listener.Addr().String()
+}
+
+// testControlClient returns a client of the control api at address
+// that trusts the server's ca and presents a certificate signed by
+// clientCA (if any)
+func testControlClient(t *testing.T, address string, serverCA testCertificateAuthority, clientCA *testCertificateAuthority) controlpb.ControlClient {
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(serverCA.certificate)
+
+	tlsConfig := &tls.Config{
+		RootCAs: rootCAs,
+	}
+
+	if clientCA != nil {
+		clientCert, clientKey := clientCA.issue(t, "deployer", x509.ExtKeyUsageClientAuth)
+
+		certificate, err := tls.X509KeyPair(clientCert, clientKey)
+		assert.Nil(t, err)
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	assert.Nil(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+	})
+
+	return controlpb.NewControlClient(conn)
+}
+
+func TestControlAPIRequiresTrustedClientCertificate(t *testing.T) {
+	ca, address := testControlAPI(t, ServerConfig{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := testControlClient(t, address, ca, nil).GetHealth(ctx, &controlpb.GetHealthRequest{})
+	assert.NotNil(t, err, "clients without a certificate should be rejected")
+
+	untrustedCA := newTestCertificateAuthority(t, "untrusted ca")
+
+	_, err = testControlClient(t, address, ca, &untrustedCA).GetHealth(ctx, &controlpb.GetHealthRequest{})
+	assert.NotNil(t, err, "clients with a certificate from an untrusted ca should be rejected")
+
+	_, err = testControlClient(t, address, ca, &ca).GetHealth(ctx, &controlpb.GetHealthRequest{})
+	assert.Nil(t, err)
+}
+
+func TestGetHealthReturnsLatestSamples(t *testing.T) {
+	kavaEndpoint := endpoint.New(endpoint.EndpointConfig{URL: TestEndpointURL})
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	assert.Nil(t, kavaEndpoint.AddSample(TestNodeId, endpoint.NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:                    TestNodeId,
+			SampledAt:                 now,
+			SyncStatus:                kava.SyncInfo{LatestBlockHeight: 100, LatestBlockTime: now},
+			SecondsBehindLive:         3,
+			SampleLatencyMilliseconds: 20,
+		},
+	}))
+
+	assert.Nil(t, kavaEndpoint.AddSample(TestEndpointURL, endpoint.NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{EndpointURL: TestEndpointURL, SampledAt: now, Up: true},
+	}))
+
+	ca, address := testControlAPI(t, ServerConfig{
+		Endpoint:    kavaEndpoint,
+		Maintenance: &testMaintenance{enabled: true},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	health, err := testControlClient(t, address, ca, &ca).GetHealth(ctx, &controlpb.GetHealthRequest{})
+
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, TestEndpointURL, health.EndpointUrl)
+	assert.True(t, health.GetUp())
+	assert.Equal(t, float32(100), health.GetUptimePercent())
+	assert.True(t, health.Maintenance)
+
+	if assert.Equal(t, 1, len(health.Nodes)) {
+		assert.Equal(t, TestNodeId, health.Nodes[0].NodeId)
+		assert.Equal(t, int64(100), health.Nodes[0].LatestBlockHeight)
+		assert.Equal(t, int64(3), health.Nodes[0].SecondsBehindLive)
+		assert.Equal(t, now, health.Nodes[0].SampledAt.AsTime())
+	}
+}
+
+func TestSetMaintenance(t *testing.T) {
+	maintenance := &testMaintenance{}
+
+	ca, address := testControlAPI(t, ServerConfig{Maintenance: maintenance})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	response, err := testControlClient(t, address, ca, &ca).SetMaintenance(ctx, &controlpb.SetMaintenanceRequest{Enabled: true})
+
+	if assert.Nil(t, err) {
+		assert.True(t, response.Enabled)
+		assert.True(t, maintenance.Enabled())
+	}
+}
+
+func TestHealUsesRequestedStrategy(t *testing.T) {
+	var healed []string
+
+	ca, address := testControlAPI(t, ServerConfig{
+		HealStrategies: []string{"restart", "standby"},
+		Heal: func(ctx context.Context, strategyName string) error {
+			healed = append(healed, strategyName)
+
+			if strategyName == "standby" {
+				return errors.New("too few other healthy in service instances")
+			}
+
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := testControlClient(t, address, ca, &ca)
+
+	response, err := client.Heal(ctx, &controlpb.HealRequest{Strategy: "restart"})
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, "restart", response.Strategy)
+	}
+
+	_, err = client.Heal(ctx, &controlpb.HealRequest{Strategy: "standby"})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+
+	_, err = client.Heal(ctx, &controlpb.HealRequest{Strategy: "reboot-instance"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	assert.Equal(t, []string{"restart", "standby"}, healed)
+}
+
+func TestHealRefusedWhileBlockedOrAutohealing(t *testing.T) {
+	var healed []string
+	var blocked error
+
+	healLock := &sync.RWMutex{}
+
+	ca, address := testControlAPI(t, ServerConfig{
+		HealStrategies: []string{"restart"},
+		Heal: func(ctx context.Context, strategyName string) error {
+			healed = append(healed, strategyName)
+
+			return nil
+		},
+		Blocked:  func() error { return blocked },
+		HealLock: healLock,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := testControlClient(t, address, ca, &ca)
+
+	blocked = errors.New("doctor is in maintenance mode")
+
+	_, err := client.Heal(ctx, &controlpb.HealRequest{Strategy: "restart"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	blocked = nil
+
+	// held for reading by an in progress autoheal
+	healLock.RLock()
+
+	_, err = client.Heal(ctx, &controlpb.HealRequest{Strategy: "restart"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	healLock.RUnlock()
+
+	_, err = client.Heal(ctx, &controlpb.HealRequest{Strategy: "restart"})
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"restart"}, healed)
+}
+
+func TestStreamMetricsSendsRequestedMetrics(t *testing.T) {
+	metricStream := NewMetricStream()
+
+	ca, address := testControlAPI(t, ServerConfig{MetricStream: metricStream})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := testControlClient(t, address, ca, &ca).StreamMetrics(ctx, &controlpb.StreamMetricsRequest{MetricNames: []string{"SyncStatus"}})
+
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	// metrics collected before the server subscribes aren't streamed
+	for !subscribed(metricStream) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Nil(t, metricStream.Collect(metric.Metric{Name: "PeerConnections", Data: map[string]int{"peers": 3}}))
+	assert.Nil(t, metricStream.Collect(metric.Metric{
+		Name:       "SyncStatus",
+		Dimensions: metric.MetricDimensions{"node_id": TestNodeId},
+		Data:       map[string]int64{"latest_block_height": 100},
+	}))
+
+	streamed, err := stream.Recv()
+
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, "SyncStatus", streamed.Name)
+	assert.Equal(t, map[string]string{"node_id": TestNodeId}, streamed.Dimensions)
+
+	var data map[string]int64
+
+	assert.Nil(t, json.Unmarshal(streamed.DataJson, &data))
+	assert.Equal(t, int64(100), data["latest_block_height"])
+}
+
+func subscribed(metricStream *MetricStream) bool {
+	metricStream.subscriptionsLock.Lock()
+	defer metricStream.subscriptionsLock.Unlock()
+
+	return len(metricStream.subscriptions) > 0
+}
+
+func TestMetricStreamDropsOldestMetricsForSlowSubscribers(t *testing.T) {
+	metricStream := NewMetricStream()
+
+	subscription := metricStream.Subscribe(2)
+
+	for _, name := range []string{"first", "second", "third"} {
+		assert.Nil(t, metricStream.Collect(metric.Metric{Name: name}))
+	}
+
+	assert.Equal(t, uint64(1), subscription.Dropped())
+	assert.Equal(t, "second", (<-subscription.Metrics()).Name)
+	assert.Equal(t, "third", (<-subscription.Metrics()).Name)
+
+	metricStream.Unsubscribe(subscription)
+
+	_, open := <-subscription.Metrics()
+	assert.False(t, open)
+}
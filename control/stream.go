@@ -0,0 +1,137 @@
+package control
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+)
+
+// CollectedMetric wraps a metric with when it was collected
+type CollectedMetric struct {
+	metric.Metric
+	CollectedAt time.Time
+}
+
+// MetricSubscription receives every metric collected to
+// the stream after it subscribed, in the order collected
+type MetricSubscription struct {
+	metrics chan CollectedMetric
+	// number of metrics dropped, accessed atomically
+	dropped uint64
+}
+
+// Metrics returns the channel metrics are received on,
+// closed once the subscriber unsubscribes
+func (s *MetricSubscription) Metrics() <-chan CollectedMetric {
+	return s.metrics
+}
+
+// Dropped returns the number of metrics dropped as the
+// subscriber wasn't keeping up with the metrics collected
+func (s *MetricSubscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// deliver sends the metric to the subscriber without blocking,
+// dropping the oldest buffered metric to make room if full
+// callers must hold the stream's subscriptions lock so metrics
+// are delivered in the order they were collected
+func (s *MetricSubscription) deliver(collected CollectedMetric) {
+	select {
+	case s.metrics <- collected:
+		return
+	default:
+	}
+
+	select {
+	case <-s.metrics:
+		atomic.AddUint64(&s.dropped, 1)
+	default:
+	}
+
+	select {
+	case s.metrics <- collected:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// MetricStream is a metric collector that delivers each metric
+// collected to it to every control api client streaming metrics,
+// each through its own buffer so a slow client doesn't block
+// collecting metrics to other collectors
+// MetricStream is safe for concurrent use
+type MetricStream struct {
+	subscriptionsLock *sync.Mutex
+	subscriptions     []*MetricSubscription
+}
+
+// NewMetricStream returns a new metric stream with no subscribers
+func NewMetricStream() *MetricStream {
+	return &MetricStream{
+		subscriptionsLock: &sync.Mutex{},
+	}
+}
+
+// Subscribe returns a subscription that buffers up to bufferSize
+// (at least one) metrics, if the subscriber falls further behind
+// the oldest buffered metric is dropped
+func (s *MetricStream) Subscribe(bufferSize int) *MetricSubscription {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	subscription := &MetricSubscription{
+		metrics: make(chan CollectedMetric, bufferSize),
+	}
+
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+
+	s.subscriptions = append(s.subscriptions, subscription)
+
+	return subscription
+}
+
+// Unsubscribe stops delivering metrics to the
+// subscription and closes its metrics channel
+func (s *MetricStream) Unsubscribe(subscription *MetricSubscription) {
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+
+	for i, existing := range s.subscriptions {
+		if existing == subscription {
+			s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+
+			close(subscription.metrics)
+
+			return
+		}
+	}
+}
+
+// Collect delivers the metric to every subscriber without blocking
+func (s *MetricStream) Collect(metric metric.Metric) error {
+	collected := CollectedMetric{
+		Metric:      metric,
+		CollectedAt: time.Now(),
+	}
+
+	s.subscriptionsLock.Lock()
+	defer s.subscriptionsLock.Unlock()
+
+	for _, subscription := range s.subscriptions {
+		subscription.deliver(collected)
+	}
+
+	return nil
+}
+
+// Close is a no-op as the stream outlives the collectors it's used
+// alongside, which are replaced when the config is reloaded,
+// subscribers stop streaming by unsubscribing
+func (s *MetricStream) Close() error {
+	return nil
+}
@@ -0,0 +1,330 @@
+// daemon.go contains types and functions for running the doctor
+// as a long lived agent that writes a pid file and reloads its
+// config file on SIGHUP (or when it changes) without dropping
+// in-memory samples
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/kava-labs/doctor/audit"
+	"github.com/kava-labs/doctor/collect"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/control"
+	"github.com/kava-labs/doctor/event"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/webhook"
+)
+
+const (
+	// how long the config file must go without changing before
+	// it's reloaded, as editors often write a file in several steps
+	ConfigFileChangeDebounce = 500 * time.Millisecond
+)
+
+var (
+	ErrAlreadyRunning = errors.New("doctor already running")
+)
+
+// writePIDFile writes the id of the current process to the file
+// at pidFilepath, creating any missing parent directories and
+// returning an error wrapping `ErrAlreadyRunning` if the file
+// holds the id of another process that is still running
+func writePIDFile(pidFilepath string) error {
+	if contents, err := os.ReadFile(pidFilepath); err == nil {
+		pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+
+		// signal 0 checks whether the process exists without signalling it
+		if err == nil && pid > 0 && pid != os.Getpid() && syscall.Kill(pid, 0) == nil {
+			return fmt.Errorf("%w with pid %d according to %s", ErrAlreadyRunning, pid, pidFilepath)
+		}
+	}
+
+	err := os.MkdirAll(filepath.Dir(pidFilepath), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(pidFilepath, []byte(fmt.Sprintf("%d\n", os.Getpid())), 0644)
+}
+
+// signalRunningDoctor sends sig to the doctor whose process id is
+// in the file at pidFilepath, returning error (if any) if the file
+// can't be read or the doctor isn't running
+func signalRunningDoctor(pidFilepath string, sig syscall.Signal) error {
+	contents, err := os.ReadFile(pidFilepath)
+
+	if err != nil {
+		return fmt.Errorf("error %s reading pid file %s, is doctor running in daemon mode?", err, pidFilepath)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+
+	if err != nil || pid <= 0 {
+		return fmt.Errorf("invalid pid %q in %s", strings.TrimSpace(string(contents)), pidFilepath)
+	}
+
+	return syscall.Kill(pid, sig)
+}
+
+// watchAutohealResets resets the autoheal restart breaker of
+// the node client each time SIGUSR2 is received (e.g. sent by the
+// reset-autoheal command) until the context is cancelled
+func watchAutohealResets(ctx context.Context, nodeClient *NodeClient, logger *logging.Logger) {
+	resets := make(chan os.Signal, 1)
+	signal.Notify(resets, syscall.SIGUSR2)
+	defer signal.Stop(resets)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-resets:
+			nodeClient.ResetRestartBreaker()
+
+			logger.Infof("reset autoheal restart breaker")
+		}
+	}
+}
+
+// DisplayReload wraps the settings of the non-interactive display
+// that are replaced when the config is reloaded, applied by the
+// display between metrics so samples retained in memory are kept
+type DisplayReload struct {
+	// replace the display's collectors, which are closed once replaced
+	MetricCollectors                      []collect.Collector
+	PeerChurnAlertThresholdPerMinute      float64
+	BlockIntervalP95AlertThresholdSeconds float64
+	NodeGroups                            []dconfig.NodeGroup
+	NodeGroupSyncLatencyToleranceSeconds  int
+}
+
+// ConfigReloaderConfig wraps values used to apply
+// config reloaded on SIGHUP or when the config file changes
+type ConfigReloaderConfig struct {
+	// whether to reload the config when SIGHUP is received
+	ReloadOnHangup bool
+	// optional channel the config file watcher
+	// signals changes to the config file on
+	ConfigFileChanges <-chan struct{}
+	// config the doctor started with, used to warn about
+	// changed settings that only take effect on restart
+	InitialConfig *dconfig.DoctorConfig
+	// reused for the reloaded collectors
+	MetricSigner  audit.Signer
+	WebhookClient *webhook.Client
+	Maintenance   *Maintenance
+	MetricStream  *control.MetricStream
+	// where to send the reloaded monitoring
+	// interval and autohealing thresholds
+	NodeClientControls chan<- NodeClientControl
+	// where to send the reloaded display settings
+	DisplayReloads chan<- DisplayReload
+	// optional bus to publish the reloaded config to
+	Events *event.Bus
+	Logger *logging.Logger
+}
+
+// watchConfigReloads reloads the config file each time SIGHUP is
+// received (if enabled) or the config file watcher signals the
+// config file changed until the context is cancelled
+func watchConfigReloads(ctx context.Context, config ConfigReloaderConfig) error {
+	hangups := make(chan os.Signal, 1)
+
+	if config.ReloadOnHangup {
+		signal.Notify(hangups, syscall.SIGHUP)
+		defer signal.Stop(hangups)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hangups:
+			reloadConfig(ctx, config)
+		case <-config.ConfigFileChanges:
+			config.Logger.Infof("config file changed, reloading config")
+
+			reloadConfig(ctx, config)
+		}
+	}
+}
+
+// watchConfigFile signals changes on the changes channel each time
+// the config file at configFilepath is written or replaced (e.g.
+// by an editor renaming a temporary file over it), once it hasn't
+// changed for ConfigFileChangeDebounce, until the context is cancelled,
+// returning error (if any) if the config file can't be watched
+func watchConfigFile(ctx context.Context, configFilepath string, changes chan<- struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	defer watcher.Close()
+
+	configFilepath = filepath.Clean(configFilepath)
+
+	// watch the directory instead of the file so the
+	// config file is still watched after being replaced
+	err = watcher.Add(filepath.Dir(configFilepath))
+
+	if err != nil {
+		return fmt.Errorf("error %s watching config file %s", err, configFilepath)
+	}
+
+	var debounce <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			if filepath.Clean(event.Name) != configFilepath || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+				continue
+			}
+
+			debounce = time.After(ConfigFileChangeDebounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+
+			return fmt.Errorf("error %s watching config file %s", err, configFilepath)
+		case <-debounce:
+			debounce = nil
+
+			// drop the change if a reload is already pending
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// reloadableConfigFields are the fields of the doctor config applied by
+// reloadConfig, changes to any other settings only take effect once the
+// doctor is restarted as they're used to create long lived clients,
+// listeners and routines
+var reloadableConfigFields = []string{
+	// metric collectors
+	"MetricCollectors",
+	"AWSRegion",
+	"MetricNamespace",
+	"FileMetricRoutes",
+	"MetricFileDirectory",
+	"CompressRotatedMetricFiles",
+	"MetricFileRetentionDays",
+	"CloudWatchFlushIntervalSeconds",
+	"CloudWatchMaxQueuedMetrics",
+	"CloudWatchAggregationPeriodSeconds",
+	"OTLPEndpoint",
+	"OTLPHeaders",
+	"OTLPResourceAttributes",
+	"OTLPTraces",
+	"MetricEmissionLimits",
+	// display
+	"PeerChurnAlertThresholdPerMinute",
+	"BlockIntervalP95AlertThresholdSeconds",
+	"NodeGroups",
+	// node client
+	"DefaultMonitoringIntervalSeconds",
+	"AutohealSyncLatencyToleranceSeconds",
+	"DowntimeRestartThresholdSeconds",
+	"NoNewBlocksRestartThresholdSeconds",
+	"AutohealRestartDelaySeconds",
+}
+
+// reloadConfig re-reads the config file, re-creating the display's
+// collectors and sending the reloaded thresholds to the node client
+// and display, continuing with the previous config if the reloaded
+// config is invalid
+func reloadConfig(ctx context.Context, config ConfigReloaderConfig) {
+	logger := config.Logger
+
+	reloaded, err := dconfig.GetDoctorConfig()
+
+	if err != nil {
+		logger.Errorf("error %s reloading config, continuing with the previous config", err)
+
+		return
+	}
+
+	// the values aren't logged as some settings are secrets
+	for _, setting := range dconfig.ChangedSettings(config.InitialConfig, reloaded, reloadableConfigFields...) {
+		logger.Warnf("%s changed, restart the doctor for the change to take effect", setting)
+	}
+
+	metricCollectorsConfig := NewMetricCollectorsConfig(reloaded, config.MetricSigner, config.WebhookClient)
+	metricCollectorsConfig.Maintenance = config.Maintenance
+	metricCollectorsConfig.MetricStream = config.MetricStream
+	metricCollectorsConfig.Ctx = ctx
+
+	collectors, err := NewMetricCollectors(metricCollectorsConfig, logger)
+
+	if err != nil {
+		logger.Errorf("error %s creating metric collectors from reloaded config, continuing with the previous config", err)
+
+		return
+	}
+
+	for _, warning := range reloaded.Warnings() {
+		logger.Warnf("config warning: %s", warning.Message)
+	}
+
+	displayReload := DisplayReload{
+		MetricCollectors:                      collectors,
+		PeerChurnAlertThresholdPerMinute:      reloaded.PeerChurnAlertThresholdPerMinute,
+		BlockIntervalP95AlertThresholdSeconds: reloaded.BlockIntervalP95AlertThresholdSeconds,
+		NodeGroups:                            reloaded.NodeGroups,
+		NodeGroupSyncLatencyToleranceSeconds:  reloaded.AutohealSyncLatencyToleranceSeconds,
+	}
+
+	select {
+	case config.DisplayReloads <- displayReload:
+	case <-ctx.Done():
+		collect.CloseAll(collectors)
+
+		return
+	}
+
+	control := NodeClientControl{
+		MonitoringIntervalSeconds:           reloaded.DefaultMonitoringIntervalSeconds,
+		AutohealSyncLatencyToleranceSeconds: reloaded.AutohealSyncLatencyToleranceSeconds,
+		DowntimeRestartThresholdSeconds:     reloaded.DowntimeRestartThresholdSeconds,
+		NoNewBlocksRestartThresholdSeconds:  reloaded.NoNewBlocksRestartThresholdSeconds,
+		AutohealRestartDelaySeconds:         reloaded.AutohealRestartDelaySeconds,
+	}
+
+	select {
+	case config.NodeClientControls <- control:
+	case <-ctx.Done():
+		return
+	}
+
+	logger.Infof("reloaded config")
+
+	config.Events.Publish(event.Event{
+		Type:    event.ConfigLoadedType,
+		Source:  reloaded.ConfigFilepath,
+		Message: "reloaded config",
+	})
+}
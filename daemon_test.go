@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePIDFileReplacesStalePIDFiles(t *testing.T) {
+	pidFilepath := filepath.Join(t.TempDir(), "doctor", "doctor.pid")
+
+	err := os.MkdirAll(filepath.Dir(pidFilepath), 0755)
+	assert.Nil(t, err)
+
+	// left behind by a doctor that crashed
+	err = os.WriteFile(pidFilepath, []byte("not a pid\n"), 0644)
+	assert.Nil(t, err)
+
+	err = writePIDFile(pidFilepath)
+	assert.Nil(t, err)
+
+	contents, err := os.ReadFile(pidFilepath)
+	assert.Nil(t, err)
+	assert.Equal(t, fmt.Sprintf("%d\n", os.Getpid()), string(contents))
+}
+
+func TestWritePIDFileRefusesWhenAlreadyRunning(t *testing.T) {
+	pidFilepath := filepath.Join(t.TempDir(), "doctor.pid")
+
+	// the parent of the test process is still running
+	err := os.WriteFile(pidFilepath, []byte(fmt.Sprintf("%d\n", os.Getppid())), 0644)
+	assert.Nil(t, err)
+
+	err = writePIDFile(pidFilepath)
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+}
+
+func TestSignalRunningDoctorRejectsInvalidPIDFiles(t *testing.T) {
+	pidFilepath := filepath.Join(t.TempDir(), "doctor.pid")
+
+	err := signalRunningDoctor(pidFilepath, syscall.SIGUSR2)
+	assert.NotNil(t, err)
+
+	err = os.WriteFile(pidFilepath, []byte("not a pid\n"), 0644)
+	assert.Nil(t, err)
+
+	err = signalRunningDoctor(pidFilepath, syscall.SIGUSR2)
+	assert.NotNil(t, err)
+}
+
+func TestWatchConfigFileSignalsChangesToTheConfigFile(t *testing.T) {
+	directory := t.TempDir()
+	configFilepath := filepath.Join(directory, "config.json")
+
+	err := os.WriteFile(configFilepath, []byte(`{"debug": false}`), 0644)
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := make(chan struct{}, 1)
+	watchErrs := make(chan error, 1)
+
+	go func() {
+		watchErrs <- watchConfigFile(ctx, configFilepath, changes)
+	}()
+
+	// give the watcher time to start watching
+	time.Sleep(100 * time.Millisecond)
+
+	// changes to other files in the directory are ignored
+	err = os.WriteFile(filepath.Join(directory, "other.json"), []byte(`{}`), 0644)
+	assert.Nil(t, err)
+
+	select {
+	case <-changes:
+		t.Fatal("expected changes to other files to be ignored")
+	case <-time.After(2 * ConfigFileChangeDebounce):
+	}
+
+	// replacing the config file like an editor would
+	replacementFilepath := filepath.Join(directory, "config.json.tmp")
+
+	err = os.WriteFile(replacementFilepath, []byte(`{"debug": true}`), 0644)
+	assert.Nil(t, err)
+
+	err = os.Rename(replacementFilepath, configFilepath)
+	assert.Nil(t, err)
+
+	select {
+	case <-changes:
+	case <-time.After(5 * ConfigFileChangeDebounce):
+		t.Fatal("expected replacing the config file to be signalled")
+	}
+
+	// writing to the replaced config file
+	err = os.WriteFile(configFilepath, []byte(`{"debug": false}`), 0644)
+	assert.Nil(t, err)
+
+	select {
+	case <-changes:
+	case <-time.After(5 * ConfigFileChangeDebounce):
+		t.Fatal("expected writing to the config file to be signalled")
+	}
+
+	cancel()
+
+	assert.Nil(t, <-watchErrs)
+}
+
+func TestReloadableConfigFieldsAreDoctorConfigFields(t *testing.T) {
+	configType := reflect.TypeOf(dconfig.DoctorConfig{})
+
+	for _, field := range reloadableConfigFields {
+		_, exists := configType.FieldByName(field)
+
+		assert.True(t, exists, "reloadable field %s isn't a field of the doctor config", field)
+	}
+}
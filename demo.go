@@ -0,0 +1,64 @@
+// demo.go contains functions for running the doctor
+// against a fake node serving scripted failure scenarios
+// (as opposed to a real node) for training and screenshots
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/demo"
+	"github.com/kava-labs/doctor/logging"
+)
+
+// startDemoNode starts a fake node serving the default scenario on a
+// local port, updating the config to watch the fake node and disabling
+// features with side effects outside of the doctor (e.g. autohealing or
+// publishing incidents), returning a function to stop the fake node
+// and error (if any)
+func startDemoNode(config *dconfig.DoctorConfig, logger *logging.Logger) (func() error, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not listen for demo node requests", err)
+	}
+
+	node := demo.NewNode(demo.NodeConfig{
+		OnPhaseChange: func(phase demo.Phase) {
+			logger.Warnf("demo node entered %s phase, %s for %v", phase.Name, phase.Description, phase.Duration)
+		},
+	})
+
+	server := &http.Server{
+		Handler: node,
+	}
+
+	go server.Serve(listener)
+
+	config.KavaNodeRPCURL = fmt.Sprintf("http://%s", listener.Addr())
+
+	// there is no real node to heal or compare against
+	config.Autoheal = false
+	config.ReferenceAPIAddress = ""
+	config.AccessLogFilepath = ""
+	config.ExpectedNodeId = ""
+	config.ExpectedNodeMoniker = ""
+	config.ChainId = ""
+
+	// probe the application as well so its crashes are detected
+	config.UptimeProbes = dconfig.ValidUptimeProbes
+
+	// keep demo samples and alerts out of real dashboards and stores
+	config.MetricCollectors = []string{dconfig.FileMetricCollector}
+	config.IncidentPublishers = nil
+	config.WebhookURL = ""
+	config.SampleStoreBackend = ""
+	config.AnomalyBaselinesFilepath = ""
+
+	logger.Infof("demo node serving scripted failure scenarios at %s", config.KavaNodeRPCURL)
+
+	return server.Close, nil
+}
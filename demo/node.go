@@ -0,0 +1,450 @@
+// package demo provides a fake kava node serving scripted
+// failure scenarios (e.g. the node falling behind or freezing)
+// for safely demonstrating what the doctor does during failures
+
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+)
+
+const (
+	DefaultBlockInterval      = 6 * time.Second
+	DefaultNodeId             = "d0c70d0c70d0c70d0c70d0c70d0c70d0c70d0c70"
+	DefaultNodeMoniker        = "doctor-demo"
+	DefaultChainId            = "kava-demo-1"
+	DefaultStartingHeight     = 1000000
+	DefaultNumberOfPeers      = 8
+	DefaultApplicationName    = "kava"
+	DefaultApplicationVersion = "0.26.0"
+	consensusStepPropose      = 3
+	consensusStepPrevote      = 4
+	defaultMempoolSize        = 12
+	defaultMempoolTxSizeBytes = 512
+	// rates peers send the node data at, peers of a frozen
+	// node send it little as they have no blocks it's missing
+	defaultPeerRecvBytesPerSecond = 16384
+	frozenPeerRecvBytesPerSecond  = 64
+	defaultPeerSendBytesPerSecond = 8192
+	defaultPeerConnectedFor       = 30 * time.Minute
+	// validators of the demo chain, which all
+	// have the same voting power and sign every block
+	DefaultNumberOfValidators   = 4
+	defaultValidatorVotingPower = 1000
+)
+
+// Phase is a period of a scenario during which
+// the fake node behaves in a scripted way
+type Phase struct {
+	Name        string
+	Description string
+	Duration    time.Duration
+	// how far the node's latest block is behind live
+	SecondsBehindLive int64
+	// whether the node reports it's catching up
+	CatchingUp bool
+	// whether the node stops producing blocks
+	Frozen bool
+	// whether the node fails all requests
+	Down bool
+	// whether the application the node runs is dead,
+	// failing requests for the application's state
+	// while the node keeps answering other requests
+	ApplicationDown bool
+}
+
+// DefaultScenario cycles through the failures the doctor
+// detects (and would autoheal), recovering after each one
+var DefaultScenario = []Phase{
+	{
+		Name:        "healthy",
+		Description: "node is synched to live",
+		Duration:    30 * time.Second,
+	},
+	{
+		Name:              "lag spike",
+		Description:       "node falls 5 minutes behind live and starts catching up",
+		Duration:          45 * time.Second,
+		SecondsBehindLive: 300,
+		CatchingUp:        true,
+	},
+	{
+		Name:        "recovery",
+		Description: "node has caught up to live",
+		Duration:    30 * time.Second,
+	},
+	{
+		Name:        "freeze",
+		Description: "node stops producing new blocks with consensus stuck in prevote",
+		Duration:    60 * time.Second,
+		Frozen:      true,
+	},
+	{
+		Name:        "recovery",
+		Description: "node is producing blocks again",
+		Duration:    30 * time.Second,
+	},
+	{
+		Name:        "downtime",
+		Description: "node's rpc api fails all requests",
+		Duration:    45 * time.Second,
+		Down:        true,
+	},
+	{
+		Name:        "recovery",
+		Description: "node's rpc api is back online",
+		Duration:    30 * time.Second,
+	},
+	{
+		Name:            "application crash",
+		Description:     "node answers status requests but the application it runs is dead",
+		Duration:        45 * time.Second,
+		Frozen:          true,
+		ApplicationDown: true,
+	},
+	{
+		Name:        "recovery",
+		Description: "application is running again",
+		Duration:    30 * time.Second,
+	},
+}
+
+// NodeConfig wraps values used to configure a fake node
+type NodeConfig struct {
+	// phases to cycle through, defaults to DefaultScenario
+	Scenario      []Phase
+	BlockInterval time.Duration
+	// called (in a new go-routine) whenever
+	// the node enters a phase of the scenario
+	OnPhaseChange func(phase Phase)
+	// when the scenario starts, defaults to when the node is created
+	StartedAt time.Time
+}
+
+// Node is a fake kava node that serves the rpc api
+// endpoints used by the doctor according to a scenario
+// Node is safe to use across go-routines
+type Node struct {
+	scenario      []Phase
+	blockInterval time.Duration
+	onPhaseChange func(phase Phase)
+	startedAt     time.Time
+	// index of the phase the node was last in, -1 if none
+	phaseIndex int
+	phaseLock  *sync.Mutex
+}
+
+// NewNode returns a new fake node using the provided config
+func NewNode(config NodeConfig) *Node {
+	scenario := config.Scenario
+
+	if len(scenario) == 0 {
+		scenario = DefaultScenario
+	}
+
+	blockInterval := config.BlockInterval
+
+	if blockInterval <= 0 {
+		blockInterval = DefaultBlockInterval
+	}
+
+	startedAt := config.StartedAt
+
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+
+	return &Node{
+		scenario:      scenario,
+		blockInterval: blockInterval,
+		onPhaseChange: config.OnPhaseChange,
+		startedAt:     startedAt,
+		phaseIndex:    -1,
+		phaseLock:     &sync.Mutex{},
+	}
+}
+
+// scenarioDuration returns how long one cycle through the scenario takes
+func (n *Node) scenarioDuration() time.Duration {
+	var duration time.Duration
+
+	for _, phase := range n.scenario {
+		duration += phase.Duration
+	}
+
+	return duration
+}
+
+// State returns the state of the node at the provided time: the
+// phase of the scenario it's in along with the index of the phase
+// in the scenario, and its sync status
+func (n *Node) State(now time.Time) (Phase, int, kava.SyncInfo) {
+	elapsed := now.Sub(n.startedAt)
+
+	if elapsed < 0 {
+		elapsed = 0
+	}
+
+	cycleDuration := n.scenarioDuration()
+	cycles := elapsed / cycleDuration
+	elapsedInCycle := elapsed % cycleDuration
+
+	// time spent producing blocks, i.e. not frozen
+	var productiveTimePerCycle time.Duration
+
+	for _, phase := range n.scenario {
+		if !phase.Frozen {
+			productiveTimePerCycle += phase.Duration
+		}
+	}
+
+	productiveTime := cycles * productiveTimePerCycle
+
+	var phaseIndex int
+	var elapsedInPhase time.Duration
+
+	for i, phase := range n.scenario {
+		if elapsedInCycle < phase.Duration {
+			phaseIndex = i
+			elapsedInPhase = elapsedInCycle
+
+			break
+		}
+
+		elapsedInCycle -= phase.Duration
+
+		if !phase.Frozen {
+			productiveTime += phase.Duration
+		}
+	}
+
+	phase := n.scenario[phaseIndex]
+
+	// time since the latest block was produced
+	sinceLatestBlock := time.Duration(phase.SecondsBehindLive) * time.Second
+
+	if phase.Frozen {
+		sinceLatestBlock += elapsedInPhase
+	} else {
+		productiveTime += elapsedInPhase
+	}
+
+	// blocks are produced every block interval
+	sinceLatestBlock += productiveTime % n.blockInterval
+
+	height := DefaultStartingHeight + int64(productiveTime/n.blockInterval) - int64(time.Duration(phase.SecondsBehindLive)*time.Second/n.blockInterval)
+
+	return phase, phaseIndex, kava.SyncInfo{
+		LatestBlockHeight: height,
+		LatestBlockTime:   now.Add(-sinceLatestBlock).Truncate(time.Second),
+		CatchingUp:        phase.CatchingUp,
+	}
+}
+
+// rpcResponse wraps the result of a json rpc request
+type rpcResponse struct {
+	Result interface{} `json:"result"`
+}
+
+// consensusState wraps the raw consensus state
+// returned by the consensus state endpoint
+type consensusState struct {
+	RoundState struct {
+		HeightRoundStep string    `json:"height/round/step"`
+		StartTime       time.Time `json:"start_time"`
+	} `json:"round_state"`
+}
+
+// abciInfo wraps the raw application state
+// returned by the abci info endpoint
+type abciInfo struct {
+	Response abciInfoResponse `json:"response"`
+}
+
+// abciInfoResponse wraps the raw state of the application
+type abciInfoResponse struct {
+	Data            string `json:"data"`
+	Version         string `json:"version"`
+	LastBlockHeight string `json:"last_block_height"`
+}
+
+// validatorAddress returns the address of the nth validator
+func validatorAddress(n int) string {
+	return fmt.Sprintf("%040X", n+1)
+}
+
+// validator wraps a raw validator returned by the validators endpoint
+type validator struct {
+	Address     string `json:"address"`
+	VotingPower string `json:"voting_power"`
+}
+
+// validatorSet wraps the raw validator set
+// returned by the validators endpoint
+type validatorSet struct {
+	Validators []validator `json:"validators"`
+	Count      string      `json:"count"`
+	Total      string      `json:"total"`
+}
+
+// commit wraps the raw commit returned by the commit endpoint
+type commit struct {
+	SignedHeader struct {
+		Commit struct {
+			Height     string                 `json:"height"`
+			Signatures []kava.CommitSignature `json:"signatures"`
+		} `json:"commit"`
+	} `json:"signed_header"`
+}
+
+// mempoolState wraps the raw mempool state returned
+// by the number of unconfirmed transactions endpoint
+type mempoolState struct {
+	Total      string `json:"total"`
+	TotalBytes string `json:"total_bytes"`
+}
+
+// ServeHTTP responds to requests to the rpc api endpoints
+// used by the doctor according to the current phase of the scenario
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+
+	phase, phaseIndex, syncInfo := n.State(now)
+
+	n.phaseLock.Lock()
+
+	if phaseIndex != n.phaseIndex {
+		n.phaseIndex = phaseIndex
+
+		if n.onPhaseChange != nil {
+			go n.onPhaseChange(phase)
+		}
+	}
+
+	n.phaseLock.Unlock()
+
+	if phase.Down {
+		http.Error(w, fmt.Sprintf("demo node is in %s phase", phase.Name), http.StatusServiceUnavailable)
+
+		return
+	}
+
+	var result interface{}
+
+	switch r.URL.Path {
+	case kava.StatusEndpointPath:
+		result = kava.NodeState{
+			NodeInfo: kava.NodeInfo{
+				Id:      DefaultNodeId,
+				Moniker: DefaultNodeMoniker,
+				Network: DefaultChainId,
+			},
+			SyncInfo: syncInfo,
+		}
+	case kava.HealthEndpointPath:
+		result = struct{}{}
+	case kava.ABCIInfoEndpointPath:
+		if phase.ApplicationDown {
+			http.Error(w, fmt.Sprintf("demo node application is dead in %s phase", phase.Name), http.StatusInternalServerError)
+
+			return
+		}
+
+		result = abciInfo{
+			Response: abciInfoResponse{
+				Data:            DefaultApplicationName,
+				Version:         DefaultApplicationVersion,
+				LastBlockHeight: fmt.Sprint(syncInfo.LatestBlockHeight),
+			},
+		}
+	case kava.ValidatorsEndpointPath:
+		var validators validatorSet
+
+		for i := 0; i < DefaultNumberOfValidators; i++ {
+			validators.Validators = append(validators.Validators, validator{
+				Address:     validatorAddress(i),
+				VotingPower: fmt.Sprint(defaultValidatorVotingPower),
+			})
+		}
+
+		validators.Count = fmt.Sprint(DefaultNumberOfValidators)
+		validators.Total = fmt.Sprint(DefaultNumberOfValidators)
+
+		result = validators
+	case kava.CommitEndpointPath:
+		var signedHeader commit
+
+		signedHeader.SignedHeader.Commit.Height = r.URL.Query().Get("height")
+
+		for i := 0; i < DefaultNumberOfValidators; i++ {
+			signedHeader.SignedHeader.Commit.Signatures = append(signedHeader.SignedHeader.Commit.Signatures, kava.CommitSignature{
+				BlockIdFlag:      kava.BlockIdFlagCommit,
+				ValidatorAddress: validatorAddress(i),
+			})
+		}
+
+		result = signedHeader
+	case kava.NetInfoEndpointPath:
+		netInfo := kava.NetInfo{
+			Listening:     true,
+			NumberOfPeers: DefaultNumberOfPeers,
+		}
+
+		recvRate := int64(defaultPeerRecvBytesPerSecond)
+
+		if phase.Frozen {
+			recvRate = frozenPeerRecvBytesPerSecond
+		}
+
+		for i := 0; i < DefaultNumberOfPeers; i++ {
+			netInfo.Peers = append(netInfo.Peers, kava.Peer{
+				NodeInfo: kava.NodeInfo{
+					Id:      fmt.Sprintf("%040x", i+1),
+					Moniker: fmt.Sprintf("demo-peer-%d", i+1),
+				},
+				IsOutbound: i%2 == 0,
+				RemoteIP:   fmt.Sprintf("10.0.0.%d", i+1),
+				ConnectionStatus: kava.ConnectionStatus{
+					Duration:    int64(defaultPeerConnectedFor),
+					SendMonitor: kava.FlowStatus{AvgRate: defaultPeerSendBytesPerSecond},
+					RecvMonitor: kava.FlowStatus{AvgRate: recvRate},
+				},
+			})
+		}
+
+		result = netInfo
+	case kava.ConsensusStateEndpointPath:
+		var state consensusState
+
+		step := consensusStepPropose
+
+		// consensus is stuck voting on the next block while frozen
+		if phase.Frozen {
+			step = consensusStepPrevote
+		}
+
+		state.RoundState.HeightRoundStep = fmt.Sprintf("%d/0/%d", syncInfo.LatestBlockHeight+1, step)
+		state.RoundState.StartTime = syncInfo.LatestBlockTime
+
+		result = state
+	case kava.NumUnconfirmedTxsEndpointPath:
+		result = mempoolState{
+			Total:      fmt.Sprint(defaultMempoolSize),
+			TotalBytes: fmt.Sprint(defaultMempoolSize * defaultMempoolTxSizeBytes),
+		}
+	default:
+		http.NotFound(w, r)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(rpcResponse{Result: result})
+}
@@ -0,0 +1,129 @@
+package demo
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/stretchr/testify/assert"
+)
+
+var testScenario = []Phase{
+	{Name: "healthy", Duration: time.Minute},
+	{Name: "lag spike", Duration: time.Minute, SecondsBehindLive: 120, CatchingUp: true},
+	{Name: "freeze", Duration: time.Minute, Frozen: true},
+	{Name: "downtime", Duration: time.Minute, Down: true},
+}
+
+func TestNodeStateFollowsScenario(t *testing.T) {
+	startedAt := time.Now().Truncate(time.Second)
+
+	node := NewNode(NodeConfig{
+		Scenario:      testScenario,
+		BlockInterval: 6 * time.Second,
+		StartedAt:     startedAt,
+	})
+
+	phase, _, syncInfo := node.State(startedAt.Add(30 * time.Second))
+	assert.Equal(t, "healthy", phase.Name)
+	assert.Equal(t, int64(DefaultStartingHeight+5), syncInfo.LatestBlockHeight)
+	assert.False(t, syncInfo.CatchingUp)
+
+	// lagging nodes are behind by the blocks produced during the lag
+	phase, _, syncInfo = node.State(startedAt.Add(90 * time.Second))
+	assert.Equal(t, "lag spike", phase.Name)
+	assert.Equal(t, int64(DefaultStartingHeight+15-20), syncInfo.LatestBlockHeight)
+	assert.Equal(t, startedAt.Add(-30*time.Second), syncInfo.LatestBlockTime)
+	assert.True(t, syncInfo.CatchingUp)
+
+	// frozen nodes don't produce blocks
+	_, _, frozenAt := node.State(startedAt.Add(125 * time.Second))
+	phase, _, syncInfo = node.State(startedAt.Add(175 * time.Second))
+	assert.Equal(t, "freeze", phase.Name)
+	assert.Equal(t, frozenAt.LatestBlockHeight, syncInfo.LatestBlockHeight)
+	assert.Equal(t, int64(DefaultStartingHeight+20), syncInfo.LatestBlockHeight)
+	assert.Equal(t, startedAt.Add(2*time.Minute), syncInfo.LatestBlockTime)
+
+	// the scenario repeats once complete
+	phase, _, syncInfo = node.State(startedAt.Add(4*time.Minute + 30*time.Second))
+	assert.Equal(t, "healthy", phase.Name)
+	assert.Equal(t, int64(DefaultStartingHeight+30+5), syncInfo.LatestBlockHeight)
+}
+
+func TestNodeServesScenarioToClients(t *testing.T) {
+	phases := make(chan Phase, 1)
+
+	node := NewNode(NodeConfig{
+		Scenario: []Phase{{Name: "downtime", Duration: time.Minute, Down: true}},
+		OnPhaseChange: func(phase Phase) {
+			phases <- phase
+		},
+	})
+
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	client, err := kava.New(kava.ClientConfig{JSONRPCURL: server.URL, HTTPReadTimeoutSeconds: 5})
+	assert.Nil(t, err)
+
+	_, err = client.GetNodeState()
+	assert.NotNil(t, err, "nodes that are down fail requests")
+	assert.Equal(t, "downtime", (<-phases).Name)
+
+	node = NewNode(NodeConfig{
+		Scenario: []Phase{{Name: "healthy", Duration: time.Minute}},
+	})
+
+	server = httptest.NewServer(node)
+	defer server.Close()
+
+	client, err = kava.New(kava.ClientConfig{JSONRPCURL: server.URL, HTTPReadTimeoutSeconds: 5})
+	assert.Nil(t, err)
+
+	nodeState, err := client.GetNodeState()
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultNodeId, nodeState.NodeInfo.Id)
+
+	netInfo, err := client.GetNetInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(DefaultNumberOfPeers), netInfo.NumberOfPeers)
+
+	consensusState, err := client.GetConsensusState()
+	assert.Nil(t, err)
+	assert.Equal(t, nodeState.SyncInfo.LatestBlockHeight+1, consensusState.Height)
+
+	_, err = client.GetMempoolState()
+	assert.Nil(t, err)
+
+	assert.Nil(t, client.CheckHealth())
+
+	abciInfo, err := client.GetABCIInfo()
+	assert.Nil(t, err)
+	assert.Equal(t, nodeState.SyncInfo.LatestBlockHeight, abciInfo.LastBlockHeight)
+
+	validators, err := client.GetValidators(nodeState.SyncInfo.LatestBlockHeight)
+	assert.Nil(t, err)
+
+	commit, err := client.GetCommit(nodeState.SyncInfo.LatestBlockHeight)
+	assert.Nil(t, err)
+
+	online, total := kava.VotingPowerOnline(validators, commit)
+	assert.Equal(t, total, online, "every demo validator signs every block")
+
+	node = NewNode(NodeConfig{
+		Scenario: []Phase{{Name: "application crash", Duration: time.Minute, Frozen: true, ApplicationDown: true}},
+	})
+
+	server = httptest.NewServer(node)
+	defer server.Close()
+
+	client, err = kava.New(kava.ClientConfig{JSONRPCURL: server.URL, HTTPReadTimeoutSeconds: 5})
+	assert.Nil(t, err)
+
+	_, err = client.GetNodeState()
+	assert.Nil(t, err, "nodes answer status requests while the application is dead")
+
+	_, err = client.GetABCIInfo()
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,135 @@
+package endpoint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	// default number of samples retained per node
+	BenchmarkRetainedSamples = 10000
+	BenchmarkNodeId          = "06ff9460163caac703c44da1b2e3108e1ba087cd"
+)
+
+func BenchmarkAddSample(b *testing.B) {
+	endpoint := createBenchmarkEndpoint()
+	start := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		sampledAt := start.Add(time.Duration(i) * time.Second)
+
+		endpoint.AddSample(BenchmarkNodeId, NodeMetrics{
+			SyncStatusMetrics: benchmarkSyncStatusSample(BenchmarkRetainedSamples+i, sampledAt),
+		})
+
+		endpoint.AddSample(BenchmarkNodeId, NodeMetrics{
+			PeerConnectionMetrics: &metric.PeerConnectionMetrics{NodeId: BenchmarkNodeId, SampledAt: sampledAt},
+		})
+
+		endpoint.AddSample(DefaultTestKavaURL, NodeMetrics{
+			UptimeMetric: &metric.UptimeMetric{EndpointURL: DefaultTestKavaURL, Up: true, SampledAt: sampledAt},
+		})
+	}
+}
+
+func BenchmarkGetLatest(b *testing.B) {
+	endpoint := createBenchmarkEndpoint()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		endpoint.GetLatest(BenchmarkNodeId)
+	}
+}
+
+func BenchmarkCalculateHashRate(b *testing.B) {
+	endpoint := createBenchmarkEndpoint()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		endpoint.CalculateHashRate(BenchmarkNodeId)
+	}
+}
+
+func BenchmarkCalculatePerNodeUptime(b *testing.B) {
+	endpoint := createBenchmarkEndpoint()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		endpoint.CalculatePerNodeUptime()
+	}
+}
+
+func BenchmarkCalculatePeerChurnPerMinute(b *testing.B) {
+	endpoint := createBenchmarkEndpoint()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		endpoint.CalculatePeerChurnPerMinute(BenchmarkNodeId)
+	}
+}
+
+// createBenchmarkEndpoint returns an endpoint retaining the default
+// max number of sync status and peer connection samples for a node
+// and uptime samples for the endpoint, as after running for a while
+func createBenchmarkEndpoint() *Endpoint {
+	endpoint := New(EndpointConfig{
+		URL:                        DefaultTestKavaURL,
+		MetricSamplesToKeepPerNode: BenchmarkRetainedSamples,
+	})
+
+	start := time.Now().Add(-BenchmarkRetainedSamples * time.Second)
+
+	for i := 0; i < BenchmarkRetainedSamples; i++ {
+		sampledAt := start.Add(time.Duration(i) * time.Second)
+
+		endpoint.AddSample(BenchmarkNodeId, NodeMetrics{
+			SyncStatusMetrics: benchmarkSyncStatusSample(i, sampledAt),
+		})
+
+		endpoint.AddSample(BenchmarkNodeId, NodeMetrics{
+			PeerConnectionMetrics: &metric.PeerConnectionMetrics{
+				NodeId:    BenchmarkNodeId,
+				PeerIds:   []string{"peer-1", "peer-2"},
+				SampledAt: sampledAt,
+			},
+		})
+
+		endpoint.AddSample(DefaultTestKavaURL, NodeMetrics{
+			UptimeMetric: &metric.UptimeMetric{
+				EndpointURL: DefaultTestKavaURL,
+				Up:          i%10 != 0,
+				SampledAt:   sampledAt,
+			},
+		})
+	}
+
+	return endpoint
+}
+
+// benchmarkSyncStatusSample returns a sync status sample
+// for a node producing one block every second
+func benchmarkSyncStatusSample(i int, sampledAt time.Time) *metric.SyncStatusMetrics {
+	return &metric.SyncStatusMetrics{
+		NodeId:                    BenchmarkNodeId,
+		SampleLatencyMilliseconds: int64(i % 250),
+		SyncStatus: kava.SyncInfo{
+			LatestBlockHeight: int64(i),
+			LatestBlockTime:   sampledAt,
+		},
+		SampledAt: sampledAt,
+	}
+}
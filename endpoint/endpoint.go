@@ -0,0 +1,1026 @@
+// package endpoint provides an in-memory store of the metric samples
+// taken for each of the nodes backing an endpoint, safe for concurrent
+// use, and synthetic metrics (e.g. hash rate or uptime) calculated
+// from those samples
+
+package endpoint
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/store"
+)
+
+const (
+	// weight below which samples are excluded from
+	// exponentially weighted moving averages
+	MinEWMASampleWeight = 0.0001
+)
+
+var (
+	ErrNodeMetricsNotFound       = errors.New("no metrics found for requested node")
+	ErrInsufficientMetricSamples = errors.New("insufficient metric samples")
+)
+
+// NodeMetrics wrap a collection of
+// metric samples for a single node
+type NodeMetrics struct {
+	SyncStatusMetrics     *metric.SyncStatusMetrics     `json:"sync_status,omitempty"`
+	UptimeMetric          *metric.UptimeMetric          `json:"uptime,omitempty"`
+	PeerConnectionMetrics *metric.PeerConnectionMetrics `json:"peer_connections,omitempty"`
+}
+
+// Represents a collection of one or more distinct
+// (by node id) kava nodes that back a given endpoint
+// e.g. the nodes that serve traffic for rpc.data.kava.io
+// and the metric samples that have been taken by the doctor
+// for those nodes (aggregated by node id)
+// up to MetricSamplesToKeepPerNode samples of each kind
+// (sync status, uptime and peer connections) are retained
+// in memory for each node, in fixed size ring buffers
+// Endpoint is safe for concurrent use
+type Endpoint struct {
+	// guards perNodeSamples
+	lock                                       *sync.RWMutex
+	perNodeSamples                             map[string]*nodeSamples
+	URL                                        string
+	MetricSamplesToKeepPerNode                 int
+	MetricSamplesForSyntheticMetricCalculation int
+	// optional on-disk store samples are persisted
+	// to so they survive restarts of the doctor program
+	SampleStore store.Store
+	// whether to exclude sync status samples from stale
+	// (e.g. cached) responses from synthetic metric calculations
+	DiscardStaleSamples bool
+}
+
+// EndpointConfig wraps config values
+// for an Endpoint
+type EndpointConfig struct {
+	URL                                        string
+	MetricSamplesToKeepPerNode                 int
+	MetricSamplesForSyntheticMetricCalculation int
+	SampleStore                                store.Store
+	DiscardStaleSamples                        bool
+}
+
+// New returns a new endpoint for tracking
+// metrics related to all nodes behind the endpoint
+func New(config EndpointConfig) *Endpoint {
+	metricSamplesToKeepPerNode := dconfig.DefaultMetricSamplesToKeepPerNode
+	metricSamplesForSyntheticMetricCalculation := dconfig.DefaultMetricSamplesForSyntheticMetricCalculation
+
+	if config.MetricSamplesToKeepPerNode > 0 {
+		metricSamplesToKeepPerNode = config.MetricSamplesToKeepPerNode
+	}
+
+	if config.MetricSamplesForSyntheticMetricCalculation > 0 {
+		metricSamplesForSyntheticMetricCalculation = config.MetricSamplesForSyntheticMetricCalculation
+	}
+
+	return &Endpoint{
+		lock:                       &sync.RWMutex{},
+		perNodeSamples:             make(map[string]*nodeSamples),
+		URL:                        config.URL,
+		MetricSamplesToKeepPerNode: metricSamplesToKeepPerNode,
+		MetricSamplesForSyntheticMetricCalculation: metricSamplesForSyntheticMetricCalculation,
+		SampleStore:         config.SampleStore,
+		DiscardStaleSamples: config.DiscardStaleSamples,
+	}
+
+}
+
+// LoadSamples loads the most recent (up to MetricSamplesToKeepPerNode)
+// samples per node from the endpoint's sample store (if any)
+// returning error (if any)
+func (e *Endpoint) LoadSamples() error {
+	if e.SampleStore == nil {
+		return nil
+	}
+
+	storedSamples, err := e.SampleStore.LoadAll()
+
+	if err != nil {
+		return err
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for nodeId, samples := range storedSamples {
+		if len(samples) > e.MetricSamplesToKeepPerNode {
+			samples = samples[len(samples)-e.MetricSamplesToKeepPerNode:]
+		}
+
+		for _, sample := range samples {
+			e.addSample(nodeId, NodeMetrics{
+				SyncStatusMetrics:     sample.SyncStatusMetrics,
+				UptimeMetric:          sample.UptimeMetric,
+				PeerConnectionMetrics: sample.PeerConnectionMetrics,
+			})
+		}
+	}
+
+	return nil
+}
+
+// AddSample adds metrics for a node to the collection of
+// metrics for that node, pruning the oldest metrics until only
+// MetricSamplesToKeepPerNode are present, and persisting the
+// metrics to the sample store (if any), returning error (if any)
+// encountered persisting the metrics
+func (e *Endpoint) AddSample(nodeId string, newMetrics NodeMetrics) error {
+	var err error
+
+	if e.SampleStore != nil {
+		err = e.SampleStore.Add(nodeId, store.Sample{
+			SyncStatusMetrics:     newMetrics.SyncStatusMetrics,
+			UptimeMetric:          newMetrics.UptimeMetric,
+			PeerConnectionMetrics: newMetrics.PeerConnectionMetrics,
+		})
+	}
+
+	e.lock.Lock()
+	e.addSample(nodeId, newMetrics)
+	e.lock.Unlock()
+
+	return err
+}
+
+// addSample adds metrics for a node to the in memory collection of
+// metrics for that node, pruning the oldest metrics of the same kind
+// until only MetricSamplesToKeepPerNode are present
+func (e *Endpoint) addSample(nodeId string, newMetrics NodeMetrics) {
+	samples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		samples = newNodeSamples(e.MetricSamplesToKeepPerNode)
+		e.perNodeSamples[nodeId] = samples
+	}
+
+	if newMetrics.SyncStatusMetrics != nil {
+		if samples.syncStatus.len() == e.MetricSamplesToKeepPerNode {
+			// prune the oldest metric
+			samples.syncStatus.removeOldest()
+		}
+
+		samples.syncStatus.add(*newMetrics.SyncStatusMetrics)
+	}
+
+	// the oldest metric is overwritten once full
+	if newMetrics.UptimeMetric != nil {
+		samples.uptime.add(*newMetrics.UptimeMetric)
+	}
+
+	if newMetrics.PeerConnectionMetrics != nil {
+		samples.peerConnections.add(*newMetrics.PeerConnectionMetrics)
+	}
+}
+
+// NodeMetrics returns the metric samples retained for the node
+// ordered from oldest to newest, returning nil if no metrics for
+// the node exist
+func (e *Endpoint) NodeMetrics(nodeId string) []NodeMetrics {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.nodeMetrics(nodeId)
+}
+
+// nodeMetrics returns the metric samples retained for the node
+// ordered from oldest to newest, returning nil if no metrics for
+// the node exist, callers must hold the lock
+func (e *Endpoint) nodeMetrics(nodeId string) []NodeMetrics {
+	samples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return nil
+	}
+
+	// merge the samples of each kind by sample time
+	other := mergeBySampledAt(samples.all(uptimeKind), samples.all(peerConnectionsKind))
+
+	return mergeBySampledAt(samples.all(syncStatusKind), other)
+}
+
+// AllNodeMetrics returns the metric samples
+// retained for each node, keyed by node id
+func (e *Endpoint) AllNodeMetrics() map[string][]NodeMetrics {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	allNodeMetrics := make(map[string][]NodeMetrics, len(e.perNodeSamples))
+
+	for nodeId := range e.perNodeSamples {
+		allNodeMetrics[nodeId] = e.nodeMetrics(nodeId)
+	}
+
+	return allNodeMetrics
+}
+
+// NodeIds returns the ids of the nodes (or endpoint urls
+// and addresses) that samples are retained for
+func (e *Endpoint) NodeIds() []string {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	nodeIds := make([]string, 0, len(e.perNodeSamples))
+
+	for nodeId := range e.perNodeSamples {
+		nodeIds = append(nodeIds, nodeId)
+	}
+
+	sort.Strings(nodeIds)
+
+	return nodeIds
+}
+
+// GetLatest returns the latest sample of each kind of metric
+// (sync status, uptime and peer connections) retained for the node
+// if no metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+func (e *Endpoint) GetLatest(nodeId string) (NodeMetrics, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	samples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return NodeMetrics{}, ErrNodeMetricsNotFound
+	}
+
+	return NodeMetrics{
+		SyncStatusMetrics:     samples.latest(syncStatusKind).SyncStatusMetrics,
+		UptimeMetric:          samples.latest(uptimeKind).UptimeMetric,
+		PeerConnectionMetrics: samples.latest(peerConnectionsKind).PeerConnectionMetrics,
+	}, nil
+}
+
+// GetWindow returns the metric samples retained for the node that
+// were sampled at or after since, ordered from oldest to newest
+// if no metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+func (e *Endpoint) GetWindow(nodeId string, since time.Time) ([]NodeMetrics, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	if _, exists := e.perNodeSamples[nodeId]; !exists {
+		return nil, ErrNodeMetricsNotFound
+	}
+
+	nodeMetrics := e.nodeMetrics(nodeId)
+
+	// samples are ordered oldest to newest
+	for len(nodeMetrics) > 0 && sampledAt(nodeMetrics[0]).Before(since) {
+		nodeMetrics = nodeMetrics[1:]
+	}
+
+	return nodeMetrics, nil
+}
+
+// isUsableSyncStatusSample returns whether the sample contains sync
+// status metrics that should be used for synthetic metric calculations
+func (e *Endpoint) isUsableSyncStatusSample(metric *NodeMetrics) bool {
+	if metric.SyncStatusMetrics == nil {
+		return false
+	}
+
+	return !(e.DiscardStaleSamples && metric.SyncStatusMetrics.Stale)
+}
+
+// returns up to the most recent metrics of the given kind that match
+// the given predicate ordered from newest to oldest, only decoding as
+// many of the compactly stored sync status samples as needed
+func takeUpToNMostRecentMetrics(samples *nodeSamples, kind metricKind, take int, predicate func(*NodeMetrics) bool) *[]NodeMetrics {
+	var takenMetrics []NodeMetrics
+
+	samples.eachNewestFirst(kind, func(metric NodeMetrics) bool {
+		if len(takenMetrics) == take {
+			return false
+		}
+
+		if predicate(&metric) {
+			takenMetrics = append(takenMetrics, metric)
+		}
+
+		return true
+	})
+
+	return &takenMetrics
+}
+
+// CalculateHashRate attempts to calculate the average number of blocks
+// hashed per second by the specified node based on the most recent
+// (up to DefaultMetricSamplesForSyntheticMetricCalculation) samples
+// of sync metrics for the node
+// if no sync metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+// if less than two sync metrics exist for the node, `ErrInsufficientMetricSamples`
+// is returned
+func (e *Endpoint) CalculateHashRate(nodeId string) (float32, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.calculateHashRate(nodeId)
+}
+
+// calculateHashRate calculates the average number of blocks hashed
+// per second by the node, callers must hold the lock
+func (e *Endpoint) calculateHashRate(nodeId string) (float32, error) {
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return 0, ErrNodeMetricsNotFound
+	}
+
+	samples := takeUpToNMostRecentMetrics(metricSamples, syncStatusKind, e.MetricSamplesForSyntheticMetricCalculation, e.isUsableSyncStatusSample)
+
+	numSamples := len(*samples)
+
+	// need at least two samples to calculate hash rate
+	if numSamples <= 1 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	// calculate running average for hash rate
+	var sumBlockRates float32
+	startingBlockHeight := (*samples)[0].SyncStatusMetrics.SyncStatus.LatestBlockHeight
+	startingBlockTime := (*samples)[0].SyncStatusMetrics.SampledAt
+
+	// remove the first sample so it isn't double counted
+	*samples = (*samples)[1:]
+
+	for _, sample := range *samples {
+		// calculate how many blocks were hashed in between the two samples
+		newBlocks := sample.SyncStatusMetrics.SyncStatus.LatestBlockHeight - startingBlockHeight
+		secondsBetweenSamples := sample.SyncStatusMetrics.SampledAt.Sub(startingBlockTime).Seconds()
+
+		blockRate := float32(newBlocks) / float32(secondsBetweenSamples)
+		sumBlockRates += float32(blockRate)
+
+		// update iteration values for next loop
+		startingBlockHeight = sample.SyncStatusMetrics.SyncStatus.LatestBlockHeight
+		startingBlockTime = sample.SyncStatusMetrics.SampledAt
+	}
+
+	// subtract 1 for the average because we are always
+	// taking the delta between at least two samples
+	return sumBlockRates / float32(numSamples-1), nil
+}
+
+// CalculateUptime attempts to calculate the overall availability
+// for a given endpoint (which may be backed by multiple nodes)
+// if no metrics (of any kind) for the endpoint exists,
+// `ErrNodeMetricsNotFound` is returned
+// if less than one uptime metrics exist for the node,
+// `ErrInsufficientMetricSamples` is returned
+func (e *Endpoint) CalculateUptime(endpointURL string) (float32, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	return e.calculateUptime(endpointURL)
+}
+
+// calculateUptime calculates the overall availability
+// of the endpoint, callers must hold the lock
+func (e *Endpoint) calculateUptime(endpointURL string) (float32, error) {
+	metricSamples, exists := e.perNodeSamples[endpointURL]
+
+	if !exists {
+		return 0, ErrNodeMetricsNotFound
+	}
+
+	uptimeMetricMatcher := func(metric *NodeMetrics) bool {
+		var match bool
+		if metric.UptimeMetric != nil {
+			match = true
+		}
+		return match
+	}
+
+	samples := takeUpToNMostRecentMetrics(metricSamples, uptimeKind, e.MetricSamplesForSyntheticMetricCalculation, uptimeMetricMatcher)
+
+	numSamples := len(*samples)
+
+	// need at least one samples to calculate uptime
+	if numSamples == 0 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	// count the total number of times the endpoint
+	// was "up"
+	var availabilityPeriods float32
+
+	for _, sample := range *samples {
+		if sample.UptimeMetric.Up {
+			availabilityPeriods += 1
+		}
+	}
+
+	return availabilityPeriods / float32(numSamples), nil
+}
+
+// CalculateWindowedUptime attempts to calculate the availability of
+// the given endpoint over the window of time ending when the endpoint
+// was last sampled, from the uptime samples taken during the window
+// (which may be fewer than the window calls for if the window is
+// longer than the retained samples cover)
+// if no metrics (of any kind) for the endpoint exists,
+// `ErrNodeMetricsNotFound` is returned
+// if no uptime metrics exist for the endpoint,
+// `ErrInsufficientMetricSamples` is returned
+func (e *Endpoint) CalculateWindowedUptime(endpointURL string, window time.Duration) (float32, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[endpointURL]
+
+	if !exists {
+		return 0, ErrNodeMetricsNotFound
+	}
+
+	if metricSamples.uptime.len() == 0 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	windowStart := sampledAt(metricSamples.latest(uptimeKind)).Add(-window)
+
+	var numSamples, availabilityPeriods float32
+
+	metricSamples.eachNewestFirst(uptimeKind, func(sample NodeMetrics) bool {
+		if sample.UptimeMetric.SampledAt.Before(windowStart) {
+			return false
+		}
+
+		numSamples++
+
+		if sample.UptimeMetric.Up {
+			availabilityPeriods++
+		}
+
+		return true
+	})
+
+	return availabilityPeriods / numSamples, nil
+}
+
+// CalculateUptimeEWMA attempts to calculate the exponentially weighted
+// moving average of the availability of the given endpoint, with the
+// weight of each uptime sample halving for every halfLife between when
+// it and the latest sample were taken, so that short outages show up
+// regardless of how many samples are retained
+// if no metrics (of any kind) for the endpoint exists,
+// `ErrNodeMetricsNotFound` is returned
+// if no uptime metrics exist for the endpoint,
+// `ErrInsufficientMetricSamples` is returned
+func (e *Endpoint) CalculateUptimeEWMA(endpointURL string, halfLife time.Duration) (float32, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[endpointURL]
+
+	if !exists {
+		return 0, ErrNodeMetricsNotFound
+	}
+
+	if metricSamples.uptime.len() == 0 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	latest := sampledAt(metricSamples.latest(uptimeKind))
+
+	var weightSum, availableWeightSum float64
+
+	metricSamples.eachNewestFirst(uptimeKind, func(sample NodeMetrics) bool {
+		weight := math.Pow(0.5, latest.Sub(sample.UptimeMetric.SampledAt).Seconds()/halfLife.Seconds())
+
+		// older samples no longer affect the average
+		if weight < MinEWMASampleWeight {
+			return false
+		}
+
+		weightSum += weight
+
+		if sample.UptimeMetric.Up {
+			availableWeightSum += weight
+		}
+
+		return true
+	})
+
+	return float32(availableWeightSum / weightSum), nil
+}
+
+// CalculatePerNodeUptime calculates the availability of each node
+// (or endpoint) that uptime samples have been taken for, keyed by the
+// node id (or endpoint url), for comparing availability across nodes
+func (e *Endpoint) CalculatePerNodeUptime() map[string]float32 {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	perNodeUptime := make(map[string]float32)
+
+	for nodeId := range e.perNodeSamples {
+		uptime, err := e.calculateUptime(nodeId)
+
+		if err != nil {
+			// no uptime samples for the node
+			continue
+		}
+
+		perNodeUptime[nodeId] = uptime
+	}
+
+	return perNodeUptime
+}
+
+// CalculateNodeGroupRollup rolls up the latest samples of the members
+// (node ids or endpoint urls) of the group, counting members more than
+// maxSecondsBehindLive behind live or down when last sampled as unhealthy
+func (e *Endpoint) CalculateNodeGroupRollup(group dconfig.NodeGroup, maxSecondsBehindLive int64, sampledAt time.Time) metric.NodeGroupRollupMetrics {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	rollup := metric.NodeGroupRollupMetrics{
+		GroupName: group.Name,
+		Members:   len(group.Members),
+		SampledAt: sampledAt,
+	}
+
+	var uptimeSum float64
+	var uptimeMembers int
+
+	for _, member := range group.Members {
+		metricSamples, exists := e.perNodeSamples[member]
+
+		if !exists {
+			continue
+		}
+
+		rollup.ReportingMembers++
+
+		var unhealthy bool
+
+		// latest usable sync status sample
+		metricSamples.syncStatus.eachNewestFirst(func(sample metric.SyncStatusMetrics) bool {
+			if !e.isUsableSyncStatusSample(&NodeMetrics{SyncStatusMetrics: &sample}) {
+				return true
+			}
+
+			if sample.SecondsBehindLive > rollup.WorstSecondsBehindLive {
+				rollup.WorstSecondsBehindLive = sample.SecondsBehindLive
+			}
+
+			unhealthy = sample.SecondsBehindLive > maxSecondsBehindLive
+
+			return false
+		})
+
+		// latest uptime sample
+		if uptimeMetric := metricSamples.latest(uptimeKind).UptimeMetric; uptimeMetric != nil {
+			unhealthy = unhealthy || !uptimeMetric.Up
+		}
+
+		if unhealthy {
+			rollup.UnhealthyMembers++
+		}
+
+		uptime, err := e.calculateUptime(member)
+
+		if err != nil {
+			// no uptime samples for the member
+			continue
+		}
+
+		uptimeSum += float64(uptime * 100)
+		uptimeMembers++
+	}
+
+	if uptimeMembers > 0 {
+		meanUptimePercent := uptimeSum / float64(uptimeMembers)
+		rollup.MeanUptimePercent = &meanUptimePercent
+	}
+
+	return rollup
+}
+
+// CalculateStatusCheckLatencyHistogram attempts to calculate the distribution
+// of status check latencies for the specified node based on the most recent
+// (up to MetricSamplesForSyntheticMetricCalculation) samples of sync metrics
+// for the node
+// if no sync metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+// if no sync metrics exist for the node, `ErrInsufficientMetricSamples`
+// is returned
+func (e *Endpoint) CalculateStatusCheckLatencyHistogram(nodeId string) (*metric.Histogram, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return nil, ErrNodeMetricsNotFound
+	}
+
+	syncStatusMetricMatcher := func(metric *NodeMetrics) bool {
+		return metric.SyncStatusMetrics != nil
+	}
+
+	samples := takeUpToNMostRecentMetrics(metricSamples, syncStatusKind, e.MetricSamplesForSyntheticMetricCalculation, syncStatusMetricMatcher)
+
+	if len(*samples) == 0 {
+		return nil, ErrInsufficientMetricSamples
+	}
+
+	latencies := make([]float64, 0, len(*samples))
+
+	for _, sample := range *samples {
+		latencies = append(latencies, float64(sample.SyncStatusMetrics.SampleLatencyMilliseconds))
+	}
+
+	return metric.NewHistogram(latencies), nil
+}
+
+// CalculatePercentile attempts to calculate the value below which
+// percentile (between 0 and 100) percent of the values selected by the
+// provided function fall, for the most recent (up to
+// MetricSamplesForSyntheticMetricCalculation) usable samples of sync
+// metrics for the specified node, e.g. the 95th percentile of how
+// many seconds behind live the node was
+// if no sync metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+// if no usable sync metrics exist for the node, `ErrInsufficientMetricSamples`
+// is returned
+func (e *Endpoint) CalculatePercentile(nodeId string, percentile float64, value func(*metric.SyncStatusMetrics) float64) (float64, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return 0, ErrNodeMetricsNotFound
+	}
+
+	samples := takeUpToNMostRecentMetrics(metricSamples, syncStatusKind, e.MetricSamplesForSyntheticMetricCalculation, e.isUsableSyncStatusSample)
+
+	if len(*samples) == 0 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	values := make([]float64, 0, len(*samples))
+
+	for _, sample := range *samples {
+		values = append(values, value(sample.SyncStatusMetrics))
+	}
+
+	return metric.NewHistogram(values).Percentile(percentile), nil
+}
+
+// CalculateAverageBlockTime attempts to calculate the average seconds
+// between blocks produced by the chain as observed by the specified
+// node, from the time between the oldest and newest block observed in
+// the most recent (up to MetricSamplesForSyntheticMetricCalculation)
+// samples of sync metrics for the node
+// if no sync metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+// if no new blocks were observed between samples for the node,
+// `ErrInsufficientMetricSamples` is returned
+func (e *Endpoint) CalculateAverageBlockTime(nodeId string) (float64, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return 0, ErrNodeMetricsNotFound
+	}
+
+	// ordered from newest to oldest
+	samples := takeUpToNMostRecentMetrics(metricSamples, syncStatusKind, e.MetricSamplesForSyntheticMetricCalculation, e.isUsableSyncStatusSample)
+
+	if len(*samples) < 2 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	newest := (*samples)[0].SyncStatusMetrics.SyncStatus
+	oldest := (*samples)[len(*samples)-1].SyncStatusMetrics.SyncStatus
+
+	newBlocks := newest.LatestBlockHeight - oldest.LatestBlockHeight
+
+	if newBlocks <= 0 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	return newest.LatestBlockTime.Sub(oldest.LatestBlockTime).Seconds() / float64(newBlocks), nil
+}
+
+// CalculateBlockIntervalHistogram attempts to calculate the distribution
+// of seconds between blocks produced by the chain as observed by the
+// specified node based on the most recent (up to
+// MetricSamplesForSyntheticMetricCalculation) samples of sync metrics
+// for the node, where the interval between samples that span more than
+// one block is averaged across the blocks produced in between
+// if no sync metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+// if no new blocks were observed between samples for the node,
+// `ErrInsufficientMetricSamples` is returned
+func (e *Endpoint) CalculateBlockIntervalHistogram(nodeId string) (*metric.Histogram, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return nil, ErrNodeMetricsNotFound
+	}
+
+	// ordered from newest to oldest
+	samples := takeUpToNMostRecentMetrics(metricSamples, syncStatusKind, e.MetricSamplesForSyntheticMetricCalculation, e.isUsableSyncStatusSample)
+
+	var intervals []float64
+
+	for i := 1; i < len(*samples); i++ {
+		newer := (*samples)[i-1].SyncStatusMetrics.SyncStatus
+		older := (*samples)[i].SyncStatusMetrics.SyncStatus
+
+		newBlocks := newer.LatestBlockHeight - older.LatestBlockHeight
+
+		if newBlocks <= 0 {
+			continue
+		}
+
+		intervals = append(intervals, newer.LatestBlockTime.Sub(older.LatestBlockTime).Seconds()/float64(newBlocks))
+	}
+
+	if len(intervals) == 0 {
+		return nil, ErrInsufficientMetricSamples
+	}
+
+	return metric.NewHistogram(intervals), nil
+}
+
+// CalculatePeerChurnPerMinute attempts to calculate the average number of
+// peers connected to or disconnected from per minute by the specified node
+// based on the most recent (up to MetricSamplesForSyntheticMetricCalculation)
+// samples of peer connection metrics for the node
+// if no metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+// if less than two peer connection metrics exist for the node,
+// `ErrInsufficientMetricSamples` is returned
+func (e *Endpoint) CalculatePeerChurnPerMinute(nodeId string) (float64, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return 0, ErrNodeMetricsNotFound
+	}
+
+	peerConnectionMetricMatcher := func(metric *NodeMetrics) bool {
+		return metric.PeerConnectionMetrics != nil
+	}
+
+	samples := takeUpToNMostRecentMetrics(metricSamples, peerConnectionsKind, e.MetricSamplesForSyntheticMetricCalculation, peerConnectionMetricMatcher)
+
+	numSamples := len(*samples)
+
+	// need at least two samples to calculate churn
+	if numSamples <= 1 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	// samples are ordered newest to oldest
+	newest := (*samples)[0].PeerConnectionMetrics
+	oldest := (*samples)[numSamples-1].PeerConnectionMetrics
+
+	minutesBetweenSamples := newest.SampledAt.Sub(oldest.SampledAt).Minutes()
+
+	if minutesBetweenSamples <= 0 {
+		return 0, ErrInsufficientMetricSamples
+	}
+
+	// count every peer that was connected to or
+	// disconnected from in between each pair of samples
+	var peerChanges int
+
+	for i := 0; i < numSamples-1; i++ {
+		newerPeers := (*samples)[i].PeerConnectionMetrics.PeerIds
+		olderPeers := (*samples)[i+1].PeerConnectionMetrics.PeerIds
+
+		peerChanges += countPeerChanges(olderPeers, newerPeers)
+	}
+
+	return float64(peerChanges) / minutesBetweenSamples, nil
+}
+
+// countPeerChanges returns the number of peers that are
+// present in only one of the two sets of peer ids
+func countPeerChanges(before []string, after []string) int {
+	peersBefore := make(map[string]bool, len(before))
+
+	for _, peerId := range before {
+		peersBefore[peerId] = true
+	}
+
+	var changes int
+
+	for _, peerId := range after {
+		if peersBefore[peerId] {
+			delete(peersBefore, peerId)
+
+			continue
+		}
+
+		// newly connected peer
+		changes++
+	}
+
+	// peers that are no longer connected
+	changes += len(peersBefore)
+
+	return changes
+}
+
+// SyncStatusSeries returns the value selected by the provided
+// function for each sync status sample retained for the node
+// ordered from oldest to newest, for use in charting
+// if no metrics for the node exists an empty series is returned
+func (e *Endpoint) SyncStatusSeries(nodeId string, value func(*metric.SyncStatusMetrics) float64) []float64 {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return []float64{}
+	}
+
+	series := make([]float64, 0, metricSamples.syncStatus.len())
+
+	metricSamples.syncStatus.each(func(sample metric.SyncStatusMetrics) {
+		if !e.isUsableSyncStatusSample(&NodeMetrics{SyncStatusMetrics: &sample}) {
+			return
+		}
+
+		series = append(series, value(&sample))
+	})
+
+	return series
+}
+
+// SyncProgress wraps estimates of how far along
+// a node that is catching up is towards being synched to live
+type SyncProgress struct {
+	LatestBlockHeight    int64
+	EstimatedHeadHeight  int64
+	BlocksRemaining      int64
+	PercentSynced        float64
+	BlocksPerSecond      float32
+	SecondsPerBlock      float64
+	EstimatedSecondsLeft float64 // negative if the node isn't syncing faster than the chain is growing
+}
+
+// CalculateSyncProgress attempts to estimate the sync progress of the specified
+// node based on the most recent (up to MetricSamplesForSyntheticMetricCalculation)
+// samples of sync metrics for the node, using the block times of the synched
+// blocks to estimate the chain's block interval and in turn the current head
+// if no sync metrics for the node exists, `ErrNodeMetricsNotFound` is returned
+// if less than two sync metrics with increasing block heights exist for the node,
+// `ErrInsufficientMetricSamples` is returned
+func (e *Endpoint) CalculateSyncProgress(nodeId string) (SyncProgress, error) {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
+
+	metricSamples, exists := e.perNodeSamples[nodeId]
+
+	if !exists {
+		return SyncProgress{}, ErrNodeMetricsNotFound
+	}
+
+	samples := takeUpToNMostRecentMetrics(metricSamples, syncStatusKind, e.MetricSamplesForSyntheticMetricCalculation, e.isUsableSyncStatusSample)
+
+	if len(*samples) <= 1 {
+		return SyncProgress{}, ErrInsufficientMetricSamples
+	}
+
+	// samples are ordered newest to oldest
+	newest := (*samples)[0].SyncStatusMetrics
+	oldest := (*samples)[len(*samples)-1].SyncStatusMetrics
+
+	blocksSynched := newest.SyncStatus.LatestBlockHeight - oldest.SyncStatus.LatestBlockHeight
+
+	if blocksSynched <= 0 {
+		return SyncProgress{}, ErrInsufficientMetricSamples
+	}
+
+	secondsPerBlock := newest.SyncStatus.LatestBlockTime.Sub(oldest.SyncStatus.LatestBlockTime).Seconds() / float64(blocksSynched)
+
+	if secondsPerBlock <= 0 {
+		return SyncProgress{}, ErrInsufficientMetricSamples
+	}
+
+	blocksPerSecond, err := e.calculateHashRate(nodeId)
+
+	if err != nil {
+		return SyncProgress{}, err
+	}
+
+	var blocksRemaining int64
+
+	if newest.SecondsBehindLive > 0 {
+		blocksRemaining = int64(float64(newest.SecondsBehindLive) / secondsPerBlock)
+	}
+
+	estimatedHeadHeight := newest.SyncStatus.LatestBlockHeight + blocksRemaining
+
+	// the chain keeps growing while the node catches up
+	// so the node only gains on the head at the difference
+	// between it's hash rate and the chain's block rate
+	estimatedSecondsLeft := float64(-1)
+	closingRate := float64(blocksPerSecond) - 1/secondsPerBlock
+
+	if blocksRemaining == 0 {
+		estimatedSecondsLeft = 0
+	} else if closingRate > 0 {
+		estimatedSecondsLeft = float64(blocksRemaining) / closingRate
+	}
+
+	return SyncProgress{
+		LatestBlockHeight:    newest.SyncStatus.LatestBlockHeight,
+		EstimatedHeadHeight:  estimatedHeadHeight,
+		BlocksRemaining:      blocksRemaining,
+		PercentSynced:        float64(newest.SyncStatus.LatestBlockHeight) / float64(estimatedHeadHeight) * 100,
+		BlocksPerSecond:      blocksPerSecond,
+		SecondsPerBlock:      secondsPerBlock,
+		EstimatedSecondsLeft: estimatedSecondsLeft,
+	}, nil
+}
+
+// Health wraps the latest view of the health of the
+// endpoint and of each node that serves it
+type Health struct {
+	// whether the endpoint was up when last
+	// sampled, nil if its uptime hasn't been sampled
+	Up *bool
+	// nil if the endpoint's uptime hasn't been sampled
+	UptimePercent *float32
+	Nodes         []NodeHealth
+}
+
+// NodeHealth wraps the latest sync status of a node
+type NodeHealth struct {
+	NodeId                         string
+	LatestBlockHeight              int64
+	SecondsBehindLive              int64
+	CatchingUp                     bool
+	StatusCheckLatencyMilliseconds int64
+	// 0 if the node's peers haven't been sampled
+	PeerCount int
+	SampledAt time.Time
+}
+
+// Health returns the latest samples of the endpoint and of
+// each node that serves it, omitting nodes whose sync status
+// hasn't been sampled
+func (e *Endpoint) Health() Health {
+	health := Health{
+		Nodes: []NodeHealth{},
+	}
+
+	if latest, err := e.GetLatest(e.URL); err == nil && latest.UptimeMetric != nil {
+		up := latest.UptimeMetric.Up
+		health.Up = &up
+	}
+
+	if uptime, err := e.CalculateUptime(e.URL); err == nil {
+		uptimePercent := uptime * 100
+		health.UptimePercent = &uptimePercent
+	}
+
+	for _, nodeId := range e.NodeIds() {
+		latest, err := e.GetLatest(nodeId)
+
+		// uptime is sampled by endpoint url rather than by node
+		if err != nil || latest.SyncStatusMetrics == nil {
+			continue
+		}
+
+		syncStatus := latest.SyncStatusMetrics
+
+		nodeHealth := NodeHealth{
+			NodeId:                         nodeId,
+			LatestBlockHeight:              syncStatus.SyncStatus.LatestBlockHeight,
+			SecondsBehindLive:              syncStatus.SecondsBehindLive,
+			CatchingUp:                     syncStatus.SyncStatus.CatchingUp,
+			StatusCheckLatencyMilliseconds: syncStatus.SampleLatencyMilliseconds,
+			SampledAt:                      syncStatus.SampledAt,
+		}
+
+		if latest.PeerConnectionMetrics != nil {
+			nodeHealth.PeerCount = len(latest.PeerConnectionMetrics.PeerIds)
+		}
+
+		health.Nodes = append(health.Nodes, nodeHealth)
+	}
+
+	return health
+}
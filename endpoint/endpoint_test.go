@@ -0,0 +1,769 @@
+package endpoint
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kava-labs/doctor/clients/kava"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	DefaultTestKavaURL = "https://example.kava.io"
+)
+
+func TestAddSampleForNodeWithNoPreviousSamples(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId: nodeId,
+		},
+	})
+
+	nodeMetrics := endpoint.NodeMetrics(nodeId)
+
+	assert.Equal(t, len(nodeMetrics), 1, "only one sample was added")
+
+	assert.NotNil(t, nodeMetrics[0].SyncStatusMetrics)
+
+	assert.Equal(t, nodeMetrics[0].SyncStatusMetrics.NodeId, nodeId, "sample node id should match test node id")
+}
+
+func TestAddSampleForNodeWithPreviousSamplesInOrder(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+
+	sample1 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 1,
+		},
+	}
+
+	sample2 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 0,
+		},
+	}
+
+	endpoint.AddSample(nodeId, sample1)
+	endpoint.AddSample(nodeId, sample2)
+
+	nodeMetrics := endpoint.NodeMetrics(nodeId)
+
+	assert.Equal(t, len(nodeMetrics), 2, "only two samples were added")
+
+	for _, nodeMetric := range nodeMetrics {
+		assert.NotNil(t, nodeMetric.SyncStatusMetrics)
+
+	}
+
+	assert.Equal(t, nodeMetrics[0], sample1, "sample node id should match test node id")
+	assert.Equal(t, nodeMetrics[1], sample2, "sample node id should match test node id")
+}
+
+func TestAddSamplePrunesOldestSample(t *testing.T) {
+	maxSamplesToKeepPerNode := 1
+	endpoint := New(EndpointConfig{URL: DefaultTestKavaURL, MetricSamplesToKeepPerNode: maxSamplesToKeepPerNode})
+
+	nodeId := uuid.New().String()
+
+	sample1 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 1,
+		},
+	}
+
+	sample2 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 0,
+		},
+	}
+
+	endpoint.AddSample(nodeId, sample1)
+	endpoint.AddSample(nodeId, sample2)
+
+	nodeMetrics := endpoint.NodeMetrics(nodeId)
+
+	assert.Equal(t, len(nodeMetrics), maxSamplesToKeepPerNode, fmt.Sprintf("only %d should be kept per node", maxSamplesToKeepPerNode))
+
+	assert.Equal(t, nodeMetrics[0], sample2, "oldest sample should be pruned")
+}
+
+func TestAddSampleAggregatesSamplesByNodeId(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId1 := uuid.New().String()
+	nodeId2 := uuid.New().String()
+
+	sample1 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId1,
+			SecondsBehindLive: 1,
+		},
+	}
+
+	sample2 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId2,
+			SecondsBehindLive: 0,
+		},
+	}
+
+	endpoint.AddSample(nodeId1, sample1)
+	endpoint.AddSample(nodeId2, sample2)
+
+	node1Metrics := endpoint.NodeMetrics(nodeId1)
+
+	assert.Equal(t, len(node1Metrics), 1, "only one samples was added for this node")
+	assert.NotNil(t, node1Metrics[0].SyncStatusMetrics)
+	assert.Equal(t, node1Metrics[0], sample1, "sample node id should match test node id")
+
+	node2Metrics := endpoint.NodeMetrics(nodeId2)
+
+	assert.Equal(t, len(node2Metrics), 1, "only one samples was added for this node")
+	assert.NotNil(t, node2Metrics[0].SyncStatusMetrics)
+	assert.Equal(t, node2Metrics[0], sample2, "sample node id should match test node id")
+}
+
+func TestCalculateNodeHashRateReturnsErrWhenNoSamplesForNode(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+
+	_, err := endpoint.CalculateHashRate(nodeId)
+
+	assert.NotNil(t, err)
+
+	assert.EqualError(t, err, ErrNodeMetricsNotFound.Error())
+}
+
+func TestCalculateNodeHashRateReturnsErrWhenOnlyOneSamplesForNode(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId: nodeId,
+		},
+	})
+
+	_, err := endpoint.CalculateHashRate(nodeId)
+
+	assert.NotNil(t, err)
+
+	assert.EqualError(t, err, ErrInsufficientMetricSamples.Error())
+}
+
+// when multiple samples (3,,4,5)
+func TestCalculateNodeHashRateCalculatesHashRateBasedOnSamples(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+
+	now := time.Now()
+
+	sample1 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 1,
+			SampledAt:         now,
+			SyncStatus: kava.SyncInfo{
+				LatestBlockHeight: 3,
+			},
+		},
+	}
+
+	sample2 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 1,
+			SampledAt:         now.Add(1 * time.Second),
+			SyncStatus: kava.SyncInfo{
+				LatestBlockHeight: 6,
+			},
+		},
+	}
+
+	sample3 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 1,
+			SampledAt:         now.Add(2 * time.Second),
+			SyncStatus: kava.SyncInfo{
+				LatestBlockHeight: 10,
+			},
+		},
+	}
+
+	sample4 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:            nodeId,
+			SecondsBehindLive: 1,
+			SampledAt:         now.Add(3 * time.Second),
+			SyncStatus: kava.SyncInfo{
+				LatestBlockHeight: 15,
+			},
+		},
+	}
+
+	endpoint.AddSample(nodeId, sample1)
+	endpoint.AddSample(nodeId, sample2)
+	endpoint.AddSample(nodeId, sample3)
+	endpoint.AddSample(nodeId, sample4)
+
+	hashRatePerSecond, err := endpoint.CalculateHashRate(nodeId)
+
+	assert.Nil(t, err)
+
+	assert.Equal(t, float32(4.0), hashRatePerSecond)
+}
+
+func TestCalculateUptimeReturnsErrWhenNoSamplesForNode(t *testing.T) {
+	endpoint := createEndpoint()
+
+	endpointURL := uuid.New().String()
+
+	_, err := endpoint.CalculateUptime(endpointURL)
+
+	assert.NotNil(t, err)
+
+	assert.EqualError(t, err, ErrNodeMetricsNotFound.Error())
+}
+
+func TestCalculateUptimeReturnsErrWhenNoUptimeSamplesForNode(t *testing.T) {
+	endpoint := createEndpoint()
+
+	endpointURL := uuid.New().String()
+
+	sample1 := NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{},
+	}
+
+	endpoint.AddSample(endpointURL, sample1)
+
+	_, err := endpoint.CalculateUptime(endpointURL)
+
+	assert.NotNil(t, err)
+
+	assert.EqualError(t, err, ErrInsufficientMetricSamples.Error())
+}
+
+func TestCalculateUptimeCalculatesUptimeBasedOnSamples(t *testing.T) {
+	endpoint := createEndpoint()
+
+	endpointURL := uuid.New().String()
+
+	sample1 := NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{
+			Up: true,
+		},
+	}
+
+	sample2 := NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{
+			Up: false,
+		},
+	}
+
+	endpoint.AddSample(endpointURL, sample1)
+	endpoint.AddSample(endpointURL, sample2)
+
+	uptime, err := endpoint.CalculateUptime(endpointURL)
+
+	assert.Nil(t, err)
+
+	assert.Equal(t, float32(0.5), uptime)
+}
+
+func TestCalculatePerNodeUptimeSkipsNodesWithoutUptimeSamples(t *testing.T) {
+	endpoint := createEndpoint()
+
+	endpointURL := uuid.New().String()
+	nodeId := uuid.New().String()
+
+	endpoint.AddSample(endpointURL, NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{
+			Up: true,
+		},
+	})
+
+	endpoint.AddSample(endpointURL, NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{
+			Up: false,
+		},
+	})
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{},
+	})
+
+	assert.Equal(t, map[string]float32{endpointURL: 0.5}, endpoint.CalculatePerNodeUptime())
+}
+
+func TestCalculateNodeGroupRollupUsesLatestSampleOfEachMember(t *testing.T) {
+	endpoint := createEndpoint()
+
+	endpointURL := uuid.New().String()
+	laggingNodeId := uuid.New().String()
+	synchedNodeId := uuid.New().String()
+
+	now := time.Now()
+
+	addSyncStatusSample := func(nodeId string, secondsBehindLive int64, sampledAt time.Time) {
+		endpoint.AddSample(nodeId, NodeMetrics{
+			SyncStatusMetrics: &metric.SyncStatusMetrics{
+				NodeId:            nodeId,
+				SecondsBehindLive: secondsBehindLive,
+				SampledAt:         sampledAt,
+				SyncStatus: kava.SyncInfo{
+					LatestBlockHeight: 100,
+					LatestBlockTime:   sampledAt.Add(-time.Duration(secondsBehindLive) * time.Second),
+				},
+			},
+		})
+	}
+
+	// only the latest sample of a member is rolled up
+	addSyncStatusSample(laggingNodeId, 10, now)
+	addSyncStatusSample(laggingNodeId, 300, now.Add(time.Second))
+	addSyncStatusSample(synchedNodeId, 500, now)
+	addSyncStatusSample(synchedNodeId, 2, now.Add(time.Second))
+
+	endpoint.AddSample(endpointURL, NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{
+			Up: true,
+		},
+	})
+
+	endpoint.AddSample(endpointURL, NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{
+			Up: false,
+		},
+	})
+
+	group := dconfig.NodeGroup{
+		Name:    "public-api",
+		Members: []string{endpointURL, laggingNodeId, synchedNodeId, uuid.New().String()},
+	}
+
+	rollup := endpoint.CalculateNodeGroupRollup(group, 120, now)
+
+	assert.Equal(t, "public-api", rollup.GroupName)
+	assert.Equal(t, 4, rollup.Members)
+	assert.Equal(t, 3, rollup.ReportingMembers)
+	assert.Equal(t, int64(300), rollup.WorstSecondsBehindLive)
+	// the lagging node and the endpoint that was down when last sampled
+	assert.Equal(t, 2, rollup.UnhealthyMembers)
+
+	if assert.NotNil(t, rollup.MeanUptimePercent) {
+		assert.Equal(t, float64(50), *rollup.MeanUptimePercent)
+	}
+}
+
+func TestCalculateSyncProgressEstimatesRemainingBlocks(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+
+	now := time.Now()
+	// chain produces a block every 5 seconds and the node is synching
+	// 10 blocks per second while 1000 seconds behind live
+	chainTime := now.Add(-1000 * time.Second)
+
+	for i := 0; i < 3; i++ {
+		endpoint.AddSample(nodeId, NodeMetrics{
+			SyncStatusMetrics: &metric.SyncStatusMetrics{
+				NodeId:            nodeId,
+				SecondsBehindLive: 1000,
+				SampledAt:         now.Add(time.Duration(i) * time.Second),
+				SyncStatus: kava.SyncInfo{
+					LatestBlockHeight: int64(100 + i*10),
+					LatestBlockTime:   chainTime.Add(time.Duration(i*50) * time.Second),
+					CatchingUp:        true,
+				},
+			},
+		})
+	}
+
+	progress, err := endpoint.CalculateSyncProgress(nodeId)
+
+	assert.Nil(t, err)
+
+	assert.Equal(t, int64(120), progress.LatestBlockHeight)
+	assert.Equal(t, float64(5), progress.SecondsPerBlock)
+	assert.Equal(t, int64(200), progress.BlocksRemaining)
+	assert.Equal(t, int64(320), progress.EstimatedHeadHeight)
+	assert.InDelta(t, 20.4, progress.EstimatedSecondsLeft, 0.1, "node gains on the head at 9.8 blocks per second")
+}
+
+func TestCalculatePeerChurnPerMinuteCountsConnectedAndDisconnectedPeers(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+	startTime := time.Now()
+
+	peerSamples := [][]string{
+		{"a", "b", "c"},
+		// b disconnected, d connected
+		{"a", "c", "d"},
+		// no change
+		{"a", "c", "d"},
+		// a and c disconnected, e connected
+		{"d", "e"},
+	}
+
+	for i, peerIds := range peerSamples {
+		endpoint.AddSample(nodeId, NodeMetrics{
+			PeerConnectionMetrics: &metric.PeerConnectionMetrics{
+				NodeId:    nodeId,
+				PeerIds:   peerIds,
+				SampledAt: startTime.Add(time.Duration(i) * 30 * time.Second),
+			},
+		})
+	}
+
+	peerChurnPerMinute, err := endpoint.CalculatePeerChurnPerMinute(nodeId)
+
+	assert.Nil(t, err)
+	// 5 peer changes over 1.5 minutes
+	assert.InDelta(t, 5/1.5, peerChurnPerMinute, 0.0001)
+}
+
+func TestCalculateBlockIntervalHistogramAveragesIntervalAcrossBlocks(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+	startTime := time.Now()
+
+	blocks := []struct {
+		height int64
+		time   time.Time
+	}{
+		{10, startTime},
+		// two blocks in ten seconds
+		{12, startTime.Add(10 * time.Second)},
+		// no new blocks
+		{12, startTime.Add(10 * time.Second)},
+		// one block in twenty seconds
+		{13, startTime.Add(30 * time.Second)},
+	}
+
+	for i, block := range blocks {
+		endpoint.AddSample(nodeId, NodeMetrics{
+			SyncStatusMetrics: &metric.SyncStatusMetrics{
+				NodeId: nodeId,
+				SyncStatus: kava.SyncInfo{
+					LatestBlockHeight: block.height,
+					LatestBlockTime:   block.time,
+				},
+				SampledAt: startTime.Add(time.Duration(i) * 5 * time.Second),
+			},
+		})
+	}
+
+	histogram, err := endpoint.CalculateBlockIntervalHistogram(nodeId)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, histogram.Count())
+	assert.Equal(t, float64(5), histogram.Min())
+	assert.Equal(t, float64(20), histogram.Max())
+
+	averageBlockTime, err := endpoint.CalculateAverageBlockTime(nodeId)
+
+	// three blocks in thirty seconds
+	assert.Nil(t, err)
+	assert.Equal(t, float64(10), averageBlockTime)
+}
+
+func TestCalculateNodeHashRateExcludesStaleSamplesWhenDiscarding(t *testing.T) {
+	endpoint := New(EndpointConfig{URL: DefaultTestKavaURL, DiscardStaleSamples: true})
+
+	nodeId := uuid.New().String()
+
+	now := time.Now()
+
+	for i, blockHeight := range []int64{10, 20, 5, 30} {
+		endpoint.AddSample(nodeId, NodeMetrics{
+			SyncStatusMetrics: &metric.SyncStatusMetrics{
+				NodeId:    nodeId,
+				SampledAt: now.Add(time.Duration(i) * time.Second),
+				SyncStatus: kava.SyncInfo{
+					LatestBlockHeight: blockHeight,
+				},
+				// a cached response for an earlier block
+				Stale: blockHeight == 5,
+			},
+		})
+	}
+
+	hashRatePerSecond, err := endpoint.CalculateHashRate(nodeId)
+
+	assert.Nil(t, err)
+
+	// 10 blocks in 1 second then 10 blocks in 2 seconds
+	assert.Equal(t, float32(7.5), hashRatePerSecond)
+}
+
+func TestGetLatestReturnsLatestSampleOfEachKind(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		UptimeMetric: &metric.UptimeMetric{Up: false, SampledAt: now.Add(-2 * time.Second)},
+	})
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{NodeId: nodeId, SampledAt: now.Add(-time.Second), SecondsBehindLive: 5},
+	})
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{NodeId: nodeId, SampledAt: now, SecondsBehindLive: 1},
+	})
+
+	latest, err := endpoint.GetLatest(nodeId)
+
+	assert.Nil(t, err)
+
+	assert.NotNil(t, latest.SyncStatusMetrics)
+	assert.Equal(t, int64(1), latest.SyncStatusMetrics.SecondsBehindLive)
+
+	assert.NotNil(t, latest.UptimeMetric)
+	assert.False(t, latest.UptimeMetric.Up)
+
+	assert.Nil(t, latest.PeerConnectionMetrics)
+
+	_, err = endpoint.GetLatest(uuid.New().String())
+
+	assert.EqualError(t, err, ErrNodeMetricsNotFound.Error())
+}
+
+func TestGetWindowExcludesSamplesBeforeSince(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for i := 3; i >= 0; i-- {
+		endpoint.AddSample(nodeId, NodeMetrics{
+			SyncStatusMetrics: &metric.SyncStatusMetrics{NodeId: nodeId, SampledAt: now.Add(-time.Duration(i) * time.Minute)},
+		})
+	}
+
+	window, err := endpoint.GetWindow(nodeId, now.Add(-90*time.Second))
+
+	assert.Nil(t, err)
+
+	assert.Equal(t, 2, len(window), "only samples from the last 90 seconds should be returned")
+	assert.True(t, window[0].SyncStatusMetrics.SampledAt.Equal(now.Add(-time.Minute)))
+	assert.True(t, window[1].SyncStatusMetrics.SampledAt.Equal(now))
+}
+
+func TestCalculatePercentileUsesSelectedValues(t *testing.T) {
+	endpoint := createEndpoint()
+
+	nodeId := uuid.New().String()
+
+	_, err := endpoint.CalculatePercentile(nodeId, 50, func(sample *metric.SyncStatusMetrics) float64 {
+		return float64(sample.SampleLatencyMilliseconds)
+	})
+
+	assert.EqualError(t, err, ErrNodeMetricsNotFound.Error())
+
+	for _, latency := range []int64{10, 20, 30, 40, 1000} {
+		endpoint.AddSample(nodeId, NodeMetrics{
+			SyncStatusMetrics: &metric.SyncStatusMetrics{NodeId: nodeId, SampleLatencyMilliseconds: latency},
+		})
+	}
+
+	median, err := endpoint.CalculatePercentile(nodeId, 50, func(sample *metric.SyncStatusMetrics) float64 {
+		return float64(sample.SampleLatencyMilliseconds)
+	})
+
+	assert.Nil(t, err)
+
+	assert.Equal(t, float64(30), median)
+}
+
+func TestCalculateWindowedUptimeOnlyUsesSamplesInWindow(t *testing.T) {
+	endpoint := createEndpoint()
+
+	endpointURL := uuid.New().String()
+	now := time.Now()
+
+	// down for the first hour, then up for the last five minutes
+	for minutesAgo := 60; minutesAgo >= 0; minutesAgo-- {
+		endpoint.AddSample(endpointURL, NodeMetrics{
+			UptimeMetric: &metric.UptimeMetric{
+				Up:        minutesAgo <= 4,
+				SampledAt: now.Add(-time.Duration(minutesAgo) * time.Minute),
+			},
+		})
+	}
+
+	uptime, err := endpoint.CalculateWindowedUptime(endpointURL, 5*time.Minute)
+
+	assert.Nil(t, err)
+	assert.Equal(t, float32(5)/6, uptime, "samples from 5 to 0 minutes ago are in the window")
+
+	uptime, err = endpoint.CalculateWindowedUptime(endpointURL, 24*time.Hour)
+
+	assert.Nil(t, err)
+	assert.Equal(t, float32(5)/61, uptime, "windows longer than the retained samples use every sample")
+
+	_, err = endpoint.CalculateWindowedUptime(uuid.New().String(), time.Hour)
+
+	assert.EqualError(t, err, ErrNodeMetricsNotFound.Error())
+}
+
+func TestCalculateUptimeEWMAWeightsRecentSamplesMore(t *testing.T) {
+	endpoint := createEndpoint()
+
+	endpointURL := uuid.New().String()
+	now := time.Now()
+
+	// a short outage after a long period of being up
+	for minutesAgo := 600; minutesAgo >= 0; minutesAgo-- {
+		endpoint.AddSample(endpointURL, NodeMetrics{
+			UptimeMetric: &metric.UptimeMetric{
+				Up:        minutesAgo > 2,
+				SampledAt: now.Add(-time.Duration(minutesAgo) * time.Minute),
+			},
+		})
+	}
+
+	rollingUptime, err := endpoint.CalculateWindowedUptime(endpointURL, 24*time.Hour)
+
+	assert.Nil(t, err)
+
+	ewmaUptime, err := endpoint.CalculateUptimeEWMA(endpointURL, 5*time.Minute)
+
+	assert.Nil(t, err)
+
+	assert.Greater(t, rollingUptime, float32(0.99), "the outage is hidden by the rolling average")
+	assert.Less(t, ewmaUptime, float32(0.9), "the outage shows up in the moving average")
+
+	_, err = endpoint.CalculateUptimeEWMA(uuid.New().String(), time.Minute)
+
+	assert.EqualError(t, err, ErrNodeMetricsNotFound.Error())
+}
+
+func TestHealthReturnsLatestSamplesOfEndpointAndNodes(t *testing.T) {
+	endpoint := createEndpoint()
+
+	assert.Equal(t, Health{Nodes: []NodeHealth{}}, endpoint.Health())
+
+	nodeId := uuid.New().String()
+	// samples are stored in utc to the millisecond
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	for _, up := range []bool{true, false} {
+		endpoint.AddSample(DefaultTestKavaURL, NodeMetrics{
+			UptimeMetric: &metric.UptimeMetric{
+				EndpointURL: DefaultTestKavaURL,
+				Up:          up,
+				SampledAt:   now,
+			},
+		})
+	}
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:                    nodeId,
+			SampledAt:                 now,
+			SampleLatencyMilliseconds: 42,
+			SecondsBehindLive:         3,
+			SyncStatus: kava.SyncInfo{
+				LatestBlockHeight: 100,
+				CatchingUp:        true,
+			},
+		},
+	})
+
+	endpoint.AddSample(nodeId, NodeMetrics{
+		PeerConnectionMetrics: &metric.PeerConnectionMetrics{
+			NodeId:  nodeId,
+			PeerIds: []string{"peer-1", "peer-2"},
+		},
+	})
+
+	health := endpoint.Health()
+
+	if assert.NotNil(t, health.Up) {
+		assert.False(t, *health.Up)
+	}
+
+	if assert.NotNil(t, health.UptimePercent) {
+		assert.Equal(t, float32(50), *health.UptimePercent)
+	}
+
+	assert.Equal(t, []NodeHealth{{
+		NodeId:                         nodeId,
+		LatestBlockHeight:              100,
+		SecondsBehindLive:              3,
+		CatchingUp:                     true,
+		StatusCheckLatencyMilliseconds: 42,
+		PeerCount:                      2,
+		SampledAt:                      now,
+	}}, health.Nodes)
+}
+
+func TestEndpointIsSafeForConcurrentUse(t *testing.T) {
+	endpoint := New(EndpointConfig{URL: DefaultTestKavaURL, MetricSamplesToKeepPerNode: 100})
+
+	nodeId := uuid.New().String()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 500; j++ {
+				endpoint.AddSample(nodeId, NodeMetrics{
+					SyncStatusMetrics: &metric.SyncStatusMetrics{NodeId: nodeId, SampledAt: time.Now()},
+				})
+
+				endpoint.AddSample(DefaultTestKavaURL, NodeMetrics{
+					UptimeMetric: &metric.UptimeMetric{Up: true, SampledAt: time.Now()},
+				})
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 500; j++ {
+				endpoint.CalculateHashRate(nodeId)
+				endpoint.CalculatePerNodeUptime()
+				endpoint.GetLatest(nodeId)
+				endpoint.AllNodeMetrics()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 100, len(endpoint.NodeMetrics(nodeId)), "only the most recent samples should be retained")
+}
+
+func createEndpoint() *Endpoint {
+	return New(EndpointConfig{URL: DefaultTestKavaURL})
+}
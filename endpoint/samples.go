@@ -0,0 +1,605 @@
+// samples.go contains types for compactly storing the metric
+// samples retained in memory for each node, as tens of thousands
+// of samples may be retained for each of many nodes
+
+package endpoint
+
+import (
+	"math"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	// max number of sync status samples stored in a single chunk
+	syncStatusSamplesPerChunk = 256
+	// offset used to represent a zero (unset) time
+	zeroTimeOffset = math.MinInt32
+)
+
+// flags packed into a single byte per sync status sample
+const (
+	catchingUpFlag uint8 = 1 << iota
+	staleFlag
+	hasBlocksBehindReferenceFlag
+	chainIdMismatchFlag
+	hasAutohealCircuitBreakerTrippedFlag
+	autohealCircuitBreakerTrippedFlag
+)
+
+// syncStatusChunk stores consecutive sync status samples for a
+// node column by column, with block heights and times stored as
+// 32 bit deltas from the first sample in the chunk instead of as a
+// slice of pointers to structs, cutting the memory used per sample
+// by roughly an order of magnitude
+// times are stored with millisecond precision and in UTC, block
+// times and sample times each relative to their own base time as a
+// node far behind live has block times too old to store as deltas
+// from the times it was sampled at
+type syncStatusChunk struct {
+	// shared by every sample in the chunk, a sample
+	// with a different value starts a new chunk
+	nodeId  string
+	chainId string
+	// value the block height deltas are relative to
+	baseBlockHeight int64
+	// columns, one entry per sample
+	blockHeightDeltas     []int32
+	blockTimes            timeColumn
+	sampledAts            timeColumn
+	latenciesMilliseconds []uint32
+	secondsBehindLive     []int32
+	blocksBehindReference []int32
+	flags                 []uint8
+	// sync phase of each sample as 1 + its index in
+	// syncPhaseNames, or 0 if the sample has no sync phase
+	syncPhases     []uint8
+	syncPhaseNames []string
+}
+
+// len returns the number of samples stored in the chunk
+func (c *syncStatusChunk) len() int {
+	return len(c.flags)
+}
+
+// add attempts to add the sample to the chunk, returning false if
+// the chunk is full or the sample can't be represented relative to
+// the chunk's base values, in which case a new chunk should be used
+func (c *syncStatusChunk) add(sample metric.SyncStatusMetrics) bool {
+	if c.len() == syncStatusSamplesPerChunk {
+		return false
+	}
+
+	if c.len() == 0 {
+		c.nodeId = sample.NodeId
+		c.chainId = sample.ChainId
+		c.baseBlockHeight = sample.SyncStatus.LatestBlockHeight
+	}
+
+	if sample.NodeId != c.nodeId || sample.ChainId != c.chainId {
+		return false
+	}
+
+	blockHeightDelta, fits := toInt32(sample.SyncStatus.LatestBlockHeight - c.baseBlockHeight)
+
+	if !fits {
+		return false
+	}
+
+	blockTimeDelta, fits := c.blockTimes.delta(sample.SyncStatus.LatestBlockTime)
+
+	if !fits {
+		return false
+	}
+
+	sampledAtDelta, fits := c.sampledAts.delta(sample.SampledAt)
+
+	if !fits {
+		return false
+	}
+
+	syncPhase, fits := c.syncPhase(sample.SyncPhase)
+
+	if !fits {
+		return false
+	}
+
+	var flags uint8
+	var blocksBehindReference int32
+
+	if sample.SyncStatus.CatchingUp {
+		flags |= catchingUpFlag
+	}
+
+	if sample.Stale {
+		flags |= staleFlag
+	}
+
+	if sample.BlocksBehindReference != nil {
+		flags |= hasBlocksBehindReferenceFlag
+		blocksBehindReference = clampToInt32(*sample.BlocksBehindReference)
+	}
+
+	if sample.ChainIdMismatch {
+		flags |= chainIdMismatchFlag
+	}
+
+	if sample.AutohealCircuitBreakerTripped != nil {
+		flags |= hasAutohealCircuitBreakerTrippedFlag
+
+		if *sample.AutohealCircuitBreakerTripped {
+			flags |= autohealCircuitBreakerTrippedFlag
+		}
+	}
+
+	c.blockHeightDeltas = append(c.blockHeightDeltas, blockHeightDelta)
+	c.blockTimes.add(sample.SyncStatus.LatestBlockTime, blockTimeDelta)
+	c.sampledAts.add(sample.SampledAt, sampledAtDelta)
+	c.latenciesMilliseconds = append(c.latenciesMilliseconds, clampToUint32(sample.SampleLatencyMilliseconds))
+	c.secondsBehindLive = append(c.secondsBehindLive, clampToInt32(sample.SecondsBehindLive))
+	c.blocksBehindReference = append(c.blocksBehindReference, blocksBehindReference)
+	c.flags = append(c.flags, flags)
+	c.syncPhases = append(c.syncPhases, syncPhase)
+
+	return true
+}
+
+// syncPhase returns the byte the sync phase is stored as and
+// whether the phase fits in the chunk, adding it to the chunk's
+// phases if it's the first sample in the chunk with the phase
+func (c *syncStatusChunk) syncPhase(phase string) (uint8, bool) {
+	if phase == "" {
+		return 0, true
+	}
+
+	for i, name := range c.syncPhaseNames {
+		if name == phase {
+			return uint8(i + 1), true
+		}
+	}
+
+	if len(c.syncPhaseNames) == math.MaxUint8 {
+		return 0, false
+	}
+
+	c.syncPhaseNames = append(c.syncPhaseNames, phase)
+
+	return uint8(len(c.syncPhaseNames)), true
+}
+
+// timeColumn stores times as millisecond deltas from
+// the first non zero time stored in the column
+type timeColumn struct {
+	// unix milliseconds, only valid once hasBase is set
+	base    int64
+	hasBase bool
+	deltas  []int32
+}
+
+// delta returns the delta of the time from the column's
+// base time and whether the delta fits in the column
+func (tc *timeColumn) delta(t time.Time) (int32, bool) {
+	if t.IsZero() {
+		return zeroTimeOffset, true
+	}
+
+	// the time becomes the base time once added
+	if !tc.hasBase {
+		return 0, true
+	}
+
+	delta, fits := toInt32(t.UnixMilli() - tc.base)
+
+	// the zero time offset is reserved
+	return delta, fits && delta != zeroTimeOffset
+}
+
+// add adds the time with its delta as returned by delta
+func (tc *timeColumn) add(t time.Time, delta int32) {
+	if !tc.hasBase && !t.IsZero() {
+		tc.base = t.UnixMilli()
+		tc.hasBase = true
+	}
+
+	tc.deltas = append(tc.deltas, delta)
+}
+
+// at returns the time stored at index i of the column
+func (tc *timeColumn) at(i int) time.Time {
+	delta := tc.deltas[i]
+
+	if delta == zeroTimeOffset {
+		return time.Time{}
+	}
+
+	return time.UnixMilli(tc.base + int64(delta)).UTC()
+}
+
+// at returns the sample stored at index i of the chunk
+func (c *syncStatusChunk) at(i int) metric.SyncStatusMetrics {
+	flags := c.flags[i]
+
+	sample := metric.SyncStatusMetrics{
+		NodeId:                    c.nodeId,
+		SampleLatencyMilliseconds: int64(c.latenciesMilliseconds[i]),
+		SyncStatus: kava.SyncInfo{
+			LatestBlockHeight: c.baseBlockHeight + int64(c.blockHeightDeltas[i]),
+			LatestBlockTime:   c.blockTimes.at(i),
+			CatchingUp:        flags&catchingUpFlag != 0,
+		},
+		SecondsBehindLive: int64(c.secondsBehindLive[i]),
+		SampledAt:         c.sampledAts.at(i),
+		Stale:             flags&staleFlag != 0,
+		ChainId:           c.chainId,
+		ChainIdMismatch:   flags&chainIdMismatchFlag != 0,
+	}
+
+	if flags&hasBlocksBehindReferenceFlag != 0 {
+		blocksBehindReference := int64(c.blocksBehindReference[i])
+		sample.BlocksBehindReference = &blocksBehindReference
+	}
+
+	if flags&hasAutohealCircuitBreakerTrippedFlag != 0 {
+		tripped := flags&autohealCircuitBreakerTrippedFlag != 0
+		sample.AutohealCircuitBreakerTripped = &tripped
+	}
+
+	if syncPhase := c.syncPhases[i]; syncPhase != 0 {
+		sample.SyncPhase = c.syncPhaseNames[syncPhase-1]
+	}
+
+	return sample
+}
+
+// syncStatusSamples stores the sync status samples for a
+// node in order from oldest to newest as a list of chunks
+type syncStatusSamples struct {
+	chunks []*syncStatusChunk
+	// index of the oldest retained sample in the first chunk
+	start int
+	count int
+}
+
+// len returns the number of samples stored
+func (s *syncStatusSamples) len() int {
+	return s.count
+}
+
+// add adds the sample as the newest sample
+func (s *syncStatusSamples) add(sample metric.SyncStatusMetrics) {
+	if len(s.chunks) == 0 || !s.chunks[len(s.chunks)-1].add(sample) {
+		chunk := &syncStatusChunk{}
+		chunk.add(sample)
+
+		s.chunks = append(s.chunks, chunk)
+	}
+
+	s.count++
+}
+
+// removeOldest removes the oldest sample (if any)
+func (s *syncStatusSamples) removeOldest() {
+	if s.count == 0 {
+		return
+	}
+
+	s.start++
+	s.count--
+
+	if s.start == s.chunks[0].len() {
+		s.chunks[0] = nil
+		s.chunks = s.chunks[1:]
+		s.start = 0
+	}
+}
+
+// eachNewestFirst calls visit for each sample from newest
+// to oldest until visit returns false
+func (s *syncStatusSamples) eachNewestFirst(visit func(sample metric.SyncStatusMetrics) bool) {
+	for i := len(s.chunks) - 1; i >= 0; i-- {
+		chunk := s.chunks[i]
+
+		start := 0
+
+		if i == 0 {
+			start = s.start
+		}
+
+		for j := chunk.len() - 1; j >= start; j-- {
+			if !visit(chunk.at(j)) {
+				return
+			}
+		}
+	}
+}
+
+// each calls visit for each sample from oldest to newest
+func (s *syncStatusSamples) each(visit func(sample metric.SyncStatusMetrics)) {
+	for i, chunk := range s.chunks {
+		start := 0
+
+		if i == 0 {
+			start = s.start
+		}
+
+		for j := start; j < chunk.len(); j++ {
+			visit(chunk.at(j))
+		}
+	}
+}
+
+// metricKind identifies the kind of metric held by a sample
+type metricKind int
+
+const (
+	syncStatusKind metricKind = iota
+	uptimeKind
+	peerConnectionsKind
+)
+
+// ringIndex tracks the positions of the samples stored in a fixed
+// size ring buffer, allowing samples of each kind of metric to be
+// stored in a slice of their own type, with the oldest sample
+// overwritten in place once the buffer is full instead of
+// reslicing and reallocating the slice
+type ringIndex struct {
+	capacity int
+	// position of the oldest sample
+	start int
+	count int
+}
+
+// len returns the number of samples stored
+func (r *ringIndex) len() int {
+	return r.count
+}
+
+// push returns the position to store the newest sample at, which
+// is the position of the oldest sample once the buffer is full
+func (r *ringIndex) push() int {
+	if r.count < r.capacity {
+		r.count++
+
+		return (r.start + r.count - 1) % r.capacity
+	}
+
+	position := r.start
+	r.start = (r.start + 1) % r.capacity
+
+	return position
+}
+
+// position returns the position of the ith oldest sample
+func (r *ringIndex) position(i int) int {
+	return (r.start + i) % r.capacity
+}
+
+// uptimeSamples stores up to a fixed number of uptime
+// samples for an endpoint in a ring buffer
+type uptimeSamples struct {
+	index   ringIndex
+	samples []metric.UptimeMetric
+}
+
+// len returns the number of samples stored
+func (u *uptimeSamples) len() int {
+	return u.index.len()
+}
+
+// add adds the sample as the newest sample,
+// removing the oldest sample if full
+func (u *uptimeSamples) add(sample metric.UptimeMetric) {
+	position := u.index.push()
+
+	// the buffer is only grown up to its capacity as samples are added
+	if position == len(u.samples) {
+		u.samples = append(u.samples, sample)
+
+		return
+	}
+
+	u.samples[position] = sample
+}
+
+// at returns the ith oldest sample
+func (u *uptimeSamples) at(i int) *metric.UptimeMetric {
+	sample := u.samples[u.index.position(i)]
+
+	return &sample
+}
+
+// peerConnectionSamples stores up to a fixed number of
+// peer connection samples for a node in a ring buffer
+type peerConnectionSamples struct {
+	index   ringIndex
+	samples []metric.PeerConnectionMetrics
+}
+
+// len returns the number of samples stored
+func (p *peerConnectionSamples) len() int {
+	return p.index.len()
+}
+
+// add adds the sample as the newest sample,
+// removing the oldest sample if full
+func (p *peerConnectionSamples) add(sample metric.PeerConnectionMetrics) {
+	position := p.index.push()
+
+	// the buffer is only grown up to its capacity as samples are added
+	if position == len(p.samples) {
+		p.samples = append(p.samples, sample)
+
+		return
+	}
+
+	p.samples[position] = sample
+}
+
+// at returns the ith oldest sample
+func (p *peerConnectionSamples) at(i int) *metric.PeerConnectionMetrics {
+	sample := p.samples[p.index.position(i)]
+
+	return &sample
+}
+
+// nodeSamples stores the samples retained for a node (or
+// endpoint for uptime samples) separately for each kind of metric,
+// so calculations over one kind don't have to scan past samples of
+// the others, with sync status samples, which are by far the most
+// numerous, stored in their compact form
+type nodeSamples struct {
+	syncStatus      syncStatusSamples
+	uptime          uptimeSamples
+	peerConnections peerConnectionSamples
+}
+
+// newNodeSamples returns storage for up to
+// capacity samples of each kind of metric
+func newNodeSamples(capacity int) *nodeSamples {
+	return &nodeSamples{
+		uptime:          uptimeSamples{index: ringIndex{capacity: capacity}},
+		peerConnections: peerConnectionSamples{index: ringIndex{capacity: capacity}},
+	}
+}
+
+// len returns the number of samples of the kind stored
+func (n *nodeSamples) len(kind metricKind) int {
+	switch kind {
+	case uptimeKind:
+		return n.uptime.len()
+	case peerConnectionsKind:
+		return n.peerConnections.len()
+	}
+
+	return n.syncStatus.len()
+}
+
+// eachNewestFirst calls visit for each sample of the kind
+// from newest to oldest until visit returns false
+func (n *nodeSamples) eachNewestFirst(kind metricKind, visit func(sample NodeMetrics) bool) {
+	switch kind {
+	case syncStatusKind:
+		n.syncStatus.eachNewestFirst(func(sample metric.SyncStatusMetrics) bool {
+			return visit(NodeMetrics{SyncStatusMetrics: &sample})
+		})
+	case uptimeKind:
+		for i := n.uptime.len() - 1; i >= 0; i-- {
+			if !visit(NodeMetrics{UptimeMetric: n.uptime.at(i)}) {
+				return
+			}
+		}
+	case peerConnectionsKind:
+		for i := n.peerConnections.len() - 1; i >= 0; i-- {
+			if !visit(NodeMetrics{PeerConnectionMetrics: n.peerConnections.at(i)}) {
+				return
+			}
+		}
+	}
+}
+
+// latest returns the newest sample of the kind, or
+// a sample without metrics if none are stored
+func (n *nodeSamples) latest(kind metricKind) NodeMetrics {
+	var latest NodeMetrics
+
+	n.eachNewestFirst(kind, func(sample NodeMetrics) bool {
+		latest = sample
+
+		return false
+	})
+
+	return latest
+}
+
+// all returns the samples of the kind ordered from oldest to newest
+func (n *nodeSamples) all(kind metricKind) []NodeMetrics {
+	samples := make([]NodeMetrics, 0, n.len(kind))
+
+	switch kind {
+	case syncStatusKind:
+		n.syncStatus.each(func(sample metric.SyncStatusMetrics) {
+			samples = append(samples, NodeMetrics{SyncStatusMetrics: &sample})
+		})
+	case uptimeKind:
+		for i := 0; i < n.uptime.len(); i++ {
+			samples = append(samples, NodeMetrics{UptimeMetric: n.uptime.at(i)})
+		}
+	case peerConnectionsKind:
+		for i := 0; i < n.peerConnections.len(); i++ {
+			samples = append(samples, NodeMetrics{PeerConnectionMetrics: n.peerConnections.at(i)})
+		}
+	}
+
+	return samples
+}
+
+// mergeBySampledAt merges the samples (each ordered from oldest
+// to newest) into a single list ordered from oldest to newest,
+// with samples taken at the same time ordered as in a then b
+func mergeBySampledAt(a []NodeMetrics, b []NodeMetrics) []NodeMetrics {
+	merged := make([]NodeMetrics, 0, len(a)+len(b))
+
+	for len(a) > 0 && len(b) > 0 {
+		if sampledAt(b[0]).Before(sampledAt(a[0])) {
+			merged = append(merged, b[0])
+			b = b[1:]
+
+			continue
+		}
+
+		merged = append(merged, a[0])
+		a = a[1:]
+	}
+
+	merged = append(merged, a...)
+
+	return append(merged, b...)
+}
+
+// sampledAt returns when the sample was taken
+func sampledAt(sample NodeMetrics) time.Time {
+	switch {
+	case sample.SyncStatusMetrics != nil:
+		return sample.SyncStatusMetrics.SampledAt
+	case sample.UptimeMetric != nil:
+		return sample.UptimeMetric.SampledAt
+	case sample.PeerConnectionMetrics != nil:
+		return sample.PeerConnectionMetrics.SampledAt
+	}
+
+	return time.Time{}
+}
+
+// toInt32 returns the value as an int32
+// and whether it fits in an int32
+func toInt32(value int64) (int32, bool) {
+	return int32(value), value >= math.MinInt32 && value <= math.MaxInt32
+}
+
+// clampToInt32 returns the value clamped to the range of an int32
+func clampToInt32(value int64) int32 {
+	if value > math.MaxInt32 {
+		return math.MaxInt32
+	}
+
+	if value < math.MinInt32 {
+		return math.MinInt32
+	}
+
+	return int32(value)
+}
+
+// clampToUint32 returns the value clamped to the range of a uint32
+func clampToUint32(value int64) uint32 {
+	if value > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	if value < 0 {
+		return 0
+	}
+
+	return uint32(value)
+}
@@ -0,0 +1,203 @@
+package endpoint
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/syncphase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncStatusSamplesRoundTripAndPrune(t *testing.T) {
+	var samples syncStatusSamples
+	var added []metric.SyncStatusMetrics
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+	blocksBehindReference := int64(3)
+
+	for i := 0; i < 3*syncStatusSamplesPerChunk; i++ {
+		sample := metric.SyncStatusMetrics{
+			NodeId:                    "node",
+			SampleLatencyMilliseconds: int64(i % 100),
+			SyncStatus: kava.SyncInfo{
+				LatestBlockHeight: 1_000_000 + int64(i),
+				LatestBlockTime:   start.Add(time.Duration(i) * 6 * time.Second),
+				CatchingUp:        i%2 == 0,
+			},
+			SecondsBehindLive: int64(i),
+			SampledAt:         start.Add(time.Duration(i)*5*time.Second + 250*time.Millisecond),
+			Stale:             i%3 == 0,
+			ChainId:           "kava_2222-10",
+		}
+
+		if i%5 == 0 {
+			sample.BlocksBehindReference = &blocksBehindReference
+		}
+
+		// unset for stale responses
+		if i%6 != 0 {
+			sample.SyncPhase = syncphase.Phases[i%len(syncphase.Phases)]
+		}
+
+		// unset when autohealing isn't enabled
+		if i%7 != 0 {
+			tripped := i%4 == 0
+			sample.AutohealCircuitBreakerTripped = &tripped
+		}
+
+		// heights too far apart to be stored as deltas start a new chunk
+		if i == syncStatusSamplesPerChunk/2 {
+			sample.SyncStatus.LatestBlockHeight += 1 << 40
+		}
+
+		// as does the node moving to a different chain
+		if i > 2*syncStatusSamplesPerChunk {
+			sample.ChainId = "kava_2221-17000"
+			sample.ChainIdMismatch = true
+		}
+
+		samples.add(sample)
+		added = append(added, sample)
+	}
+
+	for i := 0; i < syncStatusSamplesPerChunk+1; i++ {
+		samples.removeOldest()
+	}
+
+	retained := added[syncStatusSamplesPerChunk+1:]
+
+	var decoded []metric.SyncStatusMetrics
+
+	samples.each(func(sample metric.SyncStatusMetrics) {
+		decoded = append(decoded, sample)
+	})
+
+	assert.Equal(t, len(retained), samples.len())
+	assert.Equal(t, retained, decoded)
+
+	var newest []metric.SyncStatusMetrics
+
+	samples.eachNewestFirst(func(sample metric.SyncStatusMetrics) bool {
+		newest = append(newest, sample)
+
+		return len(newest) < 2
+	})
+
+	assert.Equal(t, []metric.SyncStatusMetrics{retained[len(retained)-1], retained[len(retained)-2]}, newest)
+}
+
+// fillEveryField sets every field of value (recursing into structs
+// and pointers) to a non zero value that survives being stored in a
+// sync status chunk, failing the test for fields of unhandled types
+func fillEveryField(t *testing.T, value reflect.Value, name string) {
+	switch {
+	case value.Type() == reflect.TypeOf(time.Time{}):
+		value.Set(reflect.ValueOf(time.Date(2026, 10, 16, 10, 0, 0, int(250*time.Millisecond), time.UTC)))
+
+		return
+	case value.Kind() == reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			fillEveryField(t, value.Field(i), name+"."+value.Type().Field(i).Name)
+		}
+
+		return
+	case value.Kind() == reflect.Ptr:
+		value.Set(reflect.New(value.Type().Elem()))
+
+		fillEveryField(t, value.Elem(), name)
+
+		return
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(name)
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		value.SetInt(42)
+	default:
+		t.Fatalf("field %s of type %s isn't handled, add it to the sync status chunk and this test", name, value.Type())
+	}
+}
+
+func TestSyncStatusChunkRoundTripsEveryField(t *testing.T) {
+	var sample metric.SyncStatusMetrics
+
+	fillEveryField(t, reflect.ValueOf(&sample).Elem(), "SyncStatusMetrics")
+
+	var chunk syncStatusChunk
+
+	assert.True(t, chunk.add(sample))
+	assert.Equal(t, sample, chunk.at(0))
+}
+
+func TestSyncStatusChunkStoresBlockTimesFarBehindSampleTimes(t *testing.T) {
+	var samples syncStatusSamples
+
+	sampledAt := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	sample := metric.SyncStatusMetrics{
+		NodeId: "node",
+		SyncStatus: kava.SyncInfo{
+			LatestBlockHeight: 1_000_000,
+			// further behind than fits in a 32 bit millisecond delta
+			LatestBlockTime: sampledAt.Add(-90 * 24 * time.Hour),
+			CatchingUp:      true,
+		},
+		SampledAt: sampledAt,
+	}
+
+	samples.add(sample)
+
+	var decoded []metric.SyncStatusMetrics
+
+	samples.each(func(sample metric.SyncStatusMetrics) {
+		decoded = append(decoded, sample)
+	})
+
+	assert.Equal(t, []metric.SyncStatusMetrics{sample}, decoded)
+}
+
+func TestUptimeSamplesOverwriteOldestOnceFull(t *testing.T) {
+	samples := uptimeSamples{index: ringIndex{capacity: 3}}
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 5; i++ {
+		samples.add(metric.UptimeMetric{SampledAt: start.Add(time.Duration(i) * time.Second)})
+	}
+
+	assert.Equal(t, 3, samples.len())
+	assert.Equal(t, 3, len(samples.samples), "buffer should not grow past its capacity")
+
+	for i := 0; i < samples.len(); i++ {
+		assert.Equal(t, start.Add(time.Duration(i+2)*time.Second), samples.at(i).SampledAt)
+	}
+}
+
+func TestNodeSamplesMergesKindsBySampleTime(t *testing.T) {
+	samples := newNodeSamples(10)
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	samples.peerConnections.add(metric.PeerConnectionMetrics{SampledAt: start.Add(2 * time.Second)})
+	samples.uptime.add(metric.UptimeMetric{SampledAt: start.Add(3 * time.Second)})
+	samples.syncStatus.add(metric.SyncStatusMetrics{SampledAt: start.Add(time.Second)})
+	samples.uptime.add(metric.UptimeMetric{SampledAt: start})
+
+	merged := mergeBySampledAt(samples.all(syncStatusKind), mergeBySampledAt(samples.all(uptimeKind), samples.all(peerConnectionsKind)))
+
+	assert.Equal(t, 4, len(merged))
+
+	// samples of each kind are retained in the order they were added
+	assert.NotNil(t, merged[0].SyncStatusMetrics)
+	assert.NotNil(t, merged[1].PeerConnectionMetrics)
+	assert.NotNil(t, merged[2].UptimeMetric)
+	assert.NotNil(t, merged[3].UptimeMetric)
+
+	assert.Equal(t, start, samples.latest(uptimeKind).UptimeMetric.SampledAt)
+}
@@ -0,0 +1,172 @@
+// package event provides a bus for publishing structured events about
+// the lifecycle of the node and the doctor (e.g. a health check failing
+// or autohealing starting) that the displays, metric collectors and
+// alerters each subscribe to independently, so that a slow subscriber
+// can neither block publishers nor delay or reorder the events seen by
+// other subscribers
+package event
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// event types
+	// a check of the node that was passing started failing
+	HealthCheckFailedType = "health_check_failed"
+	// autohealing started acting on a problem with the node
+	AutohealStartedType = "autoheal_started"
+	// a check of the node that was failing started passing again
+	NodeRecoveredType = "node_recovered"
+	// the doctor's config was loaded at startup or reloaded
+	ConfigLoadedType = "config_loaded"
+	// a signal of the node (e.g. status check latency) deviated
+	// strongly from the node's own recent baseline
+	AnomalyDetectedType = "anomaly_detected"
+)
+
+var (
+	ValidTypes = []string{HealthCheckFailedType, AutohealStartedType, NodeRecoveredType, ConfigLoadedType, AnomalyDetectedType}
+)
+
+// Event is a single structured event published to the bus
+type Event struct {
+	Type        string `json:"type"`
+	NodeId      string `json:"node_id,omitempty"`
+	EndpointURL string `json:"endpoint_url,omitempty"`
+	// what the event is about, e.g. the name of the
+	// health check or the kind of incident being healed
+	Source     string    `json:"source,omitempty"`
+	Message    string    `json:"message"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// Subscription receives every event published to the
+// bus after it subscribed, in the order they were published
+type Subscription struct {
+	name   string
+	events chan Event
+	// number of events dropped, accessed atomically
+	dropped uint64
+}
+
+// Name returns the name the subscriber subscribed with
+func (s *Subscription) Name() string {
+	return s.name
+}
+
+// Events returns the channel events are received on,
+// closed once the subscriber unsubscribes
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of events dropped as the
+// subscriber wasn't keeping up with the events published
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// deliver sends the event to the subscriber without blocking,
+// dropping the oldest buffered event to make room if full
+// callers must hold the bus's subscriptions lock so events are
+// delivered in the order they were published
+func (s *Subscription) deliver(event Event) {
+	select {
+	case s.events <- event:
+		return
+	default:
+	}
+
+	select {
+	case <-s.events:
+		atomic.AddUint64(&s.dropped, 1)
+	default:
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		// the subscriber drained and the buffer refilled in between,
+		// which can't happen while the lock is held but never block
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+// Bus delivers the events published to it to every subscriber,
+// each through its own buffer so subscribers don't affect each other
+// Bus is safe for concurrent use
+type Bus struct {
+	// serializes publishing so every subscriber
+	// receives events in the same order
+	subscriptionsLock *sync.Mutex
+	subscriptions     []*Subscription
+}
+
+// NewBus returns a new bus with no subscribers
+func NewBus() *Bus {
+	return &Bus{
+		subscriptionsLock: &sync.Mutex{},
+	}
+}
+
+// Subscribe returns a subscription named for the subscriber that
+// buffers up to bufferSize (at least one) events, if the subscriber
+// falls further behind the oldest buffered event is dropped
+func (b *Bus) Subscribe(name string, bufferSize int) *Subscription {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	subscription := &Subscription{
+		name:   name,
+		events: make(chan Event, bufferSize),
+	}
+
+	b.subscriptionsLock.Lock()
+	defer b.subscriptionsLock.Unlock()
+
+	b.subscriptions = append(b.subscriptions, subscription)
+
+	return subscription
+}
+
+// Unsubscribe stops delivering events to the
+// subscription and closes its events channel
+func (b *Bus) Unsubscribe(subscription *Subscription) {
+	b.subscriptionsLock.Lock()
+	defer b.subscriptionsLock.Unlock()
+
+	for i, existing := range b.subscriptions {
+		if existing == subscription {
+			b.subscriptions = append(b.subscriptions[:i], b.subscriptions[i+1:]...)
+
+			close(subscription.events)
+
+			return
+		}
+	}
+}
+
+// Publish delivers the event to every subscriber without blocking,
+// setting when the event occurred to now if not set
+// Publishing to a nil bus is a no-op so publishers
+// don't need to check whether a bus is configured
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	b.subscriptionsLock.Lock()
+	defer b.subscriptionsLock.Unlock()
+
+	for _, subscription := range b.subscriptions {
+		subscription.deliver(event)
+	}
+}
@@ -0,0 +1,92 @@
+package event
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusDeliversEventsToEverySubscriberInOrder(t *testing.T) {
+	bus := NewBus()
+
+	display := bus.Subscribe("display", 10)
+	alerter := bus.Subscribe("alerter", 10)
+
+	for _, eventType := range ValidTypes {
+		bus.Publish(Event{Type: eventType})
+	}
+
+	for _, subscription := range []*Subscription{display, alerter} {
+		var received []string
+
+		for range ValidTypes {
+			event := <-subscription.Events()
+
+			assert.False(t, event.OccurredAt.IsZero())
+
+			received = append(received, event.Type)
+		}
+
+		assert.Equal(t, ValidTypes, received, subscription.Name())
+	}
+}
+
+func TestBusDropsOldestEventsForSlowSubscribersOnly(t *testing.T) {
+	bus := NewBus()
+
+	slow := bus.Subscribe("slow", 2)
+	fast := bus.Subscribe("fast", 10)
+
+	for _, message := range []string{"1", "2", "3", "4"} {
+		bus.Publish(Event{Type: HealthCheckFailedType, Message: message})
+	}
+
+	assert.Equal(t, uint64(2), slow.Dropped())
+	assert.Equal(t, "3", (<-slow.Events()).Message, "oldest events are dropped first")
+	assert.Equal(t, "4", (<-slow.Events()).Message)
+
+	assert.Equal(t, uint64(0), fast.Dropped())
+	assert.Len(t, fast.Events(), 4)
+}
+
+func TestBusPublishingNeverBlocks(t *testing.T) {
+	bus := NewBus()
+
+	// never read from
+	bus.Subscribe("stuck", 1)
+
+	var publishers sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		publishers.Add(1)
+
+		go func() {
+			defer publishers.Done()
+
+			for j := 0; j < 100; j++ {
+				bus.Publish(Event{Type: NodeRecoveredType})
+			}
+		}()
+	}
+
+	publishers.Wait()
+}
+
+func TestBusUnsubscribeClosesSubscription(t *testing.T) {
+	bus := NewBus()
+
+	subscription := bus.Subscribe("display", 10)
+
+	bus.Unsubscribe(subscription)
+
+	bus.Publish(Event{Type: ConfigLoadedType})
+
+	_, open := <-subscription.Events()
+
+	assert.False(t, open)
+
+	var nilBus *Bus
+
+	nilBus.Publish(Event{Type: ConfigLoadedType})
+}
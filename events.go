@@ -0,0 +1,34 @@
+// events.go contains routines for subscribers of the event bus other
+// than the display, e.g. for alerting on events as they happen
+
+package main
+
+import (
+	"context"
+
+	"github.com/kava-labs/doctor/event"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/webhook"
+)
+
+// alertEventsToWebhook posts each event received by the subscription
+// to the webhook until the context is cancelled, logging any errors
+// posting events, a slow webhook only delays this subscriber's events
+func alertEventsToWebhook(ctx context.Context, subscription *event.Subscription, client *webhook.Client, logger *logging.Logger) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case busEvent, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+
+			err := client.Post(webhook.EventPayloadType, busEvent)
+
+			if err != nil {
+				logger.Errorf("error %s posting %s event to webhook", err, busEvent.Type)
+			}
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// fleet.go contains functions for running the fleet command,
+// aggregating the reports pushed by other doctors into a fleet-wide
+// summary of each endpoint shown interactively or printed as text
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/gizak/termui/v3/widgets"
+
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/fleet"
+	"github.com/kava-labs/doctor/logging"
+)
+
+// runFleet receives reports from the doctors pushing to the fleet
+// server address until interrupted (or the user quits), showing
+// the fleet-wide summary every monitoring interval, returning 0
+// if stopped and 2 if the server failed
+func runFleet(config *dconfig.DoctorConfig) int {
+	if err := config.ValidateFleetServer(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: refusing to start fleet server\n", err)
+
+		return 2
+	}
+
+	aggregator := fleet.NewAggregator(fleet.AggregatorConfig{
+		StaleAfter:           time.Duration(config.FleetStaleSeconds) * time.Second,
+		MaxSecondsBehindLive: int64(config.AutohealSyncLatencyToleranceSeconds),
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var display *fleetGUI
+
+	if config.InteractiveMode {
+		var err error
+
+		display, err = newFleetGUI()
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s, falling back to non-interactive mode\n", err)
+		}
+	}
+
+	// rejected reports are only logged in non-interactive
+	// mode as they would be drawn over by the gui
+	var logger *logging.Logger
+
+	logMessages := make(chan logging.Entry, config.LogChannelBufferSize)
+	printed := make(chan struct{})
+
+	go func() {
+		defer close(printed)
+
+		for entry := range logMessages {
+			fmt.Fprintln(os.Stderr, entry.Format(config.LogFormat, config.TimeFormat))
+		}
+	}()
+
+	if display == nil {
+		logger = logging.New(logMessages, config.LogLevel)
+
+		logger.Infof("fleet server listening on %s", config.FleetServerAddress)
+	}
+
+	served := make(chan error, 1)
+
+	server, err := fleet.NewServer(fleet.ServerConfig{
+		Address:         config.FleetServerAddress,
+		TLSCertFilepath: config.FleetServerTLSCertFilepath,
+		TLSKeyFilepath:  config.FleetServerTLSKeyFilepath,
+		Aggregator:      aggregator,
+		AuthToken:       config.FleetAuthToken,
+		Logger:          logger,
+	})
+
+	if err != nil {
+		served <- err
+	} else {
+		go func() {
+			served <- server.Serve(ctx)
+		}()
+	}
+
+	var uiEvents <-chan ui.Event
+
+	if display != nil {
+		uiEvents = ui.PollEvents()
+		display.Render(aggregator.Summary(time.Now()), config.TimeFormat)
+	}
+
+	ticker := time.NewTicker(time.Duration(config.DefaultMonitoringIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+Watch:
+	for {
+		select {
+		case <-ctx.Done():
+			err = <-served
+
+			break Watch
+		case err = <-served:
+			break Watch
+		case event := <-uiEvents:
+			switch event.ID {
+			case "q", "<C-c>":
+				stop()
+			case "<Resize>":
+				payload := event.Payload.(ui.Resize)
+				display.Resize(payload.Width, payload.Height)
+				display.Render(aggregator.Summary(time.Now()), config.TimeFormat)
+			}
+		case <-ticker.C:
+			summary := aggregator.Summary(time.Now())
+
+			if display != nil {
+				display.Render(summary, config.TimeFormat)
+
+				continue
+			}
+
+			printFleetSummary(os.Stdout, summary, config.TimeFormat)
+		}
+	}
+
+	if display != nil {
+		ui.Close()
+	}
+
+	close(logMessages)
+	<-printed
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: fleet server failed\n", err)
+
+		return 2
+	}
+
+	return 0
+}
+
+// fleetEndpointRows returns a header row followed
+// by a row summarizing each endpoint in the fleet
+func fleetEndpointRows(summary fleet.Summary) [][]string {
+	rows := [][]string{{"ENDPOINT", "AGENTS", "DOWN", "NODES", "UNHEALTHY", "MEAN UPTIME", "MIN UPTIME", "WORST BEHIND LIVE"}}
+
+	for _, endpointSummary := range summary.Endpoints {
+		rows = append(rows, []string{
+			endpointSummary.EndpointURL,
+			fmt.Sprintf("%d", endpointSummary.Agents),
+			fmt.Sprintf("%d", endpointSummary.AgentsDown),
+			fmt.Sprintf("%d", endpointSummary.Nodes),
+			fmt.Sprintf("%d", endpointSummary.UnhealthyNodes),
+			formatFleetUptime(endpointSummary.MeanUptimePercent),
+			formatFleetUptime(endpointSummary.MinUptimePercent),
+			fmt.Sprintf("%ds", endpointSummary.WorstSecondsBehindLive),
+		})
+	}
+
+	return rows
+}
+
+// fleetAgentRows returns a header row followed by a
+// row for the latest report from each agent in the fleet
+func fleetAgentRows(summary fleet.Summary, timeFormat logging.TimeFormat) [][]string {
+	rows := [][]string{{"AGENT", "ENDPOINT", "UP", "UPTIME", "NODES", "LAST REPORTED"}}
+
+	for _, agent := range summary.Agents {
+		lastReported := timeFormat.Format(agent.LastReportedAt)
+
+		if agent.Stale {
+			lastReported += " (stale)"
+		}
+
+		rows = append(rows, []string{
+			agent.AgentId,
+			agent.EndpointURL,
+			formatFleetUp(agent.Up),
+			formatFleetUptime(agent.UptimePercent),
+			fmt.Sprintf("%d", agent.Nodes),
+			lastReported,
+		})
+	}
+
+	return rows
+}
+
+// formatFleetUp formats whether the endpoint
+// was up, or - if its uptime hasn't been sampled
+func formatFleetUp(up *bool) string {
+	if up == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%t", *up)
+}
+
+// formatFleetUptime formats the uptime percent,
+// or - if no uptime has been sampled
+func formatFleetUptime(uptimePercent *float32) string {
+	if uptimePercent == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.2f%%", *uptimePercent)
+}
+
+// printFleetSummary prints tables of the endpoints
+// and agents in the summary to output
+func printFleetSummary(output io.Writer, summary fleet.Summary, timeFormat logging.TimeFormat) {
+	fmt.Fprintf(output, "fleet summary at %s\n", timeFormat.Format(summary.GeneratedAt))
+
+	for _, rows := range [][][]string{fleetEndpointRows(summary), fleetAgentRows(summary, timeFormat)} {
+		table := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+		for _, row := range rows {
+			for i, cell := range row {
+				if i > 0 {
+					fmt.Fprint(table, "\t")
+				}
+
+				fmt.Fprint(table, cell)
+			}
+
+			fmt.Fprintln(table)
+		}
+
+		table.Flush()
+		fmt.Fprintln(output)
+	}
+}
+
+// style of the header row of the fleet gui's tables
+var fleetHeaderStyle = ui.NewStyle(ui.ColorWhite, ui.ColorClear, ui.ModifierBold)
+
+// fleetGUI shows the fleet-wide summary as tables
+// of the endpoints and agents in the fleet
+type fleetGUI struct {
+	grid      *ui.Grid
+	endpoints *widgets.Table
+	agents    *widgets.Table
+}
+
+// newFleetGUI initializes the terminal and returns a fleet gui,
+// returning an error wrapping `ErrTerminalUnavailable` if the
+// terminal can't be initialized
+func newFleetGUI() (*fleetGUI, error) {
+	if fileInfo, err := os.Stdout.Stat(); err != nil || fileInfo.Mode()&os.ModeCharDevice == 0 {
+		return nil, fmt.Errorf("%w: stdout is not a terminal", ErrTerminalUnavailable)
+	}
+
+	if err := ui.Init(); err != nil {
+		return nil, fmt.Errorf("%w: failed to initialize termui: %v", ErrTerminalUnavailable, err)
+	}
+
+	endpoints := widgets.NewTable()
+	endpoints.Title = "Fleet Endpoints (PRESS q TO QUIT)"
+	endpoints.BorderStyle.Fg = ui.ColorCyan
+	endpoints.RowSeparator = false
+
+	agents := widgets.NewTable()
+	agents.Title = "Agents"
+	agents.BorderStyle.Fg = ui.ColorMagenta
+	agents.RowSeparator = false
+
+	grid := ui.NewGrid()
+	termWidth, termHeight := ui.TerminalDimensions()
+	grid.SetRect(0, 0, termWidth, termHeight)
+
+	grid.Set(
+		ui.NewRow(1.0/2, endpoints),
+		ui.NewRow(1.0/2, agents),
+	)
+
+	return &fleetGUI{
+		grid:      grid,
+		endpoints: endpoints,
+		agents:    agents,
+	}, nil
+}
+
+// Resize resizes the gui to the terminal's new dimensions
+func (fg *fleetGUI) Resize(width int, height int) {
+	fg.grid.SetRect(0, 0, width, height)
+	ui.Clear()
+}
+
+// Render draws the summary, highlighting endpoints with
+// unhealthy nodes or agents that last saw them down and
+// agents that haven't reported recently
+func (fg *fleetGUI) Render(summary fleet.Summary, timeFormat logging.TimeFormat) {
+	fg.endpoints.Rows = fleetEndpointRows(summary)
+	fg.agents.Rows = fleetAgentRows(summary, timeFormat)
+
+	// rows shift as endpoints and agents are added
+	// so their styles are reset on every render
+	fg.endpoints.RowStyles = map[int]ui.Style{0: fleetHeaderStyle}
+	fg.agents.RowStyles = map[int]ui.Style{0: fleetHeaderStyle}
+
+	for i, endpointSummary := range summary.Endpoints {
+		if endpointSummary.UnhealthyNodes > 0 || endpointSummary.AgentsDown > 0 {
+			fg.endpoints.RowStyles[i+1] = ui.NewStyle(ui.ColorRed)
+		}
+	}
+
+	for i, agent := range summary.Agents {
+		if agent.Stale || (agent.Up != nil && !*agent.Up) {
+			fg.agents.RowStyles[i+1] = ui.NewStyle(ui.ColorYellow)
+		}
+	}
+
+	ui.Render(fg.grid)
+}
@@ -0,0 +1,180 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	DefaultPushInterval = 30 * time.Second
+	DefaultPushTimeout  = 10 * time.Second
+)
+
+// AgentConfig wraps values for configuring an Agent
+type AgentConfig struct {
+	// id the agent reports as, defaults to the hostname
+	AgentId string
+	// base url of the aggregator e.g. https://fleet.example.com:8090
+	AggregatorURL string
+	// optional token sent as a bearer token with each report
+	AuthToken string
+	// how often to push a report to the aggregator
+	PushInterval time.Duration
+	// samples to report the latest of
+	Endpoint *endpoint.Endpoint
+	// optional logger for reporting errors pushing reports
+	Logger *logging.Logger
+}
+
+// Agent periodically pushes a report of the latest
+// samples of the nodes it watches to an aggregator
+type Agent struct {
+	agentId      string
+	reportsURL   string
+	authToken    string
+	pushInterval time.Duration
+	endpoint     *endpoint.Endpoint
+	logger       *logging.Logger
+	httpClient   *http.Client
+}
+
+// NewAgent returns a new agent using the provided config,
+// returning error (if any) if the aggregator url is invalid
+// or no agent id is set and the hostname can't be determined
+func NewAgent(config AgentConfig) (*Agent, error) {
+	aggregatorURL, err := url.Parse(config.AggregatorURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid aggregator url %s: %w", config.AggregatorURL, err)
+	}
+
+	if aggregatorURL.Scheme != "http" && aggregatorURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid aggregator url %s: scheme must be http or https", config.AggregatorURL)
+	}
+
+	agentId := config.AgentId
+
+	if agentId == "" {
+		agentId, err = os.Hostname()
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s getting hostname to use as the agent id", err)
+		}
+	}
+
+	pushInterval := config.PushInterval
+
+	if pushInterval <= 0 {
+		pushInterval = DefaultPushInterval
+	}
+
+	return &Agent{
+		agentId:      agentId,
+		reportsURL:   strings.TrimSuffix(aggregatorURL.String(), "/") + ReportsPath,
+		authToken:    config.AuthToken,
+		pushInterval: pushInterval,
+		endpoint:     config.Endpoint,
+		logger:       config.Logger,
+		httpClient:   &http.Client{Timeout: DefaultPushTimeout},
+	}, nil
+}
+
+// Run pushes a report to the aggregator each push interval until
+// the context is cancelled, logging (rather than returning) errors
+// pushing reports so a temporarily unavailable aggregator doesn't
+// stop the agent
+func (a *Agent) Run(ctx context.Context) error {
+	ticker := time.NewTicker(a.pushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			err := a.Push(ctx, a.Report(time.Now()))
+
+			if err != nil && a.logger != nil {
+				a.logger.Warnf("error %s pushing fleet report to %s", err, a.reportsURL)
+			}
+		}
+	}
+}
+
+// Report returns a report of the latest samples
+// of the endpoint and each node that serves it
+func (a *Agent) Report(now time.Time) Report {
+	health := a.endpoint.Health()
+
+	report := Report{
+		AgentId:       a.agentId,
+		EndpointURL:   a.endpoint.URL,
+		Up:            health.Up,
+		UptimePercent: health.UptimePercent,
+		Nodes:         []NodeReport{},
+		SentAt:        now,
+	}
+
+	for _, nodeHealth := range health.Nodes {
+		report.Nodes = append(report.Nodes, NodeReport{
+			NodeId:                         nodeHealth.NodeId,
+			LatestBlockHeight:              nodeHealth.LatestBlockHeight,
+			SecondsBehindLive:              nodeHealth.SecondsBehindLive,
+			CatchingUp:                     nodeHealth.CatchingUp,
+			StatusCheckLatencyMilliseconds: nodeHealth.StatusCheckLatencyMilliseconds,
+			PeerCount:                      nodeHealth.PeerCount,
+			SampledAt:                      nodeHealth.SampledAt,
+		})
+	}
+
+	return report
+}
+
+// Push sends the report to the aggregator,
+// returning error (if any) if it wasn't accepted
+func (a *Agent) Push(ctx context.Context, report Report) error {
+	body, err := json.Marshal(report)
+
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, a.reportsURL, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	if a.authToken != "" {
+		request.Header.Set("Authorization", "Bearer "+a.authToken)
+	}
+
+	response, err := a.httpClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return fmt.Errorf("aggregator responded with status %d: %s", response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+
+	return nil
+}
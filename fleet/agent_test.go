@@ -0,0 +1,100 @@
+package fleet
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentReportsLatestSamples(t *testing.T) {
+	kavaEndpoint := endpoint.New(endpoint.EndpointConfig{URL: TestEndpointURL})
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	assert.Nil(t, kavaEndpoint.AddSample(TestNodeId, endpoint.NodeMetrics{
+		SyncStatusMetrics: &metric.SyncStatusMetrics{
+			NodeId:                    TestNodeId,
+			SampledAt:                 now,
+			SyncStatus:                kava.SyncInfo{LatestBlockHeight: 100, LatestBlockTime: now},
+			SecondsBehindLive:         3,
+			SampleLatencyMilliseconds: 20,
+		},
+	}))
+
+	for _, up := range []bool{false, true} {
+		assert.Nil(t, kavaEndpoint.AddSample(TestEndpointURL, endpoint.NodeMetrics{
+			UptimeMetric: &metric.UptimeMetric{EndpointURL: TestEndpointURL, SampledAt: now, Up: up},
+		}))
+	}
+
+	agent, err := NewAgent(AgentConfig{
+		AgentId:       "agent-1",
+		AggregatorURL: "http://localhost:8090",
+		Endpoint:      kavaEndpoint,
+	})
+	assert.Nil(t, err)
+
+	report := agent.Report(now)
+
+	assert.Equal(t, "agent-1", report.AgentId)
+	assert.Equal(t, TestEndpointURL, report.EndpointURL)
+	if assert.NotNil(t, report.Up) {
+		assert.True(t, *report.Up)
+	}
+
+	if assert.NotNil(t, report.UptimePercent) {
+		assert.Equal(t, float32(50), *report.UptimePercent)
+	}
+
+	assert.Equal(t, []NodeReport{{
+		NodeId:                         TestNodeId,
+		LatestBlockHeight:              100,
+		SecondsBehindLive:              3,
+		StatusCheckLatencyMilliseconds: 20,
+		SampledAt:                      now,
+	}}, report.Nodes)
+}
+
+func TestAgentPushesReportsToAggregator(t *testing.T) {
+	aggregator := NewAggregator(AggregatorConfig{})
+
+	aggregatorServer := httptest.NewServer(createServer(t, ServerConfig{Aggregator: aggregator, AuthToken: "secret"}))
+	defer aggregatorServer.Close()
+
+	agent, err := NewAgent(AgentConfig{
+		AgentId:       "agent-1",
+		AggregatorURL: aggregatorServer.URL + "/",
+		AuthToken:     "secret",
+		Endpoint:      endpoint.New(endpoint.EndpointConfig{URL: TestEndpointURL}),
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, agent.Push(context.Background(), agent.Report(time.Now())))
+
+	summary := aggregator.Summary(time.Now())
+
+	if assert.Equal(t, 1, len(summary.Agents)) {
+		assert.Equal(t, "agent-1", summary.Agents[0].AgentId)
+	}
+
+	agent, err = NewAgent(AgentConfig{
+		AgentId:       "agent-2",
+		AggregatorURL: aggregatorServer.URL,
+		Endpoint:      endpoint.New(endpoint.EndpointConfig{URL: TestEndpointURL}),
+	})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, agent.Push(context.Background(), agent.Report(time.Now())), "reports without the auth token should be rejected")
+}
+
+func TestNewAgentRejectsInvalidAggregatorURL(t *testing.T) {
+	_, err := NewAgent(AgentConfig{AgentId: "agent-1", AggregatorURL: "fleet.example.com:8090"})
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,286 @@
+// package fleet provides for a central doctor (the aggregator) receiving
+// reports of the health of the nodes watched by other doctors (agents)
+// and aggregating them into a fleet-wide summary of each endpoint,
+// so teams running many nodes can watch them all from one place
+package fleet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// path agents push reports to
+	ReportsPath = "/api/v1/fleet/reports"
+	// path the fleet-wide summary is served on
+	SummaryPath = "/api/v1/fleet"
+	// how long after an agent's last report it's
+	// considered stale if not otherwise configured
+	DefaultStaleAfter = 2 * time.Minute
+)
+
+var (
+	ErrInvalidReport = errors.New("invalid fleet report")
+)
+
+// NodeReport wraps the latest sample of
+// a node taken by the agent reporting it
+type NodeReport struct {
+	NodeId                         string    `json:"node_id"`
+	LatestBlockHeight              int64     `json:"latest_block_height"`
+	SecondsBehindLive              int64     `json:"seconds_behind_live"`
+	CatchingUp                     bool      `json:"catching_up"`
+	StatusCheckLatencyMilliseconds int64     `json:"status_check_latency_milliseconds"`
+	PeerCount                      int       `json:"peer_count"`
+	SampledAt                      time.Time `json:"sampled_at"`
+}
+
+// Report wraps the health of the endpoint watched by an agent
+// and of the nodes that serve it, as pushed to the aggregator
+type Report struct {
+	AgentId     string `json:"agent_id"`
+	EndpointURL string `json:"endpoint_url"`
+	// whether the endpoint was up when last sampled by
+	// the agent, nil if the agent hasn't sampled its uptime
+	Up *bool `json:"up,omitempty"`
+	// availability of the endpoint as observed by the
+	// agent, nil if the agent hasn't sampled its uptime
+	UptimePercent *float32     `json:"uptime_percent,omitempty"`
+	Nodes         []NodeReport `json:"nodes"`
+	SentAt        time.Time    `json:"sent_at"`
+}
+
+// Validate returns an error wrapping `ErrInvalidReport`
+// if the report is missing its agent id or endpoint url
+func (r Report) Validate() error {
+	if r.AgentId == "" {
+		return fmt.Errorf("%w: missing agent_id", ErrInvalidReport)
+	}
+
+	if r.EndpointURL == "" {
+		return fmt.Errorf("%w: missing endpoint_url", ErrInvalidReport)
+	}
+
+	for _, node := range r.Nodes {
+		if node.NodeId == "" {
+			return fmt.Errorf("%w: node missing node_id", ErrInvalidReport)
+		}
+	}
+
+	return nil
+}
+
+// AgentSummary wraps the latest report received from an agent
+type AgentSummary struct {
+	AgentId        string    `json:"agent_id"`
+	EndpointURL    string    `json:"endpoint_url"`
+	Up             *bool     `json:"up,omitempty"`
+	UptimePercent  *float32  `json:"uptime_percent,omitempty"`
+	Nodes          int       `json:"nodes"`
+	LastReportedAt time.Time `json:"last_reported_at"`
+	// whether the agent hasn't reported recently, the reports
+	// of stale agents are excluded from endpoint summaries
+	Stale bool `json:"stale"`
+}
+
+// EndpointSummary wraps the health of an endpoint
+// aggregated across the agents watching it and
+// the nodes they report serve it
+type EndpointSummary struct {
+	EndpointURL string `json:"endpoint_url"`
+	// number of agents recently reporting the endpoint
+	// and how many of them last saw it down
+	Agents     int `json:"agents"`
+	AgentsDown int `json:"agents_down"`
+	// number of distinct nodes reported as serving the endpoint
+	// and how many of them are too far behind live
+	Nodes          int `json:"nodes"`
+	UnhealthyNodes int `json:"unhealthy_nodes"`
+	// mean and minimum uptime of the endpoint across the agents
+	// watching it, nil if no agent has sampled its uptime
+	MeanUptimePercent      *float32 `json:"mean_uptime_percent,omitempty"`
+	MinUptimePercent       *float32 `json:"min_uptime_percent,omitempty"`
+	WorstSecondsBehindLive int64    `json:"worst_seconds_behind_live"`
+}
+
+// Summary wraps the fleet-wide summary of each endpoint
+// and the agents reporting them, sorted by endpoint
+// url and agent id respectively
+type Summary struct {
+	Endpoints   []EndpointSummary `json:"endpoints"`
+	Agents      []AgentSummary    `json:"agents"`
+	GeneratedAt time.Time         `json:"generated_at"`
+}
+
+// AggregatorConfig wraps values for configuring an Aggregator
+type AggregatorConfig struct {
+	// how long after its last report an agent is considered stale
+	StaleAfter time.Duration
+	// how far behind live a node can be before it's
+	// considered unhealthy, 0 considers all nodes healthy
+	MaxSecondsBehindLive int64
+}
+
+// receivedReport wraps a report with
+// the time the aggregator received it
+type receivedReport struct {
+	Report
+	receivedAt time.Time
+}
+
+// Aggregator retains the latest report received from each agent
+// and summarizes them by endpoint
+// Aggregator is safe for concurrent use
+type Aggregator struct {
+	lock                 *sync.RWMutex
+	reports              map[string]receivedReport
+	staleAfter           time.Duration
+	maxSecondsBehindLive int64
+}
+
+// NewAggregator returns a new aggregator using the provided config
+func NewAggregator(config AggregatorConfig) *Aggregator {
+	staleAfter := config.StaleAfter
+
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+
+	return &Aggregator{
+		lock:                 &sync.RWMutex{},
+		reports:              make(map[string]receivedReport),
+		staleAfter:           staleAfter,
+		maxSecondsBehindLive: config.MaxSecondsBehindLive,
+	}
+}
+
+// Receive replaces the latest report from the agent
+// with the provided report, returning error (if any)
+// if the report is invalid
+func (a *Aggregator) Receive(report Report, receivedAt time.Time) error {
+	if err := report.Validate(); err != nil {
+		return err
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.reports[report.AgentId] = receivedReport{
+		Report:     report,
+		receivedAt: receivedAt,
+	}
+
+	return nil
+}
+
+// Summary summarizes the latest report from each agent by
+// endpoint, excluding reports from agents that are stale as of now
+// nodes reported by more than one agent are counted once using
+// the most recently sampled report of the node
+func (a *Aggregator) Summary(now time.Time) Summary {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	summary := Summary{
+		Endpoints:   []EndpointSummary{},
+		Agents:      make([]AgentSummary, 0, len(a.reports)),
+		GeneratedAt: now,
+	}
+
+	endpointReports := make(map[string][]Report)
+
+	for _, received := range a.reports {
+		stale := now.Sub(received.receivedAt) > a.staleAfter
+
+		summary.Agents = append(summary.Agents, AgentSummary{
+			AgentId:        received.AgentId,
+			EndpointURL:    received.EndpointURL,
+			Up:             received.Up,
+			UptimePercent:  received.UptimePercent,
+			Nodes:          len(received.Nodes),
+			LastReportedAt: received.receivedAt,
+			Stale:          stale,
+		})
+
+		if stale {
+			continue
+		}
+
+		endpointReports[received.EndpointURL] = append(endpointReports[received.EndpointURL], received.Report)
+	}
+
+	for endpointURL, reports := range endpointReports {
+		summary.Endpoints = append(summary.Endpoints, a.summarizeEndpoint(endpointURL, reports))
+	}
+
+	sort.Slice(summary.Agents, func(i, j int) bool {
+		return summary.Agents[i].AgentId < summary.Agents[j].AgentId
+	})
+
+	sort.Slice(summary.Endpoints, func(i, j int) bool {
+		return summary.Endpoints[i].EndpointURL < summary.Endpoints[j].EndpointURL
+	})
+
+	return summary
+}
+
+// summarizeEndpoint aggregates the reports
+// of the agents watching the endpoint
+func (a *Aggregator) summarizeEndpoint(endpointURL string, reports []Report) EndpointSummary {
+	endpointSummary := EndpointSummary{
+		EndpointURL: endpointURL,
+		Agents:      len(reports),
+	}
+
+	var uptimeSum float32
+	var uptimeCount int
+
+	latestNodeReports := make(map[string]NodeReport)
+
+	for _, report := range reports {
+		if report.Up != nil && !*report.Up {
+			endpointSummary.AgentsDown++
+		}
+
+		if report.UptimePercent != nil {
+			uptime := *report.UptimePercent
+
+			uptimeSum += uptime
+			uptimeCount++
+
+			if endpointSummary.MinUptimePercent == nil || uptime < *endpointSummary.MinUptimePercent {
+				endpointSummary.MinUptimePercent = &uptime
+			}
+		}
+
+		for _, node := range report.Nodes {
+			if latest, exists := latestNodeReports[node.NodeId]; exists && latest.SampledAt.After(node.SampledAt) {
+				continue
+			}
+
+			latestNodeReports[node.NodeId] = node
+		}
+	}
+
+	if uptimeCount > 0 {
+		meanUptime := uptimeSum / float32(uptimeCount)
+		endpointSummary.MeanUptimePercent = &meanUptime
+	}
+
+	endpointSummary.Nodes = len(latestNodeReports)
+
+	for _, node := range latestNodeReports {
+		if node.SecondsBehindLive > endpointSummary.WorstSecondsBehindLive {
+			endpointSummary.WorstSecondsBehindLive = node.SecondsBehindLive
+		}
+
+		if a.maxSecondsBehindLive > 0 && node.SecondsBehindLive > a.maxSecondsBehindLive {
+			endpointSummary.UnhealthyNodes++
+		}
+	}
+
+	return endpointSummary
+}
@@ -0,0 +1,105 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	TestEndpointURL = "https://rpc.data.kava.io"
+	TestNodeId      = "06ff9460163caac703c44da1b2e3108e1ba087cd"
+)
+
+func uptimePercent(percent float32) *float32 {
+	return &percent
+}
+
+func up(up bool) *bool {
+	return &up
+}
+
+func TestReceiveRejectsInvalidReports(t *testing.T) {
+	aggregator := NewAggregator(AggregatorConfig{})
+
+	assert.ErrorIs(t, aggregator.Receive(Report{EndpointURL: TestEndpointURL}, time.Now()), ErrInvalidReport)
+	assert.ErrorIs(t, aggregator.Receive(Report{AgentId: "agent-1"}, time.Now()), ErrInvalidReport)
+	assert.ErrorIs(t, aggregator.Receive(Report{AgentId: "agent-1", EndpointURL: TestEndpointURL, Nodes: []NodeReport{{}}}, time.Now()), ErrInvalidReport)
+}
+
+func TestSummaryAggregatesUptimeAcrossAgents(t *testing.T) {
+	aggregator := NewAggregator(AggregatorConfig{MaxSecondsBehindLive: 60})
+
+	now := time.Now()
+
+	assert.Nil(t, aggregator.Receive(Report{
+		AgentId:       "us-east-1",
+		EndpointURL:   TestEndpointURL,
+		Up:            up(true),
+		UptimePercent: uptimePercent(100),
+		Nodes: []NodeReport{
+			{NodeId: TestNodeId, SecondsBehindLive: 120, SampledAt: now.Add(-time.Minute)},
+			{NodeId: "1ab5c8a1b3f2e0bc5e1e5d5b9e0f6f3d8c3e1a2b", SecondsBehindLive: 5, SampledAt: now},
+		},
+	}, now))
+
+	assert.Nil(t, aggregator.Receive(Report{
+		AgentId:       "eu-west-1",
+		EndpointURL:   TestEndpointURL,
+		Up:            up(false),
+		UptimePercent: uptimePercent(90),
+		Nodes: []NodeReport{
+			// reported more recently, so replaces the other agent's report of the node
+			{NodeId: TestNodeId, SecondsBehindLive: 10, SampledAt: now},
+		},
+	}, now))
+
+	summary := aggregator.Summary(now)
+
+	assert.Equal(t, []string{"eu-west-1", "us-east-1"}, []string{summary.Agents[0].AgentId, summary.Agents[1].AgentId})
+
+	if assert.Equal(t, 1, len(summary.Endpoints)) {
+		endpointSummary := summary.Endpoints[0]
+
+		assert.Equal(t, TestEndpointURL, endpointSummary.EndpointURL)
+		assert.Equal(t, 2, endpointSummary.Agents)
+		assert.Equal(t, 1, endpointSummary.AgentsDown)
+		assert.Equal(t, 2, endpointSummary.Nodes)
+		assert.Equal(t, 0, endpointSummary.UnhealthyNodes)
+		assert.Equal(t, int64(10), endpointSummary.WorstSecondsBehindLive)
+		assert.Equal(t, float32(95), *endpointSummary.MeanUptimePercent)
+		assert.Equal(t, float32(90), *endpointSummary.MinUptimePercent)
+	}
+}
+
+func TestSummaryExcludesStaleAgents(t *testing.T) {
+	aggregator := NewAggregator(AggregatorConfig{StaleAfter: time.Minute, MaxSecondsBehindLive: 60})
+
+	now := time.Now()
+
+	assert.Nil(t, aggregator.Receive(Report{
+		AgentId:     "stale",
+		EndpointURL: "https://archive.data.kava.io",
+		Nodes:       []NodeReport{{NodeId: TestNodeId, SecondsBehindLive: 600}},
+	}, now.Add(-2*time.Minute)))
+
+	assert.Nil(t, aggregator.Receive(Report{
+		AgentId:     "fresh",
+		EndpointURL: TestEndpointURL,
+		Up:          up(true),
+		Nodes:       []NodeReport{{NodeId: TestNodeId, SecondsBehindLive: 600}},
+	}, now))
+
+	summary := aggregator.Summary(now)
+
+	assert.Equal(t, 2, len(summary.Agents))
+	assert.False(t, summary.Agents[0].Stale)
+	assert.True(t, summary.Agents[1].Stale)
+
+	if assert.Equal(t, 1, len(summary.Endpoints)) {
+		assert.Equal(t, TestEndpointURL, summary.Endpoints[0].EndpointURL)
+		assert.Equal(t, 1, summary.Endpoints[0].UnhealthyNodes)
+		assert.Nil(t, summary.Endpoints[0].MeanUptimePercent)
+	}
+}
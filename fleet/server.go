@@ -0,0 +1,189 @@
+package fleet
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	// maximum size of a report body accepted from an agent
+	MaxReportBytes = 1 << 20
+	// how long to wait for in progress requests
+	// to finish when shutting down the server
+	ShutdownTimeout = 5 * time.Second
+)
+
+// ServerConfig wraps values for configuring a fleet server
+type ServerConfig struct {
+	// address to listen for requests on e.g. 127.0.0.1:8090
+	Address string
+	// optional pem encoded certificate and key to serve
+	// over tls with, served over plain http if empty
+	TLSCertFilepath string
+	TLSKeyFilepath  string
+	Aggregator      *Aggregator
+	// optional token agents (and readers of the summary)
+	// must send as a bearer token in the Authorization header
+	AuthToken string
+	// optional logger for reporting rejected reports
+	Logger *logging.Logger
+}
+
+// Server receives reports pushed by agents
+// and serves the fleet-wide summary of them
+type Server struct {
+	address string
+	// nil if served over plain http
+	tlsConfig  *tls.Config
+	aggregator *Aggregator
+	authToken  string
+	logger     *logging.Logger
+}
+
+// ErrorResponse wraps the reason a request failed
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// NewServer returns a new fleet server using the provided config,
+// returning error (if any) if the certificate to serve with is
+// configured but can't be loaded
+func NewServer(config ServerConfig) (*Server, error) {
+	var tlsConfig *tls.Config
+
+	if config.TLSCertFilepath != "" || config.TLSKeyFilepath != "" {
+		certificate, err := tls.LoadX509KeyPair(config.TLSCertFilepath, config.TLSKeyFilepath)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s loading fleet server certificate %s", err, config.TLSCertFilepath)
+		}
+
+		tlsConfig = &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	return &Server{
+		address:    config.Address,
+		tlsConfig:  tlsConfig,
+		aggregator: config.Aggregator,
+		authToken:  config.AuthToken,
+		logger:     config.Logger,
+	}, nil
+}
+
+// Serve serves requests (until the context is cancelled) returning
+// error (if any) listening for requests, once the context is cancelled
+// in progress requests are given a chance to finish before returning
+func (s *Server) Serve(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.address)
+
+	if err != nil {
+		return err
+	}
+
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+
+	server := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	served := make(chan error, 1)
+
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// ServeHTTP routes the request to the handler for its path
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeJSON(w, http.StatusUnauthorized, ErrorResponse{Error: "missing or invalid bearer token"})
+
+		return
+	}
+
+	switch {
+	case r.URL.Path == ReportsPath && r.Method == http.MethodPost:
+		s.receiveReport(w, r)
+	case r.URL.Path == SummaryPath && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.aggregator.Summary(time.Now()))
+	case r.URL.Path == ReportsPath || r.URL.Path == SummaryPath:
+		writeJSON(w, http.StatusMethodNotAllowed, ErrorResponse{Error: "reports must be POSTed to " + ReportsPath + " and the summary fetched with GET from " + SummaryPath})
+	default:
+		writeJSON(w, http.StatusNotFound, ErrorResponse{Error: "not found, expected " + ReportsPath + " or " + SummaryPath})
+	}
+}
+
+// authorized returns whether the request has the
+// expected bearer token, or true if none is configured
+func (s *Server) authorized(r *http.Request) bool {
+	if s.authToken == "" {
+		return true
+	}
+
+	expected := "Bearer " + s.authToken
+
+	return subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) == 1
+}
+
+// receiveReport decodes the report in the request
+// body and passes it to the aggregator
+func (s *Server) receiveReport(w http.ResponseWriter, r *http.Request) {
+	var report Report
+
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxReportBytes)).Decode(&report)
+
+	if err == nil {
+		err = s.aggregator.Receive(report, time.Now())
+	}
+
+	if err != nil {
+		if s.logger != nil {
+			s.logger.Warnf("rejected fleet report from %s: %s", r.RemoteAddr, err)
+		}
+
+		message := "invalid report json: " + err.Error()
+
+		if errors.Is(err, ErrInvalidReport) {
+			message = err.Error()
+		}
+
+		writeJSON(w, http.StatusBadRequest, ErrorResponse{Error: message})
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeJSON writes the json encoded body with the status code
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	json.NewEncoder(w).Encode(body)
+}
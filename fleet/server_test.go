@@ -0,0 +1,91 @@
+package fleet
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeHTTPReceivesReportsAndServesSummary(t *testing.T) {
+	server := createServer(t, ServerConfig{Aggregator: NewAggregator(AggregatorConfig{})})
+
+	body, err := json.Marshal(Report{AgentId: "agent-1", EndpointURL: TestEndpointURL, Up: up(true), Nodes: []NodeReport{{NodeId: TestNodeId}}})
+	assert.Nil(t, err)
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, ReportsPath, bytes.NewReader(body)))
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, SummaryPath, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	var summary Summary
+
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &summary))
+
+	if assert.Equal(t, 1, len(summary.Endpoints)) {
+		assert.Equal(t, 1, summary.Endpoints[0].Nodes)
+	}
+}
+
+func TestServeHTTPRejectsInvalidReports(t *testing.T) {
+	server := createServer(t, ServerConfig{Aggregator: NewAggregator(AggregatorConfig{})})
+
+	for _, body := range []string{"not json", `{"endpoint_url":"https://rpc.data.kava.io"}`} {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, ReportsPath, bytes.NewReader([]byte(body))))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+	}
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, ReportsPath, nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestServeHTTPRequiresAuthToken(t *testing.T) {
+	server := createServer(t, ServerConfig{Aggregator: NewAggregator(AggregatorConfig{}), AuthToken: "secret"})
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, SummaryPath, nil))
+
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	request := httptest.NewRequest(http.MethodGet, SummaryPath, nil)
+	request.Header.Set("Authorization", "Bearer secret")
+
+	recorder = httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestNewServerReturnsErrorForMissingCertificate(t *testing.T) {
+	_, err := NewServer(ServerConfig{
+		TLSCertFilepath: filepath.Join(t.TempDir(), "cert.pem"),
+		TLSKeyFilepath:  filepath.Join(t.TempDir(), "key.pem"),
+		Aggregator:      NewAggregator(AggregatorConfig{}),
+	})
+
+	assert.NotNil(t, err)
+}
+
+// createServer returns a server using the provided config
+func createServer(t *testing.T, config ServerConfig) *Server {
+	t.Helper()
+
+	server, err := NewServer(config)
+
+	assert.Nil(t, err)
+
+	return server
+}
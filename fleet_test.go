@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/fleet"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintFleetSummary(t *testing.T) {
+	generatedAt := time.Date(2022, 8, 1, 12, 0, 0, 0, time.UTC)
+	uptimePercent := float32(99.5)
+	up := true
+
+	summary := fleet.Summary{
+		Endpoints: []fleet.EndpointSummary{{
+			EndpointURL:            "https://rpc.data.kava.io",
+			Agents:                 2,
+			Nodes:                  3,
+			UnhealthyNodes:         1,
+			MeanUptimePercent:      &uptimePercent,
+			WorstSecondsBehindLive: 300,
+		}},
+		Agents: []fleet.AgentSummary{{
+			AgentId:        "us-east-1",
+			EndpointURL:    "https://rpc.data.kava.io",
+			Up:             &up,
+			Nodes:          3,
+			LastReportedAt: generatedAt.Add(-5 * time.Minute),
+			Stale:          true,
+		}},
+		GeneratedAt: generatedAt,
+	}
+
+	var output bytes.Buffer
+
+	printFleetSummary(&output, summary, logging.TimeFormat{})
+
+	expected := `fleet summary at 2022-08-01T12:00:00Z
+ENDPOINT                  AGENTS  DOWN  NODES  UNHEALTHY  MEAN UPTIME  MIN UPTIME  WORST BEHIND LIVE
+https://rpc.data.kava.io  2       0     3      1          99.50%       -           300s
+
+AGENT      ENDPOINT                  UP    UPTIME  NODES  LAST REPORTED
+us-east-1  https://rpc.data.kava.io  true  -       3      2022-08-01T11:55:00Z (stale)
+
+`
+
+	assert.Equal(t, expected, output.String())
+}
@@ -5,20 +5,40 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"math"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
 	ui "github.com/gizak/termui/v3"
 	"github.com/gizak/termui/v3/widgets"
 
+	"github.com/kava-labs/doctor/backpressure"
 	"github.com/kava-labs/doctor/collect"
 	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/event"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
 	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/slo"
+	"github.com/kava-labs/doctor/store"
+	"github.com/kava-labs/doctor/syncphase"
 	"github.com/spf13/viper"
 )
 
+const (
+	// max number of characters of a node id shown on its tab
+	MaxNodeTabNameLength = 12
+)
+
+var (
+	ErrTerminalUnavailable = errors.New("terminal unavailable for interactive mode")
+)
+
 // GUIConfig wraps values
 // used to configure the GUI
 // display mode of the doctor program
@@ -28,9 +48,41 @@ type GUIConfig struct {
 	RefreshRateSeconds                         int
 	MaxMetricSamplesToRetainPerNode            int
 	MetricSamplesForSyntheticMetricCalculation int
-	MetricCollectors                           []string
-	AWSRegion                                  string
-	MetricNamespace                            string
+	MetricCollectorsConfig
+	SampleStore             store.Store
+	Logger                  *logging.Logger
+	MaxChartPointsPerSeries int
+	// peer churn per minute above which to alert, 0 disables alerting
+	PeerChurnAlertThresholdPerMinute float64
+	// 95th percentile block interval above which to alert, 0 disables alerting
+	BlockIntervalP95AlertThresholdSeconds float64
+	// whether to discard samples from stale (e.g. cached) status responses
+	DiscardStaleSamples bool
+	// config values that silently disable protection, reported
+	// (and collected as metrics) once the display starts
+	ConfigWarnings []dconfig.ConfigWarning
+	// named groups of nodes (or endpoints) to roll up metrics for
+	NodeGroups []dconfig.NodeGroup
+	// seconds behind live beyond which a group member is counted as unhealthy
+	NodeGroupSyncLatencyToleranceSeconds int
+	// windows to calculate the uptime of the endpoint over
+	UptimeWindows []time.Duration
+	// half life of the moving average uptime, 0 disables the average
+	UptimeEWMAHalfLife time.Duration
+	// service level objectives to track the error budgets of
+	SLOs []slo.Objective
+	// max number of messages retained for scrolling back through
+	MaxMessagesToRetain int
+	// time zone and layout to display times in
+	TimeFormat logging.TimeFormat
+	// channel to send adjusted monitoring interval and autohealing
+	// thresholds to, along with the values the node client started with
+	NodeClientControls chan<- NodeClientControl
+	NodeClientControl  NodeClientControl
+	// counter of messages dropped on the doctor's channels
+	DroppedMessages *backpressure.Counter
+	// optional manager to publish alerts through
+	Alerts *incident.AlertManager
 }
 
 // GUI controls the display
@@ -38,21 +90,79 @@ type GUIConfig struct {
 // using asci interactive tty
 // output devices
 type GUI struct {
-	grid               *ui.Grid
-	updateParagraph    func(count int)
-	draw               func(count int, paragraph string)
-	newMessageFunc     func(message string)
-	updateUptimeFunc   func(uptime float32)
-	kavaEndpoint       *Endpoint
-	metricCollectors   []collect.Collector
-	refreshRateSeconds int
-	debugMode          bool
-	*log.Logger
+	grid             *ui.Grid
+	updateParagraph  func(count int)
+	draw             func(paragraph string)
+	newMessageFunc   func(entry logging.Entry)
+	updateUptimeFunc func(uptime float32)
+	// scrolls the messages back (positive) or forward (negative) by pages
+	scrollMessagesFunc func(pages int)
+	// pauses or resumes auto scrolling to the newest message
+	toggleMessagesPausedFunc func()
+	// shows the threshold editor overlay with the provided text
+	showThresholdEditorFunc func(text string)
+	// hides the threshold editor overlay
+	hideThresholdEditorFunc func()
+	// updates the seconds behind live chart with the provided series
+	updateSecondsBehindLiveFunc func(series []float64)
+	// updates the block height chart with the provided series
+	updateBlockHeightFunc func(series []float64)
+	// updates the latency sparkline for the node with the provided series
+	updateLatencyFunc func(nodeId string, series []float64)
+	// updates the uptime bar chart with the uptime of each node
+	updatePerNodeUptimeFunc func(perNodeUptime map[string]float32)
+	// updates the block interval panel with the provided distribution
+	updateBlockIntervalFunc func(blockInterval metric.BlockIntervalMetric)
+	// updates the node tabs with the tracked nodes and selected node
+	updateNodeTabsFunc func(nodeIds []string, selectedIndex int)
+	// updates the timeline with the provided events for the node
+	updateTimelineFunc func(nodeId string, events []TimelineEvent)
+	// health state changes, heal actions, annotations and alerts
+	// for all nodes, of which those for the selected node are shown
+	timeline *Timeline
+	// nodes in the order they first reported, of which
+	// the metrics for the selected node are shown
+	nodeIds                 []string
+	selectedNodeId          string
+	latestSyncStatusMetrics map[string]metric.SyncStatusMetrics
+	// latest rollup of each node group, shown below the selected node
+	latestNodeGroupRollups  map[string]metric.NodeGroupRollupMetrics
+	maxChartPointsPerSeries int
+	kavaEndpoint            *endpoint.Endpoint
+	metricCollectors        []collect.Collector
+	refreshRateSeconds      int
+	debugMode               bool
+	// peer churn per minute above which to alert, 0 disables alerting
+	peerChurnAlertThresholdPerMinute float64
+	// 95th percentile block interval above which to alert, 0 disables alerting
+	blockIntervalP95AlertThresholdSeconds float64
+	discardStaleSamples                   bool
+	configWarnings                        []dconfig.ConfigWarning
+	nodeGroups                            []dconfig.NodeGroup
+	nodeGroupSyncLatencyToleranceSeconds  int
+	uptimeWindows                         []time.Duration
+	uptimeEWMAHalfLife                    time.Duration
+	sloTracker                            *slo.Tracker
+	// edits the monitoring interval and autohealing thresholds,
+	// which are sent to the node client once applied
+	thresholdEditor    *ThresholdEditor
+	nodeClientControls chan<- NodeClientControl
+	nodeClientControl  NodeClientControl
+	// number of dropped log messages already collected as metrics
+	reportedDroppedLogMessages uint64
+	droppedMessages            *backpressure.Counter
+	// number of messages dropped per channel
+	// already collected as metrics
+	reportedDroppedMessages map[string]uint64
+	// publishes alerts once as they fire, escalate and resolve
+	alerts *incident.AlertManager
+	*logging.Logger
 }
 
-// Watch watches for new measurements and log messages for the kava node with the
-// specified rpc api url, outputting them to the gui device in the desired format
-func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <-chan string, kavaNodeRPCURL string) error {
+// Watch watches (until the context is cancelled or the user quits) for new
+// measurements and log messages for the kava node with the specified rpc api url,
+// outputting them to the gui device in the desired format
+func (g *GUI) Watch(ctx context.Context, metricReadOnlyChannels MetricReadOnlyChannels, logMessages <-chan logging.Entry, kavaNodeRPCURL string) error {
 	tickerCount := 1
 
 	// create channel to subscribe to
@@ -66,23 +176,37 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 	// handle logging in separate go-routines to avoid
 	// congestion with metric event emission
 	go func() {
-		// events triggered by debug worthy events
+		// events triggered by log worthy events, filtered
+		// by the configured log level at the source
 		for logMessage := range logMessages {
-			// TODO: separate channels
-			// for debug only log messages?
-			if !g.debugMode {
-				continue
-			}
 			g.newMessageFunc(logMessage)
 		}
 	}()
 
+	// flag config that silently disables protection
+	for _, warning := range g.configWarnings {
+		g.Warnf("config warning: %s", warning.Message)
+	}
+
+	collectMetrics(g.metricCollectors, configWarningMetrics(g.configWarnings, time.Now()), g.Logger)
+
 	for {
 		select {
+		case <-ctx.Done():
+			ui.Close()
+
+			return nil
 		// events triggered by user input
 		// or action such as keyboard strokes
 		// mouse movements or window changes
 		case e := <-uiEvents:
+			// send keys to the threshold editor while it's open
+			if g.thresholdEditor.IsOpen() && e.Type == ui.KeyboardEvent {
+				g.editThresholds(e.ID)
+
+				continue
+			}
+
 			switch e.ID {
 			case "q", "<C-c>":
 				ui.Close()
@@ -93,16 +217,56 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 					`Current Config %+v
 				`, viper.AllSettings())
 
-				g.newMessageFunc(updatedParagraph)
-
-				time.Sleep(1 * time.Second)
+				g.newMessageFunc(logging.Entry{
+					Timestamp: time.Now(),
+					Level:     logging.InfoLevel,
+					Message:   updatedParagraph,
+				})
+			case "n":
+				g.selectNode(g.selectedNodeIndex() + 1)
+			case "p":
+				g.selectNode(g.selectedNodeIndex() - 1)
+			case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+				index := int(e.ID[0] - '1')
+
+				// ignore keys for nodes that haven't reported
+				if index < len(g.nodeIds) {
+					g.selectNode(index)
+				}
 			case "l":
-				// TODO: allow paging through metrics per node
-				message := fmt.Sprintf("Accumulated Metrics %+v", g.kavaEndpoint.PerNodeMetrics)
+				message := fmt.Sprintf("Accumulated Metrics for node %s %+v", g.selectedNodeId, g.kavaEndpoint.NodeMetrics(g.selectedNodeId))
+
+				g.newMessageFunc(logging.Entry{
+					Timestamp: time.Now(),
+					Level:     logging.InfoLevel,
+					Message:   message,
+				})
+			case "<PageUp>":
+				g.scrollMessagesFunc(1)
+			case "<PageDown>":
+				g.scrollMessagesFunc(-1)
+			case "s":
+				g.toggleMessagesPausedFunc()
+			case "+", "=":
+				control := g.nodeClientControl
+				control.MonitoringIntervalSeconds++
+
+				g.sendNodeClientControl(control)
+			case "-":
+				control := g.nodeClientControl
+
+				if control.MonitoringIntervalSeconds <= 1 {
+					g.Warnf("monitoring interval is already the minimum of 1 second")
+
+					break
+				}
 
-				g.newMessageFunc(message)
+				control.MonitoringIntervalSeconds--
 
-				time.Sleep(3 * time.Second)
+				g.sendNodeClientControl(control)
+			case "e":
+				g.thresholdEditor.Open(g.nodeClientControl)
+				g.showThresholdEditorFunc(g.thresholdEditor.Text())
 			case "<Resize>":
 				payload := e.Payload.(ui.Resize)
 
@@ -110,37 +274,70 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 
 				ui.Clear()
 
-				ui.Render(g.grid)
+				g.draw("")
 			}
 		// events triggered by new metric data
 		case syncStatusMetrics := <-metricReadOnlyChannels.SyncStatusMetrics:
 			// record sample in-memory for use in synthetic metric calculation
-			g.kavaEndpoint.AddSample(syncStatusMetrics.NodeId, NodeMetrics{
+			err := g.kavaEndpoint.AddSample(syncStatusMetrics.NodeId, endpoint.NodeMetrics{
 				SyncStatusMetrics: &syncStatusMetrics,
 			})
 
-			// calculate hash rate for this node
+			if err != nil {
+				g.Errorf("error %s persisting sample for %s", err, syncStatusMetrics.NodeId)
+			}
+
 			nodeId := syncStatusMetrics.NodeId
 
-			hashRatePerSecond, err := g.kavaEndpoint.CalculateNodeHashRatePerSecond(nodeId)
+			g.trackNode(nodeId)
+
+			// stale responses aren't representative of the node's current
+			// sync status so only count them when discarding stale samples
+			if syncStatusMetrics.Stale && g.discardStaleSamples {
+				collectMetrics(g.metricCollectors, append(staleResponseMetrics(syncStatusMetrics), metric.Metric{
+					Name: "SyncStatus",
+					Dimensions: map[string]string{
+						"node_id": nodeId,
+					},
+					Data:      syncStatusMetrics,
+					Timestamp: syncStatusMetrics.SampledAt,
+				}), g.Logger)
+
+				continue
+			}
+
+			// calculate hash rate for this node
+
+			hashRatePerSecond, err := g.kavaEndpoint.CalculateHashRate(nodeId)
 
 			if err != nil {
-				g.newMessageFunc(fmt.Sprintf("error %s calculating hash rate for node %s\n", err, nodeId))
+				g.Debugf("error %s calculating hash rate for node %s", err, nodeId)
 			}
 
 			latestBlockHeight := syncStatusMetrics.SyncStatus.LatestBlockHeight
 			secondsBehindLive := syncStatusMetrics.SecondsBehindLive
 			syncStatusLatencyMilliseconds := syncStatusMetrics.SampleLatencyMilliseconds
 
-			updatedParagraph := fmt.Sprintf(
-				`Node %s
-			Latest Block Height %d
-			Seconds Behind Live %d
-			Blocks Hashed (per second) %f
-			Sync Status Latency (milliseconds) %d
-			`, nodeId, latestBlockHeight, secondsBehindLive, hashRatePerSecond, syncStatusLatencyMilliseconds)
+			g.latestSyncStatusMetrics[nodeId] = syncStatusMetrics
 
-			g.draw(tickerCount, updatedParagraph)
+			// sparklines show one point per column so
+			// are trimmed to the most recent samples when drawn
+			latencySeries := g.kavaEndpoint.SyncStatusSeries(nodeId, func(syncStatusMetrics *metric.SyncStatusMetrics) float64 {
+				return float64(syncStatusMetrics.SampleLatencyMilliseconds)
+			})
+
+			g.updateLatencyFunc(nodeId, latencySeries)
+
+			groupMetrics, rollups := nodeGroupRollupMetrics(g.kavaEndpoint, g.nodeGroups, nodeId, int64(g.nodeGroupSyncLatencyToleranceSeconds), syncStatusMetrics.SampledAt)
+
+			for _, rollup := range rollups {
+				g.latestNodeGroupRollups[rollup.GroupName] = rollup
+			}
+
+			// the remaining panels only show the selected node
+			if nodeId == g.selectedNodeId {
+				g.refreshNodePanels()
+			}
 
 			// collect metrics to external storage backends
 			var metrics []metric.Metric
@@ -156,7 +353,6 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(hashRatePerSecond),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       true,
 				CollectToCloudwatch: true,
 			}
 
@@ -168,7 +364,6 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 					"node_id": nodeId,
 				},
 				Data:                syncStatusMetrics,
-				CollectToFile:       true,
 				CollectToCloudwatch: false,
 			}
 
@@ -181,7 +376,6 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(latestBlockHeight),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       false,
 				CollectToCloudwatch: true,
 			}
 
@@ -194,7 +388,7 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(secondsBehindLive),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       false,
+				Unit:                metric.UnitSeconds,
 				CollectToCloudwatch: true,
 			}
 
@@ -207,18 +401,77 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				},
 				Value:               float64(syncStatusLatencyMilliseconds),
 				Timestamp:           syncStatusMetrics.SampledAt,
-				CollectToFile:       false,
+				Unit:                metric.UnitMilliseconds,
 				CollectToCloudwatch: true,
 			}
 
 			metrics = append(metrics, statusCheckMillisecondLatencyMetric)
 
+			latencyMetrics, err := statusCheckLatencyMetrics(g.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+			if err != nil {
+				g.Debugf("error %s calculating status check latency percentiles for node %s", err, nodeId)
+			}
+
+			metrics = append(metrics, latencyMetrics...)
+
+			g.sloTracker.RecordStatusLatency(time.Duration(syncStatusMetrics.SampleLatencyMilliseconds)*time.Millisecond, syncStatusMetrics.SampledAt)
+
+			metrics = append(metrics, g.sloMetrics(slo.LatencyObjectiveKind, syncStatusMetrics.SampledAt)...)
+
+			intervalMetrics, blockInterval, err := blockIntervalMetrics(g.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+			if err != nil {
+				g.Debugf("error %s calculating block interval for node %s", err, nodeId)
+			} else {
+				// slow block production indicates consensus rounds
+				// are failing or validators are missing blocks
+				slowBlocks := g.blockIntervalP95AlertThresholdSeconds > 0 && blockInterval.P95Seconds > g.blockIntervalP95AlertThresholdSeconds
+
+				if slowBlocks {
+					g.Warnf("node %s p95 block interval of %f seconds exceeds alert threshold of %f seconds, consensus may be slow", nodeId, blockInterval.P95Seconds, g.blockIntervalP95AlertThresholdSeconds)
+				}
+
+				g.setAlert(nodeId, "BlockIntervalP95", slowBlocks, fmt.Sprintf("p95 block interval of %.1f seconds", blockInterval.P95Seconds), syncStatusMetrics.SampledAt)
+			}
+
+			metrics = append(metrics, intervalMetrics...)
+
+			// a node restoring a snapshot has no blocks to estimate from
+			if syncStatusMetrics.SyncStatus.CatchingUp && syncStatusMetrics.SyncPhase != syncphase.StateSync {
+				progressMetrics, _, err := syncProgressMetrics(g.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+				if err != nil {
+					g.Debugf("error %s estimating sync progress for node %s", err, nodeId)
+				}
+
+				metrics = append(metrics, progressMetrics...)
+			}
+
+			metrics = append(metrics, staleResponseMetrics(syncStatusMetrics)...)
+
+			metrics = append(metrics, blocksBehindReferenceMetrics(syncStatusMetrics)...)
+
+			g.setAlert(nodeId, "ChainIdMismatch", syncStatusMetrics.ChainIdMismatch, fmt.Sprintf("node is on chain %s", syncStatusMetrics.ChainId), syncStatusMetrics.SampledAt)
+
+			metrics = append(metrics, chainIdMismatchMetrics(syncStatusMetrics)...)
+
+			metrics = append(metrics, syncPhaseMetrics(syncStatusMetrics)...)
+
+			if syncStatusMetrics.AutohealCircuitBreakerTripped != nil {
+				g.setAlert(nodeId, "AutohealCircuitBreaker", *syncStatusMetrics.AutohealCircuitBreakerTripped, fmt.Sprintf("autoheal restart cap reached, autohealing stopped until reset using the %s command", ResetAutohealCommand), syncStatusMetrics.SampledAt)
+			}
+
+			metrics = append(metrics, autohealCircuitBreakerMetrics(syncStatusMetrics)...)
+
+			metrics = append(metrics, groupMetrics...)
+
 			for _, collector := range g.metricCollectors {
 				for _, metric := range metrics {
 					err := collector.Collect(metric)
 
 					if err != nil {
-						g.newMessageFunc(fmt.Sprintf("error %s collecting metric %+v\n", err, metric))
+						g.Errorf("error %s collecting metric %+v", err, metric)
 					}
 				}
 
@@ -228,21 +481,27 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 		case uptimeMetric := <-metricReadOnlyChannels.UptimeMetrics:
 			endpointURL := uptimeMetric.EndpointURL
 			// record sample in-memory for use in synthetic metric calculation
-			g.kavaEndpoint.AddSample(uptimeMetric.EndpointURL, NodeMetrics{
+			err := g.kavaEndpoint.AddSample(uptimeMetric.EndpointURL, endpoint.NodeMetrics{
 				UptimeMetric: &uptimeMetric,
 			})
 
+			if err != nil {
+				g.Errorf("error %s persisting sample for %s", err, uptimeMetric.EndpointURL)
+			}
+
 			// calculate uptime
 			uptime, err := g.kavaEndpoint.CalculateUptime(uptimeMetric.EndpointURL)
 
 			if err != nil {
-				g.newMessageFunc(fmt.Sprintf("error %s calculating uptime for %s\n", err, uptimeMetric.EndpointURL))
+				g.Errorf("error %s calculating uptime for %s", err, uptimeMetric.EndpointURL)
 				continue
 			}
 
 			// update uptime gauge
 			g.updateUptimeFunc(uptime)
 
+			g.updatePerNodeUptimeFunc(g.kavaEndpoint.CalculatePerNodeUptime())
+
 			// collect metrics to external storage backends
 			var metrics []metric.Metric
 
@@ -255,36 +514,482 @@ func (g *GUI) Watch(metricReadOnlyChannels MetricReadOnlyChannels, logMessages <
 				Data:                uptimeMetric,
 				Value:               float64(uptimeMetric.RollingAveragePercentAvailable),
 				Timestamp:           uptimeMetric.SampledAt,
-				CollectToFile:       true,
+				Unit:                metric.UnitPercent,
 				CollectToCloudwatch: true,
 			}
 
 			metrics = append(metrics, uptimeMetricForCollection)
+			metrics = append(metrics, statusCheckAttemptsMetrics(uptimeMetric)...)
+			metrics = append(metrics, uptimeProbeMetrics(uptimeMetric)...)
+
+			windowMetrics, _ := uptimeWindowMetrics(g.kavaEndpoint, endpointURL, g.uptimeWindows, g.uptimeEWMAHalfLife, uptimeMetric.SampledAt)
+
+			metrics = append(metrics, windowMetrics...)
+
+			g.sloTracker.RecordUptime(uptimeMetric.Up, uptimeMetric.SampledAt)
+
+			metrics = append(metrics, g.sloMetrics(slo.UptimeObjectiveKind, uptimeMetric.SampledAt)...)
+
+			groupMetrics, rollups := nodeGroupRollupMetrics(g.kavaEndpoint, g.nodeGroups, endpointURL, int64(g.nodeGroupSyncLatencyToleranceSeconds), uptimeMetric.SampledAt)
+
+			for _, rollup := range rollups {
+				g.latestNodeGroupRollups[rollup.GroupName] = rollup
+			}
+
+			if len(rollups) > 0 {
+				g.refreshNodePanels()
+			}
+
+			metrics = append(metrics, groupMetrics...)
+
+			// report log messages dropped since last reported
+			droppedLogMessages := g.Dropped()
+			metrics = append(metrics, droppedLogMessagesMetrics(droppedLogMessages-g.reportedDroppedLogMessages, uptimeMetric.SampledAt)...)
+			g.reportedDroppedLogMessages = droppedLogMessages
+
+			// report messages dropped on any channel since last reported
+			if g.droppedMessages != nil {
+				metrics = append(metrics, droppedMessagesMetrics(g.droppedMessages.Counts(), g.reportedDroppedMessages, uptimeMetric.SampledAt)...)
+			}
 
 			for _, collector := range g.metricCollectors {
 				for _, metric := range metrics {
 					err := collector.Collect(metric)
 
 					if err != nil {
-						g.newMessageFunc(fmt.Sprintf("error %s collecting metric %+v\n", err, metric))
+						g.Errorf("error %s collecting metric %+v", err, metric)
 					}
 				}
 
 			}
+		case peerConnectionMetrics := <-metricReadOnlyChannels.PeerConnectionMetrics:
+			nodeId := peerConnectionMetrics.NodeId
+			// record sample in-memory for use in synthetic metric calculation
+			err := g.kavaEndpoint.AddSample(nodeId, endpoint.NodeMetrics{
+				PeerConnectionMetrics: &peerConnectionMetrics,
+			})
+
+			if err != nil {
+				g.Errorf("error %s persisting sample for %s", err, nodeId)
+			}
+
+			// frozen nodes with poor peering have their peers refreshed
+			g.setAlert(nodeId, "PoorPeering", peerConnectionMetrics.PoorPeering, fmt.Sprintf("%d of %d peers low quality", len(peerConnectionMetrics.LowQualityPeerIds), len(peerConnectionMetrics.PeerIds)), peerConnectionMetrics.SampledAt)
+
+			// calculate peer churn for this node
+			metrics, peerChurnPerMinute, err := peerChurnMetrics(g.kavaEndpoint, peerConnectionMetrics)
+
+			if err != nil {
+				g.Debugf("error %s calculating peer churn for node %s", err, nodeId)
+				continue
+			}
+
+			// abnormal churn typically precedes sync instability
+			highChurn := g.peerChurnAlertThresholdPerMinute > 0 && peerChurnPerMinute > g.peerChurnAlertThresholdPerMinute
+
+			if highChurn {
+				g.Warnf("node %s peer churn of %f peers per minute exceeds alert threshold of %f, node sync may become unstable", nodeId, peerChurnPerMinute, g.peerChurnAlertThresholdPerMinute)
+			}
+
+			g.setAlert(nodeId, "PeerChurn", highChurn, fmt.Sprintf("peer churn of %.1f peers per minute", peerChurnPerMinute), peerConnectionMetrics.SampledAt)
+
+			// collect metrics to external storage backends
+			for _, collector := range g.metricCollectors {
+				for _, metric := range metrics {
+					err := collector.Collect(metric)
+
+					if err != nil {
+						g.Errorf("error %s collecting metric %+v", err, metric)
+					}
+				}
+			}
 		// events triggered on a regular time based interval
 		case <-ticker:
 			g.updateParagraph(tickerCount)
 
-			g.draw(tickerCount, "")
+			// advance the timeline's time axis
+			g.refreshTimeline()
+
+			g.draw("")
 
 			tickerCount++
+		case serviceHealth := <-metricReadOnlyChannels.ServiceHealthMetrics:
+			if serviceHealth.RestartLoop {
+				g.Warnf("ServiceRestartLoop: systemd is repeatedly restarting %s service (%d restarts, state %s/%s), autoheal restarts are suspended", serviceHealth.ServiceName, serviceHealth.Restarts, serviceHealth.ActiveState, serviceHealth.SubState)
+			}
+
+			// the service backs every node
+			g.setAlert("", "ServiceRestartLoop", serviceHealth.RestartLoop, fmt.Sprintf("systemd restarting %s service", serviceHealth.ServiceName), serviceHealth.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, serviceHealthMetrics(serviceHealth), g.Logger)
+		case consensusState := <-metricReadOnlyChannels.ConsensusStateMetrics:
+			if consensusState.Frozen {
+				g.Warnf("node %s consensus frozen at height %d round %d step %s for %.0f seconds", consensusState.NodeId, consensusState.Height, consensusState.Round, consensusState.StepName, consensusState.SecondsInStep)
+			}
+
+			g.setAlert(consensusState.NodeId, "ConsensusFrozen", consensusState.Frozen, fmt.Sprintf("consensus frozen at height %d round %d step %s", consensusState.Height, consensusState.Round, consensusState.StepName), consensusState.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, consensusStateMetrics(consensusState), g.Logger)
+		case mempool := <-metricReadOnlyChannels.MempoolMetrics:
+			if mempool.Backlogged {
+				g.Warnf("node %s mempool size of %d transactions (%d bytes) has stayed above alert threshold, node or network may be unable to keep up", mempool.NodeId, mempool.Size, mempool.Bytes)
+			}
+
+			g.setAlert(mempool.NodeId, "MempoolBacklog", mempool.Backlogged, fmt.Sprintf("mempool backlog of %d transactions", mempool.Size), mempool.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, mempoolMetrics(mempool), g.Logger)
+		case host := <-metricReadOnlyChannels.HostMetrics:
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, hostMetrics(host), g.Logger)
+		case rpcMethods := <-metricReadOnlyChannels.RPCMethodsMetrics:
+			var unavailable []string
+
+			for _, method := range rpcMethods.Methods {
+				if !method.Available {
+					unavailable = append(unavailable, method.Method)
+				}
+			}
+
+			g.setAlert(rpcMethods.NodeId, "RPCMethodsUnavailable", len(unavailable) > 0, fmt.Sprintf("rpc methods %s unavailable", strings.Join(unavailable, ", ")), rpcMethods.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, rpcMethodsMetrics(rpcMethods), g.Logger)
+		case websocket := <-metricReadOnlyChannels.WebsocketMetrics:
+			if websocket.Dropped {
+				g.Warnf("node %s websocket subscription to new block events dropped", websocket.NodeId)
+			}
+
+			g.setAlert(websocket.NodeId, "WebsocketDisconnected", !websocket.Connected, "websocket subscription to new block events disconnected", websocket.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, websocketMetrics(websocket), g.Logger)
+		case regionLatency := <-metricReadOnlyChannels.RegionLatencyMetrics:
+			var unavailable []string
+
+			for _, region := range regionLatency.Regions {
+				if !region.Up {
+					unavailable = append(unavailable, region.Region)
+				}
+			}
+
+			g.setAlert(regionLatency.EndpointURL, "RegionsUnavailable", len(unavailable) > 0, fmt.Sprintf("endpoint unavailable from regions %s", strings.Join(unavailable, ", ")), regionLatency.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, regionLatencyMetrics(regionLatency), g.Logger)
+		case consistency := <-metricReadOnlyChannels.ChainConsistencyMetrics:
+			if consistency.Diverged {
+				g.Warnf("node %s block %d hash %s app hash %s differs from reference block hash %s app hash %s", consistency.NodeId, consistency.Height, consistency.BlockHash, consistency.AppHash, consistency.ReferenceBlockHash, consistency.ReferenceAppHash)
+			}
+
+			g.setAlert(consistency.NodeId, "ChainDiverged", consistency.Diverged, fmt.Sprintf("block %d hashes differ from reference", consistency.Height), consistency.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, chainConsistencyMetrics(consistency), g.Logger)
+		case votingPower := <-metricReadOnlyChannels.VotingPowerMetrics:
+			if votingPower.Degraded {
+				g.Warnf("%.2f%% of voting power of chain %s signed block %d, chain is halted or at risk of halting", votingPower.OnlinePercent, votingPower.ChainId, votingPower.Height)
+			}
+
+			g.setAlert(votingPower.ChainId, "ChainDegraded", votingPower.Degraded, fmt.Sprintf("%.2f%% of voting power online at block %d", votingPower.OnlinePercent, votingPower.Height), votingPower.SampledAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, votingPowerMetrics(votingPower), g.Logger)
+		case addressMetrics := <-metricReadOnlyChannels.EndpointAddressMetrics:
+			// record sample in-memory keyed by the address
+			// for calculating the uptime of the address
+			err := g.kavaEndpoint.AddSample(addressMetrics.Address, endpoint.NodeMetrics{
+				UptimeMetric: &metric.UptimeMetric{
+					EndpointURL: addressMetrics.Address,
+					Up:          addressMetrics.Up,
+					SampledAt:   addressMetrics.SampledAt,
+				},
+			})
+
+			if err != nil {
+				g.Errorf("error %s persisting sample for %s", err, addressMetrics.Address)
+			}
+
+			uptime, err := g.kavaEndpoint.CalculateUptime(addressMetrics.Address)
+
+			if err != nil {
+				g.Errorf("error %s calculating uptime for %s", err, addressMetrics.Address)
+				continue
+			}
+
+			addressMetrics.RollingAveragePercentAvailable = uptime * 100
+
+			// addresses are shown alongside nodes when comparing uptime
+			g.updatePerNodeUptimeFunc(g.kavaEndpoint.CalculatePerNodeUptime())
+
+			if !addressMetrics.Up {
+				g.Warnf("address %s of %s is down", addressMetrics.Address, addressMetrics.EndpointURL)
+			}
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, endpointAddressMetrics(addressMetrics), g.Logger)
+		case healthCheck := <-metricReadOnlyChannels.HealthCheckResults:
+			if !healthCheck.Healthy {
+				g.Warnf("%s health check failed: %s", healthCheck.Check, healthCheck.Message)
+			}
+
+			// health checks are of the configured node
+			g.setAlert("", "HealthCheck "+healthCheck.Check, !healthCheck.Healthy, healthCheck.Message, healthCheck.StartedAt)
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, healthCheckMetrics(healthCheck), g.Logger)
+		case accessLog := <-metricReadOnlyChannels.AccessLogMetrics:
+			if accessLog.UnparsedLines > 0 {
+				g.Debugf("skipped %d unparseable lines in access log %s", accessLog.UnparsedLines, accessLog.LogFilepath)
+			}
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, accessLogMetrics(accessLog), g.Logger)
+		case incidentEvent := <-metricReadOnlyChannels.IncidentEvents:
+			g.timeline.AddIncidentEvent(incidentEvent)
+
+			g.refreshTimeline()
+		case newAnnotation := <-metricReadOnlyChannels.Annotations:
+			g.timeline.AddAnnotation(newAnnotation)
+
+			g.refreshTimeline()
+		case busEvent := <-metricReadOnlyChannels.Events:
+			if busEvent.Type == event.HealthCheckFailedType {
+				g.Warnf("%s", formatEvent(busEvent))
+			} else {
+				g.Infof("%s", formatEvent(busEvent))
+			}
+
+			// incidents are already on the timeline, config changes
+			// are shown alongside deploys as they can cause problems too
+			if busEvent.Type == event.ConfigLoadedType {
+				g.timeline.Add(TimelineEvent{
+					Lane:       DeployTimelineLane,
+					Message:    busEvent.Message,
+					OccurredAt: busEvent.OccurredAt,
+				})
+
+				g.refreshTimeline()
+			}
+
+			// collect metrics to external storage backends
+			collectMetrics(g.metricCollectors, eventMetrics(busEvent), g.Logger)
+		}
+	}
+}
+
+// trackNode tracks the node as one that can be
+// selected for display, selecting the first node seen
+func (g *GUI) trackNode(nodeId string) {
+	for _, trackedNodeId := range g.nodeIds {
+		if trackedNodeId == nodeId {
+			return
+		}
+	}
+
+	g.nodeIds = append(g.nodeIds, nodeId)
+
+	if g.selectedNodeId == "" {
+		g.selectNode(0)
+
+		return
+	}
+
+	g.updateNodeTabsFunc(g.nodeIds, g.selectedNodeIndex())
+}
+
+// selectedNodeIndex returns the index
+// of the selected node in the tracked nodes
+func (g *GUI) selectedNodeIndex() int {
+	for i, nodeId := range g.nodeIds {
+		if nodeId == g.selectedNodeId {
+			return i
 		}
 	}
+
+	return 0
 }
 
+// selectNode selects the tracked node at the index (wrapping
+// around past the first or last node) for display, redrawing
+// the node tabs, per node panels and timeline
+func (g *GUI) selectNode(index int) {
+	if len(g.nodeIds) == 0 {
+		return
+	}
+
+	index = (index%len(g.nodeIds) + len(g.nodeIds)) % len(g.nodeIds)
+
+	g.selectedNodeId = g.nodeIds[index]
+
+	g.updateNodeTabsFunc(g.nodeIds, index)
+
+	g.refreshNodePanels()
+
+	g.refreshTimeline()
+}
+
+// refreshNodePanels redraws the sync metrics, block height, seconds
+// behind live and block interval panels for the selected node
+func (g *GUI) refreshNodePanels() {
+	nodeId := g.selectedNodeId
+
+	syncStatusMetrics, exists := g.latestSyncStatusMetrics[nodeId]
+
+	if !exists {
+		// selected node has only returned stale responses
+		g.draw(fmt.Sprintf("Node %s\nno samples yet", nodeId))
+
+		return
+	}
+
+	hashRatePerSecond, err := g.kavaEndpoint.CalculateHashRate(nodeId)
+
+	if err != nil {
+		g.Debugf("error %s calculating hash rate for node %s", err, nodeId)
+	}
+
+	updatedParagraph := fmt.Sprintf(
+		`Node %s
+	Latest Block Height %d
+	Seconds Behind Live %d
+	Blocks Hashed (per second) %f
+	Sync Status Latency (milliseconds) %d
+	`, nodeId, syncStatusMetrics.SyncStatus.LatestBlockHeight, syncStatusMetrics.SecondsBehindLive, hashRatePerSecond, syncStatusMetrics.SampleLatencyMilliseconds)
+
+	if syncStatusMetrics.SyncPhase != "" {
+		updatedParagraph += fmt.Sprintf("Sync Phase %s\n\t", syncStatusMetrics.SyncPhase)
+	}
+
+	if syncStatusMetrics.SyncStatus.CatchingUp && syncStatusMetrics.SyncPhase != syncphase.StateSync {
+		_, progress, err := syncProgressMetrics(g.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+		if err != nil {
+			updatedParagraph += "Catching Up (estimating time to catch up...)\n\t"
+		} else {
+			updatedParagraph += fmt.Sprintf("Catching Up %.2f%% (%d blocks remaining) eta %s\n\t", progress.PercentSynced, progress.BlocksRemaining, formatEstimatedTimeToCatchUp(progress))
+		}
+	}
+
+	// rollups are shown in the order the groups are configured
+	for _, group := range g.nodeGroups {
+		if rollup, exists := g.latestNodeGroupRollups[group.Name]; exists {
+			updatedParagraph += formatNodeGroupRollup(rollup) + "\n\t"
+		}
+	}
+
+	g.draw(updatedParagraph)
+
+	// chart the retained history for this node, downsampling
+	// to keep render times constant as samples accumulate
+	secondsBehindLiveSeries := g.kavaEndpoint.SyncStatusSeries(nodeId, func(syncStatusMetrics *metric.SyncStatusMetrics) float64 {
+		return float64(syncStatusMetrics.SecondsBehindLive)
+	})
+
+	g.updateSecondsBehindLiveFunc(metric.Downsample(secondsBehindLiveSeries, g.maxChartPointsPerSeries))
+
+	blockHeightSeries := g.kavaEndpoint.SyncStatusSeries(nodeId, func(syncStatusMetrics *metric.SyncStatusMetrics) float64 {
+		return float64(syncStatusMetrics.SyncStatus.LatestBlockHeight)
+	})
+
+	g.updateBlockHeightFunc(metric.Downsample(blockHeightSeries, g.maxChartPointsPerSeries))
+
+	_, blockInterval, err := blockIntervalMetrics(g.kavaEndpoint, nodeId, syncStatusMetrics.SampledAt)
+
+	if err != nil {
+		g.Debugf("error %s calculating block interval for node %s", err, nodeId)
+
+		return
+	}
+
+	g.updateBlockIntervalFunc(blockInterval)
+}
+
+// setAlert records whether the named alert is firing
+// for the node on the timeline, redrawing the timeline,
+// and publishes it (if alerts are published)
+func (g *GUI) setAlert(nodeId string, name string, firing bool, message string, occurredAt time.Time) {
+	g.timeline.SetAlert(nodeId, name, firing, message, occurredAt)
+	g.alerts.Set(nodeId, name, firing, message, occurredAt)
+
+	if firing {
+		g.refreshTimeline()
+	}
+}
+
+// refreshTimeline redraws the timeline with the
+// events within the window for the selected node
+func (g *GUI) refreshTimeline() {
+	g.updateTimelineFunc(g.selectedNodeId, g.timeline.Events(g.selectedNodeId, time.Now().Add(-DefaultTimelineWindow)))
+}
+
+// editThresholds passes the key pressed to the threshold editor,
+// sending the edited thresholds to the node client once applied
+func (g *GUI) editThresholds(key string) {
+	control, applied := g.thresholdEditor.HandleKey(key)
+
+	if !g.thresholdEditor.IsOpen() {
+		g.hideThresholdEditorFunc()
+	} else {
+		g.showThresholdEditorFunc(g.thresholdEditor.Text())
+	}
+
+	if applied && control != g.nodeClientControl {
+		g.sendNodeClientControl(control)
+	}
+}
+
+// sendNodeClientControl sends the adjusted monitoring interval and
+// autohealing thresholds to the node client without blocking
+func (g *GUI) sendNodeClientControl(control NodeClientControl) {
+	select {
+	case g.nodeClientControls <- control:
+		g.nodeClientControl = control
+
+		g.Infof("adjusted thresholds %+v", control)
+	default:
+		g.Warnf("unable to adjust thresholds, previous adjustments are still being applied")
+	}
+}
+
+// Close flushes and closes all metric collectors
+// used by the gui, returning error (if any)
+func (g *GUI) Close() error {
+	return collect.CloseAll(g.metricCollectors)
+}
+
+// Endpoint returns the samples and synthetic
+// metrics collected for each node
+func (g *GUI) Endpoint() *endpoint.Endpoint {
+	return g.kavaEndpoint
+}
+
+// sloMetrics returns metrics for the error budgets of the objectives
+// of the kind, alerting on any error budgets burning too fast
+func (g *GUI) sloMetrics(kind string, evaluatedAt time.Time) []metric.Metric {
+	statuses := g.sloTracker.Statuses(kind, evaluatedAt)
+
+	for _, status := range statuses {
+		g.setAlert("", "SLO "+status.Name, status.Alert != "", formatSLOAlert(status), evaluatedAt)
+	}
+
+	return sloMetrics(statuses)
+}
+
+// NewGUI creates and returns a new gui
+// using the provided configuration and error (if any)
+// if the terminal can't be initialized (e.g. no tty is attached
+// or the terminal type is unsupported) an error wrapping
+// `ErrTerminalUnavailable` is returned
 func NewGUI(config GUIConfig) (*GUI, error) {
+	if fileInfo, err := os.Stdout.Stat(); err != nil || fileInfo.Mode()&os.ModeCharDevice == 0 {
+		return nil, fmt.Errorf("%w: stdout is not a terminal", ErrTerminalUnavailable)
+	}
+
 	if err := ui.Init(); err != nil {
-		panic(fmt.Errorf("failed to initialize termui: %v", err))
+		return nil, fmt.Errorf("%w: failed to initialize termui: %v", ErrTerminalUnavailable, err)
 	}
 
 	// upper left box
@@ -293,6 +998,12 @@ func NewGUI(config GUIConfig) (*GUI, error) {
 	syncMetrics.Text = `PRESS q TO QUIT
 	PRESS c TO VIEW CONFIG
 	PRESS l TO LIST SAMPLES
+	PRESS n OR p TO SHOW NEXT OR PREVIOUS NODE
+	PRESS 1-9 TO SHOW NODE
+	PRESS PgUp OR PgDn TO SCROLL MESSAGES
+	PRESS s TO PAUSE MESSAGES
+	PRESS + OR - TO CHANGE MONITORING INTERVAL
+	PRESS e TO EDIT THRESHOLDS
 	`
 	syncMetrics.SetRect(0, 0, 50, 5)
 	syncMetrics.TextStyle.Fg = ui.ColorWhite
@@ -307,12 +1018,10 @@ func NewGUI(config GUIConfig) (*GUI, error) {
 	}
 
 	// upper right box
-	messages := widgets.NewParagraph()
+	messages := NewMessageLogWidget(config.MaxMessagesToRetain)
 	messages.Title = "Messages"
-	messages.Text = ""
-	messages.SetRect(0, 0, 50, 5)
-	messages.TextStyle.Fg = ui.ColorYellow
 	messages.BorderStyle.Fg = ui.ColorMagenta
+	messages.TimeFormat = config.TimeFormat
 
 	// lower left box
 	uptimeMetric := widgets.NewGauge()
@@ -323,72 +1032,77 @@ func NewGUI(config GUIConfig) (*GUI, error) {
 	uptimeMetric.BorderStyle.Fg = ui.ColorWhite
 	uptimeMetric.TitleStyle.Fg = ui.ColorCyan
 
-	sparklineData := []float64{4, 2, 1, 6, 3, 9, 1, 4, 2, 15, 14, 9, 8, 6, 10, 13, 15, 12, 10, 5, 3, 6, 1, 7, 10, 10, 14, 13, 6, 4, 2, 1, 6, 3, 9, 1, 4, 2, 15, 14, 9, 8, 6, 10, 13, 15, 12, 10, 5, 3, 6, 1, 7, 10, 10, 14, 13, 6, 4, 2, 1, 6, 3, 9, 1, 4, 2, 15, 14, 9, 8, 6, 10, 13, 15, 12, 10, 5, 3, 6, 1, 7, 10, 10, 14, 13, 6, 4, 2, 1, 6, 3, 9, 1, 4, 2, 15, 14, 9, 8, 6, 10, 13, 15, 12, 10, 5, 3, 6, 1, 7, 10, 10, 14, 13, 6}
-
-	sl := widgets.NewSparkline()
-	sl.Title = "srv 0:"
-	sl.Data = sparklineData
-	sl.LineColor = ui.ColorCyan
-	sl.TitleStyle.Fg = ui.ColorWhite
-
-	sl2 := widgets.NewSparkline()
-	sl2.Title = "srv 1:"
-	sl2.Data = sparklineData
-	sl2.TitleStyle.Fg = ui.ColorWhite
-	sl2.LineColor = ui.ColorRed
-
-	slg := widgets.NewSparklineGroup(sl, sl2)
-	slg.Title = "Sparkline"
-	slg.SetRect(25, 5, 50, 12)
-
-	sinData := (func() []float64 {
-		n := 220
-		ps := make([]float64, n)
-		for i := range ps {
-			ps[i] = 1 + math.Sin(float64(i)/5)
-		}
-		return ps
-	})()
+	uptimeBarChart := widgets.NewBarChart()
+	uptimeBarChart.Title = "Uptime Per Node (percent)"
+	uptimeBarChart.BarWidth = 8
+	uptimeBarChart.MaxVal = 100
+	uptimeBarChart.BarColors[0] = ui.ColorGreen
+	uptimeBarChart.NumStyles[0] = ui.NewStyle(ui.ColorBlack)
+	uptimeBarChart.NumFormatter = func(value float64) string {
+		return fmt.Sprintf("%.1f", value)
+	}
+
+	// one sparkline is added per node as it first reports
+	slg := widgets.NewSparklineGroup()
+	slg.Title = "Status Check Latency (milliseconds)"
+	latencySparklines := make(map[string]*widgets.Sparkline)
 
 	lc := widgets.NewPlot()
-	lc.Title = "dot-marker Line Chart"
+	lc.Title = "Latest Block Height"
 	lc.Data = make([][]float64, 1)
-	lc.Data[0] = sinData
-	lc.SetRect(0, 15, 50, 25)
+	lc.Data[0] = []float64{0, 0}
 	lc.AxesColor = ui.ColorWhite
-	lc.LineColors[0] = ui.ColorRed
+	lc.LineColors[0] = ui.ColorGreen
 	lc.Marker = widgets.MarkerDot
 
-	barchartData := []float64{3, 2, 5, 3, 9, 5, 3, 2, 5, 8, 3, 2, 4, 5, 3, 2, 5, 7, 5, 3, 2, 6, 7, 4, 6, 3, 6, 7, 8, 3, 6, 4, 5, 3, 2, 4, 6, 4, 8, 5, 9, 4, 3, 6, 5, 3, 6}
-
 	bc := widgets.NewBarChart()
-	bc.Title = "Bar Chart"
+	bc.Title = "Block Interval (seconds)"
 	bc.SetRect(50, 0, 75, 10)
-	bc.Labels = []string{"S0", "S1", "S2", "S3", "S4", "S5"}
+	bc.Labels = []string{"Min", "Mean", "P95", "Max"}
+	bc.Data = []float64{0, 0, 0, 0}
 	bc.BarColors[0] = ui.ColorGreen
 	bc.NumStyles[0] = ui.NewStyle(ui.ColorBlack)
+	bc.NumFormatter = func(value float64) string {
+		return fmt.Sprintf("%.1f", value)
+	}
 
 	// lower right box
 	lc2 := widgets.NewPlot()
-	lc2.Title = "braille-mode Line Chart"
+	lc2.Title = "Seconds Behind Live"
 	lc2.Data = make([][]float64, 1)
-	lc2.Data[0] = sinData
+	lc2.Data[0] = []float64{0, 0}
 	lc2.SetRect(50, 15, 75, 25)
 	lc2.AxesColor = ui.ColorWhite
 	lc2.LineColors[0] = ui.ColorYellow
 
+	// top bar, one tab per node
+	nodeTabs := widgets.NewTabPane()
+	nodeTabs.Title = "Nodes"
+	nodeTabs.BorderStyle.Fg = ui.ColorCyan
+
+	// middle box
+	timeline := NewTimelineWidget()
+	timeline.Title = "Timeline"
+	timeline.BorderStyle.Fg = ui.ColorCyan
+	timeline.TimeFormat = config.TimeFormat
+
 	// set up 4 x 4 grid
 	grid := ui.NewGrid()
 	termWidth, termHeight := ui.TerminalDimensions()
 	grid.SetRect(0, 0, termWidth, termHeight)
 
 	grid.Set(
-		ui.NewRow(1.0/2,
+		ui.NewRow(1.0/10, nodeTabs),
+		ui.NewRow(3.0/10,
 			ui.NewCol(1.0/2, syncMetrics),
 			ui.NewCol(1.0/2, messages),
 		),
-		ui.NewRow(1.0/2,
-			ui.NewCol(1.0/4, uptimeMetric),
+		ui.NewRow(2.0/10, timeline),
+		ui.NewRow(4.0/10,
+			ui.NewCol(1.0/4,
+				ui.NewRow(1.0/3, uptimeMetric),
+				ui.NewRow(2.0/3, uptimeBarChart),
+			),
 			ui.NewCol(1.0/4,
 				ui.NewRow(.9/3, slg),
 				ui.NewRow(.9/3, lc),
@@ -398,80 +1112,270 @@ func NewGUI(config GUIConfig) (*GUI, error) {
 		),
 	)
 
+	// overlay for editing the monitoring interval and
+	// autohealing thresholds, shown on top of the grid
+	thresholdOverlay := widgets.NewParagraph()
+	thresholdOverlay.Title = "Edit Thresholds"
+	thresholdOverlay.BorderStyle.Fg = ui.ColorYellow
+	thresholdOverlayVisible := false
+
+	// setup function to call to draw the grid
+	// and the overlay on top of it (if shown)
+	render := func() {
+		ui.Render(grid)
+
+		if !thresholdOverlayVisible {
+			return
+		}
+
+		gridRect := grid.GetRect()
+		width, height := 70, len(thresholdFields)+5
+
+		if width > gridRect.Dx() {
+			width = gridRect.Dx()
+		}
+
+		left, top := (gridRect.Dx()-width)/2, (gridRect.Dy()-height)/2
+		thresholdOverlay.SetRect(left, top, left+width, top+height)
+
+		ui.Render(thresholdOverlay)
+	}
+
+	// setup functions to call whenever the user
+	// opens, edits or closes the threshold editor
+	showThresholdEditor := func(text string) {
+		thresholdOverlay.Text = text
+		thresholdOverlayVisible = true
+		render()
+	}
+
+	hideThresholdEditor := func() {
+		thresholdOverlayVisible = false
+		ui.Clear()
+		render()
+	}
+
 	// setup function to call whenever
 	// there is new data
-	draw := func(count int, paragraph string) {
-		slg.Sparklines[0].Data = sparklineData[:30+count%50]
-		slg.Sparklines[1].Data = sparklineData[:35+count%50]
-		lc.Data[0] = sinData[count/2%220:]
-		lc2.Data[0] = sinData[2*count%220:]
-		bc.Data = barchartData[count/2%10:]
+	draw := func(paragraph string) {
 		if paragraph != "" {
 			syncMetrics.Text = paragraph
 		}
-		ui.Render(grid)
+		render()
+	}
+
+	// setup function to call whenever
+	// there is a new seconds behind live series
+	updateSecondsBehindLive := func(series []float64) {
+		// line charts need at least two points to render
+		if len(series) < 2 {
+			return
+		}
+
+		lc2.Data[0] = series
+		render()
+	}
+
+	// setup function to call whenever
+	// there is a new block height series
+	updateBlockHeight := func(series []float64) {
+		// line charts need at least two points to render
+		if len(series) < 2 {
+			return
+		}
+
+		lc.Data[0] = series
+		render()
+	}
+
+	// setup function to call whenever there
+	// is a new latency series for a node
+	sparklineColors := []ui.Color{ui.ColorCyan, ui.ColorRed, ui.ColorYellow, ui.ColorMagenta, ui.ColorBlue}
+
+	updateLatency := func(nodeId string, series []float64) {
+		sparkline, exists := latencySparklines[nodeId]
+
+		if !exists {
+			sparkline = widgets.NewSparkline()
+			sparkline.LineColor = sparklineColors[len(latencySparklines)%len(sparklineColors)]
+			sparkline.TitleStyle.Fg = ui.ColorWhite
+
+			latencySparklines[nodeId] = sparkline
+			slg.Sparklines = append(slg.Sparklines, sparkline)
+		}
+
+		// sparklines draw from the oldest point so only
+		// pass as many of the most recent points as fit
+		if width := slg.Inner.Dx(); width > 0 && len(series) > width {
+			series = series[len(series)-width:]
+		}
+
+		sparkline.Data = series
+
+		if len(series) > 0 {
+			sparkline.Title = fmt.Sprintf("%s %.0f", nodeId, series[len(series)-1])
+		}
+
+		render()
+	}
+
+	// setup function to call whenever
+	// the uptime of each node is recalculated
+	updatePerNodeUptime := func(perNodeUptime map[string]float32) {
+		nodeIds := make([]string, 0, len(perNodeUptime))
+
+		for nodeId := range perNodeUptime {
+			nodeIds = append(nodeIds, nodeId)
+		}
+
+		sort.Strings(nodeIds)
+
+		labels := make([]string, 0, len(nodeIds))
+		data := make([]float64, 0, len(nodeIds))
+
+		for _, nodeId := range nodeIds {
+			labels = append(labels, nodeId)
+			data = append(data, float64(perNodeUptime[nodeId]*100))
+		}
+
+		uptimeBarChart.Labels = labels
+		uptimeBarChart.Data = data
+		render()
+	}
+
+	// setup function to call whenever there
+	// is a new block interval distribution
+	updateBlockInterval := func(blockInterval metric.BlockIntervalMetric) {
+		bc.Data = []float64{blockInterval.MinimumSeconds, blockInterval.MeanSeconds, blockInterval.P95Seconds, blockInterval.MaximumSeconds}
+		render()
+	}
+
+	// setup function to call whenever a node
+	// first reports or a different node is selected
+	updateNodeTabs := func(nodeIds []string, selectedIndex int) {
+		tabNames := make([]string, 0, len(nodeIds))
+
+		for i, nodeId := range nodeIds {
+			tabNames = append(tabNames, fmt.Sprintf("%d %s", i+1, ui.TrimString(nodeId, MaxNodeTabNameLength)))
+		}
+
+		nodeTabs.TabNames = tabNames
+		nodeTabs.ActiveTabIndex = selectedIndex
+		render()
+	}
+
+	// setup function to call whenever there are
+	// new events or time passes for the timeline
+	updateTimeline := func(nodeId string, events []TimelineEvent) {
+		timeline.Title = fmt.Sprintf("Timeline %s (last %v)", nodeId, timeline.Window)
+		timeline.Events = events
+		timeline.End = time.Now()
+		render()
 	}
 
 	// setup function to call whenever
 	// the uptime metric needs to be updated
 	updateUptime := func(uptime float32) {
 		uptimeMetric.Percent = int(math.Round(float64(uptime * 100)))
-		ui.Render(grid)
+		render()
 	}
 
 	// setup function to call whenever there
 	// is new debug / log messages to show
-	newMessage := func(message string) {
-		messages.Text = message
-		ui.Render(grid)
+	newMessage := func(entry logging.Entry) {
+		messages.Add(entry)
+		render()
+	}
+
+	// setup functions to call whenever the
+	// user scrolls or pauses the messages
+	renderMessages := func() {
+		messages.Title = "Messages"
+
+		if messages.Paused() {
+			messages.Title = "Messages (paused, PRESS s TO RESUME)"
+		}
+
+		render()
+	}
+
+	scrollMessages := func(pages int) {
+		messages.Scroll(pages * messages.PageSize())
+		renderMessages()
+	}
+
+	toggleMessagesPaused := func() {
+		messages.TogglePause()
+		renderMessages()
 	}
 
 	// show the initial ui to the user
-	ui.Render(grid)
+	render()
 
-	endpoint := NewEndpoint(EndpointConfig{URL: config.KavaURL,
+	kavaEndpoint := endpoint.New(endpoint.EndpointConfig{URL: config.KavaURL,
 		MetricSamplesToKeepPerNode:                 config.MaxMetricSamplesToRetainPerNode,
 		MetricSamplesForSyntheticMetricCalculation: config.MetricSamplesForSyntheticMetricCalculation,
+		SampleStore:         config.SampleStore,
+		DiscardStaleSamples: config.DiscardStaleSamples,
 	})
 
-	collectors := []collect.Collector{}
-
-	for _, collector := range config.MetricCollectors {
-		switch collector {
-		case dconfig.FileMetricCollector:
-			fileCollector, err := collect.NewFileCollector(collect.FileCollectorConfig{})
+	// restore samples from previous runs (if any)
+	err := kavaEndpoint.LoadSamples()
 
-			if err != nil {
-				return nil, err
-			}
+	if err != nil {
+		ui.Close()
 
-			collectors = append(collectors, fileCollector)
-		case dconfig.CloudwatchMetricCollector:
-			cloudwatchConfig := collect.CloudWatchCollectorConfig{
-				Ctx:             context.Background(),
-				AWSRegion:       config.AWSRegion,
-				MetricNamespace: config.MetricNamespace,
-			}
+		return nil, err
+	}
 
-			cloudwatchCollector, err := collect.NewCloudWatchCollector(cloudwatchConfig)
+	collectors, err := NewMetricCollectors(config.MetricCollectorsConfig, config.Logger)
 
-			if err != nil {
-				return nil, err
-			}
+	if err != nil {
+		ui.Close()
 
-			collectors = append(collectors, cloudwatchCollector)
-		}
+		return nil, err
 	}
 
 	return &GUI{
-		refreshRateSeconds: config.RefreshRateSeconds,
-		debugMode:          config.DebugLoggingEnabled,
-		grid:               grid,
-		updateParagraph:    updateParagraph,
-		updateUptimeFunc:   updateUptime,
-		draw:               draw,
-		newMessageFunc:     newMessage,
-		kavaEndpoint:       endpoint,
-		metricCollectors:   collectors,
+		refreshRateSeconds:                    config.RefreshRateSeconds,
+		debugMode:                             config.DebugLoggingEnabled,
+		grid:                                  grid,
+		updateParagraph:                       updateParagraph,
+		updateUptimeFunc:                      updateUptime,
+		updateSecondsBehindLiveFunc:           updateSecondsBehindLive,
+		updateBlockHeightFunc:                 updateBlockHeight,
+		updateLatencyFunc:                     updateLatency,
+		updatePerNodeUptimeFunc:               updatePerNodeUptime,
+		updateBlockIntervalFunc:               updateBlockInterval,
+		updateNodeTabsFunc:                    updateNodeTabs,
+		updateTimelineFunc:                    updateTimeline,
+		latestSyncStatusMetrics:               make(map[string]metric.SyncStatusMetrics),
+		latestNodeGroupRollups:                make(map[string]metric.NodeGroupRollupMetrics),
+		timeline:                              NewTimeline(DefaultMaxTimelineEvents),
+		maxChartPointsPerSeries:               config.MaxChartPointsPerSeries,
+		draw:                                  draw,
+		newMessageFunc:                        newMessage,
+		scrollMessagesFunc:                    scrollMessages,
+		toggleMessagesPausedFunc:              toggleMessagesPaused,
+		showThresholdEditorFunc:               showThresholdEditor,
+		hideThresholdEditorFunc:               hideThresholdEditor,
+		thresholdEditor:                       &ThresholdEditor{},
+		nodeClientControls:                    config.NodeClientControls,
+		nodeClientControl:                     config.NodeClientControl,
+		droppedMessages:                       config.DroppedMessages,
+		alerts:                                config.Alerts,
+		reportedDroppedMessages:               make(map[string]uint64),
+		kavaEndpoint:                          kavaEndpoint,
+		metricCollectors:                      collectors,
+		Logger:                                config.Logger,
+		peerChurnAlertThresholdPerMinute:      config.PeerChurnAlertThresholdPerMinute,
+		blockIntervalP95AlertThresholdSeconds: config.BlockIntervalP95AlertThresholdSeconds,
+		discardStaleSamples:                   config.DiscardStaleSamples,
+		configWarnings:                        config.ConfigWarnings,
+		nodeGroups:                            config.NodeGroups,
+		nodeGroupSyncLatencyToleranceSeconds:  config.NodeGroupSyncLatencyToleranceSeconds,
+		uptimeWindows:                         config.UptimeWindows,
+		uptimeEWMAHalfLife:                    config.UptimeEWMAHalfLife,
+		sloTracker:                            slo.NewTracker(config.SLOs),
 	}, nil
 }
@@ -0,0 +1,114 @@
+package heal
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRestartCapReached = errors.New("autoheal restart cap reached")
+)
+
+// RestartBreakerConfig wraps values
+// for configuring a RestartBreaker
+type RestartBreakerConfig struct {
+	// max number of restarts allowed in any rolling
+	// hour and day, 0 allows unlimited restarts
+	MaxRestartsPerHour int
+	MaxRestartsPerDay  int
+}
+
+// RestartBreaker caps how many times autohealing restarts the node
+// per hour and per day, so a node that is endlessly crash looping
+// isn't restarted forever. Once a cap is hit the breaker trips and
+// refuses all further restarts until it's manually reset
+// RestartBreaker is safe to use across go-routines
+type RestartBreaker struct {
+	maxRestartsPerHour int
+	maxRestartsPerDay  int
+	// times of the restarts allowed in the last day
+	restarts  []time.Time
+	tripped   bool
+	trippedAt time.Time
+	lock      *sync.Mutex
+}
+
+// NewRestartBreaker returns a new RestartBreaker
+// using the specified config
+func NewRestartBreaker(config RestartBreakerConfig) *RestartBreaker {
+	return &RestartBreaker{
+		maxRestartsPerHour: config.MaxRestartsPerHour,
+		maxRestartsPerDay:  config.MaxRestartsPerDay,
+		lock:               &sync.Mutex{},
+	}
+}
+
+// Allow records a restart at now if the breaker isn't tripped and the
+// restart is within the caps, otherwise tripping the breaker (if not
+// already tripped) and returning an error wrapping `ErrRestartCapReached`
+func (rb *RestartBreaker) Allow(now time.Time) error {
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+
+	if rb.tripped {
+		return fmt.Errorf("%w at %s, reset required", ErrRestartCapReached, rb.trippedAt.Format(time.RFC3339))
+	}
+
+	// forget restarts that no longer count towards either cap
+	var restarts []time.Time
+
+	for _, restartedAt := range rb.restarts {
+		if now.Sub(restartedAt) < 24*time.Hour {
+			restarts = append(restarts, restartedAt)
+		}
+	}
+
+	rb.restarts = restarts
+
+	var restartsInLastHour int
+
+	for _, restartedAt := range rb.restarts {
+		if now.Sub(restartedAt) < time.Hour {
+			restartsInLastHour++
+		}
+	}
+
+	if rb.maxRestartsPerHour > 0 && restartsInLastHour >= rb.maxRestartsPerHour {
+		rb.tripped = true
+		rb.trippedAt = now
+
+		return fmt.Errorf("%w, restarted %d times in the last hour", ErrRestartCapReached, restartsInLastHour)
+	}
+
+	if rb.maxRestartsPerDay > 0 && len(rb.restarts) >= rb.maxRestartsPerDay {
+		rb.tripped = true
+		rb.trippedAt = now
+
+		return fmt.Errorf("%w, restarted %d times in the last day", ErrRestartCapReached, len(rb.restarts))
+	}
+
+	rb.restarts = append(rb.restarts, now)
+
+	return nil
+}
+
+// Tripped returns whether the breaker is tripped
+// and refusing restarts until it's reset
+func (rb *RestartBreaker) Tripped() bool {
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+
+	return rb.tripped
+}
+
+// Reset resets the breaker, allowing restarts again
+// and forgetting all previously allowed restarts
+func (rb *RestartBreaker) Reset() {
+	rb.lock.Lock()
+	defer rb.lock.Unlock()
+
+	rb.tripped = false
+	rb.restarts = nil
+}
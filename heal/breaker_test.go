@@ -0,0 +1,64 @@
+package heal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartBreakerTripsAtHourlyCapUntilReset(t *testing.T) {
+	breaker := NewRestartBreaker(RestartBreakerConfig{
+		MaxRestartsPerHour: 2,
+	})
+
+	now := time.Now()
+
+	assert.Nil(t, breaker.Allow(now))
+	assert.Nil(t, breaker.Allow(now.Add(10*time.Minute)))
+
+	err := breaker.Allow(now.Add(20 * time.Minute))
+
+	assert.True(t, errors.Is(err, ErrRestartCapReached))
+	assert.True(t, breaker.Tripped())
+
+	// stays tripped even once the earlier restarts are over an hour old
+	err = breaker.Allow(now.Add(2 * time.Hour))
+
+	assert.True(t, errors.Is(err, ErrRestartCapReached))
+
+	breaker.Reset()
+
+	assert.False(t, breaker.Tripped())
+	assert.Nil(t, breaker.Allow(now.Add(2*time.Hour)))
+}
+
+func TestRestartBreakerTripsAtDailyCap(t *testing.T) {
+	breaker := NewRestartBreaker(RestartBreakerConfig{
+		MaxRestartsPerHour: 2,
+		MaxRestartsPerDay:  3,
+	})
+
+	now := time.Now()
+
+	assert.Nil(t, breaker.Allow(now))
+	assert.Nil(t, breaker.Allow(now.Add(2*time.Hour)))
+	assert.Nil(t, breaker.Allow(now.Add(4*time.Hour)))
+
+	err := breaker.Allow(now.Add(6 * time.Hour))
+
+	assert.True(t, errors.Is(err, ErrRestartCapReached))
+}
+
+func TestRestartBreakerWithoutCapsNeverTrips(t *testing.T) {
+	breaker := NewRestartBreaker(RestartBreakerConfig{})
+
+	now := time.Now()
+
+	for i := 0; i < 100; i++ {
+		assert.Nil(t, breaker.Allow(now.Add(time.Duration(i)*time.Second)))
+	}
+
+	assert.False(t, breaker.Tripped())
+}
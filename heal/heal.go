@@ -1,8 +1,9 @@
 package heal
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os/exec"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -10,31 +11,98 @@ import (
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/autoscaling"
 	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
 )
 
-// AwsDoctor is a doctor that is capable
-// of healing a kava node running in AWS
+const (
+	// autoscaling health status of an instance that is healthy
+	HealthyHealthStatus = "Healthy"
+	// how long to keep trying to place a host back in service
+	// after the heal that placed it on standby is aborted
+	StandbyExitTimeout = 2 * time.Minute
+	// how long to wait between attempts to place a host back in service
+	standbyExitRetryInterval = 10 * time.Second
+)
+
+var (
+	ErrInsufficientSiblingCapacity = errors.New("too few other healthy in service instances to place host on standby")
+	ErrHealAborted                 = errors.New("heal aborted")
+)
+
+// Healer is capable of healing a kava node
+// that has fallen too far behind live
+type Healer interface {
+	// HealOutOfSyncNode attempts to heal the node until
+	// it has caught back up to live or the context is cancelled
+	HealOutOfSyncNode(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig)
+}
+
+// AwsDoctor implements the Healer interface,
+// healing a kava node running in AWS
 type AwsDoctor struct {
 	autoscalingClient *autoscaling.AutoScaling
 	instanceId        string
-	kava              *kava.Client
+	// id of the last incident standby was refused for due to
+	// insufficient sibling capacity, to alert once per incident
+	refusedStandbyIncidentId string
 }
 
-var (
-	awsDoctor        *AwsDoctor
-	initErrorMessage *string
-)
-
 // HealerConfig wraps values for use by one or more
 // runs of one or more healer routines
 type HealerConfig struct {
 	AutohealSyncToLiveToleranceSeconds int
+	// url and id of the node being healed
+	// for use in published heal events
+	EndpointURL string
+	NodeId      string
+	// id of the incident the node is being healed for,
+	// used for tracking escalation of heal strategies
+	IncidentId string
+	// whether systemd is repeatedly restarting the blockchain
+	// service, in which case restarting it again won't help
+	ServiceRestartLoop bool
+	// how far behind live the node was when healing started
+	SecondsBehindLive int64
+	// whether most of the node's peers were low quality
+	// (e.g. sending it little data) when last sampled
+	PoorPeering bool
+	// minimum number of other healthy in service instances that must
+	// remain in the autoscaling group for the host to be placed on
+	// standby, 0 allows placing the last healthy instance on standby
+	MinHealthyInServiceInstances int
+	// optional publisher to send heal events to
+	IncidentPublisher incident.Publisher
+}
+
+// publishHealEvent publishes an event for a heal action taken
+// using the configured incident publisher (if any)
+func publishHealEvent(logger *logging.Logger, healerConfig HealerConfig, healAction string, succeeded bool, message string) {
+	if healerConfig.IncidentPublisher == nil {
+		return
+	}
+
+	event := incident.Event{
+		Type:        incident.HealActionEventType,
+		HealAction:  healAction,
+		Succeeded:   succeeded,
+		NodeId:      healerConfig.NodeId,
+		EndpointURL: healerConfig.EndpointURL,
+		Message:     message,
+		OccurredAt:  time.Now(),
+	}
+
+	err := healerConfig.IncidentPublisher.Publish(event)
+
+	if err != nil {
+		logger.Errorf("error %s publishing heal event %+v", err, event)
+	}
 }
 
 // GetNodeAutoscalingState gets the autoscaling state of the node based off it's instance id
 // returning the state and error (if any).
 func GetNodeAutoscalingState(instanceId string, client *autoscaling.AutoScaling) (string, error) {
-	autoscalingInstances, err := awsDoctor.autoscalingClient.DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
+	autoscalingInstances, err := client.DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
 		InstanceIds: []*string{
 			aws.String(instanceId),
 		},
@@ -51,35 +119,82 @@ func GetNodeAutoscalingState(instanceId string, client *autoscaling.AutoScaling)
 	return *autoscalingInstances.AutoScalingInstances[0].LifecycleState, nil
 }
 
-// StandbyNodeUntilCaughtUp will keep the ec2 instance the kava node is
+// countOtherHealthyInServiceInstances returns the number of healthy in service
+// instances in the autoscaling group other than the instance with the
+// specified id, and error (if any)
+func countOtherHealthyInServiceInstances(client *autoscaling.AutoScaling, autoscalingGroupName string, instanceId string) (int, error) {
+	autoscalingGroups, err := client.DescribeAutoScalingGroups(&autoscaling.DescribeAutoScalingGroupsInput{
+		AutoScalingGroupNames: []*string{
+			aws.String(autoscalingGroupName),
+		},
+	})
+
+	if err != nil {
+		return 0, fmt.Errorf("error %s describing autoscaling group %s", err, autoscalingGroupName)
+	}
+
+	if len(autoscalingGroups.AutoScalingGroups) != 1 {
+		return 0, fmt.Errorf("expected exactly one autoscaling group with name %s, got %+v", autoscalingGroupName, autoscalingGroups.AutoScalingGroups)
+	}
+
+	return countHealthyInServiceInstances(autoscalingGroups.AutoScalingGroups[0].Instances, instanceId), nil
+}
+
+// countHealthyInServiceInstances returns the number of healthy in service
+// instances excluding the instance with the specified id
+func countHealthyInServiceInstances(instances []*autoscaling.Instance, excludedInstanceId string) int {
+	var healthyInService int
+
+	for _, instance := range instances {
+		if aws.StringValue(instance.InstanceId) == excludedInstanceId {
+			continue
+		}
+
+		if aws.StringValue(instance.LifecycleState) == autoscaling.LifecycleStateInService && aws.StringValue(instance.HealthStatus) == HealthyHealthStatus {
+			healthyInService++
+		}
+	}
+
+	return healthyInService
+}
+
+// HealOutOfSyncNode will keep the ec2 instance the kava node is
 // on in standby (to shift resources that would be consumed by an api node
 // serving production client requests towards synching up to live faster)
-// until it catches back up.
-func StandbyNodeUntilCaughtUp(logMessages chan<- string, kavaClient *kava.Client, healerConfig HealerConfig) {
-	if initErrorMessage != nil {
-		logMessages <- fmt.Sprintf("healer init failed with error %s, skipping attempt to heal via StandbyNodeUntilCaughtUp", *initErrorMessage)
-		return
-	}
+// until it catches back up or the context is cancelled, in which case
+// the host is placed back in service (trying for up to StandbyExitTimeout)
+// so it isn't left out of service with the desired capacity of its
+// autoscaling group lowered.
+func (ad *AwsDoctor) HealOutOfSyncNode(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) {
+	err := ad.healOutOfSyncNode(ctx, logger, kavaClient, healerConfig)
 
-	awsDoctor.kava = kavaClient
+	if err != nil {
+		logger.Errorf("HealOutOfSyncNode: %s", err)
+	}
+}
 
-	awsInstanceId := awsDoctor.instanceId
+// healOutOfSyncNode heals the node as described by HealOutOfSyncNode
+// returning error (if any) if the host couldn't be placed on standby
+// (e.g. `ErrInsufficientSiblingCapacity` when too few other instances
+// would be left in service) and no other healing was attempted
+// if the context is cancelled before the node caught up and was placed
+// back in service an error wrapping `ErrHealAborted` is returned
+func (ad *AwsDoctor) healOutOfSyncNode(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	awsInstanceId := ad.instanceId
 
 	// check to see if the host is in service
-	autoscalingInstances, err := awsDoctor.autoscalingClient.DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
+	autoscalingInstances, err := ad.autoscalingClient.DescribeAutoScalingInstances(&autoscaling.DescribeAutoScalingInstancesInput{
 		InstanceIds: []*string{
 			aws.String(awsInstanceId),
 		},
 	})
 
 	if err != nil {
-		logMessages <- fmt.Sprintf("StandbyNodeUntilCaughtUp: error %s checking autoscaling state for instance %s", err, awsInstanceId)
-		return
+		return fmt.Errorf("error %s checking autoscaling state for instance %s", err, awsInstanceId)
 	}
 
 	if len(autoscalingInstances.AutoScalingInstances) != 1 {
-		logMessages <- fmt.Sprintf("StandbyNodeUntilCaughtUp: expected exactly one instance with id %s, got %+v", awsInstanceId, autoscalingInstances.AutoScalingInstances)
-		return
+		return fmt.Errorf("expected exactly one instance with id %s, got %+v", awsInstanceId, autoscalingInstances.AutoScalingInstances)
 	}
 
 	autoscalingGroupName := autoscalingInstances.AutoScalingInstances[0].AutoScalingGroupName
@@ -89,7 +204,29 @@ func StandbyNodeUntilCaughtUp(logMessages chan<- string, kavaClient *kava.Client
 	// if it's in service so it won't get any more requests
 	// until it syncs back to live
 	if *autoscalingInstances.AutoScalingInstances[0].LifecycleState == autoscaling.LifecycleStateInService {
-		state, err := awsDoctor.autoscalingClient.EnterStandby(&autoscaling.EnterStandbyInput{
+		// don't take the last healthy api node(s) out of service
+		// as a lagging node is better than no node at all
+		if healerConfig.MinHealthyInServiceInstances > 0 {
+			otherHealthyInService, err := countOtherHealthyInServiceInstances(ad.autoscalingClient, *autoscalingGroupName, awsInstanceId)
+
+			if err != nil {
+				return err
+			}
+
+			if otherHealthyInService < healerConfig.MinHealthyInServiceInstances {
+				message := fmt.Sprintf("refusing to place host %s on standby, only %d other healthy in service instances in autoscaling group %s, minimum %d", awsInstanceId, otherHealthyInService, *autoscalingGroupName, healerConfig.MinHealthyInServiceInstances)
+
+				if healerConfig.IncidentId == "" || healerConfig.IncidentId != ad.refusedStandbyIncidentId {
+					ad.refusedStandbyIncidentId = healerConfig.IncidentId
+
+					publishHealEvent(logger, healerConfig, incident.EnterStandbyHealAction, false, message)
+				}
+
+				return fmt.Errorf("%w: %s", ErrInsufficientSiblingCapacity, message)
+			}
+		}
+
+		state, err := ad.autoscalingClient.EnterStandby(&autoscaling.EnterStandbyInput{
 			AutoScalingGroupName: autoscalingGroupName,
 			InstanceIds: []*string{
 				aws.String(awsInstanceId),
@@ -98,31 +235,35 @@ func StandbyNodeUntilCaughtUp(logMessages chan<- string, kavaClient *kava.Client
 		})
 
 		if err != nil {
-			logMessages <- fmt.Sprintf("StandbyNodeUntilCaughtUp: error %s placing host on standby", err)
+			publishHealEvent(logger, healerConfig, incident.EnterStandbyHealAction, false, fmt.Sprintf("error %s placing host %s on standby", err, awsInstanceId))
 
-			return
+			return fmt.Errorf("error %s placing host on standby", err)
 		}
 
 		placedOnStandby = true
 
-		logMessages <- fmt.Sprintf("StandbyNodeUntilCaughtUp: host entered standby state with autoscaling group %+v", state.Activities)
+		logger.Infof("HealOutOfSyncNode: host entered standby state with autoscaling group %+v", state.Activities)
+
+		publishHealEvent(logger, healerConfig, incident.EnterStandbyHealAction, true, fmt.Sprintf("placed host %s on standby until it catches up", awsInstanceId))
 	} else {
-		logMessages <- "StandbyNodeUntilCaughtUp: host is not currently in service, not moving to standby"
+		logger.Debug("HealOutOfSyncNode: host is not currently in service, not moving to standby")
 	}
 
 	if *autoscalingInstances.AutoScalingInstances[0].LifecycleState == autoscaling.LifecycleStateStandby {
-		logMessages <- "StandbyNodeUntilCaughtUp: host was already on standby, will place in service once caught up"
+		logger.Debug("HealOutOfSyncNode: host was already on standby, will place in service once caught up")
 		placedOnStandby = true
 	}
 
 	// wait until the kava process catches back up to live
 	for {
-		kavaStatus, err := awsDoctor.kava.GetNodeState()
+		kavaStatus, err := kavaClient.GetNodeState()
 
 		if err != nil {
-			logMessages <- fmt.Sprintf("StandbyNodeUntilCaughtUp: error %s attempting to get kava status, sleeping for a minute before retrying", err)
+			logger.Errorf("HealOutOfSyncNode: error %s attempting to get kava status, sleeping for a minute before retrying", err)
 
-			time.Sleep(1 * time.Minute)
+			if !sleepUnlessCancelled(ctx, 1*time.Minute) {
+				return ad.abortHeal(logger, healerConfig, autoscalingGroupName, placedOnStandby)
+			}
 
 			continue
 		}
@@ -132,80 +273,158 @@ func StandbyNodeUntilCaughtUp(logMessages chan<- string, kavaClient *kava.Client
 		secondsBehindLive = int64(time.Since(currentSyncTime).Seconds())
 
 		if secondsBehindLive <= int64(healerConfig.AutohealSyncToLiveToleranceSeconds) {
-			logMessages <- (fmt.Sprintf("StandbyNodeUntilCaughtUp: node caught back up to %d seconds behind current time", healerConfig.AutohealSyncToLiveToleranceSeconds))
+			logger.Infof("HealOutOfSyncNode: node caught back up to %d seconds behind current time", healerConfig.AutohealSyncToLiveToleranceSeconds)
 			break
 		}
 
-		logMessages <- "StandbyNodeUntilCaughtUp: node is still catching up"
+		logger.Debug("HealOutOfSyncNode: node is still catching up")
 
-		time.Sleep(1 * time.Minute)
+		if !sleepUnlessCancelled(ctx, 1*time.Minute) {
+			return ad.abortHeal(logger, healerConfig, autoscalingGroupName, placedOnStandby)
+		}
 	}
 
 	// put the node back in service
 	if placedOnStandby {
-		var exitedStandby bool
-		for !exitedStandby {
-			currentState, err := GetNodeAutoscalingState(awsInstanceId, awsDoctor.autoscalingClient)
+		err := ad.exitStandby(ctx, logger, healerConfig, autoscalingGroupName, fmt.Sprintf("placed host %s back in service after catching up", awsInstanceId))
 
-			if err != nil {
-				logMessages <- err.Error()
+		if err != nil {
+			return ad.abortHeal(logger, healerConfig, autoscalingGroupName, placedOnStandby)
+		}
+	}
 
-				continue
-			}
+	logger.Info("HealOutOfSyncNode: node healed successfully by doctor")
 
-			if currentState == autoscaling.LifecycleStateInService {
-				logMessages <- "StandbyNodeUntilCaughtUp: host is no longer on standby"
+	return nil
+}
 
-				exitedStandby = true
+// abortHeal places the host back in service if the heal placed it on
+// standby, trying for up to StandbyExitTimeout as the heal's context
+// has been cancelled (e.g. the doctor is shutting down), returning an
+// error wrapping `ErrHealAborted`
+func (ad *AwsDoctor) abortHeal(logger *logging.Logger, healerConfig HealerConfig, autoscalingGroupName *string, placedOnStandby bool) error {
+	if !placedOnStandby {
+		return fmt.Errorf("%w before node caught up", ErrHealAborted)
+	}
 
-				break
-			}
+	logger.Warnf("HealOutOfSyncNode: aborting heal, placing host %s back in service", ad.instanceId)
+
+	ctx, cancel := context.WithTimeout(context.Background(), StandbyExitTimeout)
+	defer cancel()
+
+	err := ad.exitStandby(ctx, logger, healerConfig, autoscalingGroupName, fmt.Sprintf("placed host %s back in service as the heal was aborted", ad.instanceId))
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.ExitStandbyHealAction, false, fmt.Sprintf("%s placing host %s back in service after the heal was aborted, it must be placed back in service manually", err, ad.instanceId))
+
+		return fmt.Errorf("%w, then %s placing host %s back in service", ErrHealAborted, err, ad.instanceId)
+	}
 
-			state, err := awsDoctor.autoscalingClient.ExitStandby(&autoscaling.ExitStandbyInput{
+	return fmt.Errorf("%w, placed host %s back in service", ErrHealAborted, ad.instanceId)
+}
+
+// exitStandby places the host back in service, retrying every
+// standbyExitRetryInterval until it's in service or the context
+// is cancelled, publishing a heal event with the message once
+// it exits standby, returning error (if any) if the context was
+// cancelled before the host was back in service
+func (ad *AwsDoctor) exitStandby(ctx context.Context, logger *logging.Logger, healerConfig HealerConfig, autoscalingGroupName *string, message string) error {
+	for {
+		currentState, err := ad.getNodeAutoscalingState(ctx)
+
+		if err == nil && currentState == autoscaling.LifecycleStateInService {
+			logger.Debug("HealOutOfSyncNode: host is no longer on standby")
+
+			return nil
+		}
+
+		if err == nil {
+			var state *autoscaling.ExitStandbyOutput
+
+			state, err = ad.autoscalingClient.ExitStandbyWithContext(ctx, &autoscaling.ExitStandbyInput{
 				AutoScalingGroupName: autoscalingGroupName,
 				InstanceIds: []*string{
-					aws.String(awsInstanceId),
+					aws.String(ad.instanceId),
 				},
 			})
 
-			// keep trying if we encountered an error
-			if err != nil {
-				logMessages <- fmt.Sprintf("StandbyNodeUntilCaughtUp: error %s attempting to exit standby", err)
+			if err == nil {
+				logger.Infof("HealOutOfSyncNode: host exited standby %+v", state.Activities)
 
-				continue
+				publishHealEvent(logger, healerConfig, incident.ExitStandbyHealAction, true, message)
+
+				return nil
 			}
+		}
 
-			logMessages <- fmt.Sprintf("StandbyNodeUntilCaughtUp: host exited standby %+v", state.Activities)
+		// keep trying if we encountered an error
+		logger.Errorf("HealOutOfSyncNode: error %s attempting to exit standby, retrying in %v", err, standbyExitRetryInterval)
 
-			exitedStandby = true
+		if !sleepUnlessCancelled(ctx, standbyExitRetryInterval) {
+			return fmt.Errorf("error %s attempting to exit standby", err)
 		}
 	}
-
-	logMessages <- "StandbyNodeUntilCaughtUp: node healed successfully by doctor"
 }
 
-// RestartSystemdService restarts a systemd service by name
-// returning error (if any)
-func RestartSystemdService(serviceName string) error {
-	cmd := exec.Command("bash", "-c", fmt.Sprintf("sudo systemctl restart %s", serviceName))
-	output, err := cmd.CombinedOutput()
+// getNodeAutoscalingState returns the autoscaling lifecycle
+// state of the host as described by GetNodeAutoscalingState
+func (ad *AwsDoctor) getNodeAutoscalingState(ctx context.Context) (string, error) {
+	autoscalingInstances, err := ad.autoscalingClient.DescribeAutoScalingInstancesWithContext(ctx, &autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: []*string{
+			aws.String(ad.instanceId),
+		},
+	})
+
 	if err != nil {
-		return fmt.Errorf("error %s starting %s service output %s", err, serviceName, string(output))
+		return "", fmt.Errorf("error %s checking autoscaling state for instance %s", err, ad.instanceId)
 	}
 
-	return nil
+	if len(autoscalingInstances.AutoScalingInstances) != 1 {
+		return "", fmt.Errorf("expected exactly one instance with id %s, got %+v", ad.instanceId, autoscalingInstances.AutoScalingInstances)
+	}
+
+	return *autoscalingInstances.AutoScalingInstances[0].LifecycleState, nil
+}
+
+// sleepUnlessCancelled sleeps for the specified duration
+// returning false if the context was cancelled before then
+func sleepUnlessCancelled(ctx context.Context, duration time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(duration):
+		return true
+	}
 }
 
 // NewAwsDoctor returns a new AwsDoctor for healing a kava node
 // that is running in aws, and error (if any)
+// NewAwsDoctor queries the ec2 metadata service so should only
+// be called when autohealing is enabled and the node runs in aws
 func NewAwsDoctor() (*AwsDoctor, error) {
+	awsSession, instanceId, err := newInstanceAWSSession()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &AwsDoctor{
+		instanceId:        instanceId,
+		autoscalingClient: autoscaling.New(awsSession),
+	}, nil
+}
+
+// newInstanceAWSSession returns an aws session for the region of
+// the ec2 instance the doctor is running on, along with the
+// id of the instance and error (if any)
+func newInstanceAWSSession() (*session.Session, string, error) {
 	// gather information about the host needed by the healer
 
 	// create a new client using the default credential chain provider
 	awsSession, err := session.NewSession()
 
 	if err != nil {
-		return nil, fmt.Errorf("error %s creating valid aws session", err)
+		return nil, "", fmt.Errorf("error %s creating valid aws session", err)
 	}
 
 	ec2MetadataClient := ec2metadata.New(awsSession)
@@ -214,7 +433,7 @@ func NewAwsDoctor() (*AwsDoctor, error) {
 	eC2IdentityDocument, err := ec2MetadataClient.GetInstanceIdentityDocument()
 
 	if err != nil {
-		return nil, fmt.Errorf("error %s getting ec2 identity document for host to sync from", err)
+		return nil, "", fmt.Errorf("error %s getting ec2 identity document for host to sync from", err)
 	}
 
 	// re-initialize aws session using the region of the instance
@@ -227,27 +446,8 @@ func NewAwsDoctor() (*AwsDoctor, error) {
 	)
 
 	if err != nil {
-		return nil, fmt.Errorf("error %s creating valid aws session using region %s", err, instanceAWSRegion)
-	}
-
-	autoscalingClient := autoscaling.New(awsSession)
-
-	return &AwsDoctor{
-		instanceId:        eC2IdentityDocument.InstanceID,
-		autoscalingClient: autoscalingClient,
-	}, nil
-}
-
-// init creates a singleton instance of the healer that is
-// safe for concurrent usage across multiple go-routines
-func init() {
-	healer, err := NewAwsDoctor()
-
-	if err != nil {
-		initErrorMessage = aws.String(err.Error())
-
-		return
+		return nil, "", fmt.Errorf("error %s creating valid aws session using region %s", err, instanceAWSRegion)
 	}
 
-	awsDoctor = healer
+	return awsSession, eC2IdentityDocument.InstanceID, nil
 }
@@ -0,0 +1,37 @@
+package heal
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountHealthyInServiceInstancesExcludesSelf(t *testing.T) {
+	instances := []*autoscaling.Instance{
+		{
+			InstanceId:     aws.String("i-self"),
+			LifecycleState: aws.String(autoscaling.LifecycleStateInService),
+			HealthStatus:   aws.String(HealthyHealthStatus),
+		},
+		{
+			InstanceId:     aws.String("i-healthy"),
+			LifecycleState: aws.String(autoscaling.LifecycleStateInService),
+			HealthStatus:   aws.String(HealthyHealthStatus),
+		},
+		{
+			InstanceId:     aws.String("i-unhealthy"),
+			LifecycleState: aws.String(autoscaling.LifecycleStateInService),
+			HealthStatus:   aws.String("Unhealthy"),
+		},
+		{
+			InstanceId:     aws.String("i-standby"),
+			LifecycleState: aws.String(autoscaling.LifecycleStateStandby),
+			HealthStatus:   aws.String(HealthyHealthStatus),
+		},
+	}
+
+	assert.Equal(t, 1, countHealthyInServiceInstances(instances, "i-self"))
+	assert.Equal(t, 2, countHealthyInServiceInstances(instances, "i-standby"))
+}
@@ -0,0 +1,169 @@
+package heal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	// actions a service helper performs on the service
+	RestartServiceAction = "restart"
+	StopServiceAction    = "stop"
+	StartServiceAction   = "start"
+
+	// how long to wait to connect to the service helper
+	serviceHelperDialTimeout = 5 * time.Second
+	// how long to wait for a client to send its request
+	serviceHelperRequestTimeout = 10 * time.Second
+	// how long to wait for the service helper to
+	// perform the action, restarts can be slow
+	serviceHelperActionTimeout = 5 * time.Minute
+)
+
+// serviceHelperRequest is sent by the doctor
+// to ask the service helper to perform an action
+type serviceHelperRequest struct {
+	Action string `json:"action"`
+}
+
+// serviceHelperResponse is sent by the service helper
+// once it has performed (or failed to perform) an action
+type serviceHelperResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+// helperServiceManager implements the ServiceManager interface,
+// asking a privileged service helper listening on a unix socket
+// to manage the service so the doctor doesn't need to be allowed
+// to manage it (e.g. with sudo)
+type helperServiceManager struct {
+	serviceName string
+	socketPath  string
+}
+
+func (hm *helperServiceManager) Restart() error {
+	return hm.request(RestartServiceAction)
+}
+
+func (hm *helperServiceManager) Stop() error {
+	return hm.request(StopServiceAction)
+}
+
+func (hm *helperServiceManager) Start() error {
+	return hm.request(StartServiceAction)
+}
+
+// request asks the service helper to perform the action, returning
+// error (if any) connecting to the helper or reported by the helper
+func (hm *helperServiceManager) request(action string) error {
+	conn, err := net.DialTimeout("unix", hm.socketPath, serviceHelperDialTimeout)
+
+	if err != nil {
+		return fmt.Errorf("error %s connecting to service helper at %s to %s %s service", err, hm.socketPath, action, hm.serviceName)
+	}
+
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(serviceHelperActionTimeout))
+
+	err = json.NewEncoder(conn).Encode(serviceHelperRequest{
+		Action: action,
+	})
+
+	if err != nil {
+		return fmt.Errorf("error %s sending request to %s %s service to service helper", err, action, hm.serviceName)
+	}
+
+	var response serviceHelperResponse
+
+	err = json.NewDecoder(conn).Decode(&response)
+
+	if err != nil {
+		return fmt.Errorf("error %s reading service helper response to %s %s service", err, action, hm.serviceName)
+	}
+
+	if response.Error != "" {
+		return fmt.Errorf("service helper error %s attempting to %s %s service", response.Error, action, hm.serviceName)
+	}
+
+	return nil
+}
+
+// ServeServiceHelper accepts connections on the listener until the
+// context is cancelled, performing the action requested over each
+// connection using the service manager, so a privileged helper can
+// manage the service on behalf of an unprivileged doctor. Requests
+// are handled one at a time so actions on the service never overlap.
+// Returns error (if any) accepting connections
+func ServeServiceHelper(ctx context.Context, listener net.Listener, serviceManager ServiceManager, logger *logging.Logger) error {
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return err
+		}
+
+		handleServiceHelperRequest(conn, serviceManager, logger)
+	}
+}
+
+// handleServiceHelperRequest reads the request from the connection,
+// performs the requested action and responds with its result
+func handleServiceHelperRequest(conn net.Conn, serviceManager ServiceManager, logger *logging.Logger) {
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(serviceHelperRequestTimeout))
+
+	var request serviceHelperRequest
+
+	err := json.NewDecoder(conn).Decode(&request)
+
+	if err != nil {
+		logger.Errorf("error %s reading service helper request", err)
+
+		return
+	}
+
+	switch request.Action {
+	case RestartServiceAction:
+		err = serviceManager.Restart()
+	case StopServiceAction:
+		err = serviceManager.Stop()
+	case StartServiceAction:
+		err = serviceManager.Start()
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupportedServiceAction, request.Action)
+	}
+
+	var response serviceHelperResponse
+
+	if err != nil {
+		logger.Errorf("error %s performing %s action", err, request.Action)
+
+		response.Error = err.Error()
+	} else {
+		logger.Infof("performed %s action", request.Action)
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(serviceHelperRequestTimeout))
+
+	err = json.NewEncoder(conn).Encode(response)
+
+	if err != nil {
+		logger.Errorf("error %s responding to service helper request", err)
+	}
+}
@@ -0,0 +1,84 @@
+package heal
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServiceManager records each action performed on the service
+type fakeServiceManager struct {
+	actions []string
+	err     error
+}
+
+func (fm *fakeServiceManager) Restart() error {
+	fm.actions = append(fm.actions, RestartServiceAction)
+
+	return fm.err
+}
+
+func (fm *fakeServiceManager) Stop() error {
+	fm.actions = append(fm.actions, StopServiceAction)
+
+	return fm.err
+}
+
+func (fm *fakeServiceManager) Start() error {
+	fm.actions = append(fm.actions, StartServiceAction)
+
+	return fm.err
+}
+
+func TestServiceHelperPerformsRequestedActions(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "helper.sock")
+
+	listener, err := net.Listen("unix", socketPath)
+
+	assert.Nil(t, err)
+
+	helped := &fakeServiceManager{}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error)
+
+	go func() {
+		served <- ServeServiceHelper(ctx, listener, helped, newTestLogger())
+	}()
+
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		ServiceName:  "kava",
+		HelperSocket: socketPath,
+	})
+
+	assert.Nil(t, err)
+	assert.Nil(t, serviceManager.Stop())
+	assert.Nil(t, serviceManager.Start())
+	assert.Nil(t, serviceManager.Restart())
+	assert.Equal(t, []string{StopServiceAction, StartServiceAction, RestartServiceAction}, helped.actions)
+
+	// errors performing the action are returned to the doctor
+	helped.err = errors.New("unit kava.service not found")
+
+	err = serviceManager.Restart()
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unit kava.service not found")
+
+	cancel()
+
+	assert.Nil(t, <-served)
+}
+
+func TestHelperServiceManagerFailsWithoutHelper(t *testing.T) {
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		ServiceName:  "kava",
+		HelperSocket: filepath.Join(t.TempDir(), "missing.sock"),
+	})
+
+	assert.Nil(t, err)
+	assert.NotNil(t, serviceManager.Restart())
+}
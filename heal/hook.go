@@ -0,0 +1,116 @@
+package heal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	DefaultExecHookTimeout = 60 * time.Second
+)
+
+// execHookInput is the json passed on stdin to
+// the command run by the exec-hook strategy
+type execHookInput struct {
+	IncidentId         string `json:"incident_id"`
+	NodeId             string `json:"node_id"`
+	EndpointURL        string `json:"endpoint_url"`
+	SecondsBehindLive  int64  `json:"seconds_behind_live"`
+	ServiceRestartLoop bool   `json:"service_restart_loop"`
+}
+
+// execHookStrategy implements the Strategy interface, running an
+// operator configured command (e.g. a script that clears the node's
+// address book and rotates its peers) to heal the node, so custom
+// remediation can be escalated through alongside the built in strategies
+type execHookStrategy struct {
+	command []string
+	timeout time.Duration
+	blocked BlockedFunc
+}
+
+func newExecHookStrategy(config StrategyConfig) (Strategy, error) {
+	if len(config.ExecHookCommand) == 0 {
+		return nil, fmt.Errorf("a command is required for the %s heal strategy", ExecHookStrategyName)
+	}
+
+	timeout := config.ExecHookTimeout
+
+	if timeout <= 0 {
+		timeout = DefaultExecHookTimeout
+	}
+
+	return &execHookStrategy{
+		command: config.ExecHookCommand,
+		timeout: timeout,
+		blocked: config.Blocked,
+	}, nil
+}
+
+func (eh *execHookStrategy) Name() string {
+	return ExecHookStrategyName
+}
+
+// Heal runs the command with the node being healed as json on stdin,
+// killing it if it runs past the timeout, and treats the command
+// exiting with a non zero status as the heal failing
+func (eh *execHookStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	if err := eh.blocked.check(); err != nil {
+		return err
+	}
+
+	input, err := json.Marshal(execHookInput{
+		IncidentId:         healerConfig.IncidentId,
+		NodeId:             healerConfig.NodeId,
+		EndpointURL:        healerConfig.EndpointURL,
+		SecondsBehindLive:  healerConfig.SecondsBehindLive,
+		ServiceRestartLoop: healerConfig.ServiceRestartLoop,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, eh.timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, eh.command[0], eh.command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	command := strings.Join(eh.command, " ")
+
+	err = cmd.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%s killed after running for %v", command, eh.timeout)
+
+		publishHealEvent(logger, healerConfig, incident.ExecHookHealAction, false, err.Error())
+
+		return err
+	}
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.ExecHookHealAction, false, fmt.Sprintf("error %s running %s output %s", err, command, strings.TrimSpace(output.String())))
+
+		return fmt.Errorf("error %s running %s", err, command)
+	}
+
+	logger.Debugf("AutoHeal: %s output %s", command, strings.TrimSpace(output.String()))
+
+	publishHealEvent(logger, healerConfig, incident.ExecHookHealAction, true, fmt.Sprintf("ran %s", command))
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package heal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecHookStrategyRequiresCommand(t *testing.T) {
+	_, err := NewStrategy(ExecHookStrategyName, StrategyConfig{})
+
+	assert.NotNil(t, err)
+}
+
+func TestExecHookStrategyPassesNodeOnStdin(t *testing.T) {
+	inputFilepath := filepath.Join(t.TempDir(), "input.json")
+
+	strategy, err := NewStrategy(ExecHookStrategyName, StrategyConfig{
+		ExecHookCommand: []string{"sh", "-c", "cat > " + inputFilepath},
+	})
+	assert.Nil(t, err)
+
+	err = strategy.Heal(context.Background(), newTestLogger(), nil, HealerConfig{
+		IncidentId:        "incident-1",
+		NodeId:            "node-1",
+		SecondsBehindLive: 120,
+	})
+	assert.Nil(t, err)
+
+	rawInput, err := os.ReadFile(inputFilepath)
+	assert.Nil(t, err)
+
+	var input execHookInput
+	assert.Nil(t, json.Unmarshal(rawInput, &input))
+	assert.Equal(t, execHookInput{IncidentId: "incident-1", NodeId: "node-1", SecondsBehindLive: 120}, input)
+}
+
+func TestExecHookStrategyFailsOnErrorOrTimeout(t *testing.T) {
+	strategy, err := NewStrategy(ExecHookStrategyName, StrategyConfig{
+		ExecHookCommand: []string{"false"},
+	})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, strategy.Heal(context.Background(), newTestLogger(), nil, HealerConfig{}))
+
+	strategy, err = NewStrategy(ExecHookStrategyName, StrategyConfig{
+		ExecHookCommand: []string{"sleep", "10"},
+		ExecHookTimeout: 100 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+
+	startedAt := time.Now()
+
+	assert.NotNil(t, strategy.Heal(context.Background(), newTestLogger(), nil, HealerConfig{}))
+	assert.Less(t, time.Since(startedAt), 5*time.Second)
+}
@@ -0,0 +1,244 @@
+package heal
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	// path relative to the node's home directory of the peers
+	// the node has learned of, rebuilt from the node's seeds
+	// and persistent peers if removed
+	nodeAddressBookFile = "config/addrbook.json"
+	// section of config.toml with the peer settings
+	p2pConfigSection = "[p2p]"
+)
+
+// refreshPeersStrategy implements the Strategy interface, reconnecting
+// a node that is connected to too few good quality peers to known good
+// peers, either by asking the node to dial them as persistent peers or
+// (if that fails e.g. as the node's unsafe rpc methods aren't enabled)
+// by replacing the node's seeds, clearing its address book of the peers
+// it has learned of and restarting the blockchain's service
+type refreshPeersStrategy struct {
+	serviceName    string
+	serviceManager ServiceManager
+	breaker        *RestartBreaker
+	nodeHome       string
+	peers          []string
+	seeds          []string
+	blocked        BlockedFunc
+}
+
+func newRefreshPeersStrategy(config StrategyConfig) (Strategy, error) {
+	if len(config.RefreshPeers) == 0 && len(config.RefreshSeeds) == 0 {
+		return nil, fmt.Errorf("persistent peers or seeds to refresh from are required for the %s heal strategy", RefreshPeersStrategyName)
+	}
+
+	if config.BlockchainServiceName == "" {
+		return nil, fmt.Errorf("a blockchain service name is required for the %s heal strategy", RefreshPeersStrategyName)
+	}
+
+	if config.NodeHome == "" {
+		return nil, fmt.Errorf("a node home is required for the %s heal strategy", RefreshPeersStrategyName)
+	}
+
+	serviceManager, err := config.serviceManager()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &refreshPeersStrategy{
+		serviceName:    config.BlockchainServiceName,
+		serviceManager: serviceManager,
+		breaker:        config.RestartBreaker,
+		nodeHome:       config.NodeHome,
+		peers:          config.RefreshPeers,
+		seeds:          config.RefreshSeeds,
+		blocked:        config.Blocked,
+	}, nil
+}
+
+func (rp *refreshPeersStrategy) Name() string {
+	return RefreshPeersStrategyName
+}
+
+// Applies returns whether the node is connected to too few good
+// quality peers for refreshing its peers to be worthwhile
+func (rp *refreshPeersStrategy) Applies(healerConfig HealerConfig) bool {
+	return healerConfig.PoorPeering
+}
+
+func (rp *refreshPeersStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	if err := rp.blocked.check(); err != nil {
+		publishHealEvent(logger, healerConfig, incident.RefreshPeersHealAction, false, fmt.Sprintf("%s, not refreshing peers", err))
+
+		return err
+	}
+
+	if len(rp.peers) > 0 {
+		err := kavaClient.DialPeers(rp.peers, true)
+
+		if err == nil {
+			publishHealEvent(logger, healerConfig, incident.RefreshPeersHealAction, true, fmt.Sprintf("dialed persistent peers %s", strings.Join(rp.peers, ",")))
+
+			return nil
+		}
+
+		logger.Warnf("AutoHeal: error %s dialing persistent peers, refreshing address book and restarting %s service instead", err, rp.serviceName)
+	}
+
+	// dialing the peers may have taken a while
+	if err := rp.blocked.check(); err != nil {
+		publishHealEvent(logger, healerConfig, incident.RefreshPeersHealAction, false, fmt.Sprintf("%s, not restarting %s service to refresh peers", err, rp.serviceName))
+
+		return err
+	}
+
+	if rp.breaker != nil {
+		if err := rp.breaker.Allow(time.Now()); err != nil {
+			publishHealEvent(logger, healerConfig, incident.RefreshPeersHealAction, false, fmt.Sprintf("%s, not restarting %s service to refresh peers", err, rp.serviceName))
+
+			return err
+		}
+	}
+
+	err := refreshAddressBook(rp.nodeHome, rp.seeds, rp.peers)
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RefreshPeersHealAction, false, fmt.Sprintf("error %s refreshing address book", err))
+
+		return err
+	}
+
+	err = rp.serviceManager.Restart()
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RefreshPeersHealAction, false, fmt.Sprintf("error %s restarting %s service after refreshing address book", err, rp.serviceName))
+
+		return err
+	}
+
+	publishHealEvent(logger, healerConfig, incident.RefreshPeersHealAction, true, fmt.Sprintf("refreshed address book and restarted %s service", rp.serviceName))
+
+	return nil
+}
+
+// refreshAddressBook sets the seeds and persistent peers (if any)
+// in the node's config.toml and removes the node's address book,
+// so the node rediscovers peers from them once restarted
+func refreshAddressBook(nodeHome string, seeds []string, peers []string) error {
+	configFilepath := filepath.Join(nodeHome, nodeConfigFile)
+
+	contents, err := os.ReadFile(configFilepath)
+
+	if err != nil {
+		return err
+	}
+
+	updated, err := setPeerConfig(string(contents), seeds, peers)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(configFilepath, []byte(updated), 0644)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filepath.Join(nodeHome, nodeAddressBookFile))
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// setPeerConfig returns the contents of config.toml with the seeds
+// and persistent peers replaced by those provided, leaving either
+// unchanged if none are provided, returning error (if any) if
+// there is no p2p section
+func setPeerConfig(contents string, seeds []string, peers []string) (string, error) {
+	settings := make(map[string]string)
+
+	if len(seeds) > 0 {
+		settings["seeds"] = fmt.Sprintf("%q", strings.Join(seeds, ","))
+	}
+
+	if len(peers) > 0 {
+		settings["persistent_peers"] = fmt.Sprintf("%q", strings.Join(peers, ","))
+	}
+
+	lines := strings.Split(contents, "\n")
+
+	var inSection, foundSection bool
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			inSection = trimmed == p2pConfigSection
+			foundSection = foundSection || inSection
+
+			continue
+		}
+
+		if !inSection {
+			continue
+		}
+
+		key := strings.TrimSpace(strings.SplitN(trimmed, "=", 2)[0])
+
+		if value, exists := settings[key]; exists && strings.Contains(trimmed, "=") {
+			lines[i] = fmt.Sprintf("%s = %s", key, value)
+		}
+	}
+
+	if !foundSection {
+		return "", fmt.Errorf("no %s section in %s", p2pConfigSection, nodeConfigFile)
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// ParsePeerAddresses parses a comma separated list of peer addresses
+// each in the form <node id>@<host>:<port>, returning the addresses
+// and error (if any)
+func ParsePeerAddresses(rawAddresses string) ([]string, error) {
+	var addresses []string
+
+	for _, address := range strings.Split(rawAddresses, ",") {
+		address = strings.TrimSpace(address)
+
+		if address == "" {
+			continue
+		}
+
+		nodeId, hostPort, found := strings.Cut(address, "@")
+
+		if !found || nodeId == "" {
+			return nil, fmt.Errorf("invalid peer address %s, expected <node id>@<host>:<port>", address)
+		}
+
+		if _, _, err := net.SplitHostPort(hostPort); err != nil {
+			return nil, fmt.Errorf("invalid peer address %s, expected <node id>@<host>:<port>", address)
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	return addresses, nil
+}
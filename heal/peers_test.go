@@ -0,0 +1,107 @@
+package heal
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testNodeConfig = `moniker = "kava-node"
+
+[p2p]
+laddr = "tcp://0.0.0.0:26656"
+seeds = "old@seed.example.com:26656"
+persistent_peers = ""
+
+[statesync]
+enable = false
+`
+
+func TestSetPeerConfigReplacesSeedsAndPersistentPeers(t *testing.T) {
+	updated, err := setPeerConfig(testNodeConfig, []string{"a@seed-1.kava.io:26656", "b@seed-2.kava.io:26656"}, []string{"c@peer.kava.io:26656"})
+	assert.Nil(t, err)
+
+	assert.Contains(t, updated, `seeds = "a@seed-1.kava.io:26656,b@seed-2.kava.io:26656"`)
+	assert.Contains(t, updated, `persistent_peers = "c@peer.kava.io:26656"`)
+	assert.Contains(t, updated, `laddr = "tcp://0.0.0.0:26656"`)
+}
+
+func TestSetPeerConfigLeavesUnsetValuesUnchanged(t *testing.T) {
+	updated, err := setPeerConfig(testNodeConfig, nil, []string{"c@peer.kava.io:26656"})
+	assert.Nil(t, err)
+
+	assert.Contains(t, updated, `seeds = "old@seed.example.com:26656"`)
+	assert.Contains(t, updated, `persistent_peers = "c@peer.kava.io:26656"`)
+}
+
+func TestSetPeerConfigRequiresP2PSection(t *testing.T) {
+	_, err := setPeerConfig(`moniker = "kava-node"`, []string{"a@seed-1.kava.io:26656"}, nil)
+
+	assert.NotNil(t, err)
+}
+
+func TestParsePeerAddresses(t *testing.T) {
+	addresses, err := ParsePeerAddresses("a@seed-1.kava.io:26656, b@10.0.0.1:26656,")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a@seed-1.kava.io:26656", "b@10.0.0.1:26656"}, addresses)
+
+	addresses, err = ParsePeerAddresses("")
+	assert.Nil(t, err)
+	assert.Empty(t, addresses)
+
+	for _, invalid := range []string{"seed-1.kava.io:26656", "@seed-1.kava.io:26656", "a@seed-1.kava.io"} {
+		_, err = ParsePeerAddresses(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestRefreshPeersStrategyRequiresPeersOrSeeds(t *testing.T) {
+	_, err := NewStrategy(RefreshPeersStrategyName, StrategyConfig{
+		BlockchainServiceName: "kava",
+		NodeHome:              t.TempDir(),
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestRefreshPeersStrategyRefreshesAddressBookAndRestarts(t *testing.T) {
+	nodeHome := t.TempDir()
+	restartedFilepath := filepath.Join(t.TempDir(), "restarted")
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(nodeHome, "config"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(nodeHome, nodeConfigFile), []byte(testNodeConfig), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(nodeHome, nodeAddressBookFile), []byte(`{"addrs":[]}`), 0644))
+
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		Manager:        ExecServiceManager,
+		ServiceName:    "kava",
+		RestartCommand: []string{"touch", restartedFilepath},
+	})
+	assert.Nil(t, err)
+
+	strategy, err := NewStrategy(RefreshPeersStrategyName, StrategyConfig{
+		BlockchainServiceName: "kava",
+		ServiceManager:        serviceManager,
+		NodeHome:              nodeHome,
+		RefreshSeeds:          []string{"a@seed-1.kava.io:26656"},
+	})
+	assert.Nil(t, err)
+
+	assert.False(t, strategy.(ConditionalStrategy).Applies(HealerConfig{}))
+	assert.True(t, strategy.(ConditionalStrategy).Applies(HealerConfig{PoorPeering: true}))
+
+	assert.Nil(t, strategy.Heal(context.Background(), newTestLogger(), nil, HealerConfig{PoorPeering: true}))
+
+	config, err := os.ReadFile(filepath.Join(nodeHome, nodeConfigFile))
+	assert.Nil(t, err)
+	assert.Contains(t, string(config), `seeds = "a@seed-1.kava.io:26656"`)
+
+	_, err = os.Stat(filepath.Join(nodeHome, nodeAddressBookFile))
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = os.Stat(restartedFilepath)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,213 @@
+package heal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/autoscaling"
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	// ways of replacing an instance using its autoscaling group
+	SetUnhealthyReplaceMethod = "set-unhealthy"
+	TerminateReplaceMethod    = "terminate"
+
+	DefaultReplaceMethod = SetUnhealthyReplaceMethod
+
+	// autoscaling health status that causes
+	// the instance to be replaced
+	UnhealthyHealthStatus = "Unhealthy"
+	// result to complete a lifecycle action with
+	// to allow the instance to be terminated
+	continueLifecycleActionResult = "CONTINUE"
+	// how often to check if the instance is
+	// waiting on the termination lifecycle hook
+	lifecycleHookCheckInterval = 10 * time.Second
+)
+
+var (
+	ValidReplaceMethods = []string{SetUnhealthyReplaceMethod, TerminateReplaceMethod}
+
+	ErrInsufficientReplacementCapacity = errors.New("too few other healthy in service instances to replace instance")
+)
+
+// replaceInstanceStrategy implements the Strategy interface,
+// having the autoscaling group replace the instance the doctor is
+// running on, as a last resort for failures only a new instance
+// fixes (e.g. a corrupted ebs volume)
+type replaceInstanceStrategy struct {
+	autoscalingClient *autoscaling.AutoScaling
+	instanceId        string
+	method            string
+	// minimum number of other healthy in service instances
+	// required in the autoscaling group to replace the instance
+	minHealthyInServiceInstances int
+	// optional name of a termination lifecycle hook to complete
+	// once the blockchain service is stopped
+	lifecycleHookName string
+	serviceManager    ServiceManager
+	blocked           BlockedFunc
+}
+
+func newReplaceInstanceStrategy(config StrategyConfig) (Strategy, error) {
+	method := config.ReplaceMethod
+
+	if method == "" {
+		method = DefaultReplaceMethod
+	}
+
+	if method != SetUnhealthyReplaceMethod && method != TerminateReplaceMethod {
+		return nil, fmt.Errorf("invalid replace method %s, valid methods are %v", method, ValidReplaceMethods)
+	}
+
+	var serviceManager ServiceManager
+
+	if config.ReplaceLifecycleHookName != "" {
+		var err error
+
+		serviceManager, err = config.serviceManager()
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	awsSession, instanceId, err := newInstanceAWSSession()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &replaceInstanceStrategy{
+		autoscalingClient:            autoscaling.New(awsSession),
+		instanceId:                   instanceId,
+		method:                       method,
+		minHealthyInServiceInstances: config.ReplaceMinHealthyInstances,
+		lifecycleHookName:            config.ReplaceLifecycleHookName,
+		serviceManager:               serviceManager,
+		blocked:                      config.Blocked,
+	}, nil
+}
+
+func (ri *replaceInstanceStrategy) Name() string {
+	return ReplaceInstanceStrategyName
+}
+
+func (ri *replaceInstanceStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	autoscalingInstances, err := ri.autoscalingClient.DescribeAutoScalingInstancesWithContext(ctx, &autoscaling.DescribeAutoScalingInstancesInput{
+		InstanceIds: []*string{
+			aws.String(ri.instanceId),
+		},
+	})
+
+	if err != nil {
+		return fmt.Errorf("error %s checking autoscaling state for instance %s", err, ri.instanceId)
+	}
+
+	if len(autoscalingInstances.AutoScalingInstances) != 1 {
+		return fmt.Errorf("expected exactly one instance with id %s, got %+v", ri.instanceId, autoscalingInstances.AutoScalingInstances)
+	}
+
+	autoscalingGroupName := aws.StringValue(autoscalingInstances.AutoScalingInstances[0].AutoScalingGroupName)
+
+	// the replacement takes time to sync, so don't replace the
+	// instance unless enough other instances can serve meanwhile
+	if ri.minHealthyInServiceInstances > 0 {
+		otherHealthyInService, err := countOtherHealthyInServiceInstances(ri.autoscalingClient, autoscalingGroupName, ri.instanceId)
+
+		if err != nil {
+			return err
+		}
+
+		if otherHealthyInService < ri.minHealthyInServiceInstances {
+			message := fmt.Sprintf("refusing to replace instance %s, only %d other healthy in service instances in autoscaling group %s, minimum %d", ri.instanceId, otherHealthyInService, autoscalingGroupName, ri.minHealthyInServiceInstances)
+
+			publishHealEvent(logger, healerConfig, incident.ReplaceInstanceHealAction, false, message)
+
+			return fmt.Errorf("%w: %s", ErrInsufficientReplacementCapacity, message)
+		}
+	}
+
+	if err := ri.blocked.check(); err != nil {
+		publishHealEvent(logger, healerConfig, incident.ReplaceInstanceHealAction, false, fmt.Sprintf("%s, not replacing instance %s", err, ri.instanceId))
+
+		return err
+	}
+
+	switch ri.method {
+	case TerminateReplaceMethod:
+		_, err = ri.autoscalingClient.TerminateInstanceInAutoScalingGroupWithContext(ctx, &autoscaling.TerminateInstanceInAutoScalingGroupInput{
+			InstanceId:                     aws.String(ri.instanceId),
+			ShouldDecrementDesiredCapacity: aws.Bool(false),
+		})
+	default:
+		_, err = ri.autoscalingClient.SetInstanceHealthWithContext(ctx, &autoscaling.SetInstanceHealthInput{
+			InstanceId:               aws.String(ri.instanceId),
+			HealthStatus:             aws.String(UnhealthyHealthStatus),
+			ShouldRespectGracePeriod: aws.Bool(false),
+		})
+	}
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.ReplaceInstanceHealAction, false, fmt.Sprintf("error %s replacing instance %s using %s", err, ri.instanceId, ri.method))
+
+		return err
+	}
+
+	// published as soon as the replacement is requested as the
+	// doctor runs on the instance being replaced
+	publishHealEvent(logger, healerConfig, incident.ReplaceInstanceHealAction, true, fmt.Sprintf("replacing instance %s in autoscaling group %s using %s", ri.instanceId, autoscalingGroupName, ri.method))
+
+	if ri.lifecycleHookName == "" {
+		return nil
+	}
+
+	return ri.completeTerminationLifecycleAction(ctx, logger, autoscalingGroupName)
+}
+
+// completeTerminationLifecycleAction waits for the instance to be held
+// by the termination lifecycle hook, stops the blockchain service so
+// the node shuts down cleanly and completes the lifecycle action so
+// termination continues, returning error (if any)
+func (ri *replaceInstanceStrategy) completeTerminationLifecycleAction(ctx context.Context, logger *logging.Logger, autoscalingGroupName string) error {
+	for {
+		state, err := GetNodeAutoscalingState(ri.instanceId, ri.autoscalingClient)
+
+		if err != nil {
+			logger.Errorf("error %s waiting for instance %s to reach %s", err, ri.instanceId, autoscaling.LifecycleStateTerminatingWait)
+		} else if state == autoscaling.LifecycleStateTerminatingWait {
+			break
+		}
+
+		if !sleepUnlessCancelled(ctx, lifecycleHookCheckInterval) {
+			return ctx.Err()
+		}
+	}
+
+	if ri.serviceManager != nil {
+		if err := ri.serviceManager.Stop(); err != nil {
+			logger.Errorf("error %s stopping service before termination, continuing termination", err)
+		}
+	}
+
+	_, err := ri.autoscalingClient.CompleteLifecycleActionWithContext(ctx, &autoscaling.CompleteLifecycleActionInput{
+		AutoScalingGroupName:  aws.String(autoscalingGroupName),
+		LifecycleHookName:     aws.String(ri.lifecycleHookName),
+		InstanceId:            aws.String(ri.instanceId),
+		LifecycleActionResult: aws.String(continueLifecycleActionResult),
+	})
+
+	if err != nil {
+		return fmt.Errorf("error %s completing lifecycle action %s for instance %s", err, ri.lifecycleHookName, ri.instanceId)
+	}
+
+	logger.Infof("completed lifecycle action %s, instance %s is terminating", ri.lifecycleHookName, ri.instanceId)
+
+	return nil
+}
@@ -0,0 +1,16 @@
+package heal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReplaceInstanceStrategyRejectsInvalidMethods(t *testing.T) {
+	_, err := NewStrategy(ReplaceInstanceStrategyName, StrategyConfig{
+		ReplaceMethod: "detach",
+	})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid replace method detach")
+}
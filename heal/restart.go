@@ -0,0 +1,133 @@
+package heal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+)
+
+const (
+	DefaultPreRestartTimeout = 60 * time.Second
+	// how often to check the node's status while
+	// verifying it came back up after a restart
+	restartVerificationCheckInterval = 5 * time.Second
+)
+
+var (
+	ErrPreRestartCommandFailed = errors.New("pre restart command failed")
+	ErrRestartNotVerified      = errors.New("node did not come back up after restarting")
+)
+
+// preRestartServiceManager implements the ServiceManager interface,
+// running commands (e.g. snapshotting the node's database or draining
+// it from a load balancer) in order before each restart of the wrapped
+// service manager, refusing to restart if any of them fail
+type preRestartServiceManager struct {
+	ServiceManager
+	commands [][]string
+	timeout  time.Duration
+}
+
+// Restart runs the pre restart commands, then restarts the service
+// if they all succeed, returning error (if any), an error wrapping
+// ErrPreRestartCommandFailed is returned without restarting the
+// service if a command fails or runs past the timeout
+func (pm *preRestartServiceManager) Restart() error {
+	for _, command := range pm.commands {
+		if err := runWithTimeout(command, pm.timeout); err != nil {
+			return fmt.Errorf("%w: %s, not restarting", ErrPreRestartCommandFailed, err)
+		}
+	}
+
+	return pm.ServiceManager.Restart()
+}
+
+// runWithTimeout runs the command, killing it if it runs past
+// the timeout, returning error (if any) including its output
+func runWithTimeout(command []string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s killed after running for %v", strings.Join(command, " "), timeout)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error %s running %s output %s", err, strings.Join(command, " "), strings.TrimSpace(output.String()))
+	}
+
+	return nil
+}
+
+// VerifyRestart waits up to timeout for the restarted node to respond to
+// status checks and for its latest block height to advance past the
+// height it first reports, returning an error wrapping ErrRestartNotVerified
+// if it doesn't (e.g. the blockchain service is crash looping), or the
+// context's error if it's cancelled first
+func VerifyRestart(ctx context.Context, kavaClient *kava.Client, timeout time.Duration) error {
+	latestHeight := func() (int64, error) {
+		nodeState, err := kavaClient.GetNodeState()
+
+		return nodeState.SyncInfo.LatestBlockHeight, err
+	}
+
+	return verifyRestart(ctx, latestHeight, timeout, restartVerificationCheckInterval)
+}
+
+// verifyRestart verifies a restart as described by VerifyRestart
+// using latestHeight to get the node's latest block height
+func verifyRestart(ctx context.Context, latestHeight func() (int64, error), timeout time.Duration, checkInterval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	var startHeight int64
+	var responded bool
+	var lastErr error
+
+	for {
+		height, err := latestHeight()
+
+		switch {
+		case err != nil:
+			lastErr = err
+		case !responded:
+			startHeight = height
+			responded = true
+		case height > startHeight:
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+
+		if remaining <= 0 {
+			break
+		}
+
+		if remaining > checkInterval {
+			remaining = checkInterval
+		}
+
+		if !sleepUnlessCancelled(ctx, remaining) {
+			return ctx.Err()
+		}
+	}
+
+	if !responded {
+		return fmt.Errorf("%w: no response to status checks within %v, last error %s", ErrRestartNotVerified, timeout, lastErr)
+	}
+
+	return fmt.Errorf("%w: block height stuck at %d for %v", ErrRestartNotVerified, startHeight, timeout)
+}
@@ -0,0 +1,99 @@
+package heal
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreRestartCommandsRunBeforeRestart(t *testing.T) {
+	logFilepath := filepath.Join(t.TempDir(), "restart.log")
+
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		Manager:        ExecServiceManager,
+		ServiceName:    "kava",
+		RestartCommand: []string{"sh", "-c", "echo restart >> " + logFilepath},
+		PreRestartCommands: [][]string{
+			{"sh", "-c", "echo snapshot >> " + logFilepath},
+			{"sh", "-c", "echo drain >> " + logFilepath},
+		},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, serviceManager.Restart())
+
+	log, err := os.ReadFile(logFilepath)
+	assert.Nil(t, err)
+	assert.Equal(t, "snapshot\ndrain\nrestart\n", string(log))
+}
+
+func TestFailedPreRestartCommandPreventsRestart(t *testing.T) {
+	logFilepath := filepath.Join(t.TempDir(), "restart.log")
+
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		Manager:            ExecServiceManager,
+		ServiceName:        "kava",
+		RestartCommand:     []string{"sh", "-c", "echo restart >> " + logFilepath},
+		PreRestartCommands: [][]string{{"sleep", "10"}},
+		PreRestartTimeout:  100 * time.Millisecond,
+	})
+	assert.Nil(t, err)
+
+	err = serviceManager.Restart()
+
+	assert.True(t, errors.Is(err, ErrPreRestartCommandFailed))
+
+	_, err = os.Stat(logFilepath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestVerifyRestartWaitsForHeightToAdvance(t *testing.T) {
+	responses := []struct {
+		height int64
+		err    error
+	}{
+		{0, errors.New("connection refused")},
+		{100, nil},
+		{100, nil},
+		{101, nil},
+	}
+
+	var checks int
+
+	latestHeight := func() (int64, error) {
+		response := responses[checks]
+
+		if checks < len(responses)-1 {
+			checks++
+		}
+
+		return response.height, response.err
+	}
+
+	assert.Nil(t, verifyRestart(context.Background(), latestHeight, time.Second, time.Millisecond))
+	assert.Equal(t, len(responses)-1, checks)
+}
+
+func TestVerifyRestartFailsIfNodeDoesntComeBackUp(t *testing.T) {
+	unreachable := func() (int64, error) {
+		return 0, errors.New("connection refused")
+	}
+
+	err := verifyRestart(context.Background(), unreachable, 20*time.Millisecond, time.Millisecond)
+
+	assert.True(t, errors.Is(err, ErrRestartNotVerified))
+
+	stuck := func() (int64, error) {
+		return 100, nil
+	}
+
+	err = verifyRestart(context.Background(), stuck, 20*time.Millisecond, time.Millisecond)
+
+	assert.True(t, errors.Is(err, ErrRestartNotVerified))
+	assert.Contains(t, err.Error(), "stuck at 100")
+}
@@ -0,0 +1,613 @@
+package heal
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/spf13/viper"
+)
+
+const (
+	// 24 hours
+	DefaultRestoreStateThresholdSeconds = 86400
+
+	// paths relative to the node's home directory
+	nodeDataDirectory = "data"
+	nodeConfigFile    = "config/config.toml"
+	// kept when restoring the node's data so a
+	// restored validator can't double sign
+	privValidatorStateFile = "priv_validator_state.json"
+	// patterns of the directories created next to the node's home
+	// for staging the restored state and for the state it replaces
+	restoreStagingPattern  = ".doctor-restore-staging-"
+	restoreReplacedPattern = ".doctor-restore-replaced-"
+
+	// how many blocks before the latest block of the state
+	// sync rpc server to use as the trusted block
+	stateSyncTrustHeightOffset = 2000
+	// section of config.toml with the state sync settings
+	stateSyncConfigSection = "[statesync]"
+)
+
+var (
+	ErrUnsafeSnapshotPath = errors.New("snapshot contains a path outside the node home")
+	ErrInvalidSnapshot    = errors.New("invalid snapshot")
+)
+
+// restoreStateStrategy implements the Strategy interface, restoring
+// the node's state from a snapshot (or by enabling state sync) for nodes
+// so far behind live they can't reasonably catch up by syncing blocks
+// The restored state is staged before the blockchain's service is
+// stopped and swapped in for the node's data once it's stopped, moving
+// the node's previous data back into place if the swap fails
+type restoreStateStrategy struct {
+	serviceName    string
+	serviceManager ServiceManager
+	nodeHome       string
+	// optional url of a tar (or gzipped tar) snapshot of the node's
+	// home directory, state sync is used if not set
+	snapshotURL          string
+	minSecondsBehindLive int64
+	httpClient           *http.Client
+	blocked              BlockedFunc
+}
+
+func newRestoreStateStrategy(config StrategyConfig) (Strategy, error) {
+	if config.BlockchainServiceName == "" {
+		return nil, fmt.Errorf("a blockchain service name is required for the %s heal strategy", RestoreStateStrategyName)
+	}
+
+	if config.NodeHome == "" {
+		return nil, fmt.Errorf("a node home is required for the %s heal strategy", RestoreStateStrategyName)
+	}
+
+	serviceManager, err := config.serviceManager()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &restoreStateStrategy{
+		serviceName:          config.BlockchainServiceName,
+		serviceManager:       serviceManager,
+		nodeHome:             config.NodeHome,
+		snapshotURL:          config.SnapshotURL,
+		minSecondsBehindLive: config.RestoreStateThresholdSeconds,
+		httpClient:           &http.Client{},
+		blocked:              config.Blocked,
+	}, nil
+}
+
+func (rs *restoreStateStrategy) Name() string {
+	return RestoreStateStrategyName
+}
+
+// Applies returns whether the node is far enough behind
+// live for restoring its state to be worthwhile
+func (rs *restoreStateStrategy) Applies(healerConfig HealerConfig) bool {
+	return healerConfig.SecondsBehindLive >= rs.minSecondsBehindLive
+}
+
+func (rs *restoreStateStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	source := rs.snapshotURL
+
+	// staged next to the node's home so it can be moved into place
+	// without copying, and before stopping the node so a failed
+	// download or a misconfigured node doesn't leave it without data
+	staging, err := os.MkdirTemp(filepath.Dir(filepath.Clean(rs.nodeHome)), restoreStagingPattern)
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("error %s creating directory to stage restored state in", err))
+
+		return err
+	}
+
+	defer os.RemoveAll(staging)
+
+	// updated node config to write once the state is swapped in, if any
+	var nodeConfig []byte
+
+	if rs.snapshotURL != "" {
+		err = rs.stageSnapshot(ctx, staging)
+
+		if err != nil {
+			publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("error %s downloading snapshot from %s", err, source))
+
+			return err
+		}
+	} else {
+		var trustHeight int64
+		var trustHash string
+
+		trustHeight, trustHash, source, err = rs.getStateSyncTrustedBlock()
+
+		if err != nil {
+			publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("error %s finding block to trust for state sync", err))
+
+			return err
+		}
+
+		nodeConfig, err = rs.stateSyncConfig(trustHeight, trustHash)
+
+		if err == nil {
+			// state sync restores the node's state into an empty data directory
+			err = os.Mkdir(filepath.Join(staging, nodeDataDirectory), 0755)
+		}
+
+		if err != nil {
+			publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("error %s staging state sync from %s", err, source))
+
+			return err
+		}
+	}
+
+	if err := rs.blocked.check(); err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("%s, not restoring state from %s", err, source))
+
+		return err
+	}
+
+	err = rs.serviceManager.Stop()
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("error %s stopping %s service", err, rs.serviceName))
+
+		return err
+	}
+
+	err = rs.swapInStagedState(logger, staging, nodeConfig)
+
+	// the service is left stopped for an operator to investigate
+	// rather than started on state that failed to restore
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("error %s restoring state from %s, %s service left stopped", err, source, rs.serviceName))
+
+		return err
+	}
+
+	err = rs.serviceManager.Start()
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, false, fmt.Sprintf("error %s starting %s service after restoring state from %s", err, rs.serviceName, source))
+
+		return err
+	}
+
+	publishHealEvent(logger, healerConfig, incident.RestoreStateHealAction, true, fmt.Sprintf("restored state from %s and restarted %s service", source, rs.serviceName))
+
+	return nil
+}
+
+// stateSyncConfig returns the contents of the node's config.toml
+// with state sync enabled from the trusted block, returning error
+// (if any) if the config can't be read or updated
+func (rs *restoreStateStrategy) stateSyncConfig(trustHeight int64, trustHash string) ([]byte, error) {
+	contents, err := os.ReadFile(filepath.Join(rs.nodeHome, nodeConfigFile))
+
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := setStateSyncConfig(string(contents), trustHeight, trustHash)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(updated), nil
+}
+
+// swapInStagedState replaces the node's state with the staged state,
+// keeping the node's priv validator state, and writes the updated node
+// config (if any), moving the replaced state aside until the swap
+// succeeds and back into place if it fails, returning error (if any)
+// The replaced state is left aside for an operator to recover
+// if it can't be moved back into place
+func (rs *restoreStateStrategy) swapInStagedState(logger *logging.Logger, staging string, nodeConfig []byte) error {
+	err := retainPrivValidatorState(rs.nodeHome, staging)
+
+	if err != nil {
+		return err
+	}
+
+	replaced, err := os.MkdirTemp(filepath.Dir(filepath.Clean(rs.nodeHome)), restoreReplacedPattern)
+
+	if err != nil {
+		return err
+	}
+
+	// only the node's data is restored, leaving its config
+	// (e.g. its node key) as is even if the snapshot has any
+	swapped, err := swapState(rs.nodeHome, staging, replaced, []string{nodeDataDirectory})
+
+	if err == nil && nodeConfig != nil {
+		err = writeFileAtomically(filepath.Join(rs.nodeHome, nodeConfigFile), nodeConfig)
+	}
+
+	if err != nil {
+		if rollbackErr := restoreReplacedState(rs.nodeHome, replaced, swapped); rollbackErr != nil {
+			return fmt.Errorf("%s swapping in restored state, then error %s moving the node's previous state back into place from %s", err, rollbackErr, replaced)
+		}
+	}
+
+	if removeErr := os.RemoveAll(replaced); removeErr != nil {
+		logger.Warnf("AutoHeal: error %s removing the node's previous state from %s", removeErr, replaced)
+	}
+
+	return err
+}
+
+// getStateSyncTrustedBlock returns the height and hash of the block
+// to trust for state syncing, a recent block from the first of the
+// node's configured state sync rpc servers, and error (if any)
+func (rs *restoreStateStrategy) getStateSyncTrustedBlock() (int64, string, string, error) {
+	nodeConfig := viper.New()
+	nodeConfig.SetConfigFile(filepath.Join(rs.nodeHome, nodeConfigFile))
+	nodeConfig.SetConfigType("toml")
+
+	err := nodeConfig.ReadInConfig()
+
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	var rpcServer string
+
+	for _, server := range strings.Split(nodeConfig.GetString("statesync.rpc_servers"), ",") {
+		if server = strings.TrimSpace(server); server != "" {
+			rpcServer = server
+
+			break
+		}
+	}
+
+	if rpcServer == "" {
+		return 0, "", "", fmt.Errorf("no statesync.rpc_servers set in %s", nodeConfigFile)
+	}
+
+	if !strings.Contains(rpcServer, "://") {
+		rpcServer = "http://" + rpcServer
+	}
+
+	client, err := kava.New(kava.ClientConfig{
+		JSONRPCURL:             rpcServer,
+		HTTPReadTimeoutSeconds: 10,
+	})
+
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	nodeState, err := client.GetNodeState()
+
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	trustHeight := nodeState.SyncInfo.LatestBlockHeight - stateSyncTrustHeightOffset
+
+	if trustHeight < 1 {
+		trustHeight = 1
+	}
+
+	block, err := client.GetBlockHashes(trustHeight)
+
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	return trustHeight, block.BlockHash, fmt.Sprintf("state sync rpc server %s", rpcServer), nil
+}
+
+// stageSnapshot downloads the snapshot and extracts it into the
+// staging directory, returning error (if any) if the snapshot can't
+// be downloaded or extracted in full or has no data directory
+func (rs *restoreStateStrategy) stageSnapshot(ctx context.Context, staging string) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rs.snapshotURL, nil)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := rs.httpClient.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s downloading snapshot", response.Status)
+	}
+
+	err = extractSnapshot(response.Body, staging)
+
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(filepath.Join(staging, nodeDataDirectory))
+
+	if err != nil || !info.IsDir() {
+		return fmt.Errorf("%w: no %s directory", ErrInvalidSnapshot, nodeDataDirectory)
+	}
+
+	return nil
+}
+
+// retainPrivValidatorState copies the node's priv validator state
+// (if any) into the staged data directory so a restored validator
+// can't double sign, returning error (if any)
+func retainPrivValidatorState(nodeHome string, staging string) error {
+	current := filepath.Join(nodeHome, nodeDataDirectory, privValidatorStateFile)
+
+	info, err := os.Stat(current)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	contents, err := os.ReadFile(current)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(staging, nodeDataDirectory, privValidatorStateFile), contents, info.Mode().Perm())
+}
+
+// swapState moves each of the named entries of the staging directory
+// into the node's home, first moving the entry it replaces (if any) into
+// the replaced directory, returning the names of the entries swapped
+// (even partially) and error (if any)
+func swapState(nodeHome string, staging string, replaced string, names []string) ([]string, error) {
+	var swapped []string
+
+	for _, name := range names {
+		current := filepath.Join(nodeHome, name)
+
+		_, err := os.Lstat(current)
+
+		switch {
+		case err == nil:
+			err = os.Rename(current, filepath.Join(replaced, name))
+		case errors.Is(err, fs.ErrNotExist):
+			err = nil
+		}
+
+		if err != nil {
+			return swapped, err
+		}
+
+		swapped = append(swapped, name)
+
+		err = os.Rename(filepath.Join(staging, name), current)
+
+		if err != nil {
+			return swapped, err
+		}
+	}
+
+	return swapped, nil
+}
+
+// restoreReplacedState removes the swapped entries from the node's
+// home and moves the entries they replaced (if any) back into place,
+// returning error (if any)
+func restoreReplacedState(nodeHome string, replaced string, swapped []string) error {
+	for i := len(swapped) - 1; i >= 0; i-- {
+		current := filepath.Join(nodeHome, swapped[i])
+		previous := filepath.Join(replaced, swapped[i])
+
+		err := os.RemoveAll(current)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = os.Lstat(previous)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err == nil {
+			err = os.Rename(previous, current)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomically replaces the contents of the file by writing
+// them to a temporary file next to it and renaming it into place, so
+// the file is never left partially written, returning error (if any)
+func writeFileAtomically(filename string, contents []byte) error {
+	temporary := filename + ".tmp"
+
+	err := os.WriteFile(temporary, contents, 0644)
+
+	if err == nil {
+		err = os.Rename(temporary, filename)
+	}
+
+	if err != nil {
+		os.Remove(temporary)
+	}
+
+	return err
+}
+
+// extractSnapshot extracts the tar (or gzipped tar) snapshot read
+// from snapshot into the node's home directory, without overwriting
+// the retained priv validator state, returning error (if any)
+func extractSnapshot(snapshot io.Reader, nodeHome string) error {
+	reader := bufio.NewReader(snapshot)
+
+	// gzip streams start with the magic bytes 0x1f 0x8b
+	if magic, err := reader.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(reader)
+
+		if err != nil {
+			return err
+		}
+
+		defer gzipReader.Close()
+
+		snapshot = gzipReader
+	} else {
+		snapshot = reader
+	}
+
+	archive := tar.NewReader(snapshot)
+	retainedState := filepath.Join(nodeHome, nodeDataDirectory, privValidatorStateFile)
+
+	for {
+		header, err := archive.Next()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(nodeHome, header.Name)
+
+		if target != filepath.Clean(nodeHome) && !strings.HasPrefix(target, filepath.Clean(nodeHome)+string(os.PathSeparator)) {
+			return fmt.Errorf("%w: %s", ErrUnsafeSnapshotPath, header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0755)
+		case tar.TypeReg:
+			if target == retainedState {
+				continue
+			}
+
+			err = extractSnapshotFile(archive, target, os.FileMode(header.Mode).Perm())
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractSnapshotFile writes the contents of the current
+// file in the archive to target, returning error (if any)
+func extractSnapshotFile(archive *tar.Reader, target string, mode os.FileMode) error {
+	err := os.MkdirAll(filepath.Dir(target), 0755)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, archive)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// setStateSyncConfig returns the contents of config.toml with
+// state sync enabled from the block at trustHeight with trustHash,
+// adding any of the settings missing from the state sync section
+// to the end of it, returning error (if any) if there is no
+// state sync section
+func setStateSyncConfig(contents string, trustHeight int64, trustHash string) (string, error) {
+	keys := []string{"enable", "trust_height", "trust_hash"}
+	settings := map[string]string{
+		"enable":       "true",
+		"trust_height": fmt.Sprintf("%d", trustHeight),
+		"trust_hash":   fmt.Sprintf("%q", trustHash),
+	}
+
+	lines := strings.Split(contents, "\n")
+
+	var inSection bool
+	// index of the last line of the state sync section
+	// that isn't blank, -1 if there is no section
+	sectionEnd := -1
+	set := make(map[string]bool)
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "[") {
+			inSection = trimmed == stateSyncConfigSection
+
+			if inSection {
+				sectionEnd = i
+			}
+
+			continue
+		}
+
+		if !inSection {
+			continue
+		}
+
+		if trimmed != "" {
+			sectionEnd = i
+		}
+
+		key := strings.TrimSpace(strings.SplitN(trimmed, "=", 2)[0])
+
+		if value, exists := settings[key]; exists && strings.Contains(trimmed, "=") {
+			lines[i] = fmt.Sprintf("%s = %s", key, value)
+			set[key] = true
+		}
+	}
+
+	if sectionEnd == -1 {
+		return "", fmt.Errorf("no %s section in %s", stateSyncConfigSection, nodeConfigFile)
+	}
+
+	var missing []string
+
+	for _, key := range keys {
+		if !set[key] {
+			missing = append(missing, fmt.Sprintf("%s = %s", key, settings[key]))
+		}
+	}
+
+	updated := make([]string, 0, len(lines)+len(missing))
+	updated = append(updated, lines[:sectionEnd+1]...)
+	updated = append(updated, missing...)
+	updated = append(updated, lines[sectionEnd+1:]...)
+
+	return strings.Join(updated, "\n"), nil
+}
@@ -0,0 +1,271 @@
+package heal
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetStateSyncConfigOnlyChangesStateSyncSection(t *testing.T) {
+	contents := `[p2p]
+enable = false
+
+[statesync]
+enable = false
+rpc_servers = "https://rpc.kava.io:443,https://rpc.data.kava.io:443"
+trust_height = 0
+trust_hash = ""
+trust_period = "168h0m0s"
+`
+
+	updated, err := setStateSyncConfig(contents, 1000, "ABCD")
+
+	assert.Nil(t, err)
+	assert.Equal(t, `[p2p]
+enable = false
+
+[statesync]
+enable = true
+rpc_servers = "https://rpc.kava.io:443,https://rpc.data.kava.io:443"
+trust_height = 1000
+trust_hash = "ABCD"
+trust_period = "168h0m0s"
+`, updated)
+
+	_, err = setStateSyncConfig("[p2p]\n", 1000, "ABCD")
+
+	assert.NotNil(t, err)
+}
+
+func TestSetStateSyncConfigAddsMissingSettings(t *testing.T) {
+	contents := `[statesync]
+rpc_servers = "https://rpc.kava.io:443,https://rpc.data.kava.io:443"
+trust_period = "168h0m0s"
+
+[fastsync]
+version = "v0"
+`
+
+	updated, err := setStateSyncConfig(contents, 1000, "ABCD")
+
+	assert.Nil(t, err)
+	assert.Equal(t, `[statesync]
+rpc_servers = "https://rpc.kava.io:443,https://rpc.data.kava.io:443"
+trust_period = "168h0m0s"
+enable = true
+trust_height = 1000
+trust_hash = "ABCD"
+
+[fastsync]
+version = "v0"
+`, updated)
+
+	// only the missing settings are added, including
+	// when the state sync section is the last section
+	updated, err = setStateSyncConfig("[statesync]\nenable = false\n# trust_height = 0\n", 1000, "ABCD")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "[statesync]\nenable = true\n# trust_height = 0\ntrust_height = 1000\ntrust_hash = \"ABCD\"\n", updated)
+}
+
+// newTestSnapshot returns a gzipped tar containing the specified files
+func newTestSnapshot(t *testing.T, files map[string]string) *bytes.Buffer {
+	var snapshot bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&snapshot)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for name, contents := range files {
+		err := tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		})
+		assert.Nil(t, err)
+
+		_, err = tarWriter.Write([]byte(contents))
+		assert.Nil(t, err)
+	}
+
+	assert.Nil(t, tarWriter.Close())
+	assert.Nil(t, gzipWriter.Close())
+
+	return &snapshot
+}
+
+func TestExtractSnapshotRestoresDataWithoutOverwritingPrivValidatorState(t *testing.T) {
+	nodeHome := t.TempDir()
+	privValidatorState := filepath.Join(nodeHome, nodeDataDirectory, privValidatorStateFile)
+
+	assert.Nil(t, os.MkdirAll(filepath.Dir(privValidatorState), 0755))
+	assert.Nil(t, os.WriteFile(privValidatorState, []byte(`{"height":"10"}`), 0644))
+
+	snapshot := newTestSnapshot(t, map[string]string{
+		"data/application.db/000001.ldb": "state",
+		"data/priv_validator_state.json": `{"height":"0"}`,
+	})
+
+	err := extractSnapshot(snapshot, nodeHome)
+
+	assert.Nil(t, err)
+
+	restored, err := os.ReadFile(filepath.Join(nodeHome, "data", "application.db", "000001.ldb"))
+	assert.Nil(t, err)
+	assert.Equal(t, "state", string(restored))
+
+	retained, err := os.ReadFile(privValidatorState)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"height":"10"}`, string(retained))
+}
+
+func TestExtractSnapshotRejectsPathsOutsideNodeHome(t *testing.T) {
+	nodeHome := t.TempDir()
+
+	snapshot := newTestSnapshot(t, map[string]string{
+		"../escaped": "contents",
+	})
+
+	err := extractSnapshot(snapshot, nodeHome)
+
+	assert.True(t, errors.Is(err, ErrUnsafeSnapshotPath))
+}
+
+// newTestNodeHome returns the home directory of a node with data and
+// a priv validator state, in a directory of its own so anything left
+// next to it can be checked for
+func newTestNodeHome(t *testing.T) string {
+	nodeHome := filepath.Join(t.TempDir(), "kava")
+	dataDirectory := filepath.Join(nodeHome, nodeDataDirectory)
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(dataDirectory, "application.db"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(dataDirectory, "application.db", "000001.ldb"), []byte("stale state"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(dataDirectory, privValidatorStateFile), []byte(`{"height":"10"}`), 0644))
+
+	return nodeHome
+}
+
+// newTestSnapshotServer returns a server serving the snapshot
+func newTestSnapshotServer(t *testing.T, snapshot []byte) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(snapshot)
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// assertNothingLeftNextTo asserts the node home is
+// the only entry in the directory containing it
+func assertNothingLeftNextTo(t *testing.T, nodeHome string) {
+	entries, err := os.ReadDir(filepath.Dir(nodeHome))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(entries))
+	assert.Equal(t, filepath.Base(nodeHome), entries[0].Name())
+}
+
+func TestRestoreStateStrategyRestoresSnapshotKeepingPrivValidatorState(t *testing.T) {
+	nodeHome := newTestNodeHome(t)
+	serviceManager := &fakeServiceManager{}
+
+	server := newTestSnapshotServer(t, newTestSnapshot(t, map[string]string{
+		"data/blockstore.db/000001.ldb":  "blocks",
+		"data/priv_validator_state.json": `{"height":"0"}`,
+		"config/node_key.json":           "snapshot node key",
+	}).Bytes())
+
+	strategy := &restoreStateStrategy{
+		serviceName:    "kava",
+		serviceManager: serviceManager,
+		nodeHome:       nodeHome,
+		snapshotURL:    server.URL,
+		httpClient:     server.Client(),
+	}
+
+	err := strategy.Heal(context.Background(), newTestLogger(), nil, HealerConfig{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{StopServiceAction, StartServiceAction}, serviceManager.actions)
+
+	restored, err := os.ReadFile(filepath.Join(nodeHome, nodeDataDirectory, "blockstore.db", "000001.ldb"))
+	assert.Nil(t, err)
+	assert.Equal(t, "blocks", string(restored))
+
+	_, err = os.Stat(filepath.Join(nodeHome, nodeDataDirectory, "application.db"))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	retained, err := os.ReadFile(filepath.Join(nodeHome, nodeDataDirectory, privValidatorStateFile))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"height":"10"}`, string(retained))
+
+	_, err = os.Stat(filepath.Join(nodeHome, "config", "node_key.json"))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+
+	assertNothingLeftNextTo(t, nodeHome)
+}
+
+func TestRestoreStateStrategyDoesNotStopNodeForInvalidSnapshot(t *testing.T) {
+	nodeHome := newTestNodeHome(t)
+	serviceManager := &fakeServiceManager{}
+
+	server := newTestSnapshotServer(t, newTestSnapshot(t, map[string]string{
+		"config/app.toml": "no data",
+	}).Bytes())
+
+	strategy := &restoreStateStrategy{
+		serviceName:    "kava",
+		serviceManager: serviceManager,
+		nodeHome:       nodeHome,
+		snapshotURL:    server.URL,
+		httpClient:     server.Client(),
+	}
+
+	err := strategy.Heal(context.Background(), newTestLogger(), nil, HealerConfig{})
+
+	assert.True(t, errors.Is(err, ErrInvalidSnapshot))
+	assert.Empty(t, serviceManager.actions)
+
+	existing, err := os.ReadFile(filepath.Join(nodeHome, nodeDataDirectory, "application.db", "000001.ldb"))
+	assert.Nil(t, err)
+	assert.Equal(t, "stale state", string(existing))
+
+	assertNothingLeftNextTo(t, nodeHome)
+}
+
+func TestRestoreReplacedStateMovesPreviousStateBack(t *testing.T) {
+	nodeHome := newTestNodeHome(t)
+	staging := t.TempDir()
+	replaced := t.TempDir()
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(staging, nodeDataDirectory), 0755))
+	assert.Nil(t, os.MkdirAll(filepath.Join(staging, "wasm"), 0755))
+
+	swapped, err := swapState(nodeHome, staging, replaced, []string{nodeDataDirectory, "wasm"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{nodeDataDirectory, "wasm"}, swapped)
+
+	err = restoreReplacedState(nodeHome, replaced, swapped)
+
+	assert.Nil(t, err)
+
+	existing, err := os.ReadFile(filepath.Join(nodeHome, nodeDataDirectory, "application.db", "000001.ldb"))
+	assert.Nil(t, err)
+	assert.Equal(t, "stale state", string(existing))
+
+	// entries the node didn't have before are removed
+	_, err = os.Stat(filepath.Join(nodeHome, "wasm"))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
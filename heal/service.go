@@ -0,0 +1,250 @@
+package heal
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// supported service managers
+	SystemdServiceManager = "systemd"
+	OpenRCServiceManager  = "openrc"
+	LaunchdServiceManager = "launchd"
+	WindowsServiceManager = "windows"
+	// runs a configured command to restart the service
+	ExecServiceManager = "exec"
+
+	DefaultServiceManager = SystemdServiceManager
+)
+
+var (
+	ValidServiceManagers = []string{SystemdServiceManager, OpenRCServiceManager, LaunchdServiceManager, WindowsServiceManager, ExecServiceManager}
+
+	ErrUnsupportedServiceAction = errors.New("action not supported by service manager")
+)
+
+// ServiceManager restarts, stops and starts the
+// service running the blockchain using the host's
+// init system (or a configured command)
+type ServiceManager interface {
+	// Restart restarts the service, returning error (if any)
+	Restart() error
+	// Stop stops the service, returning error (if any)
+	Stop() error
+	// Start starts the service, returning error (if any)
+	Start() error
+}
+
+// ServiceManagerConfig wraps values
+// for creating a ServiceManager
+type ServiceManagerConfig struct {
+	// one of ValidServiceManagers, defaults to systemd
+	Manager string
+	// name of the service (e.g. systemd unit or launchd label)
+	ServiceName string
+	// binary and arguments run to restart the service
+	// when using the exec service manager
+	RestartCommand []string
+	// whether to run the service manager's commands with
+	// (non-interactive) sudo, ignored for the windows and
+	// exec service managers
+	Sudo bool
+	// optional path of the unix socket of a privileged service
+	// helper to ask to manage the service instead of managing
+	// it directly, see ServeServiceHelper
+	HelperSocket string
+	// optional commands run in order before each restart
+	// of the service and how long each may run for (or
+	// DefaultPreRestartTimeout if not positive)
+	PreRestartCommands [][]string
+	PreRestartTimeout  time.Duration
+}
+
+// NewServiceManager returns the service manager specified in the
+// config, returning error (if any) if the manager isn't supported
+// or is missing required config
+func NewServiceManager(config ServiceManagerConfig) (ServiceManager, error) {
+	manager, err := newServiceManager(config)
+
+	if err != nil || len(config.PreRestartCommands) == 0 {
+		return manager, err
+	}
+
+	for _, command := range config.PreRestartCommands {
+		if len(command) == 0 {
+			return nil, fmt.Errorf("empty pre restart command")
+		}
+	}
+
+	timeout := config.PreRestartTimeout
+
+	if timeout <= 0 {
+		timeout = DefaultPreRestartTimeout
+	}
+
+	return &preRestartServiceManager{
+		ServiceManager: manager,
+		commands:       config.PreRestartCommands,
+		timeout:        timeout,
+	}, nil
+}
+
+// newServiceManager returns the service manager specified
+// in the config without any pre restart commands
+func newServiceManager(config ServiceManagerConfig) (ServiceManager, error) {
+	name := config.ServiceName
+
+	if config.HelperSocket != "" {
+		return &helperServiceManager{
+			serviceName: name,
+			socketPath:  config.HelperSocket,
+		}, nil
+	}
+
+	var manager commandServiceManager
+
+	switch config.Manager {
+	case SystemdServiceManager, "":
+		manager = commandServiceManager{
+			serviceName: name,
+			restart:     []string{"systemctl", "restart", name},
+			stop:        []string{"systemctl", "stop", name},
+			start:       []string{"systemctl", "start", name},
+		}
+	case OpenRCServiceManager:
+		manager = commandServiceManager{
+			serviceName: name,
+			restart:     []string{"rc-service", name, "restart"},
+			stop:        []string{"rc-service", name, "stop"},
+			start:       []string{"rc-service", name, "start"},
+		}
+	case LaunchdServiceManager:
+		manager = commandServiceManager{
+			serviceName: name,
+			restart:     []string{"launchctl", "kickstart", "-k", "system/" + name},
+			stop:        []string{"launchctl", "stop", name},
+			start:       []string{"launchctl", "start", name},
+		}
+	case WindowsServiceManager:
+		return &commandServiceManager{
+			serviceName: name,
+			restart:     []string{"powershell", "-NoProfile", "-Command", "Restart-Service", "-Name", name},
+			stop:        []string{"powershell", "-NoProfile", "-Command", "Stop-Service", "-Name", name},
+			start:       []string{"powershell", "-NoProfile", "-Command", "Start-Service", "-Name", name},
+		}, nil
+	case ExecServiceManager:
+		if len(config.RestartCommand) == 0 {
+			return nil, fmt.Errorf("a restart command is required for the %s service manager", ExecServiceManager)
+		}
+
+		return &commandServiceManager{
+			serviceName: name,
+			restart:     config.RestartCommand,
+		}, nil
+	default:
+		return nil, fmt.Errorf("invalid service manager %s, valid service managers are %v", config.Manager, ValidServiceManagers)
+	}
+
+	if config.Sudo {
+		// fail instead of waiting on a password prompt
+		// if doctor isn't allowed to run the command
+		manager.restart = append([]string{"sudo", "-n"}, manager.restart...)
+		manager.stop = append([]string{"sudo", "-n"}, manager.stop...)
+		manager.start = append([]string{"sudo", "-n"}, manager.start...)
+	}
+
+	return &manager, nil
+}
+
+// ParseCommand splits the command into the binary and its arguments
+// on whitespace, keeping whitespace inside single or double quotes
+// and after a backslash, returning error (if any) if a quote isn't
+// terminated
+func ParseCommand(command string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	var inArg, escaped bool
+
+	for _, char := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(char)
+			escaped = false
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else if char == '\\' && quote == '"' {
+				escaped = true
+			} else {
+				arg.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArg = true
+		case char == '\\':
+			escaped = true
+			inArg = true
+		case char == ' ' || char == '\t' || char == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(char)
+			inArg = true
+		}
+	}
+
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape in command %s", command)
+	}
+
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// commandServiceManager implements the ServiceManager
+// interface, running a command for each action on the
+// service, actions without a command are unsupported
+type commandServiceManager struct {
+	serviceName string
+	restart     []string
+	stop        []string
+	start       []string
+}
+
+func (cm *commandServiceManager) Restart() error {
+	return cm.run("restarting", cm.restart)
+}
+
+func (cm *commandServiceManager) Stop() error {
+	return cm.run("stopping", cm.stop)
+}
+
+func (cm *commandServiceManager) Start() error {
+	return cm.run("starting", cm.start)
+}
+
+// run runs the command for the action, returning
+// error (if any) including the command's output
+func (cm *commandServiceManager) run(action string, command []string) error {
+	if len(command) == 0 {
+		return fmt.Errorf("%w: %s %s service", ErrUnsupportedServiceAction, action, cm.serviceName)
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("error %s %s %s service running %s output %s", err, action, cm.serviceName, strings.Join(command, " "), string(output))
+	}
+
+	return nil
+}
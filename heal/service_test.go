@@ -0,0 +1,86 @@
+package heal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewServiceManagerRejectsInvalidManager(t *testing.T) {
+	_, err := NewServiceManager(ServiceManagerConfig{
+		Manager:     "upstart",
+		ServiceName: "kava",
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestNewServiceManagerDefaultsToSystemd(t *testing.T) {
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		ServiceName: "kava",
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"systemctl", "restart", "kava"}, serviceManager.(*commandServiceManager).restart)
+}
+
+func TestNewServiceManagerUsesNonInteractiveSudo(t *testing.T) {
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		Manager:     OpenRCServiceManager,
+		ServiceName: "kava",
+		Sudo:        true,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"sudo", "-n", "rc-service", "kava", "stop"}, serviceManager.(*commandServiceManager).stop)
+}
+
+func TestExecServiceManagerRequiresRestartCommand(t *testing.T) {
+	_, err := NewServiceManager(ServiceManagerConfig{
+		Manager:     ExecServiceManager,
+		ServiceName: "kava",
+	})
+
+	assert.NotNil(t, err)
+}
+
+func TestExecServiceManagerOnlySupportsRestart(t *testing.T) {
+	serviceManager, err := NewServiceManager(ServiceManagerConfig{
+		Manager:        ExecServiceManager,
+		ServiceName:    "kava",
+		RestartCommand: []string{"true"},
+		Sudo:           true,
+	})
+
+	assert.Nil(t, err)
+	assert.Nil(t, serviceManager.Restart())
+
+	err = serviceManager.Stop()
+
+	assert.True(t, errors.Is(err, ErrUnsupportedServiceAction))
+}
+
+func TestParseCommand(t *testing.T) {
+	testCases := []struct {
+		command  string
+		expected []string
+	}{
+		{"", nil},
+		{"docker restart kava", []string{"docker", "restart", "kava"}},
+		{"  docker   restart\tkava ", []string{"docker", "restart", "kava"}},
+		{`sh -c "docker restart kava && echo 'restarted'"`, []string{"sh", "-c", "docker restart kava && echo 'restarted'"}},
+		{`echo 'a "quoted" arg' escaped\ space ""`, []string{"echo", `a "quoted" arg`, "escaped space", ""}},
+	}
+
+	for _, testCase := range testCases {
+		args, err := ParseCommand(testCase.command)
+
+		assert.Nil(t, err, testCase.command)
+		assert.Equal(t, testCase.expected, args, testCase.command)
+	}
+
+	_, err := ParseCommand(`sh -c "unterminated`)
+
+	assert.NotNil(t, err)
+}
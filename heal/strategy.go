@@ -0,0 +1,541 @@
+package heal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	// built in heal strategies
+	StandbyStrategyName          = "standby"
+	RestartServiceStrategyName   = "restart-service"
+	RebootInstanceStrategyName   = "reboot-instance"
+	RestoreStateStrategyName     = "restore-state"
+	ReplaceInstanceStrategyName  = "replace-instance"
+	DeregisterTargetStrategyName = "deregister-target"
+	DrainDNSRecordStrategyName   = "drain-dns-record"
+	ExecHookStrategyName         = "exec-hook"
+	RefreshPeersStrategyName     = "refresh-peers"
+
+	DefaultEscalationDelay = 10 * time.Minute
+	// how often to check if the node has caught up
+	// while waiting to escalate to the next strategy
+	catchUpCheckInterval = 1 * time.Minute
+)
+
+// Strategy is a single action that can
+// be taken to heal an out of sync node
+type Strategy interface {
+	// Name returns the name the strategy is registered under
+	Name() string
+	// Heal attempts to heal the node, returning error (if any)
+	Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error
+}
+
+// ConditionalStrategy is implemented by strategies that are
+// only worth attempting under certain conditions (e.g. the node
+// being far behind live), strategies that don't apply are
+// skipped when escalating through a strategy chain
+type ConditionalStrategy interface {
+	// Applies returns whether the strategy should be
+	// attempted for the node being healed
+	Applies(healerConfig HealerConfig) bool
+}
+
+// BlockedFunc returns the reason (if any) the node
+// must not be acted on to heal it at the moment,
+// e.g. the doctor being in maintenance mode
+type BlockedFunc func() error
+
+// check returns the reason (if any) the node must
+// not be acted on, nil if there is nothing to check
+func (blocked BlockedFunc) check() error {
+	if blocked == nil {
+		return nil
+	}
+
+	return blocked()
+}
+
+// StrategyConfig wraps values used
+// for creating a heal strategy
+type StrategyConfig struct {
+	BlockchainServiceName string
+	// optional check run before each step a strategy takes
+	// on the node, as the conditions autohealing is blocked
+	// under can start while a heal is in progress
+	Blocked BlockedFunc
+	// manages the blockchain service, defaults
+	// to systemd if not set
+	ServiceManager ServiceManager
+	// optional breaker capping how often the
+	// blockchain service is restarted
+	RestartBreaker *RestartBreaker
+	// how long to wait for the node to come back up and sync a new
+	// block after restarting the blockchain service before the restart
+	// is considered failed, 0 considers the restart done once the
+	// service manager returns
+	RestartVerificationTimeout time.Duration
+	// home directory of the node, optional url of a snapshot to
+	// restore the node's state from (state sync is used if not
+	// set) and how far behind live the node must be for its state
+	// to be restored when used as part of a strategy chain
+	NodeHome                     string
+	SnapshotURL                  string
+	RestoreStateThresholdSeconds int64
+	// how to have the autoscaling group replace the instance,
+	// the minimum number of other healthy in service instances
+	// required to replace it and the optional name of a termination
+	// lifecycle hook to complete once the node is stopped
+	ReplaceMethod              string
+	ReplaceMinHealthyInstances int
+	ReplaceLifecycleHookName   string
+	// load balancer target groups to deregister the instance from
+	TargetGroupARNs []string
+	// the node's Route53 weighted record to drain
+	Route53HostedZoneId  string
+	Route53RecordName    string
+	Route53RecordType    string
+	Route53SetIdentifier string
+	// command run by the exec-hook strategy and
+	// how long it may run for before being killed
+	ExecHookCommand []string
+	ExecHookTimeout time.Duration
+	// known good persistent peers and seeds (each in the form
+	// <node id>@<host>:<port>) to reconnect the node to
+	RefreshPeers []string
+	RefreshSeeds []string
+}
+
+// serviceManager returns the configured service manager, defaulting
+// to managing the blockchain service with systemd
+func (config StrategyConfig) serviceManager() (ServiceManager, error) {
+	if config.ServiceManager != nil {
+		return config.ServiceManager, nil
+	}
+
+	return NewServiceManager(ServiceManagerConfig{
+		ServiceName: config.BlockchainServiceName,
+		Sudo:        true,
+	})
+}
+
+// StrategyFactory creates a heal strategy
+// using the provided config, returning error (if any)
+type StrategyFactory func(config StrategyConfig) (Strategy, error)
+
+var (
+	strategyRegistryLock = &sync.RWMutex{}
+	strategyRegistry     = map[string]StrategyFactory{
+		StandbyStrategyName:          newStandbyStrategy,
+		RestartServiceStrategyName:   newRestartServiceStrategy,
+		RebootInstanceStrategyName:   newRebootInstanceStrategy,
+		RestoreStateStrategyName:     newRestoreStateStrategy,
+		ReplaceInstanceStrategyName:  newReplaceInstanceStrategy,
+		DeregisterTargetStrategyName: newDeregisterTargetStrategy,
+		DrainDNSRecordStrategyName:   newDrainDNSRecordStrategy,
+		ExecHookStrategyName:         newExecHookStrategy,
+		RefreshPeersStrategyName:     newRefreshPeersStrategy,
+	}
+)
+
+// RegisterStrategy registers factory for creating
+// the heal strategy with the specified name,
+// replacing any strategy already registered with that name
+func RegisterStrategy(name string, factory StrategyFactory) {
+	strategyRegistryLock.Lock()
+	defer strategyRegistryLock.Unlock()
+
+	strategyRegistry[name] = factory
+}
+
+// ValidStrategies returns the names of all registered heal strategies
+func ValidStrategies() []string {
+	strategyRegistryLock.RLock()
+	defer strategyRegistryLock.RUnlock()
+
+	var names []string
+
+	for name := range strategyRegistry {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NewStrategy creates the heal strategy registered with
+// the specified name using the provided config, returning error
+// (if any) if no strategy is registered with that name
+func NewStrategy(name string, config StrategyConfig) (Strategy, error) {
+	strategyRegistryLock.RLock()
+	factory, registered := strategyRegistry[name]
+	strategyRegistryLock.RUnlock()
+
+	if !registered {
+		return nil, fmt.Errorf("invalid heal strategy %s, valid strategies are %v", name, ValidStrategies())
+	}
+
+	return factory(config)
+}
+
+// ChainedStrategy wraps a strategy with the maximum number of
+// times it will be attempted per incident before escalating
+type ChainedStrategy struct {
+	Strategy
+	// 0 allows unlimited attempts
+	MaxAttempts int
+}
+
+// StrategyChainConfig wraps values used
+// for creating a StrategyChain
+type StrategyChainConfig struct {
+	// strategies to attempt in order of escalation
+	Strategies []ChainedStrategy
+	// how long to wait for the node to catch up after a
+	// strategy is attempted before the next attempt is allowed
+	EscalationDelay time.Duration
+	// optional check run before each strategy is attempted
+	Blocked BlockedFunc
+}
+
+// StrategyChain implements the Healer interface, escalating through
+// an ordered list of heal strategies, attempting each strategy up to
+// its maximum attempts per incident before moving to the next strategy
+type StrategyChain struct {
+	strategies      []ChainedStrategy
+	escalationDelay time.Duration
+	blocked         BlockedFunc
+	// escalation state for the current incident
+	incidentId string
+	attempts   []int
+	// strategies escalated past early for the current
+	// incident, e.g. restarts the node didn't recover from
+	exhausted []bool
+	// whether all strategies being exhausted for the
+	// current incident has been reported
+	exhaustionReported bool
+	lock               *sync.Mutex
+}
+
+// NewStrategyChain returns a new StrategyChain using the provided config
+func NewStrategyChain(config StrategyChainConfig) *StrategyChain {
+	return &StrategyChain{
+		strategies:      config.Strategies,
+		escalationDelay: config.EscalationDelay,
+		blocked:         config.Blocked,
+		attempts:        make([]int, len(config.Strategies)),
+		exhausted:       make([]bool, len(config.Strategies)),
+		lock:            &sync.Mutex{},
+	}
+}
+
+// HealOutOfSyncNode attempts the next strategy for the incident the node
+// is being healed for, then waits up to the escalation delay for the node
+// to catch back up to live before returning
+// A strategy that restarted the node without the node coming back up
+// isn't attempted again for the incident, escalating to the next strategy
+// No strategy is attempted while the chain's blocked check fails
+func (sc *StrategyChain) HealOutOfSyncNode(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) {
+	if err := sc.blocked.check(); err != nil {
+		logger.Infof("AutoHeal: %s, not attempting heal strategies for incident %s", err, healerConfig.IncidentId)
+
+		return
+	}
+
+	strategy, attempt, found := sc.nextStrategy(healerConfig)
+
+	if !found {
+		// checked every time the node is out of sync, so
+		// only alert once per incident
+		if sc.reportExhaustion(healerConfig) {
+			logger.Errorf("AutoHeal: all heal strategies exhausted for incident %s, node requires manual intervention", healerConfig.IncidentId)
+		} else {
+			logger.Debugf("AutoHeal: all heal strategies exhausted for incident %s", healerConfig.IncidentId)
+		}
+
+		return
+	}
+
+	logger.Infof("AutoHeal: attempting heal strategy %s (attempt %d) for incident %s", strategy.Name(), attempt, healerConfig.IncidentId)
+
+	err := strategy.Heal(ctx, logger, kavaClient, healerConfig)
+
+	if err != nil {
+		logger.Errorf("AutoHeal: error %s attempting heal strategy %s", err, strategy.Name())
+
+		if errors.Is(err, ErrRestartNotVerified) {
+			sc.exhaust(healerConfig, strategy)
+		}
+
+		return
+	}
+
+	// give the node time to recover before
+	// allowing escalation to the next attempt
+	if sc.escalationDelay > 0 {
+		waitUntilCaughtUp(ctx, logger, kavaClient, healerConfig, sc.escalationDelay)
+	}
+}
+
+// nextStrategy returns the next strategy to attempt for
+// the incident being healed, the attempt number for the strategy and
+// false if all strategies have been exhausted
+func (sc *StrategyChain) nextStrategy(healerConfig HealerConfig) (Strategy, int, bool) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	// start escalating from the first strategy for each new incident
+	if healerConfig.IncidentId != sc.incidentId {
+		sc.incidentId = healerConfig.IncidentId
+		sc.attempts = make([]int, len(sc.strategies))
+		sc.exhausted = make([]bool, len(sc.strategies))
+		sc.exhaustionReported = false
+	}
+
+	for i, strategy := range sc.strategies {
+		if strategy.MaxAttempts > 0 && sc.attempts[i] >= strategy.MaxAttempts {
+			continue
+		}
+
+		if sc.exhausted[i] {
+			continue
+		}
+
+		// escalate past restarting a service systemd is already restarting
+		if healerConfig.ServiceRestartLoop && strategy.Name() == RestartServiceStrategyName {
+			continue
+		}
+
+		if conditional, ok := strategy.Strategy.(ConditionalStrategy); ok && !conditional.Applies(healerConfig) {
+			continue
+		}
+
+		sc.attempts[i]++
+
+		return strategy.Strategy, sc.attempts[i], true
+	}
+
+	return nil, 0, false
+}
+
+// reportExhaustion returns true the first time it's called
+// for the incident being healed and false afterwards
+func (sc *StrategyChain) reportExhaustion(healerConfig HealerConfig) bool {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	if healerConfig.IncidentId != sc.incidentId || sc.exhaustionReported {
+		return false
+	}
+
+	sc.exhaustionReported = true
+
+	return true
+}
+
+// exhaust escalates past the strategy for the rest of
+// the incident being healed, regardless of its remaining attempts
+func (sc *StrategyChain) exhaust(healerConfig HealerConfig, exhausted Strategy) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	if healerConfig.IncidentId != sc.incidentId {
+		return
+	}
+
+	for i, strategy := range sc.strategies {
+		if strategy.Strategy == exhausted {
+			sc.exhausted[i] = true
+		}
+	}
+}
+
+// waitUntilCaughtUp waits until the node is within the sync to live
+// tolerance, the timeout elapses or the context is cancelled
+func waitUntilCaughtUp(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		kavaStatus, err := kavaClient.GetNodeState()
+
+		if err == nil && int64(time.Since(kavaStatus.SyncInfo.LatestBlockTime).Seconds()) <= int64(healerConfig.AutohealSyncToLiveToleranceSeconds) {
+			logger.Infof("AutoHeal: node caught back up to %d seconds behind current time", healerConfig.AutohealSyncToLiveToleranceSeconds)
+
+			return
+		}
+
+		remaining := time.Until(deadline)
+
+		if remaining <= 0 {
+			logger.Warnf("AutoHeal: node did not catch up within %v, escalating on next heal attempt", timeout)
+
+			return
+		}
+
+		if remaining > catchUpCheckInterval {
+			remaining = catchUpCheckInterval
+		}
+
+		if !sleepUnlessCancelled(ctx, remaining) {
+			return
+		}
+	}
+}
+
+// standbyStrategy implements the Strategy interface, placing the
+// host on standby with its autoscaling group until it catches up
+type standbyStrategy struct {
+	awsDoctor *AwsDoctor
+}
+
+func newStandbyStrategy(config StrategyConfig) (Strategy, error) {
+	awsDoctor, err := NewAwsDoctor()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &standbyStrategy{awsDoctor: awsDoctor}, nil
+}
+
+func (ss *standbyStrategy) Name() string {
+	return StandbyStrategyName
+}
+
+func (ss *standbyStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	return ss.awsDoctor.healOutOfSyncNode(ctx, logger, kavaClient, healerConfig)
+}
+
+// restartServiceStrategy implements the Strategy
+// interface, restarting the blockchain's service
+type restartServiceStrategy struct {
+	serviceName         string
+	serviceManager      ServiceManager
+	breaker             *RestartBreaker
+	verificationTimeout time.Duration
+	blocked             BlockedFunc
+}
+
+func newRestartServiceStrategy(config StrategyConfig) (Strategy, error) {
+	if config.BlockchainServiceName == "" {
+		return nil, fmt.Errorf("a blockchain service name is required for the %s heal strategy", RestartServiceStrategyName)
+	}
+
+	serviceManager, err := config.serviceManager()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &restartServiceStrategy{
+		serviceName:         config.BlockchainServiceName,
+		serviceManager:      serviceManager,
+		breaker:             config.RestartBreaker,
+		verificationTimeout: config.RestartVerificationTimeout,
+		blocked:             config.Blocked,
+	}, nil
+}
+
+func (rs *restartServiceStrategy) Name() string {
+	return RestartServiceStrategyName
+}
+
+func (rs *restartServiceStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	if err := rs.blocked.check(); err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestartServiceHealAction, false, fmt.Sprintf("%s, not restarting %s service", err, rs.serviceName))
+
+		return err
+	}
+
+	if rs.breaker != nil {
+		if err := rs.breaker.Allow(time.Now()); err != nil {
+			publishHealEvent(logger, healerConfig, incident.RestartServiceHealAction, false, fmt.Sprintf("%s, not restarting %s service", err, rs.serviceName))
+
+			return err
+		}
+	}
+
+	err := rs.serviceManager.Restart()
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestartServiceHealAction, false, fmt.Sprintf("error %s restarting %s service", err, rs.serviceName))
+
+		return err
+	}
+
+	if rs.verificationTimeout > 0 {
+		if err := VerifyRestart(ctx, kavaClient, rs.verificationTimeout); err != nil {
+			publishHealEvent(logger, healerConfig, incident.RestartServiceHealAction, false, fmt.Sprintf("restarted %s service but %s", rs.serviceName, err))
+
+			return err
+		}
+	}
+
+	publishHealEvent(logger, healerConfig, incident.RestartServiceHealAction, true, fmt.Sprintf("restarted %s service", rs.serviceName))
+
+	return nil
+}
+
+// rebootInstanceStrategy implements the Strategy interface,
+// rebooting the ec2 instance the doctor is running on
+type rebootInstanceStrategy struct {
+	ec2Client  *ec2.EC2
+	instanceId string
+	blocked    BlockedFunc
+}
+
+func newRebootInstanceStrategy(config StrategyConfig) (Strategy, error) {
+	awsSession, instanceId, err := newInstanceAWSSession()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &rebootInstanceStrategy{
+		ec2Client:  ec2.New(awsSession),
+		instanceId: instanceId,
+		blocked:    config.Blocked,
+	}, nil
+}
+
+func (ri *rebootInstanceStrategy) Name() string {
+	return RebootInstanceStrategyName
+}
+
+func (ri *rebootInstanceStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	if err := ri.blocked.check(); err != nil {
+		publishHealEvent(logger, healerConfig, incident.RebootInstanceHealAction, false, fmt.Sprintf("%s, not rebooting instance %s", err, ri.instanceId))
+
+		return err
+	}
+
+	_, err := ri.ec2Client.RebootInstancesWithContext(ctx, &ec2.RebootInstancesInput{
+		InstanceIds: []*string{
+			aws.String(ri.instanceId),
+		},
+	})
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RebootInstanceHealAction, false, fmt.Sprintf("error %s rebooting instance %s", err, ri.instanceId))
+
+		return err
+	}
+
+	// published as soon as the reboot is requested as the
+	// doctor runs on the instance being rebooted
+	publishHealEvent(logger, healerConfig, incident.RebootInstanceHealAction, true, fmt.Sprintf("rebooting instance %s", ri.instanceId))
+
+	return nil
+}
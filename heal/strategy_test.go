@@ -0,0 +1,183 @@
+package heal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeStrategy records each time it's attempted
+type fakeStrategy struct {
+	name     string
+	attempts *[]string
+	// optional error returned from each attempt
+	err error
+}
+
+func (fs *fakeStrategy) Name() string {
+	return fs.name
+}
+
+func (fs *fakeStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	*fs.attempts = append(*fs.attempts, fs.name)
+
+	return fs.err
+}
+
+func newTestLogger() *logging.Logger {
+	logMessages := make(chan logging.Entry, 100)
+
+	return logging.New(logMessages, logging.DebugLevel)
+}
+
+func TestStrategyChainEscalatesAfterMaxAttempts(t *testing.T) {
+	var attempts []string
+
+	chain := NewStrategyChain(StrategyChainConfig{
+		Strategies: []ChainedStrategy{
+			{Strategy: &fakeStrategy{name: StandbyStrategyName, attempts: &attempts}, MaxAttempts: 1},
+			{Strategy: &fakeStrategy{name: RestartServiceStrategyName, attempts: &attempts}, MaxAttempts: 2},
+		},
+	})
+
+	for i := 0; i < 4; i++ {
+		chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-1"})
+	}
+
+	// the fourth attempt finds all strategies exhausted
+	assert.Equal(t, []string{StandbyStrategyName, RestartServiceStrategyName, RestartServiceStrategyName}, attempts)
+}
+
+func TestStrategyChainRestartsEscalationForNewIncident(t *testing.T) {
+	var attempts []string
+
+	chain := NewStrategyChain(StrategyChainConfig{
+		Strategies: []ChainedStrategy{
+			{Strategy: &fakeStrategy{name: StandbyStrategyName, attempts: &attempts}, MaxAttempts: 1},
+			{Strategy: &fakeStrategy{name: RestartServiceStrategyName, attempts: &attempts}},
+		},
+	})
+
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-1"})
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-1"})
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-2"})
+
+	assert.Equal(t, []string{StandbyStrategyName, RestartServiceStrategyName, StandbyStrategyName}, attempts)
+}
+
+func TestStrategyChainEscalatesPastUnverifiedRestarts(t *testing.T) {
+	var attempts []string
+
+	chain := NewStrategyChain(StrategyChainConfig{
+		Strategies: []ChainedStrategy{
+			{Strategy: &fakeStrategy{name: RestartServiceStrategyName, attempts: &attempts, err: fmt.Errorf("%w: block height stuck at 100", ErrRestartNotVerified)}, MaxAttempts: 3},
+			{Strategy: &fakeStrategy{name: RebootInstanceStrategyName, attempts: &attempts}},
+		},
+	})
+
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-1"})
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-1"})
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-2"})
+
+	assert.Equal(t, []string{RestartServiceStrategyName, RebootInstanceStrategyName, RestartServiceStrategyName}, attempts)
+}
+
+func TestStrategyChainDoesNotAttemptStrategiesWhileBlocked(t *testing.T) {
+	var attempts []string
+
+	blocked := errors.New("doctor is in maintenance mode")
+
+	chain := NewStrategyChain(StrategyChainConfig{
+		Strategies: []ChainedStrategy{
+			{Strategy: &fakeStrategy{name: StandbyStrategyName, attempts: &attempts}, MaxAttempts: 1},
+			{Strategy: &fakeStrategy{name: RestartServiceStrategyName, attempts: &attempts}},
+		},
+		Blocked: func() error {
+			return blocked
+		},
+	})
+
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-1"})
+
+	assert.Empty(t, attempts)
+
+	// attempts aren't used up while blocked
+	blocked = nil
+
+	chain.HealOutOfSyncNode(context.Background(), newTestLogger(), nil, HealerConfig{IncidentId: "incident-1"})
+
+	assert.Equal(t, []string{StandbyStrategyName}, attempts)
+}
+
+func TestStrategyChainReportsExhaustionOncePerIncident(t *testing.T) {
+	var attempts []string
+
+	chain := NewStrategyChain(StrategyChainConfig{
+		Strategies: []ChainedStrategy{
+			{Strategy: &fakeStrategy{name: RestartServiceStrategyName, attempts: &attempts}, MaxAttempts: 1},
+		},
+	})
+
+	_, _, found := chain.nextStrategy(HealerConfig{IncidentId: "incident-1"})
+
+	assert.True(t, found)
+
+	_, _, found = chain.nextStrategy(HealerConfig{IncidentId: "incident-1"})
+
+	assert.False(t, found)
+	assert.True(t, chain.reportExhaustion(HealerConfig{IncidentId: "incident-1"}))
+	assert.False(t, chain.reportExhaustion(HealerConfig{IncidentId: "incident-1"}))
+
+	// escalation starts over for a new incident
+	chain.nextStrategy(HealerConfig{IncidentId: "incident-2"})
+	_, _, found = chain.nextStrategy(HealerConfig{IncidentId: "incident-2"})
+
+	assert.False(t, found)
+	assert.True(t, chain.reportExhaustion(HealerConfig{IncidentId: "incident-2"}))
+}
+
+func TestRestartServiceStrategyDoesNotRestartWhileBlocked(t *testing.T) {
+	serviceManager := &fakeServiceManager{}
+	blocked := errors.New("chain is degraded")
+
+	strategy, err := NewStrategy(RestartServiceStrategyName, StrategyConfig{
+		BlockchainServiceName: "kava",
+		ServiceManager:        serviceManager,
+		Blocked: func() error {
+			return blocked
+		},
+	})
+
+	assert.Nil(t, err)
+
+	err = strategy.Heal(context.Background(), newTestLogger(), nil, HealerConfig{})
+
+	assert.Equal(t, blocked, err)
+	assert.Empty(t, serviceManager.actions)
+}
+
+func TestStrategyChainSkipsStrategiesThatDontApply(t *testing.T) {
+	var attempts []string
+
+	chain := NewStrategyChain(StrategyChainConfig{
+		Strategies: []ChainedStrategy{
+			{Strategy: &restoreStateStrategy{minSecondsBehindLive: 86400}, MaxAttempts: 1},
+			{Strategy: &fakeStrategy{name: RestartServiceStrategyName, attempts: &attempts}},
+		},
+	})
+
+	strategy, _, found := chain.nextStrategy(HealerConfig{IncidentId: "incident-1", SecondsBehindLive: 600})
+
+	assert.True(t, found)
+	assert.Equal(t, RestartServiceStrategyName, strategy.Name())
+
+	strategy, _, found = chain.nextStrategy(HealerConfig{IncidentId: "incident-1", SecondsBehindLive: 2 * 86400})
+
+	assert.True(t, found)
+	assert.Equal(t, RestoreStateStrategyName, strategy.Name())
+}
@@ -0,0 +1,87 @@
+package heal
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	SystemdActiveState = "active"
+	// sub state of a unit waiting to be restarted by systemd
+	SystemdAutoRestartSubState = "auto-restart"
+)
+
+// SystemdServiceState wraps the state of a systemd service
+type SystemdServiceState struct {
+	ActiveState string
+	SubState    string
+	// number of times systemd has restarted the service
+	// since it was last started manually
+	NRestarts int
+}
+
+// GetSystemdServiceState returns the state of the
+// systemd service with the specified name and error (if any)
+func GetSystemdServiceState(serviceName string) (SystemdServiceState, error) {
+	cmd := exec.Command("systemctl", "show", serviceName, "--property=ActiveState,SubState,NRestarts")
+	output, err := cmd.CombinedOutput()
+
+	if err != nil {
+		return SystemdServiceState{}, fmt.Errorf("error %s getting state of %s service output %s", err, serviceName, string(output))
+	}
+
+	return parseSystemdServiceState(string(output))
+}
+
+// parseSystemdServiceState parses the key=value output
+// of `systemctl show`, returning the state and error (if any)
+func parseSystemdServiceState(output string) (SystemdServiceState, error) {
+	var state SystemdServiceState
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+
+		if !found {
+			continue
+		}
+
+		switch key {
+		case "ActiveState":
+			state.ActiveState = value
+		case "SubState":
+			state.SubState = value
+		case "NRestarts":
+			restarts, err := strconv.Atoi(value)
+
+			if err != nil {
+				return state, fmt.Errorf("error %s parsing NRestarts %s", err, value)
+			}
+
+			state.NRestarts = restarts
+		}
+	}
+
+	return state, nil
+}
+
+// RestartLoopDetector detects when systemd is repeatedly restarting
+// a service, where a service is considered to be in a restart loop
+// if systemd restarted it since the previous check or is about to
+// restart it, even if the service is momentarily active
+// RestartLoopDetector is not safe for concurrent use
+type RestartLoopDetector struct {
+	previousRestarts *int
+}
+
+// Check returns whether the service is in a restart loop
+// based on its current state and the state at the previous check
+func (rld *RestartLoopDetector) Check(state SystemdServiceState) bool {
+	restartedSinceLastCheck := rld.previousRestarts != nil && state.NRestarts > *rld.previousRestarts
+
+	restarts := state.NRestarts
+	rld.previousRestarts = &restarts
+
+	return restartedSinceLastCheck || state.SubState == SystemdAutoRestartSubState
+}
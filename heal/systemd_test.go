@@ -0,0 +1,29 @@
+package heal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartLoopDetectorDetectsRestartsBetweenChecks(t *testing.T) {
+	state, err := parseSystemdServiceState("ActiveState=active\nSubState=running\nNRestarts=3\n")
+
+	assert.Nil(t, err)
+	assert.Equal(t, SystemdServiceState{ActiveState: "active", SubState: "running", NRestarts: 3}, state)
+
+	var detector RestartLoopDetector
+
+	// no previous check to compare against
+	assert.False(t, detector.Check(state))
+	assert.False(t, detector.Check(state))
+
+	// restarted by systemd but momentarily active
+	state.NRestarts = 4
+	assert.True(t, detector.Check(state))
+
+	assert.False(t, detector.Check(state))
+
+	state.SubState = SystemdAutoRestartSubState
+	assert.True(t, detector.Check(state))
+}
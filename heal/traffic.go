@@ -0,0 +1,329 @@
+package heal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	DefaultRoute53RecordType = route53.RRTypeA
+)
+
+var (
+	ErrInsufficientHealthyTargets = errors.New("too few other healthy targets to deregister node")
+	ErrRecordNotFound             = errors.New("weighted record not found")
+)
+
+// waitUntilSynced waits until the node is within the sync to live
+// tolerance, returning false if the context was cancelled first
+func waitUntilSynced(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) bool {
+	for {
+		kavaStatus, err := kavaClient.GetNodeState()
+
+		if err != nil {
+			logger.Errorf("AutoHeal: error %s attempting to get kava status, sleeping for a minute before retrying", err)
+		} else if int64(time.Since(kavaStatus.SyncInfo.LatestBlockTime).Seconds()) <= int64(healerConfig.AutohealSyncToLiveToleranceSeconds) {
+			logger.Infof("AutoHeal: node caught back up to %d seconds behind current time", healerConfig.AutohealSyncToLiveToleranceSeconds)
+
+			return true
+		}
+
+		if !sleepUnlessCancelled(ctx, catchUpCheckInterval) {
+			return false
+		}
+	}
+}
+
+// deregisterTargetStrategy implements the Strategy interface,
+// deregistering the instance the doctor is running on from load
+// balancer target groups until it catches up, without changing the
+// capacity of its autoscaling group (if any) as standby does
+type deregisterTargetStrategy struct {
+	elbClient       *elbv2.ELBV2
+	instanceId      string
+	targetGroupARNs []string
+}
+
+func newDeregisterTargetStrategy(config StrategyConfig) (Strategy, error) {
+	if len(config.TargetGroupARNs) == 0 {
+		return nil, fmt.Errorf("at least one target group arn is required for the %s heal strategy", DeregisterTargetStrategyName)
+	}
+
+	awsSession, instanceId, err := newInstanceAWSSession()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &deregisterTargetStrategy{
+		elbClient:       elbv2.New(awsSession),
+		instanceId:      instanceId,
+		targetGroupARNs: config.TargetGroupARNs,
+	}, nil
+}
+
+func (dt *deregisterTargetStrategy) Name() string {
+	return DeregisterTargetStrategyName
+}
+
+// Heal deregisters the instance from each target group, waits for the
+// node to catch up and registers it again, leaving the instance
+// deregistered (to be registered again by the next heal attempt) if the
+// context is cancelled before then
+func (dt *deregisterTargetStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	targets := []*elbv2.TargetDescription{
+		{
+			Id: aws.String(dt.instanceId),
+		},
+	}
+
+	for _, targetGroupARN := range dt.targetGroupARNs {
+		// don't take the last healthy target(s) out of service
+		// as a lagging node is better than no node at all
+		if healerConfig.MinHealthyInServiceInstances > 0 {
+			health, err := dt.elbClient.DescribeTargetHealthWithContext(ctx, &elbv2.DescribeTargetHealthInput{
+				TargetGroupArn: aws.String(targetGroupARN),
+			})
+
+			if err != nil {
+				return fmt.Errorf("error %s describing health of target group %s", err, targetGroupARN)
+			}
+
+			otherHealthyTargets := countHealthyTargets(health.TargetHealthDescriptions, dt.instanceId)
+
+			if otherHealthyTargets < healerConfig.MinHealthyInServiceInstances {
+				message := fmt.Sprintf("refusing to deregister instance %s, only %d other healthy targets in target group %s, minimum %d", dt.instanceId, otherHealthyTargets, targetGroupARN, healerConfig.MinHealthyInServiceInstances)
+
+				publishHealEvent(logger, healerConfig, incident.DeregisterTargetHealAction, false, message)
+
+				return fmt.Errorf("%w: %s", ErrInsufficientHealthyTargets, message)
+			}
+		}
+
+		_, err := dt.elbClient.DeregisterTargetsWithContext(ctx, &elbv2.DeregisterTargetsInput{
+			TargetGroupArn: aws.String(targetGroupARN),
+			Targets:        targets,
+		})
+
+		if err != nil {
+			publishHealEvent(logger, healerConfig, incident.DeregisterTargetHealAction, false, fmt.Sprintf("error %s deregistering instance %s from target group %s", err, dt.instanceId, targetGroupARN))
+
+			return err
+		}
+
+		publishHealEvent(logger, healerConfig, incident.DeregisterTargetHealAction, true, fmt.Sprintf("deregistered instance %s from target group %s until it catches up", dt.instanceId, targetGroupARN))
+	}
+
+	if !waitUntilSynced(ctx, logger, kavaClient, healerConfig) {
+		logger.Warn("AutoHeal: aborting heal before node caught up, a deregistered instance will be registered again by the next heal attempt")
+
+		return nil
+	}
+
+	for _, targetGroupARN := range dt.targetGroupARNs {
+		_, err := dt.elbClient.RegisterTargetsWithContext(ctx, &elbv2.RegisterTargetsInput{
+			TargetGroupArn: aws.String(targetGroupARN),
+			Targets:        targets,
+		})
+
+		if err != nil {
+			publishHealEvent(logger, healerConfig, incident.RegisterTargetHealAction, false, fmt.Sprintf("error %s registering instance %s with target group %s", err, dt.instanceId, targetGroupARN))
+
+			return err
+		}
+
+		publishHealEvent(logger, healerConfig, incident.RegisterTargetHealAction, true, fmt.Sprintf("registered instance %s with target group %s after catching up", dt.instanceId, targetGroupARN))
+	}
+
+	return nil
+}
+
+// countHealthyTargets returns the number of healthy targets
+// excluding the target with the specified id
+func countHealthyTargets(targets []*elbv2.TargetHealthDescription, excludedTargetId string) int {
+	var healthy int
+
+	for _, target := range targets {
+		if target.Target == nil || target.TargetHealth == nil {
+			continue
+		}
+
+		if aws.StringValue(target.Target.Id) == excludedTargetId {
+			continue
+		}
+
+		if aws.StringValue(target.TargetHealth.State) == elbv2.TargetHealthStateEnumHealthy {
+			healthy++
+		}
+	}
+
+	return healthy
+}
+
+// drainDNSRecordStrategy implements the Strategy interface, setting
+// the weight of the node's Route53 weighted record to 0 until it
+// catches up so clients resolve to other nodes meanwhile
+type drainDNSRecordStrategy struct {
+	route53Client *route53.Route53
+	hostedZoneId  string
+	recordName    string
+	recordType    string
+	setIdentifier string
+	// weight the record had before it was first drained,
+	// restored once the node catches up
+	drainedFromWeight *int64
+}
+
+func newDrainDNSRecordStrategy(config StrategyConfig) (Strategy, error) {
+	if config.Route53HostedZoneId == "" || config.Route53RecordName == "" || config.Route53SetIdentifier == "" {
+		return nil, fmt.Errorf("a hosted zone id, record name and set identifier are required for the %s heal strategy", DrainDNSRecordStrategyName)
+	}
+
+	recordType := config.Route53RecordType
+
+	if recordType == "" {
+		recordType = DefaultRoute53RecordType
+	}
+
+	awsSession, _, err := newInstanceAWSSession()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &drainDNSRecordStrategy{
+		route53Client: route53.New(awsSession),
+		hostedZoneId:  config.Route53HostedZoneId,
+		recordName:    config.Route53RecordName,
+		recordType:    recordType,
+		setIdentifier: config.Route53SetIdentifier,
+	}, nil
+}
+
+func (dr *drainDNSRecordStrategy) Name() string {
+	return DrainDNSRecordStrategyName
+}
+
+// Heal sets the weight of the node's record to 0, waits for the node
+// to catch up and restores the record's weight, leaving the record
+// drained (to be restored by the next heal attempt) if the context is
+// cancelled before then
+func (dr *drainDNSRecordStrategy) Heal(ctx context.Context, logger *logging.Logger, kavaClient *kava.Client, healerConfig HealerConfig) error {
+	record, err := dr.getRecord(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	// a previous heal attempt may have been cancelled after draining
+	if dr.drainedFromWeight == nil {
+		weight := aws.Int64Value(record.Weight)
+
+		if weight == 0 {
+			return fmt.Errorf("record %s (%s) already has weight 0, not draining a record whose weight to restore is unknown", dr.recordName, dr.setIdentifier)
+		}
+
+		dr.drainedFromWeight = &weight
+	}
+
+	err = dr.setWeight(ctx, record, 0)
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.DrainDNSRecordHealAction, false, fmt.Sprintf("error %s draining record %s (%s)", err, dr.recordName, dr.setIdentifier))
+
+		return err
+	}
+
+	publishHealEvent(logger, healerConfig, incident.DrainDNSRecordHealAction, true, fmt.Sprintf("drained record %s (%s) from weight %d until the node catches up", dr.recordName, dr.setIdentifier, *dr.drainedFromWeight))
+
+	if !waitUntilSynced(ctx, logger, kavaClient, healerConfig) {
+		logger.Warn("AutoHeal: aborting heal before node caught up, a drained record will be restored by the next heal attempt")
+
+		return nil
+	}
+
+	err = dr.setWeight(ctx, record, *dr.drainedFromWeight)
+
+	if err != nil {
+		publishHealEvent(logger, healerConfig, incident.RestoreDNSRecordHealAction, false, fmt.Sprintf("error %s restoring record %s (%s) to weight %d", err, dr.recordName, dr.setIdentifier, *dr.drainedFromWeight))
+
+		return err
+	}
+
+	publishHealEvent(logger, healerConfig, incident.RestoreDNSRecordHealAction, true, fmt.Sprintf("restored record %s (%s) to weight %d after catching up", dr.recordName, dr.setIdentifier, *dr.drainedFromWeight))
+
+	dr.drainedFromWeight = nil
+
+	return nil
+}
+
+// getRecord returns the node's weighted record and error (if any)
+func (dr *drainDNSRecordStrategy) getRecord(ctx context.Context) (*route53.ResourceRecordSet, error) {
+	records, err := dr.route53Client.ListResourceRecordSetsWithContext(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:          aws.String(dr.hostedZoneId),
+		StartRecordName:       aws.String(dr.recordName),
+		StartRecordType:       aws.String(dr.recordType),
+		StartRecordIdentifier: aws.String(dr.setIdentifier),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s listing records in hosted zone %s", err, dr.hostedZoneId)
+	}
+
+	record := findWeightedRecord(records.ResourceRecordSets, dr.recordName, dr.recordType, dr.setIdentifier)
+
+	if record == nil {
+		return nil, fmt.Errorf("%w: %s %s (%s) in hosted zone %s", ErrRecordNotFound, dr.recordType, dr.recordName, dr.setIdentifier, dr.hostedZoneId)
+	}
+
+	return record, nil
+}
+
+// setWeight upserts the record with the specified weight
+func (dr *drainDNSRecordStrategy) setWeight(ctx context.Context, record *route53.ResourceRecordSet, weight int64) error {
+	updated := *record
+	updated.Weight = aws.Int64(weight)
+
+	_, err := dr.route53Client.ChangeResourceRecordSetsWithContext(ctx, &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(dr.hostedZoneId),
+		ChangeBatch: &route53.ChangeBatch{
+			Changes: []*route53.Change{
+				{
+					Action:            aws.String(route53.ChangeActionUpsert),
+					ResourceRecordSet: &updated,
+				},
+			},
+		},
+	})
+
+	return err
+}
+
+// findWeightedRecord returns the record with the specified name,
+// type and set identifier, nil if there is no such record
+func findWeightedRecord(records []*route53.ResourceRecordSet, name string, recordType string, setIdentifier string) *route53.ResourceRecordSet {
+	// route53 returns fully qualified names
+	name = strings.TrimSuffix(name, ".") + "."
+
+	for _, record := range records {
+		if !strings.EqualFold(aws.StringValue(record.Name), name) {
+			continue
+		}
+
+		if aws.StringValue(record.Type) == recordType && aws.StringValue(record.SetIdentifier) == setIdentifier {
+			return record
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package heal
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/elbv2"
+	"github.com/aws/aws-sdk-go/service/route53"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountHealthyTargetsExcludesNodeAndUnhealthyTargets(t *testing.T) {
+	target := func(id string, state string) *elbv2.TargetHealthDescription {
+		return &elbv2.TargetHealthDescription{
+			Target:       &elbv2.TargetDescription{Id: aws.String(id)},
+			TargetHealth: &elbv2.TargetHealth{State: aws.String(state)},
+		}
+	}
+
+	targets := []*elbv2.TargetHealthDescription{
+		target("i-node", elbv2.TargetHealthStateEnumHealthy),
+		target("i-healthy", elbv2.TargetHealthStateEnumHealthy),
+		target("i-draining", elbv2.TargetHealthStateEnumDraining),
+		target("i-unhealthy", elbv2.TargetHealthStateEnumUnhealthy),
+	}
+
+	assert.Equal(t, 1, countHealthyTargets(targets, "i-node"))
+}
+
+func TestFindWeightedRecordMatchesSetIdentifier(t *testing.T) {
+	records := []*route53.ResourceRecordSet{
+		{Name: aws.String("rpc.example.com."), Type: aws.String(route53.RRTypeA), SetIdentifier: aws.String("node-1"), Weight: aws.Int64(10)},
+		{Name: aws.String("rpc.example.com."), Type: aws.String(route53.RRTypeA), SetIdentifier: aws.String("node-2"), Weight: aws.Int64(20)},
+	}
+
+	record := findWeightedRecord(records, "rpc.example.com", route53.RRTypeA, "node-2")
+
+	if assert.NotNil(t, record) {
+		assert.Equal(t, int64(20), aws.Int64Value(record.Weight))
+	}
+
+	assert.Nil(t, findWeightedRecord(records, "rpc.example.com", route53.RRTypeA, "node-3"))
+	assert.Nil(t, findWeightedRecord(records, "rpc.example.com", route53.RRTypeAaaa, "node-1"))
+}
@@ -0,0 +1,376 @@
+// package host provides functions for sampling the health of the host
+// running the node (load, memory, network and disk io) from the
+// counters the linux kernel exposes under /proc
+package host
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	DefaultProcPath = "/proc"
+)
+
+// block devices that don't represent disks the node reads
+// from or writes to and are excluded from disk io metrics
+var excludedDevicePrefixes = []string{"loop", "ram", "zram"}
+
+// Stats wraps the host's load averages and memory usage along with
+// the cumulative network and disk io counters read from /proc
+type Stats struct {
+	LoadAverage1m        float64
+	LoadAverage5m        float64
+	LoadAverage15m       float64
+	MemoryTotalBytes     uint64
+	MemoryAvailableBytes uint64
+	// cumulative counters keyed by interface name
+	Network map[string]NetworkCounters
+	// cumulative counters keyed by block device name
+	Disks  map[string]DiskCounters
+	ReadAt time.Time
+}
+
+// NetworkCounters wraps the bytes an interface has
+// received and transmitted since the host booted
+type NetworkCounters struct {
+	ReceiveBytes  uint64
+	TransmitBytes uint64
+}
+
+// DiskCounters wraps the reads and writes a block device
+// has completed and the time spent on them since the host booted
+type DiskCounters struct {
+	Reads             uint64
+	ReadMilliseconds  uint64
+	Writes            uint64
+	WriteMilliseconds uint64
+}
+
+// ReadStats reads the host's current stats from the proc
+// filesystem mounted at procPath, returning error (if any)
+func ReadStats(procPath string) (Stats, error) {
+	stats := Stats{
+		ReadAt: time.Now(),
+	}
+
+	err := readLoadAverage(filepath.Join(procPath, "loadavg"), &stats)
+
+	if err != nil {
+		return stats, err
+	}
+
+	err = readMemory(filepath.Join(procPath, "meminfo"), &stats)
+
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Network, err = readNetwork(filepath.Join(procPath, "net", "dev"))
+
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Disks, err = readDisks(filepath.Join(procPath, "diskstats"))
+
+	return stats, err
+}
+
+// readLoadAverage parses the load averages from /proc/loadavg
+func readLoadAverage(path string, stats *Stats) error {
+	contents, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("error %s reading load average", err)
+	}
+
+	fields := strings.Fields(string(contents))
+
+	if len(fields) < 3 {
+		return fmt.Errorf("unexpected load average format %s", string(contents))
+	}
+
+	loadAverages := []*float64{&stats.LoadAverage1m, &stats.LoadAverage5m, &stats.LoadAverage15m}
+
+	for i, loadAverage := range loadAverages {
+		*loadAverage, err = strconv.ParseFloat(fields[i], 64)
+
+		if err != nil {
+			return fmt.Errorf("error %s parsing load average %s", err, fields[i])
+		}
+	}
+
+	return nil
+}
+
+// readMemory parses the total and available memory from /proc/meminfo
+func readMemory(path string, stats *Stats) error {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return fmt.Errorf("error %s reading memory info", err)
+	}
+
+	defer file.Close()
+
+	var foundTotal, foundAvailable bool
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		// e.g. MemAvailable:   12345678 kB
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) < 2 {
+			continue
+		}
+
+		var value *uint64
+
+		switch fields[0] {
+		case "MemTotal:":
+			value = &stats.MemoryTotalBytes
+			foundTotal = true
+		case "MemAvailable:":
+			value = &stats.MemoryAvailableBytes
+			foundAvailable = true
+		default:
+			continue
+		}
+
+		kilobytes, err := strconv.ParseUint(fields[1], 10, 64)
+
+		if err != nil {
+			return fmt.Errorf("error %s parsing memory info line %s", err, scanner.Text())
+		}
+
+		*value = kilobytes * 1024
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error %s reading memory info", err)
+	}
+
+	if !foundTotal || !foundAvailable {
+		return fmt.Errorf("MemTotal or MemAvailable missing from %s", path)
+	}
+
+	return nil
+}
+
+// readNetwork parses the bytes received and transmitted
+// by each interface (except loopback) from /proc/net/dev
+func readNetwork(path string) (map[string]NetworkCounters, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s reading network stats", err)
+	}
+
+	defer file.Close()
+
+	network := make(map[string]NetworkCounters)
+
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		// e.g. eth0: 1234 56 0 0 0 0 0 0 7890 12 0 0 0 0 0 0
+		// the first two lines are headers without a colon
+		name, counters, found := strings.Cut(scanner.Text(), ":")
+
+		if !found {
+			continue
+		}
+
+		name = strings.TrimSpace(name)
+
+		if name == "lo" {
+			continue
+		}
+
+		fields := strings.Fields(counters)
+
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("unexpected network stats format %s", scanner.Text())
+		}
+
+		receiveBytes, err := strconv.ParseUint(fields[0], 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s parsing network stats for %s", err, name)
+		}
+
+		transmitBytes, err := strconv.ParseUint(fields[8], 10, 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s parsing network stats for %s", err, name)
+		}
+
+		network[name] = NetworkCounters{
+			ReceiveBytes:  receiveBytes,
+			TransmitBytes: transmitBytes,
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error %s reading network stats", err)
+	}
+
+	return network, nil
+}
+
+// readDisks parses the reads and writes completed by each block
+// device (except loop and ram devices) and the time spent on
+// them from /proc/diskstats
+func readDisks(path string) (map[string]DiskCounters, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s reading disk stats", err)
+	}
+
+	defer file.Close()
+
+	disks := make(map[string]DiskCounters)
+
+	scanner := bufio.NewScanner(file)
+
+lines:
+	for scanner.Scan() {
+		// e.g. 259 0 nvme0n1 1234 0 5678 910 1112 0 1314 1516 0 ...
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) < 11 {
+			continue
+		}
+
+		name := fields[2]
+
+		for _, prefix := range excludedDevicePrefixes {
+			if strings.HasPrefix(name, prefix) {
+				continue lines
+			}
+		}
+
+		// reads completed, reads merged, sectors read, ms reading,
+		// writes completed, writes merged, sectors written, ms writing
+		var values [4]uint64
+
+		for i, field := range []string{fields[3], fields[6], fields[7], fields[10]} {
+			values[i], err = strconv.ParseUint(field, 10, 64)
+
+			if err != nil {
+				return nil, fmt.Errorf("error %s parsing disk stats for %s", err, name)
+			}
+		}
+
+		disks[name] = DiskCounters{
+			Reads:             values[0],
+			ReadMilliseconds:  values[1],
+			Writes:            values[2],
+			WriteMilliseconds: values[3],
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error %s reading disk stats", err)
+	}
+
+	return disks, nil
+}
+
+// Metrics returns the host metrics for the interval between the
+// previous and current stats, with network and disk io as rates
+// over the interval and disk io latency as the mean time spent on
+// each read and write completed during the interval
+func Metrics(previous Stats, current Stats) metric.HostMetrics {
+	hostMetrics := metric.HostMetrics{
+		LoadAverage1m:        current.LoadAverage1m,
+		LoadAverage5m:        current.LoadAverage5m,
+		LoadAverage15m:       current.LoadAverage15m,
+		MemoryTotalBytes:     current.MemoryTotalBytes,
+		MemoryAvailableBytes: current.MemoryAvailableBytes,
+		SampledAt:            current.ReadAt,
+	}
+
+	if current.MemoryTotalBytes > 0 {
+		hostMetrics.MemoryUsedPercent = 100 * float64(current.MemoryTotalBytes-current.MemoryAvailableBytes) / float64(current.MemoryTotalBytes)
+	}
+
+	seconds := current.ReadAt.Sub(previous.ReadAt).Seconds()
+
+	if seconds <= 0 {
+		return hostMetrics
+	}
+
+	for name, counters := range current.Network {
+		previousCounters, ok := previous.Network[name]
+
+		if !ok {
+			continue
+		}
+
+		hostMetrics.Interfaces = append(hostMetrics.Interfaces, metric.HostInterfaceMetrics{
+			Interface:              name,
+			ReceiveBytesPerSecond:  float64(delta(previousCounters.ReceiveBytes, counters.ReceiveBytes)) / seconds,
+			TransmitBytesPerSecond: float64(delta(previousCounters.TransmitBytes, counters.TransmitBytes)) / seconds,
+		})
+	}
+
+	for name, counters := range current.Disks {
+		previousCounters, ok := previous.Disks[name]
+
+		if !ok {
+			continue
+		}
+
+		reads := delta(previousCounters.Reads, counters.Reads)
+		writes := delta(previousCounters.Writes, counters.Writes)
+
+		disk := metric.HostDiskMetrics{
+			Device:          name,
+			ReadsPerSecond:  float64(reads) / seconds,
+			WritesPerSecond: float64(writes) / seconds,
+		}
+
+		if reads > 0 {
+			disk.ReadLatencyMilliseconds = float64(delta(previousCounters.ReadMilliseconds, counters.ReadMilliseconds)) / float64(reads)
+		}
+
+		if writes > 0 {
+			disk.WriteLatencyMilliseconds = float64(delta(previousCounters.WriteMilliseconds, counters.WriteMilliseconds)) / float64(writes)
+		}
+
+		hostMetrics.Disks = append(hostMetrics.Disks, disk)
+	}
+
+	// sort for stable output
+	sort.Slice(hostMetrics.Interfaces, func(i, j int) bool {
+		return hostMetrics.Interfaces[i].Interface < hostMetrics.Interfaces[j].Interface
+	})
+
+	sort.Slice(hostMetrics.Disks, func(i, j int) bool {
+		return hostMetrics.Disks[i].Device < hostMetrics.Disks[j].Device
+	})
+
+	return hostMetrics
+}
+
+// delta returns the increase of a cumulative counter,
+// 0 if the counter was reset (e.g. a device was re-attached)
+func delta(previous uint64, current uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+
+	return current - previous
+}
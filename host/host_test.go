@@ -0,0 +1,99 @@
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeProc writes a fake proc filesystem with the specified
+// network and disk counters to a temporary directory
+func writeProc(t *testing.T, netDev string, diskStats string) string {
+	procPath := t.TempDir()
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(procPath, "net"), 0755))
+	assert.Nil(t, os.WriteFile(filepath.Join(procPath, "loadavg"), []byte("1.50 0.75 0.25 2/345 6789\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(procPath, "meminfo"), []byte("MemTotal:        4000 kB\nMemFree:          500 kB\nMemAvailable:    1000 kB\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(procPath, "net", "dev"), []byte(netDev), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(procPath, "diskstats"), []byte(diskStats), 0644))
+
+	return procPath
+}
+
+const netDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+
+func TestReadStats(t *testing.T) {
+	procPath := writeProc(t, netDevHeader+`    lo:   9999      10    0    0    0     0          0         0     9999      10    0    0    0     0       0          0
+  eth0:   1000      10    0    0    0     0          0         0     2000      20    0    0    0     0       0          0
+`, `   7       0 loop0 50 0 100 5 0 0 0 0 0 5 5 0 0 0 0
+ 259       0 nvme0n1 100 0 800 200 50 0 400 500 0 600 700 0 0 0 0
+`)
+
+	stats, err := ReadStats(procPath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1.5, stats.LoadAverage1m)
+	assert.Equal(t, 0.75, stats.LoadAverage5m)
+	assert.Equal(t, 0.25, stats.LoadAverage15m)
+	assert.Equal(t, uint64(4000*1024), stats.MemoryTotalBytes)
+	assert.Equal(t, uint64(1000*1024), stats.MemoryAvailableBytes)
+	assert.Equal(t, map[string]NetworkCounters{"eth0": {ReceiveBytes: 1000, TransmitBytes: 2000}}, stats.Network)
+	assert.Equal(t, map[string]DiskCounters{"nvme0n1": {Reads: 100, ReadMilliseconds: 200, Writes: 50, WriteMilliseconds: 500}}, stats.Disks)
+}
+
+func TestReadStatsRequiresProcFilesystem(t *testing.T) {
+	_, err := ReadStats(t.TempDir())
+
+	assert.NotNil(t, err)
+}
+
+func TestMetricsCalculatesRatesAndLatencies(t *testing.T) {
+	readAt := time.Now()
+
+	previous := Stats{
+		Network: map[string]NetworkCounters{
+			"eth0": {ReceiveBytes: 1000, TransmitBytes: 2000},
+		},
+		Disks: map[string]DiskCounters{
+			"nvme0n1": {Reads: 100, ReadMilliseconds: 200, Writes: 50, WriteMilliseconds: 500},
+			"nvme1n1": {Reads: 10, ReadMilliseconds: 10, Writes: 10, WriteMilliseconds: 10},
+		},
+		ReadAt: readAt,
+	}
+
+	current := Stats{
+		LoadAverage1m:        1.5,
+		MemoryTotalBytes:     4000,
+		MemoryAvailableBytes: 1000,
+		Network: map[string]NetworkCounters{
+			"eth0": {ReceiveBytes: 11000, TransmitBytes: 7000},
+			// appeared since the previous stats
+			"eth1": {ReceiveBytes: 5000, TransmitBytes: 5000},
+		},
+		Disks: map[string]DiskCounters{
+			"nvme0n1": {Reads: 200, ReadMilliseconds: 700, Writes: 60, WriteMilliseconds: 600},
+			// counters reset since the previous stats
+			"nvme1n1": {Reads: 5, ReadMilliseconds: 5, Writes: 5, WriteMilliseconds: 5},
+		},
+		ReadAt: readAt.Add(10 * time.Second),
+	}
+
+	hostMetrics := Metrics(previous, current)
+
+	assert.Equal(t, 1.5, hostMetrics.LoadAverage1m)
+	assert.Equal(t, 75.0, hostMetrics.MemoryUsedPercent)
+	assert.Equal(t, []metric.HostInterfaceMetrics{
+		{Interface: "eth0", ReceiveBytesPerSecond: 1000, TransmitBytesPerSecond: 500},
+	}, hostMetrics.Interfaces)
+	assert.Equal(t, []metric.HostDiskMetrics{
+		{Device: "nvme0n1", ReadsPerSecond: 10, WritesPerSecond: 1, ReadLatencyMilliseconds: 5, WriteLatencyMilliseconds: 10},
+		{Device: "nvme1n1"},
+	}, hostMetrics.Disks)
+	assert.Equal(t, current.ReadAt, hostMetrics.SampledAt)
+}
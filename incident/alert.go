@@ -0,0 +1,203 @@
+package incident
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	// event types for alerts (e.g. high peer churn)
+	// raised by evaluating each sample against a threshold
+	AlertFiringEventType    = "alert_firing"
+	AlertEscalatedEventType = "alert_escalated"
+	AlertResolvedEventType  = "alert_resolved"
+
+	DefaultAlertCooldown      = 5 * time.Minute
+	DefaultAlertEscalateAfter = 15 * time.Minute
+	// maximum number of alert events waiting to be published
+	DefaultAlertQueueSize = 100
+)
+
+// AlertManagerConfig wraps values for configuring an AlertManager
+type AlertManagerConfig struct {
+	// where to publish alert events to
+	Publisher   Publisher
+	EndpointURL string
+	// minimum time between notifications that the same alert is
+	// firing, an alert that resolves and fires again within the
+	// cooldown (e.g. a value flapping around its threshold) is
+	// only notified again (along with its resolution) if it's escalated
+	Cooldown time.Duration
+	// how long an alert has to keep firing before it's
+	// escalated to critical, 0 disables escalation
+	EscalateAfter time.Duration
+	// optional logger for reporting errors
+	// publishing alert events in the background
+	Logger *logging.Logger
+}
+
+// alertState tracks an alert that is currently firing
+type alertState struct {
+	// id shared by the firing, escalated and resolved
+	// events of the alert, so they can be correlated
+	id          string
+	firingSince time.Time
+	// whether a notification was sent (i.e. the
+	// alert wasn't suppressed by the cooldown)
+	notified  bool
+	escalated bool
+}
+
+// AlertManager turns alerts evaluated for every sample (which
+// would otherwise fire every few seconds) into events published
+// only when an alert starts firing, is escalated because it keeps
+// firing and is resolved, publishing events in order from a background
+// go-routine so a slow publisher doesn't hold up evaluating samples
+// AlertManager is safe for concurrent use
+type AlertManager struct {
+	publisher     Publisher
+	endpointURL   string
+	cooldown      time.Duration
+	escalateAfter time.Duration
+	logger        *logging.Logger
+	events        chan Event
+	// number of events dropped, accessed atomically
+	dropped uint64
+
+	stateLock *sync.Mutex
+	// alerts currently firing, keyed by node id and alert name
+	firing map[string]*alertState
+	// when each alert was last notified, for enforcing the cooldown
+	lastNotifiedAt map[string]time.Time
+}
+
+// NewAlertManager returns a new AlertManager using the provided config
+// and starts the background go-routine that publishes its events
+func NewAlertManager(config AlertManagerConfig) *AlertManager {
+	am := &AlertManager{
+		publisher:      config.Publisher,
+		endpointURL:    config.EndpointURL,
+		cooldown:       config.Cooldown,
+		escalateAfter:  config.EscalateAfter,
+		logger:         config.Logger,
+		events:         make(chan Event, DefaultAlertQueueSize),
+		stateLock:      &sync.Mutex{},
+		firing:         make(map[string]*alertState),
+		lastNotifiedAt: make(map[string]time.Time),
+	}
+
+	go am.publishEvents()
+
+	return am
+}
+
+// Set records whether the named alert is firing for the node as of
+// occurredAt, publishing an event if the alert started firing (and
+// wasn't notified within the cooldown), has been firing long enough
+// to be escalated or was resolved
+// Setting an alert on a nil manager is a no-op so callers
+// don't need to check whether alerts are published
+func (am *AlertManager) Set(nodeId string, name string, firing bool, message string, occurredAt time.Time) {
+	if am == nil {
+		return
+	}
+
+	for _, event := range am.evaluate(nodeId, name, firing, message, occurredAt) {
+		select {
+		case am.events <- event:
+		default:
+			atomic.AddUint64(&am.dropped, 1)
+		}
+	}
+}
+
+// evaluate updates the state of the alert, returning
+// the events to publish for the change (if any)
+func (am *AlertManager) evaluate(nodeId string, name string, firing bool, message string, occurredAt time.Time) []Event {
+	am.stateLock.Lock()
+	defer am.stateLock.Unlock()
+
+	key := nodeId + "/" + name
+	state, active := am.firing[key]
+
+	newEvent := func(eventType string) Event {
+		return Event{
+			Type:        eventType,
+			IncidentId:  state.id,
+			Kind:        name,
+			NodeId:      nodeId,
+			EndpointURL: am.endpointURL,
+			Message:     message,
+			OccurredAt:  occurredAt,
+		}
+	}
+
+	if !firing {
+		if !active {
+			return nil
+		}
+
+		delete(am.firing, key)
+
+		if !state.notified {
+			return nil
+		}
+
+		resolved := newEvent(AlertResolvedEventType)
+		resolved.DurationSeconds = occurredAt.Sub(state.firingSince).Seconds()
+
+		return []Event{resolved}
+	}
+
+	var events []Event
+
+	if !active {
+		state = &alertState{
+			id:          uuid.New().String(),
+			firingSince: occurredAt,
+		}
+
+		am.firing[key] = state
+
+		lastNotifiedAt, notified := am.lastNotifiedAt[key]
+
+		if !notified || occurredAt.Sub(lastNotifiedAt) >= am.cooldown {
+			state.notified = true
+			am.lastNotifiedAt[key] = occurredAt
+
+			events = append(events, newEvent(AlertFiringEventType))
+		}
+	}
+
+	if am.escalateAfter > 0 && !state.escalated && occurredAt.Sub(state.firingSince) >= am.escalateAfter {
+		state.escalated = true
+		state.notified = true
+		am.lastNotifiedAt[key] = occurredAt
+
+		events = append(events, newEvent(AlertEscalatedEventType))
+	}
+
+	return events
+}
+
+// publishEvents publishes queued events in the order they were
+// queued, logging any errors publishing them
+func (am *AlertManager) publishEvents() {
+	for event := range am.events {
+		err := am.publisher.Publish(event)
+
+		if err != nil && am.logger != nil {
+			am.logger.Errorf("error %s publishing %s event for %s alert", err, event.Type, event.Kind)
+		}
+	}
+}
+
+// Dropped returns the number of events dropped
+// because the publisher wasn't keeping up
+func (am *AlertManager) Dropped() uint64 {
+	return atomic.LoadUint64(&am.dropped)
+}
@@ -0,0 +1,135 @@
+package incident
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testPublisher sends every published event to its channel
+type testPublisher chan Event
+
+func (tp testPublisher) Publish(event Event) error {
+	tp <- event
+
+	return nil
+}
+
+// receiveEvents returns the events published to the test publisher,
+// failing the test if fewer than count are published
+func receiveEvents(t *testing.T, publisher testPublisher, count int) []Event {
+	var events []Event
+
+	for len(events) < count {
+		select {
+		case event := <-publisher:
+			events = append(events, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected %d events, got %d", count, len(events))
+		}
+	}
+
+	select {
+	case event := <-publisher:
+		t.Fatalf("expected %d events, got unexpected %s event", count, event.Type)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	return events
+}
+
+func TestAlertManagerPublishesFiringOnceAndResolved(t *testing.T) {
+	publisher := make(testPublisher, 10)
+
+	alerts := NewAlertManager(AlertManagerConfig{
+		Publisher:   publisher,
+		EndpointURL: "http://localhost:26657",
+		Cooldown:    time.Minute,
+	})
+
+	firingAt := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	alerts.Set("node-1", "PeerChurn", true, "peer churn of 12.0 peers per minute", firingAt)
+	alerts.Set("node-1", "PeerChurn", true, "peer churn of 14.0 peers per minute", firingAt.Add(5*time.Second))
+	alerts.Set("node-1", "PeerChurn", false, "peer churn of 2.0 peers per minute", firingAt.Add(90*time.Second))
+
+	events := receiveEvents(t, publisher, 2)
+
+	assert.Equal(t, AlertFiringEventType, events[0].Type)
+	assert.Equal(t, "PeerChurn", events[0].Kind)
+	assert.Equal(t, "node-1", events[0].NodeId)
+	assert.Equal(t, "http://localhost:26657", events[0].EndpointURL)
+	assert.Equal(t, "peer churn of 12.0 peers per minute", events[0].Message)
+	assert.NotEmpty(t, events[0].IncidentId)
+
+	assert.Equal(t, AlertResolvedEventType, events[1].Type)
+	assert.Equal(t, events[0].IncidentId, events[1].IncidentId)
+	assert.Equal(t, float64(90), events[1].DurationSeconds)
+	assert.Equal(t, InfoSeverity, Severity(events[1]))
+}
+
+func TestAlertManagerSuppressesFlappingWithinCooldown(t *testing.T) {
+	publisher := make(testPublisher, 10)
+
+	alerts := NewAlertManager(AlertManagerConfig{
+		Publisher: publisher,
+		Cooldown:  time.Minute,
+	})
+
+	firingAt := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	alerts.Set("node-1", "MempoolBacklog", true, "", firingAt)
+	alerts.Set("node-1", "MempoolBacklog", false, "", firingAt.Add(10*time.Second))
+	// flaps within the cooldown, neither notified
+	alerts.Set("node-1", "MempoolBacklog", true, "", firingAt.Add(20*time.Second))
+	alerts.Set("node-1", "MempoolBacklog", false, "", firingAt.Add(30*time.Second))
+	// alerts for other nodes have their own cooldown
+	alerts.Set("node-2", "MempoolBacklog", true, "", firingAt.Add(30*time.Second))
+	// fires again after the cooldown
+	alerts.Set("node-1", "MempoolBacklog", true, "", firingAt.Add(2*time.Minute))
+
+	events := receiveEvents(t, publisher, 4)
+
+	assert.Equal(t, []string{AlertFiringEventType, AlertResolvedEventType, AlertFiringEventType, AlertFiringEventType}, []string{events[0].Type, events[1].Type, events[2].Type, events[3].Type})
+	assert.Equal(t, "node-2", events[2].NodeId)
+	assert.Equal(t, "node-1", events[3].NodeId)
+	assert.NotEqual(t, events[0].IncidentId, events[3].IncidentId)
+}
+
+func TestAlertManagerEscalatesAlertThatKeepsFiring(t *testing.T) {
+	publisher := make(testPublisher, 10)
+
+	alerts := NewAlertManager(AlertManagerConfig{
+		Publisher:     publisher,
+		Cooldown:      time.Hour,
+		EscalateAfter: 15 * time.Minute,
+	})
+
+	firingAt := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	alerts.Set("", "ServiceRestartLoop", true, "", firingAt)
+	alerts.Set("", "ServiceRestartLoop", false, "", firingAt.Add(time.Minute))
+	// suppressed by the cooldown until it's firing long enough to escalate
+	alerts.Set("", "ServiceRestartLoop", true, "", firingAt.Add(2*time.Minute))
+	alerts.Set("", "ServiceRestartLoop", true, "", firingAt.Add(10*time.Minute))
+	alerts.Set("", "ServiceRestartLoop", true, "", firingAt.Add(17*time.Minute))
+	alerts.Set("", "ServiceRestartLoop", true, "", firingAt.Add(30*time.Minute))
+	alerts.Set("", "ServiceRestartLoop", false, "", firingAt.Add(32*time.Minute))
+
+	events := receiveEvents(t, publisher, 4)
+
+	assert.Equal(t, AlertFiringEventType, events[0].Type)
+	assert.Equal(t, AlertResolvedEventType, events[1].Type)
+	assert.Equal(t, AlertEscalatedEventType, events[2].Type)
+	assert.Equal(t, CriticalSeverity, Severity(events[2]))
+	assert.Equal(t, AlertResolvedEventType, events[3].Type)
+	assert.Equal(t, events[2].IncidentId, events[3].IncidentId)
+	assert.Equal(t, float64(30*60), events[3].DurationSeconds)
+}
+
+func TestNilAlertManagerIgnoresAlerts(t *testing.T) {
+	var alerts *AlertManager
+
+	alerts.Set("node-1", "PeerChurn", true, "", time.Now())
+}
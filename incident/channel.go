@@ -0,0 +1,48 @@
+package incident
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var (
+	ErrChannelFull = errors.New("incident event channel is full, dropping event")
+)
+
+// ChannelPublisher implements the Publisher interface,
+// publishing events to a channel e.g. for display
+// on the timeline of the gui
+type ChannelPublisher struct {
+	events chan<- Event
+	// number of events dropped, accessed atomically
+	dropped uint64
+}
+
+// NewChannelPublisher returns a new ChannelPublisher
+// publishing events to the provided channel
+func NewChannelPublisher(events chan<- Event) *ChannelPublisher {
+	return &ChannelPublisher{
+		events: events,
+	}
+}
+
+// Publish sends the event to the channel without blocking
+// (so heal actions aren't held up by a busy display)
+// returning `ErrChannelFull` if the event can't be sent
+// Publish is safe to call across go-routines
+func (cp *ChannelPublisher) Publish(event Event) error {
+	select {
+	case cp.events <- event:
+		return nil
+	default:
+		atomic.AddUint64(&cp.dropped, 1)
+
+		return ErrChannelFull
+	}
+}
+
+// Dropped returns the number of events dropped
+// because the channel was full
+func (cp *ChannelPublisher) Dropped() uint64 {
+	return atomic.LoadUint64(&cp.dropped)
+}
@@ -0,0 +1,72 @@
+package incident
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultChatHTTPTimeout = 10 * time.Second
+)
+
+// chatText returns the plain text message for the event posted
+// to chat services (e.g. Telegram or Discord), truncated to
+// maxLength characters as longer messages are rejected
+func chatText(event Event, maxLength int) string {
+	lines := []string{
+		fmt.Sprintf("[%s] %s", Severity(event), summarize(event)),
+		event.Message,
+	}
+
+	if event.EndpointURL != "" {
+		lines = append(lines, fmt.Sprintf("endpoint: %s", event.EndpointURL))
+	}
+
+	if event.NodeId != "" {
+		lines = append(lines, fmt.Sprintf("node: %s", event.NodeId))
+	}
+
+	if event.DurationSeconds > 0 {
+		lines = append(lines, fmt.Sprintf("duration: %.0f seconds", event.DurationSeconds))
+	}
+
+	text := []rune(strings.Join(lines, "\n"))
+
+	if len(text) > maxLength {
+		text = append(text[:maxLength-3], []rune("...")...)
+	}
+
+	return string(text)
+}
+
+// postChatMessage posts the json encoded payload to the url of a chat
+// service, returning error (including the body of the response for
+// context) if the request fails or is rejected
+func postChatMessage(client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := client.Post(url, "application/json", bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		responseBody, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+
+		return fmt.Errorf("request rejected with status %d: %s", response.StatusCode, strings.TrimSpace(string(responseBody)))
+	}
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package incident
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTelegramPublisherSendsMessageToChat(t *testing.T) {
+	var message map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/bot123:secret/sendMessage", r.URL.Path)
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&message))
+	}))
+	defer server.Close()
+
+	publisher, err := NewTelegramPublisher("123:secret", "-1001234567890")
+	assert.Nil(t, err)
+
+	publisher.apiURL = server.URL
+
+	assert.Nil(t, publisher.Publish(Event{
+		Type:        IncidentOpenedEventType,
+		Kind:        NodeOfflineIncident,
+		NodeId:      "node-1",
+		EndpointURL: "http://localhost:26657",
+		Message:     "node went offline",
+	}))
+
+	assert.Equal(t, "-1001234567890", message["chat_id"])
+	assert.Equal(t, "[critical] node_offline incident opened\nnode went offline\nendpoint: http://localhost:26657\nnode: node-1", message["text"])
+}
+
+func TestTelegramPublisherRedactsBotTokenFromErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`))
+	}))
+	defer server.Close()
+
+	publisher, err := NewTelegramPublisher("123:secret", "-1")
+	assert.Nil(t, err)
+
+	publisher.apiURL = server.URL
+
+	err = publisher.Publish(Event{Type: IncidentClosedEventType, Kind: NodeOfflineIncident})
+
+	assert.ErrorContains(t, err, "chat not found")
+
+	// an unreachable api includes the request url in the error
+	server.Close()
+
+	err = publisher.Publish(Event{Type: IncidentClosedEventType, Kind: NodeOfflineIncident})
+
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestDiscordPublisherPostsMessageToWebhook(t *testing.T) {
+	var message map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&message))
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	publisher, err := NewDiscordPublisher(server.URL + "/api/webhooks/1/token")
+	assert.Nil(t, err)
+
+	assert.Nil(t, publisher.Publish(Event{
+		Type:       HealActionEventType,
+		HealAction: RestartServiceHealAction,
+		Succeeded:  true,
+		Message:    "restarted @everyone's favorite node",
+	}))
+
+	assert.Equal(t, "[warning] restart_service heal action succeeded\nrestarted @everyone's favorite node", message["content"])
+	assert.Equal(t, map[string]interface{}{"parse": []interface{}{}}, message["allowed_mentions"], "mentions in messages don't notify anyone")
+
+	_, err = NewDiscordPublisher("")
+
+	assert.NotNil(t, err)
+}
+
+func TestChatTextIsTruncated(t *testing.T) {
+	text := chatText(Event{Type: IncidentOpenedEventType, Message: strings.Repeat("é", 5000)}, MaxDiscordMessageLength)
+
+	assert.Equal(t, MaxDiscordMessageLength, utf8.RuneCountInString(text))
+	assert.True(t, strings.HasSuffix(text, "..."))
+}
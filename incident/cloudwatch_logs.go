@@ -0,0 +1,114 @@
+package incident
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+)
+
+const (
+	DefaultLogGroupName = "/kava/doctor/incidents"
+)
+
+// CloudWatchLogsPublisher implements the Publisher interface,
+// publishing events as json encoded log events to a log stream
+// (per doctor process) in a CloudWatch Logs log group
+type CloudWatchLogsPublisher struct {
+	client        *cloudwatchlogs.CloudWatchLogs
+	logGroupName  string
+	logStreamName string
+	// CloudWatch Logs requires events in
+	// a stream to be put one batch at a time
+	putLock *sync.Mutex
+}
+
+// NewCloudWatchLogsPublisher returns a new CloudWatchLogsPublisher
+// publishing to the specified log group (which is created if it doesn't
+// exist) and error (if any)
+func NewCloudWatchLogsPublisher(awsRegion string, logGroupName string) (*CloudWatchLogsPublisher, error) {
+	if logGroupName == "" {
+		logGroupName = DefaultLogGroupName
+	}
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Region: aws.String(awsRegion),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s creating valid aws session", err)
+	}
+
+	client := cloudwatchlogs.New(awsSession)
+
+	_, err = client.CreateLogGroup(&cloudwatchlogs.CreateLogGroupInput{
+		LogGroupName: aws.String(logGroupName),
+	})
+
+	if err != nil && !isResourceAlreadyExists(err) {
+		return nil, fmt.Errorf("error %s creating log group %s", err, logGroupName)
+	}
+
+	hostname, err := os.Hostname()
+
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	logStreamName := fmt.Sprintf("%s-%d", hostname, time.Now().Unix())
+
+	_, err = client.CreateLogStream(&cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(logGroupName),
+		LogStreamName: aws.String(logStreamName),
+	})
+
+	if err != nil && !isResourceAlreadyExists(err) {
+		return nil, fmt.Errorf("error %s creating log stream %s", err, logStreamName)
+	}
+
+	return &CloudWatchLogsPublisher{
+		client:        client,
+		logGroupName:  logGroupName,
+		logStreamName: logStreamName,
+		putLock:       &sync.Mutex{},
+	}, nil
+}
+
+// Publish publishes the event to CloudWatch Logs, returning error (if any)
+// Publish is safe to call across go-routines
+func (cwlp *CloudWatchLogsPublisher) Publish(event Event) error {
+	encodedEvent, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	cwlp.putLock.Lock()
+	defer cwlp.putLock.Unlock()
+
+	_, err = cwlp.client.PutLogEvents(&cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(cwlp.logGroupName),
+		LogStreamName: aws.String(cwlp.logStreamName),
+		LogEvents: []*cloudwatchlogs.InputLogEvent{
+			{
+				Message:   aws.String(string(encodedEvent)),
+				Timestamp: aws.Int64(event.OccurredAt.UnixMilli()),
+			},
+		},
+	})
+
+	return err
+}
+
+func isResourceAlreadyExists(err error) bool {
+	var awsErr awserr.Error
+
+	return errors.As(err, &awsErr) && awsErr.Code() == cloudwatchlogs.ErrCodeResourceAlreadyExistsException
+}
@@ -0,0 +1,70 @@
+package incident
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	DefaultDiscordUsername = "doctor"
+	// maximum number of characters in a discord message
+	MaxDiscordMessageLength = 2000
+)
+
+// DiscordPublisher implements the Publisher interface,
+// publishing events by posting them as messages to
+// the channel of a Discord webhook
+type DiscordPublisher struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// NewDiscordPublisher returns a new DiscordPublisher posting
+// messages to the specified Discord webhook url and error (if any)
+func NewDiscordPublisher(webhookURL string) (*DiscordPublisher, error) {
+	parsedURL, err := url.Parse(webhookURL)
+
+	if err != nil || parsedURL.Scheme == "" || parsedURL.Host == "" {
+		return nil, fmt.Errorf("a valid discord webhook url is required to post discord messages")
+	}
+
+	return &DiscordPublisher{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: DefaultChatHTTPTimeout},
+	}, nil
+}
+
+// discordAllowedMentions controls which mentions
+// in a message notify the mentioned users
+type discordAllowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+// discordMessage is the payload of a request to
+// post a message to the channel of a webhook
+type discordMessage struct {
+	Username        string                 `json:"username"`
+	Content         string                 `json:"content"`
+	AllowedMentions discordAllowedMentions `json:"allowed_mentions"`
+}
+
+// Publish posts the event as a message to the channel, returning error (if any)
+// Publish is safe to call across go-routines
+func (dp *DiscordPublisher) Publish(event Event) error {
+	err := postChatMessage(dp.httpClient, dp.webhookURL, discordMessage{
+		Username: DefaultDiscordUsername,
+		Content:  chatText(event, MaxDiscordMessageLength),
+		// messages can include text from the node (e.g. errors),
+		// which shouldn't be able to ping the whole channel
+		AllowedMentions: discordAllowedMentions{Parse: []string{}},
+	})
+
+	if err != nil {
+		// the webhook url is a credential
+		return fmt.Errorf("error posting discord message: %s", strings.ReplaceAll(err.Error(), dp.webhookURL, "REDACTED"))
+	}
+
+	return nil
+}
@@ -0,0 +1,392 @@
+package incident
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	// how to secure the connection to the smtp server
+	// upgrade a plaintext connection using STARTTLS (e.g. port 587)
+	StartTLSEmailSecurity = "starttls"
+	// connect over TLS (e.g. port 465)
+	TLSEmailSecurity = "tls"
+	// send email in plaintext, only intended for
+	// relays listening on the local host or network
+	NoneEmailSecurity = "none"
+
+	DefaultEmailSecurity = StartTLSEmailSecurity
+	DefaultEmailTimeout  = 30 * time.Second
+	// templates are executed with an EmailTemplateData
+	DefaultEmailSubjectTemplate = `[doctor] {{.Severity}}: {{.Summary}}`
+	DefaultEmailBodyTemplate    = `{{.Summary}}
+
+{{.Message}}
+
+Endpoint: {{.EndpointURL}}
+{{- if .NodeId}}
+Node: {{.NodeId}}
+{{- end}}
+{{- if .IncidentId}}
+Incident: {{.IncidentId}}
+{{- end}}
+{{- if .DurationSeconds}}
+Duration: {{printf "%.0f" .DurationSeconds}} seconds
+{{- end}}
+Occurred at: {{.OccurredAt.Format "2006-01-02T15:04:05Z07:00"}}
+`
+)
+
+var (
+	ValidEmailSecurities = []string{StartTLSEmailSecurity, TLSEmailSecurity, NoneEmailSecurity}
+)
+
+// EmailConfig wraps values for configuring an EmailPublisher
+type EmailConfig struct {
+	// host:port of the smtp server to send email through
+	SMTPAddress string
+	// one of ValidEmailSecurities, defaults to DefaultEmailSecurity
+	Security string
+	// if set, credentials to authenticate with the smtp server
+	Username string
+	Password string
+	From     string
+	// addresses to email events of each severity to, events
+	// of severities without recipients aren't emailed
+	Recipients map[string][]string
+	// text/template templates for the subject and body of
+	// emails, defaults to DefaultEmailSubjectTemplate and
+	// the template in BodyTemplateFilepath
+	SubjectTemplate string
+	// if set, file containing the template for the body
+	// of emails, defaults to DefaultEmailBodyTemplate
+	BodyTemplateFilepath string
+}
+
+// String returns the email config with the password redacted
+// so it can be safely logged (e.g. when printing the config)
+func (ec EmailConfig) String() string {
+	password := ""
+
+	if ec.Password != "" {
+		password = "REDACTED"
+	}
+
+	return fmt.Sprintf("{SMTPAddress:%s Security:%s Username:%s Password:%s From:%s Recipients:%v SubjectTemplate:%s BodyTemplateFilepath:%s}",
+		ec.SMTPAddress, ec.Security, ec.Username, password, ec.From, ec.Recipients, ec.SubjectTemplate, ec.BodyTemplateFilepath)
+}
+
+// EmailTemplateData wraps the values the
+// subject and body templates are executed with
+type EmailTemplateData struct {
+	Event
+	Severity string
+	// a one line summary of the event,
+	// e.g. node_offline incident opened
+	Summary string
+}
+
+// EmailPublisher implements the Publisher interface,
+// publishing events by emailing them to the recipients
+// of their severity through an smtp server
+type EmailPublisher struct {
+	smtpAddress     string
+	host            string
+	security        string
+	auth            smtp.Auth
+	from            string
+	recipients      map[string][]string
+	subjectTemplate *template.Template
+	bodyTemplate    *template.Template
+	timeout         time.Duration
+}
+
+// NewEmailPublisher returns a new EmailPublisher using the provided
+// config (or defaults where appropriate) and error (if any)
+func NewEmailPublisher(config EmailConfig) (*EmailPublisher, error) {
+	host, _, err := net.SplitHostPort(config.SMTPAddress)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid smtp address %s, expected <host>:<port>: %w", config.SMTPAddress, err)
+	}
+
+	security := config.Security
+
+	if security == "" {
+		security = DefaultEmailSecurity
+	}
+
+	var validSecurity bool
+
+	for _, validEmailSecurity := range ValidEmailSecurities {
+		if security == validEmailSecurity {
+			validSecurity = true
+		}
+	}
+
+	if !validSecurity {
+		return nil, fmt.Errorf("invalid email security %s, valid securities are %v", security, ValidEmailSecurities)
+	}
+
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid from address %s: %w", config.From, err)
+	}
+
+	var recipientCount int
+
+	for severity, addresses := range config.Recipients {
+		for _, address := range addresses {
+			if _, err := mail.ParseAddress(address); err != nil {
+				return nil, fmt.Errorf("invalid %s recipient %s: %w", severity, address, err)
+			}
+
+			recipientCount++
+		}
+	}
+
+	if recipientCount == 0 {
+		return nil, fmt.Errorf("at least one recipient is required to email events")
+	}
+
+	rawSubjectTemplate := config.SubjectTemplate
+
+	if rawSubjectTemplate == "" {
+		rawSubjectTemplate = DefaultEmailSubjectTemplate
+	}
+
+	subjectTemplate, err := template.New("subject").Parse(rawSubjectTemplate)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid email subject template: %w", err)
+	}
+
+	rawBodyTemplate := DefaultEmailBodyTemplate
+
+	if config.BodyTemplateFilepath != "" {
+		contents, err := os.ReadFile(config.BodyTemplateFilepath)
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s reading email body template %s", err, config.BodyTemplateFilepath)
+		}
+
+		rawBodyTemplate = string(contents)
+	}
+
+	bodyTemplate, err := template.New("body").Parse(rawBodyTemplate)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid email body template: %w", err)
+	}
+
+	var auth smtp.Auth
+
+	if config.Username != "" {
+		// refuses to send credentials over an unencrypted
+		// connection unless the server is on the local host
+		auth = smtp.PlainAuth("", config.Username, config.Password, host)
+	}
+
+	return &EmailPublisher{
+		smtpAddress:     config.SMTPAddress,
+		host:            host,
+		security:        security,
+		auth:            auth,
+		from:            config.From,
+		recipients:      config.Recipients,
+		subjectTemplate: subjectTemplate,
+		bodyTemplate:    bodyTemplate,
+		timeout:         DefaultEmailTimeout,
+	}, nil
+}
+
+// Publish emails the event to the recipients of its severity (if
+// any), returning error (if any)
+// Publish is safe to call across go-routines
+func (ep *EmailPublisher) Publish(event Event) error {
+	severity := Severity(event)
+	recipients := ep.recipients[severity]
+
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	message, err := ep.message(event, severity, recipients)
+
+	if err != nil {
+		return err
+	}
+
+	return ep.send(recipients, message)
+}
+
+// message returns the email for the event with
+// the templated subject and body, and error (if any)
+func (ep *EmailPublisher) message(event Event, severity string, recipients []string) ([]byte, error) {
+	data := EmailTemplateData{
+		Event:    event,
+		Severity: severity,
+		Summary:  summarize(event),
+	}
+
+	var subject, body bytes.Buffer
+
+	if err := ep.subjectTemplate.Execute(&subject, data); err != nil {
+		return nil, fmt.Errorf("error %s executing email subject template", err)
+	}
+
+	if err := ep.bodyTemplate.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("error %s executing email body template", err)
+	}
+
+	// subjects can't span lines
+	encodedSubject := mime.QEncoding.Encode("utf-8", strings.Join(strings.Fields(subject.String()), " "))
+
+	var message bytes.Buffer
+
+	fmt.Fprintf(&message, "From: %s\r\n", ep.from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", encodedSubject)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&message, "\r\n")
+	message.WriteString(strings.ReplaceAll(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n", "\r\n"))
+
+	return message.Bytes(), nil
+}
+
+// send sends the message to the recipients through
+// the smtp server, returning error (if any)
+func (ep *EmailPublisher) send(recipients []string, message []byte) error {
+	dialer := &net.Dialer{Timeout: ep.timeout}
+	tlsConfig := &tls.Config{ServerName: ep.host}
+
+	var conn net.Conn
+	var err error
+
+	if ep.security == TLSEmailSecurity {
+		conn, err = tls.DialWithDialer(dialer, "tcp", ep.smtpAddress, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", ep.smtpAddress)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error %s connecting to smtp server %s", err, ep.smtpAddress)
+	}
+
+	// bound the whole exchange so a hung server
+	// doesn't block publishing other events
+	conn.SetDeadline(time.Now().Add(ep.timeout))
+
+	client, err := smtp.NewClient(conn, ep.host)
+
+	if err != nil {
+		conn.Close()
+
+		return fmt.Errorf("error %s starting smtp session with %s", err, ep.smtpAddress)
+	}
+
+	defer client.Close()
+
+	if ep.security == StartTLSEmailSecurity {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("error %s starting tls with smtp server %s", err, ep.smtpAddress)
+		}
+	}
+
+	if ep.auth != nil {
+		if err := client.Auth(ep.auth); err != nil {
+			return fmt.Errorf("error %s authenticating with smtp server %s", err, ep.smtpAddress)
+		}
+	}
+
+	from, _ := mail.ParseAddress(ep.from)
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		to, _ := mail.ParseAddress(recipient)
+
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("error %s adding recipient %s", err, recipient)
+		}
+	}
+
+	writer, err := client.Data()
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// ParseEmailRecipients parses the recipients of each severity in the
+// form <severity>=<comma separated addresses>[;...], e.g.
+// critical=oncall@example.com,ops@example.com;info=ops@example.com,
+// returning the recipients and error (if any)
+func ParseEmailRecipients(rawRecipients string) (map[string][]string, error) {
+	recipients := make(map[string][]string)
+
+	for _, rawSeverityRecipients := range strings.Split(rawRecipients, ";") {
+		rawSeverityRecipients = strings.TrimSpace(rawSeverityRecipients)
+
+		if rawSeverityRecipients == "" {
+			continue
+		}
+
+		severity, rawAddresses, found := strings.Cut(rawSeverityRecipients, "=")
+		severity = strings.TrimSpace(severity)
+
+		if !found {
+			return nil, fmt.Errorf("invalid email recipients %s, expected <severity>=<addresses>", rawSeverityRecipients)
+		}
+
+		var valid bool
+
+		for _, validSeverity := range ValidSeverities {
+			if severity == validSeverity {
+				valid = true
+			}
+		}
+
+		if !valid {
+			return nil, fmt.Errorf("invalid severity %s, valid severities are %v", severity, ValidSeverities)
+		}
+
+		for _, address := range strings.Split(rawAddresses, ",") {
+			address = strings.TrimSpace(address)
+
+			if address == "" {
+				continue
+			}
+
+			if _, err := mail.ParseAddress(address); err != nil {
+				return nil, fmt.Errorf("invalid %s recipient %s: %w", severity, address, err)
+			}
+
+			recipients[severity] = append(recipients[severity], address)
+		}
+	}
+
+	return recipients, nil
+}
@@ -0,0 +1,220 @@
+package incident
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testSMTPMessage is an email received by the test smtp server
+type testSMTPMessage struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// newTestSMTPServer starts a plaintext smtp server that accepts every
+// message, returning its address and a channel of received messages
+func newTestSMTPServer(t *testing.T) (string, <-chan testSMTPMessage) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan testSMTPMessage, 10)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				reader := bufio.NewReader(conn)
+				reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+				var message testSMTPMessage
+
+				reply("220 localhost ESMTP")
+
+				for {
+					line, err := reader.ReadString('\n')
+
+					if err != nil {
+						return
+					}
+
+					command := strings.ToUpper(strings.TrimSpace(line))
+
+					switch {
+					case strings.HasPrefix(command, "EHLO"):
+						reply("250 localhost")
+					case strings.HasPrefix(command, "MAIL FROM:"):
+						message.from = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+						reply("250 OK")
+					case strings.HasPrefix(command, "RCPT TO:"):
+						message.recipients = append(message.recipients, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+						reply("250 OK")
+					case command == "DATA":
+						reply("354 end data with <CR><LF>.<CR><LF>")
+
+						var data strings.Builder
+
+						for {
+							dataLine, err := reader.ReadString('\n')
+
+							if err != nil || dataLine == ".\r\n" {
+								break
+							}
+
+							data.WriteString(dataLine)
+						}
+
+						message.data = data.String()
+						messages <- message
+						message = testSMTPMessage{}
+
+						reply("250 OK")
+					case command == "QUIT":
+						reply("221 bye")
+
+						return
+					default:
+						reply("250 OK")
+					}
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestEmailPublisherEmailsRecipientsOfSeverity(t *testing.T) {
+	address, messages := newTestSMTPServer(t)
+
+	publisher, err := NewEmailPublisher(EmailConfig{
+		SMTPAddress: address,
+		Security:    NoneEmailSecurity,
+		From:        "Doctor <doctor@example.com>",
+		Recipients: map[string][]string{
+			CriticalSeverity: {"oncall@example.com", "ops@example.com"},
+			WarningSeverity:  {"ops@example.com"},
+		},
+	})
+	assert.Nil(t, err)
+
+	occurredAt := time.Date(2023, 8, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, publisher.Publish(Event{
+		Type:        IncidentOpenedEventType,
+		IncidentId:  "incident-1",
+		Kind:        NodeOfflineIncident,
+		EndpointURL: "http://localhost:26657",
+		Message:     "node went offline",
+		OccurredAt:  occurredAt,
+	}))
+
+	select {
+	case message := <-messages:
+		assert.Equal(t, "doctor@example.com", message.from)
+		assert.Equal(t, []string{"oncall@example.com", "ops@example.com"}, message.recipients)
+		assert.Contains(t, message.data, "Subject: [doctor] critical: node_offline incident opened\r\n")
+		assert.Contains(t, message.data, "\r\nnode went offline\r\n")
+		assert.Contains(t, message.data, "Incident: incident-1\r\n")
+		assert.Contains(t, message.data, "Occurred at: 2023-08-01T12:00:00Z\r\n")
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected incident to be emailed")
+	}
+
+	assert.Nil(t, publisher.Publish(Event{
+		Type:       IncidentClosedEventType,
+		Kind:       NodeOfflineIncident,
+		Message:    "node is back online",
+		OccurredAt: occurredAt,
+	}), "events of severities without recipients aren't emailed")
+
+	select {
+	case message := <-messages:
+		t.Fatalf("expected no email, got %s", message.data)
+	default:
+	}
+}
+
+func TestEmailPublisherUsesTemplates(t *testing.T) {
+	address, messages := newTestSMTPServer(t)
+
+	bodyTemplateFilepath := filepath.Join(t.TempDir(), "body.tmpl")
+	assert.Nil(t, os.WriteFile(bodyTemplateFilepath, []byte("{{.HealAction}} on {{.NodeId}}: {{.Message}}\n"), 0644))
+
+	publisher, err := NewEmailPublisher(EmailConfig{
+		SMTPAddress:          address,
+		Security:             NoneEmailSecurity,
+		From:                 "doctor@example.com",
+		Recipients:           map[string][]string{CriticalSeverity: {"oncall@example.com"}},
+		SubjectTemplate:      "{{.Severity}} {{.NodeId}}",
+		BodyTemplateFilepath: bodyTemplateFilepath,
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, publisher.Publish(Event{
+		Type:       HealActionEventType,
+		HealAction: RestartServiceHealAction,
+		Succeeded:  false,
+		NodeId:     "node-1",
+		Message:    "systemctl exited with status 1",
+	}))
+
+	select {
+	case message := <-messages:
+		assert.Contains(t, message.data, "Subject: critical node-1\r\n")
+		assert.True(t, strings.HasSuffix(message.data, "\r\n\r\nrestart_service on node-1: systemctl exited with status 1\r\n"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected failed heal action to be emailed")
+	}
+}
+
+func TestNewEmailPublisherValidatesConfig(t *testing.T) {
+	recipients := map[string][]string{CriticalSeverity: {"oncall@example.com"}}
+
+	for _, config := range []EmailConfig{
+		{SMTPAddress: "smtp.example.com", From: "doctor@example.com", Recipients: recipients},
+		{SMTPAddress: "smtp.example.com:587", Security: "ssl", From: "doctor@example.com", Recipients: recipients},
+		{SMTPAddress: "smtp.example.com:587", From: "doctor", Recipients: recipients},
+		{SMTPAddress: "smtp.example.com:587", From: "doctor@example.com"},
+		{SMTPAddress: "smtp.example.com:587", From: "doctor@example.com", Recipients: recipients, SubjectTemplate: "{{.Severity"},
+	} {
+		_, err := NewEmailPublisher(config)
+
+		assert.NotNil(t, err, "expected config %s to be invalid", config)
+	}
+}
+
+func TestParseEmailRecipients(t *testing.T) {
+	recipients, err := ParseEmailRecipients("critical=oncall@example.com, ops@example.com; info=ops@example.com")
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]string{
+		CriticalSeverity: {"oncall@example.com", "ops@example.com"},
+		InfoSeverity:     {"ops@example.com"},
+	}, recipients)
+
+	_, err = ParseEmailRecipients("urgent=oncall@example.com")
+
+	assert.EqualError(t, err, "invalid severity urgent, valid severities are [critical warning info]")
+
+	_, err = ParseEmailRecipients("critical=oncall")
+
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,80 @@
+package incident
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+)
+
+const (
+	DefaultEventBusName = "default"
+	// source of all events published to EventBridge
+	// for use in EventBridge rule event patterns
+	EventBridgeEventSource = "kava.doctor"
+)
+
+// EventBridgePublisher implements the Publisher interface,
+// publishing events to an EventBridge event bus with the
+// event type as the detail type
+type EventBridgePublisher struct {
+	client       *eventbridge.EventBridge
+	eventBusName string
+}
+
+// NewEventBridgePublisher returns a new EventBridgePublisher
+// publishing to the specified event bus and error (if any)
+func NewEventBridgePublisher(awsRegion string, eventBusName string) (*EventBridgePublisher, error) {
+	if eventBusName == "" {
+		eventBusName = DefaultEventBusName
+	}
+
+	awsSession, err := session.NewSession(&aws.Config{
+		Region: aws.String(awsRegion),
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error %s creating valid aws session", err)
+	}
+
+	return &EventBridgePublisher{
+		client:       eventbridge.New(awsSession),
+		eventBusName: eventBusName,
+	}, nil
+}
+
+// Publish publishes the event to EventBridge, returning error (if any)
+// Publish is safe to call across go-routines
+func (ebp *EventBridgePublisher) Publish(event Event) error {
+	encodedEvent, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	output, err := ebp.client.PutEvents(&eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{
+			{
+				EventBusName: aws.String(ebp.eventBusName),
+				Source:       aws.String(EventBridgeEventSource),
+				DetailType:   aws.String(event.Type),
+				Detail:       aws.String(string(encodedEvent)),
+				Time:         aws.Time(event.OccurredAt),
+			},
+		},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if aws.Int64Value(output.FailedEntryCount) > 0 {
+		entry := output.Entries[0]
+
+		return fmt.Errorf("eventbridge rejected event with error %s: %s", aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage))
+	}
+
+	return nil
+}
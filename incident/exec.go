@@ -0,0 +1,199 @@
+package incident
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// matches events of every type
+	AllEventsSelector = "*"
+
+	DefaultExecHookTimeout        = 60 * time.Second
+	DefaultExecHookMaxConcurrency = 4
+	// maximum bytes of a failed hook's output
+	// to include in the error publishing the event
+	maxExecHookOutput = 1024
+)
+
+// ExecHook is a command run for each event matching its selector
+type ExecHook struct {
+	// <event type>[:<kind or heal action>] of events to run
+	// the command for (e.g. incident_opened:node_frozen),
+	// or AllEventsSelector for every event
+	Selector string
+	// binary and arguments of the command
+	Command []string
+}
+
+// Matches returns whether the hook should be run for the event
+func (eh ExecHook) Matches(event Event) bool {
+	if eh.Selector == AllEventsSelector {
+		return true
+	}
+
+	eventType, kind, _ := strings.Cut(eh.Selector, ":")
+
+	if eventType != event.Type {
+		return false
+	}
+
+	return kind == "" || kind == event.Kind || kind == event.HealAction
+}
+
+// ExecPublisher implements the Publisher interface, running
+// the commands of the hooks matching each event with the event
+// as json on stdin, so operators can wire in custom remediation
+// (e.g. rotating peers or calling internal apis) without forking
+// the doctor
+type ExecPublisher struct {
+	hooks   []ExecHook
+	timeout time.Duration
+	// slots for running hooks, bounding how many
+	// hooks run at once across all events
+	slots chan struct{}
+}
+
+// NewExecPublisher returns a new ExecPublisher that runs the commands of
+// the hooks for up to timeout, running at most maxConcurrency commands
+// at once (or defaults if not positive), and error (if any)
+func NewExecPublisher(hooks []ExecHook, timeout time.Duration, maxConcurrency int) (*ExecPublisher, error) {
+	if len(hooks) == 0 {
+		return nil, fmt.Errorf("at least one hook is required for the %s incident publisher", ExecPublisherName)
+	}
+
+	for _, hook := range hooks {
+		if err := ValidateExecHookSelector(hook.Selector); err != nil {
+			return nil, err
+		}
+
+		if len(hook.Command) == 0 {
+			return nil, fmt.Errorf("a command is required for the %s hook", hook.Selector)
+		}
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultExecHookTimeout
+	}
+
+	if maxConcurrency <= 0 {
+		maxConcurrency = DefaultExecHookMaxConcurrency
+	}
+
+	return &ExecPublisher{
+		hooks:   hooks,
+		timeout: timeout,
+		slots:   make(chan struct{}, maxConcurrency),
+	}, nil
+}
+
+// Publish runs the command of every hook matching the event in parallel,
+// waiting for a free slot if the maximum number of commands are already
+// running, returning the first error encountered (if any) once every
+// command has exited or been killed for running past the timeout
+// Publish is safe to call across go-routines
+func (ep *ExecPublisher) Publish(event Event) error {
+	var matchingHooks []ExecHook
+
+	for _, hook := range ep.hooks {
+		if hook.Matches(event) {
+			matchingHooks = append(matchingHooks, hook)
+		}
+	}
+
+	if len(matchingHooks) == 0 {
+		return nil
+	}
+
+	input, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(matchingHooks))
+	var hooksRunning sync.WaitGroup
+
+	for i, hook := range matchingHooks {
+		hooksRunning.Add(1)
+
+		go func(i int, hook ExecHook) {
+			defer hooksRunning.Done()
+
+			ep.slots <- struct{}{}
+			defer func() { <-ep.slots }()
+
+			errs[i] = ep.run(hook, input)
+		}(i, hook)
+	}
+
+	hooksRunning.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// run runs the command of the hook with input on stdin,
+// returning error (if any) including the command's output
+func (ep *ExecPublisher) run(hook ExecHook, input []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), ep.timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, hook.Command[0], hook.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%s hook %s killed after running for %v", hook.Selector, strings.Join(hook.Command, " "), ep.timeout)
+	}
+
+	if err != nil {
+		trimmedOutput := output.String()
+
+		if len(trimmedOutput) > maxExecHookOutput {
+			trimmedOutput = trimmedOutput[:maxExecHookOutput] + "..."
+		}
+
+		return fmt.Errorf("error %s running %s hook %s output %s", err, hook.Selector, strings.Join(hook.Command, " "), trimmedOutput)
+	}
+
+	return nil
+}
+
+// ValidateExecHookSelector returns an error if the selector isn't
+// AllEventsSelector or of the form <event type>[:<kind or heal action>]
+func ValidateExecHookSelector(selector string) error {
+	if selector == AllEventsSelector {
+		return nil
+	}
+
+	eventType, kind, hasKind := strings.Cut(selector, ":")
+
+	for _, validEventType := range ValidEventTypes {
+		if eventType == validEventType {
+			if hasKind && kind == "" {
+				return fmt.Errorf("invalid hook selector %s, expected <event type>[:<kind>]", selector)
+			}
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid hook selector %s, the event type must be one of %v or the selector %s", selector, ValidEventTypes, AllEventsSelector)
+}
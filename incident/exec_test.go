@@ -0,0 +1,117 @@
+package incident
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecHookMatches(t *testing.T) {
+	frozen := Event{Type: IncidentOpenedEventType, Kind: "node_frozen"}
+	restarted := Event{Type: HealActionEventType, HealAction: RestartServiceHealAction}
+
+	for _, testCase := range []struct {
+		selector string
+		event    Event
+		matches  bool
+	}{
+		{AllEventsSelector, frozen, true},
+		{IncidentOpenedEventType, frozen, true},
+		{IncidentOpenedEventType + ":node_frozen", frozen, true},
+		{IncidentOpenedEventType + ":node_offline", frozen, false},
+		{IncidentClosedEventType, frozen, false},
+		{HealActionEventType + ":" + RestartServiceHealAction, restarted, true},
+		{HealActionEventType + ":" + EnterStandbyHealAction, restarted, false},
+	} {
+		hook := ExecHook{Selector: testCase.selector, Command: []string{"true"}}
+
+		assert.Equal(t, testCase.matches, hook.Matches(testCase.event), "selector %s", testCase.selector)
+	}
+}
+
+func TestNewExecPublisherValidatesHooks(t *testing.T) {
+	for _, hooks := range [][]ExecHook{
+		nil,
+		{{Selector: "node_down", Command: []string{"true"}}},
+		{{Selector: IncidentOpenedEventType + ":", Command: []string{"true"}}},
+		{{Selector: IncidentOpenedEventType}},
+	} {
+		_, err := NewExecPublisher(hooks, 0, 0)
+
+		assert.NotNil(t, err, "expected hooks %+v to be invalid", hooks)
+	}
+}
+
+func TestExecPublisherPassesEventOnStdin(t *testing.T) {
+	directory := t.TempDir()
+
+	publisher, err := NewExecPublisher([]ExecHook{
+		{Selector: IncidentOpenedEventType, Command: []string{"sh", "-c", "cat > " + filepath.Join(directory, "opened.json")}},
+		{Selector: IncidentClosedEventType, Command: []string{"sh", "-c", "cat > " + filepath.Join(directory, "closed.json")}},
+	}, time.Second, 1)
+	assert.Nil(t, err)
+
+	event := Event{
+		Type:       IncidentOpenedEventType,
+		Kind:       "node_frozen",
+		IncidentId: "incident-1",
+		NodeId:     "node-1",
+		OccurredAt: time.Date(2023, 8, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	assert.Nil(t, publisher.Publish(event))
+
+	rawEvent, err := os.ReadFile(filepath.Join(directory, "opened.json"))
+	assert.Nil(t, err)
+
+	var publishedEvent Event
+	assert.Nil(t, json.Unmarshal(rawEvent, &publishedEvent))
+	assert.Equal(t, event, publishedEvent)
+
+	_, err = os.Stat(filepath.Join(directory, "closed.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestExecPublisherReturnsHookErrors(t *testing.T) {
+	publisher, err := NewExecPublisher([]ExecHook{
+		{Selector: AllEventsSelector, Command: []string{"sh", "-c", "echo unreachable >&2; exit 1"}},
+	}, time.Second, 0)
+	assert.Nil(t, err)
+
+	err = publisher.Publish(Event{Type: IncidentOpenedEventType})
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unreachable")
+}
+
+func TestExecPublisherKillsHooksAfterTimeout(t *testing.T) {
+	publisher, err := NewExecPublisher([]ExecHook{
+		{Selector: AllEventsSelector, Command: []string{"sleep", "10"}},
+	}, 100*time.Millisecond, 0)
+	assert.Nil(t, err)
+
+	startedAt := time.Now()
+
+	assert.NotNil(t, publisher.Publish(Event{Type: IncidentOpenedEventType}))
+	assert.Less(t, time.Since(startedAt), 5*time.Second)
+}
+
+func TestExecPublisherLimitsConcurrency(t *testing.T) {
+	hooks := make([]ExecHook, 3)
+
+	for i := range hooks {
+		hooks[i] = ExecHook{Selector: AllEventsSelector, Command: []string{"sleep", "0.2"}}
+	}
+
+	publisher, err := NewExecPublisher(hooks, 5*time.Second, 1)
+	assert.Nil(t, err)
+
+	startedAt := time.Now()
+
+	assert.Nil(t, publisher.Publish(Event{Type: IncidentOpenedEventType}))
+	assert.GreaterOrEqual(t, time.Since(startedAt), 600*time.Millisecond)
+}
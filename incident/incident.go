@@ -0,0 +1,356 @@
+// package incident provides types and functions for tracking incidents
+// (e.g. a node going offline) and heal actions taken by the doctor, and
+// publishing them as events to external systems (e.g. CloudWatch Logs
+// or EventBridge) so downstream automation can react to them
+package incident
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kava-labs/doctor/webhook"
+)
+
+const (
+	// event types
+	IncidentOpenedEventType = "incident_opened"
+	IncidentClosedEventType = "incident_closed"
+	HealActionEventType     = "heal_action"
+	// the node moved between restoring a state sync snapshot,
+	// replaying blocks and being synched to live
+	SyncPhaseChangedEventType = "sync_phase_changed"
+
+	// kinds of incidents
+	NodeOfflineIncident   = "node_offline"
+	NodeOutOfSyncIncident = "node_out_of_sync"
+	NodeFrozenIncident    = "node_frozen"
+	// systemd is repeatedly restarting the blockchain service
+	ServiceRestartLoopIncident = "service_restart_loop"
+	// consensus is stuck in the same height, round and step
+	ConsensusFrozenIncident = "consensus_frozen"
+	// the endpoint resolved to a node other than the pinned node
+	NodeIdentityMismatchIncident = "node_identity_mismatch"
+	// the node is on a chain other than the expected
+	// chain (e.g. a testnet or a fork)
+	ChainIdMismatchIncident = "chain_id_mismatch"
+	// the node's block or app hashes differ from the reference node
+	ChainDivergenceIncident = "chain_divergence"
+	// the voting power of the validators signing blocks is below
+	// the degraded threshold, i.e. the chain itself is halted or
+	// at risk of halting regardless of the health of the node
+	ChainDegradedIncident = "chain_degraded"
+	// autohealing hit its restart cap and is
+	// stopped until manually reset
+	AutohealCircuitBreakerIncident = "autoheal_circuit_breaker_tripped"
+
+	// heal actions
+	RestartServiceHealAction   = "restart_service"
+	EnterStandbyHealAction     = "enter_standby"
+	ExitStandbyHealAction      = "exit_standby"
+	RebootInstanceHealAction   = "reboot_instance"
+	RestoreStateHealAction     = "restore_state"
+	ReplaceInstanceHealAction  = "replace_instance"
+	DeregisterTargetHealAction = "deregister_target"
+	RegisterTargetHealAction   = "register_target"
+	DrainDNSRecordHealAction   = "drain_dns_record"
+	RestoreDNSRecordHealAction = "restore_dns_record"
+	// an operator configured command was run
+	ExecHookHealAction = "exec_hook"
+	// the node was reconnected to known good peers
+	RefreshPeersHealAction = "refresh_peers"
+
+	// severities of events, e.g. for emailing
+	// events to different lists of recipients
+	CriticalSeverity = "critical"
+	WarningSeverity  = "warning"
+	InfoSeverity     = "info"
+
+	// supported publishers
+	CloudWatchLogsPublisherName = "cloudwatch_logs"
+	EventBridgePublisherName    = "eventbridge"
+	WebhookPublisherName        = "webhook"
+	EmailPublisherName          = "email"
+	TelegramPublisherName       = "telegram"
+	DiscordPublisherName        = "discord"
+	ExecPublisherName           = "exec"
+)
+
+var (
+	ValidSeverities = []string{CriticalSeverity, WarningSeverity, InfoSeverity}
+	ValidPublishers = []string{CloudWatchLogsPublisherName, EventBridgePublisherName, WebhookPublisherName, EmailPublisherName, TelegramPublisherName, DiscordPublisherName, ExecPublisherName}
+	ValidEventTypes = []string{IncidentOpenedEventType, IncidentClosedEventType, HealActionEventType, SyncPhaseChangedEventType, AlertFiringEventType, AlertEscalatedEventType, AlertResolvedEventType}
+)
+
+// Event wraps values for an incident being opened or
+// closed, or a heal action being taken for a node
+type Event struct {
+	Type        string    `json:"type"`
+	IncidentId  string    `json:"incident_id,omitempty"`
+	Kind        string    `json:"kind,omitempty"`
+	HealAction  string    `json:"heal_action,omitempty"`
+	Succeeded   bool      `json:"succeeded"`
+	NodeId      string    `json:"node_id,omitempty"`
+	EndpointURL string    `json:"endpoint_url"`
+	Message     string    `json:"message"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	// for closed incidents, how long the incident was open for
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+}
+
+// Severity returns how urgently a person should look at the event,
+// critical for incidents being opened, heal actions that failed and
+// escalated alerts, warning for heal actions that succeeded (as the
+// node needed healing) and alerts that started firing, and info for
+// everything else (e.g. incidents being closed)
+func Severity(event Event) string {
+	switch event.Type {
+	case IncidentOpenedEventType, AlertEscalatedEventType:
+		return CriticalSeverity
+	case AlertFiringEventType:
+		return WarningSeverity
+	case HealActionEventType:
+		if !event.Succeeded {
+			return CriticalSeverity
+		}
+
+		return WarningSeverity
+	}
+
+	return InfoSeverity
+}
+
+// summarize returns a one line summary of the event,
+// e.g. node_offline incident opened
+func summarize(event Event) string {
+	switch event.Type {
+	case IncidentOpenedEventType:
+		return fmt.Sprintf("%s incident opened", event.Kind)
+	case IncidentClosedEventType:
+		return fmt.Sprintf("%s incident closed", event.Kind)
+	case AlertFiringEventType:
+		return fmt.Sprintf("%s alert firing", event.Kind)
+	case AlertEscalatedEventType:
+		return fmt.Sprintf("%s alert still firing", event.Kind)
+	case AlertResolvedEventType:
+		return fmt.Sprintf("%s alert resolved", event.Kind)
+	case HealActionEventType:
+		if !event.Succeeded {
+			return fmt.Sprintf("%s heal action failed", event.HealAction)
+		}
+
+		return fmt.Sprintf("%s heal action succeeded", event.HealAction)
+	}
+
+	return strings.ReplaceAll(event.Type, "_", " ")
+}
+
+// Publisher publishes incident and heal events
+// to an external system
+type Publisher interface {
+	// Publish publishes the event, returning error (if any)
+	Publish(event Event) error
+}
+
+// MultiPublisher implements the Publisher interface,
+// publishing events to multiple publishers
+type MultiPublisher []Publisher
+
+// Publish publishes the event to every publisher, returning
+// the first error encountered (if any) after attempting to
+// publish to every publisher
+func (mp MultiPublisher) Publish(event Event) error {
+	var firstErr error
+
+	for _, publisher := range mp {
+		if err := publisher.Publish(event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// PublisherConfig wraps values for
+// configuring incident publishers
+type PublisherConfig struct {
+	Publishers   []string
+	AWSRegion    string
+	LogGroupName string
+	EventBusName string
+	// client for posting events to a webhook,
+	// required if the webhook publisher is used
+	WebhookClient *webhook.Client
+	// smtp server and recipients to email
+	// events to if the email publisher is used
+	Email EmailConfig
+	// bot to send messages from and chat to send them to
+	// if the telegram publisher is used
+	TelegramBotToken string
+	TelegramChatId   string
+	// webhook to post messages to if the discord publisher is used
+	DiscordWebhookURL string
+	// commands to run for events if the exec publisher is used,
+	// how long each may run for and how many may run at once
+	ExecHooks              []ExecHook
+	ExecHookTimeout        time.Duration
+	ExecHookMaxConcurrency int
+	// optional windows of time events of each severity are
+	// published in, keyed by the name of the publisher
+	Schedules map[string]Schedule
+}
+
+// New returns a publisher that publishes to all the
+// publishers specified in config and error (if any)
+func New(config PublisherConfig) (Publisher, error) {
+	var publishers MultiPublisher
+
+	for _, publisherName := range config.Publishers {
+		switch publisherName {
+		case CloudWatchLogsPublisherName:
+			publisher, err := NewCloudWatchLogsPublisher(config.AWSRegion, config.LogGroupName)
+
+			if err != nil {
+				return nil, err
+			}
+
+			publishers = append(publishers, publisher)
+		case EventBridgePublisherName:
+			publisher, err := NewEventBridgePublisher(config.AWSRegion, config.EventBusName)
+
+			if err != nil {
+				return nil, err
+			}
+
+			publishers = append(publishers, publisher)
+		case WebhookPublisherName:
+			if config.WebhookClient == nil {
+				return nil, fmt.Errorf("a webhook client is required for the %s incident publisher", WebhookPublisherName)
+			}
+
+			publishers = append(publishers, NewWebhookPublisher(config.WebhookClient))
+		case EmailPublisherName:
+			publisher, err := NewEmailPublisher(config.Email)
+
+			if err != nil {
+				return nil, err
+			}
+
+			publishers = append(publishers, publisher)
+		case TelegramPublisherName:
+			publisher, err := NewTelegramPublisher(config.TelegramBotToken, config.TelegramChatId)
+
+			if err != nil {
+				return nil, err
+			}
+
+			publishers = append(publishers, publisher)
+		case DiscordPublisherName:
+			publisher, err := NewDiscordPublisher(config.DiscordWebhookURL)
+
+			if err != nil {
+				return nil, err
+			}
+
+			publishers = append(publishers, publisher)
+		case ExecPublisherName:
+			publisher, err := NewExecPublisher(config.ExecHooks, config.ExecHookTimeout, config.ExecHookMaxConcurrency)
+
+			if err != nil {
+				return nil, err
+			}
+
+			publishers = append(publishers, publisher)
+		default:
+			return nil, fmt.Errorf("invalid incident publisher %s, valid publishers are %v", publisherName, ValidPublishers)
+		}
+
+		if schedule, scheduled := config.Schedules[publisherName]; scheduled {
+			publishers[len(publishers)-1] = NewScheduledPublisher(publishers[len(publishers)-1], schedule)
+		}
+	}
+
+	return publishers, nil
+}
+
+// Tracker tracks which kinds of incidents are currently
+// open for an endpoint, so that an incident is only opened
+// once no matter how many times it's observed, and closed
+// once when it's resolved
+// Tracker is not safe for concurrent use
+type Tracker struct {
+	endpointURL   string
+	openIncidents map[string]Event
+}
+
+// NewTracker returns a new tracker for incidents
+// of the endpoint with the specified url
+func NewTracker(endpointURL string) *Tracker {
+	return &Tracker{
+		endpointURL:   endpointURL,
+		openIncidents: make(map[string]Event),
+	}
+}
+
+// Open opens an incident of the specified kind, returning the
+// incident opened event and true if no incident of that kind
+// was already open, otherwise false
+func (t *Tracker) Open(kind string, nodeId string, message string, occurredAt time.Time) (Event, bool) {
+	if _, open := t.openIncidents[kind]; open {
+		return Event{}, false
+	}
+
+	event := Event{
+		Type:        IncidentOpenedEventType,
+		IncidentId:  uuid.New().String(),
+		Kind:        kind,
+		NodeId:      nodeId,
+		EndpointURL: t.endpointURL,
+		Message:     message,
+		OccurredAt:  occurredAt,
+	}
+
+	t.openIncidents[kind] = event
+
+	return event, true
+}
+
+// Close closes the open incident of the specified kind, returning
+// the incident closed event and true if an incident of that kind
+// was open, otherwise false
+func (t *Tracker) Close(kind string, message string, occurredAt time.Time) (Event, bool) {
+	opened, open := t.openIncidents[kind]
+
+	if !open {
+		return Event{}, false
+	}
+
+	delete(t.openIncidents, kind)
+
+	return Event{
+		Type:            IncidentClosedEventType,
+		IncidentId:      opened.IncidentId,
+		Kind:            kind,
+		NodeId:          opened.NodeId,
+		EndpointURL:     t.endpointURL,
+		Message:         message,
+		OccurredAt:      occurredAt,
+		DurationSeconds: occurredAt.Sub(opened.OccurredAt).Seconds(),
+	}, true
+}
+
+// OpenIncidentId returns the id of the open incident
+// of the specified kind and whether one is open
+func (t *Tracker) OpenIncidentId(kind string) (string, bool) {
+	opened, open := t.openIncidents[kind]
+
+	return opened.IncidentId, open
+}
+
+// IsOpen returns whether an incident of the specified kind is open
+func (t *Tracker) IsOpen(kind string) bool {
+	_, open := t.openIncidents[kind]
+
+	return open
+}
@@ -0,0 +1,62 @@
+package incident
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackerOnlyOpensAndClosesIncidentOnce(t *testing.T) {
+	tracker := NewTracker("https://example.kava.io")
+	openedAt := time.Now()
+
+	opened, ok := tracker.Open(NodeOfflineIncident, "", "node went offline", openedAt)
+
+	assert.True(t, ok)
+	assert.Equal(t, IncidentOpenedEventType, opened.Type)
+	assert.NotEmpty(t, opened.IncidentId)
+	assert.True(t, tracker.IsOpen(NodeOfflineIncident))
+
+	_, ok = tracker.Open(NodeOfflineIncident, "", "node is still offline", openedAt.Add(time.Minute))
+
+	assert.False(t, ok, "already open incident should not be re-opened")
+
+	closed, ok := tracker.Close(NodeOfflineIncident, "node is back online", openedAt.Add(2*time.Minute))
+
+	assert.True(t, ok)
+	assert.Equal(t, IncidentClosedEventType, closed.Type)
+	assert.Equal(t, opened.IncidentId, closed.IncidentId)
+	assert.Equal(t, float64(120), closed.DurationSeconds)
+	assert.False(t, tracker.IsOpen(NodeOfflineIncident))
+
+	_, ok = tracker.Close(NodeOfflineIncident, "node is still online", openedAt.Add(3*time.Minute))
+
+	assert.False(t, ok, "closed incident should not be closed again")
+}
+
+func TestTrackerTracksIncidentKindsIndependently(t *testing.T) {
+	tracker := NewTracker("https://example.kava.io")
+
+	_, ok := tracker.Open(NodeFrozenIncident, "node", "node frozen", time.Now())
+	assert.True(t, ok)
+
+	_, ok = tracker.Open(NodeOutOfSyncIncident, "node", "node out of sync", time.Now())
+	assert.True(t, ok)
+
+	_, ok = tracker.Close(NodeOutOfSyncIncident, "node caught up", time.Now())
+	assert.True(t, ok)
+
+	assert.True(t, tracker.IsOpen(NodeFrozenIncident))
+}
+
+func TestChannelPublisherCountsDroppedEvents(t *testing.T) {
+	events := make(chan Event, 1)
+	publisher := NewChannelPublisher(events)
+
+	assert.Nil(t, publisher.Publish(Event{Type: IncidentOpenedEventType}))
+	assert.ErrorIs(t, publisher.Publish(Event{Type: IncidentClosedEventType}), ErrChannelFull)
+
+	assert.Equal(t, uint64(1), publisher.Dropped())
+	assert.Equal(t, IncidentOpenedEventType, (<-events).Type)
+}
@@ -0,0 +1,207 @@
+package incident
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeWindow is a daily window of time in UTC, a window
+// that ends before it starts spans midnight (e.g. 21:00-09:00)
+type TimeWindow struct {
+	// offsets since midnight UTC
+	Start time.Duration
+	End   time.Duration
+}
+
+// Contains returns whether the time of day of t
+// (in UTC) is within the window
+func (tw TimeWindow) Contains(t time.Time) bool {
+	t = t.UTC()
+	offset := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+
+	if tw.Start <= tw.End {
+		return offset >= tw.Start && offset < tw.End
+	}
+
+	return offset >= tw.Start || offset < tw.End
+}
+
+// String returns the window in the form HH:MM-HH:MM
+func (tw TimeWindow) String() string {
+	format := func(offset time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(offset.Hours()), int(offset.Minutes())%60)
+	}
+
+	return format(tw.Start) + "-" + format(tw.End)
+}
+
+// Schedule is the windows of time events of each severity
+// are published in, events of severities without
+// windows are published at any time
+type Schedule map[string][]TimeWindow
+
+// Allows returns whether an event of the
+// severity can be published at time t
+func (s Schedule) Allows(severity string, t time.Time) bool {
+	windows, scheduled := s[severity]
+
+	if !scheduled {
+		return true
+	}
+
+	for _, window := range windows {
+		if window.Contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScheduledPublisher implements the Publisher interface, only
+// publishing events to the wrapped publisher within the
+// windows of time scheduled for their severity, e.g. only paging
+// for warnings while the team that's on call is awake
+type ScheduledPublisher struct {
+	publisher Publisher
+	schedule  Schedule
+	// clock used to decide whether events are in schedule
+	now func() time.Time
+}
+
+// NewScheduledPublisher returns a new ScheduledPublisher that publishes
+// events to publisher within the windows of the schedule
+func NewScheduledPublisher(publisher Publisher, schedule Schedule) *ScheduledPublisher {
+	return &ScheduledPublisher{
+		publisher: publisher,
+		schedule:  schedule,
+		now:       time.Now,
+	}
+}
+
+// Publish publishes the event if the schedule allows events of its
+// severity to be published now, otherwise discards the event,
+// returning error (if any)
+// Publish is safe to call across go-routines
+// if the wrapped publisher is
+func (sp *ScheduledPublisher) Publish(event Event) error {
+	if !sp.schedule.Allows(Severity(event), sp.now()) {
+		return nil
+	}
+
+	return sp.publisher.Publish(event)
+}
+
+// ParseSchedules parses the schedule of each incident publisher
+// in the form <publisher>:<severity>=<comma separated HH:MM-HH:MM
+// windows in UTC>[;...], e.g. email:warning=09:00-21:00;telegram:info=
+// 08:00-12:00,13:00-17:00, returning the schedules keyed by
+// publisher name and error (if any)
+func ParseSchedules(rawSchedules string) (map[string]Schedule, error) {
+	schedules := make(map[string]Schedule)
+
+	for _, rawSchedule := range strings.Split(rawSchedules, ";") {
+		rawSchedule = strings.TrimSpace(rawSchedule)
+
+		if rawSchedule == "" {
+			continue
+		}
+
+		target, rawWindows, found := strings.Cut(rawSchedule, "=")
+
+		if !found {
+			return nil, fmt.Errorf("invalid schedule %s, expected <publisher>:<severity>=<windows>", rawSchedule)
+		}
+
+		publisher, severity, found := strings.Cut(strings.TrimSpace(target), ":")
+
+		if !found {
+			return nil, fmt.Errorf("invalid schedule %s, expected <publisher>:<severity>=<windows>", rawSchedule)
+		}
+
+		publisher = strings.TrimSpace(publisher)
+		severity = strings.TrimSpace(severity)
+
+		var validPublisher bool
+
+		for _, validPublisherName := range ValidPublishers {
+			if publisher == validPublisherName {
+				validPublisher = true
+			}
+		}
+
+		if !validPublisher {
+			return nil, fmt.Errorf("invalid incident publisher %s, valid publishers are %v", publisher, ValidPublishers)
+		}
+
+		var validSeverity bool
+
+		for _, validSeverityName := range ValidSeverities {
+			if severity == validSeverityName {
+				validSeverity = true
+			}
+		}
+
+		if !validSeverity {
+			return nil, fmt.Errorf("invalid severity %s, valid severities are %v", severity, ValidSeverities)
+		}
+
+		if _, exists := schedules[publisher][severity]; exists {
+			return nil, fmt.Errorf("%s events are scheduled more than once for the %s incident publisher", severity, publisher)
+		}
+
+		var windows []TimeWindow
+
+		for _, rawWindow := range strings.Split(rawWindows, ",") {
+			window, err := parseTimeWindow(strings.TrimSpace(rawWindow))
+
+			if err != nil {
+				return nil, err
+			}
+
+			windows = append(windows, window)
+		}
+
+		if schedules[publisher] == nil {
+			schedules[publisher] = make(Schedule)
+		}
+
+		schedules[publisher][severity] = windows
+	}
+
+	return schedules, nil
+}
+
+// parseTimeWindow parses a window in the form
+// HH:MM-HH:MM, returning the window and error (if any)
+func parseTimeWindow(rawWindow string) (TimeWindow, error) {
+	rawStart, rawEnd, found := strings.Cut(rawWindow, "-")
+
+	if !found {
+		return TimeWindow{}, fmt.Errorf("invalid time window %s, expected <HH:MM>-<HH:MM>", rawWindow)
+	}
+
+	start, err := time.Parse("15:04", strings.TrimSpace(rawStart))
+
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid start of time window %s, expected HH:MM", rawWindow)
+	}
+
+	end, err := time.Parse("15:04", strings.TrimSpace(rawEnd))
+
+	if err != nil {
+		return TimeWindow{}, fmt.Errorf("invalid end of time window %s, expected HH:MM", rawWindow)
+	}
+
+	window := TimeWindow{
+		Start: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+		End:   time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+	}
+
+	if window.Start == window.End {
+		return TimeWindow{}, fmt.Errorf("invalid time window %s, the window is empty", rawWindow)
+	}
+
+	return window, nil
+}
@@ -0,0 +1,78 @@
+package incident
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduledPublisherOnlyPublishesWithinWindows(t *testing.T) {
+	schedules, err := ParseSchedules("email:warning=09:00-21:00; email:info=22:00-02:00")
+	assert.Nil(t, err)
+
+	publisher := make(testPublisher, 10)
+	scheduled := NewScheduledPublisher(publisher, schedules[EmailPublisherName])
+
+	warning := Event{Type: AlertFiringEventType, Kind: "PeerChurn"}
+	critical := Event{Type: AlertEscalatedEventType, Kind: "PeerChurn"}
+	info := Event{Type: AlertResolvedEventType, Kind: "PeerChurn"}
+
+	for _, testCase := range []struct {
+		time   string
+		event  Event
+		allows bool
+	}{
+		{"08:59", warning, false},
+		{"09:00", warning, true},
+		{"20:59", warning, true},
+		{"21:00", warning, false},
+		{"03:00", critical, true},
+		{"23:30", info, true},
+		{"01:59", info, true},
+		{"02:00", info, false},
+	} {
+		at, err := time.Parse("2006-01-02 15:04", "2023-08-01 "+testCase.time)
+		assert.Nil(t, err)
+
+		scheduled.now = func() time.Time { return at }
+
+		assert.Nil(t, scheduled.Publish(testCase.event))
+
+		select {
+		case <-publisher:
+			assert.True(t, testCase.allows, "expected %s %s event to be discarded", testCase.time, testCase.event.Type)
+		default:
+			assert.False(t, testCase.allows, "expected %s %s event to be published", testCase.time, testCase.event.Type)
+		}
+	}
+}
+
+func TestParseSchedules(t *testing.T) {
+	schedules, err := ParseSchedules("email:warning=09:00-12:00,13:00-17:30;telegram:critical=00:00-23:59")
+
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]Schedule{
+		EmailPublisherName: {
+			WarningSeverity: {{Start: 9 * time.Hour, End: 12 * time.Hour}, {Start: 13 * time.Hour, End: 17*time.Hour + 30*time.Minute}},
+		},
+		TelegramPublisherName: {
+			CriticalSeverity: {{Start: 0, End: 23*time.Hour + 59*time.Minute}},
+		},
+	}, schedules)
+	assert.Equal(t, "13:00-17:30", schedules[EmailPublisherName][WarningSeverity][1].String())
+
+	for _, rawSchedules := range []string{
+		"email=09:00-21:00",
+		"pagerduty:warning=09:00-21:00",
+		"email:urgent=09:00-21:00",
+		"email:warning=9am-9pm",
+		"email:warning=09:00-24:00",
+		"email:warning=09:00-09:00",
+		"email:warning=09:00-12:00;email:warning=13:00-17:00",
+	} {
+		_, err := ParseSchedules(rawSchedules)
+
+		assert.NotNil(t, err, "expected schedules %s to be invalid", rawSchedules)
+	}
+}
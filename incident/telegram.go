@@ -0,0 +1,70 @@
+package incident
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	TelegramAPIURL = "https://api.telegram.org"
+	// maximum number of characters in a telegram message
+	MaxTelegramMessageLength = 4096
+)
+
+// TelegramPublisher implements the Publisher interface,
+// publishing events by sending them as messages from a
+// Telegram bot to a chat (e.g. a group of operators)
+type TelegramPublisher struct {
+	apiURL     string
+	botToken   string
+	chatId     string
+	httpClient *http.Client
+}
+
+// NewTelegramPublisher returns a new TelegramPublisher sending
+// messages to the chat with the specified id (or @username
+// of a channel) using the token of a bot that is a member
+// of the chat, and error (if any)
+func NewTelegramPublisher(botToken string, chatId string) (*TelegramPublisher, error) {
+	if botToken == "" {
+		return nil, fmt.Errorf("a bot token is required to send telegram messages")
+	}
+
+	if chatId == "" {
+		return nil, fmt.Errorf("a chat id is required to send telegram messages")
+	}
+
+	return &TelegramPublisher{
+		apiURL:     TelegramAPIURL,
+		botToken:   botToken,
+		chatId:     chatId,
+		httpClient: &http.Client{Timeout: DefaultChatHTTPTimeout},
+	}, nil
+}
+
+// telegramMessage is the payload of a request to
+// send a plain text message to a chat
+type telegramMessage struct {
+	ChatId                string `json:"chat_id"`
+	Text                  string `json:"text"`
+	DisableWebPagePreview bool   `json:"disable_web_page_preview"`
+}
+
+// Publish sends the event as a message to the chat, returning error (if any)
+// Publish is safe to call across go-routines
+func (tp *TelegramPublisher) Publish(event Event) error {
+	err := postChatMessage(tp.httpClient, fmt.Sprintf("%s/bot%s/sendMessage", tp.apiURL, tp.botToken), telegramMessage{
+		ChatId:                tp.chatId,
+		Text:                  chatText(event, MaxTelegramMessageLength),
+		DisableWebPagePreview: true,
+	})
+
+	if err != nil {
+		// errors making the request include the url,
+		// which contains the bot token
+		return fmt.Errorf("error sending telegram message: %s", strings.ReplaceAll(err.Error(), tp.botToken, "REDACTED"))
+	}
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package incident
+
+import (
+	"github.com/kava-labs/doctor/webhook"
+)
+
+// WebhookPublisher implements the Publisher interface,
+// publishing events by posting them to a webhook
+type WebhookPublisher struct {
+	client *webhook.Client
+}
+
+// NewWebhookPublisher returns a new WebhookPublisher
+// publishing events using the provided webhook client
+func NewWebhookPublisher(client *webhook.Client) *WebhookPublisher {
+	return &WebhookPublisher{
+		client: client,
+	}
+}
+
+// Publish posts the event to the webhook, returning error (if any)
+// Publish is safe to call across go-routines
+func (wp *WebhookPublisher) Publish(event Event) error {
+	return wp.client.Post(webhook.IncidentEventPayloadType, event)
+}
@@ -0,0 +1,325 @@
+// package logging provides leveled, structured logging
+// for the doctor program, supporting both human readable text
+// and newline delimited JSON output for downstream log aggregation
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Level represents the severity of a log message
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var (
+	ValidFormats = []string{TextFormat, JSONFormat}
+	ValidLevels  = []string{DebugLevel.String(), InfoLevel.String(), WarnLevel.String(), ErrorLevel.String()}
+)
+
+// String returns the lower case name of the level
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "debug"
+	case InfoLevel:
+		return "info"
+	case WarnLevel:
+		return "warn"
+	case ErrorLevel:
+		return "error"
+	}
+
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel parses the name of a log level
+// returning the level and error (if any)
+func ParseLevel(level string) (Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return DebugLevel, nil
+	case "info":
+		return InfoLevel, nil
+	case "warn", "warning":
+		return WarnLevel, nil
+	case "error":
+		return ErrorLevel, nil
+	}
+
+	return InfoLevel, fmt.Errorf("invalid log level %s, valid levels are %v", level, ValidLevels)
+}
+
+// Fields are arbitrary key value pairs
+// to associate with a log message
+// e.g. the node_id or endpoint the message is about
+type Fields = map[string]interface{}
+
+// Entry is a single structured log message
+type Entry struct {
+	Timestamp time.Time
+	Level     Level
+	Message   string
+	Fields    Fields
+}
+
+// TimeFormat formats times for display
+// in a consistent time zone and layout
+type TimeFormat struct {
+	// time zone to display times in, defaults to UTC
+	Location *time.Location
+	// layout to display times in (as used
+	// by time.Format), defaults to RFC3339
+	Layout string
+}
+
+// In returns the time in the time zone of the format
+func (tf TimeFormat) In(t time.Time) time.Time {
+	if tf.Location == nil {
+		return t.UTC()
+	}
+
+	return t.In(tf.Location)
+}
+
+// Format returns the time formatted in the
+// time zone and layout of the format
+func (tf TimeFormat) Format(t time.Time) string {
+	layout := tf.Layout
+
+	if layout == "" {
+		layout = time.RFC3339
+	}
+
+	return tf.In(t).Format(layout)
+}
+
+// Format encodes the entry as a single line of text in the
+// specified format (defaulting to text), with the timestamp
+// of text entries formatted using timeFormat and the
+// timestamp of json entries in the time zone of timeFormat
+func (e Entry) Format(format string, timeFormat TimeFormat) string {
+	if format == JSONFormat {
+		return e.json(timeFormat)
+	}
+
+	return e.text(timeFormat)
+}
+
+// text encodes the entry as a human readable line
+// with fields appended in key=value form sorted by key
+func (e Entry) text(timeFormat TimeFormat) string {
+	var builder strings.Builder
+
+	builder.WriteString(timeFormat.Format(e.Timestamp))
+	builder.WriteString(" ")
+	builder.WriteString(strings.ToUpper(e.Level.String()))
+	builder.WriteString(" ")
+	builder.WriteString(e.Message)
+
+	for _, key := range sortedKeys(e.Fields) {
+		builder.WriteString(fmt.Sprintf(" %s=%v", key, e.Fields[key]))
+	}
+
+	return builder.String()
+}
+
+// json encodes the entry as a single json object
+// with fields flattened into the top level object
+// timestamps are always encoded as RFC3339 (with
+// nanoseconds) so they can be parsed downstream
+func (e Entry) json(timeFormat TimeFormat) string {
+	encoded := make(map[string]interface{}, len(e.Fields)+3)
+
+	for key, value := range e.Fields {
+		// encode errors by message rather than as empty objects
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+
+		encoded[key] = value
+	}
+
+	encoded["timestamp"] = timeFormat.In(e.Timestamp).Format(time.RFC3339Nano)
+	encoded["level"] = e.Level.String()
+	encoded["message"] = e.Message
+
+	line, err := json.Marshal(encoded)
+
+	if err != nil {
+		// fall back to text encoding rather than losing the message
+		return e.text(timeFormat)
+	}
+
+	return string(line)
+}
+
+func sortedKeys(fields Fields) []string {
+	keys := make([]string, 0, len(fields))
+
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Logger sends structured log entries at or above
+// its configured level to a channel for display
+// or storage by the gui or cli output device
+// Logging never blocks, if the channel is full the
+// oldest entry in the channel is dropped to make room
+type Logger struct {
+	entries chan Entry
+	level   Level
+	fields  Fields
+	// number of entries dropped, shared by all loggers
+	// derived from the same logger and accessed atomically
+	dropped *uint64
+}
+
+// New returns a new logger that sends entries with a severity
+// of at least level to the provided (buffered) channel, if the
+// channel is unbuffered entries are dropped unless a reader is
+// waiting for them
+func New(entries chan Entry, level Level) *Logger {
+	return &Logger{
+		entries: entries,
+		level:   level,
+		fields:  Fields{},
+		dropped: new(uint64),
+	}
+}
+
+// Dropped returns the number of entries dropped by the logger (or any
+// logger derived from it) because the channel was full, or unbuffered
+// with no reader waiting
+func (l *Logger) Dropped() uint64 {
+	return atomic.LoadUint64(l.dropped)
+}
+
+// With returns a copy of the logger that will include
+// the provided fields (in addition to any existing fields)
+// with every entry it logs
+func (l *Logger) With(fields Fields) *Logger {
+	merged := make(Fields, len(l.fields)+len(fields))
+
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+
+	for key, value := range fields {
+		merged[key] = value
+	}
+
+	return &Logger{
+		entries: l.entries,
+		level:   l.level,
+		fields:  merged,
+		dropped: l.dropped,
+	}
+}
+
+// Enabled returns whether entries of the given
+// level will be logged by the logger
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Log logs the message with the given level
+func (l *Logger) Log(level Level, message string) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	entry := Entry{
+		Timestamp: time.Now(),
+		Level:     level,
+		Message:   message,
+		Fields:    l.fields,
+	}
+
+	for {
+		select {
+		case l.entries <- entry:
+			return
+		default:
+		}
+
+		// there's no room to make for the entry
+		if cap(l.entries) == 0 {
+			atomic.AddUint64(l.dropped, 1)
+
+			return
+		}
+
+		// drop the oldest entry to make room, retrying the
+		// send if another logger made room or took it first
+		select {
+		case <-l.entries:
+			atomic.AddUint64(l.dropped, 1)
+		default:
+		}
+	}
+}
+
+// Debug logs a debug level message
+func (l *Logger) Debug(message string) {
+	l.Log(DebugLevel, message)
+}
+
+// Debugf logs a formatted debug level message
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	if !l.Enabled(DebugLevel) {
+		return
+	}
+
+	l.Log(DebugLevel, fmt.Sprintf(format, args...))
+}
+
+// Info logs an info level message
+func (l *Logger) Info(message string) {
+	l.Log(InfoLevel, message)
+}
+
+// Infof logs a formatted info level message
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.Log(InfoLevel, fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warn level message
+func (l *Logger) Warn(message string) {
+	l.Log(WarnLevel, message)
+}
+
+// Warnf logs a formatted warn level message
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.Log(WarnLevel, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error level message
+func (l *Logger) Error(message string) {
+	l.Log(ErrorLevel, message)
+}
+
+// Errorf logs a formatted error level message
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.Log(ErrorLevel, fmt.Sprintf(format, args...))
+}
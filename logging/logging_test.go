@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerDropsOldestEntriesWhenChannelIsFull(t *testing.T) {
+	entries := make(chan Entry, 2)
+	logger := New(entries, InfoLevel)
+
+	logger.Info("message 1")
+	logger.Info("message 2")
+	// loggers derived from the logger share its dropped count
+	logger.With(Fields{"node_id": "node"}).Info("message 3")
+
+	assert.Equal(t, uint64(1), logger.Dropped())
+	assert.Equal(t, "message 2", (<-entries).Message)
+	assert.Equal(t, "message 3", (<-entries).Message)
+}
+
+func TestLoggerDropsEntriesWithoutReaderWhenChannelIsUnbuffered(t *testing.T) {
+	logger := New(make(chan Entry), InfoLevel)
+
+	logger.Info("message 1")
+	logger.Debug("below level so not dropped")
+
+	assert.Equal(t, uint64(1), logger.Dropped())
+}
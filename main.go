@@ -2,19 +2,69 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"syscall"
+	"time"
 
-	"github.com/kava-labs/doctor/config"
+	"github.com/google/gops/agent"
+	"github.com/kava-labs/doctor/annotation"
+	"github.com/kava-labs/doctor/api"
+	"github.com/kava-labs/doctor/audit"
+	"github.com/kava-labs/doctor/backpressure"
+	"github.com/kava-labs/doctor/checks"
+	"github.com/kava-labs/doctor/clients/kava"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/control"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/event"
+	"github.com/kava-labs/doctor/fleet"
+	"github.com/kava-labs/doctor/heal"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
 	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/store"
+	"github.com/kava-labs/doctor/webhook"
+	"github.com/spf13/pflag"
 )
 
-var (
-	// default context representing the lifetime
-	// of a single invocation of the doctor program
-	ctx = context.Background()
+const (
+	// how long to wait for in progress work such as
+	// autohealing actions to complete after being
+	// signalled to shutdown before exiting anyway
+	ShutdownTimeout = 30 * time.Second
+	// max number of adjustments to the monitoring interval
+	// and autohealing thresholds waiting to be applied
+	NodeClientControlsBufferSize = 10
+)
+
+// names of the channels messages are dropped from when their
+// readers fall behind, reported as the channel dimension
+// of the DoctorDroppedMessages metric
+const (
+	SyncStatusMetricsChannel       = "sync_status_metrics"
+	UptimeMetricsChannel           = "uptime_metrics"
+	PeerConnectionMetricsChannel   = "peer_connection_metrics"
+	ServiceHealthMetricsChannel    = "service_health_metrics"
+	AccessLogMetricsChannel        = "access_log_metrics"
+	MempoolMetricsChannel          = "mempool_metrics"
+	HostMetricsChannel             = "host_metrics"
+	ConsensusStateMetricsChannel   = "consensus_state_metrics"
+	RPCMethodsMetricsChannel       = "rpc_methods_metrics"
+	WebsocketMetricsChannel        = "websocket_metrics"
+	RegionLatencyMetricsChannel    = "region_latency_metrics"
+	EndpointAddressMetricsChannel  = "endpoint_address_metrics"
+	ChainConsistencyMetricsChannel = "chain_consistency_metrics"
+	VotingPowerMetricsChannel      = "voting_power_metrics"
+	AnnotationsChannel             = "annotations"
+	IncidentEventsChannel          = "incident_events"
+	LogMessagesChannel             = "log_messages"
+	DisplayEventsChannel           = "display_events"
+	WebhookEventsChannel           = "webhook_events"
+	AlertEventsChannel             = "alert_events"
 )
 
 // MetricReadOnlyChannels is a collection
@@ -22,149 +72,956 @@ var (
 // that need to display and collect metrics
 // related to the health of a kava node
 type MetricReadOnlyChannels struct {
-	SyncStatusMetrics <-chan metric.SyncStatusMetrics
-	UptimeMetrics     <-chan metric.UptimeMetric
+	SyncStatusMetrics     <-chan metric.SyncStatusMetrics
+	UptimeMetrics         <-chan metric.UptimeMetric
+	PeerConnectionMetrics <-chan metric.PeerConnectionMetrics
+	ServiceHealthMetrics  <-chan metric.ServiceHealthMetrics
+	AccessLogMetrics      <-chan metric.AccessLogMetrics
+	MempoolMetrics        <-chan metric.MempoolMetrics
+	HostMetrics           <-chan metric.HostMetrics
+	ConsensusStateMetrics <-chan metric.ConsensusStateMetrics
+	// availability of each of the probed json rpc methods
+	RPCMethodsMetrics <-chan metric.RPCMethodsMetrics
+	// state of the websocket subscription to new block events
+	WebsocketMetrics <-chan metric.WebsocketMetrics
+	// latencies of the endpoint from each region
+	RegionLatencyMetrics <-chan metric.RegionLatencyMetrics
+	// comparisons of the node's block hashes to the reference node
+	ChainConsistencyMetrics <-chan metric.ChainConsistencyMetrics
+	// voting power of the validators signing blocks
+	VotingPowerMetrics <-chan metric.VotingPowerMetrics
+	// probes of each address backing the endpoint
+	EndpointAddressMetrics <-chan metric.EndpointAddressMetrics
+	// results of the configured health checks
+	HealthCheckResults <-chan checks.CheckResult
+	// incidents opening or closing and heal actions
+	IncidentEvents <-chan incident.Event
+	// annotations (e.g. deploys) recorded using the annotate command
+	Annotations <-chan annotation.Annotation
+	// events (e.g. health checks failing) from the event bus
+	Events <-chan event.Event
+}
+
+// Display is an output device (e.g. the gui or cli)
+// for displaying and collecting metrics related
+// to the health of a kava node
+type Display interface {
+	// Watch displays and collects metrics until the context
+	// is cancelled or the user quits the display
+	Watch(ctx context.Context, metricReadOnlyChannels MetricReadOnlyChannels, logMessages <-chan logging.Entry, kavaNodeRPCURL string) error
+	// Close flushes and closes any metric collectors used by the display
+	Close() error
+	// Endpoint returns the samples and synthetic
+	// metrics collected by the display for each node
+	Endpoint() *endpoint.Endpoint
 }
 
 func main() {
+	err := run()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		os.Exit(1)
+	}
+}
+
+// run watches the node until the user quits or sends the interrupt
+// or terminate signals, returning error (if any) if the doctor
+// failed to start or any of its routines failed while running
+func run() error {
+	// context representing the lifetime of a single invocation
+	// of the doctor program, cancelled when the user sends the
+	// interrupt or terminate signals to trigger a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// parse desired configuration
+	pflag.Usage = usage
+	config, err := dconfig.GetDoctorConfig()
+
+	if err != nil {
+		return err
+	}
+
+	// watch a fake node instead of a real node for demonstrating
+	// what the doctor does during failures
+	demoMode := len(config.Args) > 0 && config.Args[0] == DemoCommand
+
+	// check the node once instead of watching it
+	// (deprecated in favor of the check command)
+	if config.Check {
+		os.Exit(runCheck(config))
+	}
+
+	// run the requested one-off command (if
+	// any) instead of watching the node
+	if !isWatchCommand(config.Args) {
+		os.Exit(runCommand(config))
+	}
+
+	// record the process id so init systems and operators
+	// can find the daemon to signal it to reload its config
+	if config.Daemon {
+		err = writePIDFile(config.PIDFilepath)
+
+		if err != nil {
+			return fmt.Errorf("%w: could not write pid file %s", err, config.PIDFilepath)
+		}
+
+		defer os.Remove(config.PIDFilepath)
+	}
+
 	// set up channel for sending log messages
 	// from async node health watching routines to
-	// either the gui or cli output device
-	logMessages := make(chan string)
+	// either the gui or cli output device, buffered
+	// so logging never blocks monitoring routines
+	logMessages := make(chan logging.Entry, config.LogChannelBufferSize)
 
-	// set up channel for sending updated
+	// set up channels for sending updated
 	// node sync status to metric collection and display
-	// endpoints
-	syncStatusMetrics := make(chan metric.SyncStatusMetrics)
-	uptimeMetrics := make(chan metric.UptimeMetric)
+	// endpoints, buffered as metrics are sent without
+	// blocking, dropping metrics if the display falls behind
+	syncStatusMetrics := make(chan metric.SyncStatusMetrics, config.MetricChannelBufferSize)
+	uptimeMetrics := make(chan metric.UptimeMetric, config.MetricChannelBufferSize)
+	peerConnectionMetrics := make(chan metric.PeerConnectionMetrics, config.MetricChannelBufferSize)
+	serviceHealthMetrics := make(chan metric.ServiceHealthMetrics, config.MetricChannelBufferSize)
+	accessLogMetrics := make(chan metric.AccessLogMetrics, config.MetricChannelBufferSize)
+	mempoolMetrics := make(chan metric.MempoolMetrics, config.MetricChannelBufferSize)
+	hostMetrics := make(chan metric.HostMetrics, config.MetricChannelBufferSize)
+	consensusStateMetrics := make(chan metric.ConsensusStateMetrics, config.MetricChannelBufferSize)
+	rpcMethodsMetrics := make(chan metric.RPCMethodsMetrics, config.MetricChannelBufferSize)
+	websocketMetrics := make(chan metric.WebsocketMetrics, config.MetricChannelBufferSize)
+	regionLatencyMetrics := make(chan metric.RegionLatencyMetrics, config.MetricChannelBufferSize)
+	healthCheckResults := make(chan checks.CheckResult, config.MetricChannelBufferSize)
+	endpointAddressMetrics := make(chan metric.EndpointAddressMetrics, config.MetricChannelBufferSize)
+	chainConsistencyMetrics := make(chan metric.ChainConsistencyMetrics, config.MetricChannelBufferSize)
+	votingPowerMetrics := make(chan metric.VotingPowerMetrics, config.MetricChannelBufferSize)
+	annotations := make(chan annotation.Annotation, config.MetricChannelBufferSize)
+	// buffered as incident events are published without
+	// blocking, dropping events if the display falls behind
+	incidentEvents := make(chan incident.Event, config.EventChannelBufferSize)
+	// buffered as controls are sent from the gui without
+	// blocking, dropping controls if the watcher falls behind
+	nodeClientControls := make(chan NodeClientControl, NodeClientControlsBufferSize)
+	displayReloads := make(chan DisplayReload)
+	// bus for structured events (e.g. autohealing starting) that the
+	// display and alerters each subscribe to with their own buffer
+	events := event.NewBus()
+	displayEvents := events.Subscribe("display", config.EventChannelBufferSize)
+
+	// count the messages dropped on every channel
+	// for reporting as a metric by the display
+	droppedMessages := backpressure.NewCounter()
+	droppedMessages.Track(DisplayEventsChannel, displayEvents.Dropped)
 
 	// collect all metric channels together for the
 	// gui or cli functions to watch and display
 	metricReadOnlyChannels := MetricReadOnlyChannels{
-		SyncStatusMetrics: syncStatusMetrics,
-		UptimeMetrics:     uptimeMetrics,
+		SyncStatusMetrics:       syncStatusMetrics,
+		UptimeMetrics:           uptimeMetrics,
+		PeerConnectionMetrics:   peerConnectionMetrics,
+		ServiceHealthMetrics:    serviceHealthMetrics,
+		AccessLogMetrics:        accessLogMetrics,
+		MempoolMetrics:          mempoolMetrics,
+		HostMetrics:             hostMetrics,
+		ConsensusStateMetrics:   consensusStateMetrics,
+		RPCMethodsMetrics:       rpcMethodsMetrics,
+		WebsocketMetrics:        websocketMetrics,
+		RegionLatencyMetrics:    regionLatencyMetrics,
+		HealthCheckResults:      healthCheckResults,
+		EndpointAddressMetrics:  endpointAddressMetrics,
+		ChainConsistencyMetrics: chainConsistencyMetrics,
+		VotingPowerMetrics:      votingPowerMetrics,
+		IncidentEvents:          incidentEvents,
+		Annotations:             annotations,
+		Events:                  displayEvents.Events(),
 	}
 
-	// parse desired configuration
-	config, err := config.GetDoctorConfig()
+	// setup structured logger for use by all
+	// monitoring and display routines
+	logger := logging.New(logMessages, config.LogLevel)
+	droppedMessages.Track(LogMessagesChannel, logger.Dropped)
 
-	if err != nil {
-		panic(err)
+	if demoMode {
+		stopDemoNode, err := startDemoNode(config, logger)
+
+		if err != nil {
+			return err
+		}
+
+		defer stopDemoNode()
 	}
 
 	// log the initial config
-	go func() {
-		logMessages <- fmt.Sprintf("doctor parsed config %+v", config)
-	}()
+	logger.Debugf("doctor parsed config %+v", config)
+
+	// start optional diagnostics agent so operators can
+	// inspect the running doctor using the gops cli
+	// without needing to pre-plan pprof ports
+	if config.DiagnosticsAgent {
+		err = agent.Listen(agent.Options{
+			Addr: config.DiagnosticsAgentAddress,
+			// cleanup is done explicitly on shutdown
+			// instead of by the agent's own signal handler
+			// which would exit before collectors are flushed
+			ShutdownCleanup: false,
+		})
+
+		if err != nil {
+			return fmt.Errorf("%w: could not start diagnostics agent on %s", err, config.DiagnosticsAgentAddress)
+		}
+
+		defer agent.Close()
+	}
+
+	// setup optional webhook for sending metrics and
+	// incident events to arbitrary downstream systems
+	webhookClient, err := newWebhookClient(config)
+
+	if err != nil {
+		return err
+	}
+
+	// setup optional publishing of incident and heal events
+	// so downstream automation can react to them
+	incidentPublisher, err := incident.New(incident.PublisherConfig{
+		Publishers:             config.IncidentPublishers,
+		AWSRegion:              config.AWSRegion,
+		LogGroupName:           config.IncidentLogGroupName,
+		EventBusName:           config.IncidentEventBusName,
+		WebhookClient:          webhookClient,
+		Email:                  config.Email,
+		TelegramBotToken:       config.TelegramBotToken,
+		TelegramChatId:         config.TelegramChatId,
+		DiscordWebhookURL:      config.DiscordWebhookURL,
+		ExecHooks:              config.ExecHooks,
+		ExecHookTimeout:        time.Duration(config.ExecHookTimeoutSeconds) * time.Second,
+		ExecHookMaxConcurrency: config.ExecHookMaxConcurrency,
+		Schedules:              config.IncidentPublisherSchedules,
+	})
+
+	if err != nil {
+		return fmt.Errorf("%w: could not initialize incident publishers", err)
+	}
+
+	// also publish events to the display for correlating
+	// them with alerts and annotations on the timeline
+	displayIncidentPublisher := incident.NewChannelPublisher(incidentEvents)
+	droppedMessages.Track(IncidentEventsChannel, displayIncidentPublisher.Dropped)
+
+	// optionally publish alerts raised by the display (e.g. high peer
+	// churn) once as they fire, escalate and resolve, the display
+	// already shows them on the timeline
+	var alerts *incident.AlertManager
+
+	if config.PublishAlerts && len(config.IncidentPublishers) > 0 {
+		alerts = incident.NewAlertManager(incident.AlertManagerConfig{
+			Publisher:     incidentPublisher,
+			EndpointURL:   config.KavaNodeRPCURL,
+			Cooldown:      time.Duration(config.AlertCooldownSeconds) * time.Second,
+			EscalateAfter: time.Duration(config.AlertEscalationSeconds) * time.Second,
+			Logger:        logger,
+		})
+
+		droppedMessages.Track(AlertEventsChannel, alerts.Dropped)
+	}
+
+	incidentPublisher = incident.MultiPublisher{incidentPublisher, displayIncidentPublisher}
+
+	// pause autohealing while the maintenance file exists
+	// or after SIGUSR1 is received until the next SIGUSR1
+	maintenance := NewMaintenance(config.MaintenanceFilepath)
 
 	// setup client for talking to the rpc
 	// api of the node to gather application
 	// metrics such as current block height and time
 	// for the doctor to use the watch the health of the node
-	nodeConfig := NodeClientConfig{
-		RPCEndpoint:                         config.KavaNodeRPCURL,
-		DefaultMonitoringIntervalSeconds:    config.DefaultMonitoringIntervalSeconds,
-		Autoheal:                            config.Autoheal,
-		AutohealBlockchainServiceName:       config.AutohealBlockchainServiceName,
-		AutohealSyncLatencyToleranceSeconds: config.AutohealSyncLatencyToleranceSeconds,
-		AutohealSyncToLiveToleranceSeconds:  config.AutohealSyncToLiveToleranceSeconds,
-		AutohealRestartDelaySeconds:         config.AutohealRestartDelaySeconds,
-		AutohealInitialAllowedDelaySeconds:  config.AutohealInitialAllowedDelaySeconds,
-		HealthChecksTimeoutSeconds:          config.HealthChecksTimeoutSeconds,
-		NoNewBlocksRestartThresholdSeconds:  config.NoNewBlocksRestartThresholdSeconds,
-		DowntimeRestartThresholdSeconds:     config.DowntimeRestartThresholdSeconds,
-	}
-
-	nodeClient, err := NewNodeClient(nodeConfig)
+	nodeConfig := newNodeClientConfig(config)
+	nodeConfig.Maintenance = maintenance
+	nodeConfig.IncidentPublisher = incidentPublisher
+	nodeConfig.Events = events
+	nodeConfig.DroppedMessages = droppedMessages
+
+	nodeClient, err := NewNodeClient(nodeConfig, logger)
 
 	if err != nil {
-		panic(fmt.Errorf("%w: could not initialize kava client using %+v", err, nodeConfig))
+		return fmt.Errorf("%w: could not initialize kava client using %+v", err, nodeConfig)
+	}
+
+	// create the configured health checks
+	var healthChecks []checks.HealthCheck
+
+	var restClient *kava.Client
+
+	if config.RESTAPIAddress != "" {
+		restClient, err = kava.New(kava.ClientConfig{
+			JSONRPCURL:             config.RESTAPIAddress,
+			HTTPReadTimeoutSeconds: config.HealthChecksTimeoutSeconds,
+			Auth:                   config.RESTAPIAuth,
+			Transport:              config.Transport,
+		})
+
+		if err != nil {
+			return fmt.Errorf("%w: could not initialize rest api client for %s", err, config.RESTAPIAddress)
+		}
+	}
+
+	for _, schedule := range config.HealthChecks {
+		intervalSeconds := schedule.IntervalSeconds
+
+		if intervalSeconds == 0 {
+			intervalSeconds = config.DefaultMonitoringIntervalSeconds
+		}
+
+		timeoutSeconds := schedule.TimeoutSeconds
+
+		if timeoutSeconds == 0 {
+			timeoutSeconds = config.HealthChecksTimeoutSeconds
+		}
+
+		healthCheck, err := checks.New(schedule.Name, checks.Config{
+			Interval:                    time.Duration(intervalSeconds) * time.Second,
+			RPCURL:                      config.KavaNodeRPCURL,
+			KavaClient:                  nodeClient.Client,
+			SyncLatencyToleranceSeconds: config.AutohealSyncLatencyToleranceSeconds,
+			MinPeers:                    config.HealthCheckMinPeers,
+			DiskPath:                    config.NodeHome,
+			DiskMinFreePercent:          config.HealthCheckDiskMinFreePercent,
+			EVMRPCURL:                   config.EVMRPCAddress,
+			RESTClient:                  restClient,
+			QueryAccountAddress:         config.HealthCheckQueryAccount,
+			SyntheticTxCommand:          config.SyntheticTxCommand,
+			Transport:                   config.Transport,
+			Policy: checks.Policy{
+				Timeout:      time.Duration(timeoutSeconds) * time.Second,
+				Retries:      schedule.Retries,
+				RetryBackoff: time.Duration(schedule.RetryBackoffSeconds) * time.Second,
+			},
+		})
+
+		if err != nil {
+			return fmt.Errorf("%w: could not initialize %s health check", err, schedule.Name)
+		}
+
+		healthChecks = append(healthChecks, healthCheck)
 	}
 
+	// setup supervisor to own all long running routines, restarting
+	// any that crash and coordinating their shutdown
+	supervisor := NewSupervisor(ctx, SupervisorConfig{
+		Logger:                logger,
+		InitialRestartBackoff: DefaultInitialRestartBackoff,
+		MaxRestartBackoff:     DefaultMaxRestartBackoff,
+	})
+
 	// watch the node's sync status endpoint
 	// to measure it's block syncing performance
-	go nodeClient.WatchSyncStatus(ctx, syncStatusMetrics, uptimeMetrics, logMessages)
+	supervisor.Go("sync-status-watcher", func(ctx context.Context) error {
+		nodeClient.WatchSyncStatus(ctx, syncStatusMetrics, uptimeMetrics, nodeClientControls)
 
-	// setup event handlers for interactive mode
-	if config.InteractiveMode {
-		// create and draw the initial interface
-		guiConfig := GUIConfig{
-			DebugLoggingEnabled:             config.DebugMode,
-			KavaURL:                         config.KavaNodeRPCURL,
-			RefreshRateSeconds:              config.DefaultMonitoringIntervalSeconds,
-			MaxMetricSamplesToRetainPerNode: config.MaxMetricSamplesToRetainPerNode,
-			MetricSamplesForSyntheticMetricCalculation: config.MetricSamplesForSyntheticMetricCalculation,
-			MetricCollectors: config.MetricCollectors,
-			MetricNamespace:  config.MetricNamespace,
-			AWSRegion:        config.AWSRegion,
-		}
+		return nil
+	})
 
-		gui, err := NewGUI(guiConfig)
+	// watch the node's peer connections
+	// to measure how frequently it's peers churn
+	supervisor.Go("peer-connections-watcher", func(ctx context.Context) error {
+		nodeClient.WatchPeerConnections(ctx, peerConnectionMetrics)
+
+		return nil
+	})
+
+	// watch the node's mempool as transactions backing up
+	// is an early warning of node or network trouble
+	supervisor.Go("mempool-watcher", func(ctx context.Context) error {
+		nodeClient.WatchMempool(ctx, mempoolMetrics)
+
+		return nil
+	})
+
+	// watch the node's consensus state to detect consensus
+	// stuck in the same round or step, which is a distinct failure
+	// mode from a node that is merely slow to serve blocks
+	supervisor.Go("consensus-state-watcher", func(ctx context.Context) error {
+		nodeClient.WatchConsensusState(ctx, consensusStateMetrics)
+
+		return nil
+	})
+
+	// run the configured health checks (if any), each at its own interval
+	if len(healthChecks) > 0 {
+		scheduler := checks.NewScheduler(healthChecks...)
+		scheduler.Events = events
+		scheduler.Dropped = droppedMessages
+
+		supervisor.Go("health-check-scheduler", func(ctx context.Context) error {
+			scheduler.Run(ctx, healthCheckResults)
+
+			return nil
+		})
+	}
+
+	supervisor.Go("maintenance-watcher", func(ctx context.Context) error {
+		maintenance.Watch(ctx, time.Duration(config.DefaultMonitoringIntervalSeconds)*time.Second, logger)
+
+		return nil
+	})
+
+	// resume autohealing stopped by the restart caps on SIGUSR2, also
+	// when not autohealing so the signal doesn't terminate the doctor
+	supervisor.Go("autoheal-reset-watcher", func(ctx context.Context) error {
+		watchAutohealResets(ctx, nodeClient, logger)
+
+		return nil
+	})
+
+	// watch for the node halting for scheduled upgrades when
+	// autohealing to avoid restarting the node mid upgrade
+	if config.Autoheal && (config.UpgradeAPIAddress != "" || config.UpgradeHeight > 0) {
+		supervisor.Go("upgrade-watcher", func(ctx context.Context) error {
+			nodeClient.WatchUpgrades(ctx)
+
+			return nil
+		})
+	}
+
+	// compare the node's block hashes to the reference node (if
+	// any) to detect the node being on a fork or having corrupt state
+	if config.ReferenceAPIAddress != "" && config.ChainConsistencyCheckIntervalSeconds > 0 {
+		supervisor.Go("chain-consistency-watcher", func(ctx context.Context) error {
+			nodeClient.WatchChainConsistency(ctx, chainConsistencyMetrics)
+
+			return nil
+		})
+	}
+
+	// compare the voting power signing blocks to the validator set
+	// to tell the chain halting apart from the node failing
+	if config.VotingPowerCheckIntervalSeconds > 0 {
+		supervisor.Go("voting-power-watcher", func(ctx context.Context) error {
+			nodeClient.WatchVotingPower(ctx, votingPowerMetrics)
+
+			return nil
+		})
+	}
+
+	// probe each address backing the endpoint individually
+	// to observe every node behind a load balanced endpoint
+	if config.ProbeEndpointAddresses {
+		supervisor.Go("endpoint-addresses-watcher", func(ctx context.Context) error {
+			nodeClient.WatchEndpointAddresses(ctx, endpointAddressMetrics)
+
+			return nil
+		})
+	}
+
+	// watch the blockchain's systemd service when autohealing
+	// to avoid stacking restarts on top of systemd's own restarts
+	if config.Autoheal && config.AutohealServiceManager == heal.SystemdServiceManager {
+		supervisor.Go("service-health-watcher", func(ctx context.Context) error {
+			nodeClient.WatchServiceHealth(ctx, serviceHealthMetrics)
+
+			return nil
+		})
+	}
+
+	// watch the node's access log (if any) to observe error
+	// rates and latencies of real client traffic, which synthetic
+	// checks miss for errors specific to certain query shapes
+	if config.AccessLogFilepath != "" {
+		supervisor.Go("access-log-watcher", func(ctx context.Context) error {
+			return nodeClient.WatchAccessLog(ctx, accessLogMetrics)
+		})
+	}
+
+	// sample the load, memory usage and network and disk io
+	// of the host as the node's health often hinges on them
+	if config.HostMetrics {
+		supervisor.Go("host-metrics-watcher", func(ctx context.Context) error {
+			nodeClient.WatchHostMetrics(ctx, hostMetrics)
+
+			return nil
+		})
+	}
+
+	// call each of the json rpc methods to probe as the node
+	// can serve some methods while others (e.g. tx_search) fail
+	if len(config.RPCProbeMethods) > 0 {
+		supervisor.Go("rpc-methods-watcher", func(ctx context.Context) error {
+			nodeClient.WatchRPCMethods(ctx, rpcMethodsMetrics)
+
+			return nil
+		})
+	}
+
+	// compare the latency of the endpoint from each region
+	// to tell slowness of the node from slowness of a region
+	if len(config.VantageEndpoints) > 0 {
+		supervisor.Go("region-latency-watcher", func(ctx context.Context) error {
+			nodeClient.WatchRegionLatency(ctx, regionLatencyMetrics)
+
+			return nil
+		})
+	}
+
+	// subscribe to new block events over the websocket interface
+	// as it can fail independently of the json rpc api
+	if config.WebsocketMonitoring {
+		supervisor.Go("websocket-watcher", func(ctx context.Context) error {
+			nodeClient.WatchWebsocket(ctx, websocketMetrics)
+
+			return nil
+		})
+	}
+
+	// watch the annotations file (if any) for annotations
+	// (e.g. deploys) to show on the timeline
+	if config.AnnotationsFilepath != "" {
+		supervisor.Go("annotations-watcher", func(ctx context.Context) error {
+			return nodeClient.WatchAnnotations(ctx, annotations)
+		})
+	}
+
+	// setup optional signing of collected metrics
+	// to allow for verifying they haven't been tampered with
+	var metricSigner audit.Signer
+
+	if config.MetricSigningAlgorithm != "" {
+		metricSigner, err = audit.NewSignerFromKeyFile(config.MetricSigningAlgorithm, config.MetricSigningKeyFilepath)
 
 		if err != nil {
-			panic(fmt.Errorf("error %s attempting to start interactive mode ", err))
+			return fmt.Errorf("%w: could not initialize metric signer", err)
 		}
+	}
 
-		// display new node health measurements as
-		// they are received and evaluated
-		// and allow the user to interactively
-		// adjust the display and measurement
-		err = gui.Watch(metricReadOnlyChannels, logMessages, config.KavaNodeRPCURL)
+	// setup optional persistence of metric samples
+	// so history survives restarts of the doctor
+	var sampleStore store.Store
+
+	if config.SampleStoreBackend != "" {
+		sampleStore, err = store.New(store.StoreConfig{
+			Backend:   config.SampleStoreBackend,
+			Path:      config.SampleStoreFilepath,
+			Retention: time.Duration(config.SampleStoreRetentionHours) * time.Hour,
+		})
 
 		if err != nil {
-			panic(fmt.Errorf("error %s attempting to watch node in interactive mode ", err))
+			return fmt.Errorf("%w: could not open sample store at %s", err, config.SampleStoreFilepath)
 		}
-	} else {
-		// setup plaintext or file cli interface
-		cliConfig := CLIConfig{
-			Logger:                          config.Logger,
-			KavaURL:                         config.KavaNodeRPCURL,
-			MaxMetricSamplesToRetainPerNode: config.MaxMetricSamplesToRetainPerNode,
-			MetricSamplesForSyntheticMetricCalculation: config.MetricSamplesForSyntheticMetricCalculation,
-			MetricCollectors: config.MetricCollectors,
-			MetricNamespace:  config.MetricNamespace,
-			AWSRegion:        config.AWSRegion,
+
+		defer sampleStore.Close()
+	}
+
+	// stream metrics as they're collected to control api clients
+	var metricStream *control.MetricStream
+
+	if config.ControlServerAddress != "" {
+		metricStream = control.NewMetricStream()
+	}
+
+	metricCollectorsConfig := NewMetricCollectorsConfig(config, metricSigner, webhookClient)
+	metricCollectorsConfig.Maintenance = maintenance
+	metricCollectorsConfig.MetricStream = metricStream
+	metricCollectorsConfig.Ctx = ctx
+
+	configWarnings := config.Warnings()
+
+	var display Display
+
+	// setup event handlers for interactive mode, daemons
+	// always run non-interactively as there's no user to interact with
+	if config.InteractiveMode && !config.Daemon {
+		// create and draw the initial interface
+		guiConfig := newGUIConfig(config)
+		guiConfig.MetricCollectorsConfig = metricCollectorsConfig
+		guiConfig.Logger = logger
+		guiConfig.SampleStore = sampleStore
+		guiConfig.ConfigWarnings = configWarnings
+		guiConfig.NodeClientControls = nodeClientControls
+		guiConfig.DroppedMessages = droppedMessages
+		guiConfig.Alerts = alerts
+
+		gui, err := NewGUI(guiConfig)
+
+		switch {
+		case errors.Is(err, ErrTerminalUnavailable):
+			// fallback to the cli instead of crashing
+			// e.g. when running in a container without a tty
+			logger.Warnf("%s, falling back to non-interactive mode", err)
+		case err != nil:
+			return fmt.Errorf("%w: could not start interactive mode", err)
+		default:
+			display = gui
 		}
+	}
+
+	// setup plaintext or file cli interface if non-interactive
+	// mode was requested or the terminal is unavailable
+	if display == nil {
+		cliConfig := newCLIConfig(config)
+		cliConfig.Logger = logger
+		cliConfig.LogOutput = os.Stdout
+		cliConfig.MetricCollectorsConfig = metricCollectorsConfig
+		cliConfig.SampleStore = sampleStore
+		cliConfig.ConfigWarnings = configWarnings
+		cliConfig.Reloads = displayReloads
+		cliConfig.DroppedMessages = droppedMessages
+		cliConfig.Alerts = alerts
 
 		cli, err := NewCLI(cliConfig)
 
 		if err != nil {
-			panic(fmt.Errorf("error %s attempting to start non-interactive mode ", err))
+			return fmt.Errorf("%w: could not start non-interactive mode", err)
+		}
+
+		display = cli
+	}
+
+	// serve the samples and synthetic metrics collected by the
+	// display so other tooling doesn't have to recompute them
+	if config.StatusServerAddress != "" {
+		statusServer, err := api.NewServer(api.ServerConfig{
+			Address:         config.StatusServerAddress,
+			TLSCertFilepath: config.StatusServerTLSCertFilepath,
+			TLSKeyFilepath:  config.StatusServerTLSKeyFilepath,
+			AuthToken:       config.StatusServerAuthToken,
+			Endpoint:        display.Endpoint(),
+		})
+
+		if err != nil {
+			return fmt.Errorf("%w: could not start status server", err)
 		}
 
-		errChan := make(chan error, 1)
+		supervisor.Go("status-server", statusServer.Serve)
+	}
 
-		// display new node health measurements as
-		// they are received and evaluated to the cli
-		// output device (stdout or file)
-		go func() {
-			defer close(errChan)
+	// push the latest samples collected by the display to the
+	// fleet aggregator (if any) so they're summarized with the
+	// rest of the fleet
+	if config.FleetAggregatorURL != "" {
+		fleetAgent, err := fleet.NewAgent(fleet.AgentConfig{
+			AgentId:       config.FleetAgentId,
+			AggregatorURL: config.FleetAggregatorURL,
+			AuthToken:     config.FleetAuthToken,
+			PushInterval:  time.Duration(config.FleetPushIntervalSeconds) * time.Second,
+			Endpoint:      display.Endpoint(),
+			Logger:        logger,
+		})
 
-			err = cli.Watch(metricReadOnlyChannels, logMessages, config.KavaNodeRPCURL)
+		if err != nil {
+			return fmt.Errorf("%w: could not start fleet agent", err)
+		}
 
-			if err != nil {
-				errChan <- fmt.Errorf("error %s attempting to watch node in non-interactive mode ", err)
-			}
-		}()
+		supervisor.Go("fleet-agent", fleetAgent.Run)
+	}
 
-		// setup handling of os signals such as Ctrl ^C
-		signals := make(chan os.Signal, 2)
-		defer close(signals)
+	// serve the control api so deployment tooling can pause
+	// autohealing (e.g. before a deploy) or heal the node
+	// without shell access to the host
+	if config.ControlServerAddress != "" {
+		controlServer, err := control.NewServer(control.ServerConfig{
+			Address:             config.ControlServerAddress,
+			TLSCertFilepath:     config.ControlTLSCertFilepath,
+			TLSKeyFilepath:      config.ControlTLSKeyFilepath,
+			TLSClientCAFilepath: config.ControlTLSClientCAFilepath,
+			Endpoint:            display.Endpoint(),
+			MetricStream:        metricStream,
+			Maintenance:         maintenance,
+			HealStrategies:      controlHealStrategies(config),
+			// requested heals are subject to the same interlocks
+			// (e.g. maintenance mode) and restart caps as autoheals
+			Blocked:  nodeClient.healBlocked,
+			HealLock: nodeClient.healLock,
+			Heal: func(ctx context.Context, strategyName string) error {
+				if strategyName == RestartHealAction {
+					strategyName = heal.RestartServiceStrategyName
+				}
+
+				strategy, err := newHealStrategy(strategyName, config, nodeClient.healBlocked, nodeClient.restartBreaker)
 
-		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
-		// keep running the doctor program until the
-		// watch is finished or the user sends the interrupt or stop
-		// signals in the tty
-		for {
-			select {
-			case <-signals:
-				os.Exit(0)
-			case err = <-errChan:
 				if err != nil {
-					panic(errChan)
+					return err
 				}
-				os.Exit(0)
-			}
+
+				return strategy.Heal(ctx, logger, nodeClient.Client, heal.HealerConfig{
+					AutohealSyncToLiveToleranceSeconds: config.AutohealSyncToLiveToleranceSeconds,
+					EndpointURL:                        config.KavaNodeRPCURL,
+					MinHealthyInServiceInstances:       config.AutohealStandbyMinHealthyInstances,
+					IncidentPublisher:                  incidentPublisher,
+				})
+			},
+			Logger: logger,
+		})
+
+		if err != nil {
+			return fmt.Errorf("%w: could not start control api", err)
 		}
+
+		supervisor.Go("control-server", controlServer.Serve)
+	}
+
+	// reload the config file on SIGHUP (or when it changes) so config
+	// changes don't require a restart that loses all in-memory samples
+	_, nonInteractive := display.(*CLI)
+
+	watchingConfigFile := config.WatchConfigFile && config.ConfigFilepath != ""
+
+	if watchingConfigFile && !nonInteractive {
+		logger.Warnf("%s is only supported in non-interactive mode, restart the doctor for config changes to take effect", dconfig.WatchConfigFileFlagName)
+
+		watchingConfigFile = false
+	}
+
+	var configFileChanges chan struct{}
+
+	if watchingConfigFile {
+		configFileChanges = make(chan struct{}, 1)
+
+		supervisor.Go("config-file-watcher", func(ctx context.Context) error {
+			return watchConfigFile(ctx, config.ConfigFilepath, configFileChanges)
+		})
+	}
+
+	if config.Daemon || watchingConfigFile {
+		supervisor.Go("config-reloader", func(ctx context.Context) error {
+			return watchConfigReloads(ctx, ConfigReloaderConfig{
+				ReloadOnHangup:     config.Daemon,
+				ConfigFileChanges:  configFileChanges,
+				InitialConfig:      config,
+				MetricSigner:       metricSigner,
+				WebhookClient:      webhookClient,
+				Maintenance:        maintenance,
+				MetricStream:       metricStream,
+				NodeClientControls: nodeClientControls,
+				DisplayReloads:     displayReloads,
+				Events:             events,
+				Logger:             logger,
+			})
+		})
+	}
+
+	// alert on events (e.g. autohealing starting) as they happen
+	// instead of waiting for metrics to cross alarm thresholds
+	if webhookClient != nil {
+		webhookEvents := events.Subscribe("webhook", config.EventChannelBufferSize)
+		droppedMessages.Track(WebhookEventsChannel, webhookEvents.Dropped)
+
+		supervisor.Go("event-webhook-alerter", func(ctx context.Context) error {
+			alertEventsToWebhook(ctx, webhookEvents, webhookClient, logger)
+
+			return nil
+		})
+	}
+
+	events.Publish(event.Event{
+		Type:    event.ConfigLoadedType,
+		Source:  config.ConfigFilepath,
+		Message: "loaded config",
+	})
+
+	// display new node health measurements as
+	// they are received and evaluated until the
+	// user quits or sends the interrupt or stop signals,
+	// signalling all supervised routines to shutdown once it returns
+	supervisor.Run("display", func(ctx context.Context) error {
+		return display.Watch(ctx, metricReadOnlyChannels, logMessages, config.KavaNodeRPCURL)
+	})
+
+	return shutdown(supervisor, display)
+}
+
+// shutdown waits (up to ShutdownTimeout) for all supervised
+// routines to finish any in progress work before flushing
+// and closing all metric collectors used by the display,
+// returning the errors (if any) of all supervised routines
+func shutdown(supervisor *Supervisor, display Display) error {
+	supervisorErr := supervisor.Wait(ShutdownTimeout)
+
+	err := display.Close()
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error %s closing metric collectors\n", err)
+	}
+
+	return supervisorErr
+}
+
+// newWebhookClient returns a client for the webhook configured
+// for sending metrics and incident events to arbitrary downstream
+// systems, nil if no webhook is configured, and error (if any)
+func newWebhookClient(config *dconfig.DoctorConfig) (*webhook.Client, error) {
+	if config.WebhookURL == "" {
+		return nil, nil
+	}
+
+	var webhookSigner audit.Signer
+
+	if config.WebhookSigningKeyFilepath != "" {
+		var err error
+
+		webhookSigner, err = audit.NewSignerFromKeyFile(audit.HMACSHA256Algorithm, config.WebhookSigningKeyFilepath)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not initialize webhook signer", err)
+		}
+	}
+
+	return webhook.New(webhook.ClientConfig{
+		URL:        config.WebhookURL,
+		Signer:     webhookSigner,
+		MaxRetries: config.WebhookMaxRetries,
+	}), nil
+}
+
+// newNodeClientConfig returns the config for watching (and autohealing)
+// the node as requested in the doctor config, the maintenance switch and
+// incident publisher are created at runtime and set by the caller
+func newNodeClientConfig(config *dconfig.DoctorConfig) NodeClientConfig {
+	return NodeClientConfig{
+		RPCEndpoint:                            config.KavaNodeRPCURL,
+		DefaultMonitoringIntervalSeconds:       config.DefaultMonitoringIntervalSeconds,
+		Autoheal:                               config.Autoheal,
+		AutohealBlockchainServiceName:          config.AutohealBlockchainServiceName,
+		AutohealSyncLatencyToleranceSeconds:    config.AutohealSyncLatencyToleranceSeconds,
+		AutohealSyncToLiveToleranceSeconds:     config.AutohealSyncToLiveToleranceSeconds,
+		AutohealRestartDelaySeconds:            config.AutohealRestartDelaySeconds,
+		AutohealInitialAllowedDelaySeconds:     config.AutohealInitialAllowedDelaySeconds,
+		HealthChecksTimeoutSeconds:             config.HealthChecksTimeoutSeconds,
+		RPCMaxRetries:                          config.RPCMaxRetries,
+		RPCRetryBackoffMilliseconds:            config.RPCRetryBackoffMilliseconds,
+		RPCAuth:                                config.KavaAPIAuth,
+		ReferenceRPCAuth:                       config.ReferenceAPIAuth,
+		UpgradeAPIAuth:                         config.UpgradeAPIAuth,
+		Transport:                              config.Transport,
+		Status:                                 config.Status,
+		UptimeProbes:                           config.UptimeProbes,
+		NoNewBlocksRestartThresholdSeconds:     config.NoNewBlocksRestartThresholdSeconds,
+		DowntimeRestartThresholdSeconds:        config.DowntimeRestartThresholdSeconds,
+		StaleResponseBehavior:                  config.StaleResponseBehavior,
+		ReferenceRPCEndpoint:                   config.ReferenceAPIAddress,
+		AutohealBlocksBehindReferenceTolerance: config.AutohealBlocksBehindReferenceTolerance,
+		AccessLogFilepath:                      config.AccessLogFilepath,
+		AccessLogFormat:                        config.AccessLogFormat,
+		AnnotationsFilepath:                    config.AnnotationsFilepath,
+		HostProcPath:                           config.HostProcPath,
+		RPCProbeMethods:                        config.RPCProbeMethods,
+		PushNewBlocks:                          config.PushNewBlocks,
+		VantageEndpoints:                       config.VantageEndpoints,
+		Region:                                 config.Region,
+		MempoolSizeAlertThreshold:              config.MempoolSizeAlertThreshold,
+		MempoolSizeAlertDurationSeconds:        config.MempoolSizeAlertDurationSeconds,
+		ConsensusFrozenThresholdSeconds:        config.ConsensusFrozenThresholdSeconds,
+		AutohealFrozenConsensus:                config.AutohealFrozenConsensus,
+		AnomalyZScoreThreshold:                 config.AnomalyZScoreThreshold,
+		AnomalyBaselineSamples:                 config.AnomalyBaselineSamples,
+		AnomalyBaselinesFilepath:               config.AnomalyBaselinesFilepath,
+		PeerMinRecvBytesPerSecond:              config.PeerMinRecvBytesPerSecond,
+		AutohealStrategies:                     config.AutohealStrategies,
+		AutohealEscalationDelaySeconds:         config.AutohealEscalationDelaySeconds,
+		AutohealStandbyMinHealthyInstances:     config.AutohealStandbyMinHealthyInstances,
+		ExpectedNodeId:                         config.ExpectedNodeId,
+		ExpectedNodeMoniker:                    config.ExpectedNodeMoniker,
+		ExpectedChainId:                        config.ChainId,
+		ChainConsistencyCheckIntervalSeconds:   config.ChainConsistencyCheckIntervalSeconds,
+		VotingPowerCheckIntervalSeconds:        config.VotingPowerCheckIntervalSeconds,
+		ChainDegradedVotingPowerPercent:        config.ChainDegradedVotingPowerPercent,
+		UpgradeAPIAddress:                      config.UpgradeAPIAddress,
+		UpgradeHeight:                          config.UpgradeHeight,
+		UpgradeWindowSeconds:                   config.UpgradeWindowSeconds,
+		AutohealMaxRestartsPerHour:             config.AutohealMaxRestartsPerHour,
+		AutohealMaxRestartsPerDay:              config.AutohealMaxRestartsPerDay,
+		NodeHome:                               config.NodeHome,
+		AutohealSnapshotURL:                    config.AutohealSnapshotURL,
+		AutohealRestoreStateThresholdSeconds:   config.AutohealRestoreStateThresholdSeconds,
+		AutohealStateSyncThresholdSeconds:      config.AutohealStateSyncThresholdSeconds,
+		AutohealReplaceMethod:                  config.AutohealReplaceMethod,
+		AutohealReplaceMinHealthyInstances:     config.AutohealReplaceMinHealthyInstances,
+		AutohealReplaceLifecycleHookName:       config.AutohealReplaceLifecycleHookName,
+		AutohealTargetGroupARNs:                config.AutohealTargetGroupARNs,
+		AutohealRoute53HostedZoneId:            config.AutohealRoute53HostedZoneId,
+		AutohealRoute53RecordName:              config.AutohealRoute53RecordName,
+		AutohealRoute53RecordType:              config.AutohealRoute53RecordType,
+		AutohealRoute53SetIdentifier:           config.AutohealRoute53SetIdentifier,
+		AutohealExecHookCommand:                config.AutohealExecHookCommand,
+		AutohealRefreshPeers:                   config.AutohealRefreshPeers,
+		AutohealRefreshSeeds:                   config.AutohealRefreshSeeds,
+		ExecHookTimeoutSeconds:                 config.ExecHookTimeoutSeconds,
+		AutohealServiceManager:                 config.AutohealServiceManager,
+		AutohealRestartCommand:                 config.AutohealRestartCommand,
+		AutohealPreRestartCommands:             config.AutohealPreRestartCommands,
+		AutohealRestartVerificationSeconds:     config.AutohealRestartVerificationSeconds,
+		AutohealServiceSudo:                    config.AutohealServiceSudo,
+		AutohealServiceHelperSocket:            config.AutohealServiceHelperSocket,
+		TimeFormat:                             config.TimeFormat,
+	}
+}
+
+// newGUIConfig returns the config for the interactive display as requested
+// in the doctor config, values created at runtime (e.g. the sample store)
+// are set by the caller
+func newGUIConfig(config *dconfig.DoctorConfig) GUIConfig {
+	return GUIConfig{
+		DebugLoggingEnabled:             config.DebugMode,
+		KavaURL:                         config.KavaNodeRPCURL,
+		RefreshRateSeconds:              config.DefaultMonitoringIntervalSeconds,
+		MaxMetricSamplesToRetainPerNode: config.MaxMetricSamplesToRetainPerNode,
+		MetricSamplesForSyntheticMetricCalculation: config.MetricSamplesForSyntheticMetricCalculation,
+		MaxChartPointsPerSeries:                    config.MaxChartPointsPerSeries,
+		MaxMessagesToRetain:                        config.MaxMessagesToRetain,
+		TimeFormat:                                 config.TimeFormat,
+		PeerChurnAlertThresholdPerMinute:           config.PeerChurnAlertThresholdPerMinute,
+		BlockIntervalP95AlertThresholdSeconds:      config.BlockIntervalP95AlertThresholdSeconds,
+		DiscardStaleSamples:                        config.StaleResponseBehavior == dconfig.StaleResponseBehaviorDiscard,
+		NodeGroups:                                 config.NodeGroups,
+		NodeGroupSyncLatencyToleranceSeconds:       config.AutohealSyncLatencyToleranceSeconds,
+		UptimeWindows:                              uptimeWindows(config),
+		UptimeEWMAHalfLife:                         time.Duration(config.UptimeEWMAHalfLifeSeconds) * time.Second,
+		SLOs:                                       config.SLOs,
+		NodeClientControl: NodeClientControl{
+			MonitoringIntervalSeconds:           config.DefaultMonitoringIntervalSeconds,
+			AutohealSyncLatencyToleranceSeconds: config.AutohealSyncLatencyToleranceSeconds,
+			DowntimeRestartThresholdSeconds:     config.DowntimeRestartThresholdSeconds,
+			NoNewBlocksRestartThresholdSeconds:  config.NoNewBlocksRestartThresholdSeconds,
+			AutohealRestartDelaySeconds:         config.AutohealRestartDelaySeconds,
+		},
+	}
+}
+
+// uptimeWindows returns the enabled windows to calculate
+// the uptime of the endpoint over, from shortest to longest
+func uptimeWindows(config *dconfig.DoctorConfig) []time.Duration {
+	var windows []time.Duration
+
+	for _, windowSeconds := range []int{config.UptimeShortWindowSeconds, config.UptimeMediumWindowSeconds, config.UptimeLongWindowSeconds} {
+		if windowSeconds > 0 {
+			windows = append(windows, time.Duration(windowSeconds)*time.Second)
+		}
+	}
+
+	sort.Slice(windows, func(i, j int) bool {
+		return windows[i] < windows[j]
+	})
+
+	return windows
+}
+
+// newCLIConfig returns the config for the non-interactive display as
+// requested in the doctor config, values created at runtime (e.g. the
+// sample store) are set by the caller
+func newCLIConfig(config *dconfig.DoctorConfig) CLIConfig {
+	return CLIConfig{
+		LogFormat:                       config.LogFormat,
+		TimeFormat:                      config.TimeFormat,
+		KavaURL:                         config.KavaNodeRPCURL,
+		MaxMetricSamplesToRetainPerNode: config.MaxMetricSamplesToRetainPerNode,
+		MetricSamplesForSyntheticMetricCalculation: config.MetricSamplesForSyntheticMetricCalculation,
+		PeerChurnAlertThresholdPerMinute:           config.PeerChurnAlertThresholdPerMinute,
+		BlockIntervalP95AlertThresholdSeconds:      config.BlockIntervalP95AlertThresholdSeconds,
+		DiscardStaleSamples:                        config.StaleResponseBehavior == dconfig.StaleResponseBehaviorDiscard,
+		NodeGroups:                                 config.NodeGroups,
+		NodeGroupSyncLatencyToleranceSeconds:       config.AutohealSyncLatencyToleranceSeconds,
+		UptimeWindows:                              uptimeWindows(config),
+		UptimeEWMAHalfLife:                         time.Duration(config.UptimeEWMAHalfLifeSeconds) * time.Second,
+		SLOs:                                       config.SLOs,
 	}
 }
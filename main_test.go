@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// fillNonZero sets every field (recursively) of the
+// value to a non zero value of the field's type
+func fillNonZero(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if value.Field(i).CanSet() {
+				fillNonZero(value.Field(i))
+			}
+		}
+	case reflect.String:
+		value.SetString("set")
+	case reflect.Bool:
+		value.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		value.SetFloat(1)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(value.Type(), 1, 1)
+		fillNonZero(slice.Index(0))
+		value.Set(slice)
+	case reflect.Map:
+		entries := reflect.MakeMap(value.Type())
+		key := reflect.New(value.Type().Key()).Elem()
+		entry := reflect.New(value.Type().Elem()).Elem()
+		fillNonZero(key)
+		fillNonZero(entry)
+		entries.SetMapIndex(key, entry)
+		value.Set(entries)
+	case reflect.Ptr:
+		pointer := reflect.New(value.Type().Elem())
+		fillNonZero(pointer.Elem())
+		value.Set(pointer)
+	}
+}
+
+// newFilledDoctorConfig returns a doctor config
+// with every field set to a non zero value
+func newFilledDoctorConfig() *dconfig.DoctorConfig {
+	config := &dconfig.DoctorConfig{}
+
+	fillNonZero(reflect.ValueOf(config).Elem())
+
+	// fields derived from comparisons need specific values
+	config.StaleResponseBehavior = dconfig.StaleResponseBehaviorDiscard
+
+	return config
+}
+
+// assertFieldsSet asserts every field of the config struct is
+// set except the fields set at runtime instead of from config
+func assertFieldsSet(t *testing.T, config interface{}, runtimeFields ...string) {
+	value := reflect.ValueOf(config)
+
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+
+		if contains(runtimeFields, field.Name) {
+			continue
+		}
+
+		assert.False(t, value.Field(i).IsZero(), "%s.%s isn't set from the doctor config", value.Type().Name(), field.Name)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestNewNodeClientConfigSetsEveryField(t *testing.T) {
+	nodeConfig := newNodeClientConfig(newFilledDoctorConfig())
+
+	assertFieldsSet(t, nodeConfig, "Maintenance", "IncidentPublisher", "Events", "DroppedMessages", "Healer")
+}
+
+func TestNewGUIConfigSetsEveryField(t *testing.T) {
+	guiConfig := newGUIConfig(newFilledDoctorConfig())
+
+	assertFieldsSet(t, guiConfig, "MetricCollectorsConfig", "SampleStore", "Logger", "ConfigWarnings", "NodeClientControls", "DroppedMessages", "Alerts")
+	assertFieldsSet(t, guiConfig.NodeClientControl)
+}
+
+func TestNewCLIConfigSetsEveryField(t *testing.T) {
+	cliConfig := newCLIConfig(newFilledDoctorConfig())
+
+	assertFieldsSet(t, cliConfig, "MetricCollectorsConfig", "SampleStore", "Logger", "LogOutput", "ProgressOutput", "ConfigWarnings", "Reloads", "DroppedMessages", "Alerts")
+}
+
+func TestNewMetricCollectorsConfigSetsEveryField(t *testing.T) {
+	metricCollectorsConfig := NewMetricCollectorsConfig(newFilledDoctorConfig(), nil, nil)
+
+	assertFieldsSet(t, metricCollectorsConfig, "MetricSigner", "WebhookClient", "Maintenance", "MetricStream", "Ctx")
+}
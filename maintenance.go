@@ -0,0 +1,146 @@
+// maintenance.go contains types and functions for pausing
+// autohealing while operators do planned work on the node,
+// without stopping the doctor and losing monitoring
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	// dimension added to metrics collected in maintenance mode
+	MaintenanceDimension = "maintenance"
+)
+
+// Maintenance tracks whether the doctor is in maintenance mode,
+// which is on while the maintenance file exists or after SIGUSR1
+// is received until the next SIGUSR1
+// Maintenance is safe to use across go-routines
+type Maintenance struct {
+	filepath string
+	// set to 1 while the maintenance file
+	// exists, accessed atomically
+	fileExists int32
+	// toggled between 0 and 1 on each
+	// SIGUSR1, accessed atomically
+	signalled int32
+}
+
+// NewMaintenance returns a new maintenance mode switch that
+// is on while the file at filepath (if set) exists
+func NewMaintenance(filepath string) *Maintenance {
+	maintenance := &Maintenance{
+		filepath: filepath,
+	}
+
+	maintenance.checkFile()
+
+	return maintenance
+}
+
+// Enabled returns whether the doctor is in maintenance mode
+func (m *Maintenance) Enabled() bool {
+	return atomic.LoadInt32(&m.fileExists) == 1 || atomic.LoadInt32(&m.signalled) == 1
+}
+
+// Toggle toggles maintenance mode as if SIGUSR1 was
+// received, returning whether maintenance mode is enabled
+func (m *Maintenance) Toggle() bool {
+	for {
+		signalled := atomic.LoadInt32(&m.signalled)
+
+		if atomic.CompareAndSwapInt32(&m.signalled, signalled, 1-signalled) {
+			return m.Enabled()
+		}
+	}
+}
+
+// Set turns maintenance mode on or off as if SIGUSR1 was received
+// while it was off or on, returning whether maintenance mode is
+// enabled, which remains on while the maintenance file exists
+func (m *Maintenance) Set(enabled bool) bool {
+	var signalled int32
+
+	if enabled {
+		signalled = 1
+	}
+
+	atomic.StoreInt32(&m.signalled, signalled)
+
+	return m.Enabled()
+}
+
+// Dimensions returns the dimensions to add to metrics
+// collected while in maintenance mode, nil otherwise
+func (m *Maintenance) Dimensions() metric.MetricDimensions {
+	if !m.Enabled() {
+		return nil
+	}
+
+	return metric.MetricDimensions{
+		MaintenanceDimension: "true",
+	}
+}
+
+// checkFile updates whether the maintenance file exists
+func (m *Maintenance) checkFile() {
+	var fileExists int32
+
+	if m.filepath != "" {
+		if _, err := os.Stat(m.filepath); err == nil {
+			fileExists = 1
+		}
+	}
+
+	atomic.StoreInt32(&m.fileExists, fileExists)
+}
+
+// Watch toggles maintenance mode each time SIGUSR1 is received and
+// checks for the maintenance file every interval, logging each time
+// maintenance mode is turned on or off, until the context is cancelled
+func (m *Maintenance) Watch(ctx context.Context, interval time.Duration, logger *logging.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	enabled := m.Enabled()
+
+	if enabled {
+		logger.Warnf("maintenance mode is on, autohealing is paused")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			m.Toggle()
+		case <-ticker.C:
+			m.checkFile()
+		}
+
+		if m.Enabled() == enabled {
+			continue
+		}
+
+		enabled = m.Enabled()
+
+		if enabled {
+			logger.Warnf("maintenance mode turned on, autohealing is paused")
+		} else {
+			logger.Infof("maintenance mode turned off, autohealing resumed")
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceEnabledWhileFileExistsOrToggled(t *testing.T) {
+	maintenanceFilepath := filepath.Join(t.TempDir(), "maintenance")
+
+	maintenance := NewMaintenance(maintenanceFilepath)
+
+	assert.False(t, maintenance.Enabled())
+	assert.Nil(t, maintenance.Dimensions())
+
+	err := os.WriteFile(maintenanceFilepath, nil, 0644)
+	assert.Nil(t, err)
+
+	maintenance.checkFile()
+
+	assert.True(t, maintenance.Enabled())
+	assert.Equal(t, "true", maintenance.Dimensions()[MaintenanceDimension])
+
+	err = os.Remove(maintenanceFilepath)
+	assert.Nil(t, err)
+
+	maintenance.checkFile()
+
+	assert.False(t, maintenance.Enabled())
+
+	assert.True(t, maintenance.Toggle())
+	assert.False(t, maintenance.Toggle())
+}
+
+func TestMaintenanceSetRemainsOnWhileFileExists(t *testing.T) {
+	maintenanceFilepath := filepath.Join(t.TempDir(), "maintenance")
+
+	maintenance := NewMaintenance(maintenanceFilepath)
+
+	assert.True(t, maintenance.Set(true))
+	assert.True(t, maintenance.Set(true), "setting maintenance mode should be idempotent")
+	assert.False(t, maintenance.Set(false))
+
+	err := os.WriteFile(maintenanceFilepath, nil, 0644)
+	assert.Nil(t, err)
+
+	maintenance.checkFile()
+
+	assert.True(t, maintenance.Set(false))
+}
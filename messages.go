@@ -0,0 +1,167 @@
+// messages.go contains the gui widget for displaying a scrollable
+// log of the most recent messages colored by their severity
+
+package main
+
+import (
+	"image"
+	"strings"
+
+	ui "github.com/gizak/termui/v3"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/logging"
+)
+
+// MessageLogWidget implements the termui Drawable interface
+// drawing the most recent messages retained in a ring buffer
+// one per line with the newest message at the bottom
+// MessageLogWidget is safe to use across go-routines
+type MessageLogWidget struct {
+	ui.Block
+	// ring buffer of retained messages, next
+	// is the index the next message is stored at
+	entries     []logging.Entry
+	next        int
+	maxMessages int
+	// number of messages scrolled back from the newest message
+	scrollOffset int
+	// whether new messages leave the view where it is
+	// instead of scrolling it to the newest message
+	paused bool
+	// time zone and layout to display message timestamps in
+	TimeFormat logging.TimeFormat
+}
+
+// NewMessageLogWidget returns a new message log
+// widget retaining up to maxMessages messages
+func NewMessageLogWidget(maxMessages int) *MessageLogWidget {
+	if maxMessages <= 0 {
+		maxMessages = dconfig.DefaultMaxMessagesToRetain
+	}
+
+	return &MessageLogWidget{
+		Block:       *ui.NewBlock(),
+		maxMessages: maxMessages,
+	}
+}
+
+// Add adds the message to the log, dropping the oldest
+// message once maxMessages messages are retained
+func (w *MessageLogWidget) Add(entry logging.Entry) {
+	w.Lock()
+	defer w.Unlock()
+
+	if len(w.entries) < w.maxMessages {
+		w.entries = append(w.entries, entry)
+	} else {
+		w.entries[w.next] = entry
+	}
+
+	w.next = (w.next + 1) % w.maxMessages
+
+	// keep showing the same messages while paused
+	if w.paused && w.scrollOffset < len(w.entries)-1 {
+		w.scrollOffset++
+	}
+}
+
+// Scroll scrolls the view back (positive) or forward (negative)
+// by the number of messages, pausing auto scroll when scrolling
+// back and resuming it once scrolled forward to the newest message
+func (w *MessageLogWidget) Scroll(messages int) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.scrollOffset += messages
+
+	if w.scrollOffset >= len(w.entries) {
+		w.scrollOffset = len(w.entries) - 1
+	}
+
+	if w.scrollOffset <= 0 {
+		w.scrollOffset = 0
+		w.paused = false
+
+		return
+	}
+
+	w.paused = true
+}
+
+// TogglePause pauses or resumes auto
+// scrolling to the newest message
+func (w *MessageLogWidget) TogglePause() {
+	w.Lock()
+	defer w.Unlock()
+
+	w.paused = !w.paused
+
+	if !w.paused {
+		w.scrollOffset = 0
+	}
+}
+
+// Paused returns whether auto scrolling
+// to the newest message is paused
+func (w *MessageLogWidget) Paused() bool {
+	w.Lock()
+	defer w.Unlock()
+
+	return w.paused
+}
+
+// PageSize returns the number of messages shown at once
+func (w *MessageLogWidget) PageSize() int {
+	return w.Inner.Dy()
+}
+
+// visible returns up to rows messages ending scrollOffset
+// messages before the newest, ordered from oldest to newest
+func (w *MessageLogWidget) visible(rows int) []logging.Entry {
+	if len(w.entries) == 0 || rows <= 0 {
+		return nil
+	}
+
+	// ring buffer ordered from oldest to newest
+	ordered := append(append([]logging.Entry{}, w.entries[w.next%len(w.entries):]...), w.entries[:w.next%len(w.entries)]...)
+
+	end := len(ordered) - w.scrollOffset
+	start := end - rows
+
+	if start < 0 {
+		start = 0
+	}
+
+	return ordered[start:end]
+}
+
+// style returns the style to draw a message of the level with
+func (w *MessageLogWidget) style(level logging.Level) ui.Style {
+	switch level {
+	case logging.DebugLevel:
+		return ui.NewStyle(ui.ColorCyan)
+	case logging.WarnLevel:
+		return ui.NewStyle(ui.ColorYellow)
+	case logging.ErrorLevel:
+		return ui.NewStyle(ui.ColorRed)
+	}
+
+	return ui.NewStyle(ui.ColorWhite)
+}
+
+// Draw draws the messages to the buffer
+func (w *MessageLogWidget) Draw(buf *ui.Buffer) {
+	w.Block.Draw(buf)
+
+	for row, entry := range w.visible(w.Inner.Dy()) {
+		// one line per message
+		line := strings.ReplaceAll(entry.Format(logging.TextFormat, w.TimeFormat), "\n", " ")
+
+		buf.SetString(ui.TrimString(line, w.Inner.Dx()), w.style(entry.Level), image.Pt(w.Inner.Min.X, w.Inner.Min.Y+row))
+	}
+
+	// indicate there are newer messages than those shown
+	if w.scrollOffset > 0 {
+		buf.SetCell(ui.NewCell(ui.DOWN_ARROW, ui.NewStyle(ui.ColorWhite)), image.Pt(w.Inner.Max.X-1, w.Inner.Max.Y-1))
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kava-labs/doctor/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func visibleMessages(widget *MessageLogWidget, rows int) []string {
+	var messages []string
+
+	for _, entry := range widget.visible(rows) {
+		messages = append(messages, entry.Message)
+	}
+
+	return messages
+}
+
+func TestMessageLogWidgetRetainsAndScrollsMostRecentMessages(t *testing.T) {
+	widget := NewMessageLogWidget(4)
+
+	for i := 1; i <= 6; i++ {
+		widget.Add(logging.Entry{Level: logging.InfoLevel, Message: fmt.Sprintf("message %d", i)})
+	}
+
+	// oldest messages are dropped once the buffer is full
+	assert.Equal(t, []string{"message 3", "message 4", "message 5", "message 6"}, visibleMessages(widget, 10))
+	assert.Equal(t, []string{"message 5", "message 6"}, visibleMessages(widget, 2))
+
+	// scrolling back pauses auto scroll so new
+	// messages don't move the messages shown
+	widget.Scroll(2)
+	assert.True(t, widget.Paused())
+	assert.Equal(t, []string{"message 3", "message 4"}, visibleMessages(widget, 2))
+
+	// message 3 has been dropped to make room for message 7
+	widget.Add(logging.Entry{Level: logging.WarnLevel, Message: "message 7"})
+	assert.Equal(t, []string{"message 4"}, visibleMessages(widget, 2))
+
+	// resuming jumps back to the newest message
+	widget.TogglePause()
+	assert.False(t, widget.Paused())
+	assert.Equal(t, []string{"message 6", "message 7"}, visibleMessages(widget, 2))
+
+	// scrolling forward past the newest message resumes auto scroll
+	widget.Scroll(1)
+	widget.Scroll(-5)
+	assert.False(t, widget.Paused())
+	assert.Equal(t, []string{"message 7"}, visibleMessages(widget, 1))
+}
@@ -0,0 +1,44 @@
+package metric
+
+// Downsample reduces series to at most maxPoints values
+// for rendering, splitting the series into equal sized buckets
+// and keeping the minimum and maximum value of each bucket
+// (in the order they occurred) so that spikes and dips
+// remain visible no matter how many samples are retained
+// if the series already has maxPoints or less values
+// (or maxPoints is less than two) the series is returned as is
+func Downsample(series []float64, maxPoints int) []float64 {
+	if maxPoints < 2 || len(series) <= maxPoints {
+		return series
+	}
+
+	buckets := maxPoints / 2
+	downsampled := make([]float64, 0, buckets*2)
+
+	for bucket := 0; bucket < buckets; bucket++ {
+		start := bucket * len(series) / buckets
+		end := (bucket + 1) * len(series) / buckets
+
+		minIndex, maxIndex := start, start
+
+		for i := start; i < end; i++ {
+			if series[i] < series[minIndex] {
+				minIndex = i
+			}
+
+			if series[i] > series[maxIndex] {
+				maxIndex = i
+			}
+		}
+
+		// preserve the ordering of the extremes
+		// so the shape of the series is maintained
+		if minIndex < maxIndex {
+			downsampled = append(downsampled, series[minIndex], series[maxIndex])
+		} else {
+			downsampled = append(downsampled, series[maxIndex], series[minIndex])
+		}
+	}
+
+	return downsampled
+}
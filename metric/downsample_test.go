@@ -0,0 +1,38 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownsampleReturnsSeriesUnchangedWhenUnderLimit(t *testing.T) {
+	series := []float64{1, 2, 3}
+
+	assert.Equal(t, series, Downsample(series, 500))
+}
+
+func TestDownsampleLimitsNumberOfPoints(t *testing.T) {
+	series := make([]float64, 10000)
+
+	for i := range series {
+		series[i] = float64(i)
+	}
+
+	downsampled := Downsample(series, 500)
+
+	assert.Equal(t, 500, len(downsampled))
+	assert.Equal(t, float64(0), downsampled[0], "first value should be preserved")
+	assert.Equal(t, float64(9999), downsampled[len(downsampled)-1], "last value should be preserved")
+}
+
+func TestDownsamplePreservesExtremes(t *testing.T) {
+	series := make([]float64, 1000)
+	series[123] = 100
+	series[456] = -100
+
+	downsampled := Downsample(series, 10)
+
+	assert.Contains(t, downsampled, float64(100), "spikes should be preserved")
+	assert.Contains(t, downsampled, float64(-100), "dips should be preserved")
+}
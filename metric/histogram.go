@@ -0,0 +1,130 @@
+package metric
+
+import (
+	"math"
+	"sort"
+)
+
+// Histogram summarizes the distribution of a set of
+// sample values (e.g. status check latencies) allowing
+// for calculation of percentiles and summary statistics
+type Histogram struct {
+	sortedValues []float64
+	sum          float64
+}
+
+// NewHistogram returns a new histogram
+// for the distribution of values
+func NewHistogram(values []float64) *Histogram {
+	sortedValues := make([]float64, len(values))
+	copy(sortedValues, values)
+
+	sort.Float64s(sortedValues)
+
+	var sum float64
+
+	for _, value := range sortedValues {
+		sum += value
+	}
+
+	return &Histogram{
+		sortedValues: sortedValues,
+		sum:          sum,
+	}
+}
+
+// Count returns the number of values in the histogram
+func (h *Histogram) Count() int {
+	return len(h.sortedValues)
+}
+
+// Sum returns the sum of all values in the histogram
+func (h *Histogram) Sum() float64 {
+	return h.sum
+}
+
+// Min returns the smallest value in the histogram
+// or 0 if the histogram is empty
+func (h *Histogram) Min() float64 {
+	if len(h.sortedValues) == 0 {
+		return 0
+	}
+
+	return h.sortedValues[0]
+}
+
+// Max returns the largest value in the histogram
+// or 0 if the histogram is empty
+func (h *Histogram) Max() float64 {
+	if len(h.sortedValues) == 0 {
+		return 0
+	}
+
+	return h.sortedValues[len(h.sortedValues)-1]
+}
+
+// Mean returns the average of all values in
+// the histogram or 0 if the histogram is empty
+func (h *Histogram) Mean() float64 {
+	if len(h.sortedValues) == 0 {
+		return 0
+	}
+
+	return h.sum / float64(len(h.sortedValues))
+}
+
+// Variance returns the (population) variance of all values
+// in the histogram or 0 if the histogram is empty
+func (h *Histogram) Variance() float64 {
+	if len(h.sortedValues) == 0 {
+		return 0
+	}
+
+	mean := h.Mean()
+
+	var sumOfSquares float64
+
+	for _, value := range h.sortedValues {
+		sumOfSquares += (value - mean) * (value - mean)
+	}
+
+	return sumOfSquares / float64(len(h.sortedValues))
+}
+
+// Percentile returns the value below which percentile
+// (between 0 and 100) percent of the values in the histogram
+// fall, linearly interpolating between the closest ranked values
+// or 0 if the histogram is empty
+func (h *Histogram) Percentile(percentile float64) float64 {
+	if len(h.sortedValues) == 0 {
+		return 0
+	}
+
+	if percentile <= 0 {
+		return h.Min()
+	}
+
+	if percentile >= 100 {
+		return h.Max()
+	}
+
+	rank := percentile / 100 * float64(len(h.sortedValues)-1)
+	lowerRank := math.Floor(rank)
+	upperRank := math.Ceil(rank)
+
+	lower := h.sortedValues[int(lowerRank)]
+	upper := h.sortedValues[int(upperRank)]
+
+	return lower + (upper-lower)*(rank-lowerRank)
+}
+
+// StatisticSet returns the summary statistics of the histogram
+// in the form expected by CloudWatch for pre-aggregated metrics
+func (h *Histogram) StatisticSet() StatisticSet {
+	return StatisticSet{
+		SampleCount: float64(h.Count()),
+		Sum:         h.Sum(),
+		Minimum:     h.Min(),
+		Maximum:     h.Max(),
+	}
+}
@@ -0,0 +1,51 @@
+package metric
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramPercentiles(t *testing.T) {
+	values := make([]float64, 0, 101)
+
+	// add values out of order to ensure they are sorted
+	for i := 100; i >= 0; i-- {
+		values = append(values, float64(i))
+	}
+
+	histogram := NewHistogram(values)
+
+	assert.Equal(t, float64(50), histogram.Percentile(50))
+	assert.Equal(t, float64(95), histogram.Percentile(95))
+	assert.Equal(t, float64(99), histogram.Percentile(99))
+	assert.Equal(t, float64(0), histogram.Percentile(0))
+	assert.Equal(t, float64(100), histogram.Percentile(100))
+	assert.Equal(t, float64(100), values[0], "input values should not be modified")
+}
+
+func TestHistogramPercentileInterpolatesBetweenValues(t *testing.T) {
+	histogram := NewHistogram([]float64{10, 20})
+
+	assert.Equal(t, float64(15), histogram.Percentile(50))
+}
+
+func TestHistogramStatisticSet(t *testing.T) {
+	histogram := NewHistogram([]float64{300, 100, 200})
+
+	assert.Equal(t, StatisticSet{
+		SampleCount: 3,
+		Sum:         600,
+		Minimum:     100,
+		Maximum:     300,
+	}, histogram.StatisticSet())
+	assert.Equal(t, float64(200), histogram.Mean())
+	assert.InDelta(t, 6666.67, histogram.Variance(), 0.01)
+}
+
+func TestEmptyHistogram(t *testing.T) {
+	histogram := NewHistogram(nil)
+
+	assert.Equal(t, 0, histogram.Count())
+	assert.Equal(t, float64(0), histogram.Percentile(99))
+}
@@ -16,10 +16,82 @@ type Metric struct {
 	Name                string           `json:"name"`
 	Dimensions          MetricDimensions `json:"dimensions"`
 	Data                interface{}      `json:"data"`
-	CollectToFile       bool             `json:"-"` // whether this metric should be collected to a file
 	CollectToCloudwatch bool             `json:"-"` // whether this metric should be collect to CloudWatch
 	Value               float64          `json:"-"` // only used for collecting metrics to AWS CloudWatch
 	Timestamp           time.Time        `json:"-"` // only used for collecting metrics to AWS CloudWatch
+	// if set, the pre-aggregated statistics of a set of samples
+	// to collect to AWS CloudWatch instead of Value
+	StatisticValues *StatisticSet `json:"-"`
+	// unit of Value (or StatisticValues), only used for
+	// collecting metrics to AWS CloudWatch
+	Unit Unit `json:"-"`
+}
+
+// Unit is the unit of measure for the value of a metric
+type Unit string
+
+const (
+	// UnitNone is used for unitless values, e.g. block heights or ratios
+	UnitNone         Unit = ""
+	UnitSeconds      Unit = "Seconds"
+	UnitMilliseconds Unit = "Milliseconds"
+	UnitCount        Unit = "Count"
+	UnitPercent      Unit = "Percent"
+	UnitBytes        Unit = "Bytes"
+	// rates of change over the sampling interval
+	UnitBytesPerSecond Unit = "Bytes/Second"
+	UnitCountPerSecond Unit = "Count/Second"
+)
+
+// StatisticSet wraps the summary statistics
+// of a set of samples for a metric
+type StatisticSet struct {
+	SampleCount float64 `json:"sample_count"`
+	Sum         float64 `json:"sum"`
+	Minimum     float64 `json:"minimum"`
+	Maximum     float64 `json:"maximum"`
+}
+
+// LatencyPercentilesMetric wraps the distribution
+// of latencies measured over a window of samples
+type LatencyPercentilesMetric struct {
+	NodeId              string  `json:"node_id"`
+	Samples             int     `json:"samples"`
+	P50Milliseconds     float64 `json:"p50_milliseconds"`
+	P95Milliseconds     float64 `json:"p95_milliseconds"`
+	P99Milliseconds     float64 `json:"p99_milliseconds"`
+	MeanMilliseconds    float64 `json:"mean_milliseconds"`
+	MaximumMilliseconds float64 `json:"maximum_milliseconds"`
+}
+
+// BlockIntervalMetric wraps the distribution of seconds
+// between blocks observed over a window of samples
+type BlockIntervalMetric struct {
+	NodeId         string  `json:"node_id"`
+	Samples        int     `json:"samples"`
+	MinimumSeconds float64 `json:"minimum_seconds"`
+	MaximumSeconds float64 `json:"maximum_seconds"`
+	MeanSeconds    float64 `json:"mean_seconds"`
+	P95Seconds     float64 `json:"p95_seconds"`
+	// seconds between the oldest and newest block observed
+	// divided by the number of blocks produced in between
+	AverageBlockTimeSeconds float64 `json:"average_block_time_seconds"`
+	// variance (in seconds squared) of the intervals
+	Variance float64 `json:"variance"`
+}
+
+// SyncProgressMetric wraps estimates of how long
+// until a catching up node is synched to live
+type SyncProgressMetric struct {
+	NodeId              string  `json:"node_id"`
+	LatestBlockHeight   int64   `json:"latest_block_height"`
+	EstimatedHeadHeight int64   `json:"estimated_head_height"`
+	BlocksRemaining     int64   `json:"blocks_remaining"`
+	PercentSynced       float64 `json:"percent_synced"`
+	BlocksPerSecond     float32 `json:"blocks_per_second"`
+	// negative if the node isn't hashing blocks
+	// faster than the chain head is growing
+	EstimatedSecondsToCatchUp float64 `json:"estimated_seconds_to_catch_up"`
 }
 
 // SyncStatusMetrics wraps metrics collected
@@ -30,6 +102,23 @@ type SyncStatusMetrics struct {
 	SyncStatus                kava.SyncInfo `json:"sync_status"`
 	SecondsBehindLive         int64         `json:"seconds_behind_live"`
 	SampledAt                 time.Time     `json:"sampled_at"`
+	// whether the node responded with a block time older than
+	// a previous response, e.g. served from a caching proxy
+	Stale bool `json:"stale"`
+	// how many blocks the node is behind the reference
+	// endpoint's chain head, nil if no reference is configured
+	// or the reference couldn't be queried
+	BlocksBehindReference *int64 `json:"blocks_behind_reference,omitempty"`
+	// chain id of the network the node is on and whether
+	// it differs from the expected chain id (if any)
+	ChainId         string `json:"chain_id"`
+	ChainIdMismatch bool   `json:"chain_id_mismatch"`
+	// whether autohealing hit its restart cap and is stopped
+	// until manually reset, nil if autohealing isn't enabled
+	AutohealCircuitBreakerTripped *bool `json:"autoheal_circuit_breaker_tripped,omitempty"`
+	// phase of synching the node is in (e.g. restoring a state
+	// sync snapshot), empty for stale responses
+	SyncPhase string `json:"sync_phase,omitempty"`
 }
 
 // UptimeMetric wraps values used to calculate
@@ -39,6 +128,271 @@ type UptimeMetric struct {
 	Up                             bool      `json:"up"`
 	SampledAt                      time.Time `json:"sampled_at"`
 	RollingAveragePercentAvailable float32   `json:"rolling_average_percent_available"`
+	// number of attempts made to get the status of the endpoint,
+	// more than one if requests failed and were retried
+	StatusCheckAttempts int `json:"status_check_attempts,omitempty"`
+	// result of each endpoint probed for the sample,
+	// the endpoint is only up if every probe succeeded
+	Probes []UptimeProbeMetric `json:"probes,omitempty"`
+}
+
+// UptimeProbeMetric wraps whether a probe (e.g. of
+// the health endpoint) of a kava endpoint succeeded
+type UptimeProbeMetric struct {
+	Probe string `json:"probe"`
+	Up    bool   `json:"up"`
+	Error string `json:"error,omitempty"`
+}
+
+// UptimeWindowsMetric wraps the availability of a given kava
+// endpoint over each configured window of time and its
+// exponentially weighted moving average availability
+type UptimeWindowsMetric struct {
+	EndpointURL string `json:"endpoint_url"`
+	// keyed by window e.g. 5m or 24h
+	PercentAvailableByWindow map[string]float32 `json:"percent_available_by_window"`
+	// omitted if the moving average is disabled
+	EWMAPercentAvailable *float32  `json:"ewma_percent_available,omitempty"`
+	SampledAt            time.Time `json:"sampled_at"`
+}
+
+// EndpointAddressMetrics wraps the result of probing one of
+// the ip addresses the host of an endpoint resolves to
+type EndpointAddressMetrics struct {
+	EndpointURL string `json:"endpoint_url"`
+	Address     string `json:"address"`
+	// id of the node serving the address, empty if the probe failed
+	NodeId                         string    `json:"node_id"`
+	Up                             bool      `json:"up"`
+	SampleLatencyMilliseconds      int64     `json:"sample_latency_milliseconds"`
+	SampledAt                      time.Time `json:"sampled_at"`
+	RollingAveragePercentAvailable float32   `json:"rolling_average_percent_available"`
+}
+
+// RegionLatencyMetrics wraps the result of requesting the status
+// of the same logical endpoint from each region it's probed from
+type RegionLatencyMetrics struct {
+	EndpointURL string          `json:"endpoint_url"`
+	Regions     []RegionLatency `json:"regions"`
+	SampledAt   time.Time       `json:"sampled_at"`
+}
+
+// RegionLatency wraps the result of requesting
+// the status of an endpoint from a single region
+type RegionLatency struct {
+	Region              string `json:"region"`
+	URL                 string `json:"url"`
+	Up                  bool   `json:"up"`
+	LatencyMilliseconds int64  `json:"latency_milliseconds"`
+	// error returned by the request, empty if it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// ChainConsistencyMetrics wraps the comparison of the hashes
+// of a block on the node to the same block on the reference node
+type ChainConsistencyMetrics struct {
+	NodeId             string `json:"node_id"`
+	Height             int64  `json:"height"`
+	BlockHash          string `json:"block_hash"`
+	ReferenceBlockHash string `json:"reference_block_hash"`
+	AppHash            string `json:"app_hash"`
+	ReferenceAppHash   string `json:"reference_app_hash"`
+	// whether either hash differs, i.e. the node is
+	// on a fork or has corrupt state
+	Diverged  bool      `json:"diverged"`
+	SampledAt time.Time `json:"sampled_at"`
+}
+
+// VotingPowerMetrics wraps the voting power of the validators
+// that signed the commit of a recent block compared to the voting
+// power of the validator set, as a measure of the health of the
+// chain itself rather than of a single node
+type VotingPowerMetrics struct {
+	ChainId string `json:"chain_id"`
+	// url of the endpoint the validator set and commit were queried from
+	EndpointURL       string  `json:"endpoint_url"`
+	Height            int64   `json:"height"`
+	Validators        int     `json:"validators"`
+	ValidatorsSigned  int     `json:"validators_signed"`
+	VotingPower       int64   `json:"voting_power"`
+	OnlineVotingPower int64   `json:"online_voting_power"`
+	OnlinePercent     float64 `json:"online_percent"`
+	// whether the online voting power is below the degraded
+	// threshold, i.e. the chain is halted or at risk of halting
+	Degraded  bool      `json:"degraded"`
+	SampledAt time.Time `json:"sampled_at"`
+}
+
+// NodeGroupRollupMetrics wraps metrics rolled up across
+// the nodes (or endpoints) that are members of a named group
+type NodeGroupRollupMetrics struct {
+	GroupName string `json:"group_name"`
+	Members   int    `json:"members"`
+	// members that samples have been taken for
+	ReportingMembers int `json:"reporting_members"`
+	// most seconds behind live of the latest sample of any member
+	WorstSecondsBehindLive int64 `json:"worst_seconds_behind_live"`
+	// mean uptime of the members uptime has been
+	// sampled for, nil if it hasn't for any member
+	MeanUptimePercent *float64 `json:"mean_uptime_percent,omitempty"`
+	// members that are too far behind live or were down when last sampled
+	UnhealthyMembers int       `json:"unhealthy_members"`
+	SampledAt        time.Time `json:"sampled_at"`
+}
+
+// PeerConnectionMetrics wraps the set of
+// peers a node was connected to when sampled
+type PeerConnectionMetrics struct {
+	NodeId  string   `json:"node_id"`
+	PeerIds []string `json:"peer_ids"`
+	// total bytes per second received from and sent
+	// to all peers since they connected
+	RecvBytesPerSecond float64 `json:"recv_bytes_per_second"`
+	SendBytesPerSecond float64 `json:"send_bytes_per_second"`
+	// peers connected long enough to judge that are
+	// sending the node too little data to be useful
+	LowQualityPeerIds []string `json:"low_quality_peer_ids,omitempty"`
+	// whether the node has no peers or most of its peers
+	// are low quality, so it's unlikely to keep up with live
+	PoorPeering bool      `json:"poor_peering"`
+	SampledAt   time.Time `json:"sampled_at"`
+}
+
+// ServiceHealthMetrics wraps the state of the
+// systemd service running the blockchain when sampled
+type ServiceHealthMetrics struct {
+	ServiceName string `json:"service_name"`
+	ActiveState string `json:"active_state"`
+	SubState    string `json:"sub_state"`
+	// number of times systemd has restarted the service
+	Restarts int `json:"restarts"`
+	// whether systemd is repeatedly restarting the service
+	RestartLoop bool      `json:"restart_loop"`
+	SampledAt   time.Time `json:"sampled_at"`
+}
+
+// ConsensusStateMetrics wraps the progress of the
+// round of consensus a node is participating in when sampled
+type ConsensusStateMetrics struct {
+	NodeId   string `json:"node_id"`
+	Height   int64  `json:"height"`
+	Round    int    `json:"round"`
+	Step     int    `json:"step"`
+	StepName string `json:"step_name"`
+	// how long the node has been in the
+	// current height, round and step
+	SecondsInStep float64 `json:"seconds_in_step"`
+	// whether the node has been in the current height, round and
+	// step for longer than the frozen consensus threshold
+	Frozen    bool      `json:"frozen"`
+	SampledAt time.Time `json:"sampled_at"`
+}
+
+// MempoolMetrics wraps the number and size of unconfirmed
+// transactions in a node's mempool when sampled
+type MempoolMetrics struct {
+	NodeId string `json:"node_id"`
+	Size   int64  `json:"size"`
+	Bytes  int64  `json:"bytes"`
+	// whether the mempool size has stayed above
+	// the alert threshold for the alert duration
+	Backlogged bool      `json:"backlogged"`
+	SampledAt  time.Time `json:"sampled_at"`
+}
+
+// WebsocketMetrics wraps the state of the subscription to new
+// block events over the node's websocket interface when a new
+// block event is received or the subscription connects or drops
+type WebsocketMetrics struct {
+	NodeId    string `json:"node_id"`
+	Connected bool   `json:"connected"`
+	// whether the subscription dropped since the last sample
+	Dropped bool `json:"dropped"`
+	// height of the new block event received, 0 if none was
+	Height int64 `json:"height"`
+	// seconds between the time of the block and
+	// when its new block event was received
+	NewBlockEventLagSeconds float64   `json:"new_block_event_lag_seconds"`
+	SampledAt               time.Time `json:"sampled_at"`
+}
+
+// RPCMethodsMetrics wraps the result of calling each
+// of the json rpc methods of a node that are probed
+type RPCMethodsMetrics struct {
+	NodeId    string             `json:"node_id"`
+	Methods   []RPCMethodMetrics `json:"methods"`
+	SampledAt time.Time          `json:"sampled_at"`
+}
+
+// RPCMethodMetrics wraps whether a call to a json rpc
+// method succeeded, how long it took and why it failed
+type RPCMethodMetrics struct {
+	Method              string `json:"method"`
+	Available           bool   `json:"available"`
+	LatencyMilliseconds int64  `json:"latency_milliseconds"`
+	Error               string `json:"error,omitempty"`
+}
+
+// HostMetrics wraps the load, memory usage and network
+// and disk io of the host running the node when sampled
+type HostMetrics struct {
+	LoadAverage1m        float64 `json:"load_average_1m"`
+	LoadAverage5m        float64 `json:"load_average_5m"`
+	LoadAverage15m       float64 `json:"load_average_15m"`
+	MemoryTotalBytes     uint64  `json:"memory_total_bytes"`
+	MemoryAvailableBytes uint64  `json:"memory_available_bytes"`
+	// percent of memory that isn't available for
+	// starting new applications without swapping
+	MemoryUsedPercent float64                `json:"memory_used_percent"`
+	Interfaces        []HostInterfaceMetrics `json:"interfaces"`
+	Disks             []HostDiskMetrics      `json:"disks"`
+	SampledAt         time.Time              `json:"sampled_at"`
+}
+
+// HostInterfaceMetrics wraps the rate of bytes a network
+// interface received and transmitted over the sampling interval
+type HostInterfaceMetrics struct {
+	Interface              string  `json:"interface"`
+	ReceiveBytesPerSecond  float64 `json:"receive_bytes_per_second"`
+	TransmitBytesPerSecond float64 `json:"transmit_bytes_per_second"`
+}
+
+// HostDiskMetrics wraps the rate and mean latency of reads and
+// writes a block device completed over the sampling interval
+type HostDiskMetrics struct {
+	Device                   string  `json:"device"`
+	ReadsPerSecond           float64 `json:"reads_per_second"`
+	WritesPerSecond          float64 `json:"writes_per_second"`
+	ReadLatencyMilliseconds  float64 `json:"read_latency_milliseconds"`
+	WriteLatencyMilliseconds float64 `json:"write_latency_milliseconds"`
+}
+
+// AccessLogMetrics wraps the error rates and latencies of real
+// client requests to the node observed in its access log over an interval
+type AccessLogMetrics struct {
+	LogFilepath  string `json:"log_filepath"`
+	Requests     int    `json:"requests"`
+	ServerErrors int    `json:"server_errors"`
+	ClientErrors int    `json:"client_errors"`
+	// ratio (between 0 and 1) of requests that failed due to the server
+	ServerErrorRate float64 `json:"server_error_rate"`
+	// ratio (between 0 and 1) of requests that failed due to the client
+	ClientErrorRate float64 `json:"client_error_rate"`
+	// latency percentiles of requests with a logged latency
+	LatencySamples         int       `json:"latency_samples"`
+	LatencyP50Milliseconds float64   `json:"latency_p50_milliseconds"`
+	LatencyP95Milliseconds float64   `json:"latency_p95_milliseconds"`
+	LatencyP99Milliseconds float64   `json:"latency_p99_milliseconds"`
+	UnparsedLines          int       `json:"unparsed_lines"`
+	SampledAt              time.Time `json:"sampled_at"`
+}
+
+// PeerChurnMetric wraps values for how frequently
+// a node is connecting to and disconnecting from peers
+type PeerChurnMetric struct {
+	NodeId             string  `json:"node_id"`
+	PeerCount          int     `json:"peer_count"`
+	PeerChurnPerMinute float64 `json:"peer_churn_per_minute"`
 }
 
 // HashRateMetric wraps values for how fast
@@ -0,0 +1,1403 @@
+// metrics.go contains functions for deriving metrics
+// to collect from the samples taken for an endpoint
+// that are shared by the cli and gui display modes
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kava-labs/doctor/backpressure"
+	"github.com/kava-labs/doctor/checks"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/endpoint"
+	"github.com/kava-labs/doctor/event"
+	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/slo"
+	"github.com/kava-labs/doctor/syncphase"
+)
+
+// StatusCheckLatencyPercentiles are the percentiles of status check
+// latency emitted for each node over the synthetic metric window
+var StatusCheckLatencyPercentiles = []float64{50, 95, 99}
+
+// statusCheckLatencyMetrics returns metrics for the distribution of status
+// check latencies for the specified node over the most recent samples
+// (up to MetricSamplesForSyntheticMetricCalculation), as percentiles
+// and a statistic set for CloudWatch, and error (if any)
+func statusCheckLatencyMetrics(kavaEndpoint *endpoint.Endpoint, nodeId string, sampledAt time.Time) ([]metric.Metric, error) {
+	histogram, err := kavaEndpoint.CalculateStatusCheckLatencyHistogram(nodeId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var metrics []metric.Metric
+
+	dimensions := map[string]string{
+		"node_id": nodeId,
+	}
+
+	for _, percentile := range StatusCheckLatencyPercentiles {
+		metrics = append(metrics, metric.Metric{
+			Name:                fmt.Sprintf("StatusCheckLatencyMillisecondsP%.0f", percentile),
+			Dimensions:          dimensions,
+			Value:               histogram.Percentile(percentile),
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitMilliseconds,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	statisticSet := histogram.StatisticSet()
+
+	metrics = append(metrics, metric.Metric{
+		Name:                "StatusCheckLatencyMillisecondsDistribution",
+		Dimensions:          dimensions,
+		StatisticValues:     &statisticSet,
+		Timestamp:           sampledAt,
+		Unit:                metric.UnitMilliseconds,
+		CollectToCloudwatch: true,
+	})
+
+	metrics = append(metrics, metric.Metric{
+		Name:       "StatusCheckLatencyPercentiles",
+		Dimensions: dimensions,
+		Data: metric.LatencyPercentilesMetric{
+			NodeId:              nodeId,
+			Samples:             histogram.Count(),
+			P50Milliseconds:     histogram.Percentile(50),
+			P95Milliseconds:     histogram.Percentile(95),
+			P99Milliseconds:     histogram.Percentile(99),
+			MeanMilliseconds:    histogram.Mean(),
+			MaximumMilliseconds: histogram.Max(),
+		},
+		Timestamp:           sampledAt,
+		CollectToCloudwatch: false,
+	})
+
+	return metrics, nil
+}
+
+// blockIntervalMetrics returns metrics for the distribution of seconds
+// between blocks observed by the specified node over the most recent
+// samples (up to MetricSamplesForSyntheticMetricCalculation), along with
+// the calculated distribution and error (if any)
+func blockIntervalMetrics(kavaEndpoint *endpoint.Endpoint, nodeId string, sampledAt time.Time) ([]metric.Metric, metric.BlockIntervalMetric, error) {
+	histogram, err := kavaEndpoint.CalculateBlockIntervalHistogram(nodeId)
+
+	if err != nil {
+		return nil, metric.BlockIntervalMetric{}, err
+	}
+
+	averageBlockTime, err := kavaEndpoint.CalculateAverageBlockTime(nodeId)
+
+	if err != nil {
+		return nil, metric.BlockIntervalMetric{}, err
+	}
+
+	blockInterval := metric.BlockIntervalMetric{
+		NodeId:                  nodeId,
+		Samples:                 histogram.Count(),
+		MinimumSeconds:          histogram.Min(),
+		MaximumSeconds:          histogram.Max(),
+		MeanSeconds:             histogram.Mean(),
+		P95Seconds:              histogram.Percentile(95),
+		AverageBlockTimeSeconds: averageBlockTime,
+		Variance:                histogram.Variance(),
+	}
+
+	dimensions := map[string]string{
+		"node_id": nodeId,
+	}
+
+	metrics := []metric.Metric{
+		{
+			Name:                "BlockIntervalSecondsMinimum",
+			Dimensions:          dimensions,
+			Value:               blockInterval.MinimumSeconds,
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "BlockIntervalSecondsMaximum",
+			Dimensions:          dimensions,
+			Value:               blockInterval.MaximumSeconds,
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "BlockIntervalSecondsMean",
+			Dimensions:          dimensions,
+			Value:               blockInterval.MeanSeconds,
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "BlockIntervalSecondsP95",
+			Dimensions:          dimensions,
+			Value:               blockInterval.P95Seconds,
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "AverageBlockTimeSeconds",
+			Dimensions:          dimensions,
+			Value:               blockInterval.AverageBlockTimeSeconds,
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		},
+		{
+			// in seconds squared, which cloudwatch has no unit for
+			Name:                "BlockIntervalVariance",
+			Dimensions:          dimensions,
+			Value:               blockInterval.Variance,
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "BlockInterval",
+			Dimensions:          dimensions,
+			Data:                blockInterval,
+			Timestamp:           sampledAt,
+			CollectToCloudwatch: false,
+		},
+	}
+
+	return metrics, blockInterval, nil
+}
+
+// syncProgressMetrics returns metrics estimating how long until the
+// specified catching up node is synched to live, comparing the node's
+// hash rate to the rate the chain head is growing at, along with the
+// estimated progress and error (if any)
+func syncProgressMetrics(kavaEndpoint *endpoint.Endpoint, nodeId string, sampledAt time.Time) ([]metric.Metric, metric.SyncProgressMetric, error) {
+	progress, err := kavaEndpoint.CalculateSyncProgress(nodeId)
+
+	if err != nil {
+		return nil, metric.SyncProgressMetric{}, err
+	}
+
+	syncProgress := metric.SyncProgressMetric{
+		NodeId:                    nodeId,
+		LatestBlockHeight:         progress.LatestBlockHeight,
+		EstimatedHeadHeight:       progress.EstimatedHeadHeight,
+		BlocksRemaining:           progress.BlocksRemaining,
+		PercentSynced:             progress.PercentSynced,
+		BlocksPerSecond:           progress.BlocksPerSecond,
+		EstimatedSecondsToCatchUp: progress.EstimatedSecondsLeft,
+	}
+
+	dimensions := map[string]string{
+		"node_id": nodeId,
+	}
+
+	var metrics []metric.Metric
+
+	// a node that isn't gaining on the chain head
+	// will never catch up so there is no estimate
+	if syncProgress.EstimatedSecondsToCatchUp >= 0 {
+		metrics = append(metrics, metric.Metric{
+			Name:                "EstimatedSecondsToCatchUp",
+			Dimensions:          dimensions,
+			Value:               syncProgress.EstimatedSecondsToCatchUp,
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	metrics = append(metrics, metric.Metric{
+		Name:                "SyncProgress",
+		Dimensions:          dimensions,
+		Data:                syncProgress,
+		Timestamp:           sampledAt,
+		CollectToCloudwatch: false,
+	})
+
+	return metrics, syncProgress, nil
+}
+
+// formatEstimatedTimeToCatchUp returns the estimated
+// time until a catching up node is synched to live
+func formatEstimatedTimeToCatchUp(syncProgress metric.SyncProgressMetric) string {
+	if syncProgress.EstimatedSecondsToCatchUp < 0 {
+		return "unknown (not gaining on the chain head)"
+	}
+
+	return (time.Duration(syncProgress.EstimatedSecondsToCatchUp) * time.Second).String()
+}
+
+// staleResponseMetrics returns metrics for counting how often
+// a node's status responses are stale (e.g. served from a caching proxy)
+func staleResponseMetrics(syncStatusMetrics metric.SyncStatusMetrics) []metric.Metric {
+	var staleResponses float64
+
+	if syncStatusMetrics.Stale {
+		staleResponses = 1
+	}
+
+	return []metric.Metric{
+		{
+			Name: "StaleResponses",
+			Dimensions: map[string]string{
+				"node_id": syncStatusMetrics.NodeId,
+			},
+			Value:               staleResponses,
+			Timestamp:           syncStatusMetrics.SampledAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// peerChurnMetrics returns metrics for how frequently the node
+// the peer connection sample was taken for is connecting to and
+// disconnecting from peers, along with the calculated churn
+// per minute and error (if any)
+func peerChurnMetrics(kavaEndpoint *endpoint.Endpoint, peerConnectionMetrics metric.PeerConnectionMetrics) ([]metric.Metric, float64, error) {
+	nodeId := peerConnectionMetrics.NodeId
+
+	peerChurnPerMinute, err := kavaEndpoint.CalculatePeerChurnPerMinute(nodeId)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dimensions := map[string]string{
+		"node_id": nodeId,
+	}
+
+	metrics := []metric.Metric{
+		{
+			Name:       "PeerChurnPerMinute",
+			Dimensions: dimensions,
+			Data: metric.PeerChurnMetric{
+				NodeId:             nodeId,
+				PeerCount:          len(peerConnectionMetrics.PeerIds),
+				PeerChurnPerMinute: peerChurnPerMinute,
+			},
+			Value:               peerChurnPerMinute,
+			Timestamp:           peerConnectionMetrics.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "PeerCount",
+			Dimensions:          dimensions,
+			Value:               float64(len(peerConnectionMetrics.PeerIds)),
+			Timestamp:           peerConnectionMetrics.SampledAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "LowQualityPeerCount",
+			Dimensions:          dimensions,
+			Value:               float64(len(peerConnectionMetrics.LowQualityPeerIds)),
+			Timestamp:           peerConnectionMetrics.SampledAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "PeerRecvBytesPerSecond",
+			Dimensions:          dimensions,
+			Value:               peerConnectionMetrics.RecvBytesPerSecond,
+			Timestamp:           peerConnectionMetrics.SampledAt,
+			Unit:                metric.UnitBytesPerSecond,
+			CollectToCloudwatch: true,
+		},
+	}
+
+	return metrics, peerChurnPerMinute, nil
+}
+
+// serviceHealthMetrics returns metrics for the health
+// of the systemd service running the blockchain
+func serviceHealthMetrics(serviceHealth metric.ServiceHealthMetrics) []metric.Metric {
+	var restartLoop float64
+
+	if serviceHealth.RestartLoop {
+		restartLoop = 1
+	}
+
+	dimensions := map[string]string{
+		"service_name": serviceHealth.ServiceName,
+	}
+
+	return []metric.Metric{
+		{
+			Name:                "ServiceRestartLoop",
+			Dimensions:          dimensions,
+			Value:               restartLoop,
+			Timestamp:           serviceHealth.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:       "ServiceHealth",
+			Dimensions: dimensions,
+			Data:       serviceHealth,
+			Timestamp:  serviceHealth.SampledAt,
+		},
+	}
+}
+
+// blocksBehindReferenceMetrics returns metrics for how many blocks the
+// node is behind the reference endpoint, empty if no reference is configured
+func blocksBehindReferenceMetrics(syncStatusMetrics metric.SyncStatusMetrics) []metric.Metric {
+	if syncStatusMetrics.BlocksBehindReference == nil {
+		return nil
+	}
+
+	return []metric.Metric{
+		{
+			Name: "BlocksBehindReference",
+			Dimensions: map[string]string{
+				"node_id": syncStatusMetrics.NodeId,
+			},
+			Value:               float64(*syncStatusMetrics.BlocksBehindReference),
+			Timestamp:           syncStatusMetrics.SampledAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// chainIdMismatchMetrics returns metrics for whether the node is on
+// a chain other than the expected chain, empty if the node didn't
+// report its chain id
+func chainIdMismatchMetrics(syncStatusMetrics metric.SyncStatusMetrics) []metric.Metric {
+	if syncStatusMetrics.ChainId == "" {
+		return nil
+	}
+
+	var mismatch float64
+
+	if syncStatusMetrics.ChainIdMismatch {
+		mismatch = 1
+	}
+
+	return []metric.Metric{
+		{
+			Name: "ChainIdMismatch",
+			Dimensions: map[string]string{
+				"node_id":  syncStatusMetrics.NodeId,
+				"chain_id": syncStatusMetrics.ChainId,
+			},
+			Value:               mismatch,
+			Timestamp:           syncStatusMetrics.SampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// syncPhaseMetrics returns metrics for the phase of synching the node
+// is in, reported as its index in syncphase.Phases (0 when live, 1 when
+// replaying blocks and 2 when restoring a state sync snapshot), empty
+// for stale responses the phase isn't detected for
+func syncPhaseMetrics(syncStatusMetrics metric.SyncStatusMetrics) []metric.Metric {
+	if syncStatusMetrics.SyncPhase == "" {
+		return nil
+	}
+
+	return []metric.Metric{
+		{
+			Name: "SyncPhase",
+			Dimensions: map[string]string{
+				"node_id": syncStatusMetrics.NodeId,
+			},
+			Value:               syncphase.Value(syncStatusMetrics.SyncPhase),
+			Timestamp:           syncStatusMetrics.SampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// autohealCircuitBreakerMetrics returns metrics for whether
+// autohealing hit its restart cap and is stopped until manually
+// reset, empty if autohealing isn't enabled
+func autohealCircuitBreakerMetrics(syncStatusMetrics metric.SyncStatusMetrics) []metric.Metric {
+	if syncStatusMetrics.AutohealCircuitBreakerTripped == nil {
+		return nil
+	}
+
+	var tripped float64
+
+	if *syncStatusMetrics.AutohealCircuitBreakerTripped {
+		tripped = 1
+	}
+
+	return []metric.Metric{
+		{
+			Name: "AutohealCircuitBreakerTripped",
+			Dimensions: map[string]string{
+				"node_id": syncStatusMetrics.NodeId,
+			},
+			Value:               tripped,
+			Timestamp:           syncStatusMetrics.SampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// chainConsistencyMetrics returns metrics for whether the node's
+// block or app hashes differ from those of the reference node
+func chainConsistencyMetrics(consistency metric.ChainConsistencyMetrics) []metric.Metric {
+	var diverged float64
+
+	if consistency.Diverged {
+		diverged = 1
+	}
+
+	return []metric.Metric{
+		{
+			Name: "ChainDiverged",
+			Dimensions: map[string]string{
+				"node_id": consistency.NodeId,
+			},
+			Data:                consistency,
+			Value:               diverged,
+			Timestamp:           consistency.SampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// votingPowerMetrics returns a metric for the percent of
+// voting power that signed the commit of a recent block
+func votingPowerMetrics(votingPower metric.VotingPowerMetrics) []metric.Metric {
+	return []metric.Metric{
+		{
+			Name: "VotingPowerOnlinePercent",
+			Dimensions: map[string]string{
+				"chain_id": votingPower.ChainId,
+			},
+			Data:                votingPower,
+			Value:               votingPower.OnlinePercent,
+			Timestamp:           votingPower.SampledAt,
+			Unit:                metric.UnitPercent,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// accessLogMetrics returns metrics for the error rates and
+// latencies of real client requests observed in the access log
+func accessLogMetrics(accessLog metric.AccessLogMetrics) []metric.Metric {
+	dimensions := map[string]string{
+		"access_log": accessLog.LogFilepath,
+	}
+
+	metrics := []metric.Metric{
+		{
+			Name:                "ObservedRequests",
+			Dimensions:          dimensions,
+			Value:               float64(accessLog.Requests),
+			Timestamp:           accessLog.SampledAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "ObservedServerErrorRate",
+			Dimensions:          dimensions,
+			Value:               accessLog.ServerErrorRate,
+			Timestamp:           accessLog.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "ObservedClientErrorRate",
+			Dimensions:          dimensions,
+			Value:               accessLog.ClientErrorRate,
+			Timestamp:           accessLog.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:       "AccessLog",
+			Dimensions: dimensions,
+			Data:       accessLog,
+			Timestamp:  accessLog.SampledAt,
+		},
+	}
+
+	// percentiles are meaningless without any requests
+	if accessLog.LatencySamples == 0 {
+		return metrics
+	}
+
+	for _, percentile := range []struct {
+		name  string
+		value float64
+	}{
+		{"P50", accessLog.LatencyP50Milliseconds},
+		{"P95", accessLog.LatencyP95Milliseconds},
+		{"P99", accessLog.LatencyP99Milliseconds},
+	} {
+		metrics = append(metrics, metric.Metric{
+			Name:                "ObservedLatencyMilliseconds" + percentile.name,
+			Dimensions:          dimensions,
+			Value:               percentile.value,
+			Timestamp:           accessLog.SampledAt,
+			Unit:                metric.UnitMilliseconds,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	return metrics
+}
+
+// mempoolMetrics returns metrics for the number and size
+// of unconfirmed transactions in the node's mempool
+func mempoolMetrics(mempool metric.MempoolMetrics) []metric.Metric {
+	dimensions := map[string]string{
+		"node_id": mempool.NodeId,
+	}
+
+	return []metric.Metric{
+		{
+			Name:                "MempoolSize",
+			Dimensions:          dimensions,
+			Value:               float64(mempool.Size),
+			Timestamp:           mempool.SampledAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "MempoolBytes",
+			Dimensions:          dimensions,
+			Value:               float64(mempool.Bytes),
+			Timestamp:           mempool.SampledAt,
+			Unit:                metric.UnitBytes,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:       "Mempool",
+			Dimensions: dimensions,
+			Data:       mempool,
+			Timestamp:  mempool.SampledAt,
+		},
+	}
+}
+
+// rpcMethodsMetrics returns metrics for whether each probed
+// json rpc method of the node is available and its latency
+func rpcMethodsMetrics(rpcMethods metric.RPCMethodsMetrics) []metric.Metric {
+	var metrics []metric.Metric
+
+	for _, method := range rpcMethods.Methods {
+		var available float64
+
+		if method.Available {
+			available = 1
+		}
+
+		dimensions := map[string]string{
+			"node_id": rpcMethods.NodeId,
+			"method":  method.Method,
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "RPCMethodAvailable",
+			Dimensions:          dimensions,
+			Value:               available,
+			Timestamp:           rpcMethods.SampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "RPCMethodLatencyMilliseconds",
+			Dimensions:          dimensions,
+			Value:               float64(method.LatencyMilliseconds),
+			Timestamp:           rpcMethods.SampledAt,
+			Unit:                metric.UnitMilliseconds,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	metrics = append(metrics, metric.Metric{
+		Name: "RPCMethods",
+		Dimensions: map[string]string{
+			"node_id": rpcMethods.NodeId,
+		},
+		Data:      rpcMethods,
+		Timestamp: rpcMethods.SampledAt,
+	})
+
+	return metrics
+}
+
+// websocketMetrics returns metrics for whether the node's websocket
+// subscription to new block events is connected and how long
+// after the block the new block event was delivered
+func websocketMetrics(websocket metric.WebsocketMetrics) []metric.Metric {
+	var connected float64
+
+	if websocket.Connected {
+		connected = 1
+	}
+
+	dimensions := map[string]string{
+		"node_id": websocket.NodeId,
+	}
+
+	metrics := []metric.Metric{
+		{
+			Name:                "WebsocketConnected",
+			Dimensions:          dimensions,
+			Value:               connected,
+			Timestamp:           websocket.SampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		},
+	}
+
+	// only new block events have a lag
+	if websocket.Height > 0 {
+		metrics = append(metrics, metric.Metric{
+			Name:                "NewBlockEventLagSeconds",
+			Dimensions:          dimensions,
+			Value:               websocket.NewBlockEventLagSeconds,
+			Timestamp:           websocket.SampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	return append(metrics, metric.Metric{
+		Name:       "Websocket",
+		Dimensions: dimensions,
+		Data:       websocket,
+		Timestamp:  websocket.SampledAt,
+	})
+}
+
+// regionLatencyMetrics returns metrics for whether the endpoint is
+// up from each region it's probed from and its latency from each region,
+// along with how much slower the endpoint is from each region than the
+// median latency across the regions it's up from
+func regionLatencyMetrics(regionLatency metric.RegionLatencyMetrics) []metric.Metric {
+	var metrics []metric.Metric
+	var latencies []float64
+
+	for _, region := range regionLatency.Regions {
+		if region.Up {
+			latencies = append(latencies, float64(region.LatencyMilliseconds))
+		}
+	}
+
+	medianLatency := metric.NewHistogram(latencies).Percentile(50)
+
+	for _, region := range regionLatency.Regions {
+		var up float64
+
+		if region.Up {
+			up = 1
+		}
+
+		dimensions := map[string]string{
+			"endpoint_url": regionLatency.EndpointURL,
+			"region":       region.Region,
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "RegionUp",
+			Dimensions:          dimensions,
+			Value:               up,
+			Timestamp:           regionLatency.SampledAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		})
+
+		// latencies of failed requests aren't comparable
+		if !region.Up {
+			continue
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "RegionLatencyMilliseconds",
+			Dimensions:          dimensions,
+			Value:               float64(region.LatencyMilliseconds),
+			Timestamp:           regionLatency.SampledAt,
+			Unit:                metric.UnitMilliseconds,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "RegionLatencyAboveMedianMilliseconds",
+			Dimensions:          dimensions,
+			Value:               float64(region.LatencyMilliseconds) - medianLatency,
+			Timestamp:           regionLatency.SampledAt,
+			Unit:                metric.UnitMilliseconds,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	return append(metrics, metric.Metric{
+		Name: "RegionLatency",
+		Dimensions: map[string]string{
+			"endpoint_url": regionLatency.EndpointURL,
+		},
+		Data:      regionLatency,
+		Timestamp: regionLatency.SampledAt,
+	})
+}
+
+// hostMetrics returns metrics for the load, memory usage
+// and network and disk io of the host running the node
+func hostMetrics(host metric.HostMetrics) []metric.Metric {
+	metrics := []metric.Metric{
+		{
+			Name:                "HostLoadAverage1m",
+			Value:               host.LoadAverage1m,
+			Timestamp:           host.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "HostLoadAverage5m",
+			Value:               host.LoadAverage5m,
+			Timestamp:           host.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "HostLoadAverage15m",
+			Value:               host.LoadAverage15m,
+			Timestamp:           host.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "HostMemoryUsedPercent",
+			Value:               host.MemoryUsedPercent,
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitPercent,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "HostMemoryAvailableBytes",
+			Value:               float64(host.MemoryAvailableBytes),
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitBytes,
+			CollectToCloudwatch: true,
+		},
+	}
+
+	for _, networkInterface := range host.Interfaces {
+		dimensions := map[string]string{
+			"interface": networkInterface.Interface,
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "HostNetworkReceiveBytesPerSecond",
+			Dimensions:          dimensions,
+			Value:               networkInterface.ReceiveBytesPerSecond,
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitBytesPerSecond,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "HostNetworkTransmitBytesPerSecond",
+			Dimensions:          dimensions,
+			Value:               networkInterface.TransmitBytesPerSecond,
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitBytesPerSecond,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	for _, disk := range host.Disks {
+		dimensions := map[string]string{
+			"device": disk.Device,
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "HostDiskReadsPerSecond",
+			Dimensions:          dimensions,
+			Value:               disk.ReadsPerSecond,
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitCountPerSecond,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "HostDiskWritesPerSecond",
+			Dimensions:          dimensions,
+			Value:               disk.WritesPerSecond,
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitCountPerSecond,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "HostDiskReadLatencyMilliseconds",
+			Dimensions:          dimensions,
+			Value:               disk.ReadLatencyMilliseconds,
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitMilliseconds,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "HostDiskWriteLatencyMilliseconds",
+			Dimensions:          dimensions,
+			Value:               disk.WriteLatencyMilliseconds,
+			Timestamp:           host.SampledAt,
+			Unit:                metric.UnitMilliseconds,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	metrics = append(metrics, metric.Metric{
+		Name:      "Host",
+		Data:      host,
+		Timestamp: host.SampledAt,
+	})
+
+	return metrics
+}
+
+// consensusStateMetrics returns metrics for the progress of
+// the round of consensus the node is participating in
+func consensusStateMetrics(consensusState metric.ConsensusStateMetrics) []metric.Metric {
+	var frozen float64
+
+	if consensusState.Frozen {
+		frozen = 1
+	}
+
+	dimensions := map[string]string{
+		"node_id": consensusState.NodeId,
+	}
+
+	return []metric.Metric{
+		{
+			Name:                "ConsensusRound",
+			Dimensions:          dimensions,
+			Value:               float64(consensusState.Round),
+			Timestamp:           consensusState.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "ConsensusStep",
+			Dimensions:          dimensions,
+			Value:               float64(consensusState.Step),
+			Timestamp:           consensusState.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "ConsensusSecondsInStep",
+			Dimensions:          dimensions,
+			Value:               consensusState.SecondsInStep,
+			Timestamp:           consensusState.SampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "ConsensusFrozen",
+			Dimensions:          dimensions,
+			Value:               frozen,
+			Timestamp:           consensusState.SampledAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:       "ConsensusState",
+			Dimensions: dimensions,
+			Data:       consensusState,
+			Timestamp:  consensusState.SampledAt,
+		},
+	}
+}
+
+// configWarningMetrics returns a metric for each config
+// warning so that alarms can be raised for doctors running
+// with config that silently disables protection
+func configWarningMetrics(warnings []dconfig.ConfigWarning, sampledAt time.Time) []metric.Metric {
+	var metrics []metric.Metric
+
+	for _, warning := range warnings {
+		metrics = append(metrics, metric.Metric{
+			Name: "ConfigWarning",
+			Dimensions: map[string]string{
+				"setting": warning.Setting,
+			},
+			Data:                warning,
+			Value:               1,
+			Unit:                metric.UnitCount,
+			Timestamp:           sampledAt,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	return metrics
+}
+
+// endpointAddressMetrics returns metrics for the availability of
+// one of the addresses backing an endpoint and (if the probe of the
+// address succeeded) which node is serving the address
+func endpointAddressMetrics(addressMetrics metric.EndpointAddressMetrics) []metric.Metric {
+	metrics := []metric.Metric{
+		{
+			Name: "EndpointAddressUptime",
+			Dimensions: map[string]string{
+				"endpoint_url": addressMetrics.EndpointURL,
+				"address":      addressMetrics.Address,
+			},
+			Data:                addressMetrics,
+			Value:               float64(addressMetrics.RollingAveragePercentAvailable),
+			Unit:                metric.UnitPercent,
+			Timestamp:           addressMetrics.SampledAt,
+			CollectToCloudwatch: true,
+		},
+	}
+
+	if addressMetrics.NodeId != "" {
+		metrics = append(metrics, metric.Metric{
+			Name: "EndpointAddressNode",
+			Dimensions: map[string]string{
+				"endpoint_url": addressMetrics.EndpointURL,
+				"address":      addressMetrics.Address,
+				"node_id":      addressMetrics.NodeId,
+			},
+			Data:                addressMetrics,
+			Value:               1,
+			Unit:                metric.UnitNone,
+			Timestamp:           addressMetrics.SampledAt,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	return metrics
+}
+
+// healthCheckMetrics returns metrics for the result of a health
+// check: whether the node passed the check, how long the check
+// took and the value (if any) measured by the check
+func healthCheckMetrics(result checks.CheckResult) []metric.Metric {
+	var healthy float64
+
+	if result.Healthy {
+		healthy = 1
+	}
+
+	dimensions := map[string]string{
+		"check": result.Check,
+	}
+
+	metrics := []metric.Metric{
+		{
+			Name:                "HealthCheckHealthy",
+			Dimensions:          dimensions,
+			Data:                result,
+			Value:               healthy,
+			Unit:                metric.UnitNone,
+			Timestamp:           result.StartedAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "HealthCheckDurationMilliseconds",
+			Dimensions:          dimensions,
+			Value:               float64(result.Duration.Milliseconds()),
+			Unit:                metric.UnitMilliseconds,
+			Timestamp:           result.StartedAt,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "HealthCheckValue",
+			Dimensions:          dimensions,
+			Value:               result.Value,
+			Unit:                result.Unit,
+			Timestamp:           result.StartedAt,
+			CollectToCloudwatch: true,
+		},
+	}
+
+	if result.Healthy && result.MetricName != "" {
+		metrics = append(metrics, metric.Metric{
+			Name:                result.MetricName,
+			Dimensions:          dimensions,
+			Value:               result.Value,
+			Unit:                result.Unit,
+			Timestamp:           result.StartedAt,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	return metrics
+}
+
+// statusCheckAttemptsMetrics returns a metric for the number of
+// attempts made to get the status of the endpoint for the uptime
+// sample, where more than one attempt means requests were retried
+// after failing with transient errors
+func statusCheckAttemptsMetrics(uptimeMetric metric.UptimeMetric) []metric.Metric {
+	if uptimeMetric.StatusCheckAttempts == 0 {
+		return nil
+	}
+
+	return []metric.Metric{
+		{
+			Name: "StatusCheckAttempts",
+			Dimensions: map[string]string{
+				"endpoint_url": uptimeMetric.EndpointURL,
+			},
+			Value:               float64(uptimeMetric.StatusCheckAttempts),
+			Unit:                metric.UnitCount,
+			Timestamp:           uptimeMetric.SampledAt,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// uptimeProbeMetrics returns a metric for whether each endpoint
+// probed for the uptime sample responded, for telling apart the
+// node being down from the application it runs being down
+func uptimeProbeMetrics(uptimeMetric metric.UptimeMetric) []metric.Metric {
+	var metrics []metric.Metric
+
+	for _, probe := range uptimeMetric.Probes {
+		var up float64
+
+		if probe.Up {
+			up = 1
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name: "UptimeProbeUp",
+			Dimensions: map[string]string{
+				"endpoint_url": uptimeMetric.EndpointURL,
+				"probe":        probe.Probe,
+			},
+			Value:               up,
+			Unit:                metric.UnitNone,
+			Timestamp:           uptimeMetric.SampledAt,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	return metrics
+}
+
+// droppedLogMessagesMetrics returns a metric for the number of log
+// messages dropped (since last reported) because the display
+// wasn't reading them as fast as they were logged
+func droppedLogMessagesMetrics(dropped uint64, sampledAt time.Time) []metric.Metric {
+	return []metric.Metric{
+		{
+			Name:                "DroppedLogMessages",
+			Value:               float64(dropped),
+			Unit:                metric.UnitCount,
+			Timestamp:           sampledAt,
+			CollectToCloudwatch: true,
+		},
+	}
+}
+
+// droppedMessagesMetrics returns DoctorDroppedMessages metrics for the
+// number of messages dropped in total and on each channel (since last
+// reported) because the channel's reader fell behind, updating the
+// reported counts per channel to the dropped counts
+func droppedMessagesMetrics(dropped map[string]uint64, reported map[string]uint64, sampledAt time.Time) []metric.Metric {
+	var total uint64
+
+	var metrics []metric.Metric
+
+	for _, channel := range backpressure.Channels(dropped) {
+		sinceReported := dropped[channel] - reported[channel]
+		reported[channel] = dropped[channel]
+
+		total += sinceReported
+
+		metrics = append(metrics, metric.Metric{
+			Name: "DoctorDroppedMessages",
+			Dimensions: map[string]string{
+				"channel": channel,
+			},
+			Value:               float64(sinceReported),
+			Unit:                metric.UnitCount,
+			Timestamp:           sampledAt,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	// always report the total so alarms on it have data
+	return append(metrics, metric.Metric{
+		Name:                "DoctorDroppedMessages",
+		Value:               float64(total),
+		Unit:                metric.UnitCount,
+		Timestamp:           sampledAt,
+		CollectToCloudwatch: true,
+	})
+}
+
+// uptimeWindowMetrics returns metrics for the uptime of the endpoint
+// over each window (e.g. Uptime5m for a five minute window) and for
+// its exponentially weighted moving average uptime (UptimeEWMA) if
+// halfLife is positive, along with the calculated uptimes, skipping
+// any uptimes that can't be calculated
+func uptimeWindowMetrics(kavaEndpoint *endpoint.Endpoint, endpointURL string, windows []time.Duration, halfLife time.Duration, sampledAt time.Time) ([]metric.Metric, metric.UptimeWindowsMetric) {
+	var metrics []metric.Metric
+
+	dimensions := map[string]string{
+		"endpoint_url": endpointURL,
+	}
+
+	uptimeWindows := metric.UptimeWindowsMetric{
+		EndpointURL:              endpointURL,
+		PercentAvailableByWindow: make(map[string]float32),
+		SampledAt:                sampledAt,
+	}
+
+	for _, window := range windows {
+		uptime, err := kavaEndpoint.CalculateWindowedUptime(endpointURL, window)
+
+		if err != nil {
+			continue
+		}
+
+		uptimeWindows.PercentAvailableByWindow[formatUptimeWindow(window)] = uptime * 100
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "Uptime" + formatUptimeWindow(window),
+			Dimensions:          dimensions,
+			Value:               float64(uptime * 100),
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitPercent,
+			CollectToCloudwatch: true,
+		})
+	}
+
+	if halfLife > 0 {
+		uptime, err := kavaEndpoint.CalculateUptimeEWMA(endpointURL, halfLife)
+
+		if err == nil {
+			uptimePercent := uptime * 100
+			uptimeWindows.EWMAPercentAvailable = &uptimePercent
+
+			metrics = append(metrics, metric.Metric{
+				Name:                "UptimeEWMA",
+				Dimensions:          dimensions,
+				Value:               float64(uptimePercent),
+				Timestamp:           sampledAt,
+				Unit:                metric.UnitPercent,
+				CollectToCloudwatch: true,
+			})
+		}
+	}
+
+	if len(metrics) == 0 {
+		return nil, uptimeWindows
+	}
+
+	metrics = append(metrics, metric.Metric{
+		Name:                "UptimeWindows",
+		Dimensions:          dimensions,
+		Data:                uptimeWindows,
+		Timestamp:           sampledAt,
+		CollectToCloudwatch: false,
+	})
+
+	return metrics, uptimeWindows
+}
+
+// formatUptimeWindow formats the window in the largest whole
+// unit of hours, minutes or seconds, e.g. 5m or 24h
+func formatUptimeWindow(window time.Duration) string {
+	switch {
+	case window >= time.Hour && window%time.Hour == 0:
+		return fmt.Sprintf("%dh", window/time.Hour)
+	case window >= time.Minute && window%time.Minute == 0:
+		return fmt.Sprintf("%dm", window/time.Minute)
+	}
+
+	return fmt.Sprintf("%ds", window/time.Second)
+}
+
+// formatUptimeWindows formats the uptime over each window
+// from shortest to longest followed by the moving average
+func formatUptimeWindows(uptimeWindows metric.UptimeWindowsMetric, windows []time.Duration) string {
+	var formatted []string
+
+	for _, window := range windows {
+		if uptime, calculated := uptimeWindows.PercentAvailableByWindow[formatUptimeWindow(window)]; calculated {
+			formatted = append(formatted, fmt.Sprintf("%s %.2f%%", formatUptimeWindow(window), uptime))
+		}
+	}
+
+	if uptimeWindows.EWMAPercentAvailable != nil {
+		formatted = append(formatted, fmt.Sprintf("ewma %.2f%%", *uptimeWindows.EWMAPercentAvailable))
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+// sloMetrics returns metrics for the error budget of each
+// service level objective, including how much of the budget
+// remains and the rate it's burning at over the fast burn window
+func sloMetrics(statuses []slo.Status) []metric.Metric {
+	var metrics []metric.Metric
+
+	for _, status := range statuses {
+		dimensions := map[string]string{
+			"slo": status.Name,
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "ErrorBudgetRemainingPercent",
+			Dimensions:          dimensions,
+			Value:               status.ErrorBudgetRemainingPercent,
+			Timestamp:           status.EvaluatedAt,
+			Unit:                metric.UnitPercent,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "SLOCompliancePercent",
+			Dimensions:          dimensions,
+			Value:               status.CompliancePercent,
+			Timestamp:           status.EvaluatedAt,
+			Unit:                metric.UnitPercent,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "ErrorBudgetBurnRate",
+			Dimensions:          dimensions,
+			Value:               status.FastBurnRate,
+			Timestamp:           status.EvaluatedAt,
+			Unit:                metric.UnitNone,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "SLOStatus",
+			Dimensions:          dimensions,
+			Data:                status,
+			Timestamp:           status.EvaluatedAt,
+			CollectToCloudwatch: false,
+		})
+	}
+
+	return metrics
+}
+
+// formatSLOAlert formats the reason the error
+// budget of the objective is burning too fast
+func formatSLOAlert(status slo.Status) string {
+	burnRate := status.FastBurnRate
+
+	if status.Alert == slo.SlowBurnAlert {
+		burnRate = status.SlowBurnRate
+	}
+
+	return fmt.Sprintf("slo %s error budget burning %.1fx faster than sustainable (%s), %.1f%% of the budget remaining", status.Name, burnRate, status.Alert, status.ErrorBudgetRemainingPercent)
+}
+
+// nodeGroupRollupMetrics returns metrics rolling up the latest samples
+// of the members of each group the node (or endpoint) is a member of,
+// along with the calculated rollups, counting members more than
+// maxSecondsBehindLive behind live or down as unhealthy
+func nodeGroupRollupMetrics(kavaEndpoint *endpoint.Endpoint, groups []dconfig.NodeGroup, member string, maxSecondsBehindLive int64, sampledAt time.Time) ([]metric.Metric, []metric.NodeGroupRollupMetrics) {
+	var metrics []metric.Metric
+	var rollups []metric.NodeGroupRollupMetrics
+
+	for _, group := range groups {
+		var isMember bool
+
+		for _, groupMember := range group.Members {
+			if groupMember == member {
+				isMember = true
+
+				break
+			}
+		}
+
+		if !isMember {
+			continue
+		}
+
+		rollup := kavaEndpoint.CalculateNodeGroupRollup(group, maxSecondsBehindLive, sampledAt)
+
+		rollups = append(rollups, rollup)
+
+		dimensions := map[string]string{
+			"node_group": group.Name,
+		}
+
+		metrics = append(metrics, metric.Metric{
+			Name:                "NodeGroupWorstSecondsBehindLive",
+			Dimensions:          dimensions,
+			Value:               float64(rollup.WorstSecondsBehindLive),
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitSeconds,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:                "NodeGroupUnhealthyMembers",
+			Dimensions:          dimensions,
+			Value:               float64(rollup.UnhealthyMembers),
+			Timestamp:           sampledAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		}, metric.Metric{
+			Name:       "NodeGroupRollup",
+			Dimensions: dimensions,
+			Data:       rollup,
+			Timestamp:  sampledAt,
+		})
+
+		if rollup.MeanUptimePercent != nil {
+			metrics = append(metrics, metric.Metric{
+				Name:                "NodeGroupMeanUptime",
+				Dimensions:          dimensions,
+				Value:               *rollup.MeanUptimePercent,
+				Timestamp:           sampledAt,
+				Unit:                metric.UnitPercent,
+				CollectToCloudwatch: true,
+			})
+		}
+	}
+
+	return metrics, rollups
+}
+
+// formatNodeGroupRollup returns a one line summary of the rollup for display
+func formatNodeGroupRollup(rollup metric.NodeGroupRollupMetrics) string {
+	meanUptime := "unknown"
+
+	if rollup.MeanUptimePercent != nil {
+		meanUptime = fmt.Sprintf("%.1f%%", *rollup.MeanUptimePercent)
+	}
+
+	return fmt.Sprintf("group %s %d of %d members unhealthy (%d reporting), worst %d seconds behind live, mean uptime %s", rollup.GroupName, rollup.UnhealthyMembers, rollup.Members, rollup.ReportingMembers, rollup.WorstSecondsBehindLive, meanUptime)
+}
+
+// eventMetrics returns metrics counting the events
+// published to the event bus by type
+func eventMetrics(busEvent event.Event) []metric.Metric {
+	dimensions := map[string]string{
+		"event_type": busEvent.Type,
+	}
+
+	return []metric.Metric{
+		{
+			Name:                "Events",
+			Dimensions:          dimensions,
+			Value:               1,
+			Timestamp:           busEvent.OccurredAt,
+			Unit:                metric.UnitCount,
+			CollectToCloudwatch: true,
+		},
+		{
+			Name:                "Event",
+			Dimensions:          dimensions,
+			Data:                busEvent,
+			Timestamp:           busEvent.OccurredAt,
+			CollectToCloudwatch: false,
+		},
+	}
+}
+
+// formatEvent returns a one line description of the event for display
+func formatEvent(busEvent event.Event) string {
+	description := busEvent.Type
+
+	if busEvent.Source != "" {
+		description += " " + busEvent.Source
+	}
+
+	if busEvent.NodeId != "" {
+		description += " for node " + busEvent.NodeId
+	}
+
+	return description + ": " + busEvent.Message
+}
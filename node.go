@@ -8,12 +8,29 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/kava-labs/doctor/accesslog"
+	"github.com/kava-labs/doctor/annotation"
+	"github.com/kava-labs/doctor/anomaly"
+	"github.com/kava-labs/doctor/backpressure"
 	"github.com/kava-labs/doctor/clients/kava"
+	dconfig "github.com/kava-labs/doctor/config"
+	"github.com/kava-labs/doctor/event"
 	"github.com/kava-labs/doctor/heal"
+	"github.com/kava-labs/doctor/host"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
 	"github.com/kava-labs/doctor/metric"
+	"github.com/kava-labs/doctor/syncphase"
 )
 
 // NodeClientConfig wraps config
@@ -30,6 +47,164 @@ type NodeClientConfig struct {
 	HealthChecksTimeoutSeconds          int
 	NoNewBlocksRestartThresholdSeconds  int
 	DowntimeRestartThresholdSeconds     int
+	StaleResponseBehavior               string
+	// retries of requests to the endpoint that failed with a
+	// transient error, so they aren't counted as downtime
+	RPCMaxRetries               int
+	RPCRetryBackoffMilliseconds int
+	// credentials to send with requests to the endpoint,
+	// the reference endpoint and the upgrade api
+	RPCAuth          kava.Auth
+	ReferenceRPCAuth kava.Auth
+	UpgradeAPIAuth   kava.Auth
+	// proxy and tls settings for requests to all endpoints
+	Transport kava.TransportConfig
+	// status endpoint and fields of the chain the node is on
+	Status kava.StatusConfig
+	// endpoints (starting with the status) that must
+	// all respond for the node to be considered up
+	UptimeProbes []string
+	// optional url of an endpoint (e.g. a public rpc) to compare
+	// the node's block height against to determine if it's out of sync
+	ReferenceRPCEndpoint string
+	// blocks behind the reference endpoint above which the node
+	// is considered out of sync instead of using seconds behind live
+	AutohealBlocksBehindReferenceTolerance int
+	// optional access log of real client traffic to the node
+	// (e.g. from a proxy in front of the node's api) to observe
+	// error rates and latencies from
+	AccessLogFilepath string
+	AccessLogFormat   string
+	// optional file of annotations (e.g. deploys) to watch
+	AnnotationsFilepath string
+	// path the proc filesystem of the host is mounted at
+	// for sampling host metrics (e.g. memory usage)
+	HostProcPath string
+	// json rpc methods to probe the availability of
+	RPCProbeMethods []dconfig.RPCProbeMethod
+	// whether to check the sync status when new block events are
+	// received over the websocket interface instead of polling
+	PushNewBlocks bool
+	// urls reaching the same logical endpoint from other
+	// regions to compare the latency of the endpoint against
+	VantageEndpoints []dconfig.VantageEndpoint
+	// region the doctor is running in
+	Region string
+	// number of unconfirmed transactions above which the mempool
+	// is considered backed up once it has stayed above the threshold
+	// for the alert duration, 0 disables alerting
+	MempoolSizeAlertThreshold       int
+	MempoolSizeAlertDurationSeconds int
+	// seconds consensus can be stuck in the same height, round and
+	// step before it is considered frozen, 0 disables detection
+	ConsensusFrozenThresholdSeconds int
+	// whether to restart the blockchain service when consensus
+	// is frozen (independently of the no new blocks threshold)
+	AutohealFrozenConsensus bool
+	// modified z-score against the node's baseline of its previous
+	// samples above which status check latency and block production
+	// rate are considered anomalous, 0 disables detection
+	AnomalyZScoreThreshold float64
+	AnomalyBaselineSamples int
+	// optional file to save anomaly detection baselines to, so
+	// detection resumes without a warm-up after a restart
+	AnomalyBaselinesFilepath string
+	// average bytes per second below which a peer is considered
+	// low quality, 0 disables judging the quality of peers
+	PeerMinRecvBytesPerSecond float64
+	// known good persistent peers and seeds to reconnect a frozen
+	// node with poor peering to instead of only restarting it
+	AutohealRefreshPeers []string
+	AutohealRefreshSeeds []string
+	// optional publisher to send incident and heal events to
+	IncidentPublisher incident.Publisher
+	// optional bus to publish checks of the node failing and
+	// recovering and autohealing starting to
+	Events *event.Bus
+	// optional counter of metrics dropped because
+	// the display fell behind reading them
+	DroppedMessages *backpressure.Counter
+	// strategies to escalate through when healing out of sync nodes
+	AutohealStrategies             []dconfig.AutohealStrategy
+	AutohealEscalationDelaySeconds int
+	// minimum number of other healthy in service instances
+	// required in the autoscaling group to place the node on standby
+	AutohealStandbyMinHealthyInstances int
+	// max number of times to restart the blockchain service in
+	// any rolling hour and day before autohealing stops until
+	// manually reset, 0 allows unlimited restarts
+	AutohealMaxRestartsPerHour int
+	AutohealMaxRestartsPerDay  int
+	// home directory of the node, optional url of a snapshot and
+	// how far behind live the node must be for the restore-state
+	// strategy to restore the node's data
+	NodeHome                             string
+	AutohealSnapshotURL                  string
+	AutohealRestoreStateThresholdSeconds int64
+	// seconds a node restoring a state sync snapshot may go without
+	// synching a new block before it's restarted, 0 never restarts it
+	AutohealStateSyncThresholdSeconds int
+	// how the replace-instance strategy replaces the instance
+	AutohealReplaceMethod              string
+	AutohealReplaceMinHealthyInstances int
+	AutohealReplaceLifecycleHookName   string
+	// load balancer target groups and Route53 weighted record
+	// to take the node out of while it catches up
+	AutohealTargetGroupARNs      []string
+	AutohealRoute53HostedZoneId  string
+	AutohealRoute53RecordName    string
+	AutohealRoute53RecordType    string
+	AutohealRoute53SetIdentifier string
+	// command run by the exec-hook strategy and
+	// how long it may run for before being killed
+	AutohealExecHookCommand []string
+	ExecHookTimeoutSeconds  int
+	// service manager used to restart, stop and start the blockchain
+	// service, the command to run for the exec service manager, whether
+	// to use sudo and the optional socket of a privileged helper to ask
+	// to manage the service instead
+	AutohealServiceManager      string
+	AutohealRestartCommand      []string
+	AutohealServiceSudo         bool
+	AutohealServiceHelperSocket string
+	// commands run before each restart of the blockchain service
+	// and how long to wait for the node to come back up after it
+	AutohealPreRestartCommands         [][]string
+	AutohealRestartVerificationSeconds int
+	// optional id and/or moniker of the node the endpoint is expected
+	// to resolve to, autohealing is refused while it resolves to a
+	// different node (e.g. doctor pointed at a public load balancer)
+	ExpectedNodeId      string
+	ExpectedNodeMoniker string
+	// optional chain id the node is expected to be on, autohealing
+	// is refused while the node is on a different chain
+	ExpectedChainId string
+	// how often to compare the hashes of a recent block against
+	// the reference endpoint (if any), 0 disables the comparison
+	ChainConsistencyCheckIntervalSeconds int
+	// how often to compare the voting power of the validators signing
+	// blocks against the validator set, 0 disables the comparison, and
+	// the percent of voting power online below which the chain is
+	// considered degraded and autoheal restarts are suppressed
+	VotingPowerCheckIntervalSeconds int
+	ChainDegradedVotingPowerPercent float64
+	// optional url of the node's cosmos rest api to query for
+	// scheduled upgrades and/or the height of a scheduled upgrade,
+	// autohealing is suppressed from when the node halts for the
+	// upgrade until it produces blocks past the upgrade height or
+	// the upgrade window passes
+	UpgradeAPIAddress    string
+	UpgradeHeight        int64
+	UpgradeWindowSeconds int
+	// optional maintenance mode switch, autohealing
+	// is paused while in maintenance mode
+	Maintenance *Maintenance
+	// time zone and layout to format times in log and incident messages
+	TimeFormat logging.TimeFormat
+	// optional healer to use for healing out of sync nodes, if nil a
+	// chain of the configured autoheal strategies is created the
+	// first time autohealing is attempted
+	Healer heal.Healer
 }
 
 // NodeClient provides methods
@@ -37,100 +212,489 @@ type NodeClientConfig struct {
 // API and OS shell for a given node
 type NodeClient struct {
 	*kava.Client
-	config NodeClientConfig
+	// client for the reference endpoint, nil if not configured
+	referenceClient *kava.Client
+	// client for the cosmos rest api, nil if not configured
+	upgradeClient *kava.Client
+	config        NodeClientConfig
+	logger        *logging.Logger
+	// lazily initialized healer for out of sync nodes
+	healer     heal.Healer
+	healerLock *sync.Mutex
+	// caps how often the blockchain service is restarted
+	restartBreaker *heal.RestartBreaker
+	// restarts the blockchain service
+	serviceManager heal.ServiceManager
+	// set to 1 while systemd is repeatedly restarting
+	// the blockchain service, accessed atomically
+	serviceRestartLoop int32
+	// set to 1 while the endpoint resolves to a node other
+	// than the expected (pinned) node, accessed atomically
+	identityMismatch int32
+	// set to 1 while the node is on a chain other
+	// than the expected chain, accessed atomically
+	chainIdMismatch int32
+	// set to 1 while the node's block or app hashes differ
+	// from those of the reference node, accessed atomically
+	chainDiverged int32
+	// set to 1 while the node is halted for a scheduled
+	// upgrade, accessed atomically
+	upgradeInProgress int32
+	// set to 1 while most of the node's peers are
+	// low quality, accessed atomically
+	poorPeering int32
+	// set to 1 while the voting power online is below the
+	// degraded threshold, accessed atomically
+	chainDegraded int32
+	// held for reading by autoheal actions and exclusively by
+	// heals requested through the control api, so requested
+	// heals and autoheals don't race each other
+	healLock *sync.RWMutex
 }
 
+const (
+	// how long a peer must be connected for before
+	// the data it sends the node is judged
+	peerQualityGracePeriod = time.Minute
+)
+
+var (
+	ErrServiceRestartLoop   = errors.New("systemd is repeatedly restarting the service")
+	ErrNodeIdentityMismatch = errors.New("endpoint resolved to a node other than the expected node")
+	ErrChainDiverged        = errors.New("node's block or app hashes differ from the reference node")
+	ErrUpgradeInProgress    = errors.New("node is halted for a scheduled upgrade")
+	ErrMaintenanceMode      = errors.New("doctor is in maintenance mode")
+	ErrChainDegraded        = errors.New("chain is halted or at risk of halting")
+	ErrHealInProgress       = errors.New("node is being healed through the control api")
+)
+
 // NewNodeCLient creates and returns a new node client
-// using the provided configuration
-func NewNodeClient(config NodeClientConfig) (*NodeClient, error) {
+// using the provided configuration and logger
+func NewNodeClient(config NodeClientConfig, logger *logging.Logger) (*NodeClient, error) {
 	kavaClient, err := kava.New(kava.ClientConfig{
 		JSONRPCURL:             config.RPCEndpoint,
 		HTTPReadTimeoutSeconds: config.HealthChecksTimeoutSeconds,
+		MaxRetries:             config.RPCMaxRetries,
+		RetryBackoff:           time.Duration(config.RPCRetryBackoffMilliseconds) * time.Millisecond,
+		Auth:                   config.RPCAuth,
+		Transport:              config.Transport,
+		Status:                 config.Status,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: could not initialize kava client", err)
+	}
+
+	var referenceClient *kava.Client
+
+	if config.ReferenceRPCEndpoint != "" {
+		referenceClient, err = kava.New(kava.ClientConfig{
+			JSONRPCURL:             config.ReferenceRPCEndpoint,
+			HTTPReadTimeoutSeconds: config.HealthChecksTimeoutSeconds,
+			MaxRetries:             config.RPCMaxRetries,
+			RetryBackoff:           time.Duration(config.RPCRetryBackoffMilliseconds) * time.Millisecond,
+			Auth:                   config.ReferenceRPCAuth,
+			Transport:              config.Transport,
+			Status:                 config.Status,
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not initialize reference client", err)
+		}
+	}
+
+	var upgradeClient *kava.Client
+
+	if config.UpgradeAPIAddress != "" {
+		upgradeClient, err = kava.New(kava.ClientConfig{
+			JSONRPCURL:             config.UpgradeAPIAddress,
+			HTTPReadTimeoutSeconds: config.HealthChecksTimeoutSeconds,
+			MaxRetries:             config.RPCMaxRetries,
+			RetryBackoff:           time.Duration(config.RPCRetryBackoffMilliseconds) * time.Millisecond,
+			Auth:                   config.UpgradeAPIAuth,
+			Transport:              config.Transport,
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: could not initialize upgrade client", err)
+		}
+	}
+
+	serviceManager, err := heal.NewServiceManager(heal.ServiceManagerConfig{
+		Manager:            config.AutohealServiceManager,
+		ServiceName:        config.AutohealBlockchainServiceName,
+		RestartCommand:     config.AutohealRestartCommand,
+		Sudo:               config.AutohealServiceSudo,
+		HelperSocket:       config.AutohealServiceHelperSocket,
+		PreRestartCommands: config.AutohealPreRestartCommands,
+		PreRestartTimeout:  time.Duration(config.ExecHookTimeoutSeconds) * time.Second,
 	})
 
 	if err != nil {
-		panic(fmt.Errorf("%w: could not initialize kava client", err))
+		return nil, fmt.Errorf("%w: could not initialize service manager", err)
 	}
 
 	return &NodeClient{
-		config: config,
-		Client: kavaClient,
+		serviceManager:  serviceManager,
+		referenceClient: referenceClient,
+		upgradeClient:   upgradeClient,
+		config:          config,
+		Client:          kavaClient,
+		logger:          logger.With(logging.Fields{"endpoint": config.RPCEndpoint}),
+		healer:          config.Healer,
+		healerLock:      &sync.Mutex{},
+		healLock:        &sync.RWMutex{},
+		restartBreaker: heal.NewRestartBreaker(heal.RestartBreakerConfig{
+			MaxRestartsPerHour: config.AutohealMaxRestartsPerHour,
+			MaxRestartsPerDay:  config.AutohealMaxRestartsPerDay,
+		}),
 	}, nil
 }
 
+// getHealer returns the healer to use for healing out of sync nodes,
+// creating a chain of the configured autoheal strategies on first use
+// if no healer was configured, returning error (if any) if the healer
+// can't be created
+func (nc *NodeClient) getHealer() (heal.Healer, error) {
+	nc.healerLock.Lock()
+	defer nc.healerLock.Unlock()
+
+	if nc.healer != nil {
+		return nc.healer, nil
+	}
+
+	var strategies []heal.ChainedStrategy
+
+	for _, autohealStrategy := range nc.config.AutohealStrategies {
+		strategy, err := heal.NewStrategy(autohealStrategy.Name, heal.StrategyConfig{
+			BlockchainServiceName:        nc.config.AutohealBlockchainServiceName,
+			Blocked:                      nc.healBlocked,
+			ServiceManager:               nc.serviceManager,
+			RestartBreaker:               nc.restartBreaker,
+			RestartVerificationTimeout:   time.Duration(nc.config.AutohealRestartVerificationSeconds) * time.Second,
+			NodeHome:                     nc.config.NodeHome,
+			SnapshotURL:                  nc.config.AutohealSnapshotURL,
+			RestoreStateThresholdSeconds: nc.config.AutohealRestoreStateThresholdSeconds,
+			ReplaceMethod:                nc.config.AutohealReplaceMethod,
+			ReplaceMinHealthyInstances:   nc.config.AutohealReplaceMinHealthyInstances,
+			ReplaceLifecycleHookName:     nc.config.AutohealReplaceLifecycleHookName,
+			TargetGroupARNs:              nc.config.AutohealTargetGroupARNs,
+			Route53HostedZoneId:          nc.config.AutohealRoute53HostedZoneId,
+			Route53RecordName:            nc.config.AutohealRoute53RecordName,
+			Route53RecordType:            nc.config.AutohealRoute53RecordType,
+			Route53SetIdentifier:         nc.config.AutohealRoute53SetIdentifier,
+			ExecHookCommand:              nc.config.AutohealExecHookCommand,
+			ExecHookTimeout:              time.Duration(nc.config.ExecHookTimeoutSeconds) * time.Second,
+			RefreshPeers:                 nc.config.AutohealRefreshPeers,
+			RefreshSeeds:                 nc.config.AutohealRefreshSeeds,
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("error %s creating autoheal strategy %s", err, autohealStrategy.Name)
+		}
+
+		strategies = append(strategies, heal.ChainedStrategy{
+			Strategy:    strategy,
+			MaxAttempts: autohealStrategy.MaxAttempts,
+		})
+	}
+
+	nc.healer = heal.NewStrategyChain(heal.StrategyChainConfig{
+		Strategies:      strategies,
+		EscalationDelay: time.Duration(nc.config.AutohealEscalationDelaySeconds) * time.Second,
+		Blocked:         nc.healBlocked,
+	})
+
+	return nc.healer, nil
+}
+
+// referenceBlockHeight wraps the result of
+// polling the reference endpoint's block height
+type referenceBlockHeight struct {
+	height int64
+	err    error
+}
+
+// getReferenceBlockHeight polls the reference endpoint's block height in
+// the background, returning a channel the result will be sent to, which
+// is closed without a result if no reference endpoint is configured
+func (nc *NodeClient) getReferenceBlockHeight() <-chan referenceBlockHeight {
+	result := make(chan referenceBlockHeight, 1)
+
+	if nc.referenceClient == nil {
+		close(result)
+
+		return result
+	}
+
+	go func() {
+		referenceState, err := nc.referenceClient.GetNodeState()
+
+		result <- referenceBlockHeight{
+			height: referenceState.SyncInfo.LatestBlockHeight,
+			err:    err,
+		}
+	}()
+
+	return result
+}
+
+// syncStatusChecks returns a channel that triggers a check of the node's
+// sync status each tick of the ticker, or if push new blocks is enabled
+// whenever a new block event is received over the websocket interface,
+// only falling back to the ticker for intervals without any new block
+// events (e.g. while the subscription is down or the node is frozen)
+func (nc *NodeClient) syncStatusChecks(ctx context.Context, ticker *time.Ticker) <-chan time.Time {
+	if !nc.config.PushNewBlocks {
+		return ticker.C
+	}
+
+	checks := make(chan time.Time)
+	newBlockEvents := make(chan kava.NewBlockEvent)
+
+	go nc.subscribeNewBlockEvents(ctx, newBlockEvents)
+
+	go func() {
+		var receivedSinceTick bool
+
+		for {
+			var checkAt time.Time
+
+			select {
+			case <-ctx.Done():
+				return
+			case event := <-newBlockEvents:
+				receivedSinceTick = true
+				checkAt = event.ReceivedAt
+			case tick := <-ticker.C:
+				// the node was already checked for the new blocks
+				if receivedSinceTick {
+					receivedSinceTick = false
+
+					continue
+				}
+
+				checkAt = tick
+			}
+
+			select {
+			case checks <- checkAt:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return checks
+}
+
+// subscribeNewBlockEvents subscribes (until the context is cancelled)
+// to new block events over the node's websocket interface, sending
+// each event to the provided channel and resubscribing each
+// monitoring interval after the subscription drops
+func (nc *NodeClient) subscribeNewBlockEvents(ctx context.Context, newBlockEvents chan<- kava.NewBlockEvent) {
+	for {
+		subscription, err := nc.SubscribeNewBlocks(ctx)
+
+		if err != nil {
+			nc.logger.Warnf("error %s subscribing to new block events, polling sync status instead", err)
+		} else {
+			nc.logger.Debugf("subscribed to new block events")
+
+			for {
+				event, err := subscription.Next()
+
+				if errors.Is(err, kava.ErrSubscriptionClosed) {
+					return
+				}
+
+				if err != nil {
+					nc.logger.Warnf("subscription to new block events dropped with error %s, polling sync status instead", err)
+
+					subscription.Close()
+
+					break
+				}
+
+				select {
+				case newBlockEvents <- event:
+				case <-ctx.Done():
+					subscription.Close()
+
+					return
+				}
+			}
+		}
+
+		// wait before resubscribing
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second):
+		}
+	}
+}
+
+// NodeClientControl adjusts the monitoring interval and autohealing
+// thresholds used by a running WatchSyncStatus routine, so they can be
+// tuned without restarting the doctor and losing accumulated samples
+type NodeClientControl struct {
+	MonitoringIntervalSeconds           int
+	AutohealSyncLatencyToleranceSeconds int
+	DowntimeRestartThresholdSeconds     int
+	NoNewBlocksRestartThresholdSeconds  int
+	AutohealRestartDelaySeconds         int
+}
+
+// apply updates the config with the adjusted values
+func (c NodeClientControl) apply(config *NodeClientConfig) {
+	config.DefaultMonitoringIntervalSeconds = c.MonitoringIntervalSeconds
+	config.AutohealSyncLatencyToleranceSeconds = c.AutohealSyncLatencyToleranceSeconds
+	config.DowntimeRestartThresholdSeconds = c.DowntimeRestartThresholdSeconds
+	config.NoNewBlocksRestartThresholdSeconds = c.NoNewBlocksRestartThresholdSeconds
+	config.AutohealRestartDelaySeconds = c.AutohealRestartDelaySeconds
+}
+
 // WatchSyncStatus watches  (until the context is cancelled)
 // the sync status for the node and sends any new data to the provided channel.
-func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics chan<- metric.SyncStatusMetrics, uptimeMetrics chan<- metric.UptimeMetric, logMessages chan<- string) {
-	// create channel that will emit
+// The monitoring interval and autohealing thresholds are adjusted
+// whenever a control is received on the (optional) controls channel.
+// Once the context is cancelled WatchSyncStatus waits for any in progress
+// autohealing actions to finish or abort before returning.
+func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics chan<- metric.SyncStatusMetrics, uptimeMetrics chan<- metric.UptimeMetric, controls <-chan NodeClientControl) {
+	// copy of the settings adjustable while running,
+	// only accessed from this routine
+	settings := nc.config
+
+	// create ticker that will emit
 	// an event every DefaultMonitoringIntervalSeconds seconds
-	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+	ticker := time.NewTicker(time.Duration(settings.DefaultMonitoringIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	statusChecks := nc.syncStatusChecks(ctx, ticker)
 
 	var outOfSyncAutohealingInProgress bool
+	var autohealingRoutines sync.WaitGroup
 	var lastRestartedByAutohealingAt *time.Time
 	lastNewBlockObservedAt := time.Now()
 	var lastSynchedBlockNumber int64
 	var currentDowntimeStartedAt *time.Time
+	// newest block time seen per node, for
+	// detecting stale (e.g. cached) responses
+	latestBlockTimes := make(map[string]time.Time)
+	syncPhases := syncphase.NewDetector()
+	incidents := incident.NewTracker(nc.config.RPCEndpoint)
+
+	var anomalies *anomaly.Detector
+	// last new block seen per node, for
+	// measuring the rate blocks are produced at
+	lastNewBlocks := make(map[string]kava.SyncInfo)
+
+	var anomalyBaselinesSavedAt time.Time
+
+	if nc.config.AnomalyZScoreThreshold > 0 {
+		anomalies = anomaly.NewDetector(nc.config.AnomalyZScoreThreshold, nc.config.AnomalyBaselineSamples)
+
+		nc.restoreAnomalyBaselines(anomalies)
+	}
 
 	earliestAllowedRestartTime := time.Now().Add(time.Duration(nc.config.AutohealInitialAllowedDelaySeconds) * time.Second)
 
 	for {
 		select {
 		case <-ctx.Done():
+			nc.saveAnomalyBaselines(anomalies)
+
+			autohealingRoutines.Wait()
+
 			return
-		case <-ticker:
+		case control := <-controls:
+			if control.MonitoringIntervalSeconds <= 0 {
+				nc.logger.Warnf("ignoring invalid monitoring interval of %d seconds", control.MonitoringIntervalSeconds)
+
+				continue
+			}
+
+			if control.MonitoringIntervalSeconds != settings.DefaultMonitoringIntervalSeconds {
+				ticker.Reset(time.Duration(control.MonitoringIntervalSeconds) * time.Second)
+			}
+
+			control.apply(&settings)
+
+			nc.logger.Infof("adjusted sync status monitoring to %+v", control)
+		case <-statusChecks:
 			// get the current sync status of the node
 			// timing how long it takes for the node
 			// to respond to the request as well
 			statusCheckStartedAt := time.Now()
-			nodeState, err := nc.GetNodeState()
+			// poll the reference endpoint (if any) in parallel with the
+			// node so both block heights are sampled at the same time
+			referenceBlockHeight := nc.getReferenceBlockHeight()
+			nodeState, attempts, err := nc.GetNodeStateAttempts()
 			statusCheckEndedAt := time.Now()
 
+			// the node answering status requests doesn't mean
+			// the application it runs is alive, it's only up if
+			// the other probed endpoints respond as well
+			probes, err := nc.probeUptime(err)
+
 			uptimeMetric := metric.UptimeMetric{
-				EndpointURL: nc.config.RPCEndpoint,
-				SampledAt:   statusCheckStartedAt,
-				Up:          true,
+				EndpointURL:         nc.config.RPCEndpoint,
+				SampledAt:           statusCheckStartedAt,
+				Up:                  true,
+				StatusCheckAttempts: attempts,
+				Probes:              probes,
+			}
+
+			if err == nil && attempts > 1 {
+				nc.logger.Debugf("got node status after %d attempts", attempts)
 			}
 
 			if err != nil {
 				// send uptime metric to metric collector
 				// for aggregation and storage
 				uptimeMetric.Up = false
-				// log error, but don't block the monitoring
-				// routine if the logMessage channel is full
-				go func() {
-					logMessages <- fmt.Sprintf("error %s getting node status", err)
-					uptimeMetrics <- uptimeMetric
-				}()
+				nc.logger.Errorf("error %s probing node", err)
+
+				// don't block the monitoring routine
+				// if the display isn't reading metrics
+				nc.sendUptimeMetric(uptimeMetrics, uptimeMetric)
 
 				// if this is the first time the api was unavailable
 				// or it went down after being restarted
 				// set the start of the downtime window
 				if currentDowntimeStartedAt == nil {
-					logMessages <- fmt.Sprintf("node went offline at %+v", statusCheckStartedAt)
+					nc.logger.Warnf("node went offline at %s", nc.config.TimeFormat.Format(statusCheckStartedAt))
 					downtimeStartedAt := statusCheckStartedAt
 					currentDowntimeStartedAt = &downtimeStartedAt
 				}
+
+				if event, opened := incidents.Open(incident.NodeOfflineIncident, "", fmt.Sprintf("node went offline with error %s", err), statusCheckStartedAt); opened {
+					nc.publishIncidentEvent(event)
+				}
 				// TODO: refactor into node.AutohealOfflineNode()
 				if nc.config.Autoheal {
 					// check if the downtime deserves a restart
 					downtimeDuration := statusCheckStartedAt.Sub(*currentDowntimeStartedAt)
-					logMessages <- fmt.Sprintf("node has been down for %+v downtime threshold seconds %v, restart delay seconds %d", downtimeDuration, nc.config.DowntimeRestartThresholdSeconds, nc.config.AutohealRestartDelaySeconds)
+					nc.logger.Infof("node has been down for %+v downtime threshold seconds %v, restart delay seconds %d", downtimeDuration, settings.DowntimeRestartThresholdSeconds, settings.AutohealRestartDelaySeconds)
 
 					// if the node was previously restarted
 					// don't restart until AutohealRestartDelaySeconds have passed
 					if lastRestartedByAutohealingAt != nil {
-						if downtimeDuration < time.Duration(time.Duration(nc.config.AutohealRestartDelaySeconds)*time.Second) {
-							logMessages <- fmt.Sprintf("not restarting offline node, current downtime %v last restarted %f seconds ago at %v restart delay seconds %d", downtimeDuration, time.Since(*lastRestartedByAutohealingAt).Seconds(), lastRestartedByAutohealingAt, nc.config.AutohealRestartDelaySeconds)
+						if downtimeDuration < time.Duration(time.Duration(settings.AutohealRestartDelaySeconds)*time.Second) {
+							nc.logger.Infof("not restarting offline node, current downtime %v last restarted %f seconds ago at %s restart delay seconds %d", downtimeDuration, time.Since(*lastRestartedByAutohealingAt).Seconds(), nc.config.TimeFormat.Format(*lastRestartedByAutohealingAt), settings.AutohealRestartDelaySeconds)
 
 							// keep checking the health of the endpoint
 							continue
 						}
 
 						// restart the node
+						nc.publishAutohealStarted("", incident.NodeOfflineIncident, fmt.Sprintf("restarting node offline for %v", downtimeDuration))
+
 						err = nc.RestartBlockchainService()
 
-						if err != nil {
-							logMessages <- fmt.Sprintf("error %s restarting node", err)
+						if err != nil && !errors.Is(err, heal.ErrRestartNotVerified) {
+							nc.logger.Errorf("error %s restarting node", err)
 							// keep checking the health of the endpoint
 							continue
 						}
@@ -139,7 +703,7 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 						now := time.Now()
 						lastRestartedByAutohealingAt = &now
 
-						logMessages <- fmt.Sprintf("restarted node at %v", lastRestartedByAutohealingAt)
+						nc.logger.Warnf("restarted node at %s", nc.config.TimeFormat.Format(now))
 
 						// reset downtime clock
 						currentDowntimeStartedAt = nil
@@ -149,14 +713,16 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 					}
 
 					// otherwise only restart the node if it's been down long enough
-					if downtimeDuration > time.Duration(time.Duration(nc.config.DowntimeRestartThresholdSeconds)*time.Second) {
+					if downtimeDuration > time.Duration(time.Duration(settings.DowntimeRestartThresholdSeconds)*time.Second) {
 						// this is the first time the node is being restarted
 						// for the current downtime window
 						// restart the node
+						nc.publishAutohealStarted("", incident.NodeOfflineIncident, fmt.Sprintf("restarting node offline for %v", downtimeDuration))
+
 						err = nc.RestartBlockchainService()
 
-						if err != nil {
-							logMessages <- fmt.Sprintf("error %s restarting node", err)
+						if err != nil && !errors.Is(err, heal.ErrRestartNotVerified) {
+							nc.logger.Errorf("error %s restarting node", err)
 							// keep checking the health of the endpoint
 							continue
 						}
@@ -165,7 +731,7 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 						now := time.Now()
 						lastRestartedByAutohealingAt = &now
 
-						logMessages <- fmt.Sprintf("restarted node at %v", lastRestartedByAutohealingAt)
+						nc.logger.Warnf("restarted node at %s", nc.config.TimeFormat.Format(now))
 
 						// reset downtime clock
 						currentDowntimeStartedAt = nil
@@ -174,7 +740,7 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 						continue
 					}
 
-					logMessages <- fmt.Sprintf("not restarting node, down for %v seconds, downtime threshold seconds %v", downtimeDuration, nc.config.DowntimeRestartThresholdSeconds)
+					nc.logger.Infof("not restarting node, down for %v seconds, downtime threshold seconds %v", downtimeDuration, settings.DowntimeRestartThresholdSeconds)
 
 				}
 
@@ -182,6 +748,49 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 				continue
 			}
 
+			// acting on a node other than the one doctor is meant to be
+			// watching could take down an unrelated (e.g. public) node
+			if mismatch := nc.checkNodeIdentity(nodeState.NodeInfo); mismatch != "" {
+				atomic.StoreInt32(&nc.identityMismatch, 1)
+
+				if event, opened := incidents.Open(incident.NodeIdentityMismatchIncident, nodeState.NodeInfo.Id, mismatch, statusCheckEndedAt); opened {
+					nc.logger.Errorf("%s, refusing to autoheal until the endpoint resolves to the expected node", mismatch)
+					nc.publishIncidentEvent(event)
+				}
+			} else {
+				atomic.StoreInt32(&nc.identityMismatch, 0)
+
+				if event, closed := incidents.Close(incident.NodeIdentityMismatchIncident, "endpoint resolved to the expected node", statusCheckEndedAt); closed {
+					nc.logger.Infof("endpoint resolved to the expected node %s again", nodeState.NodeInfo.Id)
+					nc.publishIncidentEvent(event)
+				}
+			}
+
+			// restarting a node pointed at a testnet or a fork
+			// won't bring it onto the expected chain
+			chainIdErr := nodeState.NodeInfo.ValidateChainId(nc.config.ExpectedChainId)
+
+			if chainIdErr != nil {
+				atomic.StoreInt32(&nc.chainIdMismatch, 1)
+
+				if event, opened := incidents.Open(incident.ChainIdMismatchIncident, nodeState.NodeInfo.Id, chainIdErr.Error(), statusCheckEndedAt); opened {
+					nc.logger.Errorf("%s, refusing to autoheal until the node is on the expected chain", chainIdErr)
+					nc.publishIncidentEvent(event)
+				}
+			} else {
+				atomic.StoreInt32(&nc.chainIdMismatch, 0)
+
+				if event, closed := incidents.Close(incident.ChainIdMismatchIncident, "node is on the expected chain", statusCheckEndedAt); closed {
+					nc.logger.Infof("node %s is on the expected chain %s again", nodeState.NodeInfo.Id, nodeState.NodeInfo.Network)
+					nc.publishIncidentEvent(event)
+				}
+			}
+
+			if event, closed := incidents.Close(incident.NodeOfflineIncident, "node is back online", statusCheckEndedAt); closed {
+				nc.logger.Infof("node is back online at %s", nc.config.TimeFormat.Format(statusCheckEndedAt))
+				nc.publishIncidentEvent(event)
+			}
+
 			var secondsBehindLive int64
 			currentSyncTime := nodeState.SyncInfo.LatestBlockTime
 			currentBlockNumber := nodeState.SyncInfo.LatestBlockHeight
@@ -193,68 +802,280 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 				SyncStatus:                nodeState.SyncInfo,
 				SampleLatencyMilliseconds: statusCheckEndedAt.Sub(statusCheckStartedAt).Milliseconds(),
 				SecondsBehindLive:         secondsBehindLive,
+				ChainId:                   nodeState.NodeInfo.Network,
+				ChainIdMismatch:           chainIdErr != nil,
+			}
+
+			if nc.config.Autoheal {
+				restartBreakerTripped := nc.inRestartBreak()
+				metrics.AutohealCircuitBreakerTripped = &restartBreakerTripped
+
+				if restartBreakerTripped {
+					if event, opened := incidents.Open(incident.AutohealCircuitBreakerIncident, nodeState.NodeInfo.Id, "autoheal restart cap reached, autohealing stopped until reset", statusCheckEndedAt); opened {
+						nc.logger.Errorf("autoheal restart cap reached, autohealing stopped until reset using the %s command or SIGUSR2", ResetAutohealCommand)
+						nc.publishIncidentEvent(event)
+					}
+				} else if event, closed := incidents.Close(incident.AutohealCircuitBreakerIncident, "autohealing was reset", statusCheckEndedAt); closed {
+					nc.logger.Infof("autohealing was reset, resuming autohealing")
+					nc.publishIncidentEvent(event)
+				}
+			}
+
+			logger := nc.logger.With(logging.Fields{"node_id": nodeState.NodeInfo.Id})
+
+			if reference, polled := <-referenceBlockHeight; polled {
+				if reference.err != nil {
+					logger.Warnf("error %s getting reference endpoint %s status", reference.err, nc.config.ReferenceRPCEndpoint)
+				} else {
+					blocksBehindReference := reference.height - currentBlockNumber
+					metrics.BlocksBehindReference = &blocksBehindReference
+				}
+			}
+
+			// comparing against a reference chain head is a less noisy
+			// signal of the node falling behind than comparing block
+			// times to the local clock, so prefer it when available
+			outOfSync := secondsBehindLive > int64(settings.AutohealSyncLatencyToleranceSeconds)
+			syncLag := fmt.Sprintf("%d seconds behind live", secondsBehindLive)
+
+			if metrics.BlocksBehindReference != nil && nc.config.AutohealBlocksBehindReferenceTolerance > 0 {
+				outOfSync = *metrics.BlocksBehindReference > int64(nc.config.AutohealBlocksBehindReferenceTolerance)
+				syncLag = fmt.Sprintf("%d blocks behind reference", *metrics.BlocksBehindReference)
+			}
+
+			// caching proxies in front of the endpoint may serve a
+			// previously cached response, which would corrupt any
+			// measurements of how far behind live the node is
+			if latestBlockTime, seen := latestBlockTimes[nodeState.NodeInfo.Id]; seen && currentSyncTime.Before(latestBlockTime) {
+				metrics.Stale = true
+			} else {
+				latestBlockTimes[nodeState.NodeInfo.Id] = currentSyncTime
+
+				if anomalies != nil {
+					nc.observeAnomalies(anomalies, logger, nodeState.NodeInfo.Id, anomaly.StatusLatencySignal, float64(metrics.SampleLatencyMilliseconds), "milliseconds", statusCheckEndedAt)
+
+					// measured using block times rather than when blocks were
+					// sampled, so the rate doesn't depend on the sampling interval
+					lastNewBlock, seen := lastNewBlocks[nodeState.NodeInfo.Id]
+
+					if !seen || currentBlockNumber > lastNewBlock.LatestBlockHeight {
+						lastNewBlocks[nodeState.NodeInfo.Id] = nodeState.SyncInfo
+					}
+
+					if seen && currentBlockNumber > lastNewBlock.LatestBlockHeight && currentSyncTime.After(lastNewBlock.LatestBlockTime) {
+						blocksPerMinute := float64(currentBlockNumber-lastNewBlock.LatestBlockHeight) / currentSyncTime.Sub(lastNewBlock.LatestBlockTime).Minutes()
+
+						nc.observeAnomalies(anomalies, logger, nodeState.NodeInfo.Id, anomaly.BlockRateSignal, blocksPerMinute, "blocks per minute", statusCheckEndedAt)
+					}
+
+					if statusCheckEndedAt.Sub(anomalyBaselinesSavedAt) >= anomaly.StateSaveInterval {
+						nc.saveAnomalyBaselines(anomalies)
+
+						anomalyBaselinesSavedAt = statusCheckEndedAt
+					}
+				}
+
+				var previousSyncPhase string
+
+				metrics.SyncPhase, previousSyncPhase = syncPhases.Observe(nodeState.NodeInfo.Id, nodeState.SyncInfo, statusCheckEndedAt)
+
+				if previousSyncPhase != "" && previousSyncPhase != metrics.SyncPhase {
+					logger.Infof("node sync phase changed from %s to %s at block %d", previousSyncPhase, metrics.SyncPhase, currentBlockNumber)
+
+					nc.publishIncidentEvent(incident.Event{
+						Type:        incident.SyncPhaseChangedEventType,
+						Kind:        metrics.SyncPhase,
+						NodeId:      nodeState.NodeInfo.Id,
+						EndpointURL: nc.config.RPCEndpoint,
+						Message:     fmt.Sprintf("node sync phase changed from %s to %s at block %d", previousSyncPhase, metrics.SyncPhase, currentBlockNumber),
+						OccurredAt:  statusCheckEndedAt,
+					})
+				}
+			}
+
+			// no blocks are synched while a state sync snapshot is being
+			// restored, which can legitimately take much longer than a
+			// node going without new blocks once it's replaying blocks
+			noNewBlocksRestartThreshold := time.Duration(settings.NoNewBlocksRestartThresholdSeconds) * time.Second
+			checkFrozen := true
+
+			if metrics.SyncPhase == syncphase.StateSync {
+				noNewBlocksRestartThreshold = time.Duration(nc.config.AutohealStateSyncThresholdSeconds) * time.Second
+				checkFrozen = nc.config.AutohealStateSyncThresholdSeconds > 0
 			}
 
-			go func() {
-				logMessages <- fmt.Sprintf("node state %+v", nodeState)
-				syncStatusMetrics <- metrics
-				uptimeMetrics <- uptimeMetric
-			}()
+			logger.Debugf("node state %+v", nodeState)
+
+			nc.sendSyncStatusMetrics(syncStatusMetrics, metrics)
+			nc.sendUptimeMetric(uptimeMetrics, uptimeMetric)
+
+			if metrics.Stale {
+				logger.Warnf("node returned stale status with block time %s older than previously seen block time %s", nc.config.TimeFormat.Format(currentSyncTime), nc.config.TimeFormat.Format(latestBlockTimes[nodeState.NodeInfo.Id]))
+
+				// don't make autohealing decisions based off stale data
+				if nc.config.StaleResponseBehavior == dconfig.StaleResponseBehaviorDiscard {
+					continue
+				}
+			}
 
 			// if the node has synched any new blocks since the last block
 			if currentBlockNumber > lastSynchedBlockNumber {
 				// update frozen node health indicator
 				lastNewBlockObservedAt = statusCheckEndedAt
-				logMessages <- "node has synched new blocks since last check"
+				logger.Debug("node has synched new blocks since last check")
+
+				if event, closed := incidents.Close(incident.NodeFrozenIncident, fmt.Sprintf("node synched new block %d", currentBlockNumber), statusCheckEndedAt); closed {
+					nc.publishIncidentEvent(event)
+				}
 			} else {
-				logMessages <- fmt.Sprintf("node has been frozen for %f seconds since %v\n NoNewBlocksRestartThresholdSeconds %d", statusCheckEndedAt.Sub(lastNewBlockObservedAt).Seconds(), lastNewBlockObservedAt, nc.config.NoNewBlocksRestartThresholdSeconds)
+				logger.Debugf("node has been frozen for %f seconds since %s\n no new blocks restart threshold %v in sync phase %s", statusCheckEndedAt.Sub(lastNewBlockObservedAt).Seconds(), nc.config.TimeFormat.Format(lastNewBlockObservedAt), noNewBlocksRestartThreshold, metrics.SyncPhase)
+
+				if checkFrozen && statusCheckEndedAt.Sub(lastNewBlockObservedAt) > noNewBlocksRestartThreshold {
+					if event, opened := incidents.Open(incident.NodeFrozenIncident, nodeState.NodeInfo.Id, fmt.Sprintf("node has not synched a new block since %s", nc.config.TimeFormat.Format(lastNewBlockObservedAt)), statusCheckEndedAt); opened {
+						nc.publishIncidentEvent(event)
+					}
+				}
+			}
+
+			// track incidents of the node falling behind live
+			// independently of whether autohealing is enabled
+			if outOfSync {
+				if event, opened := incidents.Open(incident.NodeOutOfSyncIncident, nodeState.NodeInfo.Id, fmt.Sprintf("node is %s", syncLag), statusCheckEndedAt); opened {
+					nc.publishIncidentEvent(event)
+				}
+			} else if event, closed := incidents.Close(incident.NodeOutOfSyncIncident, fmt.Sprintf("node caught back up to %s", syncLag), statusCheckEndedAt); closed {
+				nc.publishIncidentEvent(event)
+			}
+
+			if nc.config.Autoheal && nc.inIdentityMismatch() {
+				logger.Debugf("not autohealing node %s, endpoint resolved to a node other than the expected node", nodeState.NodeInfo.Id)
+
+				// update frozen node health indicator
+				lastSynchedBlockNumber = currentBlockNumber
+
+				continue
+			}
+
+			if nc.config.Autoheal && nc.inMaintenance() {
+				logger.Debugf("not autohealing node %s, doctor is in maintenance mode", nodeState.NodeInfo.Id)
+
+				// update frozen node health indicator
+				lastSynchedBlockNumber = currentBlockNumber
+
+				continue
+			}
+
+			if nc.config.Autoheal && nc.inRestartBreak() {
+				logger.Debugf("not autohealing node %s, autoheal restart cap reached", nodeState.NodeInfo.Id)
+
+				// update frozen node health indicator
+				lastSynchedBlockNumber = currentBlockNumber
+
+				continue
+			}
+
+			if nc.config.Autoheal && nc.inUpgrade() {
+				logger.Debugf("not autohealing node %s, node is halted for a scheduled upgrade", nodeState.NodeInfo.Id)
+
+				// update frozen node health indicator
+				lastSynchedBlockNumber = currentBlockNumber
+
+				continue
+			}
+
+			if nc.config.Autoheal && nc.inChainDivergence() {
+				logger.Debugf("not autohealing node %s, node has diverged from the reference node", nodeState.NodeInfo.Id)
+
+				// update frozen node health indicator
+				lastSynchedBlockNumber = currentBlockNumber
+
+				continue
+			}
+
+			if nc.config.Autoheal && nc.inChainDegraded() {
+				logger.Debugf("not autohealing node %s, chain is halted or at risk of halting", nodeState.NodeInfo.Id)
+
+				// update frozen node health indicator
+				lastSynchedBlockNumber = currentBlockNumber
+
+				continue
+			}
+
+			if nc.config.Autoheal && nc.inChainIdMismatch() {
+				logger.Debugf("not autohealing node %s, node is on chain %s instead of %s", nodeState.NodeInfo.Id, nodeState.NodeInfo.Network, nc.config.ExpectedChainId)
+
+				// update frozen node health indicator
+				lastSynchedBlockNumber = currentBlockNumber
+
+				continue
 			}
 
 			// TODO: refactor into node.AutohealOutOfSyncNode()
 			if nc.config.Autoheal {
-				go func() {
-					logMessages <- fmt.Sprintf("AutoHeal: node %s is %d seconds behind live, AutohealSyncLatencyToleranceSeconds %d, ", nodeState.NodeInfo.Id, secondsBehindLive, int64(nc.config.AutohealSyncLatencyToleranceSeconds))
-				}()
-				if secondsBehindLive > int64(nc.config.AutohealSyncLatencyToleranceSeconds) {
-					go func() {
-						logMessages <- fmt.Sprintf("node %s is more than %d seconds behind live: %d, checking to see if it is already being healed", nodeState.NodeInfo.Id, nc.config.AutohealSyncLatencyToleranceSeconds, secondsBehindLive)
-					}()
+				// copied as settings may be adjusted while logging
+				syncLatencyToleranceSeconds := settings.AutohealSyncLatencyToleranceSeconds
+				logger.Debugf("AutoHeal: node %s is %d seconds behind live, AutohealSyncLatencyToleranceSeconds %d, ", nodeState.NodeInfo.Id, secondsBehindLive, int64(syncLatencyToleranceSeconds))
+				if outOfSync {
+					logger.Warnf("node %s is out of sync (%s), checking to see if it is already being healed", nodeState.NodeInfo.Id, syncLag)
 
 					// check to see if there is already a healer working on this issue
 					if outOfSyncAutohealingInProgress {
-						go func() {
-							logMessages <- fmt.Sprintf("AutoHeal: node %s is currently being autohealed", nodeState.NodeInfo.Id)
-						}()
+						logger.Infof("AutoHeal: node %s is currently being autohealed", nodeState.NodeInfo.Id)
 						goto AutohealFrozenNodeBegin
 					}
 
 					outOfSyncAutohealingInProgress = true
 
-					go func() {
-						logMessages <- fmt.Sprintf("node %s is more than %d seconds behind live: %d, attempting autohealing actions", nodeState.NodeInfo.Id, nc.config.AutohealSyncLatencyToleranceSeconds, secondsBehindLive)
-					}()
+					incidentId, _ := incidents.OpenIncidentId(incident.NodeOutOfSyncIncident)
+
+					logger.Infof("node %s is out of sync (%s), attempting autohealing actions", nodeState.NodeInfo.Id, syncLag)
+
+					nc.publishAutohealStarted(nodeState.NodeInfo.Id, incident.NodeOutOfSyncIncident, fmt.Sprintf("healing node %s", syncLag))
 
 					// node, heal thyself
+					autohealingRoutines.Add(1)
+
 					go func() {
+						defer autohealingRoutines.Done()
 						defer func() {
-							go func() {
-								logMessages <- "AutoHeal: releasing lock"
-							}()
+							logger.Debug("AutoHeal: releasing lock")
 							outOfSyncAutohealingInProgress = false
-							go func() {
-								logMessages <- "AutoHeal: released lock"
-							}()
+							logger.Debug("AutoHeal: released lock")
 						}()
 
-						heal.StandbyNodeUntilCaughtUp(logMessages, nc.Client, heal.HealerConfig{
+						if !nc.healLock.TryRLock() {
+							logger.Infof("AutoHeal: skipping attempt to heal node %s, %s", nodeState.NodeInfo.Id, ErrHealInProgress)
+
+							return
+						}
+
+						defer nc.healLock.RUnlock()
+
+						healer, err := nc.getHealer()
+
+						if err != nil {
+							logger.Errorf("AutoHeal: error %s initializing healer, skipping attempt to heal node %s", err, nodeState.NodeInfo.Id)
+
+							return
+						}
+
+						healer.HealOutOfSyncNode(ctx, logger, nc.Client, heal.HealerConfig{
 							AutohealSyncToLiveToleranceSeconds: nc.config.AutohealSyncToLiveToleranceSeconds,
+							EndpointURL:                        nc.config.RPCEndpoint,
+							NodeId:                             nodeState.NodeInfo.Id,
+							IncidentId:                         incidentId,
+							ServiceRestartLoop:                 nc.inServiceRestartLoop(),
+							PoorPeering:                        nc.inPoorPeering(),
+							SecondsBehindLive:                  secondsBehindLive,
+							MinHealthyInServiceInstances:       nc.config.AutohealStandbyMinHealthyInstances,
+							IncidentPublisher:                  nc.config.IncidentPublisher,
 						})
 					}()
 				} else {
-					logMessages <- fmt.Sprintf("node %s is less than %d seconds behind live, doesn't need to be auto healed", nodeState.NodeInfo.Id, nc.config.AutohealSyncLatencyToleranceSeconds)
+					logger.Debugf("node %s is in sync (%s), doesn't need to be auto healed", nodeState.NodeInfo.Id, syncLag)
 				}
 			} else {
-				logMessages <- fmt.Sprintf("auto heal not enabled for node %s, skipping autoheal checks", nodeState.NodeInfo.Id)
+				logger.Debugf("auto heal not enabled for node %s, skipping autoheal checks", nodeState.NodeInfo.Id)
 			}
 
 		AutohealFrozenNodeBegin:
@@ -264,29 +1085,31 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 				// if configured, allow an initial buffer from service start to first autoheal restart
 				// if we are still in that initial buffer. if so, continue checking the health
 				if time.Now().Before(earliestAllowedRestartTime) {
-					logMessages <- fmt.Sprintf("not restarting frozen node, still in initial restart delay buffer: buffer %d sec, first restart allowed at %s", nc.config.AutohealInitialAllowedDelaySeconds, earliestAllowedRestartTime)
+					logger.Debugf("not restarting frozen node, still in initial restart delay buffer: buffer %d sec, first restart allowed at %s", nc.config.AutohealInitialAllowedDelaySeconds, nc.config.TimeFormat.Format(earliestAllowedRestartTime))
 					continue
 				}
 
 				// check if the node has been frozen long enough to deserve a restart
 				frozenDuration := time.Since(lastNewBlockObservedAt)
 
-				if frozenDuration > time.Duration(time.Duration(nc.config.NoNewBlocksRestartThresholdSeconds)*time.Second) {
+				if checkFrozen && frozenDuration > noNewBlocksRestartThreshold {
 					// if the node was previously restarted
 					// don't restart until AutohealRestartDelaySeconds have passed
 					if lastRestartedByAutohealingAt != nil {
-						if frozenDuration < time.Duration(time.Duration(nc.config.AutohealRestartDelaySeconds)*time.Second) {
-							logMessages <- fmt.Sprintf("not restarting frozen node, current freezetime %v last restarted %f seconds ago at %v restart delay seconds %d", frozenDuration, time.Since(*lastRestartedByAutohealingAt).Seconds(), lastRestartedByAutohealingAt, nc.config.AutohealRestartDelaySeconds)
+						if frozenDuration < time.Duration(time.Duration(settings.AutohealRestartDelaySeconds)*time.Second) {
+							logger.Infof("not restarting frozen node, current freezetime %v last restarted %f seconds ago at %s restart delay seconds %d", frozenDuration, time.Since(*lastRestartedByAutohealingAt).Seconds(), nc.config.TimeFormat.Format(*lastRestartedByAutohealingAt), settings.AutohealRestartDelaySeconds)
 
 							// keep checking the health of the endpoint
 							continue
 						}
 
 						// restart the node
-						err = nc.RestartBlockchainService()
+						nc.publishAutohealStarted(nodeState.NodeInfo.Id, incident.NodeFrozenIncident, fmt.Sprintf("restarting node frozen for %v", frozenDuration))
 
-						if err != nil {
-							logMessages <- fmt.Sprintf("error %s restarting node", err)
+						err = nc.restartFrozenNode(ctx, nodeState.NodeInfo.Id)
+
+						if err != nil && !errors.Is(err, heal.ErrRestartNotVerified) {
+							logger.Errorf("error %s restarting node", err)
 							// keep checking the health of the endpoint
 							continue
 						}
@@ -295,7 +1118,7 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 						now := time.Now()
 						lastRestartedByAutohealingAt = &now
 
-						logMessages <- fmt.Sprintf("restarted node at %v", lastRestartedByAutohealingAt)
+						logger.Warnf("restarted node at %s", nc.config.TimeFormat.Format(now))
 
 						// reset frozen clock
 						lastNewBlockObservedAt = time.Now()
@@ -304,13 +1127,15 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 						continue
 					}
 
-					logMessages <- fmt.Sprintf("autohealing frozen node, last block synched at %v,NoNewBlocksRestartThresholdSeconds %d", lastNewBlockObservedAt, nc.config.NoNewBlocksRestartThresholdSeconds)
+					logger.Warnf("autohealing frozen node, last block synched at %s, no new blocks restart threshold %v in sync phase %s", nc.config.TimeFormat.Format(lastNewBlockObservedAt), noNewBlocksRestartThreshold, metrics.SyncPhase)
 
 					// restart the node
-					err = nc.RestartBlockchainService()
+					nc.publishAutohealStarted(nodeState.NodeInfo.Id, incident.NodeFrozenIncident, fmt.Sprintf("restarting node frozen for %v", frozenDuration))
 
-					if err != nil {
-						logMessages <- fmt.Sprintf("error %s restarting node", err)
+					err = nc.restartFrozenNode(ctx, nodeState.NodeInfo.Id)
+
+					if err != nil && !errors.Is(err, heal.ErrRestartNotVerified) {
+						logger.Errorf("error %s restarting node", err)
 						// keep checking the health of the endpoint
 						continue
 					}
@@ -319,7 +1144,7 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 					now := time.Now()
 					lastRestartedByAutohealingAt = &now
 
-					logMessages <- fmt.Sprintf("restarted node at %v", lastRestartedByAutohealingAt)
+					logger.Warnf("restarted node at %s", nc.config.TimeFormat.Format(now))
 
 					// reset frozen clock
 					lastNewBlockObservedAt = time.Now()
@@ -328,7 +1153,7 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 					continue
 				}
 
-				logMessages <- fmt.Sprintf("not restarting node, frozen for %v seconds, frozen threshold seconds %v", frozenDuration.Seconds(), nc.config.NoNewBlocksRestartThresholdSeconds)
+				logger.Debugf("not restarting node, frozen for %v seconds, frozen threshold %v in sync phase %s", frozenDuration.Seconds(), noNewBlocksRestartThreshold, metrics.SyncPhase)
 			}
 
 			// update frozen node health indicator
@@ -337,8 +1162,1544 @@ func (nc *NodeClient) WatchSyncStatus(ctx context.Context, syncStatusMetrics cha
 	}
 }
 
-// RestartBlockchainService restarts the blockchain's systemd service
-// returning error (if any)
-func (nc *NodeClient) RestartBlockchainService() error {
-	return heal.RestartSystemdService(nc.config.AutohealBlockchainServiceName)
+// sendSyncStatusMetrics sends the metrics for display and collection
+// without blocking (so a stalled display can't delay autohealing)
+// counting the metrics as dropped if the channel is full
+func (nc *NodeClient) sendSyncStatusMetrics(syncStatusMetrics chan<- metric.SyncStatusMetrics, metrics metric.SyncStatusMetrics) {
+	select {
+	case syncStatusMetrics <- metrics:
+	default:
+		nc.config.DroppedMessages.Drop(SyncStatusMetricsChannel)
+	}
+}
+
+// probeUptime probes each endpoint configured for sampling the
+// uptime of the node besides the status (which was probed with the
+// provided result), returning the result of each probe and error
+// (if any) from the first probe to fail
+func (nc *NodeClient) probeUptime(statusErr error) ([]metric.UptimeProbeMetric, error) {
+	// only the status is probed unless configured otherwise
+	if len(nc.config.UptimeProbes) <= 1 {
+		return nil, statusErr
+	}
+
+	var probes []metric.UptimeProbeMetric
+	var firstErr error
+
+	for _, probe := range nc.config.UptimeProbes {
+		var err error
+
+		switch probe {
+		case dconfig.UptimeProbeStatus:
+			err = statusErr
+		case dconfig.UptimeProbeHealth:
+			err = nc.CheckHealth()
+		case dconfig.UptimeProbeABCIInfo:
+			_, err = nc.GetABCIInfo()
+		default:
+			err = fmt.Errorf("unsupported uptime probe %s", probe)
+		}
+
+		probeMetric := metric.UptimeProbeMetric{
+			Probe: probe,
+			Up:    err == nil,
+		}
+
+		if err != nil {
+			probeMetric.Error = err.Error()
+
+			if firstErr == nil && probe == dconfig.UptimeProbeStatus {
+				firstErr = err
+			} else if firstErr == nil {
+				firstErr = fmt.Errorf("%w: %s probe failed", err, probe)
+			}
+		}
+
+		probes = append(probes, probeMetric)
+	}
+
+	return probes, firstErr
+}
+
+// sendUptimeMetric sends the metric for display and collection
+// without blocking (so a stalled display can't delay autohealing)
+// counting the metric as dropped if the channel is full
+func (nc *NodeClient) sendUptimeMetric(uptimeMetrics chan<- metric.UptimeMetric, uptimeMetric metric.UptimeMetric) {
+	select {
+	case uptimeMetrics <- uptimeMetric:
+	default:
+		nc.config.DroppedMessages.Drop(UptimeMetricsChannel)
+	}
+}
+
+// WatchPeerConnections watches (until the context is cancelled)
+// the set of peers the node is connected to, sending a sample
+// of the connected peer ids to the provided channel each interval
+// for use in calculating how frequently the node's peers churn
+func (nc *NodeClient) WatchPeerConnections(ctx context.Context, peerConnectionMetrics chan<- metric.PeerConnectionMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			sampledAt := time.Now()
+
+			// peer connections are tracked per node so lookup
+			// the id of the node currently serving the endpoint
+			nodeState, err := nc.GetNodeState()
+
+			if err != nil {
+				nc.logger.Debugf("error %s getting node status for peer connection sample", err)
+
+				continue
+			}
+
+			netInfo, err := nc.GetNetInfo()
+
+			if err != nil {
+				nc.logger.Errorf("error %s getting node net info", err)
+
+				continue
+			}
+
+			metrics := nc.peerQuality(netInfo)
+			metrics.NodeId = nodeState.NodeInfo.Id
+			metrics.SampledAt = sampledAt
+
+			if metrics.PoorPeering {
+				atomic.StoreInt32(&nc.poorPeering, 1)
+			} else {
+				atomic.StoreInt32(&nc.poorPeering, 0)
+			}
+
+			select {
+			case peerConnectionMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(PeerConnectionMetricsChannel)
+			}
+		}
+	}
+}
+
+// peerQuality returns the peers the node is connected to, the data
+// exchanged with them and which of them are low quality, peers
+// connected for less than peerQualityGracePeriod aren't judged as
+// they haven't had time to send the node much data
+func (nc *NodeClient) peerQuality(netInfo kava.NetInfo) metric.PeerConnectionMetrics {
+	var metrics metric.PeerConnectionMetrics
+
+	metrics.PeerIds = make([]string, 0, len(netInfo.Peers))
+
+	for _, peer := range netInfo.Peers {
+		metrics.PeerIds = append(metrics.PeerIds, peer.NodeInfo.Id)
+		metrics.RecvBytesPerSecond += float64(peer.ConnectionStatus.RecvMonitor.AvgRate)
+		metrics.SendBytesPerSecond += float64(peer.ConnectionStatus.SendMonitor.AvgRate)
+
+		if nc.config.PeerMinRecvBytesPerSecond <= 0 || peer.ConnectedFor() < peerQualityGracePeriod {
+			continue
+		}
+
+		if float64(peer.ConnectionStatus.RecvMonitor.AvgRate) < nc.config.PeerMinRecvBytesPerSecond {
+			metrics.LowQualityPeerIds = append(metrics.LowQualityPeerIds, peer.NodeInfo.Id)
+		}
+	}
+
+	if nc.config.PeerMinRecvBytesPerSecond > 0 {
+		metrics.PoorPeering = len(netInfo.Peers) == 0 || len(metrics.LowQualityPeerIds)*2 > len(netInfo.Peers)
+	}
+
+	return metrics
+}
+
+// WatchConsensusState watches (until the context is cancelled) the round of
+// consensus the node is participating in, sending the current height, round
+// and step to the provided channel each interval and detecting when consensus
+// is stuck in the same step, restarting the node if autohealing frozen consensus
+func (nc *NodeClient) WatchConsensusState(ctx context.Context, consensusStateMetrics chan<- metric.ConsensusStateMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	frozenThreshold := time.Duration(nc.config.ConsensusFrozenThresholdSeconds) * time.Second
+	earliestAllowedRestartTime := time.Now().Add(time.Duration(nc.config.AutohealInitialAllowedDelaySeconds) * time.Second)
+	incidents := incident.NewTracker(nc.config.RPCEndpoint)
+
+	var lastConsensusState kava.ConsensusState
+	// when the node was first observed in the current height, round and step
+	var stepObservedAt time.Time
+	var lastRestartedByAutohealingAt *time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			sampledAt := time.Now()
+
+			// consensus state is tracked per node so lookup
+			// the id of the node currently serving the endpoint
+			nodeState, err := nc.GetNodeState()
+
+			if err != nil {
+				nc.logger.Debugf("error %s getting node status for consensus state sample", err)
+
+				continue
+			}
+
+			consensusState, err := nc.GetConsensusState()
+
+			if err != nil {
+				nc.logger.Errorf("error %s getting node consensus state", err)
+
+				continue
+			}
+
+			logger := nc.logger.With(logging.Fields{"node_id": nodeState.NodeInfo.Id})
+
+			// consensus doesn't run while the node is restoring a snapshot or
+			// replaying blocks, so it isn't frozen until the node catches up
+			if stepObservedAt.IsZero() || nodeState.SyncInfo.CatchingUp || consensusState.Height != lastConsensusState.Height || consensusState.Round != lastConsensusState.Round || consensusState.Step != lastConsensusState.Step {
+				lastConsensusState = consensusState
+				stepObservedAt = sampledAt
+			}
+
+			timeInStep := sampledAt.Sub(stepObservedAt)
+			frozen := frozenThreshold > 0 && timeInStep > frozenThreshold
+
+			if frozen {
+				if event, opened := incidents.Open(incident.ConsensusFrozenIncident, nodeState.NodeInfo.Id, fmt.Sprintf("consensus stuck at height %d round %d step %s for %v", consensusState.Height, consensusState.Round, consensusState.StepName(), timeInStep), sampledAt); opened {
+					nc.publishIncidentEvent(event)
+				}
+			} else if event, closed := incidents.Close(incident.ConsensusFrozenIncident, fmt.Sprintf("consensus progressed to height %d round %d step %s", consensusState.Height, consensusState.Round, consensusState.StepName()), sampledAt); closed {
+				nc.publishIncidentEvent(event)
+			}
+
+			metrics := metric.ConsensusStateMetrics{
+				NodeId:        nodeState.NodeInfo.Id,
+				Height:        consensusState.Height,
+				Round:         consensusState.Round,
+				Step:          consensusState.Step,
+				StepName:      consensusState.StepName(),
+				SecondsInStep: timeInStep.Seconds(),
+				Frozen:        frozen,
+				SampledAt:     sampledAt,
+			}
+
+			select {
+			case consensusStateMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(ConsensusStateMetricsChannel)
+			}
+
+			if !frozen || !nc.config.Autoheal || !nc.config.AutohealFrozenConsensus {
+				continue
+			}
+
+			if sampledAt.Before(earliestAllowedRestartTime) {
+				logger.Debugf("not restarting node with frozen consensus, still in initial restart delay buffer: buffer %d sec, first restart allowed at %s", nc.config.AutohealInitialAllowedDelaySeconds, nc.config.TimeFormat.Format(earliestAllowedRestartTime))
+
+				continue
+			}
+
+			// give the node time to recover from the previous restart
+			if lastRestartedByAutohealingAt != nil && sampledAt.Sub(*lastRestartedByAutohealingAt) < time.Duration(nc.config.AutohealRestartDelaySeconds)*time.Second {
+				logger.Infof("not restarting node with frozen consensus, last restarted at %s restart delay seconds %d", nc.config.TimeFormat.Format(*lastRestartedByAutohealingAt), nc.config.AutohealRestartDelaySeconds)
+
+				continue
+			}
+
+			logger.Warnf("autohealing node with frozen consensus, stuck at height %d round %d step %s for %v", consensusState.Height, consensusState.Round, consensusState.StepName(), timeInStep)
+
+			nc.publishAutohealStarted(nodeState.NodeInfo.Id, incident.ConsensusFrozenIncident, fmt.Sprintf("restarting node with consensus stuck at height %d round %d step %s for %v", consensusState.Height, consensusState.Round, consensusState.StepName(), timeInStep))
+
+			err = nc.RestartBlockchainService()
+
+			if err != nil && !errors.Is(err, heal.ErrRestartNotVerified) {
+				logger.Errorf("error %s restarting node", err)
+
+				continue
+			}
+
+			now := time.Now()
+			lastRestartedByAutohealingAt = &now
+
+			logger.Warnf("restarted node at %s", nc.config.TimeFormat.Format(now))
+
+			// reset frozen clock
+			stepObservedAt = now
+		}
+	}
+}
+
+// WatchMempool watches (until the context is cancelled) the
+// node's mempool, sending the number and size of unconfirmed
+// transactions to the provided channel each interval
+func (nc *NodeClient) WatchMempool(ctx context.Context, mempoolMetrics chan<- metric.MempoolMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	alertDuration := time.Duration(nc.config.MempoolSizeAlertDurationSeconds) * time.Second
+
+	// when the mempool size most recently rose above the alert
+	// threshold, zero if it is currently at or below the threshold
+	var aboveThresholdSince time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			sampledAt := time.Now()
+
+			// mempool state is tracked per node so lookup
+			// the id of the node currently serving the endpoint
+			nodeState, err := nc.GetNodeState()
+
+			if err != nil {
+				nc.logger.Debugf("error %s getting node status for mempool sample", err)
+
+				continue
+			}
+
+			mempoolState, err := nc.GetMempoolState()
+
+			if err != nil {
+				nc.logger.Errorf("error %s getting node mempool state", err)
+
+				continue
+			}
+
+			if nc.config.MempoolSizeAlertThreshold > 0 && mempoolState.NumberOfTxs > int64(nc.config.MempoolSizeAlertThreshold) {
+				if aboveThresholdSince.IsZero() {
+					aboveThresholdSince = sampledAt
+				}
+			} else {
+				aboveThresholdSince = time.Time{}
+			}
+
+			metrics := metric.MempoolMetrics{
+				NodeId:     nodeState.NodeInfo.Id,
+				Size:       mempoolState.NumberOfTxs,
+				Bytes:      mempoolState.TotalBytes,
+				Backlogged: !aboveThresholdSince.IsZero() && sampledAt.Sub(aboveThresholdSince) >= alertDuration,
+				SampledAt:  sampledAt,
+			}
+
+			select {
+			case mempoolMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(MempoolMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchEndpointAddresses watches (until the context is cancelled) the
+// ip addresses the host of the endpoint resolves to, each interval
+// resolving the host and probing the status of each address
+// individually, sending the result for each address to the provided
+// channel, as a load balanced endpoint only ever reports the status
+// of whichever node the load balancer picks for a request
+func (nc *NodeClient) WatchEndpointAddresses(ctx context.Context, endpointAddressMetrics chan<- metric.EndpointAddressMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	rpcURL, socketPath, err := kava.ParseRPCAddress(nc.config.RPCEndpoint)
+
+	if err != nil {
+		nc.logger.Errorf("error %s parsing endpoint %s, not probing its addresses", err, nc.config.RPCEndpoint)
+
+		return
+	}
+
+	// a unix socket only ever reaches the local node
+	if socketPath != "" {
+		nc.logger.Infof("endpoint %s is a unix socket, not probing its addresses", nc.config.RPCEndpoint)
+
+		return
+	}
+
+	endpointURL, err := url.Parse(rpcURL)
+
+	if err != nil {
+		nc.logger.Errorf("error %s parsing endpoint %s, not probing its addresses", err, nc.config.RPCEndpoint)
+
+		return
+	}
+
+	// clients for each address, reused across intervals
+	// while the host continues to resolve to the address
+	addressClients := make(map[string]*kava.Client)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			resolvedAddresses, err := net.DefaultResolver.LookupHost(ctx, endpointURL.Hostname())
+
+			if err != nil {
+				nc.logger.Errorf("error %s resolving addresses of %s", err, endpointURL.Hostname())
+
+				continue
+			}
+
+			resolvedClients := make(map[string]*kava.Client)
+
+			for _, address := range resolvedAddresses {
+				client, exists := addressClients[address]
+
+				if !exists {
+					client, err = kava.New(kava.ClientConfig{
+						JSONRPCURL:             nc.config.RPCEndpoint,
+						HTTPReadTimeoutSeconds: nc.config.HealthChecksTimeoutSeconds,
+						DialAddress:            address,
+						Auth:                   nc.config.RPCAuth,
+						Transport:              nc.config.Transport,
+						Status:                 nc.config.Status,
+					})
+
+					if err != nil {
+						nc.logger.Errorf("error %s creating client for address %s", err, address)
+
+						continue
+					}
+				}
+
+				resolvedClients[address] = client
+
+				sampledAt := time.Now()
+
+				nodeState, err := client.GetNodeState()
+
+				metrics := metric.EndpointAddressMetrics{
+					EndpointURL:               nc.config.RPCEndpoint,
+					Address:                   address,
+					NodeId:                    nodeState.NodeInfo.Id,
+					Up:                        err == nil,
+					SampleLatencyMilliseconds: time.Since(sampledAt).Milliseconds(),
+					SampledAt:                 sampledAt,
+				}
+
+				if err != nil {
+					nc.logger.Debugf("error %s getting node status from address %s", err, address)
+				}
+
+				select {
+				case endpointAddressMetrics <- metrics:
+				default:
+					nc.config.DroppedMessages.Drop(EndpointAddressMetricsChannel)
+				}
+			}
+
+			// stop tracking addresses the host no longer resolves to
+			addressClients = resolvedClients
+		}
+	}
+}
+
+// WatchRegionLatency watches (until the context is cancelled) the latency
+// of requesting the status of the endpoint from the doctor's region and
+// from each of the vantage endpoints reaching it from other regions,
+// sending the latencies for each interval to the provided channel so
+// slowness from a single region can be told apart from global slowness
+func (nc *NodeClient) WatchRegionLatency(ctx context.Context, regionLatencyMetrics chan<- metric.RegionLatencyMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	regionClients := map[string]*kava.Client{
+		nc.config.Region: nc.Client,
+	}
+	regionURLs := map[string]string{
+		nc.config.Region: nc.config.RPCEndpoint,
+	}
+	regions := []string{nc.config.Region}
+
+	for _, vantage := range nc.config.VantageEndpoints {
+		client, err := kava.New(kava.ClientConfig{
+			JSONRPCURL:             vantage.URL,
+			HTTPReadTimeoutSeconds: nc.config.HealthChecksTimeoutSeconds,
+			Auth:                   nc.config.RPCAuth,
+			Transport:              nc.config.Transport,
+			Status:                 nc.config.Status,
+		})
+
+		if err != nil {
+			nc.logger.Errorf("error %s creating client for vantage endpoint %s, not probing region %s", err, vantage.URL, vantage.Region)
+
+			continue
+		}
+
+		regionClients[vantage.Region] = client
+		regionURLs[vantage.Region] = vantage.URL
+		regions = append(regions, vantage.Region)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			metrics := metric.RegionLatencyMetrics{
+				EndpointURL: nc.config.RPCEndpoint,
+				Regions:     make([]metric.RegionLatency, len(regions)),
+				SampledAt:   time.Now(),
+			}
+
+			// probe every region at the same time
+			// so the latencies are comparable
+			var probes sync.WaitGroup
+
+			for i, region := range regions {
+				probes.Add(1)
+
+				go func(i int, region string) {
+					defer probes.Done()
+
+					startedAt := time.Now()
+
+					_, err := regionClients[region].GetNodeState()
+
+					latency := metric.RegionLatency{
+						Region:              region,
+						URL:                 regionURLs[region],
+						Up:                  err == nil,
+						LatencyMilliseconds: time.Since(startedAt).Milliseconds(),
+					}
+
+					if err != nil {
+						latency.Error = err.Error()
+					}
+
+					metrics.Regions[i] = latency
+				}(i, region)
+			}
+
+			probes.Wait()
+
+			select {
+			case regionLatencyMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(RegionLatencyMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchChainConsistency watches (until the context is cancelled)
+// whether the node is on the same chain as the reference node, each
+// interval comparing the block and app hashes of the most recent block
+// both nodes have, sending the comparison to the provided channel
+// A node that has diverged (e.g. is on a fork or has corrupt state)
+// isn't autohealed, as restarting it would only make matters worse
+func (nc *NodeClient) WatchChainConsistency(ctx context.Context, chainConsistencyMetrics chan<- metric.ChainConsistencyMetrics) {
+	if nc.referenceClient == nil || nc.config.ChainConsistencyCheckIntervalSeconds <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(nc.config.ChainConsistencyCheckIntervalSeconds) * time.Second).C
+
+	incidents := incident.NewTracker(nc.config.RPCEndpoint)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			sampledAt := time.Now()
+
+			nodeState, err := nc.GetNodeState()
+
+			if err != nil {
+				nc.logger.Debugf("error %s getting node status for chain consistency check", err)
+
+				continue
+			}
+
+			referenceState, err := nc.referenceClient.GetNodeState()
+
+			if err != nil {
+				nc.logger.Warnf("error %s getting reference endpoint %s status for chain consistency check", err, nc.config.ReferenceRPCEndpoint)
+
+				continue
+			}
+
+			// compare the most recent block both nodes have
+			height := nodeState.SyncInfo.LatestBlockHeight
+
+			if referenceState.SyncInfo.LatestBlockHeight < height {
+				height = referenceState.SyncInfo.LatestBlockHeight
+			}
+
+			nodeHashes, err := nc.GetBlockHashes(height)
+
+			if err != nil {
+				nc.logger.Errorf("error %s getting node block %d", err, height)
+
+				continue
+			}
+
+			referenceHashes, err := nc.referenceClient.GetBlockHashes(height)
+
+			if err != nil {
+				nc.logger.Warnf("error %s getting reference endpoint %s block %d", err, nc.config.ReferenceRPCEndpoint, height)
+
+				continue
+			}
+
+			metrics := metric.ChainConsistencyMetrics{
+				NodeId:             nodeState.NodeInfo.Id,
+				Height:             height,
+				BlockHash:          nodeHashes.BlockHash,
+				ReferenceBlockHash: referenceHashes.BlockHash,
+				AppHash:            nodeHashes.AppHash,
+				ReferenceAppHash:   referenceHashes.AppHash,
+				Diverged:           nodeHashes.BlockHash != referenceHashes.BlockHash || nodeHashes.AppHash != referenceHashes.AppHash,
+				SampledAt:          sampledAt,
+			}
+
+			if metrics.Diverged {
+				atomic.StoreInt32(&nc.chainDiverged, 1)
+
+				divergence := fmt.Sprintf("node block %d hash %s app hash %s differs from reference block hash %s app hash %s", height, metrics.BlockHash, metrics.AppHash, metrics.ReferenceBlockHash, metrics.ReferenceAppHash)
+
+				if event, opened := incidents.Open(incident.ChainDivergenceIncident, nodeState.NodeInfo.Id, divergence, sampledAt); opened {
+					nc.logger.Errorf("%s, node may be on a fork or have corrupt state, refusing to autoheal", divergence)
+					nc.publishIncidentEvent(event)
+				}
+			} else {
+				atomic.StoreInt32(&nc.chainDiverged, 0)
+
+				if event, closed := incidents.Close(incident.ChainDivergenceIncident, fmt.Sprintf("node block %d hashes match the reference node", height), sampledAt); closed {
+					nc.logger.Infof("node %s block %d hashes match the reference node again", nodeState.NodeInfo.Id, height)
+					nc.publishIncidentEvent(event)
+				}
+			}
+
+			select {
+			case chainConsistencyMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(ChainConsistencyMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchVotingPower watches (until the context is cancelled) the voting
+// power of the validators that signed the commit of the latest block
+// compared to the validator set, queried from the reference endpoint
+// (if any) so a frozen node doesn't hide the state of the chain, sending
+// the voting power online to the provided channel each interval and
+// suppressing autoheal restarts while it's below the degraded threshold,
+// as the chain can't produce blocks with less than two thirds of voting
+// power online and restarting the node during a halt only adds churn
+func (nc *NodeClient) WatchVotingPower(ctx context.Context, votingPowerMetrics chan<- metric.VotingPowerMetrics) {
+	if nc.config.VotingPowerCheckIntervalSeconds <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(nc.config.VotingPowerCheckIntervalSeconds) * time.Second).C
+
+	client := nc.Client
+	endpointURL := nc.config.RPCEndpoint
+
+	if nc.referenceClient != nil {
+		client = nc.referenceClient
+		endpointURL = nc.config.ReferenceRPCEndpoint
+	}
+
+	incidents := incident.NewTracker(nc.config.RPCEndpoint)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			sampledAt := time.Now()
+
+			nodeState, err := client.GetNodeState()
+
+			if err != nil {
+				nc.logger.Debugf("error %s getting %s status for voting power check", err, endpointURL)
+
+				continue
+			}
+
+			// the commit of the latest block is only the votes the node
+			// saw before moving on, the canonical commit of the block
+			// before it (included in the latest block) has every vote
+			height := nodeState.SyncInfo.LatestBlockHeight - 1
+
+			if height < 1 {
+				continue
+			}
+
+			validators, err := client.GetValidators(height)
+
+			if err != nil {
+				nc.logger.Errorf("error %s getting %s validator set at height %d", err, endpointURL, height)
+
+				continue
+			}
+
+			commit, err := client.GetCommit(height)
+
+			if err != nil {
+				nc.logger.Errorf("error %s getting %s commit at height %d", err, endpointURL, height)
+
+				continue
+			}
+
+			onlineVotingPower, votingPower := kava.VotingPowerOnline(validators, commit)
+
+			metrics := metric.VotingPowerMetrics{
+				ChainId:           nodeState.NodeInfo.Network,
+				EndpointURL:       endpointURL,
+				Height:            height,
+				Validators:        len(validators),
+				VotingPower:       votingPower,
+				OnlineVotingPower: onlineVotingPower,
+				SampledAt:         sampledAt,
+			}
+
+			for _, signature := range commit.Signatures {
+				if signature.BlockIdFlag == kava.BlockIdFlagCommit {
+					metrics.ValidatorsSigned++
+				}
+			}
+
+			if votingPower > 0 {
+				metrics.OnlinePercent = float64(onlineVotingPower) / float64(votingPower) * 100
+			}
+
+			metrics.Degraded = metrics.OnlinePercent < nc.config.ChainDegradedVotingPowerPercent
+
+			if metrics.Degraded {
+				atomic.StoreInt32(&nc.chainDegraded, 1)
+
+				degradation := fmt.Sprintf("%.2f%% of voting power signed block %d of chain %s, below the degraded threshold of %.2f%%", metrics.OnlinePercent, height, metrics.ChainId, nc.config.ChainDegradedVotingPowerPercent)
+
+				if event, opened := incidents.Open(incident.ChainDegradedIncident, "", degradation, sampledAt); opened {
+					nc.logger.Errorf("%s, chain is halted or at risk of halting, suppressing autoheal restarts", degradation)
+					nc.publishIncidentEvent(event)
+				}
+			} else {
+				atomic.StoreInt32(&nc.chainDegraded, 0)
+
+				if event, closed := incidents.Close(incident.ChainDegradedIncident, fmt.Sprintf("%.2f%% of voting power signed block %d", metrics.OnlinePercent, height), sampledAt); closed {
+					nc.logger.Infof("%.2f%% of voting power signed block %d of chain %s, autoheal restarts resumed", metrics.OnlinePercent, height, metrics.ChainId)
+					nc.publishIncidentEvent(event)
+				}
+			}
+
+			select {
+			case votingPowerMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(VotingPowerMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchUpgrades watches (until the context is cancelled) for the node
+// halting for a scheduled upgrade (configured or queried from the
+// upgrade module), suppressing autohealing from when the node reaches
+// the upgrade height until it produces blocks past the upgrade height
+// or the upgrade window passes, as restarting the node mid upgrade
+// interferes with operators (or cosmovisor) swapping its binary
+func (nc *NodeClient) WatchUpgrades(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	upgradeWindow := time.Duration(nc.config.UpgradeWindowSeconds) * time.Second
+	upgradeName := "configured upgrade"
+	upgradeHeight := nc.config.UpgradeHeight
+
+	// the last height the node reported, kept while the node is
+	// offline as it's expected to go offline when it halts
+	var latestHeight int64
+	// when the node was first observed halted at the upgrade height
+	var haltedAt time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			now := time.Now()
+
+			// the plan is cleared once the upgrade is applied, so
+			// keep the height of the last plan seen until then
+			if nc.upgradeClient != nil {
+				plan, err := nc.upgradeClient.GetCurrentUpgradePlan()
+
+				if err != nil {
+					nc.logger.Debugf("error %s getting current upgrade plan from %s", err, nc.config.UpgradeAPIAddress)
+				} else if plan != nil && plan.Height != upgradeHeight {
+					upgradeName = plan.Name
+					upgradeHeight = plan.Height
+
+					nc.logger.Infof("upgrade %s scheduled at height %d, autohealing will be suppressed while the node is halted for the upgrade", upgradeName, upgradeHeight)
+				}
+			}
+
+			nodeState, err := nc.GetNodeState()
+
+			if err == nil {
+				latestHeight = nodeState.SyncInfo.LatestBlockHeight
+			}
+
+			// the node halts after committing the block before the
+			// upgrade height, the upgraded node then commits the
+			// block at the upgrade height
+			halted := upgradeHeight > 0 && latestHeight >= upgradeHeight-1 && latestHeight <= upgradeHeight
+
+			switch {
+			case halted && haltedAt.IsZero():
+				haltedAt = now
+
+				atomic.StoreInt32(&nc.upgradeInProgress, 1)
+
+				nc.logger.Warnf("node reached height %d of %s at height %d, suppressing autohealing for up to %v until the node produces blocks past the upgrade height", latestHeight, upgradeName, upgradeHeight, upgradeWindow)
+			case halted && nc.inUpgrade() && now.Sub(haltedAt) > upgradeWindow:
+				atomic.StoreInt32(&nc.upgradeInProgress, 0)
+
+				nc.logger.Warnf("upgrade window of %v passed without the node producing blocks past upgrade height %d, resuming autohealing", upgradeWindow, upgradeHeight)
+			case !halted && !haltedAt.IsZero():
+				haltedAt = time.Time{}
+
+				atomic.StoreInt32(&nc.upgradeInProgress, 0)
+
+				nc.logger.Infof("node produced blocks past upgrade height %d, resuming autohealing", upgradeHeight)
+			}
+		}
+	}
+}
+
+// WatchServiceHealth watches (until the context is cancelled) the
+// blockchain's systemd service, sending the state of the service to
+// the provided channel each interval and tracking whether systemd is
+// repeatedly restarting it so autohealing doesn't add more restarts
+func (nc *NodeClient) WatchServiceHealth(ctx context.Context, serviceHealthMetrics chan<- metric.ServiceHealthMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	var restartLoopDetector heal.RestartLoopDetector
+	incidents := incident.NewTracker(nc.config.RPCEndpoint)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			sampledAt := time.Now()
+
+			state, err := heal.GetSystemdServiceState(nc.config.AutohealBlockchainServiceName)
+
+			if err != nil {
+				nc.logger.Debugf("%s, skipping systemd service health check", err)
+
+				continue
+			}
+
+			restartLoop := restartLoopDetector.Check(state)
+
+			if restartLoop {
+				atomic.StoreInt32(&nc.serviceRestartLoop, 1)
+
+				if event, opened := incidents.Open(incident.ServiceRestartLoopIncident, "", fmt.Sprintf("systemd is repeatedly restarting %s service, %d restarts", nc.config.AutohealBlockchainServiceName, state.NRestarts), sampledAt); opened {
+					nc.publishIncidentEvent(event)
+				}
+			} else if state.ActiveState == heal.SystemdActiveState {
+				atomic.StoreInt32(&nc.serviceRestartLoop, 0)
+
+				if event, closed := incidents.Close(incident.ServiceRestartLoopIncident, fmt.Sprintf("%s service is active and no longer restarting", nc.config.AutohealBlockchainServiceName), sampledAt); closed {
+					nc.publishIncidentEvent(event)
+				}
+			}
+
+			metrics := metric.ServiceHealthMetrics{
+				ServiceName: nc.config.AutohealBlockchainServiceName,
+				ActiveState: state.ActiveState,
+				SubState:    state.SubState,
+				Restarts:    state.NRestarts,
+				RestartLoop: restartLoop,
+				SampledAt:   sampledAt,
+			}
+
+			select {
+			case serviceHealthMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(ServiceHealthMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchHostMetrics watches (until the context is cancelled) the
+// load, memory usage and network and disk io of the host running
+// the node, sending the host metrics for each interval to the
+// provided channel
+func (nc *NodeClient) WatchHostMetrics(ctx context.Context, hostMetrics chan<- metric.HostMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	previous, err := host.ReadStats(nc.config.HostProcPath)
+
+	if err != nil {
+		nc.logger.Errorf("error %s reading host stats", err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			current, err := host.ReadStats(nc.config.HostProcPath)
+
+			if err != nil {
+				nc.logger.Errorf("error %s reading host stats", err)
+
+				continue
+			}
+
+			metrics := host.Metrics(previous, current)
+			previous = current
+
+			select {
+			case hostMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(HostMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchRPCMethods watches (until the context is cancelled) the
+// availability of each of the json rpc methods to probe, each interval
+// calling every method and sending whether each call succeeded and
+// its latency to the provided channel, surfacing partial api breakage
+// (e.g. tx indexing disabled) that probing a single endpoint misses
+func (nc *NodeClient) WatchRPCMethods(ctx context.Context, rpcMethodsMetrics chan<- metric.RPCMethodsMetrics) {
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker:
+			sampledAt := time.Now()
+
+			// method availability is tracked per node so lookup
+			// the id of the node currently serving the endpoint
+			nodeState, err := nc.GetNodeState()
+
+			if err != nil {
+				nc.logger.Debugf("error %s getting node status for rpc method probes", err)
+
+				continue
+			}
+
+			metrics := metric.RPCMethodsMetrics{
+				NodeId:    nodeState.NodeInfo.Id,
+				SampledAt: sampledAt,
+			}
+
+			for _, probe := range nc.config.RPCProbeMethods {
+				var params json.RawMessage
+
+				if probe.Params != "" {
+					params = json.RawMessage(probe.Params)
+				}
+
+				startedAt := time.Now()
+
+				err := nc.CallMethod(probe.Method, params)
+
+				methodMetrics := metric.RPCMethodMetrics{
+					Method:              probe.Method,
+					Available:           err == nil,
+					LatencyMilliseconds: time.Since(startedAt).Milliseconds(),
+				}
+
+				if err != nil {
+					methodMetrics.Error = err.Error()
+				}
+
+				metrics.Methods = append(metrics.Methods, methodMetrics)
+			}
+
+			select {
+			case rpcMethodsMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(RPCMethodsMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchWebsocket watches (until the context is cancelled) the
+// subscription to new block events over the node's websocket interface,
+// sending whether the subscription is connected and the delay between
+// each block and the delivery of its event to the provided channel,
+// resubscribing each interval after the subscription drops as the
+// websocket interface can fail independently of the json rpc api
+func (nc *NodeClient) WatchWebsocket(ctx context.Context, websocketMetrics chan<- metric.WebsocketMetrics) {
+	interval := time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second
+
+	// sends the metrics without blocking, returning
+	// whether to keep watching the subscription
+	send := func(metrics metric.WebsocketMetrics) bool {
+		select {
+		case websocketMetrics <- metrics:
+		default:
+			nc.config.DroppedMessages.Drop(WebsocketMetricsChannel)
+		}
+
+		return ctx.Err() == nil
+	}
+
+	for {
+		// subscription state is tracked per node so lookup
+		// the id of the node currently serving the endpoint
+		var nodeId string
+
+		nodeState, err := nc.GetNodeState()
+
+		if err != nil {
+			nc.logger.Debugf("error %s getting node status for websocket subscription", err)
+		} else {
+			nodeId = nodeState.NodeInfo.Id
+		}
+
+		subscription, err := nc.SubscribeNewBlocks(ctx)
+
+		if err != nil {
+			nc.logger.Debugf("error %s subscribing to new block events", err)
+
+			if !send(metric.WebsocketMetrics{
+				NodeId:    nodeId,
+				Connected: false,
+				SampledAt: time.Now(),
+			}) {
+				return
+			}
+		} else {
+			if !send(metric.WebsocketMetrics{
+				NodeId:    nodeId,
+				Connected: true,
+				SampledAt: time.Now(),
+			}) {
+				subscription.Close()
+
+				return
+			}
+
+			for {
+				event, err := subscription.Next()
+
+				if errors.Is(err, kava.ErrSubscriptionClosed) {
+					return
+				}
+
+				if err != nil {
+					nc.logger.Debugf("websocket subscription to new block events dropped with error %s", err)
+
+					subscription.Close()
+
+					if !send(metric.WebsocketMetrics{
+						NodeId:    nodeId,
+						Connected: false,
+						Dropped:   true,
+						SampledAt: time.Now(),
+					}) {
+						return
+					}
+
+					break
+				}
+
+				if !send(metric.WebsocketMetrics{
+					NodeId:                  nodeId,
+					Connected:               true,
+					Height:                  event.Height,
+					NewBlockEventLagSeconds: event.ReceivedAt.Sub(event.BlockTime).Seconds(),
+					SampledAt:               event.ReceivedAt,
+				}) {
+					subscription.Close()
+
+					return
+				}
+			}
+		}
+
+		// wait before resubscribing
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// WatchAccessLog watches (until the context is cancelled) the access log
+// of real client traffic to the node, sending the error rates and latencies
+// of the requests logged during each interval to the provided channel,
+// returning error (if any) if the log format is unsupported
+func (nc *NodeClient) WatchAccessLog(ctx context.Context, accessLogMetrics chan<- metric.AccessLogMetrics) error {
+	parse, err := accesslog.NewParser(nc.config.AccessLogFormat)
+
+	if err != nil {
+		return err
+	}
+
+	tailer := accesslog.NewTailer(nc.config.AccessLogFilepath)
+	defer tailer.Close()
+
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker:
+			sampledAt := time.Now()
+
+			lines, err := tailer.ReadLines()
+
+			if err != nil {
+				nc.logger.Debugf("error %s reading access log %s, skipping access log check", err, nc.config.AccessLogFilepath)
+
+				continue
+			}
+
+			metrics := metric.AccessLogMetrics{
+				LogFilepath: nc.config.AccessLogFilepath,
+				SampledAt:   sampledAt,
+			}
+
+			var latencies []float64
+
+			for _, line := range lines {
+				entry, err := parse(line)
+
+				if err != nil {
+					metrics.UnparsedLines++
+
+					continue
+				}
+
+				metrics.Requests++
+
+				if entry.ServerError() {
+					metrics.ServerErrors++
+				}
+
+				if entry.ClientError() {
+					metrics.ClientErrors++
+				}
+
+				if entry.HasLatency {
+					latencies = append(latencies, float64(entry.Latency.Microseconds())/1000)
+				}
+			}
+
+			if metrics.Requests > 0 {
+				metrics.ServerErrorRate = float64(metrics.ServerErrors) / float64(metrics.Requests)
+				metrics.ClientErrorRate = float64(metrics.ClientErrors) / float64(metrics.Requests)
+			}
+
+			histogram := metric.NewHistogram(latencies)
+
+			metrics.LatencySamples = histogram.Count()
+			metrics.LatencyP50Milliseconds = histogram.Percentile(50)
+			metrics.LatencyP95Milliseconds = histogram.Percentile(95)
+			metrics.LatencyP99Milliseconds = histogram.Percentile(99)
+
+			select {
+			case accessLogMetrics <- metrics:
+			default:
+				nc.config.DroppedMessages.Drop(AccessLogMetricsChannel)
+			}
+		}
+	}
+}
+
+// WatchAnnotations watches (until the context is cancelled) for
+// annotations appended to the annotations file (e.g. by the annotate
+// command) sending each annotation to the annotations channel
+// returning error (if any) if the annotations file can't be created
+func (nc *NodeClient) WatchAnnotations(ctx context.Context, annotations chan<- annotation.Annotation) error {
+	// create the file up front so that annotations
+	// appended before it's first read aren't skipped
+	file, err := os.OpenFile(nc.config.AnnotationsFilepath, os.O_CREATE|os.O_RDONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	file.Close()
+
+	tailer := accesslog.NewTailer(nc.config.AnnotationsFilepath)
+	defer tailer.Close()
+
+	ticker := time.NewTicker(time.Duration(nc.config.DefaultMonitoringIntervalSeconds) * time.Second).C
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker:
+			lines, err := tailer.ReadLines()
+
+			if err != nil {
+				nc.logger.Debugf("error %s reading annotations file %s, skipping annotations check", err, nc.config.AnnotationsFilepath)
+
+				continue
+			}
+
+			for _, line := range lines {
+				newAnnotation, err := annotation.Parse(line)
+
+				if err != nil {
+					nc.logger.Debugf("skipping annotation: %s", err)
+
+					continue
+				}
+
+				select {
+				case annotations <- newAnnotation:
+				default:
+					nc.config.DroppedMessages.Drop(AnnotationsChannel)
+				}
+			}
+		}
+	}
+}
+
+// inServiceRestartLoop returns whether systemd is
+// repeatedly restarting the blockchain service
+func (nc *NodeClient) inServiceRestartLoop() bool {
+	return atomic.LoadInt32(&nc.serviceRestartLoop) == 1
+}
+
+// inPoorPeering returns whether most of the peers the
+// node was last connected to were low quality
+func (nc *NodeClient) inPoorPeering() bool {
+	return atomic.LoadInt32(&nc.poorPeering) == 1
+}
+
+// inIdentityMismatch returns whether the endpoint last resolved
+// to a node other than the expected (pinned) node
+func (nc *NodeClient) inIdentityMismatch() bool {
+	return atomic.LoadInt32(&nc.identityMismatch) == 1
+}
+
+// inUpgrade returns whether the node is
+// halted for a scheduled upgrade
+func (nc *NodeClient) inUpgrade() bool {
+	return atomic.LoadInt32(&nc.upgradeInProgress) == 1
+}
+
+// inChainDegraded returns whether the voting power online
+// was last below the degraded threshold
+func (nc *NodeClient) inChainDegraded() bool {
+	return atomic.LoadInt32(&nc.chainDegraded) == 1
+}
+
+// inMaintenance returns whether the doctor is in maintenance mode
+func (nc *NodeClient) inMaintenance() bool {
+	return nc.config.Maintenance != nil && nc.config.Maintenance.Enabled()
+}
+
+// inRestartBreak returns whether autohealing hit its restart
+// cap and is stopped until the restart breaker is reset
+func (nc *NodeClient) inRestartBreak() bool {
+	return nc.restartBreaker.Tripped()
+}
+
+// ResetRestartBreaker resets the autoheal restart breaker,
+// allowing autohealing to restart the node again
+func (nc *NodeClient) ResetRestartBreaker() {
+	nc.restartBreaker.Reset()
+}
+
+// inChainDivergence returns whether the node's hashes last
+// differed from those of the reference node
+func (nc *NodeClient) inChainDivergence() bool {
+	return atomic.LoadInt32(&nc.chainDiverged) == 1
+}
+
+// inChainIdMismatch returns whether the node was last
+// seen on a chain other than the expected chain
+func (nc *NodeClient) inChainIdMismatch() bool {
+	return atomic.LoadInt32(&nc.chainIdMismatch) == 1
+}
+
+// checkNodeIdentity returns a description of how the node
+// differs from the expected node, or an empty string if the
+// node matches (or no expected node is configured)
+func (nc *NodeClient) checkNodeIdentity(nodeInfo kava.NodeInfo) string {
+	if nc.config.ExpectedNodeId != "" && nodeInfo.Id != nc.config.ExpectedNodeId {
+		return fmt.Sprintf("endpoint resolved to node %s (%s) instead of expected node %s", nodeInfo.Id, nodeInfo.Moniker, nc.config.ExpectedNodeId)
+	}
+
+	if nc.config.ExpectedNodeMoniker != "" && nodeInfo.Moniker != nc.config.ExpectedNodeMoniker {
+		return fmt.Sprintf("endpoint resolved to node %s with moniker %s instead of expected moniker %s", nodeInfo.Id, nodeInfo.Moniker, nc.config.ExpectedNodeMoniker)
+	}
+
+	return ""
+}
+
+// RestartBlockchainService restarts the blockchain's systemd service
+// publishing a heal event for the restart and returning error (if any)
+// if systemd is already repeatedly restarting the service
+// `ErrServiceRestartLoop` is returned without restarting it
+// if the endpoint last resolved to a node other than the expected
+// node `ErrNodeIdentityMismatch` is returned without restarting it
+// if the node was last seen on a chain other than the expected
+// chain `kava.ErrChainIdMismatch` is returned without restarting it
+// if the node last diverged from the reference node `ErrChainDiverged`
+// is returned without restarting it
+// if the node is halted for a scheduled upgrade `ErrUpgradeInProgress`
+// is returned without restarting it
+// if the doctor is in maintenance mode `ErrMaintenanceMode`
+// is returned without restarting it
+// if the voting power online was last below the degraded threshold
+// `ErrChainDegraded` is returned without restarting it
+// if restarting the service would exceed the autoheal restart caps
+// (or already did) an error wrapping `heal.ErrRestartCapReached`
+// is returned without restarting it
+// if the node is being healed through the control api an error
+// wrapping `ErrHealInProgress` is returned without restarting it
+// if restart verification is enabled and the node doesn't come back
+// up an error wrapping `heal.ErrRestartNotVerified` is returned after
+// restarting it
+func (nc *NodeClient) RestartBlockchainService() error {
+	if !nc.healLock.TryRLock() {
+		return fmt.Errorf("%w, not restarting %s", ErrHealInProgress, nc.config.AutohealBlockchainServiceName)
+	}
+
+	defer nc.healLock.RUnlock()
+
+	if err := nc.restartBlocked(); err != nil {
+		return err
+	}
+
+	if err := nc.restartBreaker.Allow(time.Now()); err != nil {
+		return fmt.Errorf("%w, not restarting %s", err, nc.config.AutohealBlockchainServiceName)
+	}
+
+	err := nc.serviceManager.Restart()
+
+	event := incident.Event{
+		Type:        incident.HealActionEventType,
+		HealAction:  incident.RestartServiceHealAction,
+		Succeeded:   err == nil,
+		EndpointURL: nc.config.RPCEndpoint,
+		Message:     fmt.Sprintf("restarted %s service", nc.config.AutohealBlockchainServiceName),
+		OccurredAt:  time.Now(),
+	}
+
+	if err != nil {
+		event.Message = fmt.Sprintf("error %s restarting %s service", err, nc.config.AutohealBlockchainServiceName)
+	}
+
+	if err == nil && nc.config.AutohealRestartVerificationSeconds > 0 {
+		err = heal.VerifyRestart(context.Background(), nc.Client, time.Duration(nc.config.AutohealRestartVerificationSeconds)*time.Second)
+
+		if err != nil {
+			event.Succeeded = false
+			event.Message = fmt.Sprintf("restarted %s service but %s", nc.config.AutohealBlockchainServiceName, err)
+
+			nc.logger.Errorf("error %s verifying restart of %s service", err, nc.config.AutohealBlockchainServiceName)
+		}
+	}
+
+	nc.publishIncidentEvent(event)
+
+	return err
+}
+
+// restartBlocked returns the reason (if any) the blockchain
+// service shouldn't be restarted as described by RestartBlockchainService
+func (nc *NodeClient) restartBlocked() error {
+	if nc.inServiceRestartLoop() {
+		return fmt.Errorf("%w %s, not restarting", ErrServiceRestartLoop, nc.config.AutohealBlockchainServiceName)
+	}
+
+	return nc.healBlocked()
+}
+
+// healBlocked returns the reason (if any) the node shouldn't be acted
+// on to heal it, checked before each step of the autoheal strategies
+// as healing an out of sync node can take long enough for one of the
+// conditions autohealing is skipped for to start meanwhile
+// a service restart loop doesn't block healing as the strategy chain
+// escalates past restarting the service instead
+func (nc *NodeClient) healBlocked() error {
+	if nc.inIdentityMismatch() {
+		return fmt.Errorf("%w, not restarting %s", ErrNodeIdentityMismatch, nc.config.AutohealBlockchainServiceName)
+	}
+
+	if nc.inChainIdMismatch() {
+		return fmt.Errorf("%w, not restarting %s", kava.ErrChainIdMismatch, nc.config.AutohealBlockchainServiceName)
+	}
+
+	if nc.inChainDivergence() {
+		return fmt.Errorf("%w, not restarting %s", ErrChainDiverged, nc.config.AutohealBlockchainServiceName)
+	}
+
+	if nc.inUpgrade() {
+		return fmt.Errorf("%w, not restarting %s", ErrUpgradeInProgress, nc.config.AutohealBlockchainServiceName)
+	}
+
+	if nc.inMaintenance() {
+		return fmt.Errorf("%w, not restarting %s", ErrMaintenanceMode, nc.config.AutohealBlockchainServiceName)
+	}
+
+	if nc.inChainDegraded() {
+		return fmt.Errorf("%w, not restarting %s", ErrChainDegraded, nc.config.AutohealBlockchainServiceName)
+	}
+
+	return nil
+}
+
+// restartFrozenNode restarts the blockchain service of a frozen node,
+// refreshing its peers first if most of the peers it's connected to
+// are low quality and peers or seeds to refresh from are configured
+// as restarting alone would likely reconnect it to the same peers
+// returning error (if any) as described by RestartBlockchainService
+func (nc *NodeClient) restartFrozenNode(ctx context.Context, nodeId string) error {
+	if !nc.inPoorPeering() || (len(nc.config.AutohealRefreshPeers) == 0 && len(nc.config.AutohealRefreshSeeds) == 0) {
+		return nc.RestartBlockchainService()
+	}
+
+	if !nc.healLock.TryRLock() {
+		return fmt.Errorf("%w, not restarting %s", ErrHealInProgress, nc.config.AutohealBlockchainServiceName)
+	}
+
+	defer nc.healLock.RUnlock()
+
+	if err := nc.restartBlocked(); err != nil {
+		return err
+	}
+
+	strategy, err := heal.NewStrategy(heal.RefreshPeersStrategyName, heal.StrategyConfig{
+		BlockchainServiceName: nc.config.AutohealBlockchainServiceName,
+		Blocked:               nc.restartBlocked,
+		ServiceManager:        nc.serviceManager,
+		RestartBreaker:        nc.restartBreaker,
+		NodeHome:              nc.config.NodeHome,
+		RefreshPeers:          nc.config.AutohealRefreshPeers,
+		RefreshSeeds:          nc.config.AutohealRefreshSeeds,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return strategy.Heal(ctx, nc.logger, nc.Client, heal.HealerConfig{
+		EndpointURL:       nc.config.RPCEndpoint,
+		NodeId:            nodeId,
+		PoorPeering:       true,
+		IncidentPublisher: nc.config.IncidentPublisher,
+	})
+}
+
+// publishIncidentEvent publishes the event (in the background to avoid
+// blocking the monitoring routines) to the configured incident publisher
+// if any, logging any errors encountered publishing the event, and
+// publishes incidents opening and closing as checks of the node failing
+// and recovering to the event bus (if any)
+func (nc *NodeClient) publishIncidentEvent(incidentEvent incident.Event) {
+	switch incidentEvent.Type {
+	case incident.IncidentOpenedEventType:
+		nc.publishEvent(event.HealthCheckFailedType, incidentEvent.NodeId, incidentEvent.Kind, incidentEvent.Message, incidentEvent.OccurredAt)
+	case incident.IncidentClosedEventType:
+		nc.publishEvent(event.NodeRecoveredType, incidentEvent.NodeId, incidentEvent.Kind, incidentEvent.Message, incidentEvent.OccurredAt)
+	}
+
+	if nc.config.IncidentPublisher == nil {
+		return
+	}
+
+	go func() {
+		err := nc.config.IncidentPublisher.Publish(incidentEvent)
+
+		if err != nil {
+			nc.logger.Errorf("error %s publishing incident event %+v", err, incidentEvent)
+		}
+	}()
+}
+
+// observeAnomalies scores the sample of the signal against the node's
+// baseline, logging and publishing an anomaly detected event to the
+// event bus (if any) when the signal starts deviating from its baseline
+func (nc *NodeClient) observeAnomalies(anomalies *anomaly.Detector, logger *logging.Logger, nodeId string, signal string, value float64, unit string, sampledAt time.Time) {
+	observation := anomalies.Observe(nodeId, signal, value)
+
+	switch {
+	case observation.Started:
+		message := fmt.Sprintf("%s of %.1f %s has a z-score of %.1f against the node's baseline of %.1f %s", signal, value, unit, observation.ZScore, observation.Median, unit)
+
+		logger.Warnf("anomaly detected, %s", message)
+
+		nc.publishEvent(event.AnomalyDetectedType, nodeId, signal, message, sampledAt)
+	case observation.Ended:
+		logger.Infof("%s of %.1f %s is back within the node's baseline of %.1f %s", signal, value, unit, observation.Median, unit)
+	}
+}
+
+// restoreAnomalyBaselines restores the baselines saved to the
+// anomaly baselines file (if any) to the detector, logging any
+// errors loading the baselines
+func (nc *NodeClient) restoreAnomalyBaselines(anomalies *anomaly.Detector) {
+	if nc.config.AnomalyBaselinesFilepath == "" {
+		return
+	}
+
+	state, err := anomaly.LoadState(nc.config.AnomalyBaselinesFilepath)
+
+	if err != nil {
+		nc.logger.Warnf("error %s loading anomaly baselines, anomalies won't be detected until new baselines are collected", err)
+
+		return
+	}
+
+	anomalies.Restore(state.Baselines)
+
+	if len(state.Baselines) > 0 {
+		nc.logger.Infof("restored anomaly baselines of %d nodes saved at %s", len(state.Baselines), nc.config.TimeFormat.Format(state.SavedAt))
+	}
+}
+
+// saveAnomalyBaselines saves the baselines of the detector (if any)
+// to the anomaly baselines file (if any), logging any errors
+func (nc *NodeClient) saveAnomalyBaselines(anomalies *anomaly.Detector) {
+	if anomalies == nil || nc.config.AnomalyBaselinesFilepath == "" {
+		return
+	}
+
+	err := anomaly.SaveState(nc.config.AnomalyBaselinesFilepath, anomaly.State{
+		SavedAt:   time.Now(),
+		Baselines: anomalies.Baselines(),
+	})
+
+	if err != nil {
+		nc.logger.Errorf("error %s saving anomaly baselines to %s", err, nc.config.AnomalyBaselinesFilepath)
+	}
+}
+
+// publishAutohealStarted publishes autohealing starting to act on the
+// specified kind of incident with the node to the event bus (if any)
+func (nc *NodeClient) publishAutohealStarted(nodeId string, incidentKind string, message string) {
+	nc.publishEvent(event.AutohealStartedType, nodeId, incidentKind, message, time.Now())
+}
+
+// publishEvent publishes an event of the specified type
+// about the endpoint to the event bus (if any)
+func (nc *NodeClient) publishEvent(eventType string, nodeId string, source string, message string, occurredAt time.Time) {
+	nc.config.Events.Publish(event.Event{
+		Type:        eventType,
+		NodeId:      nodeId,
+		EndpointURL: nc.config.RPCEndpoint,
+		Source:      source,
+		Message:     message,
+		OccurredAt:  occurredAt,
+	})
 }
@@ -0,0 +1,307 @@
+// package preflight provides checks for catching provisioning
+// errors (e.g. the wrong genesis file) on a freshly provisioned
+// node before they surface as confusing sync failures hours later
+package preflight
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	DefaultGenesisFile     = "config/genesis.json"
+	DefaultAddressBookFile = "config/addrbook.json"
+	NodeConfigFile         = "config/config.toml"
+	DefaultDialTimeout     = 3 * time.Second
+)
+
+// Config wraps values for running preflight checks against a node
+type Config struct {
+	// home directory of the node, e.g. ~/.kava
+	NodeHome string
+	// if set, hex encoded sha256 hash the node's
+	// genesis file is expected to have
+	ExpectedGenesisSHA256 string
+	// how long to wait when checking a port is reachable
+	DialTimeout time.Duration
+}
+
+// Result is the outcome of a single preflight check
+type Result struct {
+	Name   string
+	Passed bool
+	// why the check passed or failed
+	Detail string
+}
+
+// Passed returns whether all the checks passed
+func Passed(results []Result) bool {
+	for _, result := range results {
+		if !result.Passed {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Run runs all preflight checks for the node,
+// returning the result of each check in order
+func Run(config Config) []Result {
+	dialTimeout := DefaultDialTimeout
+
+	if config.DialTimeout > 0 {
+		dialTimeout = config.DialTimeout
+	}
+
+	nodeConfig := viper.New()
+	nodeConfig.SetConfigFile(filepath.Join(config.NodeHome, NodeConfigFile))
+	nodeConfig.SetConfigType("toml")
+
+	nodeConfig.SetDefault("genesis_file", DefaultGenesisFile)
+	nodeConfig.SetDefault("p2p.addr_book_file", DefaultAddressBookFile)
+
+	configErr := nodeConfig.ReadInConfig()
+
+	results := []Result{
+		checkNodeConfig(nodeConfig, configErr),
+		checkGenesisFile(resolve(config.NodeHome, nodeConfig.GetString("genesis_file")), config.ExpectedGenesisSHA256),
+		checkAddressBook(resolve(config.NodeHome, nodeConfig.GetString("p2p.addr_book_file")), nodeConfig),
+	}
+
+	// the listen addresses are only known if the config was read
+	if configErr != nil {
+		return results
+	}
+
+	return append(results,
+		checkPortReachable("p2p port reachable", nodeConfig.GetString("p2p.laddr"), dialTimeout),
+		checkPortReachable("rpc port reachable", nodeConfig.GetString("rpc.laddr"), dialTimeout),
+	)
+}
+
+// checkNodeConfig checks the values in the node's
+// config.toml are consistent with a node that can sync
+func checkNodeConfig(nodeConfig *viper.Viper, configErr error) Result {
+	result := Result{
+		Name: "config.toml values",
+	}
+
+	if configErr != nil {
+		result.Detail = fmt.Sprintf("could not read %s: %s", nodeConfig.ConfigFileUsed(), configErr)
+
+		return result
+	}
+
+	var problems []string
+
+	if nodeConfig.GetString("moniker") == "" {
+		problems = append(problems, "moniker is not set")
+	}
+
+	for _, key := range []string{"p2p.laddr", "rpc.laddr"} {
+		if _, err := listenPort(nodeConfig.GetString(key)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s %s", key, err))
+		}
+	}
+
+	if nodeConfig.GetBool("statesync.enable") {
+		if len(splitList(nodeConfig.GetString("statesync.rpc_servers"))) < 2 {
+			problems = append(problems, "statesync is enabled but less than two statesync.rpc_servers are set")
+		}
+
+		if nodeConfig.GetInt64("statesync.trust_height") <= 0 || nodeConfig.GetString("statesync.trust_hash") == "" {
+			problems = append(problems, "statesync is enabled but statesync.trust_height and statesync.trust_hash are not set")
+		}
+	}
+
+	if len(problems) > 0 {
+		result.Detail = strings.Join(problems, ", ")
+
+		return result
+	}
+
+	result.Passed = true
+	result.Detail = fmt.Sprintf("moniker %s", nodeConfig.GetString("moniker"))
+
+	return result
+}
+
+// checkGenesisFile checks the genesis file exists, is valid json
+// and (if expected hash is set) has the expected sha256 hash
+func checkGenesisFile(genesisFilepath string, expectedSHA256 string) Result {
+	result := Result{
+		Name: "genesis file",
+	}
+
+	genesis, err := os.ReadFile(genesisFilepath)
+
+	if err != nil {
+		result.Detail = err.Error()
+
+		return result
+	}
+
+	var genesisDoc struct {
+		ChainId string `json:"chain_id"`
+	}
+
+	err = json.Unmarshal(genesis, &genesisDoc)
+
+	if err != nil {
+		result.Detail = fmt.Sprintf("%s is not valid json: %s", genesisFilepath, err)
+
+		return result
+	}
+
+	hash := sha256.Sum256(genesis)
+	actualSHA256 := hex.EncodeToString(hash[:])
+
+	if expectedSHA256 != "" && !strings.EqualFold(actualSHA256, expectedSHA256) {
+		result.Detail = fmt.Sprintf("sha256 %s of %s does not match expected %s", actualSHA256, genesisFilepath, expectedSHA256)
+
+		return result
+	}
+
+	result.Passed = true
+	result.Detail = fmt.Sprintf("chain id %s, sha256 %s", genesisDoc.ChainId, actualSHA256)
+
+	if expectedSHA256 == "" {
+		result.Detail += " (no expected hash set)"
+	}
+
+	return result
+}
+
+// checkAddressBook checks the node has peers to dial, either
+// from an existing address book or configured seeds or peers
+func checkAddressBook(addressBookFilepath string, nodeConfig *viper.Viper) Result {
+	result := Result{
+		Name: "address book",
+	}
+
+	fileInfo, err := os.Stat(addressBookFilepath)
+
+	if err == nil && fileInfo.Size() > 0 {
+		result.Passed = true
+		result.Detail = fmt.Sprintf("%s present", addressBookFilepath)
+
+		return result
+	}
+
+	peers := len(splitList(nodeConfig.GetString("p2p.seeds"))) + len(splitList(nodeConfig.GetString("p2p.persistent_peers")))
+
+	if peers > 0 {
+		result.Passed = true
+		result.Detail = fmt.Sprintf("%s not present, %d seeds and persistent peers configured", addressBookFilepath, peers)
+
+		return result
+	}
+
+	result.Detail = fmt.Sprintf("%s not present and no p2p.seeds or p2p.persistent_peers configured", addressBookFilepath)
+
+	return result
+}
+
+// checkPortReachable checks the port the
+// node is configured to listen on accepts connections
+func checkPortReachable(name string, listenAddress string, dialTimeout time.Duration) Result {
+	result := Result{
+		Name: name,
+	}
+
+	address, err := dialAddress(listenAddress)
+
+	if err != nil {
+		result.Detail = err.Error()
+
+		return result
+	}
+
+	connection, err := net.DialTimeout("tcp", address, dialTimeout)
+
+	if err != nil {
+		result.Detail = err.Error()
+
+		return result
+	}
+
+	connection.Close()
+
+	result.Passed = true
+	result.Detail = fmt.Sprintf("%s accepting connections", address)
+
+	return result
+}
+
+// listenPort returns the port of a tendermint
+// listen address (e.g. tcp://0.0.0.0:26656)
+func listenPort(listenAddress string) (string, error) {
+	address, err := dialAddress(listenAddress)
+
+	if err != nil {
+		return "", err
+	}
+
+	_, port, err := net.SplitHostPort(address)
+
+	return port, err
+}
+
+// dialAddress returns the host and port to dial to connect
+// to a tendermint listen address, dialing the loopback
+// address if the node listens on all interfaces
+func dialAddress(listenAddress string) (string, error) {
+	if listenAddress == "" {
+		return "", fmt.Errorf("listen address is not set")
+	}
+
+	parsedAddress, err := url.Parse(listenAddress)
+
+	if err != nil || parsedAddress.Scheme != "tcp" || parsedAddress.Port() == "" {
+		return "", fmt.Errorf("invalid listen address %s, expected the form tcp://<host>:<port>", listenAddress)
+	}
+
+	host := parsedAddress.Hostname()
+
+	if host == "" || net.ParseIP(host).IsUnspecified() {
+		host = "127.0.0.1"
+	}
+
+	return net.JoinHostPort(host, parsedAddress.Port()), nil
+}
+
+// resolve returns the path relative to
+// the node's home directory if not absolute
+func resolve(nodeHome string, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+
+	return filepath.Join(nodeHome, path)
+}
+
+// splitList splits a comma separated list, ignoring empty elements
+func splitList(list string) []string {
+	var elements []string
+
+	for _, element := range strings.Split(list, ",") {
+		element = strings.TrimSpace(element)
+
+		if element != "" {
+			elements = append(elements, element)
+		}
+	}
+
+	return elements
+}
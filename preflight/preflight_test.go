@@ -0,0 +1,60 @@
+package preflight
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunReportsChecklist(t *testing.T) {
+	nodeHome := t.TempDir()
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(nodeHome, "config"), 0755))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	defer listener.Close()
+
+	nodeConfig := fmt.Sprintf(`moniker = "test-node"
+
+[rpc]
+laddr = "tcp://%s"
+
+[p2p]
+laddr = "tcp://127.0.0.1:1"
+seeds = ""
+persistent_peers = ""
+
+[statesync]
+enable = true
+rpc_servers = "localhost:26657"
+`, listener.Addr())
+
+	assert.Nil(t, os.WriteFile(filepath.Join(nodeHome, NodeConfigFile), []byte(nodeConfig), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(nodeHome, DefaultGenesisFile), []byte(`{"chain_id":"kava_2222-10"}`), 0644))
+
+	results := Run(Config{
+		NodeHome:              nodeHome,
+		ExpectedGenesisSHA256: "0000",
+	})
+
+	passed := make(map[string]bool)
+
+	for _, result := range results {
+		passed[result.Name] = result.Passed
+	}
+
+	assert.Equal(t, map[string]bool{
+		"config.toml values": false,
+		"genesis file":       false,
+		"address book":       false,
+		"p2p port reachable": false,
+		"rpc port reachable": true,
+	}, passed)
+	assert.False(t, Passed(results))
+}
@@ -0,0 +1,269 @@
+// package secrets resolves references to secrets in config values
+// (e.g. webhook urls with embedded tokens) so secrets don't have to
+// be stored in the config file itself
+package secrets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	// prefix of values that reference a file holding the secret
+	FileReferencePrefix = "@"
+	// prefix of values that reference an AWS Secrets Manager secret,
+	// optionally followed by #<key> to use a key of a json secret
+	SecretsManagerReferencePrefix = "secretsmanager:"
+	// prefix of values that reference an
+	// AWS Systems Manager Parameter Store parameter
+	ParameterStoreReferencePrefix = "ssm:"
+)
+
+var (
+	ErrMissingEnvironmentVariable = errors.New("environment variable not set")
+
+	// ${NAME} references to environment variables, the
+	// bare $NAME form isn't supported so that values
+	// containing a literal $ aren't mangled
+	environmentVariableReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+)
+
+// Store looks up the values of secrets by name
+type Store interface {
+	GetSecret(name string) (string, error)
+}
+
+// ResolverConfig wraps values used to create a Resolver
+type ResolverConfig struct {
+	// region of the default AWS secret stores
+	AWSRegion string
+	// optional stores to resolve references with instead of
+	// AWS Secrets Manager and Parameter Store e.g. for testing
+	SecretsManager Store
+	ParameterStore Store
+}
+
+// Resolver resolves references to secrets in config values
+type Resolver struct {
+	secretsManager Store
+	parameterStore Store
+}
+
+// NewResolver creates a new Resolver using the specified config,
+// AWS sessions are only created once an AWS secret is referenced
+// so resolving values without AWS references doesn't require AWS
+func NewResolver(config ResolverConfig) *Resolver {
+	secretsManager := config.SecretsManager
+
+	if secretsManager == nil {
+		secretsManager = &secretsManagerStore{
+			awsStore: awsStore{awsRegion: config.AWSRegion},
+		}
+	}
+
+	parameterStore := config.ParameterStore
+
+	if parameterStore == nil {
+		parameterStore = &parameterStoreStore{
+			awsStore: awsStore{awsRegion: config.AWSRegion},
+		}
+	}
+
+	return &Resolver{
+		secretsManager: secretsManager,
+		parameterStore: parameterStore,
+	}
+}
+
+// Resolve interpolates ${NAME} environment variable references in
+// the value and then, if the value is a reference to a secret
+// (@<filepath>, secretsmanager:<secret id>[#<key>] or ssm:<parameter
+// name>) returns the referenced secret, otherwise returning the
+// interpolated value as is, returning error (if any) resolving it
+// Errors never include the resolved secret
+func (r *Resolver) Resolve(value string) (string, error) {
+	value, err := interpolateEnvironment(value)
+
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.HasPrefix(value, FileReferencePrefix):
+		secretFilepath, err := homedir.Expand(strings.TrimPrefix(value, FileReferencePrefix))
+
+		if err != nil {
+			return "", fmt.Errorf("error %s trying to expand home directory for secret file %s", err, value)
+		}
+
+		contents, err := os.ReadFile(secretFilepath)
+
+		if err != nil {
+			return "", fmt.Errorf("error %s reading secret file %s", err, secretFilepath)
+		}
+
+		// files written by editors and orchestrators
+		// usually end with a trailing new line
+		return strings.TrimRight(string(contents), "\r\n"), nil
+	case strings.HasPrefix(value, SecretsManagerReferencePrefix):
+		secretId := strings.TrimPrefix(value, SecretsManagerReferencePrefix)
+
+		var key string
+
+		if index := strings.LastIndex(secretId, "#"); index >= 0 {
+			secretId, key = secretId[:index], secretId[index+1:]
+		}
+
+		secret, err := r.secretsManager.GetSecret(secretId)
+
+		if err != nil {
+			return "", fmt.Errorf("error %s getting secret %s from secrets manager", err, secretId)
+		}
+
+		if key == "" {
+			return secret, nil
+		}
+
+		var keyedSecret map[string]interface{}
+
+		if err := json.Unmarshal([]byte(secret), &keyedSecret); err != nil {
+			return "", fmt.Errorf("secret %s from secrets manager must be a json object to get key %s", secretId, key)
+		}
+
+		keyedValue, exists := keyedSecret[key]
+
+		if !exists {
+			return "", fmt.Errorf("secret %s from secrets manager has no key %s", secretId, key)
+		}
+
+		if stringValue, isString := keyedValue.(string); isString {
+			return stringValue, nil
+		}
+
+		return fmt.Sprint(keyedValue), nil
+	case strings.HasPrefix(value, ParameterStoreReferencePrefix):
+		name := strings.TrimPrefix(value, ParameterStoreReferencePrefix)
+
+		secret, err := r.parameterStore.GetSecret(name)
+
+		if err != nil {
+			return "", fmt.Errorf("error %s getting parameter %s from parameter store", err, name)
+		}
+
+		return secret, nil
+	}
+
+	return value, nil
+}
+
+// interpolateEnvironment replaces each ${NAME} in the value with
+// the value of the environment variable, returning an error wrapping
+// ErrMissingEnvironmentVariable if any variable isn't set
+func interpolateEnvironment(value string) (string, error) {
+	var err error
+
+	interpolated := environmentVariableReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := environmentVariableReference.FindStringSubmatch(reference)[1]
+
+		environmentValue, set := os.LookupEnv(name)
+
+		if !set && err == nil {
+			err = fmt.Errorf("%w: %s", ErrMissingEnvironmentVariable, name)
+		}
+
+		return environmentValue
+	})
+
+	return interpolated, err
+}
+
+// awsStore lazily creates the AWS session
+// used to look up secrets on first use
+type awsStore struct {
+	awsRegion  string
+	once       sync.Once
+	awsSession *session.Session
+	sessionErr error
+}
+
+// session returns the AWS session for
+// the store and error (if any) creating it
+func (as *awsStore) session() (*session.Session, error) {
+	as.once.Do(func() {
+		as.awsSession, as.sessionErr = session.NewSession(&aws.Config{
+			Region: aws.String(as.awsRegion),
+		})
+
+		if as.sessionErr != nil {
+			as.sessionErr = fmt.Errorf("error %s creating valid aws session", as.sessionErr)
+		}
+	})
+
+	return as.awsSession, as.sessionErr
+}
+
+// secretsManagerStore implements the Store
+// interface using AWS Secrets Manager
+type secretsManagerStore struct {
+	awsStore
+}
+
+// GetSecret returns the current string value of the secret
+func (sms *secretsManagerStore) GetSecret(secretId string) (string, error) {
+	awsSession, err := sms.session()
+
+	if err != nil {
+		return "", err
+	}
+
+	output, err := secretsmanager.New(awsSession).GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretId),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if output.SecretString == nil {
+		return string(output.SecretBinary), nil
+	}
+
+	return aws.StringValue(output.SecretString), nil
+}
+
+// parameterStoreStore implements the Store interface
+// using AWS Systems Manager Parameter Store
+type parameterStoreStore struct {
+	awsStore
+}
+
+// GetSecret returns the (decrypted) value of the parameter
+func (pss *parameterStoreStore) GetSecret(name string) (string, error) {
+	awsSession, err := pss.session()
+
+	if err != nil {
+		return "", err
+	}
+
+	output, err := ssm.New(awsSession).GetParameter(&ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(output.Parameter.Value), nil
+}
@@ -0,0 +1,104 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapStore implements the Store interface
+// using a map of secret names to values
+type mapStore map[string]string
+
+func (ms mapStore) GetSecret(name string) (string, error) {
+	secret, exists := ms[name]
+
+	if !exists {
+		return "", errors.New("secret not found")
+	}
+
+	return secret, nil
+}
+
+func newTestResolver() *Resolver {
+	return NewResolver(ResolverConfig{
+		SecretsManager: mapStore{
+			"doctor/webhook": `{"url": "https://hooks.example.com/token", "retries": 3}`,
+			"doctor/token":   "secretsmanager-token",
+		},
+		ParameterStore: mapStore{
+			"/doctor/webhook-url": "https://hooks.example.com/parameter-token",
+		},
+	})
+}
+
+func TestResolvePassesThroughPlainValues(t *testing.T) {
+	resolved, err := newTestResolver().Resolve("https://hooks.example.com/$literal")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://hooks.example.com/$literal", resolved)
+}
+
+func TestResolveInterpolatesEnvironmentVariables(t *testing.T) {
+	t.Setenv("DOCTOR_TEST_TOKEN", "env-token")
+
+	resolved, err := newTestResolver().Resolve("https://hooks.example.com/${DOCTOR_TEST_TOKEN}")
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://hooks.example.com/env-token", resolved)
+
+	_, err = newTestResolver().Resolve("https://hooks.example.com/${DOCTOR_TEST_UNSET_TOKEN}")
+
+	assert.True(t, errors.Is(err, ErrMissingEnvironmentVariable))
+}
+
+func TestResolveReadsFileReferences(t *testing.T) {
+	secretFilepath := filepath.Join(t.TempDir(), "webhook_url")
+
+	err := os.WriteFile(secretFilepath, []byte("https://hooks.example.com/file-token\n"), 0600)
+	assert.Nil(t, err)
+
+	resolved, err := newTestResolver().Resolve("@" + secretFilepath)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "https://hooks.example.com/file-token", resolved)
+
+	_, err = newTestResolver().Resolve("@" + secretFilepath + ".missing")
+
+	assert.NotNil(t, err)
+}
+
+func TestResolveLooksUpAWSReferences(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{"secretsmanager:doctor/token", "secretsmanager-token"},
+		{"secretsmanager:doctor/webhook#url", "https://hooks.example.com/token"},
+		{"secretsmanager:doctor/webhook#retries", "3"},
+		{"ssm:/doctor/webhook-url", "https://hooks.example.com/parameter-token"},
+	}
+
+	for _, testCase := range testCases {
+		resolved, err := newTestResolver().Resolve(testCase.value)
+
+		assert.Nil(t, err, testCase.value)
+		assert.Equal(t, testCase.expected, resolved, testCase.value)
+	}
+
+	for _, value := range []string{"secretsmanager:doctor/missing", "secretsmanager:doctor/token#url", "secretsmanager:doctor/webhook#missing", "ssm:/doctor/missing"} {
+		_, err := newTestResolver().Resolve(value)
+
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestResolveErrorsDontIncludeSecrets(t *testing.T) {
+	_, err := newTestResolver().Resolve("secretsmanager:doctor/token#url")
+
+	assert.NotNil(t, err)
+	assert.NotContains(t, err.Error(), "secretsmanager-token")
+}
@@ -0,0 +1,361 @@
+// package slo tracks service level objectives declared by operators
+// (e.g. 99.9% uptime or 99% of status checks faster than 500ms over 30
+// days), measuring how much of each objective's error budget has been
+// spent and how fast it's burning from the doctor's uptime and status
+// check latency samples
+package slo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// the endpoint is up for the target percent of uptime samples
+	UptimeObjectiveKind = "uptime"
+	// the target percent of status checks are faster than a threshold
+	LatencyObjectiveKind = "latency"
+
+	// resolution samples are counted at, so that only one count of
+	// good and total samples is retained per bucket per objective
+	BucketDuration = time.Minute
+
+	// burn rate over both windows above which the error budget will
+	// be spent in (window / threshold) e.g. ~2 days of a 30 day window
+	FastBurnRateThreshold = 14.4
+	// burn rate over both windows above which the error budget will
+	// be spent in (window / threshold) e.g. 5 days of a 30 day window
+	SlowBurnRateThreshold = 6
+
+	// alerts raised when the error budget is burning too fast
+	FastBurnAlert = "fast_burn"
+	SlowBurnAlert = "slow_burn"
+)
+
+var (
+	ValidObjectiveKinds = []string{UptimeObjectiveKind, LatencyObjectiveKind}
+
+	// long and short windows the burn rate must exceed the fast and slow
+	// thresholds over for alerting, the short window stops the alert
+	// shortly after the budget stops burning
+	FastBurnWindows = []time.Duration{time.Hour, 5 * time.Minute}
+	SlowBurnWindows = []time.Duration{6 * time.Hour, 30 * time.Minute}
+)
+
+// Objective is a service level objective
+// declared by the operator of the node
+type Objective struct {
+	Name string
+	Kind string
+	// percent of samples that must be good over the window
+	TargetPercent float64
+	// for latency objectives, status checks that took
+	// at least as long as the threshold are counted as bad
+	LatencyThreshold time.Duration
+	// window of time the objective is measured over e.g. 30 days
+	Window time.Duration
+}
+
+// ErrorBudget returns the fraction of samples
+// that may be bad while meeting the objective
+func (o Objective) ErrorBudget() float64 {
+	return 1 - o.TargetPercent/100
+}
+
+// ParseObjectives parses objectives in the form
+// <name>=uptime:<target percent>:<window days> or
+// <name>=latency:<target percent>:<threshold milliseconds>:<window days>
+// separated by semicolons, returning the objectives and error (if any)
+func ParseObjectives(rawObjectives string) ([]Objective, error) {
+	var objectives []Objective
+
+	names := make(map[string]bool)
+
+	for _, rawObjective := range strings.Split(rawObjectives, ";") {
+		rawObjective = strings.TrimSpace(rawObjective)
+
+		if rawObjective == "" {
+			continue
+		}
+
+		name, rawSpec, found := strings.Cut(rawObjective, "=")
+		name = strings.TrimSpace(name)
+
+		if !found || name == "" {
+			return nil, fmt.Errorf("invalid slo %s, expected <name>=<kind>:<target percent>[:<threshold milliseconds>]:<window days>", rawObjective)
+		}
+
+		if names[name] {
+			return nil, fmt.Errorf("slo %s defined multiple times", name)
+		}
+
+		names[name] = true
+
+		objective, err := parseObjective(name, strings.Split(rawSpec, ":"))
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid slo %s: %w", name, err)
+		}
+
+		objectives = append(objectives, objective)
+	}
+
+	return objectives, nil
+}
+
+// parseObjective parses the colon separated fields
+// of the objective with the specified name
+func parseObjective(name string, fields []string) (Objective, error) {
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	objective := Objective{
+		Name: name,
+		Kind: fields[0],
+	}
+
+	var rawTarget, rawThreshold, rawWindow string
+
+	switch {
+	case objective.Kind == UptimeObjectiveKind && len(fields) == 3:
+		rawTarget, rawWindow = fields[1], fields[2]
+	case objective.Kind == LatencyObjectiveKind && len(fields) == 4:
+		rawTarget, rawThreshold, rawWindow = fields[1], fields[2], fields[3]
+	case objective.Kind == UptimeObjectiveKind:
+		return Objective{}, fmt.Errorf("expected uptime:<target percent>:<window days>")
+	case objective.Kind == LatencyObjectiveKind:
+		return Objective{}, fmt.Errorf("expected latency:<target percent>:<threshold milliseconds>:<window days>")
+	default:
+		return Objective{}, fmt.Errorf("unsupported kind %s, supported kinds are %v", objective.Kind, ValidObjectiveKinds)
+	}
+
+	target, err := strconv.ParseFloat(rawTarget, 64)
+
+	if err != nil || target <= 0 || target >= 100 {
+		return Objective{}, fmt.Errorf("target percent %s must be a number between 0 and 100 exclusive", rawTarget)
+	}
+
+	objective.TargetPercent = target
+
+	if rawThreshold != "" {
+		thresholdMilliseconds, err := strconv.Atoi(rawThreshold)
+
+		if err != nil || thresholdMilliseconds <= 0 {
+			return Objective{}, fmt.Errorf("threshold %s must be a positive number of milliseconds", rawThreshold)
+		}
+
+		objective.LatencyThreshold = time.Duration(thresholdMilliseconds) * time.Millisecond
+	}
+
+	windowDays, err := strconv.Atoi(rawWindow)
+
+	if err != nil || windowDays <= 0 {
+		return Objective{}, fmt.Errorf("window %s must be a positive number of days", rawWindow)
+	}
+
+	objective.Window = time.Duration(windowDays) * 24 * time.Hour
+
+	return objective, nil
+}
+
+// Status is the state of an objective's error budget
+type Status struct {
+	Name          string  `json:"name"`
+	Kind          string  `json:"kind"`
+	TargetPercent float64 `json:"target_percent"`
+	// number of samples taken over the objective's window
+	Samples int `json:"samples"`
+	// percent of good samples over the objective's window
+	CompliancePercent float64 `json:"compliance_percent"`
+	// percent of the error budget left for the objective's
+	// window, negative once the objective has been missed
+	ErrorBudgetRemainingPercent float64 `json:"error_budget_remaining_percent"`
+	// rate the error budget is spent at over the long fast and slow
+	// burn windows, where a rate of 1 spends exactly the error budget
+	// over the objective's window
+	FastBurnRate float64 `json:"fast_burn_rate"`
+	SlowBurnRate float64 `json:"slow_burn_rate"`
+	// FastBurnAlert, SlowBurnAlert or empty if not burning too fast
+	Alert       string    `json:"alert,omitempty"`
+	EvaluatedAt time.Time `json:"evaluated_at"`
+}
+
+// bucket counts the samples for
+// an objective taken in one minute
+type bucket struct {
+	// unix minute the counts are for
+	minute int64
+	good   int
+	total  int
+}
+
+// objectiveTracker counts the samples for an objective
+// over its window in a ring buffer of buckets
+type objectiveTracker struct {
+	objective Objective
+	buckets   []bucket
+}
+
+// record counts the sample taken at the specified time
+func (o *objectiveTracker) record(good bool, sampledAt time.Time) {
+	minute := sampledAt.Unix() / int64(BucketDuration/time.Second)
+	b := &o.buckets[minute%int64(len(o.buckets))]
+
+	// reuse buckets from before the window
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+
+	b.total++
+
+	if good {
+		b.good++
+	}
+}
+
+// count returns the number of good and total samples
+// taken over the window ending at the specified time
+func (o *objectiveTracker) count(window time.Duration, until time.Time) (int, int) {
+	untilMinute := until.Unix() / int64(BucketDuration/time.Second)
+	minutes := int64(window / BucketDuration)
+
+	if minutes > int64(len(o.buckets)) {
+		minutes = int64(len(o.buckets))
+	}
+
+	var good, total int
+
+	for minute := untilMinute; minute > untilMinute-minutes; minute-- {
+		b := o.buckets[minute%int64(len(o.buckets))]
+
+		if b.minute != minute {
+			continue
+		}
+
+		good += b.good
+		total += b.total
+	}
+
+	return good, total
+}
+
+// burnRate returns the rate the error budget was spent
+// at over the window ending at the specified time
+func (o *objectiveTracker) burnRate(window time.Duration, until time.Time) float64 {
+	good, total := o.count(window, until)
+
+	if total == 0 {
+		return 0
+	}
+
+	return float64(total-good) / float64(total) / o.objective.ErrorBudget()
+}
+
+// burning returns whether the burn rate over every
+// window ending at the specified time exceeds the threshold
+func (o *objectiveTracker) burning(windows []time.Duration, threshold float64, until time.Time) bool {
+	for _, window := range windows {
+		if o.burnRate(window, until) <= threshold {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Tracker tracks the error budgets of objectives
+// from uptime and status check latency samples
+type Tracker struct {
+	objectives []*objectiveTracker
+}
+
+// NewTracker returns a new tracker for the objectives
+func NewTracker(objectives []Objective) *Tracker {
+	var trackers []*objectiveTracker
+
+	for _, objective := range objectives {
+		buckets := int(objective.Window / BucketDuration)
+
+		if buckets < 1 {
+			buckets = 1
+		}
+
+		trackers = append(trackers, &objectiveTracker{
+			objective: objective,
+			buckets:   make([]bucket, buckets),
+		})
+	}
+
+	return &Tracker{
+		objectives: trackers,
+	}
+}
+
+// RecordUptime counts the uptime sample
+// towards each uptime objective
+func (t *Tracker) RecordUptime(up bool, sampledAt time.Time) {
+	for _, tracker := range t.objectives {
+		if tracker.objective.Kind == UptimeObjectiveKind {
+			tracker.record(up, sampledAt)
+		}
+	}
+}
+
+// RecordStatusLatency counts the latency of the status
+// check towards each latency objective
+func (t *Tracker) RecordStatusLatency(latency time.Duration, sampledAt time.Time) {
+	for _, tracker := range t.objectives {
+		if tracker.objective.Kind == LatencyObjectiveKind {
+			tracker.record(latency < tracker.objective.LatencyThreshold, sampledAt)
+		}
+	}
+}
+
+// Statuses returns the status of the error budget of each objective
+// of the specified kind that samples have been taken for, as of the
+// specified time
+func (t *Tracker) Statuses(kind string, evaluatedAt time.Time) []Status {
+	var statuses []Status
+
+	for _, tracker := range t.objectives {
+		objective := tracker.objective
+
+		if objective.Kind != kind {
+			continue
+		}
+
+		good, total := tracker.count(objective.Window, evaluatedAt)
+
+		if total == 0 {
+			continue
+		}
+
+		badFraction := float64(total-good) / float64(total)
+
+		status := Status{
+			Name:                        objective.Name,
+			Kind:                        objective.Kind,
+			TargetPercent:               objective.TargetPercent,
+			Samples:                     total,
+			CompliancePercent:           float64(good) / float64(total) * 100,
+			ErrorBudgetRemainingPercent: (1 - badFraction/objective.ErrorBudget()) * 100,
+			FastBurnRate:                tracker.burnRate(FastBurnWindows[0], evaluatedAt),
+			SlowBurnRate:                tracker.burnRate(SlowBurnWindows[0], evaluatedAt),
+			EvaluatedAt:                 evaluatedAt,
+		}
+
+		switch {
+		case tracker.burning(FastBurnWindows, FastBurnRateThreshold, evaluatedAt):
+			status.Alert = FastBurnAlert
+		case tracker.burning(SlowBurnWindows, SlowBurnRateThreshold, evaluatedAt):
+			status.Alert = SlowBurnAlert
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
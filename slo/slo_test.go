@@ -0,0 +1,122 @@
+package slo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseObjectives(t *testing.T) {
+	objectives, err := ParseObjectives("availability=uptime:99.9:30; fast-status = latency:99:500:7;")
+
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Objective{
+		{
+			Name:          "availability",
+			Kind:          UptimeObjectiveKind,
+			TargetPercent: 99.9,
+			Window:        30 * 24 * time.Hour,
+		},
+		{
+			Name:             "fast-status",
+			Kind:             LatencyObjectiveKind,
+			TargetPercent:    99,
+			LatencyThreshold: 500 * time.Millisecond,
+			Window:           7 * 24 * time.Hour,
+		},
+	}, objectives)
+}
+
+func TestParseObjectivesRejectsInvalidObjectives(t *testing.T) {
+	testCases := []struct {
+		rawObjectives string
+		expectedError string
+	}{
+		{"uptime:99.9:30", "invalid slo uptime:99.9:30, expected <name>=<kind>:<target percent>[:<threshold milliseconds>]:<window days>"},
+		{"a=uptime:99:30;a=uptime:99.9:30", "slo a defined multiple times"},
+		{"a=errors:99:30", "invalid slo a: unsupported kind errors, supported kinds are [uptime latency]"},
+		{"a=uptime:99:500:30", "invalid slo a: expected uptime:<target percent>:<window days>"},
+		{"a=latency:99:30", "invalid slo a: expected latency:<target percent>:<threshold milliseconds>:<window days>"},
+		{"a=uptime:100:30", "invalid slo a: target percent 100 must be a number between 0 and 100 exclusive"},
+		{"a=latency:99:fast:30", "invalid slo a: threshold fast must be a positive number of milliseconds"},
+		{"a=uptime:99:0", "invalid slo a: window 0 must be a positive number of days"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.rawObjectives, func(t *testing.T) {
+			_, err := ParseObjectives(tc.rawObjectives)
+
+			assert.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestTrackerCalculatesErrorBudgetRemaining(t *testing.T) {
+	tracker := NewTracker([]Objective{
+		{Name: "availability", Kind: UptimeObjectiveKind, TargetPercent: 99, Window: 30 * 24 * time.Hour},
+		{Name: "fast-status", Kind: LatencyObjectiveKind, TargetPercent: 90, LatencyThreshold: 500 * time.Millisecond, Window: 30 * 24 * time.Hour},
+	})
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// one bad sample per 200 spends half the uptime error budget,
+	// spread over days so recent burn rates stay low
+	for i := 0; i < 2000; i++ {
+		sampledAt := now.Add(-time.Duration(2000-i) * 5 * time.Minute)
+
+		tracker.RecordUptime(i%200 != 0, sampledAt)
+		tracker.RecordStatusLatency(time.Duration(i%10)*100*time.Millisecond, sampledAt)
+	}
+
+	uptimeStatuses := tracker.Statuses(UptimeObjectiveKind, now)
+
+	assert.Len(t, uptimeStatuses, 1)
+	assert.Equal(t, "availability", uptimeStatuses[0].Name)
+	assert.Equal(t, 2000, uptimeStatuses[0].Samples)
+	assert.InDelta(t, 99.5, uptimeStatuses[0].CompliancePercent, 0.0001)
+	assert.InDelta(t, 50, uptimeStatuses[0].ErrorBudgetRemainingPercent, 0.0001)
+	assert.Empty(t, uptimeStatuses[0].Alert)
+
+	latencyStatuses := tracker.Statuses(LatencyObjectiveKind, now)
+
+	// half of the status checks took at least 500ms
+	assert.Len(t, latencyStatuses, 1)
+	assert.InDelta(t, 50, latencyStatuses[0].CompliancePercent, 0.0001)
+	assert.InDelta(t, -400, latencyStatuses[0].ErrorBudgetRemainingPercent, 0.0001, "objective missed by 4x its error budget")
+}
+
+func TestTrackerAlertsWhenErrorBudgetBurnsFast(t *testing.T) {
+	tracker := NewTracker([]Objective{
+		{Name: "availability", Kind: UptimeObjectiveKind, TargetPercent: 99.9, Window: 30 * 24 * time.Hour},
+	})
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	assert.Empty(t, tracker.Statuses(UptimeObjectiveKind, now), "no samples taken")
+
+	// up for the last day until 10 minutes ago
+	for minutesAgo := 24 * 60; minutesAgo > 10; minutesAgo-- {
+		tracker.RecordUptime(true, now.Add(-time.Duration(minutesAgo)*time.Minute))
+	}
+
+	for minutesAgo := 10; minutesAgo >= 0; minutesAgo-- {
+		tracker.RecordUptime(false, now.Add(-time.Duration(minutesAgo)*time.Minute))
+	}
+
+	statuses := tracker.Statuses(UptimeObjectiveKind, now)
+
+	assert.Len(t, statuses, 1)
+	assert.Equal(t, FastBurnAlert, statuses[0].Alert)
+	assert.Greater(t, statuses[0].FastBurnRate, float64(FastBurnRateThreshold))
+
+	// recovered, the short window stops the alert
+	for minutesAgo := -1; minutesAgo >= -10; minutesAgo-- {
+		tracker.RecordUptime(true, now.Add(-time.Duration(minutesAgo)*time.Minute))
+	}
+
+	statuses = tracker.Statuses(UptimeObjectiveKind, now.Add(10*time.Minute))
+
+	assert.NotEqual(t, FastBurnAlert, statuses[0].Alert)
+}
@@ -0,0 +1,197 @@
+package store
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// how often samples older than the retention
+	// period are deleted from the store
+	DefaultPruneInterval = 1 * time.Hour
+	// how long to wait to acquire a lock on the database
+	// file before assuming another doctor process is using it
+	DefaultBoltOpenTimeout = 5 * time.Second
+)
+
+// BoltStore implements the Store interface,
+// persisting samples to a BoltDB database file
+// with a bucket per node id and samples keyed by
+// the time they were taken at
+type BoltStore struct {
+	db           *bolt.DB
+	retention    time.Duration
+	lastPrunedAt time.Time
+	pruneLock    *sync.Mutex
+}
+
+// NewBoltStore attempts to open (creating if needed) the
+// BoltDB database at the configured path, returning the
+// BoltStore and error (if any)
+func NewBoltStore(config StoreConfig) (*BoltStore, error) {
+	err := os.MkdirAll(filepath.Dir(config.Path), 0700)
+
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(config.Path, 0600, &bolt.Options{Timeout: DefaultBoltOpenTimeout})
+
+	if err != nil {
+		return nil, err
+	}
+
+	store := &BoltStore{
+		db:        db,
+		retention: config.Retention,
+		pruneLock: &sync.Mutex{},
+	}
+
+	// don't load samples that should have already been deleted
+	err = store.prune(time.Now())
+
+	if err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// sampleKey returns a key that sorts samples
+// by the time they were taken at, using the bucket
+// sequence to break ties between samples taken
+// at the same time
+func sampleKey(sampledAt time.Time, sequence uint64) []byte {
+	key := make([]byte, 16)
+
+	binary.BigEndian.PutUint64(key[:8], uint64(sampledAt.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], sequence)
+
+	return key
+}
+
+// Add persists the sample for the node with the provided id,
+// periodically deleting samples older than the retention period
+// Add is safe to call across go-routines
+func (bs *BoltStore) Add(nodeId string, sample Sample) error {
+	encodedSample, err := json.Marshal(sample)
+
+	if err != nil {
+		return err
+	}
+
+	err = bs.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists([]byte(nodeId))
+
+		if err != nil {
+			return err
+		}
+
+		sequence, err := bucket.NextSequence()
+
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(sampleKey(sample.SampledAt(), sequence), encodedSample)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return bs.pruneIfDue(time.Now())
+}
+
+// LoadAll returns all retained samples per node
+// id ordered from oldest to newest
+func (bs *BoltStore) LoadAll() (map[string][]Sample, error) {
+	samples := make(map[string][]Sample)
+
+	err := bs.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(nodeId []byte, bucket *bolt.Bucket) error {
+			var nodeSamples []Sample
+
+			err := bucket.ForEach(func(_ []byte, encodedSample []byte) error {
+				var sample Sample
+
+				err := json.Unmarshal(encodedSample, &sample)
+
+				if err != nil {
+					return err
+				}
+
+				nodeSamples = append(nodeSamples, sample)
+
+				return nil
+			})
+
+			if err != nil {
+				return err
+			}
+
+			samples[string(nodeId)] = nodeSamples
+
+			return nil
+		})
+	})
+
+	return samples, err
+}
+
+// pruneIfDue prunes samples older than the retention period
+// if it's been at least DefaultPruneInterval since the last prune
+func (bs *BoltStore) pruneIfDue(now time.Time) error {
+	bs.pruneLock.Lock()
+	defer bs.pruneLock.Unlock()
+
+	if now.Sub(bs.lastPrunedAt) < DefaultPruneInterval {
+		return nil
+	}
+
+	return bs.prune(now)
+}
+
+// prune deletes all samples taken before the retention period
+// if no retention period is configured samples are kept forever
+func (bs *BoltStore) prune(now time.Time) error {
+	bs.lastPrunedAt = now
+
+	if bs.retention <= 0 {
+		return nil
+	}
+
+	cutoff := sampleKey(now.Add(-bs.retention), 0)
+
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(_ []byte, bucket *bolt.Bucket) error {
+			cursor := bucket.Cursor()
+
+			// keys are sorted by sample time so stop
+			// at the first sample within the retention period
+			for key, _ := cursor.First(); key != nil && bytes.Compare(key, cutoff) < 0; key, _ = cursor.First() {
+				err := cursor.Delete()
+
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+	})
+}
+
+// Close closes the database file
+func (bs *BoltStore) Close() error {
+	return bs.db.Close()
+}
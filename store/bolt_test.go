@@ -0,0 +1,59 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoltStoreLoadsSamplesAcrossRestartsInOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "samples.db")
+	now := time.Now()
+
+	store, err := NewBoltStore(StoreConfig{Path: path, Retention: time.Hour})
+	assert.Nil(t, err)
+
+	for _, sampledAt := range []time.Time{now.Add(-2 * time.Minute), now.Add(-1 * time.Minute), now} {
+		err = store.Add("node", Sample{SyncStatusMetrics: &metric.SyncStatusMetrics{NodeId: "node", SampledAt: sampledAt}})
+		assert.Nil(t, err)
+	}
+
+	err = store.Add("https://rpc.data.kava.io", Sample{UptimeMetric: &metric.UptimeMetric{Up: true, SampledAt: now}})
+	assert.Nil(t, err)
+	assert.Nil(t, store.Close())
+
+	store, err = NewBoltStore(StoreConfig{Path: path, Retention: time.Hour})
+	assert.Nil(t, err)
+	defer store.Close()
+
+	samples, err := store.LoadAll()
+	assert.Nil(t, err)
+	assert.Len(t, samples["node"], 3)
+	assert.Len(t, samples["https://rpc.data.kava.io"], 1)
+	assert.True(t, samples["node"][0].SampledAt().Equal(now.Add(-2*time.Minute)))
+	assert.True(t, samples["node"][2].SampledAt().Equal(now))
+}
+
+func TestBoltStorePrunesSamplesOlderThanRetention(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "samples.db")
+	now := time.Now()
+
+	store, err := NewBoltStore(StoreConfig{Path: path, Retention: time.Hour})
+	assert.Nil(t, err)
+	defer store.Close()
+
+	for _, sampledAt := range []time.Time{now.Add(-3 * time.Hour), now.Add(-2 * time.Hour), now} {
+		err = store.Add("node", Sample{SyncStatusMetrics: &metric.SyncStatusMetrics{NodeId: "node", SampledAt: sampledAt}})
+		assert.Nil(t, err)
+	}
+
+	err = store.prune(now)
+	assert.Nil(t, err)
+
+	samples, err := store.LoadAll()
+	assert.Nil(t, err)
+	assert.Len(t, samples["node"], 1)
+}
@@ -0,0 +1,78 @@
+// package store provides definitions and implementations
+// for persisting metric samples to disk so that the history
+// used for synthetic metric calculations survives restarts
+// of the doctor program
+package store
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kava-labs/doctor/metric"
+)
+
+const (
+	BoltBackend = "bolt"
+)
+
+var (
+	ValidBackends = []string{BoltBackend}
+)
+
+// Sample wraps a single metric sample for a node
+// (or endpoint) to persist
+type Sample struct {
+	SyncStatusMetrics     *metric.SyncStatusMetrics     `json:"sync_status_metrics,omitempty"`
+	UptimeMetric          *metric.UptimeMetric          `json:"uptime_metric,omitempty"`
+	PeerConnectionMetrics *metric.PeerConnectionMetrics `json:"peer_connection_metrics,omitempty"`
+}
+
+// SampledAt returns the time the sample was taken at
+func (s Sample) SampledAt() time.Time {
+	if s.SyncStatusMetrics != nil {
+		return s.SyncStatusMetrics.SampledAt
+	}
+
+	if s.UptimeMetric != nil {
+		return s.UptimeMetric.SampledAt
+	}
+
+	if s.PeerConnectionMetrics != nil {
+		return s.PeerConnectionMetrics.SampledAt
+	}
+
+	return time.Time{}
+}
+
+// Store persists metric samples grouped by
+// node id (or endpoint url) in the order they were added
+type Store interface {
+	// Add persists the sample for the node with the provided id
+	Add(nodeId string, sample Sample) error
+	// LoadAll returns all retained samples per node
+	// id ordered from oldest to newest
+	LoadAll() (map[string][]Sample, error)
+	// Close flushes and releases any resources held by the store
+	Close() error
+}
+
+// StoreConfig wraps values for
+// configuring a sample store
+type StoreConfig struct {
+	Backend string
+	Path    string
+	// how long to retain samples for
+	// before they are deleted from the store
+	Retention time.Duration
+}
+
+// New creates and returns a new store using
+// the configured backend and error (if any)
+func New(config StoreConfig) (Store, error) {
+	switch config.Backend {
+	case BoltBackend:
+		return NewBoltStore(config)
+	}
+
+	return nil, fmt.Errorf("invalid sample store backend %s, valid backends are %v", config.Backend, ValidBackends)
+}
@@ -0,0 +1,250 @@
+// supervisor.go contains a supervisor that owns the lifecycle
+// of the doctor's long running routines, restarting any that
+// crash and coordinating their shutdown
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kava-labs/doctor/logging"
+)
+
+const (
+	DefaultInitialRestartBackoff = 1 * time.Second
+	DefaultMaxRestartBackoff     = 1 * time.Minute
+)
+
+var (
+	ErrRoutineExited = errors.New("routine exited before being stopped")
+)
+
+// SupervisedRoutine is a long running routine that runs until
+// the context is cancelled, returning error (if any)
+type SupervisedRoutine func(ctx context.Context) error
+
+// SupervisorConfig wraps values used
+// for creating a Supervisor
+type SupervisorConfig struct {
+	Logger *logging.Logger
+	// how long to wait before restarting a routine after
+	// its first crash, doubling for each consecutive crash
+	InitialRestartBackoff time.Duration
+	// the maximum time to wait before restarting a routine, a
+	// routine that runs for longer than this without crashing has
+	// its backoff reset to the initial restart backoff
+	MaxRestartBackoff time.Duration
+}
+
+// routineFailures tracks how often
+// a supervised routine has crashed
+type routineFailures struct {
+	crashes int
+	lastErr error
+}
+
+// Supervisor owns a group of routines, restarting with backoff any that
+// crash before the supervisor is stopped and aggregating their errors
+type Supervisor struct {
+	ctx                   context.Context
+	cancel                context.CancelFunc
+	logger                *logging.Logger
+	initialRestartBackoff time.Duration
+	maxRestartBackoff     time.Duration
+	routines              *sync.WaitGroup
+	failures              map[string]*routineFailures
+	failuresLock          *sync.Mutex
+}
+
+// NewSupervisor returns a new supervisor whose routines
+// are stopped when the provided context is cancelled
+func NewSupervisor(ctx context.Context, config SupervisorConfig) *Supervisor {
+	ctx, cancel := context.WithCancel(ctx)
+
+	initialRestartBackoff := config.InitialRestartBackoff
+
+	if initialRestartBackoff <= 0 {
+		initialRestartBackoff = DefaultInitialRestartBackoff
+	}
+
+	maxRestartBackoff := config.MaxRestartBackoff
+
+	if maxRestartBackoff < initialRestartBackoff {
+		maxRestartBackoff = initialRestartBackoff
+	}
+
+	return &Supervisor{
+		ctx:                   ctx,
+		cancel:                cancel,
+		logger:                config.Logger,
+		initialRestartBackoff: initialRestartBackoff,
+		maxRestartBackoff:     maxRestartBackoff,
+		routines:              &sync.WaitGroup{},
+		failures:              map[string]*routineFailures{},
+		failuresLock:          &sync.Mutex{},
+	}
+}
+
+// Go runs the routine in the background, restarting it with backoff
+// whenever it panics, errors or returns before the supervisor is stopped
+func (s *Supervisor) Go(name string, routine SupervisedRoutine) {
+	s.routines.Add(1)
+
+	go func() {
+		defer s.routines.Done()
+
+		backoff := s.initialRestartBackoff
+
+		for {
+			startedAt := time.Now()
+
+			err := runRoutine(s.ctx, routine)
+
+			// routines are expected to exit once stopped
+			if s.ctx.Err() != nil {
+				return
+			}
+
+			if err == nil {
+				err = ErrRoutineExited
+			}
+
+			s.recordFailure(name, err)
+
+			// a routine that ran stably for a while before
+			// crashing shouldn't wait as if it's crash looping
+			if time.Since(startedAt) > s.maxRestartBackoff {
+				backoff = s.initialRestartBackoff
+			}
+
+			s.log(func(logger *logging.Logger) {
+				logger.Errorf("supervised routine %s crashed with error %s, restarting in %v", name, err, backoff)
+			})
+
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+
+			if backoff > s.maxRestartBackoff {
+				backoff = s.maxRestartBackoff
+			}
+		}
+	}()
+}
+
+// Run runs the routine in the calling goroutine without restarting it
+// (e.g. the display the doctor exits after), stopping all supervised
+// routines once it returns and recording its error (if any) for Wait
+func (s *Supervisor) Run(name string, routine SupervisedRoutine) {
+	defer s.Stop()
+
+	err := runRoutine(s.ctx, routine)
+
+	if err != nil {
+		s.recordFailure(name, err)
+	}
+}
+
+// Stop signals all supervised routines to stop
+func (s *Supervisor) Stop() {
+	s.cancel()
+}
+
+// Wait stops and waits (up to timeout) for all supervised routines
+// to exit, returning an error summarizing all routine failures (if any)
+func (s *Supervisor) Wait(timeout time.Duration) error {
+	s.Stop()
+
+	routinesDone := make(chan struct{})
+
+	go func() {
+		defer close(routinesDone)
+
+		s.routines.Wait()
+	}()
+
+	var timeoutErr error
+
+	select {
+	case <-routinesDone:
+	case <-time.After(timeout):
+		timeoutErr = fmt.Errorf("timed out after %v waiting for supervised routines to exit", timeout)
+	}
+
+	s.failuresLock.Lock()
+	defer s.failuresLock.Unlock()
+
+	var names []string
+
+	for name := range s.failures {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var failures []string
+
+	for _, name := range names {
+		failure := s.failures[name]
+
+		failures = append(failures, fmt.Sprintf("%s failed %d time(s), last error: %s", name, failure.crashes, failure.lastErr))
+	}
+
+	if timeoutErr != nil {
+		failures = append(failures, timeoutErr.Error())
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s", strings.Join(failures, "; "))
+}
+
+// recordFailure records the routine with the specified name failed
+func (s *Supervisor) recordFailure(name string, err error) {
+	s.failuresLock.Lock()
+	defer s.failuresLock.Unlock()
+
+	failure, exists := s.failures[name]
+
+	if !exists {
+		failure = &routineFailures{}
+		s.failures[name] = failure
+	}
+
+	failure.crashes++
+	failure.lastErr = err
+}
+
+// log logs using the supervisor's logger (if any) without blocking
+// the calling routine on the display consuming the log message
+func (s *Supervisor) log(logFunc func(logger *logging.Logger)) {
+	if s.logger == nil {
+		return
+	}
+
+	go logFunc(s.logger)
+}
+
+// runRoutine runs the routine, recovering
+// and returning any panic as an error
+func runRoutine(ctx context.Context, routine SupervisedRoutine) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return routine(ctx)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupervisorRestartsCrashedRoutines(t *testing.T) {
+	supervisor := NewSupervisor(context.Background(), SupervisorConfig{
+		InitialRestartBackoff: time.Millisecond,
+		MaxRestartBackoff:     time.Millisecond,
+	})
+
+	var runs int32
+
+	supervisor.Go("crasher", func(ctx context.Context) error {
+		if atomic.AddInt32(&runs, 1) < 3 {
+			panic("crashed")
+		}
+
+		<-ctx.Done()
+
+		return nil
+	})
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&runs) == 3
+	}, time.Second, time.Millisecond)
+
+	err := supervisor.Wait(time.Second)
+
+	assert.EqualError(t, err, "crasher failed 2 time(s), last error: panic: crashed")
+}
+
+func TestSupervisorRunStopsSupervisedRoutines(t *testing.T) {
+	supervisor := NewSupervisor(context.Background(), SupervisorConfig{})
+
+	stopped := make(chan struct{})
+
+	supervisor.Go("watcher", func(ctx context.Context) error {
+		<-ctx.Done()
+
+		close(stopped)
+
+		return nil
+	})
+
+	supervisor.Run("display", func(ctx context.Context) error {
+		return nil
+	})
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("supervised routine not stopped after display exited")
+	}
+
+	assert.Nil(t, supervisor.Wait(time.Second))
+}
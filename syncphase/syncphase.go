@@ -0,0 +1,99 @@
+// package syncphase detects which phase of synching a node is in from
+// its status alone (without access to its logs), distinguishing nodes
+// restoring a state sync snapshot and nodes replaying blocks to catch
+// up from nodes that are synched to live
+package syncphase
+
+import (
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+)
+
+const (
+	// the node is restoring a state sync snapshot
+	StateSync = "statesync"
+	// the node is replaying blocks to catch up to live
+	BlockSync = "blocksync"
+	// the node is synched to live and participating in consensus
+	Live = "live"
+
+	// blocks per second faster than which a node can't replay blocks,
+	// a greater jump in height between two samples is taken to mean
+	// a snapshot was restored in between
+	MaxBlockSyncBlocksPerSecond = 1000
+)
+
+var (
+	// phases in the order of their reported values
+	Phases = []string{Live, BlockSync, StateSync}
+)
+
+// Value returns the number the phase is reported as in
+// metrics, its index in Phases, or -1 for an unknown phase
+func Value(phase string) float64 {
+	for i, knownPhase := range Phases {
+		if phase == knownPhase {
+			return float64(i)
+		}
+	}
+
+	return -1
+}
+
+// observation is the last sample of a node's sync status
+type observation struct {
+	phase       string
+	blockHeight int64
+	sampledAt   time.Time
+}
+
+// Detector detects the sync phase of nodes from consecutive
+// samples of their sync status
+// Detector is not safe for concurrent use
+type Detector struct {
+	observations map[string]observation
+}
+
+// NewDetector returns a new detector
+func NewDetector() *Detector {
+	return &Detector{
+		observations: make(map[string]observation),
+	}
+}
+
+// Observe returns the sync phase of the specified node as of the
+// sync status sampled at the specified time, along with the phase
+// the node was in as of its previous sample (empty if this is the
+// first sample observed for the node)
+func (d *Detector) Observe(nodeId string, syncInfo kava.SyncInfo, sampledAt time.Time) (string, string) {
+	previous, observed := d.observations[nodeId]
+
+	phase := BlockSync
+
+	switch {
+	case !syncInfo.CatchingUp:
+		phase = Live
+	case syncInfo.LatestBlockHeight == 0:
+		// no blocks are stored until the snapshot is restored
+		phase = StateSync
+	case observed && previous.blockHeight > 0:
+		elapsedSeconds := sampledAt.Sub(previous.sampledAt).Seconds()
+
+		if elapsedSeconds < 1 {
+			elapsedSeconds = 1
+		}
+
+		if float64(syncInfo.LatestBlockHeight-previous.blockHeight) > elapsedSeconds*MaxBlockSyncBlocksPerSecond {
+			phase = StateSync
+		}
+	}
+
+	d.observations[nodeId] = observation{
+		phase:       phase,
+		blockHeight: syncInfo.LatestBlockHeight,
+		sampledAt:   sampledAt,
+	}
+
+	return phase, previous.phase
+}
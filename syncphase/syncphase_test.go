@@ -0,0 +1,61 @@
+package syncphase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/clients/kava"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	TestNodeId = "06ff9460163caac703c44da1b2e3108e1ba087cd"
+)
+
+func TestDetectorDetectsPhaseTransitions(t *testing.T) {
+	detector := NewDetector()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		description      string
+		syncInfo         kava.SyncInfo
+		expectedPhase    string
+		expectedPrevious string
+	}{
+		{"restoring snapshot", kava.SyncInfo{CatchingUp: true}, StateSync, ""},
+		{"still restoring snapshot", kava.SyncInfo{CatchingUp: true}, StateSync, StateSync},
+		{"replaying blocks after the snapshot", kava.SyncInfo{LatestBlockHeight: 2000000, CatchingUp: true}, BlockSync, StateSync},
+		{"replaying blocks", kava.SyncInfo{LatestBlockHeight: 2000500, CatchingUp: true}, BlockSync, BlockSync},
+		{"synched", kava.SyncInfo{LatestBlockHeight: 2001000}, Live, BlockSync},
+		{"fell behind", kava.SyncInfo{LatestBlockHeight: 2001001, CatchingUp: true}, BlockSync, Live},
+		{"restored from a newer snapshot", kava.SyncInfo{LatestBlockHeight: 3000000, CatchingUp: true}, StateSync, BlockSync},
+	}
+
+	for i, tc := range testCases {
+		phase, previous := detector.Observe(TestNodeId, tc.syncInfo, now.Add(time.Duration(i)*10*time.Second))
+
+		assert.Equal(t, tc.expectedPhase, phase, tc.description)
+		assert.Equal(t, tc.expectedPrevious, previous, tc.description)
+	}
+}
+
+func TestDetectorTracksNodesIndependently(t *testing.T) {
+	detector := NewDetector()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	detector.Observe(TestNodeId, kava.SyncInfo{LatestBlockHeight: 100}, now)
+
+	phase, previous := detector.Observe("other-node", kava.SyncInfo{LatestBlockHeight: 2000000, CatchingUp: true}, now.Add(time.Second))
+
+	assert.Equal(t, BlockSync, phase, "jump in height is relative to the same node's previous sample")
+	assert.Empty(t, previous)
+}
+
+func TestValue(t *testing.T) {
+	assert.Equal(t, float64(0), Value(Live))
+	assert.Equal(t, float64(1), Value(BlockSync))
+	assert.Equal(t, float64(2), Value(StateSync))
+	assert.Equal(t, float64(-1), Value("unknown"))
+}
@@ -0,0 +1,153 @@
+// thresholds.go contains types for editing the monitoring interval
+// and autohealing thresholds of a running doctor from the gui
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// thresholdField is a setting that can be edited using the threshold editor
+type thresholdField struct {
+	name string
+	// smallest valid value for the setting
+	min   int
+	value func(control *NodeClientControl) *int
+}
+
+// thresholdFields are the settings that can be edited in display order
+var thresholdFields = []thresholdField{
+	{
+		name:  "monitoring interval seconds",
+		min:   1,
+		value: func(control *NodeClientControl) *int { return &control.MonitoringIntervalSeconds },
+	},
+	{
+		name:  "autoheal sync latency tolerance seconds",
+		min:   1,
+		value: func(control *NodeClientControl) *int { return &control.AutohealSyncLatencyToleranceSeconds },
+	},
+	{
+		name:  "downtime restart threshold seconds",
+		min:   1,
+		value: func(control *NodeClientControl) *int { return &control.DowntimeRestartThresholdSeconds },
+	},
+	{
+		name:  "no new blocks restart threshold seconds",
+		min:   1,
+		value: func(control *NodeClientControl) *int { return &control.NoNewBlocksRestartThresholdSeconds },
+	},
+	{
+		name:  "autoheal restart delay seconds",
+		min:   0,
+		value: func(control *NodeClientControl) *int { return &control.AutohealRestartDelaySeconds },
+	},
+}
+
+// ThresholdEditor tracks the state of editing the monitoring
+// interval and autohealing thresholds, with one setting selected
+// at a time that digits typed by the user replace the value of
+// ThresholdEditor is not safe to use across go-routines
+type ThresholdEditor struct {
+	control  NodeClientControl
+	selected int
+	// digits typed for the selected setting, if any
+	input string
+	open  bool
+}
+
+// Open starts editing the provided settings
+func (e *ThresholdEditor) Open(control NodeClientControl) {
+	*e = ThresholdEditor{
+		control: control,
+		open:    true,
+	}
+}
+
+// IsOpen returns whether settings are being edited
+func (e *ThresholdEditor) IsOpen() bool {
+	return e.open
+}
+
+// commitInput sets the selected setting to the typed
+// digits (if any) if they're a valid value for the setting
+func (e *ThresholdEditor) commitInput() {
+	if e.input == "" {
+		return
+	}
+
+	field := thresholdFields[e.selected]
+
+	value, err := strconv.Atoi(e.input)
+
+	if err == nil && value >= field.min {
+		*field.value(&e.control) = value
+	}
+
+	e.input = ""
+}
+
+// HandleKey handles a key pressed (using termui key names)
+// while editing, returning the edited settings and true once
+// the user applies them, the editor is closed once the user
+// applies or cancels the edits
+func (e *ThresholdEditor) HandleKey(key string) (NodeClientControl, bool) {
+	switch key {
+	case "<Escape>":
+		e.open = false
+	case "<Enter>":
+		e.commitInput()
+		e.open = false
+
+		return e.control, true
+	case "<Up>":
+		e.commitInput()
+
+		if e.selected > 0 {
+			e.selected--
+		}
+	case "<Down>":
+		e.commitInput()
+
+		if e.selected < len(thresholdFields)-1 {
+			e.selected++
+		}
+	case "<Backspace>", "<C-<Backspace>>":
+		if len(e.input) > 0 {
+			e.input = e.input[:len(e.input)-1]
+		}
+	default:
+		if len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+			e.input += key
+		}
+	}
+
+	return NodeClientControl{}, false
+}
+
+// Text returns the settings being edited
+// formatted for display, one per line
+func (e *ThresholdEditor) Text() string {
+	var builder strings.Builder
+
+	builder.WriteString("Up/Down to select, type a value, Enter to apply, Esc to cancel\n\n")
+
+	for i, field := range thresholdFields {
+		value := strconv.Itoa(*field.value(&e.control))
+		marker := "  "
+
+		if i == e.selected {
+			marker = "> "
+
+			if e.input != "" {
+				value = e.input + "_"
+			}
+		}
+
+		builder.WriteString(fmt.Sprintf("%s%s: %s\n", marker, field.name, value))
+	}
+
+	return builder.String()
+}
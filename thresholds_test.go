@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThresholdEditorAppliesValidEdits(t *testing.T) {
+	initial := NodeClientControl{
+		MonitoringIntervalSeconds:           5,
+		AutohealSyncLatencyToleranceSeconds: 120,
+		DowntimeRestartThresholdSeconds:     300,
+		NoNewBlocksRestartThresholdSeconds:  300,
+		AutohealRestartDelaySeconds:         600,
+	}
+
+	editor := &ThresholdEditor{}
+	editor.Open(initial)
+	assert.True(t, editor.IsOpen())
+
+	// replace the monitoring interval, correcting a typo
+	for _, key := range []string{"1", "5", "<Backspace>", "0"} {
+		editor.HandleKey(key)
+	}
+
+	assert.Contains(t, editor.Text(), "> monitoring interval seconds: 10_")
+
+	// a value below the minimum is discarded when moving on
+	editor.HandleKey("<Down>")
+	editor.HandleKey("0")
+	editor.HandleKey("<Down>")
+
+	editor.HandleKey("9")
+	editor.HandleKey("0")
+
+	control, applied := editor.HandleKey("<Enter>")
+	assert.True(t, applied)
+	assert.False(t, editor.IsOpen())
+
+	expected := initial
+	expected.MonitoringIntervalSeconds = 10
+	expected.DowntimeRestartThresholdSeconds = 90
+
+	assert.Equal(t, expected, control)
+}
+
+func TestThresholdEditorCancelsEdits(t *testing.T) {
+	editor := &ThresholdEditor{}
+	editor.Open(NodeClientControl{MonitoringIntervalSeconds: 5})
+
+	editor.HandleKey("7")
+
+	_, applied := editor.HandleKey("<Escape>")
+	assert.False(t, applied)
+	assert.False(t, editor.IsOpen())
+}
@@ -0,0 +1,281 @@
+// timeline.go contains types for correlating the health state
+// changes, heal actions, deploy annotations and alerts for a node
+// on a single time axis, and the gui widget for drawing them
+
+package main
+
+import (
+	"image"
+	"sort"
+	"time"
+
+	ui "github.com/gizak/termui/v3"
+	"github.com/kava-labs/doctor/annotation"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/logging"
+	"github.com/kava-labs/doctor/syncphase"
+)
+
+const (
+	// span of time shown on the timeline
+	DefaultTimelineWindow = 1 * time.Hour
+	// max number of events retained for the timeline
+	DefaultMaxTimelineEvents = 1000
+)
+
+// TimelineLane is the kind of event shown on a lane of the timeline
+type TimelineLane int
+
+const (
+	HealthTimelineLane TimelineLane = iota
+	HealTimelineLane
+	DeployTimelineLane
+	AlertTimelineLane
+)
+
+// TimelineLanes are the lanes of the timeline in display order
+var TimelineLanes = []TimelineLane{HealthTimelineLane, HealTimelineLane, DeployTimelineLane, AlertTimelineLane}
+
+// String returns the label for the lane
+func (l TimelineLane) String() string {
+	switch l {
+	case HealthTimelineLane:
+		return "health"
+	case HealTimelineLane:
+		return "heal"
+	case DeployTimelineLane:
+		return "deploy"
+	case AlertTimelineLane:
+		return "alert"
+	}
+
+	return "unknown"
+}
+
+// TimelineEvent is a single event on the timeline
+type TimelineEvent struct {
+	Lane TimelineLane
+	// node the event is for, empty for
+	// events that apply to every node
+	NodeId     string
+	Message    string
+	OccurredAt time.Time
+	// whether the event is a recovery (e.g. an incident
+	// closing or a heal action succeeding)
+	Recovered bool
+}
+
+// Timeline retains the most recent events for all nodes
+// Timeline is not safe to use across go-routines
+type Timeline struct {
+	events    []TimelineEvent
+	maxEvents int
+	// alerts currently firing, keyed by node id and alert name
+	firingAlerts map[string]bool
+}
+
+// NewTimeline returns a new timeline
+// retaining up to maxEvents events
+func NewTimeline(maxEvents int) *Timeline {
+	if maxEvents <= 0 {
+		maxEvents = DefaultMaxTimelineEvents
+	}
+
+	return &Timeline{
+		maxEvents:    maxEvents,
+		firingAlerts: make(map[string]bool),
+	}
+}
+
+// Add adds the event to the timeline, pruning the oldest
+// events until only maxEvents events are retained
+func (t *Timeline) Add(event TimelineEvent) {
+	// events (e.g. from annotations) may arrive out of order
+	index := sort.Search(len(t.events), func(i int) bool {
+		return t.events[i].OccurredAt.After(event.OccurredAt)
+	})
+
+	t.events = append(t.events, TimelineEvent{})
+	copy(t.events[index+1:], t.events[index:])
+	t.events[index] = event
+
+	if len(t.events) > t.maxEvents {
+		t.events = t.events[len(t.events)-t.maxEvents:]
+	}
+}
+
+// AddIncidentEvent adds an incident opening or closing
+// or a heal action being taken to the timeline
+func (t *Timeline) AddIncidentEvent(event incident.Event) {
+	timelineEvent := TimelineEvent{
+		Lane:       HealthTimelineLane,
+		NodeId:     event.NodeId,
+		Message:    event.Message,
+		OccurredAt: event.OccurredAt,
+		Recovered:  event.Type == incident.IncidentClosedEventType,
+	}
+
+	if event.Type == incident.HealActionEventType {
+		timelineEvent.Lane = HealTimelineLane
+		timelineEvent.Recovered = event.Succeeded
+	}
+
+	if event.Type == incident.SyncPhaseChangedEventType {
+		timelineEvent.Recovered = event.Kind == syncphase.Live
+	}
+
+	t.Add(timelineEvent)
+}
+
+// AddAnnotation adds a deploy annotation to the timeline
+func (t *Timeline) AddAnnotation(annotation annotation.Annotation) {
+	t.Add(TimelineEvent{
+		Lane:       DeployTimelineLane,
+		NodeId:     annotation.NodeId,
+		Message:    annotation.Message,
+		OccurredAt: annotation.OccurredAt,
+	})
+}
+
+// SetAlert records whether the named alert is firing for the node,
+// adding an event to the timeline only when the alert starts firing
+// so that an alert firing for every sample is only shown once
+func (t *Timeline) SetAlert(nodeId string, name string, firing bool, message string, occurredAt time.Time) {
+	key := nodeId + "/" + name
+
+	if firing && !t.firingAlerts[key] {
+		t.Add(TimelineEvent{
+			Lane:       AlertTimelineLane,
+			NodeId:     nodeId,
+			Message:    message,
+			OccurredAt: occurredAt,
+		})
+	}
+
+	t.firingAlerts[key] = firing
+}
+
+// Events returns the events for the node (including events
+// for every node) that occurred at or after since
+// ordered from oldest to newest
+func (t *Timeline) Events(nodeId string, since time.Time) []TimelineEvent {
+	var events []TimelineEvent
+
+	for _, event := range t.events {
+		if event.OccurredAt.Before(since) {
+			continue
+		}
+
+		if event.NodeId != "" && event.NodeId != nodeId {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// TimelineWidget implements the termui Drawable interface
+// drawing timeline events as markers on one lane per kind
+// of event along a shared time axis, with the message
+// of the most recent event shown underneath
+type TimelineWidget struct {
+	ui.Block
+	Events []TimelineEvent
+	// span of time ending at End shown on the timeline
+	Window time.Duration
+	End    time.Time
+	// time zone to label the time axis and events in, the
+	// labels are always shown in a compact layout
+	TimeFormat logging.TimeFormat
+}
+
+// NewTimelineWidget returns a new timeline widget
+func NewTimelineWidget() *TimelineWidget {
+	return &TimelineWidget{
+		Block:  *ui.NewBlock(),
+		Window: DefaultTimelineWindow,
+	}
+}
+
+// marker returns the marker and style to draw for the event
+func (w *TimelineWidget) marker(event TimelineEvent) (rune, ui.Style) {
+	switch event.Lane {
+	case HealthTimelineLane, HealTimelineLane:
+		if event.Recovered {
+			return '▲', ui.NewStyle(ui.ColorGreen)
+		}
+
+		return '▼', ui.NewStyle(ui.ColorRed)
+	case DeployTimelineLane:
+		return '◆', ui.NewStyle(ui.ColorCyan)
+	}
+
+	return '!', ui.NewStyle(ui.ColorYellow)
+}
+
+// Draw draws the timeline to the buffer
+func (w *TimelineWidget) Draw(buf *ui.Buffer) {
+	w.Block.Draw(buf)
+
+	labelWidth := 0
+
+	for _, lane := range TimelineLanes {
+		if len(lane.String())+1 > labelWidth {
+			labelWidth = len(lane.String()) + 1
+		}
+	}
+
+	axisWidth := w.Inner.Dx() - labelWidth
+
+	if axisWidth <= 1 || w.Window <= 0 {
+		return
+	}
+
+	start := w.End.Add(-w.Window)
+
+	// one row per lane, leaving a row for the time
+	// axis and most recent event if there's room
+	for row, lane := range TimelineLanes {
+		y := w.Inner.Min.Y + row
+
+		if y >= w.Inner.Max.Y {
+			return
+		}
+
+		buf.SetString(lane.String(), ui.NewStyle(ui.ColorWhite), image.Pt(w.Inner.Min.X, y))
+
+		for x := 0; x < axisWidth; x++ {
+			buf.SetCell(ui.NewCell('·', ui.NewStyle(ui.ColorBlack)), image.Pt(w.Inner.Min.X+labelWidth+x, y))
+		}
+
+		for _, event := range w.Events {
+			if event.Lane != lane || event.OccurredAt.Before(start) || event.OccurredAt.After(w.End) {
+				continue
+			}
+
+			x := int(float64(event.OccurredAt.Sub(start)) / float64(w.Window) * float64(axisWidth-1))
+
+			marker, style := w.marker(event)
+
+			buf.SetCell(ui.NewCell(marker, style), image.Pt(w.Inner.Min.X+labelWidth+x, y))
+		}
+	}
+
+	axisY := w.Inner.Min.Y + len(TimelineLanes)
+
+	if axisY < w.Inner.Max.Y {
+		startLabel := w.TimeFormat.In(start).Format("15:04")
+		endLabel := w.TimeFormat.In(w.End).Format("15:04")
+
+		buf.SetString(startLabel, ui.NewStyle(ui.ColorWhite), image.Pt(w.Inner.Min.X+labelWidth, axisY))
+		buf.SetString(endLabel, ui.NewStyle(ui.ColorWhite), image.Pt(w.Inner.Max.X-len(endLabel), axisY))
+	}
+
+	if axisY+1 < w.Inner.Max.Y && len(w.Events) > 0 {
+		latest := w.Events[len(w.Events)-1]
+
+		buf.SetString(ui.TrimString(w.TimeFormat.In(latest.OccurredAt).Format("15:04:05")+" "+latest.Lane.String()+": "+latest.Message, w.Inner.Dx()), ui.NewStyle(ui.ColorWhite), image.Pt(w.Inner.Min.X, axisY+1))
+	}
+}
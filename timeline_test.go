@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/annotation"
+	"github.com/kava-labs/doctor/incident"
+	"github.com/kava-labs/doctor/syncphase"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimelineCorrelatesEventsForNode(t *testing.T) {
+	timeline := NewTimeline(10)
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	timeline.AddIncidentEvent(incident.Event{
+		Type:       incident.IncidentOpenedEventType,
+		NodeId:     "node-1",
+		Message:    "node is 300 seconds behind live",
+		OccurredAt: start.Add(2 * time.Minute),
+	})
+	timeline.AddIncidentEvent(incident.Event{
+		Type:       incident.HealActionEventType,
+		Succeeded:  true,
+		Message:    "restarted kava service",
+		OccurredAt: start.Add(3 * time.Minute),
+	})
+	// annotations may be recorded out of order
+	timeline.AddAnnotation(annotation.Annotation{
+		Message:    "deployed v0.24.1",
+		OccurredAt: start.Add(1 * time.Minute),
+	})
+
+	// alerts are only added when they start firing
+	for minute, firing := range []bool{true, true, false, true} {
+		timeline.SetAlert("node-2", "PeerChurn", firing, "high peer churn", start.Add(time.Duration(minute)*time.Minute))
+	}
+
+	var lanes []TimelineLane
+
+	for _, event := range timeline.Events("node-1", start) {
+		lanes = append(lanes, event.Lane)
+	}
+
+	assert.Equal(t, []TimelineLane{DeployTimelineLane, HealthTimelineLane, HealTimelineLane}, lanes)
+
+	node2Events := timeline.Events("node-2", start.Add(90*time.Second))
+
+	assert.Len(t, node2Events, 2)
+	assert.Equal(t, AlertTimelineLane, node2Events[1].Lane)
+	assert.Equal(t, start.Add(3*time.Minute), node2Events[1].OccurredAt)
+}
+
+func TestTimelineShowsSyncPhaseChangesAsHealthEvents(t *testing.T) {
+	timeline := NewTimeline(10)
+
+	start := time.Date(2026, 10, 16, 10, 0, 0, 0, time.UTC)
+
+	for minute, phase := range []string{syncphase.StateSync, syncphase.BlockSync, syncphase.Live} {
+		timeline.AddIncidentEvent(incident.Event{
+			Type:       incident.SyncPhaseChangedEventType,
+			Kind:       phase,
+			NodeId:     "node-1",
+			Message:    "node sync phase changed to " + phase,
+			OccurredAt: start.Add(time.Duration(minute) * time.Minute),
+		})
+	}
+
+	events := timeline.Events("node-1", start)
+
+	assert.Len(t, events, 3)
+
+	for _, event := range events {
+		assert.Equal(t, HealthTimelineLane, event.Lane)
+	}
+
+	assert.False(t, events[1].Recovered, "replaying blocks")
+	assert.True(t, events[2].Recovered, "synched to live")
+}
@@ -0,0 +1,168 @@
+// package webhook provides a client for sending json encoded
+// payloads (e.g. metrics or alerts) to an arbitrary http endpoint,
+// allowing the doctor to be integrated with downstream systems
+// without needing a dedicated collector for each system
+package webhook
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kava-labs/doctor/audit"
+)
+
+const (
+	// header containing the hex encoded signature
+	// of the request body when signing is enabled
+	SignatureHeader          = "X-Doctor-Signature"
+	DefaultMaxRetries        = 3
+	DefaultRetryBackoff      = 1 * time.Second
+	DefaultHTTPTimeout       = 10 * time.Second
+	MetricPayloadType        = "metric"
+	IncidentEventPayloadType = "incident_event"
+	EventPayloadType         = "event"
+)
+
+// Payload wraps data sent to a webhook
+// with the type of data being sent
+type Payload struct {
+	Type   string      `json:"type"`
+	SentAt time.Time   `json:"sent_at"`
+	Data   interface{} `json:"data"`
+}
+
+// ClientConfig wraps values for
+// configuring a webhook client
+type ClientConfig struct {
+	URL string
+	// if set, payloads are signed (e.g. using a HMAC shared
+	// secret) so the receiver can verify they came from the doctor
+	Signer       audit.Signer
+	MaxRetries   int
+	RetryBackoff time.Duration
+	HTTPTimeout  time.Duration
+}
+
+// Client posts payloads to a webhook url,
+// retrying failed requests with exponential backoff
+type Client struct {
+	url          string
+	signer       audit.Signer
+	maxRetries   int
+	retryBackoff time.Duration
+	httpClient   *http.Client
+}
+
+// New returns a new webhook client using the
+// provided config (or defaults where appropriate)
+func New(config ClientConfig) *Client {
+	maxRetries := DefaultMaxRetries
+
+	if config.MaxRetries > 0 {
+		maxRetries = config.MaxRetries
+	}
+
+	retryBackoff := DefaultRetryBackoff
+
+	if config.RetryBackoff > 0 {
+		retryBackoff = config.RetryBackoff
+	}
+
+	httpTimeout := DefaultHTTPTimeout
+
+	if config.HTTPTimeout > 0 {
+		httpTimeout = config.HTTPTimeout
+	}
+
+	return &Client{
+		url:          config.URL,
+		signer:       config.Signer,
+		maxRetries:   maxRetries,
+		retryBackoff: retryBackoff,
+		httpClient: &http.Client{
+			Timeout: httpTimeout,
+		},
+	}
+}
+
+// Post posts a payload of the specified type with the provided data
+// to the webhook, retrying up to the configured max retries if the
+// request fails or the webhook responds with a server error or
+// rate limits the request, returning error (if any)
+func (c *Client) Post(payloadType string, data interface{}) error {
+	body, err := json.Marshal(Payload{
+		Type:   payloadType,
+		SentAt: time.Now(),
+		Data:   data,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	var signature string
+
+	if c.signer != nil {
+		rawSignature, err := c.signer.Sign(body)
+
+		if err != nil {
+			return err
+		}
+
+		signature = hex.EncodeToString(rawSignature)
+	}
+
+	backoff := c.retryBackoff
+
+	for attempt := 0; ; attempt++ {
+		retryable, err := c.post(body, signature)
+
+		if err == nil || !retryable || attempt == c.maxRetries {
+			return err
+		}
+
+		time.Sleep(backoff)
+
+		backoff *= 2
+	}
+}
+
+// post makes a single request to the webhook, returning error (if any)
+// and whether the request should be retried
+func (c *Client) post(body []byte, signature string) (bool, error) {
+	request, err := http.NewRequest(http.MethodPost, c.url, bytes.NewReader(body))
+
+	if err != nil {
+		return false, err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	if signature != "" {
+		request.Header.Set(SignatureHeader, signature)
+	}
+
+	response, err := c.httpClient.Do(request)
+
+	if err != nil {
+		return true, err
+	}
+
+	defer response.Body.Close()
+
+	// drain the body to allow the connection to be reused
+	io.Copy(io.Discard, response.Body)
+
+	if response.StatusCode >= 200 && response.StatusCode <= 299 {
+		return false, nil
+	}
+
+	retryable := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+
+	return retryable, fmt.Errorf("webhook %s responded with status %d", c.url, response.StatusCode)
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kava-labs/doctor/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostSignsPayload(t *testing.T) {
+	key := []byte("secret")
+
+	var payload Payload
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mac := hmac.New(sha256.New, key)
+		mac.Write(body)
+
+		assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), r.Header.Get(SignatureHeader))
+		assert.Nil(t, json.Unmarshal(body, &payload))
+	}))
+	defer server.Close()
+
+	client := New(ClientConfig{URL: server.URL, Signer: audit.NewHMACSigner(key)})
+
+	err := client.Post(MetricPayloadType, map[string]string{"name": "LatestBlockHeight"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, MetricPayloadType, payload.Type)
+}
+
+func TestPostRetriesServerErrors(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := New(ClientConfig{URL: server.URL, RetryBackoff: time.Millisecond})
+
+	assert.Nil(t, client.Post(MetricPayloadType, nil))
+	assert.Equal(t, 3, requests)
+}
+
+func TestPostDoesNotRetryClientErrors(t *testing.T) {
+	var requests int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := New(ClientConfig{URL: server.URL, RetryBackoff: time.Millisecond})
+
+	assert.NotNil(t, client.Post(MetricPayloadType, nil))
+	assert.Equal(t, 1, requests)
+}